@@ -9,13 +9,14 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"os"
 	"strings"
-	"sync"
 	"time"
 
-	"github.com/dgrijalva/jwt-go"
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+
+	"packages/jwt"
 )
 
 type User struct {
@@ -48,14 +49,67 @@ func LogInfo(ctx context.Context, msg string, fields map[string]any) {
 	log.Printf("[INFO] %s | context: %v", msg, fields)
 }
 
-// In-memory token storage for starter package
-var (
-	revokedTokens = make(map[string]bool)
-	tokenMutex    sync.RWMutex
-)
+// defaultRevocationTTL bounds how long a revocation is kept when the token carries no expiry
+const defaultRevocationTTL = 24 * time.Hour
+
+// defaultTokenStore backs the package-level helpers for callers that don't inject a store
+var defaultTokenStore TokenStore = NewMemoryTokenStore()
+
+// TokenScope is the issuer ("iss") and audience ("aud") accepted tokens must carry. An empty
+// field is not checked. It must match the scope auth-service issues tokens with.
+type TokenScope = jwt.Scope
+
+// ScopeFromEnv reads the expected token scope from JWT_ISSUER and JWT_AUDIENCE
+func ScopeFromEnv() TokenScope {
+	return TokenScope{Issuer: os.Getenv("JWT_ISSUER"), Audience: os.Getenv("JWT_AUDIENCE")}
+}
+
+// Authenticator validates JWT access tokens and checks them against a TokenStore.
+type Authenticator struct {
+	secrets []string // current secret first, then retired ones still accepted
+	scope   TokenScope
+	store   TokenStore
+}
+
+// NewAuthenticator creates an Authenticator that signs with secret and records revocations in store.
+// Tokens signed with any of previousSecrets are still accepted, so the secret can be rotated
+// without logging everyone out. Pass a PostgresTokenStore to share revocations across restarts
+// and replicas. Tokens must carry the issuer and audience from ScopeFromEnv; use WithScope
+// to expect others.
+func NewAuthenticator(secret string, store TokenStore, previousSecrets ...string) *Authenticator {
+	if store == nil {
+		store = NewMemoryTokenStore()
+	}
+	return &Authenticator{secrets: append([]string{secret}, previousSecrets...), scope: ScopeFromEnv(), store: store}
+}
+
+// WithScope sets the issuer and audience accepted tokens must carry
+func (a *Authenticator) WithScope(scope TokenScope) *Authenticator {
+	a.scope = scope
+	return a
+}
 
 // AuthMiddleware validates JWT access token and injects user into context.
-func AuthMiddleware(JWTAccessTokenSecret string) gin.HandlerFunc {
+// It uses the package's in-memory token store; use NewAuthenticator to inject another store.
+func AuthMiddleware(JWTAccessTokenSecret string, previousSecrets ...string) gin.HandlerFunc {
+	return NewAuthenticator(JWTAccessTokenSecret, defaultTokenStore, previousSecrets...).AuthMiddleware()
+}
+
+// SignoutMiddleware validates token format but allows expired tokens for signout.
+// It uses the package's in-memory token store; use NewAuthenticator to inject another store.
+func SignoutMiddleware(JWTAccessTokenSecret string, previousSecrets ...string) gin.HandlerFunc {
+	return NewAuthenticator(JWTAccessTokenSecret, defaultTokenStore, previousSecrets...).SignoutMiddleware()
+}
+
+// RevokeToken adds a token to the package's in-memory revoked tokens list
+func RevokeToken(token string) {
+	if err := NewAuthenticator("", defaultTokenStore).RevokeToken(context.Background(), token); err != nil {
+		LogError(context.Background(), err, "Failed to revoke token", http.StatusInternalServerError)
+	}
+}
+
+// AuthMiddleware validates JWT access token and injects user into context.
+func (a *Authenticator) AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 		token, err := extractToken(c.GetHeader("Authorization"))
@@ -66,15 +120,11 @@ func AuthMiddleware(JWTAccessTokenSecret string) gin.HandlerFunc {
 			return
 		}
 
-		// Check if token is revoked in memory
-		if isTokenRevoked(token) {
-			LogError(ctx, nil, "Token is revoked", http.StatusUnauthorized)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token is revoked"})
-			c.Abort()
+		if !a.checkNotRevoked(c, token) {
 			return
 		}
 
-		user, err := parseUserFromToken(token, JWTAccessTokenSecret, ctx)
+		user, err := parseUserFromToken(token, a.secrets, a.scope, ctx)
 		if err != nil {
 			LogError(ctx, err, "Failed to validate token", http.StatusUnauthorized)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
@@ -90,7 +140,7 @@ func AuthMiddleware(JWTAccessTokenSecret string) gin.HandlerFunc {
 }
 
 // SignoutMiddleware validates token format but allows expired tokens for signout
-func SignoutMiddleware(JWTAccessTokenSecret string) gin.HandlerFunc {
+func (a *Authenticator) SignoutMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		ctx := c.Request.Context()
 		token, err := extractToken(c.GetHeader("Authorization"))
@@ -102,20 +152,17 @@ func SignoutMiddleware(JWTAccessTokenSecret string) gin.HandlerFunc {
 		}
 
 		// For signout, we only check if the token format is valid
-		// We don't validate if it's expired or revoked, as users should be able to sign out
+		// We don't validate if it's expired, as users should be able to sign out
 		// even with expired tokens to clear their session
 
-		// Check if token is revoked in memory (if it's a valid token that was previously revoked)
-		if isTokenRevoked(token) {
-			LogError(ctx, nil, "Token is revoked", http.StatusUnauthorized)
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "token is revoked"})
-			c.Abort()
+		// Reject tokens that were previously revoked
+		if !a.checkNotRevoked(c, token) {
 			return
 		}
 
 		// Try to parse the token to get user info if possible, but don't fail if expired
 		// We'll use a more lenient parsing approach for signout
-		if user, err := parseUserFromTokenLenient(token, JWTAccessTokenSecret, ctx); err == nil && user != nil {
+		if user, err := parseUserFromTokenLenient(token, a.secrets, a.scope, ctx); err == nil && user != nil {
 			// Token is valid, add user to context
 			newCtx := context.WithValue(c.Request.Context(), ctxKeyUser{}, user)
 			c.Request = c.Request.WithContext(newCtx)
@@ -126,6 +173,42 @@ func SignoutMiddleware(JWTAccessTokenSecret string) gin.HandlerFunc {
 	}
 }
 
+// RevokeToken records the token as revoked until it expires
+func (a *Authenticator) RevokeToken(ctx context.Context, token string) error {
+	return a.store.Revoke(ctx, HashToken(token), tokenExpiry(token))
+}
+
+// checkNotRevoked aborts the request and returns false if the token is revoked or the
+// store can't be consulted
+func (a *Authenticator) checkNotRevoked(c *gin.Context, token string) bool {
+	ctx := c.Request.Context()
+	revoked, err := a.store.IsRevoked(ctx, HashToken(token))
+	if err != nil {
+		LogError(ctx, err, "Failed to check token revocation", http.StatusInternalServerError)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to validate token"})
+		c.Abort()
+		return false
+	}
+	if revoked {
+		LogError(ctx, nil, "Token is revoked", http.StatusUnauthorized)
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "token is revoked"})
+		c.Abort()
+		return false
+	}
+	return true
+}
+
+// tokenExpiry returns when the token expires, so its revocation can be dropped afterwards.
+// The signature isn't checked: an unparseable token is kept for defaultRevocationTTL.
+func tokenExpiry(tokenStr string) time.Time {
+	if claims, err := jwt.ParseUnverified(tokenStr); err == nil {
+		if expiresAt, err := claims.ExpiresAt(); err == nil {
+			return expiresAt
+		}
+	}
+	return time.Now().Add(defaultRevocationTTL)
+}
+
 // extractToken extracts Bearer token from Authorization header
 func extractToken(authHeader string) (string, error) {
 	if authHeader == "" {
@@ -138,54 +221,17 @@ func extractToken(authHeader string) (string, error) {
 }
 
 // parseUserFromToken extracts and validates user details from access token
-func parseUserFromToken(tokenStr string, secret string, ctx context.Context) (*User, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
-	if err != nil || !token.Valid {
+func parseUserFromToken(tokenStr string, secrets []string, scope TokenScope, ctx context.Context) (*User, error) {
+	// A token minted for another service is rejected even when its signature is valid
+	claims, err := jwt.Validate(tokenStr, scope, secrets...)
+	if err != nil {
+		LogError(ctx, err, "Invalid token", http.StatusUnauthorized)
 		return nil, errors.New("invalid token")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		LogError(ctx, nil, "Invalid token claims", http.StatusUnauthorized)
-		return nil, errors.New("invalid token claims")
-	}
-
-	if exp, ok := claims["exp"].(float64); !ok || int64(exp) < time.Now().Unix() {
-		LogError(ctx, nil, "Token expired or missing expiration", http.StatusUnauthorized)
-		return nil, errors.New("token expired or missing expiration")
-	}
-
-	user := &User{}
-	var errMsg string
-	if id, ok := claims["user_id"].(string); ok && id != "" {
-		uid, err := uuid.Parse(id)
-		if err != nil {
-			errMsg = "invalid user ID format in token"
-		} else {
-			user.ID = uid
-		}
-	} else {
-		errMsg = "missing or invalid user ID in token"
-	}
-
-	if errMsg != "" {
-		LogError(ctx, nil, errMsg, http.StatusUnauthorized)
-		return nil, errors.New(errMsg)
-	}
-
-	user.Name = claims["name"].(string)
-	if user.Name == "" {
-		user.Name = "Unknown User"
-	}
-
-	user.Email = claims["email"].(string)
-	if user.Email == "" {
-		user.Email = "Unknown Email"
+	user, err := userFromClaims(claims, ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	LogInfo(ctx, "User authenticated successfully", map[string]any{"user_id": user.ID})
@@ -193,75 +239,49 @@ func parseUserFromToken(tokenStr string, secret string, ctx context.Context) (*U
 }
 
 // parseUserFromTokenLenient is a more lenient version of parseUserFromToken that allows expired tokens.
-func parseUserFromTokenLenient(tokenStr string, secret string, ctx context.Context) (*User, error) {
-	token, err := jwt.Parse(tokenStr, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return []byte(secret), nil
-	})
+func parseUserFromTokenLenient(tokenStr string, secrets []string, scope TokenScope, ctx context.Context) (*User, error) {
+	claims, err := jwt.Parse(tokenStr, secrets...)
 	if err != nil {
 		return nil, errors.New("invalid token")
 	}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		LogError(ctx, nil, "Invalid token claims", http.StatusUnauthorized)
-		return nil, errors.New("invalid token claims")
+	// A token minted for another service is rejected even when its signature is valid
+	if err := scope.Verify(claims); err != nil {
+		LogError(ctx, err, "Token scope mismatch", http.StatusUnauthorized)
+		return nil, err
 	}
 
-	// Allow expired tokens for signout
-	// if exp, ok := claims["exp"].(float64); !ok || int64(exp) < time.Now().Unix() {
-	// 	LogError(ctx, nil, "Token expired or missing expiration", http.StatusUnauthorized)
-	// 	return nil, errors.New("token expired or missing expiration")
-	// }
-
-	user := &User{}
-	var errMsg string
-	if id, ok := claims["user_id"].(string); ok && id != "" {
-		uid, err := uuid.Parse(id)
-		if err != nil {
-			errMsg = "invalid user ID format in token"
-		} else {
-			user.ID = uid
-		}
-	} else {
-		errMsg = "missing or invalid user ID in token"
-	}
-
-	if errMsg != "" {
-		LogError(ctx, nil, errMsg, http.StatusUnauthorized)
-		return nil, errors.New(errMsg)
-	}
-
-	user.Name = claims["name"].(string)
-	if user.Name == "" {
-		user.Name = "Unknown User"
-	}
-
-	user.Email = claims["email"].(string)
-	if user.Email == "" {
-		user.Email = "Unknown Email"
+	user, err := userFromClaims(claims, ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	LogInfo(ctx, "User authenticated successfully (lenient)", map[string]any{"user_id": user.ID})
 	return user, nil
 }
 
-// isTokenRevoked checks if a token is revoked in memory
-func isTokenRevoked(token string) bool {
-	tokenHash := HashToken(token)
-	tokenMutex.RLock()
-	defer tokenMutex.RUnlock()
-	return revokedTokens[tokenHash]
-}
+// userFromClaims builds the user a token was issued to. Only the user ID is required; a
+// missing name or email is filled with a placeholder.
+func userFromClaims(claims jwt.Claims, ctx context.Context) (*User, error) {
+	id, err := claims.String("user_id")
+	if err != nil {
+		LogError(ctx, err, "missing or invalid user ID in token", http.StatusUnauthorized)
+		return nil, errors.New("missing or invalid user ID in token")
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		LogError(ctx, err, "invalid user ID format in token", http.StatusUnauthorized)
+		return nil, errors.New("invalid user ID format in token")
+	}
 
-// RevokeToken adds a token to the revoked tokens list
-func RevokeToken(token string) {
-	tokenHash := HashToken(token)
-	tokenMutex.Lock()
-	defer tokenMutex.Unlock()
-	revokedTokens[tokenHash] = true
+	user := &User{ID: uid, Name: "Unknown User", Email: "Unknown Email"}
+	if name, err := claims.String("name"); err == nil {
+		user.Name = name
+	}
+	if email, err := claims.String("email"); err == nil {
+		user.Email = email
+	}
+	return user, nil
 }
 
 // ctxKeyUser is the type used for storing user in context to avoid key collisions.
@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"packages/jwt"
+)
+
+const previousTestSecret = "previous-test-secret"
+
+// signTestToken signs a valid access token with secret, naming it in the kid header when kid is set
+func signTestToken(t *testing.T, secret string, kid bool) string {
+	t.Helper()
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"user_id": uuid.NewString(),
+		"name":    "Test User",
+		"email":   "test@example.com",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	})
+	if kid {
+		token.Header["kid"] = jwt.KeyID(secret)
+	}
+	signed, err := token.SignedString([]byte(secret))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthenticator_KeyRotation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator := NewAuthenticator(testSecret, NewMemoryTokenStore(), previousTestSecret)
+
+	router := gin.New()
+	router.GET("/me", authenticator.AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{name: "current secret with kid", token: signTestToken(t, testSecret, true), status: http.StatusOK},
+		{name: "current secret without kid", token: signTestToken(t, testSecret, false), status: http.StatusOK},
+		{name: "previous secret with kid", token: signTestToken(t, previousTestSecret, true), status: http.StatusOK},
+		{name: "previous secret without kid", token: signTestToken(t, previousTestSecret, false), status: http.StatusOK},
+		{name: "unknown secret", token: signTestToken(t, "unknown-secret", true), status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestAuthenticator_PreviousSecretDoesNotRescueExpiredToken(t *testing.T) {
+	token := newTestToken(t, time.Now().Add(-time.Minute))
+
+	_, err := jwt.Validate(token, TokenScope{}, previousTestSecret, testSecret)
+
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+}
+
+func TestAuthenticator_RejectsExpiredToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator := NewAuthenticator(testSecret, NewMemoryTokenStore())
+	token := newTestToken(t, time.Now().Add(-time.Minute))
+
+	router := gin.New()
+	router.GET("/me", authenticator.AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+	req := httptest.NewRequest(http.MethodGet, "/me", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+
+	// The expiry is rejected by the library's registered claims validation
+	_, err := jwt.Validate(token, TokenScope{}, testSecret)
+	assert.ErrorIs(t, err, gojwt.ErrTokenExpired)
+
+	// Signing out still accepts the expired token
+	user, err := parseUserFromTokenLenient(token, []string{testSecret}, TokenScope{}, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Test User", user.Name)
+}
+
+// signScopedTestToken signs a valid access token with testSecret carrying extra claims
+func signScopedTestToken(t *testing.T, extra gojwt.MapClaims) string {
+	t.Helper()
+	claims := gojwt.MapClaims{
+		"user_id": uuid.NewString(),
+		"name":    "Test User",
+		"email":   "test@example.com",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}
+	for key, value := range extra {
+		claims[key] = value
+	}
+	signed, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, claims).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestAuthenticator_TokenScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	authenticator := NewAuthenticator(testSecret, NewMemoryTokenStore()).
+		WithScope(TokenScope{Issuer: "auth-service", Audience: "chat-service"})
+
+	router := gin.New()
+	router.GET("/me", authenticator.AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	tests := []struct {
+		name   string
+		claims gojwt.MapClaims
+		status int
+	}{
+		{name: "matching", claims: gojwt.MapClaims{"iss": "auth-service", "aud": "chat-service"}, status: http.StatusOK},
+		{name: "audience in list", claims: gojwt.MapClaims{"iss": "auth-service", "aud": []string{"auth-service", "chat-service"}}, status: http.StatusOK},
+		{name: "wrong audience", claims: gojwt.MapClaims{"iss": "auth-service", "aud": "billing-service"}, status: http.StatusUnauthorized},
+		{name: "wrong issuer", claims: gojwt.MapClaims{"iss": "someone-else", "aud": "chat-service"}, status: http.StatusUnauthorized},
+		{name: "missing audience", claims: gojwt.MapClaims{"iss": "auth-service"}, status: http.StatusUnauthorized},
+		{name: "missing claims", status: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/me", nil)
+			req.Header.Set("Authorization", "Bearer "+signScopedTestToken(t, tt.claims))
+			rec := httptest.NewRecorder()
+			router.ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.status, rec.Code)
+		})
+	}
+}
+
+func TestParseUserFromTokenLenient_RejectsWrongAudience(t *testing.T) {
+	token := signScopedTestToken(t, gojwt.MapClaims{"aud": "billing-service"})
+
+	_, err := parseUserFromTokenLenient(token, []string{testSecret}, TokenScope{Audience: "chat-service"}, context.Background())
+	assert.Error(t, err)
+
+	user, err := parseUserFromTokenLenient(token, []string{testSecret}, TokenScope{Audience: "billing-service"}, context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "Test User", user.Name)
+}
+
+func TestNewAuthenticator_ReadsScopeFromEnv(t *testing.T) {
+	t.Setenv("JWT_ISSUER", "auth-service")
+	t.Setenv("JWT_AUDIENCE", "chat-service")
+
+	authenticator := NewAuthenticator(testSecret, nil)
+
+	assert.Equal(t, TokenScope{Issuer: "auth-service", Audience: "chat-service"}, authenticator.scope)
+}
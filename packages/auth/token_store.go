@@ -0,0 +1,122 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TokenStore records revoked tokens by their hash so they can be rejected until they expire.
+type TokenStore interface {
+	// Revoke marks the token hash as revoked until expiry.
+	Revoke(ctx context.Context, tokenHash string, expiry time.Time) error
+	// IsRevoked reports whether the token hash has been revoked and has not yet expired.
+	IsRevoked(ctx context.Context, tokenHash string) (bool, error)
+}
+
+// MemoryTokenStore is a process-local TokenStore. Revocations are lost on restart and
+// are not shared between replicas.
+type MemoryTokenStore struct {
+	mu     sync.RWMutex
+	tokens map[string]time.Time
+	now    func() time.Time
+}
+
+// NewMemoryTokenStore creates an empty in-memory token store
+func NewMemoryTokenStore() *MemoryTokenStore {
+	return &MemoryTokenStore{
+		tokens: make(map[string]time.Time),
+		now:    time.Now,
+	}
+}
+
+// Revoke marks the token hash as revoked until expiry
+func (s *MemoryTokenStore) Revoke(ctx context.Context, tokenHash string, expiry time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens[tokenHash] = expiry
+	return nil
+}
+
+// IsRevoked reports whether the token hash is revoked and not yet expired
+func (s *MemoryTokenStore) IsRevoked(ctx context.Context, tokenHash string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	expiry, ok := s.tokens[tokenHash]
+	if !ok {
+		return false, nil
+	}
+	return s.now().Before(expiry), nil
+}
+
+// Prune removes expired revocations and returns how many were removed
+func (s *MemoryTokenStore) Prune(ctx context.Context) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	var removed int64
+	for hash, expiry := range s.tokens {
+		if !now.Before(expiry) {
+			delete(s.tokens, hash)
+			removed++
+		}
+	}
+	return removed, nil
+}
+
+const (
+	revokeTokenQuery = `
+		INSERT INTO revoked_tokens (token_hash, expires_at)
+		VALUES ($1, $2)
+		ON CONFLICT (token_hash) DO UPDATE SET expires_at = EXCLUDED.expires_at`
+
+	isTokenRevokedQuery = `
+		SELECT EXISTS (
+			SELECT 1 FROM revoked_tokens
+			WHERE token_hash = $1 AND expires_at > NOW()
+		)`
+
+	pruneRevokedTokensQuery = `
+		DELETE FROM revoked_tokens
+		WHERE expires_at <= NOW()`
+)
+
+// PostgresTokenStore is a TokenStore backed by the revoked_tokens table, so revocations
+// survive restarts and are visible to every replica sharing the database.
+type PostgresTokenStore struct {
+	db *sql.DB
+}
+
+// NewPostgresTokenStore creates a token store using db. The caller owns db and its driver.
+func NewPostgresTokenStore(db *sql.DB) *PostgresTokenStore {
+	return &PostgresTokenStore{db: db}
+}
+
+// Revoke marks the token hash as revoked until expiry
+func (s *PostgresTokenStore) Revoke(ctx context.Context, tokenHash string, expiry time.Time) error {
+	if _, err := s.db.ExecContext(ctx, revokeTokenQuery, tokenHash, expiry); err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+// IsRevoked reports whether the token hash is revoked and not yet expired
+func (s *PostgresTokenStore) IsRevoked(ctx context.Context, tokenHash string) (bool, error) {
+	var revoked bool
+	if err := s.db.QueryRowContext(ctx, isTokenRevokedQuery, tokenHash).Scan(&revoked); err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return revoked, nil
+}
+
+// Prune deletes expired revocations and returns how many were removed. Run it
+// periodically to keep the table small.
+func (s *PostgresTokenStore) Prune(ctx context.Context) (int64, error) {
+	result, err := s.db.ExecContext(ctx, pruneRevokedTokensQuery)
+	if err != nil {
+		return 0, fmt.Errorf("failed to prune revoked tokens: %w", err)
+	}
+	return result.RowsAffected()
+}
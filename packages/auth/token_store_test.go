@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "test-secret"
+
+func newTestToken(t *testing.T, expiry time.Time) string {
+	t.Helper()
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"user_id": uuid.NewString(),
+		"name":    "Test User",
+		"email":   "test@example.com",
+		"exp":     expiry.Unix(),
+	})
+	signed, err := token.SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestMemoryTokenStore_RevokeAndCheck(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+
+	revoked, err := store.IsRevoked(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, store.Revoke(ctx, "hash-1", time.Now().Add(time.Hour)))
+
+	revoked, err = store.IsRevoked(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked(ctx, "hash-2")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestMemoryTokenStore_Expiry(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryTokenStore()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Revoke(ctx, "expired", now.Add(-time.Minute)))
+	require.NoError(t, store.Revoke(ctx, "active", now.Add(time.Minute)))
+
+	revoked, err := store.IsRevoked(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, revoked, "expired revocations should no longer apply")
+
+	removed, err := store.Prune(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), removed)
+	assert.NotContains(t, store.tokens, "expired")
+	assert.Contains(t, store.tokens, "active")
+
+	revoked, err = store.IsRevoked(ctx, "active")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func TestPostgresTokenStore_Queries(t *testing.T) {
+	assert.Contains(t, revokeTokenQuery, "INSERT INTO revoked_tokens")
+	assert.Contains(t, revokeTokenQuery, "ON CONFLICT (token_hash)")
+	assert.Contains(t, isTokenRevokedQuery, "expires_at > NOW()")
+	assert.Contains(t, pruneRevokedTokensQuery, "DELETE FROM revoked_tokens")
+	assert.Contains(t, pruneRevokedTokensQuery, "expires_at <= NOW()")
+}
+
+func TestAuthenticator_RevokedTokenIsRejected(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := NewMemoryTokenStore()
+	authenticator := NewAuthenticator(testSecret, store)
+
+	router := gin.New()
+	router.GET("/me", authenticator.AuthMiddleware(), func(c *gin.Context) {
+		c.Status(http.StatusOK)
+	})
+
+	expiry := time.Now().Add(time.Hour)
+	token := newTestToken(t, expiry)
+	request := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request())
+
+	require.NoError(t, authenticator.RevokeToken(ctx, token))
+	assert.Equal(t, http.StatusUnauthorized, request())
+
+	// The revocation is kept only as long as the token itself is valid
+	assert.Equal(t, expiry.Unix(), store.tokens[HashToken(token)].Unix())
+}
@@ -0,0 +1,171 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+
+	zlog "packages/logger"
+
+	"github.com/pressly/goose"
+)
+
+var (
+	// ErrSchemaDrift is returned when the database records a migration that is not in the
+	// migrations directory, so the schema no longer matches what the code expects
+	ErrSchemaDrift = errors.New("database schema is ahead of the migrations")
+	// ErrDirtySchema is returned when an earlier migration failed part way. The schema has to
+	// be repaired by hand and the dirty flag in schema_migrations cleared before the service
+	// starts again.
+	ErrDirtySchema = errors.New("database schema is dirty")
+)
+
+const (
+	createSchemaMigrationsQuery = `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT NOT NULL,
+			dirty BOOLEAN NOT NULL
+		)`
+
+	selectSchemaMigrationQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+
+	clearSchemaMigrationsQuery = `DELETE FROM schema_migrations`
+
+	insertSchemaMigrationQuery = `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`
+)
+
+// MigrationConfig says where the migrations live and whether to apply them at startup
+type MigrationConfig struct {
+	Dir string
+	// Enabled applies pending migrations at startup; turn it off when the schema is managed
+	// outside the service
+	Enabled bool
+}
+
+// RunMigrations applies the pending migrations from cfg.Dir. goose records the applied
+// versions, so migrations already run are skipped. schema_migrations holds the version being
+// applied and is marked dirty until that migration succeeds, so a failed migration stops
+// every later start with ErrDirtySchema. It does nothing when cfg.Enabled is off.
+func RunMigrations(ctx context.Context, db *sql.DB, cfg MigrationConfig, logger *zlog.Logger) error {
+	if !cfg.Enabled {
+		logger.Info(ctx, "Migrations disabled, skipping")
+		return nil
+	}
+
+	if _, err := os.Stat(cfg.Dir); os.IsNotExist(err) {
+		logger.Error(ctx, err, "Migrations directory not found", http.StatusInternalServerError, map[string]any{"path": cfg.Dir})
+		return fmt.Errorf("migrations directory does not exist: %w", err)
+	}
+
+	logger.Info(ctx, "Applying migrations", map[string]any{"migrations_dir": cfg.Dir})
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		logger.Error(ctx, err, "Failed to set goose dialect", http.StatusInternalServerError)
+		return fmt.Errorf("failed to set goose dialect: %w", err)
+	}
+
+	if err := checkDirty(ctx, db); err != nil {
+		logger.Error(ctx, err, "Database schema is in an unexpected state", http.StatusInternalServerError)
+		return err
+	}
+
+	pending, err := pendingMigrations(db, cfg.Dir)
+	if err != nil {
+		logger.Error(ctx, err, "Database schema is in an unexpected state", http.StatusInternalServerError)
+		return err
+	}
+
+	for _, migration := range pending {
+		if err := setSchemaVersion(ctx, db, migration.Version, true); err != nil {
+			return err
+		}
+		if err := migration.Up(db); err != nil {
+			logger.Error(ctx, err, "Failed to apply migration, schema left dirty", http.StatusInternalServerError, map[string]any{"version": migration.Version})
+			return fmt.Errorf("failed to apply migration %d: %w", migration.Version, err)
+		}
+		if err := setSchemaVersion(ctx, db, migration.Version, false); err != nil {
+			return err
+		}
+	}
+
+	logger.Info(ctx, "Migrations applied successfully", map[string]any{"applied": len(pending)})
+	return nil
+}
+
+// checkDirty fails with ErrDirtySchema when schema_migrations records a migration that did
+// not finish
+func checkDirty(ctx context.Context, db *sql.DB) error {
+	if _, err := db.ExecContext(ctx, createSchemaMigrationsQuery); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var (
+		version int64
+		dirty   bool
+	)
+	err := db.QueryRowContext(ctx, selectSchemaMigrationQuery).Scan(&version, &dirty)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return nil
+	case err != nil:
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	case dirty:
+		return fmt.Errorf("%w: migration %d did not finish", ErrDirtySchema, version)
+	}
+	return nil
+}
+
+// pendingMigrations returns the migrations newer than the database's version. It fails with
+// ErrSchemaDrift when the database is at a version newer than the newest migration, which
+// happens when a newer release migrated it and was rolled back.
+func pendingMigrations(db *sql.DB, dir string) (goose.Migrations, error) {
+	current, err := goose.GetDBVersion(db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	migrations, err := goose.CollectMigrations(dir, 0, math.MaxInt64)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect migrations: %w", err)
+	}
+
+	var latest int64
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	if current > latest {
+		return nil, fmt.Errorf("%w: database is at version %d, newest migration is %d", ErrSchemaDrift, current, latest)
+	}
+
+	var pending goose.Migrations
+	for _, migration := range migrations {
+		if migration.Version > current {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// setSchemaVersion replaces the schema_migrations row with version and its dirty flag
+func setSchemaVersion(ctx context.Context, db *sql.DB, version int64, dirty bool) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin schema_migrations update: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, clearSchemaMigrationsQuery); err != nil {
+		return fmt.Errorf("failed to clear schema_migrations: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, insertSchemaMigrationQuery, version, dirty); err != nil {
+		return fmt.Errorf("failed to record schema version %d: %w", version, err)
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit schema_migrations update: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	zlog "packages/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMigrationsTest returns a mocked connection and a config applying one sample migration
+func newMigrationsTest(t *testing.T) (*sql.DB, sqlmock.Sqlmock, MigrationConfig) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	dir := t.TempDir()
+	migration := "-- +goose Up\nCREATE TABLE widgets (id INT);\n\n-- +goose Down\nDROP TABLE widgets;\n"
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "0001_widgets.sql"), []byte(migration), 0o644))
+
+	return db, mock, MigrationConfig{Dir: dir, Enabled: true}
+}
+
+// gooseVersions is the goose version table holding the given applied versions, newest first
+func gooseVersions(versions ...int64) *sqlmock.Rows {
+	rows := sqlmock.NewRows([]string{"version_id", "is_applied"})
+	for _, v := range versions {
+		rows.AddRow(v, true)
+	}
+	return rows
+}
+
+// expectSchemaMigration expects the schema_migrations table to be read, holding rows
+func expectSchemaMigration(mock sqlmock.Sqlmock, rows *sqlmock.Rows) {
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_migrations").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery("SELECT version, dirty FROM schema_migrations").WillReturnRows(rows)
+}
+
+// expectSetSchemaVersion expects the schema_migrations row to be replaced
+func expectSetSchemaVersion(mock sqlmock.Sqlmock, version int64, dirty bool) {
+	mock.ExpectBegin()
+	mock.ExpectExec("DELETE FROM schema_migrations").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec("INSERT INTO schema_migrations").WithArgs(version, dirty).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+}
+
+// noSchemaMigration is an empty schema_migrations table
+func noSchemaMigration() *sqlmock.Rows {
+	return sqlmock.NewRows([]string{"version", "dirty"})
+}
+
+func TestRunMigrations_AppliesPendingOnce(t *testing.T) {
+	db, mock, cfg := newMigrationsTest(t)
+	logger := zlog.New(zlog.Config{Level: "error"})
+
+	// First start: the version table is created and the migration applied
+	expectSchemaMigration(mock, noSchemaMigration())
+	mock.ExpectQuery("SELECT version_id, is_applied from goose_db_version").WillReturnError(errors.New("relation does not exist"))
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE goose_db_version").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO goose_db_version").WithArgs(0, true).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectSetSchemaVersion(mock, 1, true)
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE widgets").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("INSERT INTO goose_db_version").WithArgs(1, true).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	expectSetSchemaVersion(mock, 1, false)
+
+	require.NoError(t, RunMigrations(context.Background(), db, cfg, logger))
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// Second start: nothing is pending, so no statement runs
+	expectSchemaMigration(mock, noSchemaMigration().AddRow(1, false))
+	mock.ExpectQuery("SELECT version_id, is_applied from goose_db_version").WillReturnRows(gooseVersions(1, 0))
+
+	require.NoError(t, RunMigrations(context.Background(), db, cfg, logger))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunMigrations_FailureLeavesSchemaDirty(t *testing.T) {
+	db, mock, cfg := newMigrationsTest(t)
+	logger := zlog.New(zlog.Config{Level: "error"})
+
+	expectSchemaMigration(mock, noSchemaMigration())
+	mock.ExpectQuery("SELECT version_id, is_applied from goose_db_version").WillReturnRows(gooseVersions(0))
+	expectSetSchemaVersion(mock, 1, true)
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE widgets").WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+
+	err := RunMigrations(context.Background(), db, cfg, logger)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to apply migration 1")
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	// The next start finds the dirty marker and refuses to touch the schema
+	expectSchemaMigration(mock, noSchemaMigration().AddRow(1, true))
+
+	err = RunMigrations(context.Background(), db, cfg, logger)
+	assert.ErrorIs(t, err, ErrDirtySchema)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunMigrations_FailsWhenSchemaIsAhead(t *testing.T) {
+	db, mock, cfg := newMigrationsTest(t)
+
+	expectSchemaMigration(mock, noSchemaMigration().AddRow(2, false))
+	mock.ExpectQuery("SELECT version_id, is_applied from goose_db_version").WillReturnRows(gooseVersions(2, 1, 0))
+
+	err := RunMigrations(context.Background(), db, cfg, zlog.New(zlog.Config{Level: "error"}))
+	assert.ErrorIs(t, err, ErrSchemaDrift)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRunMigrations_DisabledIsNoOp(t *testing.T) {
+	db, mock, cfg := newMigrationsTest(t)
+	cfg.Enabled = false
+
+	require.NoError(t, RunMigrations(context.Background(), db, cfg, zlog.New(zlog.Config{Level: "error"})))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,156 @@
+package grpcutil
+
+import (
+	"compress/gzip"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// CompressResponses gzips response bodies of at least minBytes for clients that send
+// Accept-Encoding: gzip. Server-sent events, responses flushed before reaching minBytes
+// and WebSocket upgrades are passed through unchanged.
+func CompressResponses(next http.Handler, minBytes int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Upgrade") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Add("Vary", "Accept-Encoding")
+		if !acceptsGzip(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, minBytes: minBytes, status: http.StatusOK}
+		defer gw.close()
+		next.ServeHTTP(gw, r)
+	})
+}
+
+// acceptsGzip reports whether the request's Accept-Encoding allows a gzip response
+func acceptsGzip(r *http.Request) bool {
+	for _, value := range r.Header.Values("Accept-Encoding") {
+		for _, coding := range strings.Split(value, ",") {
+			name, params, _ := strings.Cut(coding, ";")
+			if !strings.EqualFold(strings.TrimSpace(name), "gzip") {
+				continue
+			}
+			q, weighted := strings.CutPrefix(strings.TrimSpace(params), "q=")
+			if !weighted {
+				return true
+			}
+			weight, err := strconv.ParseFloat(q, 64)
+			return err == nil && weight > 0
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter buffers the start of a response until it is known whether it is worth
+// compressing, then either gzips the rest or writes it through as is
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	minBytes int
+	status   int
+	buf      []byte
+	gz       *gzip.Writer
+	// passthrough is set once the response is known to be sent uncompressed
+	passthrough bool
+	wroteHeader bool
+}
+
+func (w *gzipResponseWriter) WriteHeader(code int) {
+	if w.wroteHeader || w.gz != nil || w.passthrough {
+		return
+	}
+	w.status = code
+	w.wroteHeader = true
+	if !w.compressible() {
+		w.startPassthrough()
+	}
+}
+
+func (w *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	if w.passthrough {
+		return w.ResponseWriter.Write(b)
+	}
+	if w.gz != nil {
+		return w.gz.Write(b)
+	}
+
+	w.buf = append(w.buf, b...)
+	if len(w.buf) >= w.minBytes {
+		if err := w.startGzip(); err != nil {
+			return 0, err
+		}
+	}
+	return len(b), nil
+}
+
+// Flush sends buffered output to the client. A response flushed before it reaches minBytes
+// is a stream and is sent uncompressed.
+func (w *gzipResponseWriter) Flush() {
+	if w.gz != nil {
+		w.gz.Flush()
+	} else if !w.passthrough {
+		w.startPassthrough()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer
+func (w *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// compressible reports whether the response headers allow gzipping the body
+func (w *gzipResponseWriter) compressible() bool {
+	if w.status < http.StatusOK || w.status == http.StatusNoContent || w.status == http.StatusNotModified {
+		return false
+	}
+	header := w.Header()
+	if header.Get("Content-Encoding") != "" {
+		return false
+	}
+	return !strings.HasPrefix(header.Get("Content-Type"), "text/event-stream")
+}
+
+// startPassthrough sends the headers and anything buffered so far uncompressed
+func (w *gzipResponseWriter) startPassthrough() {
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if len(w.buf) > 0 {
+		w.ResponseWriter.Write(w.buf)
+		w.buf = nil
+	}
+}
+
+// startGzip switches the response to gzip and compresses anything buffered so far
+func (w *gzipResponseWriter) startGzip() error {
+	header := w.Header()
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+	w.ResponseWriter.WriteHeader(w.status)
+
+	w.gz = gzip.NewWriter(w.ResponseWriter)
+	_, err := w.gz.Write(w.buf)
+	w.buf = nil
+	return err
+}
+
+// close finishes the response: a body below minBytes is sent uncompressed
+func (w *gzipResponseWriter) close() {
+	switch {
+	case w.gz != nil:
+		w.gz.Close()
+	case !w.passthrough:
+		w.startPassthrough()
+	}
+}
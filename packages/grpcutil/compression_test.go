@@ -0,0 +1,105 @@
+package grpcutil
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usersHandler serves a JSON list of count users, like ListUsers through the gateway
+func usersHandler(count int) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		users := make([]map[string]string, count)
+		for i := range users {
+			users[i] = map[string]string{
+				"id":       fmt.Sprintf("user-%d", i),
+				"username": fmt.Sprintf("user%d", i),
+				"email":    fmt.Sprintf("user%d@example.com", i),
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"users": users})
+	})
+}
+
+// getUsers requests the users list with the given Accept-Encoding
+func getUsers(t *testing.T, handler http.Handler, acceptEncoding string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+	req.Header.Set("Accept-Encoding", acceptEncoding)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+// decodeUserCount decodes a users list and returns how many users it holds
+func decodeUserCount(t *testing.T, r io.Reader) int {
+	t.Helper()
+	var body struct {
+		Users []map[string]string `json:"users"`
+	}
+	require.NoError(t, json.NewDecoder(r).Decode(&body))
+	return len(body.Users)
+}
+
+func TestCompressResponses_GzipsLargeJSONWhenAccepted(t *testing.T) {
+	handler := CompressResponses(usersHandler(100), 1024)
+
+	rec := getUsers(t, handler, "gzip")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", rec.Header().Get("Vary"))
+
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	assert.Equal(t, 100, decodeUserCount(t, reader))
+}
+
+func TestCompressResponses_LeavesResponsePlain(t *testing.T) {
+	tests := []struct {
+		name           string
+		users          int
+		acceptEncoding string
+	}{
+		{name: "gzip not accepted", users: 100, acceptEncoding: "identity"},
+		{name: "gzip refused", users: 100, acceptEncoding: "gzip;q=0"},
+		{name: "below threshold", users: 1, acceptEncoding: "gzip"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := getUsers(t, CompressResponses(usersHandler(tt.users), 1024), tt.acceptEncoding)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Empty(t, rec.Header().Get("Content-Encoding"))
+			assert.Equal(t, tt.users, decodeUserCount(t, rec.Body))
+		})
+	}
+}
+
+func TestCompressResponses_SkipsServerSentEvents(t *testing.T) {
+	event := "data: " + strings.Repeat("x", 2048) + "\n\n"
+	handler := CompressResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(event))
+		w.(http.Flusher).Flush()
+	}), 1024)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/chat/ai/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.True(t, rec.Flushed)
+	assert.Equal(t, event, rec.Body.String())
+}
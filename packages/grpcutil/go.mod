@@ -0,0 +1,26 @@
+module grpcutil
+
+go 1.24.6
+
+require (
+	github.com/stretchr/testify v1.11.1
+	golang.org/x/net v0.43.0
+	google.golang.org/grpc v1.75.0
+	google.golang.org/protobuf v1.36.8
+	packages/logger v0.0.0
+)
+
+require (
+	github.com/davecgh/go-spew v1.1.1 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/mattn/go-colorable v0.1.13 // indirect
+	github.com/mattn/go-isatty v0.0.20 // indirect
+	github.com/pmezard/go-difflib v1.0.0 // indirect
+	github.com/rs/zerolog v1.34.0 // indirect
+	golang.org/x/sys v0.35.0 // indirect
+	golang.org/x/text v0.28.0 // indirect
+	google.golang.org/genproto/googleapis/rpc v0.0.0-20250825161204-c5933d9347a5 // indirect
+	gopkg.in/yaml.v3 v3.0.1 // indirect
+)
+
+replace packages/logger => ../logger
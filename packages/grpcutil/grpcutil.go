@@ -0,0 +1,161 @@
+package grpcutil
+
+import (
+	"encoding/json"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
+)
+
+// Config holds the client connection settings shared by the services
+type Config struct {
+	// Credentials secures the connection; nil dials without TLS
+	Credentials credentials.TransportCredentials
+
+	// Keepalive pings
+	KeepaliveTime       time.Duration // idle time before pinging the server
+	KeepaliveTimeout    time.Duration // time to wait for a ping ack before closing
+	PermitWithoutStream bool          // ping even without active calls
+
+	// Reconnect backoff
+	BackoffBaseDelay  time.Duration
+	BackoffMaxDelay   time.Duration
+	MinConnectTimeout time.Duration
+
+	// MaxRetryAttempts is how many times a call failing with UNAVAILABLE is attempted in
+	// total; 1 or less disables retries. Only enable it for connections whose methods are
+	// safe to repeat.
+	MaxRetryAttempts int
+
+	// MaxRecvMsgSize caps response size in bytes (0 keeps the gRPC default of 4 MiB)
+	MaxRecvMsgSize int
+
+	// LoadBalancingPolicy chooses which of the addresses the target resolves to serves each
+	// call, such as "round_robin" or "pick_first"; empty uses DefaultLoadBalancingPolicy
+	LoadBalancingPolicy string
+}
+
+// DefaultLoadBalancingPolicy balances calls over every resolved backend
+const DefaultLoadBalancingPolicy = "round_robin"
+
+// DefaultConfig returns insecure dial settings with the keepalive and backoff values used
+// between the services
+func DefaultConfig() Config {
+	return Config{
+		KeepaliveTime:     60 * time.Second,
+		KeepaliveTimeout:  10 * time.Second,
+		BackoffBaseDelay:  time.Second,
+		BackoffMaxDelay:   30 * time.Second,
+		MinConnectTimeout: 10 * time.Second,
+	}
+}
+
+// DialOptions returns the dial options for cfg. Callers add their own stats handlers and
+// interceptors.
+func DialOptions(cfg Config) []grpc.DialOption {
+	backoffConfig := backoff.DefaultConfig
+	backoffConfig.BaseDelay = cfg.BackoffBaseDelay
+	backoffConfig.MaxDelay = cfg.BackoffMaxDelay
+
+	options := []grpc.DialOption{
+		grpc.WithTransportCredentials(TransportCredentials(cfg)),
+		grpc.WithKeepaliveParams(KeepaliveParams(cfg)),
+		grpc.WithConnectParams(grpc.ConnectParams{
+			Backoff:           backoffConfig,
+			MinConnectTimeout: cfg.MinConnectTimeout,
+		}),
+		grpc.WithDefaultServiceConfig(ServiceConfig(cfg)),
+	}
+	if cfg.MaxRetryAttempts <= 1 {
+		options = append(options, grpc.WithDisableRetry())
+	}
+	if cfg.MaxRecvMsgSize > 0 {
+		options = append(options, grpc.WithDefaultCallOptions(grpc.MaxCallRecvMsgSize(cfg.MaxRecvMsgSize)))
+	}
+	return options
+}
+
+// TransportCredentials returns cfg.Credentials, or insecure credentials when none are set
+func TransportCredentials(cfg Config) credentials.TransportCredentials {
+	if cfg.Credentials == nil {
+		return insecure.NewCredentials()
+	}
+	return cfg.Credentials
+}
+
+// KeepaliveParams returns the client keepalive parameters for cfg
+func KeepaliveParams(cfg Config) keepalive.ClientParameters {
+	return keepalive.ClientParameters{
+		Time:                cfg.KeepaliveTime,
+		Timeout:             cfg.KeepaliveTimeout,
+		PermitWithoutStream: cfg.PermitWithoutStream,
+	}
+}
+
+// DefaultKeepaliveMinTime is the shortest client ping interval servers allow by default.
+// It is half the KeepaliveTime of DefaultConfig, so the services' own clients are never
+// refused.
+const DefaultKeepaliveMinTime = 30 * time.Second
+
+// KeepaliveEnforcementPolicy returns the server option limiting how often clients may
+// ping. A client pinging more often than every minTime, or without active calls when
+// permitWithoutStream is false, is sent GOAWAY (too_many_pings) and disconnected; other
+// connections are unaffected. A minTime below one second uses DefaultKeepaliveMinTime.
+func KeepaliveEnforcementPolicy(minTime time.Duration, permitWithoutStream bool) grpc.ServerOption {
+	if minTime < time.Second {
+		minTime = DefaultKeepaliveMinTime
+	}
+	return grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+		MinTime:             minTime,
+		PermitWithoutStream: permitWithoutStream,
+	})
+}
+
+// serviceConfig is the subset of the gRPC service config set by ServiceConfig
+type serviceConfig struct {
+	LoadBalancingPolicy string         `json:"loadBalancingPolicy"`
+	MethodConfig        []methodConfig `json:"methodConfig,omitempty"`
+}
+
+type methodConfig struct {
+	Name        []struct{}   `json:"name"`
+	RetryPolicy *retryPolicy `json:"retryPolicy"`
+}
+
+type retryPolicy struct {
+	MaxAttempts          int      `json:"maxAttempts"`
+	InitialBackoff       string   `json:"initialBackoff"`
+	MaxBackoff           string   `json:"maxBackoff"`
+	BackoffMultiplier    float64  `json:"backoffMultiplier"`
+	RetryableStatusCodes []string `json:"retryableStatusCodes"`
+}
+
+// ServiceConfig returns the default service config JSON for cfg: its load balancing policy
+// (round robin unless set) and, when MaxRetryAttempts is above 1, a retry policy for
+// UNAVAILABLE applied to every method
+func ServiceConfig(cfg Config) string {
+	sc := serviceConfig{LoadBalancingPolicy: cfg.LoadBalancingPolicy}
+	if sc.LoadBalancingPolicy == "" {
+		sc.LoadBalancingPolicy = DefaultLoadBalancingPolicy
+	}
+	if cfg.MaxRetryAttempts > 1 {
+		sc.MethodConfig = []methodConfig{{
+			Name: []struct{}{{}},
+			RetryPolicy: &retryPolicy{
+				MaxAttempts:          cfg.MaxRetryAttempts,
+				InitialBackoff:       "0.1s",
+				MaxBackoff:           "1s",
+				BackoffMultiplier:    2,
+				RetryableStatusCodes: []string{"UNAVAILABLE"},
+			},
+		}}
+	}
+
+	// Marshalling plain structs of strings and numbers cannot fail
+	data, _ := json.Marshal(sc)
+	return string(data)
+}
@@ -0,0 +1,127 @@
+package grpcutil
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/net/http2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
+)
+
+func TestTransportCredentials(t *testing.T) {
+	assert.Equal(t, "insecure", TransportCredentials(DefaultConfig()).Info().SecurityProtocol)
+
+	cfg := DefaultConfig()
+	cfg.Credentials = credentials.NewTLS(&tls.Config{MinVersion: tls.VersionTLS12})
+	assert.Equal(t, "tls", TransportCredentials(cfg).Info().SecurityProtocol)
+}
+
+func TestKeepaliveParams(t *testing.T) {
+	cfg := DefaultConfig()
+	assert.Equal(t, keepalive.ClientParameters{Time: 60 * time.Second, Timeout: 10 * time.Second}, KeepaliveParams(cfg))
+
+	cfg.KeepaliveTime = 30 * time.Second
+	cfg.KeepaliveTimeout = 5 * time.Second
+	cfg.PermitWithoutStream = true
+	assert.Equal(t, keepalive.ClientParameters{Time: 30 * time.Second, Timeout: 5 * time.Second, PermitWithoutStream: true}, KeepaliveParams(cfg))
+}
+
+func TestServiceConfig(t *testing.T) {
+	var sc map[string]any
+	require.NoError(t, json.Unmarshal([]byte(ServiceConfig(DefaultConfig())), &sc))
+	assert.Equal(t, map[string]any{"loadBalancingPolicy": "round_robin"}, sc)
+
+	cfg := DefaultConfig()
+	cfg.MaxRetryAttempts = 3
+	require.NoError(t, json.Unmarshal([]byte(ServiceConfig(cfg)), &sc))
+	methods := sc["methodConfig"].([]any)
+	require.Len(t, methods, 1)
+	policy := methods[0].(map[string]any)["retryPolicy"].(map[string]any)
+	assert.Equal(t, float64(3), policy["maxAttempts"])
+	assert.Equal(t, []any{"UNAVAILABLE"}, policy["retryableStatusCodes"])
+
+	cfg = DefaultConfig()
+	cfg.LoadBalancingPolicy = "pick_first"
+	require.NoError(t, json.Unmarshal([]byte(ServiceConfig(cfg)), &sc))
+	assert.Equal(t, "pick_first", sc["loadBalancingPolicy"])
+}
+
+func TestDialOptions_ConnectsToServer(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	for name, retries := range map[string]int{"without retries": 0, "with retries": 3} {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultConfig()
+			cfg.MaxRetryAttempts = retries
+			cfg.MaxRecvMsgSize = 1 << 20
+
+			conn, err := grpc.NewClient(lis.Addr().String(), DialOptions(cfg)...)
+			require.NoError(t, err)
+			defer conn.Close()
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+			require.NoError(t, err)
+			assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+		})
+	}
+}
+
+func TestKeepaliveEnforcementPolicy_DisconnectsClientPingingTooOften(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(KeepaliveEnforcementPolicy(time.Minute, true))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	// gRPC clients never ping more often than every 10 seconds, so speak HTTP/2 directly
+	abusive, err := net.Dial("tcp", lis.Addr().String())
+	require.NoError(t, err)
+	defer abusive.Close()
+	require.NoError(t, abusive.SetDeadline(time.Now().Add(5*time.Second)))
+	_, err = abusive.Write([]byte(http2.ClientPreface))
+	require.NoError(t, err)
+	framer := http2.NewFramer(abusive, abusive)
+	require.NoError(t, framer.WriteSettings())
+	// The first ping is free; after three more within MinTime the server gives up
+	for i := range 4 {
+		require.NoError(t, framer.WritePing(false, [8]byte{byte(i)}))
+	}
+
+	var goAway *http2.GoAwayFrame
+	for goAway == nil {
+		frame, err := framer.ReadFrame()
+		require.NoError(t, err)
+		goAway, _ = frame.(*http2.GoAwayFrame)
+	}
+	assert.Equal(t, http2.ErrCodeEnhanceYourCalm, goAway.ErrCode)
+	assert.Equal(t, "too_many_pings", string(goAway.DebugData()))
+
+	// Clients keeping to the policy are still served
+	conn, err := grpc.NewClient(lis.Addr().String(), DialOptions(DefaultConfig())...)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
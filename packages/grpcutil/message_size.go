@@ -0,0 +1,41 @@
+package grpcutil
+
+import (
+	"context"
+
+	zlog "packages/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// MessageSizeServerOptions bounds the messages the server sends to maxBytes. The transport
+// accepts requests up to twice maxBytes, so moderately oversized requests still reach
+// UnaryMessageSizeInterceptor and get a descriptive error; anything larger is dropped by
+// gRPC before it is decoded.
+func MessageSizeServerOptions(maxBytes int) []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(2 * maxBytes),
+		grpc.MaxSendMsgSize(maxBytes),
+	}
+}
+
+// UnaryMessageSizeInterceptor rejects requests larger than maxBytes with InvalidArgument,
+// naming the actual and allowed sizes
+func UnaryMessageSizeInterceptor(maxBytes int, logger *zlog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if msg, ok := req.(proto.Message); ok {
+			if size := proto.Size(msg); size > maxBytes {
+				logger.Warn(ctx, "Request message too large", map[string]any{
+					"method":    info.FullMethod,
+					"size":      size,
+					"max_bytes": maxBytes,
+				})
+				return nil, status.Errorf(codes.InvalidArgument, "request message is %d bytes, larger than the %d byte limit", size, maxBytes)
+			}
+		}
+		return handler(ctx, req)
+	}
+}
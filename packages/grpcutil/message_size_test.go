@@ -0,0 +1,93 @@
+package grpcutil
+
+import (
+	"context"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestUnaryMessageSizeInterceptor(t *testing.T) {
+	const maxBytes = 256
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	interceptor := UnaryMessageSizeInterceptor(maxBytes, logger)
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Echo"}
+
+	called := false
+	handler := func(ctx context.Context, req any) (any, error) {
+		called = true
+		return "ok", nil
+	}
+
+	t.Run("within limit", func(t *testing.T) {
+		called = false
+		resp, err := interceptor(context.Background(), wrapperspb.String("hello"), info, handler)
+
+		require.NoError(t, err)
+		assert.Equal(t, "ok", resp)
+		assert.True(t, called)
+	})
+
+	t.Run("oversized request", func(t *testing.T) {
+		called = false
+		_, err := interceptor(context.Background(), wrapperspb.String(strings.Repeat("a", maxBytes)), info, handler)
+
+		st, ok := status.FromError(err)
+		require.True(t, ok)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, "request message is 259 bytes, larger than the 256 byte limit", st.Message())
+		assert.False(t, called, "oversized requests must not reach the handler")
+	})
+}
+
+func TestMessageSizeServerOptions(t *testing.T) {
+	const maxBytes = 1024
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	options := append(MessageSizeServerOptions(maxBytes), grpc.UnaryInterceptor(UnaryMessageSizeInterceptor(maxBytes, logger)))
+	server := grpc.NewServer(options...)
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(lis)
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	client := healthpb.NewHealthClient(conn)
+
+	t.Run("within limit", func(t *testing.T) {
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{})
+		require.NoError(t, err)
+	})
+
+	t.Run("just beyond limit", func(t *testing.T) {
+		// Decoded by the transport and rejected with the sizes by the interceptor
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: strings.Repeat("a", maxBytes)})
+
+		st := status.Convert(err)
+		assert.Equal(t, codes.InvalidArgument, st.Code())
+		assert.Equal(t, "request message is 1027 bytes, larger than the 1024 byte limit", st.Message())
+	})
+
+	t.Run("far beyond limit", func(t *testing.T) {
+		// The transport drops these before they are decoded
+		_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: strings.Repeat("a", 4*maxBytes)})
+
+		assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	})
+}
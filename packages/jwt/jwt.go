@@ -0,0 +1,227 @@
+// Package jwt signs, parses and validates the HMAC-signed JWTs issued by auth-service, so
+// every service checks tokens the same way.
+package jwt
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	// ErrInvalidToken is returned for a token that is malformed or uses an unexpected
+	// signing method
+	ErrInvalidToken = errors.New("invalid token")
+	// ErrSignatureInvalid is returned when no secret matches the token's signature
+	ErrSignatureInvalid = errors.New("token signature is invalid")
+	// ErrTokenExpired is returned for a token past its expiry or not valid yet
+	ErrTokenExpired = errors.New("token expired")
+	// ErrMissingClaim is returned when a required claim is absent or has the wrong type
+	ErrMissingClaim = errors.New("missing or invalid claim")
+	// ErrTokenScope is returned when a token's issuer or audience isn't the expected one
+	ErrTokenScope = errors.New("token issuer or audience mismatch")
+)
+
+// Claims are the claims of a token, as decoded from its JSON payload
+type Claims map[string]any
+
+// String returns the named claim, failing with ErrMissingClaim when it is absent, empty or
+// not a string
+func (c Claims) String(name string) (string, error) {
+	value, ok := c[name].(string)
+	if !ok || value == "" {
+		return "", fmt.Errorf("%w: %s", ErrMissingClaim, name)
+	}
+	return value, nil
+}
+
+// ExpiresAt returns the time in the "exp" claim, failing with ErrMissingClaim when it is
+// absent or not a number
+func (c Claims) ExpiresAt() (time.Time, error) {
+	exp, err := gojwt.MapClaims(c).GetExpirationTime()
+	if err != nil || exp == nil {
+		return time.Time{}, fmt.Errorf("%w: exp", ErrMissingClaim)
+	}
+	return exp.Time, nil
+}
+
+// Scope is the issuer ("iss") and audience ("aud") tokens are signed with and validated
+// against, so a token minted for one service isn't accepted by another. An empty field is
+// neither set on signed tokens nor checked.
+type Scope struct {
+	Issuer   string
+	Audience string
+}
+
+// Apply sets the issuer and audience claims of the scope
+func (s Scope) Apply(claims Claims) Claims {
+	if s.Issuer != "" {
+		claims["iss"] = s.Issuer
+	}
+	if s.Audience != "" {
+		claims["aud"] = s.Audience
+	}
+	return claims
+}
+
+// Verify checks that claims carry the issuer and audience of the scope. A token without
+// the claim is rejected like one with another value.
+func (s Scope) Verify(claims Claims) error {
+	if s.Issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != s.Issuer {
+			return fmt.Errorf("%w: unexpected issuer %q", ErrTokenScope, iss)
+		}
+	}
+	if s.Audience != "" && !hasAudience(claims["aud"], s.Audience) {
+		return fmt.Errorf("%w: token is not intended for %q", ErrTokenScope, s.Audience)
+	}
+	return nil
+}
+
+// hasAudience reports whether aud, a single audience or a list of them, includes audience
+func hasAudience(aud any, audience string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == audience
+	case []any:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	case []string:
+		for _, a := range aud {
+			if a == audience {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// KeyID identifies a signing secret in the "kid" header of the tokens it signs. It is a
+// truncated hash, so the secret itself is never exposed.
+func KeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:8])
+}
+
+// Sign signs claims with secret using HS256 and records which secret was used in the kid
+// header
+func Sign(claims Claims, secret string) (string, error) {
+	token := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims(claims))
+	token.Header["kid"] = KeyID(secret)
+	return token.SignedString([]byte(secret))
+}
+
+// signatureParser checks signatures only, so expired tokens can still be read
+var signatureParser = gojwt.NewParser(gojwt.WithoutClaimsValidation())
+
+// claimsParser also checks the registered time claims, requiring an expiry
+var claimsParser = gojwt.NewParser(gojwt.WithExpirationRequired())
+
+// Parse verifies the signature of a token against secrets and returns its claims, without
+// checking its expiry or scope. The secret named by the kid header is tried first; tokens
+// without one are tried against each secret in turn, so retired secrets keep verifying
+// the tokens they signed.
+func Parse(tokenString string, secrets ...string) (Claims, error) {
+	return parse(signatureParser, tokenString, secrets)
+}
+
+// Validate parses a token like Parse and checks that it carries an expiry that has not
+// passed, is already valid and belongs to scope
+func Validate(tokenString string, scope Scope, secrets ...string) (Claims, error) {
+	claims, err := parse(claimsParser, tokenString, secrets)
+	if err != nil {
+		return nil, err
+	}
+	if err := scope.Verify(claims); err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// ParseUnverified returns the claims of a token without checking its signature. Only use
+// it for claims that are not trusted, such as the expiry of a token being revoked.
+func ParseUnverified(tokenString string) (Claims, error) {
+	claims := gojwt.MapClaims{}
+	if _, _, err := signatureParser.ParseUnverified(tokenString, claims); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+	return Claims(claims), nil
+}
+
+// parse verifies a token with parser against secrets, trying the secret named by the kid
+// header first
+func parse(parser *gojwt.Parser, tokenString string, secrets []string) (Claims, error) {
+	if len(secrets) == 0 {
+		return nil, fmt.Errorf("%w: no secret to verify it with", ErrInvalidToken)
+	}
+
+	ordered := append([]string(nil), secrets...)
+	if kid := unverifiedKeyID(tokenString); kid != "" {
+		for i, secret := range ordered {
+			if KeyID(secret) == kid {
+				ordered[0], ordered[i] = ordered[i], ordered[0]
+				break
+			}
+		}
+	}
+
+	var firstErr error
+	for i, secret := range ordered {
+		claims, err := parseWithSecret(parser, tokenString, secret)
+		if err == nil {
+			return claims, nil
+		}
+		if i == 0 {
+			firstErr = err
+		}
+
+		// Only a signature mismatch can be fixed by another secret; the signature is
+		// checked before the claims, so an expired token is only reported as such once
+		// the right secret is found
+		if !errors.Is(err, ErrSignatureInvalid) {
+			return nil, err
+		}
+	}
+	return nil, firstErr
+}
+
+// parseWithSecret verifies a token with parser against a single secret. Errors wrap both
+// the package's error and the library's.
+func parseWithSecret(parser *gojwt.Parser, tokenString, secret string) (Claims, error) {
+	claims := gojwt.MapClaims{}
+	_, err := parser.ParseWithClaims(tokenString, claims, func(token *gojwt.Token) (any, error) {
+		if _, ok := token.Method.(*gojwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	switch {
+	case err == nil:
+		return Claims(claims), nil
+	case errors.Is(err, gojwt.ErrTokenSignatureInvalid):
+		return nil, fmt.Errorf("%w: %w", ErrSignatureInvalid, err)
+	case errors.Is(err, gojwt.ErrTokenRequiredClaimMissing), errors.Is(err, gojwt.ErrInvalidType):
+		return nil, fmt.Errorf("%w: %w", ErrMissingClaim, err)
+	case errors.Is(err, gojwt.ErrTokenExpired), errors.Is(err, gojwt.ErrTokenNotValidYet):
+		return nil, fmt.Errorf("%w: %w", ErrTokenExpired, err)
+	default:
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+}
+
+// unverifiedKeyID reads the kid header without checking the signature
+func unverifiedKeyID(tokenString string) string {
+	token, _, err := signatureParser.ParseUnverified(tokenString, gojwt.MapClaims{})
+	if err != nil {
+		return ""
+	}
+	kid, _ := token.Header["kid"].(string)
+	return kid
+}
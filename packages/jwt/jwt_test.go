@@ -0,0 +1,183 @@
+package jwt
+
+import (
+	"testing"
+	"time"
+
+	gojwt "github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testSecret         = "test-secret"
+	previousTestSecret = "previous-test-secret"
+)
+
+// signTestToken signs claims with secret, adding a user ID and an expiry an hour away
+// unless claims sets them
+func signTestToken(t *testing.T, secret string, claims Claims) string {
+	t.Helper()
+	full := Claims{"user_id": "user-1", "exp": time.Now().Add(time.Hour).Unix()}
+	for name, value := range claims {
+		full[name] = value
+	}
+	token, err := Sign(full, secret)
+	require.NoError(t, err)
+	return token
+}
+
+func TestValidate_ValidToken(t *testing.T) {
+	token := signTestToken(t, testSecret, Claims{"type": "access"})
+
+	claims, err := Validate(token, Scope{}, testSecret)
+
+	require.NoError(t, err)
+	userID, err := claims.String("user_id")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", userID)
+	tokenType, err := claims.String("type")
+	require.NoError(t, err)
+	assert.Equal(t, "access", tokenType)
+}
+
+func TestValidate_ExpiredToken(t *testing.T) {
+	token := signTestToken(t, testSecret, Claims{"exp": time.Now().Add(-time.Minute).Unix()})
+
+	// The expiry is checked by the library's registered claims validation
+	_, err := Validate(token, Scope{}, testSecret)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+	assert.ErrorIs(t, err, gojwt.ErrTokenExpired)
+
+	// Parse only checks the signature, so an expired token can still be read
+	claims, err := Parse(token, testSecret)
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims["user_id"])
+}
+
+func TestValidate_NotValidYet(t *testing.T) {
+	token := signTestToken(t, testSecret, Claims{"nbf": time.Now().Add(time.Hour).Unix()})
+
+	_, err := Validate(token, Scope{}, testSecret)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestValidate_WrongSignature(t *testing.T) {
+	token := signTestToken(t, "another-secret", nil)
+
+	_, err := Validate(token, Scope{}, testSecret, previousTestSecret)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+
+	_, err = Parse(token, testSecret)
+	assert.ErrorIs(t, err, ErrSignatureInvalid)
+}
+
+func TestValidate_MissingClaim(t *testing.T) {
+	token := signTestToken(t, testSecret, Claims{"exp": nil})
+
+	_, err := Validate(token, Scope{}, testSecret)
+	assert.ErrorIs(t, err, ErrMissingClaim)
+
+	// A token without any expiry is rejected by the library as well
+	noExpiry, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{"user_id": "user-1"}).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	_, err = Validate(noExpiry, Scope{}, testSecret)
+	assert.ErrorIs(t, err, ErrMissingClaim)
+	assert.ErrorIs(t, err, gojwt.ErrTokenRequiredClaimMissing)
+
+	claims, err := Parse(token, testSecret)
+	require.NoError(t, err)
+	_, err = claims.String("email")
+	assert.ErrorIs(t, err, ErrMissingClaim)
+	_, err = Claims{"email": 42}.String("email")
+	assert.ErrorIs(t, err, ErrMissingClaim)
+}
+
+func TestValidate_UnexpectedSigningMethod(t *testing.T) {
+	token, err := gojwt.NewWithClaims(gojwt.SigningMethodNone, gojwt.MapClaims{
+		"user_id": "user-1",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString(gojwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+
+	_, err = Validate(token, Scope{}, testSecret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestValidate_MalformedToken(t *testing.T) {
+	_, err := Validate("not-a-token", Scope{}, testSecret)
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
+
+func TestValidate_KeyRotation(t *testing.T) {
+	// Tokens issued before the kid header was added carry none
+	legacy, err := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"user_id": "user-1",
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString([]byte(previousTestSecret))
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		token string
+	}{
+		{name: "current secret", token: signTestToken(t, testSecret, nil)},
+		{name: "previous secret with kid", token: signTestToken(t, previousTestSecret, nil)},
+		{name: "previous secret without kid", token: legacy},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(tt.token, Scope{}, testSecret, previousTestSecret)
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestValidate_PreviousSecretDoesNotRescueExpiredToken(t *testing.T) {
+	token := signTestToken(t, testSecret, Claims{"exp": time.Now().Add(-time.Minute).Unix()})
+
+	_, err := Validate(token, Scope{}, previousTestSecret, testSecret)
+	assert.ErrorIs(t, err, ErrTokenExpired)
+}
+
+func TestValidate_Scope(t *testing.T) {
+	scope := Scope{Issuer: "auth-service", Audience: "chat-service"}
+
+	tests := []struct {
+		name    string
+		claims  Claims
+		wantErr bool
+	}{
+		{name: "matching", claims: scope.Apply(Claims{})},
+		{name: "audience in list", claims: Claims{"iss": "auth-service", "aud": []string{"auth-service", "chat-service"}}},
+		{name: "wrong audience", claims: Claims{"iss": "auth-service", "aud": "billing-service"}, wantErr: true},
+		{name: "wrong issuer", claims: Claims{"iss": "someone-else", "aud": "chat-service"}, wantErr: true},
+		{name: "missing audience", claims: Claims{"iss": "auth-service"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Validate(signTestToken(t, testSecret, tt.claims), scope, testSecret)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrTokenScope)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestParseUnverified(t *testing.T) {
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	token := signTestToken(t, "unknown-secret", Claims{"exp": expiry.Unix()})
+
+	claims, err := ParseUnverified(token)
+	require.NoError(t, err)
+	expiresAt, err := claims.ExpiresAt()
+	require.NoError(t, err)
+	assert.True(t, expiry.Equal(expiresAt))
+
+	_, err = ParseUnverified("not-a-token")
+	assert.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -2,6 +2,7 @@ package logger
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"os"
@@ -29,12 +30,22 @@ type Config struct {
 	TimeFormat string    // Timestamp format
 	Service    string    // Service name for structured logging
 	Version    string    // Service version for structured logging
+
+	// SampleRate keeps one in every SampleRate INFO and DEBUG messages; 0 or 1 keeps them
+	// all. WARN and more severe messages are never sampled.
+	SampleRate int
+	// SampleBurst lets this many INFO and DEBUG messages per second through before
+	// SampleRate applies. Only used when SampleRate is above 1.
+	SampleBurst int
 }
 
 // Logger wraps zerolog.Logger with additional functionality
 type Logger struct {
 	*zerolog.Logger
 	config Config
+
+	// mu guards the embedded Logger, which SetLevel replaces while other goroutines log
+	mu sync.RWMutex
 }
 
 // singleton instance
@@ -48,76 +59,109 @@ type ctxKey string
 
 const correlationIDCtxKey ctxKey = "correlation_id"
 
-// NewLogger initializes and returns the singleton logger
+// NewLogger initializes and returns the process-wide singleton logger.
+// Only the first call's config takes effect; later calls return the same instance
+// regardless of their config. Use New to build an independent logger.
 func NewLogger(config Config) *Logger {
 	once.Do(func() {
-		// Set defaults
-		if config.Output == nil {
-			config.Output = os.Stdout
-		}
-		if config.TimeFormat == "" {
-			config.TimeFormat = DefaultTimeFormat
-		}
-		if config.Level == "" {
-			config.Level = DefaultLevel
-		}
+		instance = New(config)
+	})
 
-		// Configure zerolog
-		zerolog.TimeFieldFormat = config.TimeFormat
-
-		var logger zerolog.Logger
-		if config.JSONFormat {
-			logger = zerolog.New(config.Output).With().Timestamp().Logger()
-		} else {
-			logger = zerolog.New(zerolog.ConsoleWriter{
-				Out:        config.Output,
-				TimeFormat: config.TimeFormat,
-				FormatLevel: func(i any) string {
-					if ll, ok := i.(string); ok {
-						switch ll {
-						case "debug":
-							return "\x1b[36mDBG\x1b[0m"
-						case "info":
-							return "\x1b[32mINF\x1b[0m"
-						case "warn":
-							return "\x1b[33mWRN\x1b[0m"
-						case "error":
-							return "\x1b[31mERR\x1b[0m"
-						case "fatal":
-							return "\x1b[35mFTL\x1b[0m"
-						case "panic":
-							return "\x1b[35mPNC\x1b[0m"
-						default:
-							return ll
-						}
+	return instance
+}
+
+// New builds a new logger from config. Unlike NewLogger, every call returns a
+// fresh instance, so services can each use their own level and format.
+func New(config Config) *Logger {
+	// Set defaults
+	if config.Output == nil {
+		config.Output = os.Stdout
+	}
+	if config.TimeFormat == "" {
+		config.TimeFormat = DefaultTimeFormat
+	}
+	if config.Level == "" {
+		config.Level = DefaultLevel
+	}
+
+	// Configure zerolog
+	zerolog.TimeFieldFormat = config.TimeFormat
+
+	var logger zerolog.Logger
+	if config.JSONFormat {
+		logger = zerolog.New(config.Output).With().Timestamp().Logger()
+	} else {
+		logger = zerolog.New(zerolog.ConsoleWriter{
+			Out:        config.Output,
+			TimeFormat: config.TimeFormat,
+			FormatLevel: func(i any) string {
+				if ll, ok := i.(string); ok {
+					switch ll {
+					case "debug":
+						return "\x1b[36mDBG\x1b[0m"
+					case "info":
+						return "\x1b[32mINF\x1b[0m"
+					case "warn":
+						return "\x1b[33mWRN\x1b[0m"
+					case "error":
+						return "\x1b[31mERR\x1b[0m"
+					case "fatal":
+						return "\x1b[35mFTL\x1b[0m"
+					case "panic":
+						return "\x1b[35mPNC\x1b[0m"
+					default:
+						return ll
 					}
-					return "???"
-				},
-			}).With().Timestamp().Logger()
-		}
+				}
+				return "???"
+			},
+		}).With().Timestamp().Logger()
+	}
 
-		// Set log level
-		level, err := zerolog.ParseLevel(config.Level)
-		if err != nil {
-			level = zerolog.InfoLevel
-		}
-		logger = logger.Level(level)
+	// Set log level
+	level, err := zerolog.ParseLevel(config.Level)
+	if err != nil {
+		level = zerolog.InfoLevel
+	}
+	logger = logger.Level(level)
 
-		// Add service context if provided
-		if config.Service != "" {
-			logger = logger.With().Str("service", config.Service).Logger()
-		}
-		if config.Version != "" {
-			logger = logger.With().Str("version", config.Version).Logger()
-		}
+	if sampler := newSampler(config.SampleRate, config.SampleBurst); sampler != nil {
+		logger = logger.Sample(sampler)
+	}
 
-		instance = &Logger{
-			Logger: &logger,
-			config: config,
-		}
-	})
+	// Add service context if provided
+	if config.Service != "" {
+		logger = logger.With().Str("service", config.Service).Logger()
+	}
+	if config.Version != "" {
+		logger = logger.With().Str("version", config.Version).Logger()
+	}
 
-	return instance
+	return &Logger{
+		Logger: &logger,
+		config: config,
+	}
+}
+
+// newSampler returns a sampler that thins out INFO and DEBUG messages, or nil when rate
+// keeps every message
+func newSampler(rate, burst int) zerolog.Sampler {
+	if rate <= 1 {
+		return nil
+	}
+
+	var sampler zerolog.Sampler = &zerolog.BasicSampler{N: uint32(rate)}
+	if burst > 0 {
+		sampler = &zerolog.BurstSampler{
+			Burst:       uint32(burst),
+			Period:      time.Second,
+			NextSampler: sampler,
+		}
+	}
+	return zerolog.LevelSampler{
+		DebugSampler: sampler,
+		InfoSampler:  sampler,
+	}
 }
 
 // WithCorrelationID adds a correlation ID to the context
@@ -128,8 +172,8 @@ func WithCorrelationID(ctx context.Context, correlationID string) context.Contex
 	return context.WithValue(ctx, correlationIDCtxKey, correlationID)
 }
 
-// getCorrelationID retrieves correlation ID from context
-func getCorrelationID(ctx context.Context) string {
+// CorrelationID returns the correlation ID stored in ctx, or "" if there is none
+func CorrelationID(ctx context.Context) string {
 	if ctx == nil {
 		return ""
 	}
@@ -141,10 +185,10 @@ func getCorrelationID(ctx context.Context) string {
 
 // Info logs an info message with optional fields
 func (l *Logger) Info(ctx context.Context, message string, fields ...map[string]any) {
-	event := l.Logger.Info()
+	event := l.base().Info()
 
 	// Add correlation ID if available
-	if correlationID := getCorrelationID(ctx); correlationID != "" {
+	if correlationID := CorrelationID(ctx); correlationID != "" {
 		event = event.Str(CorrelationIDKey, correlationID)
 	}
 
@@ -165,12 +209,39 @@ func (l *Logger) Info(ctx context.Context, message string, fields ...map[string]
 	event.Msg(message)
 }
 
+// AppError is implemented by application errors that carry a classification. Error
+// logs the type and code of any AppError found in the error chain as structured fields.
+type AppError interface {
+	error
+	ErrorType() string
+	ErrorCode() string
+}
+
+// rootCause follows the errors.Unwrap chain of err to its innermost error. It returns nil
+// when err wraps nothing.
+func rootCause(err error) error {
+	var cause error
+	for next := errors.Unwrap(err); next != nil; next = errors.Unwrap(next) {
+		cause = next
+	}
+	return cause
+}
+
 // Error logs an error message with optional fields and status code
 func (l *Logger) Error(ctx context.Context, err error, message string, statusCode int, fields ...map[string]any) {
-	event := l.Logger.Error().Err(err)
+	event := l.base().Error().Err(err)
+
+	// Surface the classification of application errors and the root cause of wrapped ones
+	var appErr AppError
+	if errors.As(err, &appErr) {
+		event = event.Str("error_type", appErr.ErrorType()).Str("error_code", appErr.ErrorCode())
+	}
+	if cause := rootCause(err); cause != nil {
+		event = event.Str("cause", cause.Error())
+	}
 
 	// Add correlation ID if available
-	if correlationID := getCorrelationID(ctx); correlationID != "" {
+	if correlationID := CorrelationID(ctx); correlationID != "" {
 		event = event.Str(CorrelationIDKey, correlationID)
 	}
 
@@ -198,10 +269,10 @@ func (l *Logger) Error(ctx context.Context, err error, message string, statusCod
 
 // Debug logs a debug message with optional fields
 func (l *Logger) Debug(ctx context.Context, message string, fields ...map[string]any) {
-	event := l.Logger.Debug()
+	event := l.base().Debug()
 
 	// Add correlation ID if available
-	if correlationID := getCorrelationID(ctx); correlationID != "" {
+	if correlationID := CorrelationID(ctx); correlationID != "" {
 		event = event.Str(CorrelationIDKey, correlationID)
 	}
 
@@ -224,10 +295,10 @@ func (l *Logger) Debug(ctx context.Context, message string, fields ...map[string
 
 // Warn logs a warning message with optional fields
 func (l *Logger) Warn(ctx context.Context, message string, fields ...map[string]any) {
-	event := l.Logger.Warn()
+	event := l.base().Warn()
 
 	// Add correlation ID if available
-	if correlationID := getCorrelationID(ctx); correlationID != "" {
+	if correlationID := CorrelationID(ctx); correlationID != "" {
 		event = event.Str(CorrelationIDKey, correlationID)
 	}
 
@@ -250,10 +321,10 @@ func (l *Logger) Warn(ctx context.Context, message string, fields ...map[string]
 
 // Fatal logs a fatal message and exits the program
 func (l *Logger) Fatal(ctx context.Context, err error, message string, fields ...map[string]any) {
-	event := l.Logger.Fatal().Err(err)
+	event := l.base().Fatal().Err(err)
 
 	// Add correlation ID if available
-	if correlationID := getCorrelationID(ctx); correlationID != "" {
+	if correlationID := CorrelationID(ctx); correlationID != "" {
 		event = event.Str(CorrelationIDKey, correlationID)
 	}
 
@@ -276,7 +347,7 @@ func (l *Logger) Fatal(ctx context.Context, err error, message string, fields ..
 
 // WithFields creates a new logger with additional fields
 func (l *Logger) WithFields(fields map[string]any) *Logger {
-	newLogger := l.Logger.With()
+	newLogger := l.base().With()
 	for key, value := range fields {
 		newLogger = newLogger.Interface(key, value)
 	}
@@ -291,6 +362,8 @@ func (l *Logger) WithFields(fields map[string]any) *Logger {
 // SetLevel changes the log level dynamically
 func (l *Logger) SetLevel(level string) {
 	if parsedLevel, err := zerolog.ParseLevel(level); err == nil {
+		l.mu.Lock()
+		defer l.mu.Unlock()
 		newLogger := l.Logger.Level(parsedLevel)
 		l.Logger = &newLogger
 	}
@@ -298,5 +371,12 @@ func (l *Logger) SetLevel(level string) {
 
 // GetLevel returns the current log level
 func (l *Logger) GetLevel() string {
-	return l.Logger.GetLevel().String()
+	return l.base().GetLevel().String()
+}
+
+// base returns the current zerolog logger, safe against concurrent SetLevel calls
+func (l *Logger) base() *zerolog.Logger {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.Logger
 }
@@ -0,0 +1,192 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestNew_ReturnsIndependentLoggers(t *testing.T) {
+	var debugOut, warnOut bytes.Buffer
+	debugLogger := New(Config{Level: "debug", Output: &debugOut, JSONFormat: true})
+	warnLogger := New(Config{Level: "warn", Output: &warnOut, JSONFormat: true})
+
+	if debugLogger == warnLogger {
+		t.Fatal("expected New to return distinct instances")
+	}
+
+	ctx := context.Background()
+	debugLogger.Debug(ctx, "debug message")
+	warnLogger.Debug(ctx, "debug message")
+
+	if !strings.Contains(debugOut.String(), "debug message") {
+		t.Errorf("debug logger dropped a debug message: %q", debugOut.String())
+	}
+	if warnOut.Len() != 0 {
+		t.Errorf("warn logger wrote a debug message: %q", warnOut.String())
+	}
+
+	if got := debugLogger.GetLevel(); got != "debug" {
+		t.Errorf("debug logger level = %q, want %q", got, "debug")
+	}
+	if got := warnLogger.GetLevel(); got != "warn" {
+		t.Errorf("warn logger level = %q, want %q", got, "warn")
+	}
+
+	warnLogger.SetLevel("error")
+	if got := debugLogger.GetLevel(); got != "debug" {
+		t.Errorf("SetLevel on one logger changed another: level = %q", got)
+	}
+}
+
+func TestSetLevel_ConcurrentWithLogging(t *testing.T) {
+	var out bytes.Buffer
+	var outMu sync.Mutex
+	l := New(Config{Level: "info", Output: &lockedWriter{w: &out, mu: &outMu}, JSONFormat: true})
+
+	ctx := context.Background()
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				l.Info(ctx, "message")
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				if j%2 == 0 {
+					l.SetLevel("debug")
+				} else {
+					l.SetLevel("info")
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// lockedWriter serialises writes so the test only exercises the logger's own locking
+type lockedWriter struct {
+	w  *bytes.Buffer
+	mu *sync.Mutex
+}
+
+func (w *lockedWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.w.Write(p)
+}
+
+func TestCorrelationID(t *testing.T) {
+	ctx := WithCorrelationID(context.Background(), "req-123")
+	if got := CorrelationID(ctx); got != "req-123" {
+		t.Errorf("CorrelationID = %q, want %q", got, "req-123")
+	}
+
+	if got := CorrelationID(WithCorrelationID(context.Background(), "")); got == "" {
+		t.Error("expected a generated correlation ID")
+	}
+
+	if got := CorrelationID(context.Background()); got != "" {
+		t.Errorf("CorrelationID without one set = %q, want empty", got)
+	}
+}
+
+func TestNew_SamplesInfoButNotErrors(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(Config{Level: "debug", Output: &out, JSONFormat: true, SampleRate: 10})
+
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		logger.Info(ctx, "sampled info")
+		logger.Error(ctx, errors.New("boom"), "unsampled error", 500)
+	}
+
+	if got := strings.Count(out.String(), "sampled info"); got != 10 {
+		t.Errorf("emitted %d of 100 info messages, want 10", got)
+	}
+	if got := strings.Count(out.String(), "unsampled error"); got != 100 {
+		t.Errorf("emitted %d of 100 error messages, want all of them", got)
+	}
+}
+
+func TestNew_SampleBurstPassesFirstMessages(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(Config{Level: "debug", Output: &out, JSONFormat: true, SampleRate: 1000, SampleBurst: 5})
+
+	for i := 0; i < 50; i++ {
+		logger.Debug(context.Background(), "burst debug")
+	}
+
+	// The burst of 5 passes, then the 1 in 1000 sampler keeps only the first of the rest
+	if got := strings.Count(out.String(), "burst debug"); got != 6 {
+		t.Errorf("emitted %d of 50 debug messages, want 6", got)
+	}
+}
+
+// testAppError is a classified error like the services' AppError types
+type testAppError struct {
+	errType string
+	code    string
+	err     error
+}
+
+func (e *testAppError) Error() string     { return e.code + ": " + e.err.Error() }
+func (e *testAppError) Unwrap() error     { return e.err }
+func (e *testAppError) ErrorType() string { return e.errType }
+func (e *testAppError) ErrorCode() string { return e.code }
+
+// decodeLine parses the single JSON log line written to out
+func decodeLine(t *testing.T, out *bytes.Buffer) map[string]any {
+	t.Helper()
+	var entry map[string]any
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to decode log line %q: %v", out.String(), err)
+	}
+	return entry
+}
+
+func TestError_LogsAppErrorFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(Config{Level: "debug", Output: &out, JSONFormat: true})
+
+	root := errors.New("connection refused")
+	appErr := &testAppError{errType: "database", code: "DB_UNAVAILABLE", err: root}
+	logger.Error(context.Background(), fmt.Errorf("load user: %w", appErr), "Failed to load user", 500)
+
+	entry := decodeLine(t, &out)
+	if got := entry["error_type"]; got != "database" {
+		t.Errorf("error_type = %v, want %q", got, "database")
+	}
+	if got := entry["error_code"]; got != "DB_UNAVAILABLE" {
+		t.Errorf("error_code = %v, want %q", got, "DB_UNAVAILABLE")
+	}
+	if got := entry["cause"]; got != "connection refused" {
+		t.Errorf("cause = %v, want %q", got, "connection refused")
+	}
+	if got := entry["error"]; got != "load user: DB_UNAVAILABLE: connection refused" {
+		t.Errorf("error = %v, want the full message", got)
+	}
+}
+
+func TestError_PlainErrorHasNoExtraFields(t *testing.T) {
+	var out bytes.Buffer
+	logger := New(Config{Level: "debug", Output: &out, JSONFormat: true})
+
+	logger.Error(context.Background(), errors.New("boom"), "Something failed", 500)
+
+	entry := decodeLine(t, &out)
+	for _, field := range []string{"error_type", "error_code", "cause"} {
+		if _, ok := entry[field]; ok {
+			t.Errorf("unexpected %s field in %v", field, entry)
+		}
+	}
+}
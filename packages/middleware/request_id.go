@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	zlog "packages/logger"
+
+	"google.golang.org/grpc/metadata"
+)
+
+const (
+	// RequestIDHeader carries the request ID in both directions
+	RequestIDHeader = "X-Request-ID"
+	// RequestIDMetadata is the metadata key the REST gateways forward the request ID in
+	RequestIDMetadata = "x-request-id"
+
+	// MaxRequestIDLength bounds client supplied request IDs
+	MaxRequestIDLength = 128
+)
+
+// ValidRequestID accepts short IDs made of letters, digits, '-', '_' and '.', so a
+// client can't inject arbitrary text into logs and headers
+func ValidRequestID(id string) bool {
+	if id == "" || len(id) > MaxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// IncomingRequestID returns the first well-formed ID found under keys in the incoming gRPC
+// metadata, or "" when there is none
+func IncomingRequestID(ctx context.Context, keys ...string) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	for _, key := range keys {
+		if values := md.Get(key); len(values) > 0 && ValidRequestID(values[0]) {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// GatewayRequestIDMetadata forwards the request ID stored as the context's correlation ID
+// to the gRPC server, so a request logs under one ID on both sides of the gateway
+func GatewayRequestIDMetadata(ctx context.Context, r *http.Request) metadata.MD {
+	requestID := zlog.CorrelationID(ctx)
+	if requestID == "" {
+		return nil
+	}
+	return metadata.Pairs(RequestIDMetadata, requestID)
+}
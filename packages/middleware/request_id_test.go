@@ -0,0 +1,68 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestValidRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{name: "plain id", id: "support-ticket-42", want: true},
+		{name: "uuid", id: "0f8fad5b-d9cb-469f-a165-70867728950e", want: true},
+		{name: "dots and underscores", id: "svc.req_1", want: true},
+		{name: "empty", id: ""},
+		{name: "line break", id: "bad id\r\nx"},
+		{name: "non ascii", id: "réq"},
+		{name: "at the limit", id: strings.Repeat("a", MaxRequestIDLength), want: true},
+		{name: "too long", id: strings.Repeat("a", MaxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, ValidRequestID(tt.id))
+		})
+	}
+}
+
+func TestIncomingRequestID(t *testing.T) {
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{name: "first key wins", md: metadata.Pairs("x-request-id", "req-1", "x-correlation-id", "corr-1"), want: "req-1"},
+		{name: "falls back to later key", md: metadata.Pairs("x-correlation-id", "corr-1"), want: "corr-1"},
+		{name: "malformed id skipped", md: metadata.Pairs("x-request-id", "bad\nid", "x-correlation-id", "corr-1"), want: "corr-1"},
+		{name: "nothing usable", md: metadata.Pairs("x-request-id", strings.Repeat("a", MaxRequestIDLength+1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+			assert.Equal(t, tt.want, IncomingRequestID(ctx, "x-request-id", "x-correlation-id"))
+		})
+	}
+
+	assert.Empty(t, IncomingRequestID(context.Background(), "x-request-id"))
+}
+
+func TestGatewayRequestIDMetadata(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/v1/users", nil)
+
+	md := GatewayRequestIDMetadata(zlog.WithCorrelationID(context.Background(), "req-42"), req)
+	assert.Equal(t, []string{"req-42"}, md.Get(RequestIDMetadata))
+
+	assert.Empty(t, GatewayRequestIDMetadata(context.Background(), req))
+}
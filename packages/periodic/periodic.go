@@ -0,0 +1,67 @@
+package periodic
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Job runs a task right away and then once per interval in the background, until Stop is
+// called or the context it was started with is done
+type Job struct {
+	interval time.Duration
+	timeout  time.Duration
+	run      func(ctx context.Context)
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// New creates a job that calls run every interval, each call bounded by timeout
+func New(interval, timeout time.Duration, run func(ctx context.Context)) *Job {
+	return &Job{
+		interval: interval,
+		timeout:  timeout,
+		run:      run,
+		stop:     make(chan struct{}),
+	}
+}
+
+// Start runs the task right away and then once per interval in the background
+func (j *Job) Start(ctx context.Context) {
+	j.wg.Add(1)
+	go func() {
+		defer j.wg.Done()
+
+		ticker := time.NewTicker(j.interval)
+		defer ticker.Stop()
+
+		for {
+			j.runOnce(ctx)
+
+			select {
+			case <-j.stop:
+				return
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop stops the background job and waits for a running pass to finish. It is safe to call
+// more than once.
+func (j *Job) Stop() {
+	j.stopOnce.Do(func() { close(j.stop) })
+	j.wg.Wait()
+}
+
+// runOnce runs a single pass of the task within the job's timeout
+func (j *Job) runOnce(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, j.timeout)
+	defer cancel()
+
+	j.run(ctx)
+}
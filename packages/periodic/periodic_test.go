@@ -0,0 +1,72 @@
+package periodic
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJob_RunsImmediatelyAndStops(t *testing.T) {
+	var runs atomic.Int32
+	job := New(time.Hour, time.Minute, func(ctx context.Context) { runs.Add(1) })
+
+	job.Start(context.Background())
+	require.Eventually(t, func() bool { return runs.Load() == 1 }, time.Second, 5*time.Millisecond)
+
+	job.Stop()
+	job.Stop() // stopping twice is harmless
+	assert.Equal(t, int32(1), runs.Load())
+}
+
+func TestJob_RunsEveryInterval(t *testing.T) {
+	var runs atomic.Int32
+	job := New(10*time.Millisecond, time.Minute, func(ctx context.Context) { runs.Add(1) })
+
+	job.Start(context.Background())
+	defer job.Stop()
+
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, 5*time.Millisecond)
+}
+
+func TestJob_BoundsEachPassByTimeout(t *testing.T) {
+	deadlines := make(chan time.Duration, 1)
+	job := New(time.Hour, 50*time.Millisecond, func(ctx context.Context) {
+		deadline, ok := ctx.Deadline()
+		require.True(t, ok)
+		deadlines <- time.Until(deadline)
+	})
+
+	job.Start(context.Background())
+	defer job.Stop()
+
+	select {
+	case remaining := <-deadlines:
+		assert.LessOrEqual(t, remaining, 50*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("job did not run")
+	}
+}
+
+func TestJob_StopsWhenContextIsDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	job := New(time.Hour, time.Minute, func(ctx context.Context) {})
+
+	job.Start(ctx)
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		job.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("job kept running after its context was cancelled")
+	}
+}
@@ -0,0 +1,63 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	zlog "packages/logger"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// CorrelationIDKey is the span attribute carrying the request correlation ID
+const CorrelationIDKey = attribute.Key("correlation_id")
+
+// Setup configures tracing for the process. The W3C trace context propagator is always
+// installed so incoming trace headers are passed on to downstream calls. Spans are only
+// exported when endpoint is set; otherwise the global no-op tracer provider stays in place.
+// The returned function flushes pending spans and must be called on shutdown.
+func Setup(ctx context.Context, endpoint, serviceName string) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx, otlptracegrpc.WithEndpointURL(endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP trace exporter: %w", err)
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(semconv.ServiceName(serviceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace resource: %w", err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// SetCorrelationID records the correlation ID carried by ctx on the span active in ctx
+func SetCorrelationID(ctx context.Context) {
+	if correlationID := zlog.CorrelationID(ctx); correlationID != "" {
+		trace.SpanFromContext(ctx).SetAttributes(CorrelationIDKey.String(correlationID))
+	}
+}
@@ -0,0 +1,70 @@
+package tracing
+
+import (
+	"context"
+	"testing"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// restoreGlobals puts back the global tracer provider and propagator once the test ends
+func restoreGlobals(t *testing.T) {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+}
+
+func TestSetup_WithoutEndpointOnlyInstallsPropagator(t *testing.T) {
+	restoreGlobals(t)
+	provider := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), "", "test-service")
+	require.NoError(t, err)
+	require.NoError(t, shutdown(context.Background()))
+
+	assert.Equal(t, provider, otel.GetTracerProvider(), "spans are not exported without an endpoint")
+	carrier := propagation.MapCarrier{}
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "span")
+	defer span.End()
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	assert.NotEmpty(t, carrier.Get("traceparent"))
+}
+
+func TestSetup_WithEndpointInstallsProvider(t *testing.T) {
+	restoreGlobals(t)
+	provider := otel.GetTracerProvider()
+
+	shutdown, err := Setup(context.Background(), "http://127.0.0.1:4317", "test-service")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = shutdown(context.Background()) })
+
+	assert.NotEqual(t, provider, otel.GetTracerProvider())
+}
+
+func TestSetCorrelationID(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tracer := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)).Tracer("test")
+
+	ctx, span := tracer.Start(zlog.WithCorrelationID(context.Background(), "request-1"), "with ID")
+	SetCorrelationID(ctx)
+	span.End()
+
+	ctx, span = tracer.Start(context.Background(), "without ID")
+	SetCorrelationID(ctx)
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Contains(t, spans[0].Attributes(), CorrelationIDKey.String("request-1"))
+	assert.Empty(t, spans[1].Attributes())
+}
@@ -12,7 +12,7 @@ import (
 
 	"auth-service/models"
 
-	"github.com/golang-jwt/jwt"
+	"packages/jwt"
 )
 
 // TokenConfig holds JWT configuration
@@ -24,7 +24,7 @@ type TokenConfig struct {
 
 // GenerateAccessTokenSimple creates a new access token for a user (simplified version)
 func GenerateAccessTokenSimple(userID string, email string, name string, role string, secret string) (string, error) {
-	claims := jwt.MapClaims{
+	claims := jwt.Claims{
 		"user_id": userID,
 		"name":    name,
 		"email":   email,
@@ -33,25 +33,23 @@ func GenerateAccessTokenSimple(userID string, email string, name string, role st
 		"iat":     time.Now().Unix(),
 		"type":    "access",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(claims, secret)
 }
 
 // GenerateRefreshTokenSimple creates a new refresh token for a user (simplified version)
 func GenerateRefreshTokenSimple(userID string, secret string) (string, error) {
-	claims := jwt.MapClaims{
+	claims := jwt.Claims{
 		"user_id": userID,
 		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
 		"iat":     time.Now().Unix(),
 		"type":    "refresh",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(claims, secret)
 }
 
 // GenerateAccessToken creates a new access token for a user
 func GenerateAccessToken(user *models.User, secret string) (string, error) {
-	claims := jwt.MapClaims{
+	claims := jwt.Claims{
 		"user_id": user.ID,
 		"name":    user.Name,
 		"email":   user.Email,
@@ -59,13 +57,12 @@ func GenerateAccessToken(user *models.User, secret string) (string, error) {
 		"iat":     time.Now().Unix(),
 		"type":    "access",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(claims, secret)
 }
 
 // GenerateRefreshToken creates a new refresh token for a user
 func GenerateRefreshToken(user *models.User, secret string) (string, error) {
-	claims := jwt.MapClaims{
+	claims := jwt.Claims{
 		"user_id": user.ID,
 		"name":    user.Name,
 		"email":   user.Email,
@@ -73,29 +70,12 @@ func GenerateRefreshToken(user *models.User, secret string) (string, error) {
 		"iat":     time.Now().Unix(),
 		"type":    "refresh",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(claims, secret)
 }
 
 // ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString, secret string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
-	}
-
-	return nil, fmt.Errorf("invalid token")
+func ValidateToken(tokenString, secret string) (jwt.Claims, error) {
+	return jwt.Validate(tokenString, jwt.Scope{}, secret)
 }
 
 // GenerateTimedCode creates a time-based encrypted code
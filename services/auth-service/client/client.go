@@ -15,7 +15,7 @@ import (
 
 func main() {
 	// Initialize logger
-	logger := zlog.NewLogger(zlog.Config{
+	logger := zlog.New(zlog.Config{
 		Level:      "debug",
 		Output:     nil, // Use default stdout
 		JSONFormat: false,
@@ -1,12 +1,15 @@
 package config
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 
+	"auth-service/utils"
+
 	"github.com/joho/godotenv"
 )
 
@@ -28,12 +31,28 @@ type Config struct {
 	PostgresPort          string
 	JWTAccessTokenSecret  string
 	JWTRefreshTokenSecret string
+	JWTPreviousSecrets    []string // retired access token secrets, still accepted for verification
+	JWTIssuer             string   // "iss" claim set on issued tokens and required on validation; empty disables
+	JWTAudience           string   // "aud" claim set on issued tokens and required on validation; empty disables
 	AllowedOrigins        []string
+	CORSMaxAge            int // in seconds, how long browsers may cache a preflight response
 	LogLevel              string
 	LogJSONFormat         bool
+	LogSampleRate         int // keep one in every LogSampleRate INFO/DEBUG logs; 0 or 1 keeps all
+	LogSampleBurst        int // INFO/DEBUG logs per second kept before sampling applies
 	HealthCheckTimeout    int // in seconds
 	ServerReadTimeout     int // in seconds
 	ServerWriteTimeout    int // in seconds
+	ShutdownTimeout       int // in seconds, how long shutdown waits for in-flight requests
+	GRPCMaxMessageBytes   int // largest gRPC request or response message
+
+	// gRPC Keepalive Enforcement
+	GRPCKeepaliveMinTime             int  // in seconds, clients pinging more often are disconnected
+	GRPCKeepalivePermitWithoutStream bool // allow pings from clients without active calls
+
+	// REST Response Compression
+	CompressionEnabled  bool
+	CompressionMinBytes int // smallest response body that is gzipped
 
 	// Security Configuration
 	TLSEnabled    bool
@@ -46,6 +65,11 @@ type Config struct {
 	RateLimitEnabled  bool
 	RateLimitRequests int
 	RateLimitWindow   int // in seconds
+	RateLimitFailOpen bool
+
+	// Redis Configuration (shared rate limiting, in-memory when RedisAddr is empty)
+	RedisAddr     string
+	RedisPassword string
 
 	// Security Headers
 	SecurityHeadersEnabled bool
@@ -58,6 +82,31 @@ type Config struct {
 	RequireLowercase    bool
 	RequireNumbers      bool
 	RequireSpecialChars bool
+	BcryptCost          int // cost for new password hashes; weaker hashes are upgraded at sign in
+
+	// Password Reset
+	PasswordResetExpiration int // in minutes
+
+	// Sign In Lockout
+	LoginLockoutThreshold int // failed attempts before locking, 0 disables lockout
+	LoginLockoutWindow    int // in minutes
+	LoginLockoutCooldown  int // in minutes
+
+	// Token Cleanup
+	TokenCleanupInterval int // in seconds, 0 disables pruning of expired revoked tokens
+
+	// Administration
+	AdminUserIDs         []string // users allowed to call admin-only RPCs
+	AdminAllowedCIDRs    []string // networks that may call AdminIPFilterMethods, any network when empty
+	AdminDeniedCIDRs     []string // networks refused AdminIPFilterMethods even when allowed
+	AdminIPFilterMethods []string // full gRPC method names the network lists apply to
+
+	// Trusted Proxies
+	TrustedProxyCIDRs  []string // peers whose TrustedProxyHeader names the real caller
+	TrustedProxyHeader string
+
+	// Tracing
+	OTelExporterEndpoint string // OTLP gRPC collector URL, tracing export is disabled when empty
 
 	// JWT Configuration
 	JWTExpirationTime    int // in minutes
@@ -67,7 +116,8 @@ type Config struct {
 	DBSSLMode            string
 	DBMaxConnections     int
 	DBMaxIdleConnections int
-	DBConnectionTimeout  int // in seconds
+	DBConnectionTimeout  int  // in seconds
+	RunMigrations        bool // apply pending migrations at startup
 
 	// Logging Security
 	LogSensitiveData  bool
@@ -75,10 +125,35 @@ type Config struct {
 	LogResponseBody   bool
 }
 
-// LoadConfig loads and validates configuration from environment variables
+// LoadConfig loads and validates configuration from environment variables, fetching the
+// JWT secrets from the provider selected by SECRET_PROVIDER
 func LoadConfig() (*Config, error) {
 	// Load .env file only if it exists, without overwriting existing env vars
 	_ = godotenv.Load() // Ignore error if .env doesn't exist
+
+	secrets, err := NewSecretProvider(getEnv("SECRET_PROVIDER", ENV_SECRET_PROVIDER), getEnv("SECRET_FILE_DIR", "/run/secrets"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid configuration: %w", err)
+	}
+	return LoadConfigWithSecrets(context.Background(), secrets)
+}
+
+// LoadConfigWithSecrets loads and validates configuration from environment variables,
+// fetching the JWT secrets from secrets
+func LoadConfigWithSecrets(ctx context.Context, secrets SecretProvider) (*Config, error) {
+	accessSecret, err := secrets.GetSecret(ctx, "JWT_ACCESS_TOKEN_SECRET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT_ACCESS_TOKEN_SECRET: %w", err)
+	}
+	refreshSecret, err := secrets.GetSecret(ctx, "JWT_REFRESH_TOKEN_SECRET")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT_REFRESH_TOKEN_SECRET: %w", err)
+	}
+	previousSecrets, err := secrets.GetSecret(ctx, "JWT_PREVIOUS_SECRETS")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load JWT_PREVIOUS_SECRETS: %w", err)
+	}
+
 	raw := getEnv("ALLOWED_ORIGINS", "")
 
 	// Parse TLS version strings
@@ -94,14 +169,30 @@ func LoadConfig() (*Config, error) {
 		PostgresDB:            getEnv("POSTGRES_DB", "starter_db"),
 		PostgresHost:          getEnv("POSTGRES_HOST", "localhost"),
 		PostgresPort:          getEnv("POSTGRES_PORT", "5432"),
-		JWTAccessTokenSecret:  getEnv("JWT_ACCESS_TOKEN_SECRET", ""),
-		JWTRefreshTokenSecret: getEnv("JWT_REFRESH_TOKEN_SECRET", ""),
+		JWTAccessTokenSecret:  accessSecret,
+		JWTRefreshTokenSecret: refreshSecret,
+		JWTPreviousSecrets:    splitList(previousSecrets),
+		JWTIssuer:             getEnv("JWT_ISSUER", ""),
+		JWTAudience:           getEnv("JWT_AUDIENCE", ""),
 		AllowedOrigins:        strings.Split(raw, ","),
+		CORSMaxAge:            getEnvInt("CORS_MAX_AGE", 86400), // 24 hours
 		LogLevel:              getEnv("LOG_LEVEL", "debug"),
 		LogJSONFormat:         getEnv("LOG_JSON_FORMAT", "false") == "true",
+		LogSampleRate:         getEnvInt("LOG_SAMPLE_RATE", 0),
+		LogSampleBurst:        getEnvInt("LOG_SAMPLE_BURST", 0),
 		HealthCheckTimeout:    getEnvInt("HEALTH_CHECK_TIMEOUT", 5),
 		ServerReadTimeout:     getEnvInt("SERVER_READ_TIMEOUT", 10),
 		ServerWriteTimeout:    getEnvInt("SERVER_WRITE_TIMEOUT", 10),
+		ShutdownTimeout:       getEnvInt("SHUTDOWN_TIMEOUT_SECONDS", 5),
+		GRPCMaxMessageBytes:   getEnvInt("GRPC_MAX_MESSAGE_BYTES", 4194304), // 4 MiB
+
+		// gRPC Keepalive Enforcement
+		GRPCKeepaliveMinTime:             getEnvInt("GRPC_KEEPALIVE_MIN_TIME", 30), // half the keepalive time of our clients
+		GRPCKeepalivePermitWithoutStream: getEnv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "true") == "true",
+
+		// REST Response Compression
+		CompressionEnabled:  getEnv("COMPRESSION_ENABLED", "true") == "true",
+		CompressionMinBytes: getEnvInt("COMPRESSION_MIN_BYTES", 1024), // 1 KiB
 
 		// Security Configuration
 		TLSEnabled:    getEnv("TLS_ENABLED", "false") == "true",
@@ -114,6 +205,11 @@ func LoadConfig() (*Config, error) {
 		RateLimitEnabled:  getEnv("RATE_LIMIT_ENABLED", "true") == "true",
 		RateLimitRequests: getEnvInt("RATE_LIMIT_REQUESTS", 100),
 		RateLimitWindow:   getEnvInt("RATE_LIMIT_WINDOW", 60),
+		RateLimitFailOpen: getEnv("RATE_LIMIT_FAIL_OPEN", "true") == "true",
+
+		// Redis Configuration
+		RedisAddr:     getEnv("REDIS_ADDR", ""),
+		RedisPassword: getEnv("REDIS_PASSWORD", ""),
 
 		// Security Headers
 		SecurityHeadersEnabled: getEnv("SECURITY_HEADERS_ENABLED", "true") == "true",
@@ -126,6 +222,31 @@ func LoadConfig() (*Config, error) {
 		RequireLowercase:    getEnv("REQUIRE_LOWERCASE", "true") == "true",
 		RequireNumbers:      getEnv("REQUIRE_NUMBERS", "true") == "true",
 		RequireSpecialChars: getEnv("REQUIRE_SPECIAL_CHARS", "true") == "true",
+		BcryptCost:          getEnvInt("BCRYPT_COST", 14),
+
+		// Password Reset
+		PasswordResetExpiration: getEnvInt("PASSWORD_RESET_EXPIRATION", 30), // 30 minutes
+
+		// Sign In Lockout
+		LoginLockoutThreshold: getEnvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+		LoginLockoutWindow:    getEnvInt("LOGIN_LOCKOUT_WINDOW", 15),   // 15 minutes
+		LoginLockoutCooldown:  getEnvInt("LOGIN_LOCKOUT_COOLDOWN", 15), // 15 minutes
+
+		// Token Cleanup
+		TokenCleanupInterval: getEnvInt("TOKEN_CLEANUP_INTERVAL", 3600), // 1 hour
+
+		// Administration
+		AdminUserIDs:         getEnvList("ADMIN_USER_IDS"),
+		AdminAllowedCIDRs:    getEnvList("ADMIN_ALLOWED_CIDRS"),
+		AdminDeniedCIDRs:     getEnvList("ADMIN_DENIED_CIDRS"),
+		AdminIPFilterMethods: getEnvListOrDefault("ADMIN_IP_FILTER_METHODS", "/auth.AuthService/ListUsers,/auth.AuthService/ListAuditEvents,/auth.AuthService/RevokeAllUserTokens"),
+
+		// Trusted Proxies (the REST gateway relays requests from loopback)
+		TrustedProxyCIDRs:  getEnvListOrDefault("TRUSTED_PROXY_CIDRS", "127.0.0.1/32,::1/128"),
+		TrustedProxyHeader: getEnv("TRUSTED_PROXY_HEADER", "x-forwarded-for"),
+
+		// Tracing
+		OTelExporterEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
 
 		// JWT Configuration
 		JWTExpirationTime:    getEnvInt("JWT_EXPIRATION_TIME", 15),   // 15 minutes
@@ -136,6 +257,7 @@ func LoadConfig() (*Config, error) {
 		DBMaxConnections:     getEnvInt("DB_MAX_CONNECTIONS", 25),
 		DBMaxIdleConnections: getEnvInt("DB_MAX_IDLE_CONNECTIONS", 5),
 		DBConnectionTimeout:  getEnvInt("DB_CONNECTION_TIMEOUT", 30),
+		RunMigrations:        getEnv("RUN_MIGRATIONS", "true") == "true",
 
 		// Logging Security
 		LogSensitiveData:  getEnv("LOG_SENSITIVE_DATA", "false") == "true",
@@ -168,6 +290,11 @@ func parseTLSVersion(version string) uint16 {
 	}
 }
 
+// TokenScope returns the issuer and audience tokens are issued with and validated against
+func (c *Config) TokenScope() utils.TokenScope {
+	return utils.TokenScope{Issuer: c.JWTIssuer, Audience: c.JWTAudience}
+}
+
 // getEnv retrieves an environment variable or returns a fallback
 func getEnv(key, fallback string) string {
 	if value, exists := os.LookupEnv(key); exists {
@@ -176,6 +303,28 @@ func getEnv(key, fallback string) string {
 	return fallback
 }
 
+// getEnvList retrieves a comma-separated environment variable, skipping empty entries
+func getEnvList(key string) []string {
+	return getEnvListOrDefault(key, "")
+}
+
+// getEnvListOrDefault retrieves a comma-separated environment variable, skipping empty
+// entries, or splits fallback when the variable is not set
+func getEnvListOrDefault(key, fallback string) []string {
+	return splitList(getEnv(key, fallback))
+}
+
+// splitList splits a comma-separated value, skipping empty entries
+func splitList(raw string) []string {
+	var values []string
+	for _, value := range strings.Split(raw, ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 // getEnvInt retrieves an environment variable as an integer or returns a fallback
 func getEnvInt(key string, fallback int) int {
 	if value, exists := os.LookupEnv(key); exists {
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestLoadConfig(t *testing.T) {
@@ -329,6 +330,39 @@ func TestGetEnvInt(t *testing.T) {
 	}
 }
 
+func TestGetEnvList(t *testing.T) {
+	tests := []struct {
+		name     string
+		envValue string
+		expected []string
+	}{
+		{
+			name:     "unset environment variable",
+			expected: nil,
+		},
+		{
+			name:     "single value",
+			envValue: "a",
+			expected: []string{"a"},
+		},
+		{
+			name:     "values with whitespace and empty entries",
+			envValue: " a, b ,,c, ",
+			expected: []string{"a", "b", "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				t.Setenv("TEST_LIST_VAR", tt.envValue)
+			}
+
+			assert.Equal(t, tt.expected, getEnvList("TEST_LIST_VAR"))
+		})
+	}
+}
+
 func TestEnvironmentConstants(t *testing.T) {
 	// Test that environment constants are properly defined
 	assert.Equal(t, "production", PRODUCTION_ENV)
@@ -438,3 +472,138 @@ func BenchmarkGetEnvInt(b *testing.B) {
 		getEnvInt("BENCHMARK_TEST_INT_VAR", 100)
 	}
 }
+
+func TestLoadConfig_BcryptCost(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 14, config.BcryptCost)
+
+	t.Setenv("BCRYPT_COST", "12")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 12, config.BcryptCost)
+
+	for _, cost := range []string{"3", "32"} {
+		t.Setenv("BCRYPT_COST", cost)
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "BCRYPT_COST must be between 4 and 31", "cost %s", cost)
+	}
+}
+
+func TestLoadConfig_AdminNetworks(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Empty(t, config.AdminAllowedCIDRs)
+	assert.Equal(t, []string{"/auth.AuthService/ListUsers", "/auth.AuthService/ListAuditEvents", "/auth.AuthService/RevokeAllUserTokens"}, config.AdminIPFilterMethods)
+	assert.Equal(t, []string{"127.0.0.1/32", "::1/128"}, config.TrustedProxyCIDRs)
+	assert.Equal(t, "x-forwarded-for", config.TrustedProxyHeader)
+
+	t.Setenv("ADMIN_ALLOWED_CIDRS", "10.0.0.0/8, 192.168.1.0/24")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"10.0.0.0/8", "192.168.1.0/24"}, config.AdminAllowedCIDRs)
+
+	t.Setenv("ADMIN_ALLOWED_CIDRS", "10.0.0.1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `ADMIN_ALLOWED_CIDRS entry "10.0.0.1" is not a CIDR range`)
+}
+
+func TestLoadConfig_TokenCleanupInterval(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 3600, config.TokenCleanupInterval)
+
+	t.Setenv("TOKEN_CLEANUP_INTERVAL", "0")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 0, config.TokenCleanupInterval)
+
+	t.Setenv("TOKEN_CLEANUP_INTERVAL", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "TOKEN_CLEANUP_INTERVAL cannot be negative")
+}
+
+func TestLoadConfig_Compression(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, config.CompressionEnabled)
+	assert.Equal(t, 1024, config.CompressionMinBytes)
+
+	t.Setenv("COMPRESSION_ENABLED", "false")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, config.CompressionEnabled)
+
+	t.Setenv("COMPRESSION_MIN_BYTES", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "COMPRESSION_MIN_BYTES cannot be negative")
+}
+
+func TestLoadConfig_CORSMaxAge(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 86400, config.CORSMaxAge)
+
+	t.Setenv("CORS_MAX_AGE", "600")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 600, config.CORSMaxAge)
+
+	t.Setenv("CORS_MAX_AGE", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "CORS_MAX_AGE cannot be negative")
+}
+
+func TestLoadConfig_GRPCKeepalivePolicy(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 30, config.GRPCKeepaliveMinTime)
+	assert.True(t, config.GRPCKeepalivePermitWithoutStream)
+
+	t.Setenv("GRPC_KEEPALIVE_MIN_TIME", "10")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "false")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 10, config.GRPCKeepaliveMinTime)
+	assert.False(t, config.GRPCKeepalivePermitWithoutStream)
+
+	t.Setenv("GRPC_KEEPALIVE_MIN_TIME", "0")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "GRPC_KEEPALIVE_MIN_TIME must be positive")
+}
+
+func TestLoadConfig_ShutdownTimeout(t *testing.T) {
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-access-tokens-32")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "this-is-a-very-long-secret-key-for-refresh-tokens-32")
+
+	config, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 5, config.ShutdownTimeout)
+
+	t.Setenv("SHUTDOWN_TIMEOUT_SECONDS", "30")
+	config, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 30, config.ShutdownTimeout)
+
+	t.Setenv("SHUTDOWN_TIMEOUT_SECONDS", "0")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "SHUTDOWN_TIMEOUT_SECONDS must be positive")
+}
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ENV_SECRET_PROVIDER  = "env"
+	FILE_SECRET_PROVIDER = "file"
+)
+
+// SecretProvider looks up secrets such as the JWT signing keys by name. An empty value
+// with a nil error means the secret is not set.
+type SecretProvider interface {
+	GetSecret(ctx context.Context, name string) (string, error)
+}
+
+// NewSecretProvider returns the secret provider selected by SECRET_PROVIDER. dir is the
+// directory the file provider reads from and is ignored by the others.
+func NewSecretProvider(name, dir string) (SecretProvider, error) {
+	switch strings.ToLower(name) {
+	case "", ENV_SECRET_PROVIDER:
+		return EnvSecretProvider{}, nil
+	case FILE_SECRET_PROVIDER:
+		if dir == "" {
+			return nil, fmt.Errorf("SECRET_FILE_DIR is required when SECRET_PROVIDER is %q", FILE_SECRET_PROVIDER)
+		}
+		return FileSecretProvider{Dir: dir}, nil
+	default:
+		return nil, fmt.Errorf("unsupported SECRET_PROVIDER %q (must be %q or %q)", name, ENV_SECRET_PROVIDER, FILE_SECRET_PROVIDER)
+	}
+}
+
+// EnvSecretProvider reads each secret from the environment variable of the same name
+type EnvSecretProvider struct{}
+
+// GetSecret returns the value of the environment variable name
+func (EnvSecretProvider) GetSecret(_ context.Context, name string) (string, error) {
+	return getEnv(name, ""), nil
+}
+
+// FileSecretProvider reads each secret from a file of the same name in Dir, the layout
+// used by Docker and Kubernetes secret mounts and by vault agents rendering to disk.
+// Files are read on every call, so a rotated secret is picked up on the next load.
+type FileSecretProvider struct {
+	Dir string
+}
+
+// GetSecret returns the contents of Dir/name without surrounding whitespace. A missing
+// file means the secret is not set.
+func (p FileSecretProvider) GetSecret(_ context.Context, name string) (string, error) {
+	if name == "" || name != filepath.Base(name) {
+		return "", fmt.Errorf("invalid secret name %q", name)
+	}
+
+	data, err := os.ReadFile(filepath.Join(p.Dir, name))
+	if errors.Is(err, os.ErrNotExist) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret %s: %w", name, err)
+	}
+	return strings.TrimSpace(string(data)), nil
+}
@@ -0,0 +1,123 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccessSecret  = "this-is-a-very-long-secret-key-for-access-tokens-32"
+	testRefreshSecret = "this-is-a-very-long-secret-key-for-refresh-tokens-32"
+)
+
+// fakeSecretProvider serves secrets from a map, or fails every lookup with err
+type fakeSecretProvider struct {
+	secrets map[string]string
+	err     error
+}
+
+func (p fakeSecretProvider) GetSecret(_ context.Context, name string) (string, error) {
+	if p.err != nil {
+		return "", p.err
+	}
+	return p.secrets[name], nil
+}
+
+func TestNewSecretProvider(t *testing.T) {
+	provider, err := NewSecretProvider("", "")
+	require.NoError(t, err)
+	assert.Equal(t, EnvSecretProvider{}, provider)
+
+	provider, err = NewSecretProvider("FILE", "/run/secrets")
+	require.NoError(t, err)
+	assert.Equal(t, FileSecretProvider{Dir: "/run/secrets"}, provider)
+
+	_, err = NewSecretProvider("file", "")
+	assert.ErrorContains(t, err, "SECRET_FILE_DIR is required")
+
+	_, err = NewSecretProvider("vault", "")
+	assert.ErrorContains(t, err, `unsupported SECRET_PROVIDER "vault"`)
+}
+
+func TestEnvSecretProvider(t *testing.T) {
+	t.Setenv("TEST_SECRET", "  from-env  ")
+
+	value, err := EnvSecretProvider{}.GetSecret(context.Background(), "TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "from-env", value)
+
+	value, err = EnvSecretProvider{}.GetSecret(context.Background(), "TEST_SECRET_MISSING")
+	require.NoError(t, err)
+	assert.Empty(t, value)
+}
+
+func TestFileSecretProvider(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "TEST_SECRET"), []byte("from-file\n"), 0o600))
+	provider := FileSecretProvider{Dir: dir}
+
+	value, err := provider.GetSecret(context.Background(), "TEST_SECRET")
+	require.NoError(t, err)
+	assert.Equal(t, "from-file", value)
+
+	value, err = provider.GetSecret(context.Background(), "TEST_SECRET_MISSING")
+	require.NoError(t, err)
+	assert.Empty(t, value)
+
+	_, err = provider.GetSecret(context.Background(), "../TEST_SECRET")
+	assert.ErrorContains(t, err, "invalid secret name")
+}
+
+func TestLoadConfigWithSecrets(t *testing.T) {
+	// Secrets come from the provider, not the environment
+	t.Setenv("JWT_ACCESS_TOKEN_SECRET", "ignored")
+	t.Setenv("JWT_REFRESH_TOKEN_SECRET", "ignored")
+
+	cfg, err := LoadConfigWithSecrets(context.Background(), fakeSecretProvider{secrets: map[string]string{
+		"JWT_ACCESS_TOKEN_SECRET":  testAccessSecret,
+		"JWT_REFRESH_TOKEN_SECRET": testRefreshSecret,
+		"JWT_PREVIOUS_SECRETS":     strings.Repeat("a", 32) + ", " + strings.Repeat("b", 32),
+	}})
+	require.NoError(t, err)
+	assert.Equal(t, testAccessSecret, cfg.JWTAccessTokenSecret)
+	assert.Equal(t, testRefreshSecret, cfg.JWTRefreshTokenSecret)
+	assert.Equal(t, []string{strings.Repeat("a", 32), strings.Repeat("b", 32)}, cfg.JWTPreviousSecrets)
+
+	_, err = LoadConfigWithSecrets(context.Background(), fakeSecretProvider{secrets: map[string]string{
+		"JWT_ACCESS_TOKEN_SECRET":  "too-short",
+		"JWT_REFRESH_TOKEN_SECRET": testRefreshSecret,
+	}})
+	assert.ErrorContains(t, err, "JWT_ACCESS_TOKEN_SECRET must be at least 32 characters long")
+
+	_, err = LoadConfigWithSecrets(context.Background(), fakeSecretProvider{secrets: map[string]string{
+		"JWT_ACCESS_TOKEN_SECRET": testAccessSecret,
+	}})
+	assert.ErrorContains(t, err, "JWT_REFRESH_TOKEN_SECRET cannot be empty")
+
+	_, err = LoadConfigWithSecrets(context.Background(), fakeSecretProvider{err: errors.New("vault sealed")})
+	assert.ErrorContains(t, err, "failed to load JWT_ACCESS_TOKEN_SECRET: vault sealed")
+}
+
+func TestLoadConfig_FileSecretProvider(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "JWT_ACCESS_TOKEN_SECRET"), []byte(testAccessSecret+"\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "JWT_REFRESH_TOKEN_SECRET"), []byte(testRefreshSecret+"\n"), 0o600))
+	t.Setenv("SECRET_PROVIDER", "file")
+	t.Setenv("SECRET_FILE_DIR", dir)
+
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, testAccessSecret, cfg.JWTAccessTokenSecret)
+	assert.Equal(t, testRefreshSecret, cfg.JWTRefreshTokenSecret)
+
+	t.Setenv("SECRET_PROVIDER", "vault")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `unsupported SECRET_PROVIDER "vault"`)
+}
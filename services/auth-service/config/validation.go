@@ -3,8 +3,12 @@ package config
 import (
 	"fmt"
 	"net"
+	"net/netip"
+	"net/url"
 	"strconv"
 	"strings"
+
+	"golang.org/x/crypto/bcrypt"
 )
 
 // ValidationError represents a configuration validation error
@@ -86,6 +90,41 @@ func ValidateConfig(cfg *Config) *ValidationResult {
 		result.AddError("rate_limiting", err.Error())
 	}
 
+	// Validate sign in lockout configuration
+	if err := validateLoginLockoutConfig(cfg); err != nil {
+		result.AddError("login_lockout", err.Error())
+	}
+
+	// Validate revoked token cleanup
+	if err := validateTokenCleanupConfig(cfg); err != nil {
+		result.AddError("token_cleanup", err.Error())
+	}
+
+	// Validate admin network restrictions
+	if err := validateAdminNetworkConfig(cfg); err != nil {
+		result.AddError("admin_network", err.Error())
+	}
+
+	// Validate gRPC message size limit and keepalive enforcement
+	if err := validateGRPCConfig(cfg); err != nil {
+		result.AddError("grpc", err.Error())
+	}
+
+	// Validate REST response compression
+	if err := validateCompressionConfig(cfg); err != nil {
+		result.AddError("compression", err.Error())
+	}
+
+	// Validate graceful shutdown timeout
+	if err := validateShutdownConfig(cfg); err != nil {
+		result.AddError("shutdown", err.Error())
+	}
+
+	// Validate tracing configuration
+	if err := validateTracingConfig(cfg); err != nil {
+		result.AddError("tracing", err.Error())
+	}
+
 	// Validate password policy
 	if err := validatePasswordPolicy(cfg); err != nil {
 		result.AddError("password_policy", err.Error())
@@ -177,6 +216,12 @@ func validateJWTConfig(cfg *Config) error {
 		return fmt.Errorf("JWT_REFRESH_TOKEN_SECRET must be at least 32 characters long")
 	}
 
+	for _, secret := range cfg.JWTPreviousSecrets {
+		if len(secret) < 32 {
+			return fmt.Errorf("JWT_PREVIOUS_SECRETS entries must be at least 32 characters long")
+		}
+	}
+
 	// Check for weak secrets in development
 	if cfg.Environment == DEVELOPMENT_ENV {
 		if cfg.JWTAccessTokenSecret == "default-access" {
@@ -205,6 +250,10 @@ func validateLoggingConfig(cfg *Config) error {
 		return fmt.Errorf("LOG_LEVEL must be one of: %s", strings.Join(validLevels, ", "))
 	}
 
+	if cfg.LogSampleRate < 0 || cfg.LogSampleBurst < 0 {
+		return fmt.Errorf("LOG_SAMPLE_RATE and LOG_SAMPLE_BURST cannot be negative")
+	}
+
 	return nil
 }
 
@@ -223,6 +272,10 @@ func validateCORSConfig(cfg *Config) error {
 		}
 	}
 
+	if cfg.CORSMaxAge < 0 {
+		return fmt.Errorf("CORS_MAX_AGE cannot be negative")
+	}
+
 	return nil
 }
 
@@ -355,6 +408,105 @@ func validateRateLimitConfig(cfg *Config) error {
 	return nil
 }
 
+// validateLoginLockoutConfig validates sign in lockout configuration
+func validateLoginLockoutConfig(cfg *Config) error {
+	if cfg.LoginLockoutThreshold < 0 {
+		return fmt.Errorf("LOGIN_LOCKOUT_THRESHOLD cannot be negative")
+	}
+
+	if cfg.LoginLockoutThreshold > 0 {
+		if cfg.LoginLockoutWindow <= 0 {
+			return fmt.Errorf("LOGIN_LOCKOUT_WINDOW must be positive when lockout is enabled")
+		}
+
+		if cfg.LoginLockoutCooldown <= 0 {
+			return fmt.Errorf("LOGIN_LOCKOUT_COOLDOWN must be positive when lockout is enabled")
+		}
+	}
+
+	return nil
+}
+
+// validateTokenCleanupConfig validates the revoked token cleanup interval
+func validateTokenCleanupConfig(cfg *Config) error {
+	if cfg.TokenCleanupInterval < 0 {
+		return fmt.Errorf("TOKEN_CLEANUP_INTERVAL cannot be negative")
+	}
+
+	return nil
+}
+
+// validateAdminNetworkConfig checks that the admin network lists and trusted proxies are CIDR ranges
+func validateAdminNetworkConfig(cfg *Config) error {
+	lists := []struct {
+		name   string
+		values []string
+	}{
+		{"ADMIN_ALLOWED_CIDRS", cfg.AdminAllowedCIDRs},
+		{"ADMIN_DENIED_CIDRS", cfg.AdminDeniedCIDRs},
+		{"TRUSTED_PROXY_CIDRS", cfg.TrustedProxyCIDRs},
+	}
+	for _, list := range lists {
+		for _, value := range list.values {
+			if _, err := netip.ParsePrefix(value); err != nil {
+				return fmt.Errorf("%s entry %q is not a CIDR range", list.name, value)
+			}
+		}
+	}
+
+	if len(cfg.TrustedProxyCIDRs) > 0 && cfg.TrustedProxyHeader == "" {
+		return fmt.Errorf("TRUSTED_PROXY_HEADER cannot be empty when TRUSTED_PROXY_CIDRS is set")
+	}
+
+	return nil
+}
+
+// validateGRPCConfig validates the gRPC message size limit. The server lets the transport
+// receive twice the limit, which must still fit in an int32.
+func validateGRPCConfig(cfg *Config) error {
+	if cfg.GRPCMaxMessageBytes <= 0 || cfg.GRPCMaxMessageBytes > 1<<30 {
+		return fmt.Errorf("GRPC_MAX_MESSAGE_BYTES must be between 1 and %d", 1<<30)
+	}
+
+	if cfg.GRPCKeepaliveMinTime <= 0 {
+		return fmt.Errorf("GRPC_KEEPALIVE_MIN_TIME must be positive")
+	}
+
+	return nil
+}
+
+// validateCompressionConfig validates the REST response compression threshold
+func validateCompressionConfig(cfg *Config) error {
+	if cfg.CompressionMinBytes < 0 {
+		return fmt.Errorf("COMPRESSION_MIN_BYTES cannot be negative")
+	}
+
+	return nil
+}
+
+// validateShutdownConfig validates the graceful shutdown timeout
+func validateShutdownConfig(cfg *Config) error {
+	if cfg.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT_SECONDS must be positive")
+	}
+
+	return nil
+}
+
+// validateTracingConfig validates the trace exporter configuration
+func validateTracingConfig(cfg *Config) error {
+	if cfg.OTelExporterEndpoint == "" {
+		return nil
+	}
+
+	endpoint, err := url.Parse(cfg.OTelExporterEndpoint)
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be an http or https URL")
+	}
+
+	return nil
+}
+
 // validatePasswordPolicy validates password policy configuration
 func validatePasswordPolicy(cfg *Config) error {
 	if cfg.MinPasswordLength < 8 {
@@ -364,6 +516,10 @@ func validatePasswordPolicy(cfg *Config) error {
 	if cfg.MinPasswordLength > 128 {
 		return fmt.Errorf("MIN_PASSWORD_LENGTH cannot exceed 128")
 	}
+
+	if cfg.BcryptCost < bcrypt.MinCost || cfg.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("BCRYPT_COST must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
 	
 	// Validate password complexity requirements
 	if cfg.RequireUppercase && cfg.RequireLowercase && cfg.RequireNumbers && cfg.RequireSpecialChars {
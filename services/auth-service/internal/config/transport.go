@@ -35,11 +35,13 @@ type ServerConfig struct {
 
 // GatewayConfig holds REST gateway configuration
 type GatewayConfig struct {
-	RESTPort       string   `json:"rest_port"`
-	AllowedOrigins []string `json:"allowed_origins"`
-	AllowedMethods []string `json:"allowed_methods"`
-	AllowedHeaders []string `json:"allowed_headers"`
-	MaxAge         int      `json:"max_age"`
+	RESTPort            string   `json:"rest_port"`
+	AllowedOrigins      []string `json:"allowed_origins"`
+	AllowedMethods      []string `json:"allowed_methods"`
+	AllowedHeaders      []string `json:"allowed_headers"`
+	MaxAge              int      `json:"max_age"`
+	CompressionEnabled  bool     `json:"compression_enabled"`
+	CompressionMinBytes int      `json:"compression_min_bytes"`
 }
 
 // HealthConfig holds health check configuration
@@ -81,9 +83,11 @@ func DefaultTransportConfig() *TransportConfig {
 			ShutdownTimeout:   5 * time.Second,
 		},
 		Gateway: GatewayConfig{
-			AllowedMethods: []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-			AllowedHeaders: []string{"Content-Type", "Authorization", "X-Requested-With"},
-			MaxAge:         86400, // 24 hours
+			AllowedMethods:      []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
+			AllowedHeaders:      []string{"Content-Type", "Authorization", "X-Requested-With"},
+			MaxAge:              86400, // 24 hours
+			CompressionEnabled:  true,
+			CompressionMinBytes: 1024,
 		},
 		Health: HealthConfig{
 			Timeout:        5 * time.Second,
@@ -22,7 +22,7 @@ type AuthService interface {
 	SignOut(accessToken string) error
 	RefreshToken(refreshToken string) (*TokenResponse, error)
 	RevokeToken(accessToken string) error
-	ValidateToken(ctx context.Context, accessToken string, secret string) (*User, error)
+	ValidateToken(ctx context.Context, accessToken string, secret string, previousSecrets ...string) (*User, error)
 }
 
 // UserService defines the interface for user management business logic
@@ -2,14 +2,21 @@ package grpc
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	"api/auth/v1/proto"
 	"auth-service/internal/services"
+	authentication "auth-service/internal/services/auth"
+	"auth-service/internal/transport/middleware"
 	"auth-service/models"
+	"auth-service/utils"
 	zlog "packages/logger"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
@@ -44,13 +51,19 @@ func (h *AuthHandler) SignUp(ctx context.Context, req *proto.UserCreateRequest)
 
 	// Call service
 	user, err := h.service.Auth.SignUp(ctx, userReq)
+	if appErr := utils.GetAppError(err); appErr != nil && appErr.Code == utils.ErrCodeUserAlreadyExists {
+		h.logger.Warn(ctx, "SignUp rejected, email already registered", map[string]any{
+			"email": req.Email,
+		})
+		return nil, status.Error(codes.AlreadyExists, appErr.Message)
+	}
 	if err != nil {
 		h.logger.Error(ctx, err, "SignUp failed", 400)
 		return nil, status.Errorf(codes.InvalidArgument, "signup failed: %v", err)
 	}
 
 	// Generate tokens for the user using secrets from config
-	accessToken, refreshToken, err := h.service.Auth.GenerateTokens(ctx, user, h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTRefreshTokenSecret)
+	accessToken, refreshToken, err := h.service.Auth.GenerateTokens(ctx, user, clientInfo(ctx), h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTRefreshTokenSecret)
 	if err != nil {
 		h.logger.Error(ctx, err, "Failed to generate tokens", 500)
 		return nil, status.Errorf(codes.InvalidArgument, "failed to generate tokens: %v", err)
@@ -85,13 +98,21 @@ func (h *AuthHandler) SignIn(ctx context.Context, req *proto.Credentials) (*prot
 
 	// Convert protobuf request to internal model
 	creds := &models.Credentials{
-		Email:    req.Email,
-		Password: req.Password,
+		Email:     req.Email,
+		Password:  req.Password,
+		ClientIP:  middleware.ClientIPFromContext(ctx),
+		UserAgent: middleware.UserAgent(ctx),
 	}
 
 	// Call service with JWT secrets
 	user, accessToken, refreshToken, err := h.service.Auth.SignIn(ctx, creds, h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTRefreshTokenSecret)
 	if err != nil {
+		if errors.Is(err, authentication.ErrTooManySignInAttempts) {
+			h.logger.Warn(ctx, "SignIn locked", map[string]any{
+				"email": req.Email,
+			})
+			return nil, status.Errorf(codes.ResourceExhausted, "%v", err)
+		}
 		h.logger.Error(ctx, err, "SignIn failed", 401)
 		return nil, status.Errorf(codes.Unauthenticated, "signin failed: %v", err)
 	}
@@ -138,8 +159,12 @@ func (h *AuthHandler) RefreshToken(ctx context.Context, req *proto.RefreshTokenR
 	h.logger.Info(ctx, "Processing RefreshToken request")
 
 	// Call service with JWT secrets
-	tokens, err := h.service.Auth.RefreshToken(ctx, req.RefreshToken, h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTRefreshTokenSecret)
+	tokens, err := h.service.Auth.RefreshToken(ctx, req.RefreshToken, clientInfo(ctx), h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTRefreshTokenSecret)
 	if err != nil {
+		if errors.Is(err, authentication.ErrRefreshTokenReused) {
+			h.logger.Error(ctx, err, "RefreshToken reuse detected", 401)
+			return nil, status.Error(codes.Unauthenticated, "refresh token has already been used")
+		}
 		h.logger.Error(ctx, err, "RefreshToken failed", 400)
 		return nil, status.Errorf(codes.InvalidArgument, "token refresh failed: %v", err)
 	}
@@ -170,6 +195,123 @@ func (h *AuthHandler) RevokeToken(ctx context.Context, req *proto.RevokeTokenReq
 	return &proto.Empty{}, nil
 }
 
+// ListSessions handles listing the caller's active sessions
+func (h *AuthHandler) ListSessions(ctx context.Context, req *proto.ListSessionsRequest) (*proto.ListSessionsResponse, error) {
+	h.logger.Info(ctx, "Processing ListSessions request")
+
+	user, err := h.authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call service
+	sessions, err := h.service.Auth.ListSessions(ctx, user.ID)
+	if err != nil {
+		h.logger.Error(ctx, err, "ListSessions failed", 500)
+		return nil, status.Error(codes.Internal, "failed to list sessions")
+	}
+
+	// Convert to protobuf response
+	protoSessions := make([]*proto.Session, len(sessions))
+	for i := range sessions {
+		protoSessions[i] = convertSessionToProto(&sessions[i])
+	}
+
+	h.logger.Info(ctx, "ListSessions completed successfully", map[string]any{
+		"count": len(sessions),
+	})
+
+	return &proto.ListSessionsResponse{Sessions: protoSessions}, nil
+}
+
+// RevokeSession handles signing the caller out of one of their sessions
+func (h *AuthHandler) RevokeSession(ctx context.Context, req *proto.RevokeSessionRequest) (*proto.Empty, error) {
+	h.logger.Info(ctx, "Processing RevokeSession request", map[string]any{
+		"session_id": req.SessionId,
+	})
+
+	sessionID, err := uuid.Parse(req.SessionId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid session_id")
+	}
+
+	user, err := h.authenticatedUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call service
+	if err := h.service.Auth.RevokeSession(ctx, user.ID, sessionID); err != nil {
+		if errors.Is(err, authentication.ErrSessionNotFound) {
+			return nil, status.Error(codes.NotFound, err.Error())
+		}
+		h.logger.Error(ctx, err, "RevokeSession failed", 500)
+		return nil, status.Error(codes.Internal, "session revocation failed")
+	}
+
+	h.logger.Info(ctx, "RevokeSession completed successfully", map[string]any{
+		"session_id": req.SessionId,
+	})
+
+	return &proto.Empty{}, nil
+}
+
+// RequestPasswordReset handles password reset requests. It reports success for unknown
+// emails too, so the response does not reveal which addresses are registered.
+func (h *AuthHandler) RequestPasswordReset(ctx context.Context, req *proto.RequestPasswordResetRequest) (*proto.Empty, error) {
+	h.logger.Info(ctx, "Processing RequestPasswordReset request")
+
+	if req.Email == "" {
+		return nil, status.Error(codes.InvalidArgument, "email is required")
+	}
+
+	// Call service
+	ttl := time.Duration(h.service.Config.PasswordResetExpiration) * time.Minute
+	if err := h.service.Auth.RequestPasswordReset(ctx, req.Email, ttl); err != nil {
+		h.logger.Error(ctx, err, "RequestPasswordReset failed", 500)
+		return nil, status.Error(codes.Internal, "password reset request failed")
+	}
+
+	h.logger.Info(ctx, "RequestPasswordReset completed successfully")
+
+	return &proto.Empty{}, nil
+}
+
+// ConfirmPasswordReset handles setting a new password with a reset token
+func (h *AuthHandler) ConfirmPasswordReset(ctx context.Context, req *proto.ConfirmPasswordResetRequest) (*proto.Empty, error) {
+	h.logger.Info(ctx, "Processing ConfirmPasswordReset request")
+
+	if req.Token == "" {
+		return nil, status.Error(codes.InvalidArgument, "token is required")
+	}
+
+	// Call service with the configured password policy
+	cfg := h.service.Config
+	policy := authentication.PasswordPolicy{
+		MinLength:           cfg.MinPasswordLength,
+		RequireUppercase:    cfg.RequireUppercase,
+		RequireLowercase:    cfg.RequireLowercase,
+		RequireNumbers:      cfg.RequireNumbers,
+		RequireSpecialChars: cfg.RequireSpecialChars,
+	}
+	if err := h.service.Auth.ConfirmPasswordReset(ctx, req.Token, req.NewPassword, policy); err != nil {
+		switch {
+		case errors.Is(err, authentication.ErrWeakPassword):
+			h.logger.Error(ctx, err, "ConfirmPasswordReset rejected weak password", 400)
+			return nil, status.Errorf(codes.InvalidArgument, "password reset failed: %v", err)
+		case errors.Is(err, authentication.ErrInvalidPasswordResetToken):
+			h.logger.Error(ctx, err, "ConfirmPasswordReset rejected token", 401)
+			return nil, status.Error(codes.Unauthenticated, err.Error())
+		}
+		h.logger.Error(ctx, err, "ConfirmPasswordReset failed", 500)
+		return nil, status.Error(codes.Internal, "password reset failed")
+	}
+
+	h.logger.Info(ctx, "ConfirmPasswordReset completed successfully")
+
+	return &proto.Empty{}, nil
+}
+
 // ListUsers handles user listing
 func (h *AuthHandler) ListUsers(ctx context.Context, req *proto.ListUsersRequest) (*proto.ListUsersResponse, error) {
 	h.logger.Info(ctx, "Processing ListUsers request", map[string]any{
@@ -215,12 +357,96 @@ func (h *AuthHandler) ListUsers(ctx context.Context, req *proto.ListUsersRequest
 	return response, nil
 }
 
+// ListAuditEvents handles audit event listing. Only administrators reach it; the
+// security middleware rejects everyone else.
+func (h *AuthHandler) ListAuditEvents(ctx context.Context, req *proto.ListAuditEventsRequest) (*proto.ListAuditEventsResponse, error) {
+	h.logger.Info(ctx, "Processing ListAuditEvents request", map[string]any{
+		"page":       req.Page,
+		"limit":      req.Limit,
+		"user_id":    req.UserId,
+		"event_type": req.EventType,
+	})
+
+	// Set default values for pagination
+	page := int(req.Page)
+	limit := int(req.Limit)
+
+	if page <= 0 {
+		page = 1
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	filter := models.AuditEventFilter{EventType: req.EventType}
+	if req.UserId != "" {
+		userID, err := uuid.Parse(req.UserId)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, "invalid user_id")
+		}
+		filter.UserID = &userID
+	}
+
+	// Call service
+	events, total, err := h.service.Audit.ListEvents(ctx, filter, page, limit)
+	if err != nil {
+		h.logger.Error(ctx, err, "ListAuditEvents failed", 500)
+		return nil, status.Error(codes.Internal, "failed to list audit events")
+	}
+
+	// Convert to protobuf response
+	protoEvents := make([]*proto.AuditEvent, len(events))
+	for i := range events {
+		protoEvents[i] = convertAuditEventToProto(&events[i])
+	}
+
+	response := &proto.ListAuditEventsResponse{
+		Events: protoEvents,
+		Total:  int32(total),
+		Page:   int32(page),
+		Limit:  int32(limit),
+	}
+
+	h.logger.Info(ctx, "ListAuditEvents completed successfully", map[string]any{
+		"count": len(events),
+	})
+
+	return response, nil
+}
+
+// RevokeAllUserTokens handles revoking every token of a user, signing them out of all
+// sessions. Only administrators reach it; the security middleware rejects everyone else.
+func (h *AuthHandler) RevokeAllUserTokens(ctx context.Context, req *proto.RevokeAllUserTokensRequest) (*proto.Empty, error) {
+	h.logger.Info(ctx, "Processing RevokeAllUserTokens request", map[string]any{
+		"user_id": req.UserId,
+	})
+
+	userID, err := uuid.Parse(req.UserId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid user_id")
+	}
+
+	// Call service
+	revoked, err := h.service.Auth.RevokeAllUserTokens(ctx, userID)
+	if err != nil {
+		h.logger.Error(ctx, err, "RevokeAllUserTokens failed", 500)
+		return nil, status.Error(codes.Internal, "token revocation failed")
+	}
+
+	h.logger.Info(ctx, "RevokeAllUserTokens completed successfully", map[string]any{
+		"user_id": req.UserId,
+		"revoked": revoked,
+	})
+
+	return &proto.Empty{}, nil
+}
+
 // ValidateToken handles token validation
 func (h *AuthHandler) ValidateToken(ctx context.Context, req *proto.ValidateTokenRequest) (*proto.ValidateTokenResponse, error) {
 	h.logger.Info(ctx, "Processing ValidateToken request")
 
 	// Call service with JWT secret
-	user, err := h.service.Auth.ValidateToken(ctx, req.Token, h.service.Config.JWTAccessTokenSecret)
+	user, err := h.service.Auth.ValidateToken(ctx, req.Token, h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTPreviousSecrets...)
 	if err != nil {
 		h.logger.Error(ctx, err, "ValidateToken failed", 401)
 		return &proto.ValidateTokenResponse{
@@ -237,6 +463,42 @@ func (h *AuthHandler) ValidateToken(ctx context.Context, req *proto.ValidateToke
 
 // Helper functions to convert between internal models and protobuf messages
 
+// authenticatedUser returns the owner of the bearer token sent with the call
+func (h *AuthHandler) authenticatedUser(ctx context.Context) (*models.User, error) {
+	token := bearerToken(ctx)
+	if token == "" {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+
+	user, err := h.service.Auth.ValidateToken(ctx, token, h.service.Config.JWTAccessTokenSecret, h.service.Config.JWTPreviousSecrets...)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	return user, nil
+}
+
+// bearerToken returns the bearer token in the request metadata, or an empty string
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 || !strings.HasPrefix(tokens[0], "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(tokens[0], "Bearer ")
+}
+
+// clientInfo describes the caller for the session its tokens are issued in
+func clientInfo(ctx context.Context) models.ClientInfo {
+	return models.ClientInfo{
+		UserAgent: middleware.UserAgent(ctx),
+		IPAddress: middleware.ClientIPFromContext(ctx),
+	}
+}
+
 func convertUserToProto(user *models.User) *proto.User {
 	return &proto.User{
 		Id:        user.ID.String(),
@@ -247,6 +509,31 @@ func convertUserToProto(user *models.User) *proto.User {
 	}
 }
 
+func convertAuditEventToProto(event *models.AuditEvent) *proto.AuditEvent {
+	protoEvent := &proto.AuditEvent{
+		Id:        event.ID.String(),
+		EventType: event.EventType,
+		Method:    event.Method,
+		IpAddress: event.IPAddress,
+		UserAgent: event.UserAgent,
+		CreatedAt: timestamppb.New(event.CreatedAt),
+	}
+	if event.UserID != nil {
+		protoEvent.UserId = event.UserID.String()
+	}
+	return protoEvent
+}
+
+func convertSessionToProto(session *models.Session) *proto.Session {
+	return &proto.Session{
+		Id:         session.ID.String(),
+		UserAgent:  session.UserAgent,
+		IpAddress:  session.IPAddress,
+		CreatedAt:  timestamppb.New(session.CreatedAt),
+		LastSeenAt: timestamppb.New(session.LastSeenAt),
+	}
+}
+
 func convertUserTokenToProto(token *models.UserToken) *proto.UserToken {
 	return &proto.UserToken{
 		Id:               token.ID.String(),
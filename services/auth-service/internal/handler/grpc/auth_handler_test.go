@@ -1,17 +1,32 @@
 package grpc
 
 import (
+	"context"
+	"io"
+	"net"
 	"testing"
 	"time"
 
+	"api/auth/v1/proto"
 	"auth-service/config"
+	"auth-service/internal/repository"
 	"auth-service/internal/services"
+	"auth-service/internal/transport/middleware"
 	"auth-service/models"
+	"auth-service/utils"
 
 	zlog "packages/logger"
 
+	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
 )
 
 func TestNewAuthHandler(t *testing.T) {
@@ -125,6 +140,239 @@ func TestConvertUserTokenToProto_EdgeCases(t *testing.T) {
 	assert.NotNil(t, protoToken.CreatedAt)
 }
 
+func TestConvertAuditEventToProto(t *testing.T) {
+	userID := uuid.New()
+	event := &models.AuditEvent{
+		ID:        uuid.New(),
+		UserID:    &userID,
+		EventType: "sign_in",
+		Method:    "/auth.AuthService/SignIn",
+		IPAddress: "203.0.113.7",
+		UserAgent: "test-client/1.0",
+		CreatedAt: time.Now(),
+	}
+
+	protoEvent := convertAuditEventToProto(event)
+
+	assert.Equal(t, event.ID.String(), protoEvent.Id)
+	assert.Equal(t, userID.String(), protoEvent.UserId)
+	assert.Equal(t, "sign_in", protoEvent.EventType)
+	assert.Equal(t, "/auth.AuthService/SignIn", protoEvent.Method)
+	assert.Equal(t, "203.0.113.7", protoEvent.IpAddress)
+	assert.Equal(t, "test-client/1.0", protoEvent.UserAgent)
+
+	// Events for unknown users, such as failed sign ins, have no user ID
+	event.UserID = nil
+	assert.Empty(t, convertAuditEventToProto(event).UserId)
+}
+
+func TestAuthHandler_ListAuditEvents(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	handler := NewAuthHandler(services.NewService(db, logger, &config.Config{}), logger)
+
+	userID := uuid.New()
+	mock.ExpectPrepare("FROM audit_events").
+		ExpectQuery().
+		WithArgs(userID, userID, "sign_in", "sign_in", 2, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "event_type", "method", "ip_address", "user_agent", "created_at"}).
+			AddRow(uuid.New(), userID, "sign_in", "/auth.AuthService/SignIn", "203.0.113.7", "test-client/1.0", time.Now()))
+	mock.ExpectPrepare("SELECT COUNT").
+		ExpectQuery().
+		WithArgs(userID, userID, "sign_in", "sign_in").
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+
+	resp, err := handler.ListAuditEvents(context.Background(), &proto.ListAuditEventsRequest{
+		Page:      2,
+		Limit:     2,
+		UserId:    userID.String(),
+		EventType: "sign_in",
+	})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, resp.Events, 1)
+	assert.Equal(t, userID.String(), resp.Events[0].UserId)
+	assert.Equal(t, int32(3), resp.Total)
+	assert.Equal(t, int32(2), resp.Page)
+	assert.Equal(t, int32(2), resp.Limit)
+}
+
+func TestAuthHandler_ListAuditEvents_InvalidUserID(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	handler := NewAuthHandler(&services.Service{}, logger)
+
+	_, err := handler.ListAuditEvents(context.Background(), &proto.ListAuditEventsRequest{UserId: "not-a-uuid"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestAuthHandler_SignIn_LockedReturnsResourceExhausted(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	handler := NewAuthHandler(services.NewService(db, logger, &config.Config{
+		LoginLockoutThreshold: 5,
+		LoginLockoutWindow:    15,
+		LoginLockoutCooldown:  15,
+	}), logger)
+
+	mock.ExpectPrepare("FROM login_attempts").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(time.Now().Add(time.Minute)))
+
+	_, err = handler.SignIn(context.Background(), &proto.Credentials{Email: "test@example.com", Password: "Password123"})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_SignUp_DuplicateEmailReturnsAlreadyExists(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	handler := NewAuthHandler(services.NewService(db, logger, &config.Config{
+		BcryptCost:            bcrypt.MinCost,
+		JWTAccessTokenSecret:  "test-access-secret",
+		JWTRefreshTokenSecret: "test-refresh-secret",
+	}), logger)
+
+	userColumns := []string{"id", "name", "email", "password", "created_at", "updated_at"}
+	userID := uuid.New()
+	now := time.Now()
+	mock.ExpectPrepare("FROM users").ExpectQuery().WillReturnRows(sqlmock.NewRows(userColumns))
+	mock.ExpectPrepare("INSERT INTO users").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "created_at", "updated_at"}).
+			AddRow(userID, "Test User", "test@example.com", now, now))
+	mock.ExpectPrepare("INSERT INTO refresh_token_families").ExpectExec().WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows(userColumns).AddRow(userID, "Test User", "test@example.com", "hash", now, now))
+
+	req := &proto.UserCreateRequest{Name: "Test User", Email: "test@example.com", Password: "Password123"}
+	resp, err := handler.SignUp(context.Background(), req)
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), resp.User.Id)
+
+	_, err = handler.SignUp(context.Background(), req)
+
+	assert.Equal(t, codes.AlreadyExists, status.Code(err))
+	assert.Equal(t, "a user with this email already exists", status.Convert(err).Message())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_ListSessions(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	cfg := &config.Config{JWTAccessTokenSecret: "test-access-secret"}
+	handler := NewAuthHandler(services.NewService(db, logger, cfg), logger)
+
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+	accessToken, err := utils.GenerateAccessToken(user, cfg.JWTAccessTokenSecret, utils.TokenScope{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	sessionID := uuid.New()
+	mock.ExpectPrepare("FROM user_tokens").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "user_id", "access_token", "refresh_token", "access_expires_at",
+			"refresh_expires_at", "is_revoked", "family_id", "created_at",
+		}).AddRow(uuid.New(), user.ID, accessToken, "refresh-token", now.Add(time.Minute), now.Add(time.Hour), false, sessionID, now))
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}).
+			AddRow(user.ID, user.Name, user.Email, "hash", now, now))
+	mock.ExpectPrepare("FROM sessions").
+		ExpectQuery().
+		WithArgs(user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "user_agent", "ip_address", "created_at", "last_seen_at"}).
+			AddRow(sessionID, user.ID, "test-client/1.0", "203.0.113.7", now, now))
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+accessToken))
+	resp, err := handler.ListSessions(ctx, &proto.ListSessionsRequest{})
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, resp.Sessions, 1)
+	assert.Equal(t, sessionID.String(), resp.Sessions[0].Id)
+	assert.Equal(t, "test-client/1.0", resp.Sessions[0].UserAgent)
+	assert.Equal(t, "203.0.113.7", resp.Sessions[0].IpAddress)
+}
+
+func TestAuthHandler_RevokeSession_Errors(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	handler := NewAuthHandler(&services.Service{}, logger)
+
+	_, err := handler.RevokeSession(context.Background(), &proto.RevokeSessionRequest{SessionId: "not-a-uuid"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = handler.RevokeSession(context.Background(), &proto.RevokeSessionRequest{SessionId: uuid.NewString()})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthHandler_RevokeAllUserTokens(t *testing.T) {
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer mockDB.Close()
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	handler := NewAuthHandler(services.NewService(db, logger, &config.Config{}), logger)
+
+	userID := uuid.New()
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE refresh_token_families").
+		ExpectExec().
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, err = handler.RevokeAllUserTokens(context.Background(), &proto.RevokeAllUserTokensRequest{UserId: userID.String()})
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthHandler_RevokeAllUserTokens_InvalidUserID(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	handler := NewAuthHandler(&services.Service{}, logger)
+
+	_, err := handler.RevokeAllUserTokens(context.Background(), &proto.RevokeAllUserTokensRequest{UserId: "not-a-uuid"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestClientInfo_UsesClientIPResolvedByMiddleware(t *testing.T) {
+	addr, err := net.ResolveTCPAddr("tcp", "127.0.0.1:51000")
+	require.NoError(t, err)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	ctx = metadata.NewIncomingContext(ctx, metadata.MD{"x-forwarded-for": []string{"10.0.0.1"}})
+	ctx = middleware.WithClientIP(ctx, "198.51.100.1")
+
+	assert.Equal(t, "198.51.100.1", clientInfo(ctx).IPAddress)
+	assert.Empty(t, clientInfo(context.Background()).IPAddress)
+}
+
 func TestAuthHandler_MethodSignatures(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
 	service := &services.Service{}
@@ -151,6 +399,9 @@ func TestAuthHandler_MethodSignatures(t *testing.T) {
 	// Test ListUsers method exists
 	_ = handler.ListUsers
 
+	// Test ListAuditEvents method exists
+	_ = handler.ListAuditEvents
+
 	// If we get here, all methods exist
 	assert.True(t, true)
 }
@@ -0,0 +1,25 @@
+package http
+
+import (
+	"net/http"
+
+	zlog "packages/logger"
+	"packages/middleware"
+)
+
+// withRequestID tags every REST request with a request ID, used as the correlation ID of
+// its logs, and echoes it in the X-Request-ID response header. A well-formed ID sent by
+// the client is kept so its logs line up with ours; otherwise a new one is generated.
+func withRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID := r.Header.Get(middleware.RequestIDHeader)
+		if !middleware.ValidRequestID(requestID) {
+			requestID = ""
+		}
+
+		ctx := zlog.WithCorrelationID(r.Context(), requestID)
+		w.Header().Set(middleware.RequestIDHeader, zlog.CorrelationID(ctx))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
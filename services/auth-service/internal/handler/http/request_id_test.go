@@ -0,0 +1,48 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithRequestID(t *testing.T) {
+	tests := []struct {
+		name     string
+		header   string
+		wantKept bool
+	}{
+		{name: "client id echoed", header: "support-ticket-42", wantKept: true},
+		{name: "missing id generated", header: ""},
+		{name: "unsafe id replaced", header: "bad id\r\nx"},
+		{name: "long id replaced", header: strings.Repeat("a", middleware.MaxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := withRequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = zlog.CorrelationID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/v1/auth/signin", nil)
+			req.Header.Set(middleware.RequestIDHeader, tt.header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, seen)
+			assert.Equal(t, seen, rec.Header().Get(middleware.RequestIDHeader))
+			if tt.wantKept {
+				assert.Equal(t, tt.header, seen)
+			} else {
+				assert.NotEqual(t, tt.header, seen)
+			}
+		})
+	}
+}
@@ -7,20 +7,24 @@ import (
 	"fmt"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"api/auth/v1/proto"
 	"auth-service/internal/config"
 	"auth-service/internal/transport/errors"
 
+	"packages/grpcutil"
 	zlog "packages/logger"
+	"packages/middleware"
 
 	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/connectivity"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/status"
 	"google.golang.org/protobuf/encoding/protojson"
 )
@@ -35,6 +39,7 @@ type RESTGateway struct {
 	grpcAddr    string
 	tlsEnabled  bool
 	tlsConfig   any
+	metrics     http.Handler
 }
 
 // NewRESTGateway creates a new REST gateway instance
@@ -46,6 +51,12 @@ func NewRESTGateway(cfg *config.GatewayConfig, logger *zlog.Logger) *RESTGateway
 	}
 }
 
+// SetMetricsHandler sets the handler served on /metrics. It must be called before
+// CreateGateway; without it the endpoint is not registered.
+func (g *RESTGateway) SetMetricsHandler(handler http.Handler) {
+	g.metrics = handler
+}
+
 // CreateGateway creates the REST gateway server and listener
 func (g *RESTGateway) CreateGateway(ctx context.Context, grpcAddr string, tlsEnabled bool, tlsConfig any) error {
 	// Create REST listener
@@ -79,6 +90,7 @@ func (g *RESTGateway) CreateGateway(ctx context.Context, grpcAddr string, tlsEna
 			},
 		}),
 		runtime.WithErrorHandler(g.createErrorHandler()),
+		runtime.WithMetadata(middleware.GatewayRequestIDMetadata),
 	)
 
 	// Create custom HTTP mux to wrap gRPC gateway
@@ -87,6 +99,11 @@ func (g *RESTGateway) CreateGateway(ctx context.Context, grpcAddr string, tlsEna
 	// Register custom health endpoints
 	g.registerCustomHealthEndpoints(customMux)
 
+	// Expose Prometheus metrics
+	if g.metrics != nil {
+		customMux.Handle("/metrics", g.metrics)
+	}
+
 	// Register gRPC gateway handlers
 	if err := g.registerHandlers(ctx, gwMux); err != nil {
 		return fmt.Errorf("failed to register REST handlers: %w", err)
@@ -95,9 +112,14 @@ func (g *RESTGateway) CreateGateway(ctx context.Context, grpcAddr string, tlsEna
 	// Mount gRPC gateway under the custom mux
 	customMux.Handle("/", gwMux)
 
+	var handler http.Handler = customMux
+	if g.config.CompressionEnabled {
+		handler = grpcutil.CompressResponses(handler, g.config.CompressionMinBytes)
+	}
+
 	// Create HTTP server with proper timeout configurations
 	g.server = &http.Server{
-		Handler:           g.createMiddleware(customMux),
+		Handler:           withRequestID(g.createTracingMiddleware(g.createMiddleware(handler))),
 		Addr:              restLis.Addr().String(),
 		ReadTimeout:       30 * time.Second,
 		WriteTimeout:      30 * time.Second,
@@ -114,43 +136,30 @@ func (g *RESTGateway) CreateGateway(ctx context.Context, grpcAddr string, tlsEna
 			"/v1/health/service-info",
 			"/health",
 			"/v1/health",
+			"/metrics",
 		},
 	})
 
 	return nil
 }
 
-// createDialOptions creates gRPC dial options
+// createDialOptions creates gRPC dial options. Retries stay disabled (MaxRetryAttempts is
+// left at 0) to avoid conflicts with the gateway's own retry logic.
 func (g *RESTGateway) createDialOptions(tlsEnabled bool, tlsConfig any) []grpc.DialOption {
-	var dialOptions []grpc.DialOption
+	dialCfg := grpcutil.DefaultConfig()
 
 	// Configure TLS if enabled
 	if tlsEnabled {
+		clientTLS := &tls.Config{}
 		if tlsCfg, ok := tlsConfig.(*config.TLSConfig); ok {
-			dialOptions = append(dialOptions, grpc.WithTransportCredentials(credentials.NewTLS(&tls.Config{
-				MinVersion: tlsCfg.MinVersion,
-				MaxVersion: tlsCfg.MaxVersion,
-			})))
+			clientTLS.MinVersion = tlsCfg.MinVersion
+			clientTLS.MaxVersion = tlsCfg.MaxVersion
 		}
-	} else {
-		dialOptions = append(dialOptions, grpc.WithInsecure())
+		dialCfg.Credentials = credentials.NewTLS(clientTLS)
 	}
 
-	// Simplified connection options for gRPC gateway
-	dialOptions = append(dialOptions,
-		// Basic load balancing
-		grpc.WithDefaultServiceConfig(`{"loadBalancingPolicy":"round_robin"}`),
-		// Keep connections alive but be more conservative
-		grpc.WithKeepaliveParams(keepalive.ClientParameters{
-			Time:                60 * time.Second, // Longer interval for gateway
-			Timeout:             10 * time.Second, // Longer timeout
-			PermitWithoutStream: false,            // More conservative
-		}),
-		// Disable retry to avoid conflicts with gateway's own retry logic
-		grpc.WithDisableRetry(),
-	)
-
-	return dialOptions
+	// Carry the REST request's trace over to the gRPC server
+	return append(grpcutil.DialOptions(dialCfg), grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
 }
 
 // createErrorHandler creates a custom error handler
@@ -575,13 +584,21 @@ func (g *RESTGateway) createMiddleware(handler http.Handler) http.Handler {
 		if origin != "" && g.isAllowedOrigin(origin) {
 			w.Header().Set("Access-Control-Allow-Origin", origin)
 		}
-		w.Header().Set("Access-Control-Allow-Methods", g.joinStrings(g.config.AllowedMethods, ", "))
-		w.Header().Set("Access-Control-Allow-Headers", g.joinStrings(g.config.AllowedHeaders, ", "))
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
-		w.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", g.config.MaxAge))
 
 		// Handle preflight requests
 		if r.Method == "OPTIONS" {
+			if requestedMethod := r.Header.Get("Access-Control-Request-Method"); requestedMethod != "" {
+				if !g.isAllowedMethod(requestedMethod) {
+					w.WriteHeader(http.StatusForbidden)
+					return
+				}
+				w.Header().Set("Access-Control-Allow-Methods", g.joinStrings(g.config.AllowedMethods, ", "))
+				if headers := g.allowedRequestHeaders(r.Header.Get("Access-Control-Request-Headers")); len(headers) > 0 {
+					w.Header().Set("Access-Control-Allow-Headers", g.joinStrings(headers, ", "))
+				}
+				w.Header().Set("Access-Control-Max-Age", fmt.Sprintf("%d", g.config.MaxAge))
+			}
 			w.WriteHeader(http.StatusOK)
 			return
 		}
@@ -607,6 +624,19 @@ func (g *RESTGateway) createMiddleware(handler http.Handler) http.Handler {
 	})
 }
 
+// createTracingMiddleware starts a server span for every REST request, continuing the
+// caller's trace when a traceparent header is sent. Health probes are not traced.
+func (g *RESTGateway) createTracingMiddleware(handler http.Handler) http.Handler {
+	return otelhttp.NewHandler(handler, "rest",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !strings.HasPrefix(r.URL.Path, "/v1/health") && r.URL.Path != "/health"
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
+
 // isAllowedOrigin checks if an origin is in the allowed origins list
 func (g *RESTGateway) isAllowedOrigin(origin string) bool {
 	for _, allowed := range g.config.AllowedOrigins {
@@ -617,6 +647,31 @@ func (g *RESTGateway) isAllowedOrigin(origin string) bool {
 	return false
 }
 
+// isAllowedMethod checks if a preflight's requested method is in the allowed methods list
+func (g *RESTGateway) isAllowedMethod(method string) bool {
+	for _, allowed := range g.config.AllowedMethods {
+		if strings.EqualFold(allowed, method) {
+			return true
+		}
+	}
+	return false
+}
+
+// allowedRequestHeaders returns the allowed headers named in a preflight's
+// Access-Control-Request-Headers value, spelled as configured
+func (g *RESTGateway) allowedRequestHeaders(requested string) []string {
+	var headers []string
+	for _, allowed := range g.config.AllowedHeaders {
+		for _, header := range strings.Split(requested, ",") {
+			if strings.EqualFold(strings.TrimSpace(header), allowed) {
+				headers = append(headers, allowed)
+				break
+			}
+		}
+	}
+	return headers
+}
+
 // joinStrings joins a slice of strings with a separator
 func (g *RESTGateway) joinStrings(strs []string, sep string) string {
 	if len(strs) == 0 {
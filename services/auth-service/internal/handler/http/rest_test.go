@@ -0,0 +1,90 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"auth-service/internal/config"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestErrorHandler_AlreadyExistsIsConflict(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	gateway := NewRESTGateway(&config.GatewayConfig{}, logger)
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/auth/signup", nil)
+	rec := httptest.NewRecorder()
+	gateway.createErrorHandler()(context.Background(), nil, nil, rec, req,
+		status.Error(codes.AlreadyExists, "a user with this email already exists"))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+	assert.Equal(t, float64(http.StatusConflict), body["status_code"])
+}
+
+func newCORSTestGateway() *RESTGateway {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	return NewRESTGateway(&config.GatewayConfig{
+		AllowedOrigins: []string{"http://localhost:3000"},
+		AllowedMethods: []string{"GET", "POST"},
+		AllowedHeaders: []string{"Content-Type", "Authorization"},
+		MaxAge:         600,
+	}, logger)
+}
+
+func preflight(t *testing.T, gateway *RESTGateway, method, headers string) *httptest.ResponseRecorder {
+	t.Helper()
+	handler := gateway.createMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("preflight reached the handler")
+	}))
+
+	req := httptest.NewRequest(http.MethodOptions, "/v1/auth/signin", nil)
+	req.Header.Set("Origin", "http://localhost:3000")
+	req.Header.Set("Access-Control-Request-Method", method)
+	if headers != "" {
+		req.Header.Set("Access-Control-Request-Headers", headers)
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestCORSMiddleware_AllowedPreflight(t *testing.T) {
+	rec := preflight(t, newCORSTestGateway(), "POST", "content-type")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "http://localhost:3000", rec.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "GET, POST", rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Content-Type", rec.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", rec.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORSMiddleware_DisallowedMethod(t *testing.T) {
+	rec := preflight(t, newCORSTestGateway(), "DELETE", "")
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Methods"))
+	assert.Empty(t, rec.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORSMiddleware_HeaderIntersection(t *testing.T) {
+	gateway := newCORSTestGateway()
+
+	rec := preflight(t, gateway, "GET", "authorization, X-Custom-Header, Content-Type")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "Content-Type, Authorization", rec.Header().Get("Access-Control-Allow-Headers"))
+
+	rec = preflight(t, gateway, "GET", "X-Custom-Header")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Empty(t, rec.Header().Get("Access-Control-Allow-Headers"))
+}
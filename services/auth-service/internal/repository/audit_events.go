@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+
+	"auth-service/models"
+)
+
+const (
+	insertAuditEventQuery = `
+		INSERT INTO audit_events (
+			user_id,
+			event_type,
+			method,
+			ip_address,
+			user_agent,
+			created_at
+		) VALUES (
+			:user_id,
+			:event_type,
+			:method,
+			:ip_address,
+			:user_agent,
+			:created_at
+		)
+	`
+
+	// Empty filters match every event
+	listAuditEventsQuery = `
+		SELECT
+			id,
+			user_id,
+			event_type,
+			method,
+			ip_address,
+			user_agent,
+			created_at
+		FROM audit_events
+		WHERE (CAST(:user_id AS UUID) IS NULL OR user_id = :user_id)
+			AND (:event_type = '' OR event_type = :event_type)
+		ORDER BY created_at DESC
+		LIMIT :limit OFFSET :offset
+	`
+
+	countAuditEventsQuery = `
+		SELECT COUNT(*)
+		FROM audit_events
+		WHERE (CAST(:user_id AS UUID) IS NULL OR user_id = :user_id)
+			AND (:event_type = '' OR event_type = :event_type)
+	`
+)
+
+// CreateAuditEvent stores an audit event
+func (db *DB) CreateAuditEvent(ctx context.Context, event *models.AuditEvent) error {
+	params := map[string]any{
+		"user_id":    event.UserID,
+		"event_type": event.EventType,
+		"method":     event.Method,
+		"ip_address": event.IPAddress,
+		"user_agent": event.UserAgent,
+		"created_at": event.CreatedAt,
+	}
+
+	if _, err := execNamed(ctx, db, insertAuditEventQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "insert audit event failed", status)
+		return mappedErr
+	}
+
+	return nil
+}
+
+// ListAuditEvents retrieves audit events matching filter, newest first
+func (db *DB) ListAuditEvents(ctx context.Context, filter models.AuditEventFilter, limit, offset int) ([]models.AuditEvent, error) {
+	params := auditEventFilterParams(filter)
+	params["limit"] = limit
+	params["offset"] = offset
+
+	stmt, err := db.PrepareNamedContext(ctx, listAuditEventsQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare list audit events failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	events := []models.AuditEvent{}
+	if err := stmt.SelectContext(ctx, &events, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "list audit events failed", status)
+		return nil, mappedErr
+	}
+
+	return events, nil
+}
+
+// CountAuditEvents returns the number of audit events matching filter
+func (db *DB) CountAuditEvents(ctx context.Context, filter models.AuditEventFilter) (int, error) {
+	stmt, err := db.PrepareNamedContext(ctx, countAuditEventsQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare count audit events failed", http.StatusInternalServerError)
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var count int
+	if err := stmt.GetContext(ctx, &count, auditEventFilterParams(filter)); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "count audit events failed", status)
+		return 0, mappedErr
+	}
+
+	return count, nil
+}
+
+// auditEventFilterParams builds the named parameters shared by the list and count queries
+func auditEventFilterParams(filter models.AuditEventFilter) map[string]any {
+	params := map[string]any{
+		"user_id":    nil,
+		"event_type": filter.EventType,
+	}
+	if filter.UserID != nil {
+		params["user_id"] = *filter.UserID
+	}
+	return params
+}
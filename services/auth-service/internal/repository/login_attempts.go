@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	getLoginLockedUntilQuery = `
+		SELECT MAX(locked_until)
+		FROM login_attempts
+		WHERE attempt_key = ANY(:attempt_keys) AND locked_until > :now
+	`
+
+	// A failure outside the current window starts a new one instead of adding to it
+	recordLoginFailureQuery = `
+		INSERT INTO login_attempts (
+			attempt_key,
+			failures,
+			window_started_at,
+			updated_at
+		) VALUES (
+			:attempt_key,
+			1,
+			:now,
+			:now
+		)
+		ON CONFLICT (attempt_key) DO UPDATE SET
+			failures = CASE
+				WHEN login_attempts.window_started_at > :window_start THEN login_attempts.failures + 1
+				ELSE 1
+			END,
+			window_started_at = CASE
+				WHEN login_attempts.window_started_at > :window_start THEN login_attempts.window_started_at
+				ELSE :now
+			END,
+			updated_at = :now
+		RETURNING failures
+	`
+
+	// Locking starts a fresh count, so the cooldown is followed by a full set of attempts
+	lockLoginQuery = `
+		UPDATE login_attempts
+		SET locked_until = :locked_until, failures = 0, window_started_at = :now, updated_at = :now
+		WHERE attempt_key = :attempt_key
+	`
+
+	clearLoginAttemptsQuery = `
+		DELETE FROM login_attempts WHERE attempt_key = :attempt_key
+	`
+)
+
+// GetLoginLockedUntil returns when the latest active lock on any of keys ends, or nil when
+// none of them is locked at now
+func (db *DB) GetLoginLockedUntil(ctx context.Context, keys []string, now time.Time) (*time.Time, error) {
+	params := map[string]any{
+		"attempt_keys": pq.Array(keys),
+		"now":          now,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, getLoginLockedUntilQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare select login lock failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var lockedUntil *time.Time
+	if err := stmt.GetContext(ctx, &lockedUntil, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "select login lock failed", status)
+		return nil, mappedErr
+	}
+
+	return lockedUntil, nil
+}
+
+// RecordLoginFailure counts a failed sign in against key and returns the number of failures
+// since windowStart. Failures from before windowStart are forgotten.
+func (db *DB) RecordLoginFailure(ctx context.Context, key string, now, windowStart time.Time) (int, error) {
+	params := map[string]any{
+		"attempt_key":  key,
+		"now":          now,
+		"window_start": windowStart,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, recordLoginFailureQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare record login failure failed", http.StatusInternalServerError)
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var failures int
+	if err := stmt.GetContext(ctx, &failures, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "record login failure failed", status)
+		return 0, mappedErr
+	}
+
+	return failures, nil
+}
+
+// LockLogin refuses sign ins for key until lockedUntil
+func (db *DB) LockLogin(ctx context.Context, key string, now, lockedUntil time.Time) error {
+	params := map[string]any{
+		"attempt_key":  key,
+		"now":          now,
+		"locked_until": lockedUntil,
+	}
+
+	if _, err := execNamed(ctx, db, lockLoginQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "lock login failed", status)
+		return mappedErr
+	}
+
+	db.logger.Warn(ctx, "sign in locked after repeated failures", map[string]any{
+		"attempt_key":  key,
+		"locked_until": lockedUntil,
+	})
+
+	return nil
+}
+
+// ClearLoginAttempts forgets the failed sign ins counted against key
+func (db *DB) ClearLoginAttempts(ctx context.Context, key string) error {
+	params := map[string]any{
+		"attempt_key": key,
+	}
+
+	if _, err := execNamed(ctx, db, clearLoginAttemptsQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "clear login attempts failed", status)
+		return mappedErr
+	}
+
+	return nil
+}
@@ -0,0 +1,30 @@
+-- +goose Up
+-- Create refresh_token_families table. A family is started at sign in and follows the
+-- refresh token through every rotation, so reuse of an old token can revoke the chain.
+CREATE TABLE IF NOT EXISTS refresh_token_families (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Hashes of refresh tokens that have already been exchanged for a new pair
+CREATE TABLE IF NOT EXISTS consumed_refresh_tokens (
+    token_hash TEXT PRIMARY KEY,
+    family_id UUID NOT NULL REFERENCES refresh_token_families(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    consumed_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_consumed_refresh_tokens_family_id ON consumed_refresh_tokens(family_id);
+
+-- Tokens issued before this migration have no family until their first rotation
+ALTER TABLE user_tokens ADD COLUMN IF NOT EXISTS family_id UUID REFERENCES refresh_token_families(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_user_tokens_family_id ON user_tokens(family_id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+ALTER TABLE user_tokens DROP COLUMN IF EXISTS family_id;
+DROP TABLE IF EXISTS consumed_refresh_tokens;
+DROP TABLE IF EXISTS refresh_token_families;
@@ -0,0 +1,19 @@
+-- +goose Up
+-- Create audit_events table for security-relevant calls such as sign in and token
+-- revocation. user_id is kept when the user is deleted so the trail survives.
+CREATE TABLE IF NOT EXISTS audit_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    event_type TEXT NOT NULL,
+    method TEXT NOT NULL,
+    ip_address TEXT NOT NULL DEFAULT '',
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_events_user_id ON audit_events(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_events_event_type ON audit_events(event_type, created_at DESC);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS audit_events;
@@ -0,0 +1,23 @@
+-- +goose Up
+-- Create sessions table describing the client each refresh token family was issued to.
+-- A session shares its id with its family, whose revoked_at also ends the session.
+CREATE TABLE IF NOT EXISTS sessions (
+    id UUID PRIMARY KEY REFERENCES refresh_token_families(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_agent TEXT NOT NULL DEFAULT '',
+    ip_address TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    last_seen_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_user_id ON sessions(user_id);
+
+-- Families started before this migration get a session without client details
+INSERT INTO sessions (id, user_id, created_at, last_seen_at)
+SELECT id, user_id, created_at, created_at
+FROM refresh_token_families
+ON CONFLICT (id) DO NOTHING;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"auth-service/models"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrPasswordResetNotFound is returned when no reset matches a token hash
+	ErrPasswordResetNotFound = errors.New("password reset not found")
+	// ErrPasswordResetConsumed is returned when a reset token has already been used
+	ErrPasswordResetConsumed = errors.New("password reset already used")
+)
+
+const (
+	insertPasswordResetQuery = `
+		INSERT INTO password_resets (
+			user_id,
+			token_hash,
+			expires_at
+		) VALUES (
+			:user_id,
+			:token_hash,
+			:expires_at
+		)
+	`
+
+	getPasswordResetByTokenHashQuery = `
+		SELECT
+			id,
+			user_id,
+			token_hash,
+			expires_at,
+			used_at,
+			created_at
+		FROM password_resets
+		WHERE token_hash = :token_hash
+	`
+
+	consumePasswordResetQuery = `
+		UPDATE password_resets
+		SET used_at = :used_at
+		WHERE id = :id AND used_at IS NULL
+	`
+
+	updateUserPasswordQuery = `
+		UPDATE users
+		SET password = :password, updated_at = :updated_at
+		WHERE id = :user_id
+	`
+
+	// Any other reset links sent to the user stop working once one has been used
+	consumeUserPasswordResetsQuery = `
+		UPDATE password_resets
+		SET used_at = :updated_at
+		WHERE user_id = :user_id AND used_at IS NULL
+	`
+
+	revokeUserTokensQuery = `
+		UPDATE user_tokens
+		SET is_revoked = true
+		WHERE user_id = :user_id AND is_revoked = false
+	`
+)
+
+// CreatePasswordReset stores the hash of a new password reset token for a user
+func (db *DB) CreatePasswordReset(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time) error {
+	params := map[string]any{
+		"user_id":    userID,
+		"token_hash": tokenHash,
+		"expires_at": expiresAt,
+	}
+
+	if _, err := execNamed(ctx, db, insertPasswordResetQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "insert password reset failed", status)
+		return mappedErr
+	}
+
+	db.logger.Info(ctx, "password reset created successfully", map[string]any{
+		"user_id":    userID,
+		"expires_at": expiresAt,
+	})
+
+	return nil
+}
+
+// GetPasswordResetByTokenHash retrieves a password reset by the hash of its token
+func (db *DB) GetPasswordResetByTokenHash(ctx context.Context, tokenHash string) (*models.PasswordReset, error) {
+	params := map[string]any{
+		"token_hash": tokenHash,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, getPasswordResetByTokenHashQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare select password reset failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var reset models.PasswordReset
+	if err := stmt.GetContext(ctx, &reset, params); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, ErrPasswordResetNotFound
+		}
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "select password reset failed", status)
+		return nil, mappedErr
+	}
+
+	return &reset, nil
+}
+
+// CompletePasswordReset consumes a password reset, sets the user's new password hash and
+// revokes every session of the user, all in one transaction. It returns
+// ErrPasswordResetConsumed when the reset was used concurrently.
+func (db *DB) CompletePasswordReset(ctx context.Context, reset *models.PasswordReset, passwordHash string) error {
+	now := time.Now().UTC()
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin password reset failed", http.StatusInternalServerError)
+		return err
+	}
+	defer tx.Rollback()
+
+	consumed, err := execNamed(ctx, tx, consumePasswordResetQuery, map[string]any{
+		"id":      reset.ID,
+		"used_at": now,
+	})
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "consume password reset failed", status)
+		return mappedErr
+	}
+	if consumed == 0 {
+		return ErrPasswordResetConsumed
+	}
+
+	params := map[string]any{
+		"user_id":    reset.UserID,
+		"password":   passwordHash,
+		"updated_at": now,
+	}
+	for _, query := range []string{updateUserPasswordQuery, consumeUserPasswordResetsQuery, revokeUserTokensQuery} {
+		if _, err := execNamed(ctx, tx, query, params); err != nil {
+			status, mappedErr := HandlePgError(err)
+			db.logger.Error(ctx, mappedErr, "password reset failed", status)
+			return mappedErr
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit password reset failed", http.StatusInternalServerError)
+		return err
+	}
+
+	db.logger.Info(ctx, "password reset completed successfully", map[string]any{
+		"user_id": reset.UserID,
+	})
+
+	return nil
+}
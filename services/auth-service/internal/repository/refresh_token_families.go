@@ -0,0 +1,262 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	"auth-service/models"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrRefreshTokenConsumed is returned when a refresh token has already been exchanged
+var ErrRefreshTokenConsumed = errors.New("refresh token already consumed")
+
+const (
+	createTokenFamilyQuery = `
+		WITH family AS (
+			INSERT INTO refresh_token_families (user_id)
+			VALUES (:user_id)
+			RETURNING id
+		), session AS (
+			INSERT INTO sessions (id, user_id)
+			SELECT family.id, :user_id
+			FROM family
+		)
+		SELECT id FROM family
+	`
+
+	consumeRefreshTokenQuery = `
+		INSERT INTO consumed_refresh_tokens (
+			token_hash,
+			family_id,
+			expires_at
+		) VALUES (
+			:token_hash,
+			:family_id,
+			:expires_at
+		)
+		ON CONFLICT (token_hash) DO NOTHING
+	`
+
+	rotateTokensQuery = `
+		UPDATE user_tokens
+		SET access_token = :access_token,
+			refresh_token = :refresh_token,
+			access_expires_at = :access_expires_at,
+			refresh_expires_at = :refresh_expires_at,
+			family_id = :family_id,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = :id AND refresh_token = :old_refresh_token AND is_revoked = false
+	`
+
+	touchSessionQuery = `
+		UPDATE sessions
+		SET last_seen_at = CURRENT_TIMESTAMP,
+			user_agent = :user_agent,
+			ip_address = :ip_address
+		WHERE id = :family_id
+	`
+
+	getConsumedTokenFamilyQuery = `
+		SELECT family_id
+		FROM consumed_refresh_tokens
+		WHERE token_hash = :token_hash
+	`
+
+	revokeTokenFamilyQuery = `
+		UPDATE refresh_token_families
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = :family_id AND revoked_at IS NULL
+	`
+
+	revokeFamilyTokensQuery = `
+		UPDATE user_tokens
+		SET is_revoked = true
+		WHERE family_id = :family_id
+	`
+)
+
+// RotateRefreshToken replaces the tokens of a session with a new pair and records the hash
+// of the old refresh token as consumed, all in one transaction. The session is marked as
+// last seen from client. It returns ErrRefreshTokenConsumed when the old refresh token was
+// exchanged concurrently.
+func (db *DB) RotateRefreshToken(ctx context.Context, token *models.UserToken, client models.ClientInfo, oldTokenHash, accessToken, refreshToken string, accessExpiresAt, refreshExpiresAt time.Time) (*models.UserToken, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin rotate tokens failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Tokens issued before families existed join a new family on their first rotation
+	var familyID uuid.UUID
+	if token.FamilyID != nil {
+		familyID = *token.FamilyID
+	} else {
+		if familyID, err = createTokenFamily(ctx, tx, token.UserID); err != nil {
+			status, mappedErr := HandlePgError(err)
+			db.logger.Error(ctx, mappedErr, "create token family failed", status)
+			return nil, mappedErr
+		}
+	}
+
+	consumed, err := execNamed(ctx, tx, consumeRefreshTokenQuery, map[string]any{
+		"token_hash": oldTokenHash,
+		"family_id":  familyID,
+		"expires_at": token.RefreshExpiresAt,
+	})
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "consume refresh token failed", status)
+		return nil, mappedErr
+	}
+	if consumed == 0 {
+		return nil, ErrRefreshTokenConsumed
+	}
+
+	rotated, err := execNamed(ctx, tx, rotateTokensQuery, map[string]any{
+		"id":                 token.ID,
+		"old_refresh_token":  token.RefreshToken,
+		"access_token":       accessToken,
+		"refresh_token":      refreshToken,
+		"access_expires_at":  accessExpiresAt,
+		"refresh_expires_at": refreshExpiresAt,
+		"family_id":          familyID,
+	})
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "rotate tokens failed", status)
+		return nil, mappedErr
+	}
+	if rotated == 0 {
+		return nil, ErrRefreshTokenConsumed
+	}
+
+	if _, err := execNamed(ctx, tx, touchSessionQuery, map[string]any{
+		"family_id":  familyID,
+		"user_agent": client.UserAgent,
+		"ip_address": client.IPAddress,
+	}); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "touch session failed", status)
+		return nil, mappedErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit rotate tokens failed", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	db.logger.Info(ctx, "refresh token rotated successfully", map[string]any{
+		"token_id":  token.ID,
+		"family_id": familyID,
+	})
+
+	return &models.UserToken{
+		ID:               token.ID,
+		UserID:           token.UserID,
+		AccessToken:      accessToken,
+		RefreshToken:     refreshToken,
+		AccessExpiresAt:  accessExpiresAt,
+		RefreshExpiresAt: refreshExpiresAt,
+		IsRevoked:        false,
+		FamilyID:         &familyID,
+		CreatedAt:        token.CreatedAt,
+	}, nil
+}
+
+// GetConsumedTokenFamily returns the family of an already consumed refresh token. The
+// boolean is false when the token hash has not been consumed.
+func (db *DB) GetConsumedTokenFamily(ctx context.Context, tokenHash string) (uuid.UUID, bool, error) {
+	params := map[string]any{
+		"token_hash": tokenHash,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, getConsumedTokenFamilyQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare select consumed token failed", http.StatusInternalServerError)
+		return uuid.Nil, false, err
+	}
+	defer stmt.Close()
+
+	var familyID uuid.UUID
+	if err := stmt.GetContext(ctx, &familyID, params); err != nil {
+		if err == sql.ErrNoRows {
+			return uuid.Nil, false, nil
+		}
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "select consumed token failed", status)
+		return uuid.Nil, false, mappedErr
+	}
+
+	return familyID, true, nil
+}
+
+// RevokeTokenFamily marks a refresh token family and every token issued in it as revoked
+func (db *DB) RevokeTokenFamily(ctx context.Context, familyID uuid.UUID) error {
+	params := map[string]any{
+		"family_id": familyID,
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin revoke token family failed", http.StatusInternalServerError)
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{revokeTokenFamilyQuery, revokeFamilyTokensQuery} {
+		if _, err := execNamed(ctx, tx, query, params); err != nil {
+			status, mappedErr := HandlePgError(err)
+			db.logger.Error(ctx, mappedErr, "revoke token family failed", status)
+			return mappedErr
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit revoke token family failed", http.StatusInternalServerError)
+		return err
+	}
+
+	db.logger.Info(ctx, "token family revoked successfully", map[string]any{
+		"family_id": familyID,
+	})
+
+	return nil
+}
+
+// createTokenFamily starts a new refresh token family for a user, with a session that has
+// no client details yet
+func createTokenFamily(ctx context.Context, tx *sqlx.Tx, userID uuid.UUID) (uuid.UUID, error) {
+	stmt, err := tx.PrepareNamedContext(ctx, createTokenFamilyQuery)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer stmt.Close()
+
+	var familyID uuid.UUID
+	if err := stmt.GetContext(ctx, &familyID, map[string]any{"user_id": userID}); err != nil {
+		return uuid.Nil, err
+	}
+	return familyID, nil
+}
+
+// execNamed runs a named statement and returns the number of affected rows
+func execNamed(ctx context.Context, p NamedPreparer, query string, params map[string]any) (int64, error) {
+	stmt, err := p.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	result, err := stmt.ExecContext(ctx, params)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
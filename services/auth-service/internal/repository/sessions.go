@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"auth-service/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned when a session does not exist, belongs to another user
+// or has already been revoked
+var ErrSessionNotFound = errors.New("session not found")
+
+const (
+	listSessionsQuery = `
+		SELECT
+			s.id,
+			s.user_id,
+			s.user_agent,
+			s.ip_address,
+			s.created_at,
+			s.last_seen_at
+		FROM sessions s
+		JOIN refresh_token_families f ON f.id = s.id
+		WHERE s.user_id = :user_id AND f.revoked_at IS NULL
+		ORDER BY s.last_seen_at DESC
+	`
+
+	revokeUserTokenFamilyQuery = `
+		UPDATE refresh_token_families
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE id = :family_id AND user_id = :user_id AND revoked_at IS NULL
+	`
+
+	revokeAllUserTokenFamiliesQuery = `
+		UPDATE refresh_token_families
+		SET revoked_at = CURRENT_TIMESTAMP
+		WHERE user_id = :user_id AND revoked_at IS NULL
+	`
+
+	revokeAllUserTokensQuery = `
+		UPDATE user_tokens
+		SET is_revoked = true
+		WHERE user_id = :user_id AND NOT is_revoked
+	`
+)
+
+// ListSessions retrieves the active sessions of a user, most recently seen first
+func (db *DB) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	params := map[string]any{
+		"user_id": userID,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, listSessionsQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare list sessions failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	sessions := []models.Session{}
+	if err := stmt.SelectContext(ctx, &sessions, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "list sessions failed", status)
+		return nil, mappedErr
+	}
+
+	return sessions, nil
+}
+
+// RevokeSession ends one of a user's sessions, revoking every token issued in it. It
+// returns ErrSessionNotFound when the user has no such active session.
+func (db *DB) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	params := map[string]any{
+		"user_id":   userID,
+		"family_id": sessionID,
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin revoke session failed", http.StatusInternalServerError)
+		return err
+	}
+	defer tx.Rollback()
+
+	revoked, err := execNamed(ctx, tx, revokeUserTokenFamilyQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "revoke session failed", status)
+		return mappedErr
+	}
+	if revoked == 0 {
+		return ErrSessionNotFound
+	}
+
+	if _, err := execNamed(ctx, tx, revokeFamilyTokensQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "revoke session tokens failed", status)
+		return mappedErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit revoke session failed", http.StatusInternalServerError)
+		return err
+	}
+
+	db.logger.Info(ctx, "session revoked successfully", map[string]any{
+		"user_id":    userID,
+		"session_id": sessionID,
+	})
+
+	return nil
+}
+
+// RevokeAllUserTokens ends every session of a user, revoking all of their access and
+// refresh tokens. It returns how many tokens were revoked.
+func (db *DB) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	params := map[string]any{
+		"user_id": userID,
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin revoke all user tokens failed", http.StatusInternalServerError)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	if _, err := execNamed(ctx, tx, revokeAllUserTokenFamiliesQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "revoke user sessions failed", status)
+		return 0, mappedErr
+	}
+
+	revoked, err := execNamed(ctx, tx, revokeAllUserTokensQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "revoke user tokens failed", status)
+		return 0, mappedErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit revoke all user tokens failed", http.StatusInternalServerError)
+		return 0, err
+	}
+
+	db.logger.Info(ctx, "all user tokens revoked successfully", map[string]any{
+		"user_id": userID,
+		"revoked": revoked,
+	})
+
+	return revoked, nil
+}
@@ -5,17 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"auth-service/config"
 
+	"packages/database"
 	zlog "packages/logger"
 
+	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
-	"github.com/pressly/goose"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 const (
@@ -41,6 +42,7 @@ type DB struct {
 type Config struct {
 	ConnStr         string
 	MigrationsDir   string
+	RunMigrations   bool
 	ConnTimeout     time.Duration
 	MaxOpenConns    int
 	MaxIdleConns    int
@@ -57,11 +59,13 @@ func NewDB(ctx context.Context, cfg *Config, logger *zlog.Logger) (*DB, error) {
 		return nil, fmt.Errorf("config and logger must not be nil")
 	}
 
-	dbx, err := sqlx.Open("postgres", cfg.ConnStr)
+	// Every query runs in a span under the caller's trace
+	sqlDB, err := otelsql.Open("postgres", cfg.ConnStr, otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL))
 	if err != nil {
 		logger.Error(ctx, err, "Failed to open database connection", http.StatusInternalServerError)
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	dbx := sqlx.NewDb(sqlDB, "postgres")
 
 	// Configure connection pool
 	dbx.SetMaxOpenConns(cfg.MaxOpenConns)
@@ -78,17 +82,20 @@ func NewDB(ctx context.Context, cfg *Config, logger *zlog.Logger) (*DB, error) {
 	}
 
 	// Run migrations
-	if err := runMigrations(ctx, dbx, cfg.MigrationsDir, logger); err != nil {
+	if err := database.RunMigrations(ctx, dbx.DB, database.MigrationConfig{Dir: cfg.MigrationsDir, Enabled: cfg.RunMigrations}, logger); err != nil {
 		logger.Error(ctx, err, "Database migrations failed", http.StatusInternalServerError)
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	logger.Info(ctx, "Database connection established and migrations applied successfully")
-	return &DB{DB: dbx, logger: func() *zlog.Logger {
-		return logger.WithFields(map[string]any{
-			"layer": APP_LAYER,
-		})
-	}()}, nil
+	logger.Info(ctx, "Database connection established")
+	return NewDBFromConn(dbx, logger), nil
+}
+
+// NewDBFromConn wraps an already opened connection without pinging it or running migrations
+func NewDBFromConn(dbx *sqlx.DB, logger *zlog.Logger) *DB {
+	return &DB{DB: dbx, logger: logger.WithFields(map[string]any{
+		"layer": APP_LAYER,
+	})}
 }
 
 // InitDB initializes the database using the application config
@@ -117,6 +124,7 @@ func FromConfig(appCfg *config.Config) *Config {
 			appCfg.PostgresDB,
 		),
 		MigrationsDir:   DefaultMigrationsDir,
+		RunMigrations:   appCfg.RunMigrations,
 		ConnTimeout:     DefaultConnTimeout,
 		MaxOpenConns:    DefaultMaxOpenConns,
 		MaxIdleConns:    DefaultMaxIdleConns,
@@ -124,29 +132,6 @@ func FromConfig(appCfg *config.Config) *Config {
 	}
 }
 
-// runMigrations applies database migrations from the specified directory
-func runMigrations(ctx context.Context, db *sqlx.DB, migrationsDir string, logger *zlog.Logger) error {
-	if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
-		logger.Error(ctx, err, "Migrations directory not found", http.StatusInternalServerError, map[string]any{"path": migrationsDir})
-		return fmt.Errorf("migrations directory does not exist: %w", err)
-	}
-
-	logger.Info(ctx, "Applying migrations", map[string]any{"migrations_dir": migrationsDir})
-
-	if err := goose.SetDialect("postgres"); err != nil {
-		logger.Error(ctx, err, "Failed to set goose dialect", http.StatusInternalServerError)
-		return fmt.Errorf("failed to set goose dialect: %w", err)
-	}
-
-	if err := goose.Up(db.DB, migrationsDir); err != nil {
-		logger.Error(ctx, err, "Failed to apply migrations", http.StatusInternalServerError)
-		return fmt.Errorf("failed to apply migrations: %w", err)
-	}
-
-	logger.Info(ctx, "Migrations applied successfully")
-	return nil
-}
-
 // defaultUniqueMessage generates a user-friendly message for unique constraint violations
 func defaultUniqueMessage(constraint string) string {
 	parts := strings.Split(constraint, "_")
@@ -14,21 +14,33 @@ import (
 
 const (
 	storeTokensQuery = `
+		WITH family AS (
+			INSERT INTO refresh_token_families (user_id)
+			VALUES (:user_id)
+			RETURNING id
+		), session AS (
+			INSERT INTO sessions (id, user_id, user_agent, ip_address)
+			SELECT family.id, :user_id, :user_agent, :ip_address
+			FROM family
+		)
 		INSERT INTO user_tokens (
 			user_id, 
 			access_token, 
 			refresh_token, 
 			access_expires_at, 
 			refresh_expires_at, 
-			is_revoked
-		) VALUES (
+			is_revoked,
+			family_id
+		)
+		SELECT
 			:user_id,
 			:access_token,
 			:refresh_token,
 			:access_expires_at,
 			:refresh_expires_at,
-			false
-		)
+			false,
+			family.id
+		FROM family
 	`
 
 	revokeTokenQuery = `
@@ -46,6 +58,7 @@ const (
 			access_expires_at, 
 			refresh_expires_at, 
 			is_revoked, 
+			family_id,
 			created_at
 		FROM user_tokens
 		WHERE access_token = :access_token
@@ -60,11 +73,24 @@ const (
 			access_expires_at, 
 			refresh_expires_at, 
 			is_revoked, 
+			family_id,
 			created_at
 		FROM user_tokens
 		WHERE refresh_token = :refresh_token
 	`
 
+	// A revoked token row can go once its refresh token, the longer lived of the pair,
+	// has expired: neither token would be accepted any more
+	pruneRevokedTokensQuery = `
+		DELETE FROM user_tokens
+		WHERE is_revoked AND refresh_expires_at <= :now
+	`
+
+	pruneRevocationsQuery = `
+		DELETE FROM revoked_tokens
+		WHERE expires_at <= :now
+	`
+
 	updateAccessTokenQuery = `
 		UPDATE user_tokens
 		SET access_token = :access_token, access_expires_at = :access_expires_at
@@ -72,10 +98,13 @@ const (
 	`
 )
 
-// StoreTokens stores access and refresh tokens for a user
-func (db *DB) StoreTokens(ctx context.Context, userID uuid.UUID, accessToken, refreshToken string, accessExpiresAt, refreshExpiresAt time.Time) error {
+// StoreTokens stores access and refresh tokens for a user in a new refresh token family,
+// starting a session for the client they are issued to
+func (db *DB) StoreTokens(ctx context.Context, userID uuid.UUID, client models.ClientInfo, accessToken, refreshToken string, accessExpiresAt, refreshExpiresAt time.Time) error {
 	params := map[string]any{
 		"user_id":            userID,
+		"user_agent":         client.UserAgent,
+		"ip_address":         client.IPAddress,
 		"access_token":       accessToken,
 		"refresh_token":      refreshToken,
 		"access_expires_at":  accessExpiresAt,
@@ -242,3 +271,24 @@ func (db *DB) UpdateAccessToken(ctx context.Context, tokenID uuid.UUID, newAcces
 
 	return nil
 }
+
+// PruneExpiredRevokedTokens deletes revoked tokens that expired by now, along with the
+// revocations recorded for tokens that expired by now, and returns how many rows were removed
+func (db *DB) PruneExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	params := map[string]any{
+		"now": now,
+	}
+
+	var pruned int64
+	for _, query := range []string{pruneRevokedTokensQuery, pruneRevocationsQuery} {
+		removed, err := execNamed(ctx, db, query, params)
+		if err != nil {
+			status, mappedErr := HandlePgError(err)
+			db.logger.Error(ctx, mappedErr, "prune revoked tokens failed", status)
+			return pruned, mappedErr
+		}
+		pruned += removed
+	}
+
+	return pruned, nil
+}
@@ -6,12 +6,16 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"auth-service/models"
 
 	"github.com/google/uuid"
 )
 
+// ErrUserNotFound is returned when no user matches the lookup
+var ErrUserNotFound = errors.New("user not found")
+
 // Named queries
 const (
 	insertUserQuery = `
@@ -70,6 +74,14 @@ const (
 	countUsersQuery = `
 		SELECT COUNT(*) FROM users
 	`
+
+	// Only replaces the hash it was computed from, so a password changed in the meantime
+	// is never overwritten
+	replaceUserPasswordQuery = `
+		UPDATE users
+		SET password = :password, updated_at = :updated_at
+		WHERE id = :user_id AND password = :old_password
+	`
 )
 
 // CreateUser inserts a new user into the database
@@ -119,7 +131,7 @@ func (db *DB) GetUserByEmail(ctx context.Context, email string) (*models.User, e
 			db.logger.Info(ctx, "user not found", map[string]any{
 				"email": email,
 			})
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
@@ -148,7 +160,7 @@ func (db *DB) GetUserByID(ctx context.Context, id uuid.UUID) (*models.User, erro
 			db.logger.Info(ctx, "user not found", map[string]any{
 				"id": id,
 			})
-			return nil, errors.New("user not found")
+			return nil, ErrUserNotFound
 		}
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
@@ -206,3 +218,24 @@ func (db *DB) CountUsers(ctx context.Context) (int, error) {
 
 	return count, nil
 }
+
+// ReplaceUserPassword replaces a user's password hash with passwordHash, but only while it
+// is still oldHash. It reports whether the hash was replaced; it is not when the password
+// was changed, for example by a password reset, since oldHash was read.
+func (db *DB) ReplaceUserPassword(ctx context.Context, userID uuid.UUID, oldHash, passwordHash string) (bool, error) {
+	params := map[string]any{
+		"user_id":      userID,
+		"password":     passwordHash,
+		"old_password": oldHash,
+		"updated_at":   time.Now(),
+	}
+
+	updated, err := execNamed(ctx, db, replaceUserPasswordQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "update password failed", status)
+		return false, mappedErr
+	}
+
+	return updated > 0, nil
+}
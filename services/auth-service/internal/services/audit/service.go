@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"auth-service/internal/repository"
+	"auth-service/models"
+
+	zlog "packages/logger"
+)
+
+// AuditLogger persists security audit events and lists them for administrators
+type AuditLogger struct {
+	DB     *repository.DB
+	logger *zlog.Logger
+}
+
+// NewAuditLogger creates a new audit logger
+func NewAuditLogger(db *repository.DB, logger *zlog.Logger) *AuditLogger {
+	return &AuditLogger{
+		DB:     db,
+		logger: logger,
+	}
+}
+
+// Record stores an audit event, stamping it with the current time when it has none
+func (a *AuditLogger) Record(ctx context.Context, event *models.AuditEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now().UTC()
+	}
+
+	if err := a.DB.CreateAuditEvent(ctx, event); err != nil {
+		a.logger.Error(ctx, err, "failed to record audit event", http.StatusInternalServerError, map[string]any{
+			"event_type": event.EventType,
+			"method":     event.Method,
+		})
+		return err
+	}
+
+	return nil
+}
+
+// ListEvents retrieves audit events matching filter with pagination, newest first
+func (a *AuditLogger) ListEvents(ctx context.Context, filter models.AuditEventFilter, page, limit int) ([]models.AuditEvent, int, error) {
+	offset := (page - 1) * limit
+	if page <= 0 {
+		offset = 0
+	}
+
+	events, err := a.DB.ListAuditEvents(ctx, filter, limit, offset)
+	if err != nil {
+		a.logger.Error(ctx, err, "failed to retrieve audit events", http.StatusInternalServerError, map[string]any{
+			"page":   page,
+			"limit":  limit,
+			"offset": offset,
+		})
+		return nil, 0, err
+	}
+
+	total, err := a.DB.CountAuditEvents(ctx, filter)
+	if err != nil {
+		a.logger.Error(ctx, err, "failed to count audit events", http.StatusInternalServerError, nil)
+		return events, 0, err
+	}
+
+	return events, total, nil
+}
@@ -0,0 +1,179 @@
+package authentication
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"auth-service/models"
+	"auth-service/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testLockoutPolicy = LockoutPolicy{
+	Threshold: 3,
+	Window:    15 * time.Minute,
+	Cooldown:  10 * time.Minute,
+}
+
+const (
+	lockoutEmail    = "Test@Example.com"
+	lockoutEmailKey = "email:test@example.com"
+	lockoutIPKey    = "ip:203.0.113.7"
+)
+
+// timeArg is a sqlmock argument matcher that records the time it is matched against
+type timeArg struct {
+	value *time.Time
+}
+
+func (a timeArg) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	if ok {
+		*a.value = t
+	}
+	return ok
+}
+
+func newLockoutCredentials(password string) *models.Credentials {
+	return &models.Credentials{Email: lockoutEmail, Password: password, ClientIP: "203.0.113.7"}
+}
+
+func newLockoutUser(t *testing.T) *models.User {
+	t.Helper()
+	hash, err := utils.HashPasswordWithCost(strongPassword, bcrypt.MinCost)
+	require.NoError(t, err)
+	return &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com", Password: hash}
+}
+
+func expectLockCheck(mock sqlmock.Sqlmock, lockedUntil *time.Time) {
+	rows := sqlmock.NewRows([]string{"max"})
+	if lockedUntil != nil {
+		rows.AddRow(*lockedUntil)
+	} else {
+		rows.AddRow(nil)
+	}
+	mock.ExpectPrepare("FROM login_attempts").ExpectQuery().WillReturnRows(rows)
+}
+
+func expectUserLookup(mock sqlmock.Sqlmock, user *models.User) {
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"})
+	if user != nil {
+		rows.AddRow(user.ID, user.Name, user.Email, user.Password, time.Now(), time.Now())
+	}
+	mock.ExpectPrepare("FROM users").ExpectQuery().WillReturnRows(rows)
+}
+
+func expectFailureRecorded(mock sqlmock.Sqlmock, key string, failures int) {
+	mock.ExpectPrepare("INSERT INTO login_attempts").
+		ExpectQuery().
+		WithArgs(key, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"failures"}).AddRow(failures))
+}
+
+func TestAuthService_SignIn_LocksAfterRepeatedFailures(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetLockoutPolicy(testLockoutPolicy)
+
+	expectLockCheck(mock, nil)
+	expectUserLookup(mock, newLockoutUser(t))
+	expectFailureRecorded(mock, lockoutEmailKey, 3)
+	var lockedUntil time.Time
+	mock.ExpectPrepare("UPDATE login_attempts").
+		ExpectExec().
+		WithArgs(timeArg{value: &lockedUntil}, sqlmock.AnyArg(), sqlmock.AnyArg(), lockoutEmailKey).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectFailureRecorded(mock, lockoutIPKey, 1)
+
+	before := time.Now()
+	_, _, _, err := service.SignIn(context.Background(), newLockoutCredentials("Wr0ng-Password!"), "access-secret", "refresh-secret")
+
+	require.EqualError(t, err, "invalid credentials")
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.WithinDuration(t, before.Add(testLockoutPolicy.Cooldown), lockedUntil, 5*time.Second)
+}
+
+func TestAuthService_SignIn_RefusedWhileLocked(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetLockoutPolicy(testLockoutPolicy)
+
+	// Even the right password is refused, and the user is never looked up
+	lockedUntil := time.Now().Add(5 * time.Minute)
+	expectLockCheck(mock, &lockedUntil)
+
+	user, _, _, err := service.SignIn(context.Background(), newLockoutCredentials(strongPassword), "access-secret", "refresh-secret")
+
+	assert.ErrorIs(t, err, ErrTooManySignInAttempts)
+	assert.Nil(t, user)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_SignIn_UnknownEmailCountsLikeWrongPassword(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetLockoutPolicy(testLockoutPolicy)
+
+	expectLockCheck(mock, nil)
+	expectUserLookup(mock, nil)
+	expectFailureRecorded(mock, lockoutEmailKey, 1)
+	expectFailureRecorded(mock, lockoutIPKey, 1)
+
+	_, _, _, err := service.SignIn(context.Background(), newLockoutCredentials(strongPassword), "access-secret", "refresh-secret")
+
+	require.EqualError(t, err, "invalid credentials")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_SignIn_SucceedsAfterCooldownAndResetsCount(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetLockoutPolicy(testLockoutPolicy)
+	user := newLockoutUser(t)
+
+	// Once the cooldown has passed the lock query no longer finds an active lock
+	var checkedAt time.Time
+	mock.ExpectPrepare("FROM login_attempts").
+		ExpectQuery().
+		WithArgs(sqlmock.AnyArg(), timeArg{value: &checkedAt}).
+		WillReturnRows(sqlmock.NewRows([]string{"max"}).AddRow(nil))
+	expectUserLookup(mock, user)
+	mock.ExpectPrepare("DELETE FROM login_attempts").
+		ExpectExec().
+		WithArgs(lockoutEmailKey).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("INSERT INTO refresh_token_families").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	signedIn, accessToken, _, err := service.SignIn(context.Background(), newLockoutCredentials(strongPassword), "access-secret-access-secret-access", "refresh-secret-refresh-secret-refresh")
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, user.ID, signedIn.ID)
+	assert.NotEmpty(t, accessToken)
+	assert.WithinDuration(t, time.Now(), checkedAt, 5*time.Second)
+}
+
+func TestAuthService_SignIn_LockoutDisabled(t *testing.T) {
+	service, mock := newMockAuthService(t)
+
+	expectUserLookup(mock, nil)
+
+	_, _, _, err := service.SignIn(context.Background(), newLockoutCredentials(strongPassword), "access-secret", "refresh-secret")
+
+	require.EqualError(t, err, "invalid credentials")
+	assert.NoError(t, mock.ExpectationsWereMet(), "no login attempts should be recorded")
+}
+
+func TestLoginAttemptKeys(t *testing.T) {
+	emailKey, keys := loginAttemptKeys("  Test@Example.com ", "203.0.113.7")
+	assert.Equal(t, lockoutEmailKey, emailKey)
+	assert.Equal(t, []string{lockoutEmailKey, lockoutIPKey}, keys)
+
+	_, keys = loginAttemptKeys("test@example.com", "")
+	assert.Equal(t, []string{lockoutEmailKey}, keys)
+}
@@ -0,0 +1,148 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"auth-service/internal/repository"
+	"auth-service/models"
+	"auth-service/utils"
+	zlog "packages/logger"
+)
+
+// passwordResetTokenLength is the number of random bytes in a reset token
+const passwordResetTokenLength = 32
+
+var (
+	// ErrInvalidPasswordResetToken is returned for reset tokens that are unknown, expired or
+	// already used. The cases are not told apart to callers.
+	ErrInvalidPasswordResetToken = errors.New("invalid or expired password reset token")
+	// ErrWeakPassword is returned when a new password does not satisfy the password policy
+	ErrWeakPassword = errors.New("password does not meet the password policy")
+)
+
+// PasswordPolicy is the strength policy a new password must satisfy
+type PasswordPolicy struct {
+	MinLength           int
+	RequireUppercase    bool
+	RequireLowercase    bool
+	RequireNumbers      bool
+	RequireSpecialChars bool
+}
+
+// PasswordResetSender delivers a password reset token to the user, e.g. by email
+type PasswordResetSender interface {
+	SendPasswordReset(ctx context.Context, user *models.User, token string, expiresAt time.Time) error
+}
+
+// logPasswordResetSender is the default sender. It only records that a reset was
+// requested, so deployments must configure a real sender for resets to reach users.
+type logPasswordResetSender struct {
+	logger *zlog.Logger
+}
+
+func (l logPasswordResetSender) SendPasswordReset(ctx context.Context, user *models.User, token string, expiresAt time.Time) error {
+	l.logger.Warn(ctx, "no password reset sender configured, reset token not delivered", map[string]any{
+		"user_id": user.ID.String(),
+	})
+	return nil
+}
+
+// SetPasswordResetSender sets how password reset tokens are delivered to users
+func (s *AuthService) SetPasswordResetSender(sender PasswordResetSender) {
+	s.resetSender = sender
+}
+
+// RequestPasswordReset issues a single-use reset token for the user with the given email
+// and hands it to the reset sender. It succeeds whether or not the email belongs to a
+// user, so callers cannot use it to discover registered addresses.
+func (s *AuthService) RequestPasswordReset(ctx context.Context, email string, ttl time.Duration) error {
+	user, err := s.DB.GetUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			s.logger.Info(ctx, "password reset requested for unknown email")
+			return nil
+		}
+		s.logger.Error(ctx, err, "failed to look up user for password reset", http.StatusInternalServerError)
+		return err
+	}
+
+	token, err := utils.GenerateSecureToken(passwordResetTokenLength)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to generate password reset token", http.StatusInternalServerError)
+		return err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if err := s.DB.CreatePasswordReset(ctx, user.ID, utils.HashToken(token), expiresAt); err != nil {
+		s.logger.Error(ctx, err, "failed to store password reset", http.StatusInternalServerError, map[string]any{
+			"user_id": user.ID.String(),
+		})
+		return err
+	}
+
+	if err := s.resetSender.SendPasswordReset(ctx, user, token, expiresAt); err != nil {
+		s.logger.Error(ctx, err, "failed to send password reset", http.StatusInternalServerError, map[string]any{
+			"user_id": user.ID.String(),
+		})
+		return err
+	}
+
+	s.logger.Info(ctx, "password reset requested", map[string]any{
+		"user_id": user.ID.String(),
+	})
+	return nil
+}
+
+// ConfirmPasswordReset sets a new password using a reset token. The token is consumed and
+// every existing session of the user is revoked.
+func (s *AuthService) ConfirmPasswordReset(ctx context.Context, token, newPassword string, policy PasswordPolicy) error {
+	if err := utils.ValidatePasswordStrength(newPassword, policy.MinLength, policy.RequireUppercase, policy.RequireLowercase, policy.RequireNumbers, policy.RequireSpecialChars); err != nil {
+		s.logger.Error(ctx, err, "weak password", http.StatusBadRequest)
+		return fmt.Errorf("%w: %v", ErrWeakPassword, err)
+	}
+
+	reset, err := s.DB.GetPasswordResetByTokenHash(ctx, utils.HashToken(token))
+	if err != nil {
+		if errors.Is(err, repository.ErrPasswordResetNotFound) {
+			s.logger.Error(ctx, err, "unknown password reset token", http.StatusUnauthorized)
+			return ErrInvalidPasswordResetToken
+		}
+		return err
+	}
+
+	if reset.UsedAt != nil {
+		s.logger.Error(ctx, ErrInvalidPasswordResetToken, "password reset token already used", http.StatusUnauthorized, map[string]any{
+			"user_id": reset.UserID.String(),
+		})
+		return ErrInvalidPasswordResetToken
+	}
+
+	if time.Now().After(reset.ExpiresAt) {
+		s.logger.Error(ctx, ErrInvalidPasswordResetToken, "password reset token expired", http.StatusUnauthorized, map[string]any{
+			"user_id": reset.UserID.String(),
+		})
+		return ErrInvalidPasswordResetToken
+	}
+
+	hashedPassword, err := utils.HashPasswordWithCost(newPassword, s.bcryptCost)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to hash password", http.StatusInternalServerError)
+		return err
+	}
+
+	if err := s.DB.CompletePasswordReset(ctx, reset, hashedPassword); err != nil {
+		if errors.Is(err, repository.ErrPasswordResetConsumed) {
+			return ErrInvalidPasswordResetToken
+		}
+		return err
+	}
+
+	s.logger.Info(ctx, "password reset successfully", map[string]any{
+		"user_id": reset.UserID.String(),
+	})
+	return nil
+}
@@ -0,0 +1,230 @@
+package authentication
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"auth-service/models"
+	"auth-service/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const strongPassword = "Corr3ct-Horse-Battery"
+
+var testPasswordPolicy = PasswordPolicy{
+	MinLength:           12,
+	RequireUppercase:    true,
+	RequireLowercase:    true,
+	RequireNumbers:      true,
+	RequireSpecialChars: true,
+}
+
+// recordingResetSender captures the reset tokens handed to it
+type recordingResetSender struct {
+	user  *models.User
+	token string
+}
+
+func (r *recordingResetSender) SendPasswordReset(ctx context.Context, user *models.User, token string, expiresAt time.Time) error {
+	r.user, r.token = user, token
+	return nil
+}
+
+// stringArg is a sqlmock argument matcher that records the string it is matched against
+type stringArg struct {
+	value *string
+}
+
+func (a stringArg) Match(v driver.Value) bool {
+	s, ok := v.(string)
+	if ok {
+		*a.value = s
+	}
+	return ok
+}
+
+func expectPasswordReset(mock sqlmock.Sqlmock, token string, reset *models.PasswordReset) {
+	mock.ExpectPrepare("FROM password_resets").
+		ExpectQuery().
+		WithArgs(utils.HashToken(token)).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token_hash", "expires_at", "used_at", "created_at"}).
+			AddRow(reset.ID, reset.UserID, reset.TokenHash, reset.ExpiresAt, reset.UsedAt, reset.CreatedAt))
+}
+
+func newPasswordReset(token string, expiresAt time.Time) *models.PasswordReset {
+	return &models.PasswordReset{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now(),
+	}
+}
+
+func TestAuthService_RequestPasswordReset_StoresTokenHash(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	sender := &recordingResetSender{}
+	service.SetPasswordResetSender(sender)
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	now := time.Now()
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WithArgs(user.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}).
+			AddRow(user.ID, user.Name, user.Email, "hash", now, now))
+	var storedHash string
+	mock.ExpectPrepare("INSERT INTO password_resets").
+		ExpectExec().
+		WithArgs(user.ID, stringArg{value: &storedHash}, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	err := service.RequestPasswordReset(context.Background(), user.Email, 30*time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.NotEmpty(t, sender.token)
+	assert.Equal(t, user.ID, sender.user.ID)
+	assert.Equal(t, utils.HashToken(sender.token), storedHash, "only the token hash should be stored")
+}
+
+func TestAuthService_RequestPasswordReset_UnknownEmailSucceeds(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	sender := &recordingResetSender{}
+	service.SetPasswordResetSender(sender)
+
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WithArgs("nobody@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}))
+
+	err := service.RequestPasswordReset(context.Background(), "nobody@example.com", 30*time.Minute)
+
+	assert.NoError(t, err)
+	assert.Empty(t, sender.token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_RequestPasswordReset_LookupFailureIsReturned(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	sender := &recordingResetSender{}
+	service.SetPasswordResetSender(sender)
+
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WithArgs("test@example.com").
+		WillReturnError(errors.New("connection refused"))
+
+	err := service.RequestPasswordReset(context.Background(), "test@example.com", 30*time.Minute)
+
+	assert.Error(t, err)
+	assert.Empty(t, sender.token)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_ConfirmPasswordReset(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	reset := newPasswordReset("reset-token", time.Now().Add(time.Hour))
+
+	expectPasswordReset(mock, "reset-token", reset)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE password_resets").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), reset.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	var passwordHash string
+	mock.ExpectPrepare("UPDATE users").
+		ExpectExec().
+		WithArgs(stringArg{value: &passwordHash}, sqlmock.AnyArg(), reset.UserID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE password_resets").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), reset.UserID).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(reset.UserID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := service.ConfirmPasswordReset(context.Background(), "reset-token", strongPassword, testPasswordPolicy)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.True(t, utils.CheckPasswordHash(strongPassword, passwordHash))
+}
+
+func TestAuthService_ConfirmPasswordReset_RejectsInvalidTokens(t *testing.T) {
+	usedAt := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name   string
+		expect func(mock sqlmock.Sqlmock)
+	}{
+		{
+			name: "unknown token",
+			expect: func(mock sqlmock.Sqlmock) {
+				mock.ExpectPrepare("FROM password_resets").
+					ExpectQuery().
+					WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "token_hash", "expires_at", "used_at", "created_at"}))
+			},
+		},
+		{
+			name: "expired token",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectPasswordReset(mock, "reset-token", newPasswordReset("reset-token", time.Now().Add(-time.Second)))
+			},
+		},
+		{
+			name: "reused token",
+			expect: func(mock sqlmock.Sqlmock) {
+				reset := newPasswordReset("reset-token", time.Now().Add(time.Hour))
+				reset.UsedAt = &usedAt
+				expectPasswordReset(mock, "reset-token", reset)
+			},
+		},
+		{
+			name: "token used concurrently",
+			expect: func(mock sqlmock.Sqlmock) {
+				expectPasswordReset(mock, "reset-token", newPasswordReset("reset-token", time.Now().Add(time.Hour)))
+				mock.ExpectBegin()
+				mock.ExpectPrepare("UPDATE password_resets").
+					ExpectExec().
+					WillReturnResult(sqlmock.NewResult(0, 0))
+				mock.ExpectRollback()
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mock := newMockAuthService(t)
+			tt.expect(mock)
+
+			err := service.ConfirmPasswordReset(context.Background(), "reset-token", strongPassword, testPasswordPolicy)
+
+			assert.ErrorIs(t, err, ErrInvalidPasswordResetToken)
+			assert.NoError(t, mock.ExpectationsWereMet(), "the password must not be changed")
+		})
+	}
+}
+
+func TestAuthService_ConfirmPasswordReset_EnforcesPasswordStrength(t *testing.T) {
+	for _, password := range []string{"short1!A", "alllowercase-123", "NoDigitsHere!!", "NO-LOWERCASE-123"} {
+		t.Run(password, func(t *testing.T) {
+			service, mock := newMockAuthService(t)
+
+			err := service.ConfirmPasswordReset(context.Background(), "reset-token", password, testPasswordPolicy)
+
+			assert.ErrorIs(t, err, ErrWeakPassword)
+			assert.NoError(t, mock.ExpectationsWereMet(), "the reset token must not be consumed")
+		})
+	}
+}
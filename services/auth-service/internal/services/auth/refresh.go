@@ -1,6 +1,7 @@
 package authentication
 
 import (
+	"auth-service/internal/repository"
 	"auth-service/models"
 	"auth-service/utils"
 	"context"
@@ -9,8 +10,14 @@ import (
 	"time"
 )
 
-// RefreshToken refreshes an access token using a refresh token
-func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, accessSecret, refreshSecret string) (*models.UserToken, error) {
+// ErrRefreshTokenReused is returned when an already rotated refresh token is presented
+// again. The token family it belongs to has been revoked by the time it is returned.
+var ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+
+// RefreshToken exchanges a refresh token for a new access and refresh token pair. The
+// presented refresh token is consumed, and presenting it again revokes every token
+// descending from the same sign in. The session is marked as last seen from client.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, client models.ClientInfo, accessSecret, refreshSecret string) (*models.UserToken, error) {
 	if refreshToken == "" {
 		err := errors.New("refresh token cannot be empty")
 		s.logger.Error(ctx, err, "validation error", http.StatusBadRequest)
@@ -18,18 +25,24 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, acc
 	}
 
 	// Validate the refresh token JWT
-	claims, err := utils.ValidateToken(refreshToken, refreshSecret)
+	claims, err := utils.ValidateToken(refreshToken, s.tokenScope, refreshSecret)
 	if err != nil {
 		s.logger.Error(ctx, err, "invalid refresh token", http.StatusUnauthorized)
 		return nil, errors.New("invalid refresh token")
 	}
 
 	// Validate token type
-	if tokenType, ok := claims["type"].(string); !ok || tokenType != "refresh" {
+	if tokenType, err := claims.String("type"); err != nil || tokenType != "refresh" {
 		s.logger.Error(ctx, errors.New("invalid token type"), "invalid refresh token claims", http.StatusUnauthorized)
 		return nil, errors.New("invalid refresh token")
 	}
 
+	// A consumed token means it leaked or was replayed, so the whole family is revoked
+	tokenHash := utils.HashToken(refreshToken)
+	if err := s.checkRefreshTokenReuse(ctx, tokenHash); err != nil {
+		return nil, err
+	}
+
 	// Get token from database
 	token, err := s.DB.GetTokenByRefreshToken(ctx, refreshToken)
 	if err != nil {
@@ -64,11 +77,12 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, acc
 		return nil, errors.New("user not found")
 	}
 
-	// Generate new access token
+	// Generate new token pair
 	now := time.Now()
 	accessExpiresAt := now.Add(15 * time.Minute)
+	refreshExpiresAt := now.Add(7 * 24 * time.Hour)
 
-	newAccessToken, err := utils.GenerateAccessToken(user, accessSecret)
+	newAccessToken, err := utils.GenerateAccessToken(user, accessSecret, s.tokenScope)
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to generate new access token", http.StatusInternalServerError, map[string]any{
 			"user_id": user.ID.String(),
@@ -76,24 +90,28 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, acc
 		return nil, err
 	}
 
-	// Update the token in database with new access token
-	if err := s.DB.UpdateAccessToken(ctx, token.ID, newAccessToken, accessExpiresAt); err != nil {
-		s.logger.Error(ctx, err, "failed to update access token", http.StatusInternalServerError, map[string]any{
-			"token_id": token.ID.String(),
+	newRefreshToken, err := utils.GenerateRefreshToken(user, refreshSecret, s.tokenScope)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to generate new refresh token", http.StatusInternalServerError, map[string]any{
+			"user_id": user.ID.String(),
 		})
 		return nil, err
 	}
 
-	// Create updated token model
-	updatedToken := &models.UserToken{
-		ID:               token.ID,
-		UserID:           token.UserID,
-		AccessToken:      newAccessToken,
-		RefreshToken:     refreshToken,
-		AccessExpiresAt:  accessExpiresAt,
-		RefreshExpiresAt: token.RefreshExpiresAt,
-		IsRevoked:        false,
-		CreatedAt:        token.CreatedAt,
+	// Rotate the tokens, consuming the presented refresh token
+	rotatedToken, err := s.DB.RotateRefreshToken(ctx, token, client, tokenHash, newAccessToken, newRefreshToken, accessExpiresAt, refreshExpiresAt)
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenConsumed) {
+			// Lost a race with another exchange of the same token
+			if err := s.checkRefreshTokenReuse(ctx, tokenHash); err != nil {
+				return nil, err
+			}
+			return nil, ErrRefreshTokenReused
+		}
+		s.logger.Error(ctx, err, "failed to rotate tokens", http.StatusInternalServerError, map[string]any{
+			"token_id": token.ID.String(),
+		})
+		return nil, err
 	}
 
 	s.logger.Info(ctx, "token refreshed successfully", map[string]any{
@@ -101,5 +119,30 @@ func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string, acc
 		"token_id": token.ID.String(),
 	})
 
-	return updatedToken, nil
+	return rotatedToken, nil
+}
+
+// checkRefreshTokenReuse revokes the token family and returns ErrRefreshTokenReused when
+// the refresh token hash has already been consumed
+func (s *AuthService) checkRefreshTokenReuse(ctx context.Context, tokenHash string) error {
+	familyID, consumed, err := s.DB.GetConsumedTokenFamily(ctx, tokenHash)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to check refresh token reuse", http.StatusInternalServerError)
+		return err
+	}
+	if !consumed {
+		return nil
+	}
+
+	s.logger.Warn(ctx, "refresh token reuse detected, revoking token family", map[string]any{
+		"family_id": familyID.String(),
+	})
+	if err := s.DB.RevokeTokenFamily(ctx, familyID); err != nil {
+		s.logger.Error(ctx, err, "failed to revoke token family", http.StatusInternalServerError, map[string]any{
+			"family_id": familyID.String(),
+		})
+		return err
+	}
+
+	return ErrRefreshTokenReused
 }
@@ -0,0 +1,181 @@
+package authentication
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"auth-service/internal/repository"
+	"auth-service/models"
+	"auth-service/utils"
+	zlog "packages/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testAccessSecret  = "test-access-secret"
+	testRefreshSecret = "test-refresh-secret"
+)
+
+var testClient = models.ClientInfo{UserAgent: "test-client/1.0", IPAddress: "203.0.113.7"}
+
+// newMockAuthService returns an auth service backed by a sqlmock database
+func newMockAuthService(t *testing.T) (*AuthService, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	return NewAuthService(db, logger), mock
+}
+
+// refreshFixture is a signed in user holding a refresh token
+type refreshFixture struct {
+	user         *models.User
+	tokenID      uuid.UUID
+	familyID     uuid.UUID
+	refreshToken string
+}
+
+func newRefreshFixture(t *testing.T) refreshFixture {
+	t.Helper()
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+	refreshToken, err := utils.GenerateRefreshToken(user, testRefreshSecret, utils.TokenScope{})
+	require.NoError(t, err)
+	return refreshFixture{
+		user:         user,
+		tokenID:      uuid.New(),
+		familyID:     uuid.New(),
+		refreshToken: refreshToken,
+	}
+}
+
+func expectNotConsumed(mock sqlmock.Sqlmock, tokenHash string) {
+	mock.ExpectPrepare("FROM consumed_refresh_tokens").
+		ExpectQuery().
+		WithArgs(tokenHash).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id"}))
+}
+
+func expectConsumed(mock sqlmock.Sqlmock, tokenHash string, familyID uuid.UUID) {
+	mock.ExpectPrepare("FROM consumed_refresh_tokens").
+		ExpectQuery().
+		WithArgs(tokenHash).
+		WillReturnRows(sqlmock.NewRows([]string{"family_id"}).AddRow(familyID))
+}
+
+func expectSession(mock sqlmock.Sqlmock, f refreshFixture) {
+	now := time.Now()
+	mock.ExpectPrepare("FROM user_tokens").
+		ExpectQuery().
+		WithArgs(f.refreshToken).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "user_id", "access_token", "refresh_token", "access_expires_at",
+			"refresh_expires_at", "is_revoked", "family_id", "created_at",
+		}).AddRow(
+			f.tokenID, f.user.ID, "old-access-token", f.refreshToken, now.Add(time.Minute),
+			now.Add(24*time.Hour), false, f.familyID, now,
+		))
+
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WithArgs(f.user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "name", "email", "password", "created_at", "updated_at",
+		}).AddRow(f.user.ID, f.user.Name, f.user.Email, "hash", now, now))
+}
+
+func expectFamilyRevoked(mock sqlmock.Sqlmock, familyID uuid.UUID) {
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE refresh_token_families").
+		ExpectExec().
+		WithArgs(familyID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(familyID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+}
+
+func TestAuthService_RefreshToken_RotatesTokens(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	f := newRefreshFixture(t)
+	tokenHash := utils.HashToken(f.refreshToken)
+
+	expectNotConsumed(mock, tokenHash)
+	expectSession(mock, f)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO consumed_refresh_tokens").
+		ExpectExec().
+		WithArgs(tokenHash, f.familyID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), f.familyID, f.tokenID, f.refreshToken).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE sessions").
+		ExpectExec().
+		WithArgs(testClient.UserAgent, testClient.IPAddress, f.familyID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	tokens, err := service.RefreshToken(context.Background(), f.refreshToken, testClient, testAccessSecret, testRefreshSecret)
+	require.NoError(t, err)
+
+	assert.Equal(t, f.tokenID, tokens.ID)
+	assert.NotEqual(t, f.refreshToken, tokens.RefreshToken)
+	require.NotNil(t, tokens.FamilyID)
+	assert.Equal(t, f.familyID, *tokens.FamilyID)
+
+	claims, err := utils.ValidateToken(tokens.RefreshToken, utils.TokenScope{}, testRefreshSecret)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", claims["type"])
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_RefreshToken_ReuseRevokesFamily(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	f := newRefreshFixture(t)
+	tokenHash := utils.HashToken(f.refreshToken)
+
+	expectConsumed(mock, tokenHash, f.familyID)
+	expectFamilyRevoked(mock, f.familyID)
+
+	tokens, err := service.RefreshToken(context.Background(), f.refreshToken, testClient, testAccessSecret, testRefreshSecret)
+
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Nil(t, tokens)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_RefreshToken_ConcurrentReuseRevokesFamily(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	f := newRefreshFixture(t)
+	tokenHash := utils.HashToken(f.refreshToken)
+
+	// Another request consumes the token between the reuse check and the rotation
+	expectNotConsumed(mock, tokenHash)
+	expectSession(mock, f)
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO consumed_refresh_tokens").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	expectConsumed(mock, tokenHash, f.familyID)
+	expectFamilyRevoked(mock, f.familyID)
+
+	tokens, err := service.RefreshToken(context.Background(), f.refreshToken, testClient, testAccessSecret, testRefreshSecret)
+
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+	assert.Nil(t, tokens)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,55 @@
+package authentication
+
+import (
+	"context"
+	"net/http"
+
+	"auth-service/models"
+	"auth-service/utils"
+)
+
+// SetBcryptCost sets the bcrypt cost new password hashes are made with. Hashes made with a
+// lower cost are upgraded the next time their user signs in. A zero cost keeps
+// utils.DefaultBcryptCost.
+func (s *AuthService) SetBcryptCost(cost int) {
+	if cost == 0 {
+		cost = utils.DefaultBcryptCost
+	}
+	s.bcryptCost = cost
+}
+
+// rehashPasswordIfNeeded rehashes the password of a user who has just signed in when the
+// stored hash was made with a lower cost than the configured one. The plain password is
+// only available at sign in, so this is the one chance to upgrade the hash. Failures are
+// logged and do not fail the sign in; the upgrade is retried on the next one. The hash is
+// only replaced while it is still the one the password was checked against, so a password
+// reset completing in the meantime is never undone.
+func (s *AuthService) rehashPasswordIfNeeded(ctx context.Context, user *models.User, password string) {
+	if !utils.NeedsRehash(user.Password, s.bcryptCost) {
+		return
+	}
+
+	hash, err := utils.HashPasswordWithCost(password, s.bcryptCost)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to rehash password", http.StatusInternalServerError)
+		return
+	}
+
+	replaced, err := s.DB.ReplaceUserPassword(ctx, user.ID, user.Password, hash)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to store rehashed password", http.StatusInternalServerError)
+		return
+	}
+	if !replaced {
+		s.logger.Info(ctx, "password changed during sign in, rehash skipped", map[string]any{
+			"user_id": user.ID.String(),
+		})
+		return
+	}
+	user.Password = hash
+
+	s.logger.Info(ctx, "password rehashed with new bcrypt cost", map[string]any{
+		"user_id": user.ID.String(),
+		"cost":    s.bcryptCost,
+	})
+}
@@ -0,0 +1,99 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+
+	"auth-service/models"
+	"auth-service/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	rehashAccessSecret  = "access-secret-access-secret-access"
+	rehashRefreshSecret = "refresh-secret-refresh-secret-refresh"
+)
+
+func newUserWithCost(t *testing.T, cost int) *models.User {
+	t.Helper()
+	hash, err := utils.HashPasswordWithCost(strongPassword, cost)
+	require.NoError(t, err)
+	return &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com", Password: hash}
+}
+
+func expectTokensIssued(mock sqlmock.Sqlmock) {
+	mock.ExpectPrepare("INSERT INTO refresh_token_families").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+}
+
+func TestAuthService_SignIn_RehashesWeakerHash(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetBcryptCost(11)
+	user := newUserWithCost(t, 10)
+
+	expectUserLookup(mock, user)
+	var newHash string
+	mock.ExpectPrepare("UPDATE users").
+		ExpectExec().
+		WithArgs(stringArg{value: &newHash}, sqlmock.AnyArg(), user.ID.String(), user.Password).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectTokensIssued(mock)
+
+	credentials := &models.Credentials{Email: user.Email, Password: strongPassword}
+	signedIn, _, _, err := service.SignIn(context.Background(), credentials, rehashAccessSecret, rehashRefreshSecret)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	cost, err := bcrypt.Cost([]byte(newHash))
+	require.NoError(t, err)
+	assert.Equal(t, 11, cost)
+	assert.True(t, utils.CheckPasswordHash(strongPassword, newHash))
+	assert.Equal(t, newHash, signedIn.Password)
+}
+
+func TestAuthService_SignIn_KeepsHashAtTargetCost(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetBcryptCost(10)
+	user := newUserWithCost(t, 10)
+	originalHash := user.Password
+
+	expectUserLookup(mock, user)
+	expectTokensIssued(mock)
+
+	credentials := &models.Credentials{Email: user.Email, Password: strongPassword}
+	signedIn, _, _, err := service.SignIn(context.Background(), credentials, rehashAccessSecret, rehashRefreshSecret)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, originalHash, signedIn.Password, "a hash at the target cost must not be rewritten")
+}
+
+func TestAuthService_SignIn_RehashDoesNotUndoConcurrentPasswordChange(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.SetBcryptCost(11)
+	user := newUserWithCost(t, 10)
+	originalHash := user.Password
+
+	expectUserLookup(mock, user)
+	// A password reset committed after the lookup, so the stored hash no longer matches
+	// and the compare-and-swap updates nothing
+	mock.ExpectPrepare("UPDATE users").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg(), user.ID.String(), originalHash).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	expectTokensIssued(mock)
+
+	credentials := &models.Credentials{Email: user.Email, Password: strongPassword}
+	signedIn, _, _, err := service.SignIn(context.Background(), credentials, rehashAccessSecret, rehashRefreshSecret)
+
+	require.NoError(t, err, "a skipped rehash must not fail the sign in")
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, originalHash, signedIn.Password)
+}
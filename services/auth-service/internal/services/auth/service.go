@@ -2,20 +2,27 @@ package authentication
 
 import (
 	"auth-service/internal/repository"
+	"auth-service/utils"
 
 	zlog "packages/logger"
 )
 
 // AuthService handles authentication operations
 type AuthService struct {
-	DB     *repository.DB
-	logger *zlog.Logger
+	DB          *repository.DB
+	logger      *zlog.Logger
+	resetSender PasswordResetSender
+	lockout     LockoutPolicy
+	bcryptCost  int
+	tokenScope  utils.TokenScope
 }
 
 // NewAuthService creates a new authentication service
 func NewAuthService(db *repository.DB, logger *zlog.Logger) *AuthService {
 	return &AuthService{
-		DB:     db,
-		logger: logger,
+		DB:          db,
+		logger:      logger,
+		resetSender: logPasswordResetSender{logger: logger},
+		bcryptCost:  utils.DefaultBcryptCost,
 	}
 }
@@ -0,0 +1,68 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"auth-service/internal/repository"
+	"auth-service/models"
+
+	"github.com/google/uuid"
+)
+
+// ErrSessionNotFound is returned when revoking a session the user does not have
+var ErrSessionNotFound = errors.New("session not found")
+
+// ListSessions returns the active sessions of a user, most recently seen first
+func (s *AuthService) ListSessions(ctx context.Context, userID uuid.UUID) ([]models.Session, error) {
+	sessions, err := s.DB.ListSessions(ctx, userID)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to list sessions", http.StatusInternalServerError, map[string]any{
+			"user_id": userID.String(),
+		})
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs a user out of one of their sessions, revoking its access and refresh
+// tokens. It returns ErrSessionNotFound for sessions of other users, so their IDs cannot be
+// probed.
+func (s *AuthService) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID) error {
+	if err := s.DB.RevokeSession(ctx, userID, sessionID); err != nil {
+		if errors.Is(err, repository.ErrSessionNotFound) {
+			return ErrSessionNotFound
+		}
+		s.logger.Error(ctx, err, "failed to revoke session", http.StatusInternalServerError, map[string]any{
+			"user_id":    userID.String(),
+			"session_id": sessionID.String(),
+		})
+		return err
+	}
+
+	s.logger.Info(ctx, "session revoked successfully", map[string]any{
+		"user_id":    userID.String(),
+		"session_id": sessionID.String(),
+	})
+	return nil
+}
+
+// RevokeAllUserTokens signs a user out of every session, so none of their access tokens
+// validate any more. Administrators use it to lock out a compromised account; the user
+// can still sign in again.
+func (s *AuthService) RevokeAllUserTokens(ctx context.Context, userID uuid.UUID) (int64, error) {
+	revoked, err := s.DB.RevokeAllUserTokens(ctx, userID)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to revoke all user tokens", http.StatusInternalServerError, map[string]any{
+			"user_id": userID.String(),
+		})
+		return 0, err
+	}
+
+	s.logger.Info(ctx, "all user tokens revoked successfully", map[string]any{
+		"user_id": userID.String(),
+		"revoked": revoked,
+	})
+	return revoked, nil
+}
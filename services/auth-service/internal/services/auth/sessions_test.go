@@ -0,0 +1,157 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/models"
+	"auth-service/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// expectAccessToken returns the stored token row for accessToken
+func expectAccessToken(mock sqlmock.Sqlmock, userID, familyID uuid.UUID, accessToken string, revoked bool) {
+	now := time.Now()
+	mock.ExpectPrepare("FROM user_tokens").
+		ExpectQuery().
+		WithArgs(accessToken).
+		WillReturnRows(sqlmock.NewRows([]string{
+			"id", "user_id", "access_token", "refresh_token", "access_expires_at",
+			"refresh_expires_at", "is_revoked", "family_id", "created_at",
+		}).AddRow(
+			uuid.New(), userID, accessToken, "refresh-token", now.Add(time.Minute),
+			now.Add(24*time.Hour), revoked, familyID, now,
+		))
+}
+
+func TestAuthService_ListSessions(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	userID := uuid.New()
+	laptop, phone := uuid.New(), uuid.New()
+	now := time.Now()
+
+	mock.ExpectPrepare("FROM sessions").
+		ExpectQuery().
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "user_agent", "ip_address", "created_at", "last_seen_at"}).
+			AddRow(laptop, userID, "laptop/1.0", "203.0.113.7", now.Add(-time.Hour), now).
+			AddRow(phone, userID, "phone/2.0", "198.51.100.4", now.Add(-2*time.Hour), now.Add(-time.Hour)))
+
+	sessions, err := service.ListSessions(context.Background(), userID)
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	require.Len(t, sessions, 2)
+	assert.Equal(t, laptop, sessions[0].ID)
+	assert.Equal(t, "laptop/1.0", sessions[0].UserAgent)
+	assert.Equal(t, phone, sessions[1].ID)
+	assert.Equal(t, "198.51.100.4", sessions[1].IPAddress)
+}
+
+func TestAuthService_RevokeSession(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+	sessionID := uuid.New()
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE refresh_token_families").
+		ExpectExec().
+		WithArgs(sessionID, user.ID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(sessionID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, service.RevokeSession(context.Background(), user.ID, sessionID))
+
+	// The access token issued in the session no longer validates
+	accessToken, err := utils.GenerateAccessToken(user, testAccessSecret, utils.TokenScope{})
+	require.NoError(t, err)
+	expectAccessToken(mock, user.ID, sessionID, accessToken, true)
+
+	validated, err := service.ValidateToken(context.Background(), accessToken, testAccessSecret)
+
+	assert.EqualError(t, err, "token revoked")
+	assert.Nil(t, validated)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_RevokeSession_NotFound(t *testing.T) {
+	service, mock := newMockAuthService(t)
+
+	// Another user's session, or one already revoked, matches no rows
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE refresh_token_families").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := service.RevokeSession(context.Background(), uuid.New(), uuid.New())
+
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_RevokeAllUserTokens(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	compromised := &models.User{ID: uuid.New(), Name: "Compromised User", Email: "compromised@example.com"}
+	other := &models.User{ID: uuid.New(), Name: "Other User", Email: "other@example.com"}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE refresh_token_families").
+		ExpectExec().
+		WithArgs(compromised.ID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(compromised.ID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	revoked, err := service.RevokeAllUserTokens(context.Background(), compromised.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), revoked)
+
+	// A token the user held before is rejected
+	compromisedToken, err := utils.GenerateAccessToken(compromised, testAccessSecret, utils.TokenScope{})
+	require.NoError(t, err)
+	expectAccessToken(mock, compromised.ID, uuid.New(), compromisedToken, true)
+
+	validated, err := service.ValidateToken(context.Background(), compromisedToken, testAccessSecret)
+	assert.EqualError(t, err, "token revoked")
+	assert.Nil(t, validated)
+
+	// Other users stay signed in
+	otherToken, err := utils.GenerateAccessToken(other, testAccessSecret, utils.TokenScope{})
+	require.NoError(t, err)
+	expectAccessToken(mock, other.ID, uuid.New(), otherToken, false)
+	expectUserLookup(mock, other)
+
+	validated, err = service.ValidateToken(context.Background(), otherToken, testAccessSecret)
+	require.NoError(t, err)
+	assert.Equal(t, other.ID, validated.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_Signout_EndsSession(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	userID, familyID := uuid.New(), uuid.New()
+	const accessToken = "signed-out-access-token"
+
+	expectAccessToken(mock, userID, familyID, accessToken, false)
+	mock.ExpectPrepare("UPDATE user_tokens").
+		ExpectExec().
+		WithArgs(accessToken).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	expectFamilyRevoked(mock, familyID)
+
+	require.NoError(t, service.Signout(context.Background(), accessToken))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
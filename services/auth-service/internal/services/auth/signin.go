@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"auth-service/models"
 	"auth-service/utils"
@@ -23,10 +24,19 @@ func (s *AuthService) SignIn(ctx context.Context, credentials *models.Credential
 		return nil, "", "", fmt.Errorf("validation error: %w", err)
 	}
 
+	// Refuse locked emails and addresses before looking at the password, so a correct
+	// guess during the cooldown is not told apart from a wrong one
+	now := time.Now().UTC()
+	emailKey, attemptKeys := loginAttemptKeys(credentials.Email, credentials.ClientIP)
+	if err := s.checkLockout(ctx, attemptKeys, now); err != nil {
+		return nil, "", "", err
+	}
+
 	// Get user by email
 	user, err := s.DB.GetUserByEmail(ctx, credentials.Email)
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to fetch user", http.StatusInternalServerError, nil)
+		s.recordSignInFailure(ctx, attemptKeys, now)
 		return nil, "", "", errors.New("invalid credentials")
 	}
 
@@ -34,11 +44,17 @@ func (s *AuthService) SignIn(ctx context.Context, credentials *models.Credential
 	if !utils.CheckPasswordHash(credentials.Password, user.Password) {
 		err := fmt.Errorf("invalid email or password")
 		s.logger.Error(ctx, err, "password mismatch", http.StatusUnauthorized, nil)
+		s.recordSignInFailure(ctx, attemptKeys, now)
 		return nil, "", "", errors.New("invalid credentials")
 	}
+	s.clearSignInFailures(ctx, emailKey)
+	s.rehashPasswordIfNeeded(ctx, user, credentials.Password)
 
 	// Generate tokens
-	accessToken, refreshToken, err := s.GenerateTokens(ctx, user, accessSecret, refreshSecret)
+	accessToken, refreshToken, err := s.GenerateTokens(ctx, user, models.ClientInfo{
+		UserAgent: credentials.UserAgent,
+		IPAddress: credentials.ClientIP,
+	}, accessSecret, refreshSecret)
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to generate token", http.StatusInternalServerError, nil)
 		return nil, "", "", err
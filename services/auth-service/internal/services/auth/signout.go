@@ -6,18 +6,36 @@ import (
 	"net/http"
 )
 
-// Signout revokes all tokens for a user
+// Signout revokes an access token and ends the session it was issued in, so the session's
+// refresh token stops working too
 func (s *AuthService) Signout(ctx context.Context, accessToken string) error {
 	if accessToken == "" {
 		err := errors.New("access token can not be empty")
 		s.logger.Error(ctx, err, "validation error", http.StatusBadRequest)
 		return err
 	}
+
+	token, err := s.DB.GetTokenByAccessToken(ctx, accessToken)
+	if err != nil {
+		s.logger.Error(ctx, err, "failed to signout user", http.StatusUnauthorized, nil)
+		return err
+	}
+
 	if err := s.RevokeToken(ctx, accessToken); err != nil {
 		s.logger.Error(ctx, err, "failed to signout user", http.StatusInternalServerError, nil)
 		return err
 	}
 
+	// Tokens issued before sessions existed have no family to revoke
+	if token.FamilyID != nil {
+		if err := s.DB.RevokeTokenFamily(ctx, *token.FamilyID); err != nil {
+			s.logger.Error(ctx, err, "failed to end session", http.StatusInternalServerError, map[string]any{
+				"session_id": token.FamilyID.String(),
+			})
+			return err
+		}
+	}
+
 	s.logger.Info(ctx, "user signed out successfully", map[string]any{
 		"access_token": accessToken,
 	})
@@ -6,16 +6,22 @@ import (
 	"net/http"
 	"time"
 
+	"auth-service/internal/repository"
 	"auth-service/models"
 	"auth-service/utils"
 )
 
+// userAlreadyExistsError reports a signup for an email that is already registered
+func userAlreadyExistsError(err error) *utils.AppError {
+	return utils.NewConflictError(utils.ErrCodeUserAlreadyExists, "a user with this email already exists", err)
+}
+
 // SignUp registers a new user
 func (s *AuthService) SignUp(ctx context.Context, req *models.UserCreateRequest) (*models.User, error) {
 	// Check if user already exists
 	existingUser, _ := s.DB.GetUserByEmail(ctx, req.Email)
 	if existingUser != nil {
-		err := errors.New("user already exists")
+		err := userAlreadyExistsError(nil)
 		s.logger.Error(ctx, err, "user already exists", http.StatusConflict, map[string]any{
 			"email": req.Email,
 		})
@@ -23,7 +29,7 @@ func (s *AuthService) SignUp(ctx context.Context, req *models.UserCreateRequest)
 	}
 
 	// Hash password
-	hashedPassword, err := utils.HashPassword(req.Password)
+	hashedPassword, err := utils.HashPasswordWithCost(req.Password, s.bcryptCost)
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to hash password", http.StatusInternalServerError, nil)
 		return nil, err
@@ -39,6 +45,14 @@ func (s *AuthService) SignUp(ctx context.Context, req *models.UserCreateRequest)
 	}
 
 	user, err = s.DB.CreateUser(ctx, user)
+	// A concurrent signup with the same email passes the check above; the unique index
+	// on users.email rejects it here instead
+	if errors.Is(err, repository.ErrUniqueViolation) {
+		s.logger.Error(ctx, err, "user already exists", http.StatusConflict, map[string]any{
+			"email": req.Email,
+		})
+		return nil, userAlreadyExistsError(err)
+	}
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to create user", http.StatusInternalServerError, nil)
 		return nil, err
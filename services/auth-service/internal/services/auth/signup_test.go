@@ -0,0 +1,77 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"auth-service/models"
+	"auth-service/utils"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newSignUpRequest() *models.UserCreateRequest {
+	return &models.UserCreateRequest{Name: "Test User", Email: "test@example.com", Password: "Password123"}
+}
+
+// expectEmailLookup answers the existing user check, with a row when registered is set
+func expectEmailLookup(mock sqlmock.Sqlmock, registered bool) {
+	rows := sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"})
+	if registered {
+		now := time.Now()
+		rows.AddRow(uuid.New(), "Test User", "test@example.com", "hash", now, now)
+	}
+	mock.ExpectPrepare("FROM users").ExpectQuery().WillReturnRows(rows)
+}
+
+func TestAuthService_SignUp_DuplicateEmail(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.bcryptCost = bcrypt.MinCost
+	userID := uuid.New()
+	now := time.Now()
+
+	expectEmailLookup(mock, false)
+	mock.ExpectPrepare("INSERT INTO users").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "created_at", "updated_at"}).
+			AddRow(userID, "Test User", "test@example.com", now, now))
+	expectEmailLookup(mock, true)
+
+	user, err := service.SignUp(context.Background(), newSignUpRequest())
+	require.NoError(t, err)
+	assert.Equal(t, userID, user.ID)
+
+	user, err = service.SignUp(context.Background(), newSignUpRequest())
+
+	assert.Nil(t, user)
+	appErr := utils.GetAppError(err)
+	require.NotNil(t, appErr)
+	assert.Equal(t, utils.ErrCodeUserAlreadyExists, appErr.Code)
+	assert.Equal(t, utils.ErrorTypeConflict, appErr.Type)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAuthService_SignUp_ConcurrentDuplicateHitsUniqueIndex(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	service.bcryptCost = bcrypt.MinCost
+
+	expectEmailLookup(mock, false)
+	mock.ExpectPrepare("INSERT INTO users").
+		ExpectQuery().
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "users_email_key"})
+
+	user, err := service.SignUp(context.Background(), newSignUpRequest())
+
+	assert.Nil(t, user)
+	appErr := utils.GetAppError(err)
+	require.NotNil(t, appErr)
+	assert.Equal(t, utils.ErrCodeUserAlreadyExists, appErr.Code)
+	assert.Equal(t, 409, appErr.StatusCode)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
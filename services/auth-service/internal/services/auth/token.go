@@ -10,13 +10,20 @@ import (
 	"auth-service/utils"
 )
 
-// GenerateTokens creates access and refresh tokens for a user
-func (s *AuthService) GenerateTokens(ctx context.Context, user *models.User, accessSecret, refreshSecret string) (string, string, error) {
+// SetTokenScope sets the issuer and audience tokens are generated with and required to
+// carry on validation
+func (s *AuthService) SetTokenScope(scope utils.TokenScope) {
+	s.tokenScope = scope
+}
+
+// GenerateTokens creates access and refresh tokens for a user, starting a new session for
+// client
+func (s *AuthService) GenerateTokens(ctx context.Context, user *models.User, client models.ClientInfo, accessSecret, refreshSecret string) (string, string, error) {
 	now := time.Now()
 	accessExpiresAt := now.Add(15 * time.Minute)
 	refreshExpiresAt := now.Add(7 * 24 * time.Hour)
 
-	accessToken, err := utils.GenerateAccessToken(user, accessSecret)
+	accessToken, err := utils.GenerateAccessToken(user, accessSecret, s.tokenScope)
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to generate access token", http.StatusInternalServerError, map[string]any{
 			"user_id": user.ID.String(),
@@ -24,7 +31,7 @@ func (s *AuthService) GenerateTokens(ctx context.Context, user *models.User, acc
 		return "", "", err
 	}
 
-	refreshToken, err := utils.GenerateRefreshToken(user, refreshSecret)
+	refreshToken, err := utils.GenerateRefreshToken(user, refreshSecret, s.tokenScope)
 	if err != nil {
 		s.logger.Error(ctx, err, "failed to generate refresh token", http.StatusInternalServerError, map[string]any{
 			"user_id": user.ID.String(),
@@ -32,7 +39,7 @@ func (s *AuthService) GenerateTokens(ctx context.Context, user *models.User, acc
 		return "", "", err
 	}
 
-	if err := s.DB.StoreTokens(ctx, user.ID, accessToken, refreshToken, accessExpiresAt, refreshExpiresAt); err != nil {
+	if err := s.DB.StoreTokens(ctx, user.ID, client, accessToken, refreshToken, accessExpiresAt, refreshExpiresAt); err != nil {
 		s.logger.Error(ctx, err, "failed to store tokens", http.StatusInternalServerError, map[string]any{
 			"user_id": user.ID.String(),
 		})
@@ -45,17 +52,18 @@ func (s *AuthService) GenerateTokens(ctx context.Context, user *models.User, acc
 	return accessToken, refreshToken, nil
 }
 
-// ValidateToken validates an access token
-func (s *AuthService) ValidateToken(ctx context.Context, accessToken string, secret string) (*models.User, error) {
+// ValidateToken validates an access token signed with secret or one of the retired
+// previousSecrets
+func (s *AuthService) ValidateToken(ctx context.Context, accessToken string, secret string, previousSecrets ...string) (*models.User, error) {
 	// First validate the JWT token
-	claims, err := utils.ValidateToken(accessToken, secret)
+	claims, err := utils.ValidateToken(accessToken, s.tokenScope, secret, previousSecrets...)
 	if err != nil {
 		s.logger.Error(ctx, err, "invalid JWT token", http.StatusUnauthorized, nil)
 		return nil, errors.New("invalid token")
 	}
 
 	// Validate token type
-	if tokenType, ok := claims["type"].(string); !ok || tokenType != "access" {
+	if tokenType, err := claims.String("type"); err != nil || tokenType != "access" {
 		s.logger.Error(ctx, errors.New("invalid token type"), "invalid token claims", http.StatusUnauthorized, nil)
 		return nil, errors.New("invalid token")
 	}
@@ -0,0 +1,69 @@
+package authentication
+
+import (
+	"context"
+	"time"
+
+	zlog "packages/logger"
+	"packages/periodic"
+)
+
+// tokenCleanupTimeout bounds a single cleanup pass
+const tokenCleanupTimeout = time.Minute
+
+// RevokedTokenPruner deletes revoked tokens that have expired. It is implemented by
+// repository.DB.
+type RevokedTokenPruner interface {
+	PruneExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error)
+}
+
+// TokenJanitor periodically deletes revoked tokens past their expiry, so the revocation
+// tables only hold tokens that would otherwise still be accepted
+type TokenJanitor struct {
+	pruner RevokedTokenPruner
+	logger *zlog.Logger
+	now    func() time.Time // replaced in tests
+	job    *periodic.Job
+}
+
+// NewTokenJanitor creates a janitor that prunes expired revoked tokens every interval
+func NewTokenJanitor(pruner RevokedTokenPruner, logger *zlog.Logger, interval time.Duration) *TokenJanitor {
+	j := &TokenJanitor{
+		pruner: pruner,
+		logger: logger,
+		now:    time.Now,
+	}
+	j.job = periodic.New(interval, tokenCleanupTimeout, j.runLogged)
+	return j
+}
+
+// Start runs a cleanup pass right away and then once per interval in the background,
+// until Stop is called or ctx is done
+func (j *TokenJanitor) Start(ctx context.Context) {
+	j.job.Start(ctx)
+}
+
+// Stop stops the background job and waits for a running pass to finish
+func (j *TokenJanitor) Stop() {
+	j.job.Stop()
+}
+
+// RunOnce deletes the revoked tokens that expired by now and returns how many were removed
+func (j *TokenJanitor) RunOnce(ctx context.Context) (int64, error) {
+	return j.pruner.PruneExpiredRevokedTokens(ctx, j.now().UTC())
+}
+
+// runLogged runs one cleanup pass, logging its outcome
+func (j *TokenJanitor) runLogged(ctx context.Context) {
+	pruned, err := j.RunOnce(ctx)
+	if err != nil {
+		j.logger.Warn(ctx, "Failed to prune expired revoked tokens", map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	j.logger.Info(ctx, "Pruned expired revoked tokens", map[string]any{
+		"pruned": pruned,
+	})
+}
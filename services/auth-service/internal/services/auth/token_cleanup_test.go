@@ -0,0 +1,98 @@
+package authentication
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	zlog "packages/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryTokenPruner holds revoked tokens by their expiry
+type memoryTokenPruner struct {
+	mu     sync.Mutex
+	tokens map[string]time.Time
+	runs   int
+}
+
+func (p *memoryTokenPruner) PruneExpiredRevokedTokens(ctx context.Context, now time.Time) (int64, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.runs++
+
+	var pruned int64
+	for token, expiresAt := range p.tokens {
+		if !expiresAt.After(now) {
+			delete(p.tokens, token)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (p *memoryTokenPruner) runCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.runs
+}
+
+func TestTokenJanitor_RunOnce_RemovesOnlyExpiredTokens(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	pruner := &memoryTokenPruner{tokens: map[string]time.Time{
+		"expired-yesterday": now.Add(-24 * time.Hour),
+		"expired-just-now":  now,
+		"live":              now.Add(time.Minute),
+		"live-long":         now.Add(7 * 24 * time.Hour),
+	}}
+	janitor := NewTokenJanitor(pruner, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), time.Hour)
+	janitor.now = func() time.Time { return now }
+
+	pruned, err := janitor.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), pruned)
+	assert.Equal(t, map[string]time.Time{
+		"live":      now.Add(time.Minute),
+		"live-long": now.Add(7 * 24 * time.Hour),
+	}, pruner.tokens)
+}
+
+func TestTokenJanitor_RunOnce_PrunesBothRevocationTables(t *testing.T) {
+	service, mock := newMockAuthService(t)
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	janitor := NewTokenJanitor(service.DB, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), time.Hour)
+	janitor.now = func() time.Time { return now }
+
+	mock.ExpectPrepare("DELETE FROM user_tokens").
+		ExpectExec().
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectPrepare("DELETE FROM revoked_tokens").
+		ExpectExec().
+		WithArgs(now).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	pruned, err := janitor.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, int64(5), pruned)
+}
+
+func TestTokenJanitor_StartRunsImmediatelyAndStops(t *testing.T) {
+	pruner := &memoryTokenPruner{tokens: map[string]time.Time{}}
+	janitor := NewTokenJanitor(pruner, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), time.Hour)
+
+	janitor.Start(context.Background())
+	require.Eventually(t, func() bool { return pruner.runCount() == 1 }, time.Second, 5*time.Millisecond)
+
+	janitor.Stop()
+	janitor.Stop() // stopping twice is harmless
+	assert.Equal(t, 1, pruner.runCount())
+}
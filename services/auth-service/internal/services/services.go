@@ -1,7 +1,10 @@
 package services
 
 import (
+	"time"
+
 	"auth-service/config"
+	"auth-service/internal/services/audit"
 	auth "auth-service/internal/services/auth"
 	"auth-service/internal/services/users"
 	"auth-service/internal/repository"
@@ -15,14 +18,25 @@ type Service struct {
 	DB     *repository.DB
 	User   *users.UserService
 	Auth   *auth.AuthService
+	Audit  *audit.AuditLogger
 }
 
 // NewService creates a new service instance
 func NewService(db *repository.DB, logger *zlog.Logger, cfg *config.Config) *Service {
+	authService := auth.NewAuthService(db, logger)
+	authService.SetLockoutPolicy(auth.LockoutPolicy{
+		Threshold: cfg.LoginLockoutThreshold,
+		Window:    time.Duration(cfg.LoginLockoutWindow) * time.Minute,
+		Cooldown:  time.Duration(cfg.LoginLockoutCooldown) * time.Minute,
+	})
+	authService.SetBcryptCost(cfg.BcryptCost)
+	authService.SetTokenScope(cfg.TokenScope())
+
 	return &Service{
 		Config: cfg,
 		DB:     db,
 		User:   users.NewUserService(db, logger),
-		Auth:   auth.NewAuthService(db, logger),
+		Auth:   authService,
+		Audit:  audit.NewAuditLogger(db, logger),
 	}
 }
@@ -25,6 +25,7 @@ func TestNewService(t *testing.T) {
 	assert.Nil(t, service.DB)
 	assert.NotNil(t, service.User)
 	assert.NotNil(t, service.Auth)
+	assert.NotNil(t, service.Audit)
 }
 
 func TestService_Structure(t *testing.T) {
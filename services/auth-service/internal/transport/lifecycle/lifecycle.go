@@ -14,23 +14,26 @@ import (
 	zlog "packages/logger"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
 )
 
 // Manager handles server lifecycle operations
 type Manager struct {
-	logger     *zlog.Logger
-	config     *config.HealthConfig
-	grpcServer *grpc.Server
-	restServer any // Will be *http.Server
-	grpcLis    net.Listener
-	restLis    net.Listener
+	logger       *zlog.Logger
+	config       *config.HealthConfig
+	serverConfig *config.ServerConfig
+	grpcServer   *grpc.Server
+	restServer   any // Will be *http.Server
+	grpcLis      net.Listener
+	restLis      net.Listener
 }
 
 // NewManager creates a new lifecycle manager
-func NewManager(logger *zlog.Logger, cfg *config.HealthConfig) *Manager {
+func NewManager(logger *zlog.Logger, cfg *config.HealthConfig, serverCfg *config.ServerConfig) *Manager {
 	return &Manager{
-		logger: logger,
-		config: cfg,
+		logger:       logger,
+		config:       cfg,
+		serverConfig: serverCfg,
 	}
 }
 
@@ -99,7 +102,7 @@ func (lm *Manager) waitForGRPCReady(ctx context.Context) error {
 			default:
 				// Try to connect to the gRPC server to check if it's ready
 				conn, err := grpc.Dial(lm.grpcLis.Addr().String(),
-					grpc.WithInsecure(),
+					grpc.WithTransportCredentials(insecure.NewCredentials()),
 					grpc.WithBlock(),
 					grpc.WithTimeout(100*time.Millisecond))
 				if err == nil {
@@ -131,28 +134,47 @@ func (lm *Manager) waitForGRPCReady(ctx context.Context) error {
 	}
 }
 
-// Shutdown gracefully shuts down both servers
+// Shutdown gracefully shuts down both servers, letting in-flight requests finish for up
+// to the configured shutdown timeout before they are cut off
 func (lm *Manager) Shutdown(ctx context.Context) error {
-	lm.logger.Info(ctx, "Shutting down servers")
+	lm.logger.Info(ctx, "Shutting down servers", map[string]any{
+		"timeout": lm.serverConfig.ShutdownTimeout.String(),
+	})
 
 	// Create a context with timeout for shutdown
-	shutdownCtx, cancel := context.WithTimeout(ctx, lm.config.Timeout)
+	shutdownCtx, cancel := context.WithTimeout(ctx, lm.serverConfig.ShutdownTimeout)
 	defer cancel()
 
-	// Graceful stop the gRPC server
-	if lm.grpcServer != nil {
-		lm.grpcServer.GracefulStop()
-	}
-
-	// Shutdown the REST server
+	// Shutdown the REST server first, since its requests are proxied to the gRPC server
 	if lm.restServer != nil {
 		if restServer, ok := lm.restServer.(interface{ Shutdown(context.Context) error }); ok {
 			if err := restServer.Shutdown(shutdownCtx); err != nil {
-				lm.logger.Error(shutdownCtx, err, "Failed to shutdown REST server", 500)
+				lm.logger.Warn(ctx, "REST server shutdown timed out, forcing close", map[string]any{
+					"error": err.Error(),
+				})
+				if closer, ok := lm.restServer.(interface{ Close() error }); ok {
+					closer.Close()
+				}
 			}
 		}
 	}
 
+	// Graceful stop the gRPC server, forcing it once the deadline passes
+	if lm.grpcServer != nil {
+		done := make(chan struct{})
+		go func() {
+			lm.grpcServer.GracefulStop()
+			close(done)
+		}()
+
+		select {
+		case <-done:
+		case <-shutdownCtx.Done():
+			lm.logger.Warn(ctx, "gRPC server shutdown timed out, forcing stop")
+			lm.grpcServer.Stop()
+		}
+	}
+
 	lm.logger.Info(ctx, "Server shutdown completed")
 	return nil
 }
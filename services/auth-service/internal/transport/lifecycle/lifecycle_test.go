@@ -0,0 +1,66 @@
+package lifecycle
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"auth-service/internal/config"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newShutdownTestManager serves handler alongside an empty gRPC server and returns the
+// manager with the REST base URL
+func newShutdownTestManager(t *testing.T, shutdownTimeout time.Duration, handler http.Handler) (*Manager, string) {
+	t.Helper()
+
+	grpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	restLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	restServer := &http.Server{Handler: handler}
+	go grpcServer.Serve(grpcLis)
+	go restServer.Serve(restLis)
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	lm := NewManager(logger, &config.HealthConfig{}, &config.ServerConfig{ShutdownTimeout: shutdownTimeout})
+	lm.SetServers(grpcServer, restServer, grpcLis, restLis)
+
+	return lm, "http://" + restLis.Addr().String()
+}
+
+func TestManager_ShutdownForcesStopAtConfiguredTimeout(t *testing.T) {
+	started := make(chan struct{})
+	lm, url := newShutdownTestManager(t, 200*time.Millisecond, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(3 * time.Second)
+		w.Write([]byte("done"))
+	}))
+
+	results := make(chan error, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err == nil {
+			_, err = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		results <- err
+	}()
+	<-started
+
+	begin := time.Now()
+	require.NoError(t, lm.Shutdown(context.Background()), "the manager forces the stop instead of failing")
+
+	assert.Less(t, time.Since(begin), time.Second, "shutdown should give up at the configured timeout")
+	assert.Error(t, <-results, "the request must be cut off")
+}
@@ -0,0 +1,87 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"auth-service/config"
+
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// clientIPCtxKey carries the resolved caller address from the metrics interceptor to the
+// interceptors and handlers after it
+type clientIPCtxKey struct{}
+
+// WithClientIP returns a copy of ctx carrying the caller's IP address
+func WithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPCtxKey{}, ip)
+}
+
+// ClientIPFromContext returns the caller's IP address stored by WithClientIP, or an empty
+// string when there is none
+func ClientIPFromContext(ctx context.Context) string {
+	ip, _ := ctx.Value(clientIPCtxKey{}).(string)
+	return ip
+}
+
+// ClientIPResolver finds the address of the caller behind a gRPC call, looking through
+// trusted proxies such as the REST gateway
+type ClientIPResolver struct {
+	trustedProxies []netip.Prefix
+	proxyHeader    string
+}
+
+// NewClientIPResolver creates a resolver from the TRUSTED_PROXY_* configuration
+func NewClientIPResolver(cfg *config.Config) (*ClientIPResolver, error) {
+	trustedProxies, err := parsePrefixes(cfg.TrustedProxyCIDRs)
+	if err != nil {
+		return nil, fmt.Errorf("invalid TRUSTED_PROXY_CIDRS: %w", err)
+	}
+
+	return &ClientIPResolver{
+		trustedProxies: trustedProxies,
+		proxyHeader:    strings.ToLower(cfg.TrustedProxyHeader),
+	}, nil
+}
+
+// ClientIP returns the caller's address, reporting false when the context carries no peer
+// or the proxy header is malformed. When the peer is a trusted proxy the proxy header is
+// walked from the right, skipping trusted proxies, and the first other hop is the caller;
+// entries left of it were supplied by the client and are not trusted. A nil resolver
+// trusts no proxies.
+func (r *ClientIPResolver) ClientIP(ctx context.Context) (netip.Addr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, false
+	}
+
+	addr, ok := parseAddr(p.Addr.String())
+	if !ok || r == nil || !containsAddr(r.trustedProxies, addr) {
+		return addr, ok
+	}
+
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return addr, true
+	}
+
+	values := md.Get(r.proxyHeader)
+	for i := len(values) - 1; i >= 0; i-- {
+		hops := strings.Split(values[i], ",")
+		for j := len(hops) - 1; j >= 0; j-- {
+			hop, ok := parseAddr(strings.TrimSpace(hops[j]))
+			if !ok {
+				return netip.Addr{}, false
+			}
+			if !containsAddr(r.trustedProxies, hop) {
+				return hop, true
+			}
+			addr = hop
+		}
+	}
+	return addr, true
+}
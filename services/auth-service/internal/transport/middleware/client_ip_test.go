@@ -0,0 +1,151 @@
+package middleware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"auth-service/config"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newTestClientIPResolver returns a resolver trusting proxies on loopback
+func newTestClientIPResolver(t *testing.T) *ClientIPResolver {
+	t.Helper()
+	resolver, err := NewClientIPResolver(&config.Config{
+		TrustedProxyCIDRs:  []string{"127.0.0.1/32", "::1/128"},
+		TrustedProxyHeader: "X-Forwarded-For",
+	})
+	require.NoError(t, err)
+	return resolver
+}
+
+// logEntries decodes the JSON log lines written to buf
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var entries []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &entry))
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func TestNewClientIPResolver_InvalidCIDR(t *testing.T) {
+	_, err := NewClientIPResolver(&config.Config{TrustedProxyCIDRs: []string{"not-a-cidr"}})
+	assert.Error(t, err)
+}
+
+func TestClientIPResolver_ClientIP(t *testing.T) {
+	resolver := newTestClientIPResolver(t)
+
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		wantIP string
+		wantOK bool
+	}{
+		{name: "direct peer", ctx: peerContext("203.0.113.7:54321"), wantIP: "203.0.113.7", wantOK: true},
+		{name: "ipv6 peer", ctx: peerContext("[2001:db8::1]:54321"), wantIP: "2001:db8::1", wantOK: true},
+		{name: "no peer", ctx: context.Background(), wantOK: false},
+		{name: "forwarded by trusted proxy", ctx: peerContext("127.0.0.1:40000", "x-forwarded-for", "198.51.100.4"), wantIP: "198.51.100.4", wantOK: true},
+		{name: "spoofed hop left of caller", ctx: peerContext("127.0.0.1:40000", "x-forwarded-for", "10.0.0.1, 198.51.100.4"), wantIP: "198.51.100.4", wantOK: true},
+		{name: "header from untrusted peer ignored", ctx: peerContext("203.0.113.7:54321", "x-forwarded-for", "198.51.100.4"), wantIP: "203.0.113.7", wantOK: true},
+		{name: "malformed header", ctx: peerContext("127.0.0.1:40000", "x-forwarded-for", "garbage"), wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			addr, ok := resolver.ClientIP(tt.ctx)
+			assert.Equal(t, tt.wantOK, ok)
+			if tt.wantOK {
+				assert.Equal(t, tt.wantIP, addr.String())
+			}
+		})
+	}
+}
+
+func TestClientIPResolver_NilTrustsNoProxies(t *testing.T) {
+	var resolver *ClientIPResolver
+
+	addr, ok := resolver.ClientIP(peerContext("127.0.0.1:40000", "x-forwarded-for", "198.51.100.4"))
+
+	assert.True(t, ok)
+	assert.Equal(t, "127.0.0.1", addr.String())
+}
+
+func TestMetricsMiddleware_LogsClientIP(t *testing.T) {
+	tests := []struct {
+		name   string
+		ctx    context.Context
+		err    error
+		wantIP string
+	}{
+		{name: "direct peer", ctx: peerContext("203.0.113.7:54321"), wantIP: "203.0.113.7"},
+		{name: "forwarded by trusted proxy", ctx: peerContext("127.0.0.1:40000", "x-forwarded-for", "198.51.100.4"), wantIP: "198.51.100.4"},
+		{name: "failed request", ctx: peerContext("203.0.113.7:54321"), err: errors.New("boom"), wantIP: "203.0.113.7"},
+		{name: "no peer", ctx: context.Background()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := zlog.New(zlog.Config{Level: "debug", JSONFormat: true, Output: &buf})
+			middleware := NewMetricsMiddleware(logger, newTestClientIPResolver(t))
+
+			var handlerIP string
+			_, _ = middleware.UnaryMetricsInterceptor()(tt.ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}, func(ctx context.Context, req any) (any, error) {
+				handlerIP = ClientIPFromContext(ctx)
+				return nil, tt.err
+			})
+
+			assert.Equal(t, tt.wantIP, handlerIP)
+			entries := logEntries(t, &buf)
+			require.Len(t, entries, 2)
+			for _, entry := range entries {
+				if tt.wantIP == "" {
+					assert.NotContains(t, entry, "client_ip")
+				} else {
+					assert.Equal(t, tt.wantIP, entry["client_ip"])
+				}
+			}
+		})
+	}
+}
+
+func TestMetricsMiddleware_StreamLogsClientIP(t *testing.T) {
+	var buf bytes.Buffer
+	logger := zlog.New(zlog.Config{Level: "debug", JSONFormat: true, Output: &buf})
+	middleware := NewMetricsMiddleware(logger, newTestClientIPResolver(t))
+
+	var handlerIP string
+	err := middleware.StreamMetricsInterceptor()(nil, &MockServerStream{ctx: peerContext("203.0.113.7:54321")}, &grpc.StreamServerInfo{FullMethod: "/test.Service/TestStream"}, func(srv any, stream grpc.ServerStream) error {
+		handlerIP = ClientIPFromContext(stream.Context())
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "203.0.113.7", handlerIP)
+	entries := logEntries(t, &buf)
+	require.Len(t, entries, 2)
+	for _, entry := range entries {
+		assert.Equal(t, "203.0.113.7", entry["client_ip"])
+	}
+}
+
+func TestPeerAddress_PrefersResolvedClientIP(t *testing.T) {
+	ctx := WithClientIP(peerContext("127.0.0.1:40000"), "198.51.100.4")
+
+	assert.Equal(t, "198.51.100.4", peerAddress(ctx))
+	assert.Equal(t, "127.0.0.1", peerAddress(peerContext("127.0.0.1:40000")))
+	assert.Empty(t, peerAddress(context.Background()))
+}
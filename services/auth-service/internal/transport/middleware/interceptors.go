@@ -5,10 +5,10 @@ import (
 	"time"
 
 	zlog "packages/logger"
+	sharedmw "packages/middleware"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
-	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
 
@@ -104,14 +104,11 @@ func StreamLoggingInterceptor(logger *zlog.Logger) grpc.StreamServerInterceptor
 	}
 }
 
-// extractCorrelationID extracts correlation ID from gRPC metadata
+// extractCorrelationID extracts the correlation ID from gRPC metadata: the request ID
+// forwarded by the REST gateway, or else an x-correlation-id sent by a gRPC client. IDs
+// that are not well formed are ignored, so a new one is generated instead.
 func extractCorrelationID(ctx context.Context) string {
-	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if correlationIDs := md.Get("x-correlation-id"); len(correlationIDs) > 0 {
-			return correlationIDs[0]
-		}
-	}
-	return ""
+	return sharedmw.IncomingRequestID(ctx, sharedmw.RequestIDMetadata, "x-correlation-id")
 }
 
 // wrappedServerStream wraps grpc.ServerStream to include correlation ID
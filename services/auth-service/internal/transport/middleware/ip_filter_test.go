@@ -0,0 +1,161 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"auth-service/config"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const listUsersMethod = "/auth.AuthService/ListUsers"
+
+// newTestIPFilter returns an IP filter guarding ListUsers, trusting proxies on loopback
+func newTestIPFilter(t *testing.T, allowed, denied []string) *IPFilterMiddleware {
+	t.Helper()
+	filter, err := NewIPFilterMiddleware(zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), &config.Config{
+		AdminAllowedCIDRs:    allowed,
+		AdminDeniedCIDRs:     denied,
+		AdminIPFilterMethods: []string{listUsersMethod},
+		TrustedProxyCIDRs:    []string{"127.0.0.1/32", "::1/128"},
+		TrustedProxyHeader:   "X-Forwarded-For",
+	})
+	require.NoError(t, err)
+	return filter
+}
+
+// callThrough runs the unary interceptor for method and reports the status code and whether
+// the handler was reached
+func callThrough(filter *IPFilterMiddleware, ctx context.Context, method string) (codes.Code, bool) {
+	called := false
+	_, err := filter.UnaryIPFilterInterceptor()(ctx, nil, &grpc.UnaryServerInfo{FullMethod: method}, func(ctx context.Context, req any) (any, error) {
+		called = true
+		return nil, nil
+	})
+	return status.Code(err), called
+}
+
+func TestIPFilter_AllowList(t *testing.T) {
+	filter := newTestIPFilter(t, []string{"10.0.0.0/8", "2001:db8::/32"}, nil)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		method   string
+		wantCode codes.Code
+	}{
+		{name: "in range", ctx: peerContext("10.1.2.3:54321"), method: listUsersMethod, wantCode: codes.OK},
+		{name: "in range ipv6", ctx: peerContext("[2001:db8::1]:54321"), method: listUsersMethod, wantCode: codes.OK},
+		{name: "ipv4 mapped ipv6 in range", ctx: peerContext("[::ffff:10.1.2.3]:54321"), method: listUsersMethod, wantCode: codes.OK},
+		{name: "out of range", ctx: peerContext("203.0.113.7:54321"), method: listUsersMethod, wantCode: codes.PermissionDenied},
+		{name: "no peer", ctx: context.Background(), method: listUsersMethod, wantCode: codes.PermissionDenied},
+		{name: "method not filtered", ctx: peerContext("203.0.113.7:54321"), method: "/auth.AuthService/SignIn", wantCode: codes.OK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, called := callThrough(filter, tt.ctx, tt.method)
+
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantCode == codes.OK, called)
+		})
+	}
+}
+
+func TestIPFilter_DenyListOverridesAllowList(t *testing.T) {
+	filter := newTestIPFilter(t, []string{"10.0.0.0/8"}, []string{"10.6.0.0/16"})
+
+	code, _ := callThrough(filter, peerContext("10.6.0.1:54321"), listUsersMethod)
+	assert.Equal(t, codes.PermissionDenied, code)
+
+	code, _ = callThrough(filter, peerContext("10.7.0.1:54321"), listUsersMethod)
+	assert.Equal(t, codes.OK, code)
+}
+
+func TestIPFilter_DenyListOnly(t *testing.T) {
+	filter := newTestIPFilter(t, nil, []string{"203.0.113.0/24"})
+
+	code, _ := callThrough(filter, peerContext("203.0.113.7:54321"), listUsersMethod)
+	assert.Equal(t, codes.PermissionDenied, code)
+
+	code, _ = callThrough(filter, peerContext("198.51.100.1:54321"), listUsersMethod)
+	assert.Equal(t, codes.OK, code)
+}
+
+func TestIPFilter_NoListsAllowsEverything(t *testing.T) {
+	filter := newTestIPFilter(t, nil, nil)
+
+	code, called := callThrough(filter, context.Background(), listUsersMethod)
+
+	assert.Equal(t, codes.OK, code)
+	assert.True(t, called)
+}
+
+func TestIPFilter_ForwardedFor(t *testing.T) {
+	filter := newTestIPFilter(t, []string{"10.0.0.0/8"}, nil)
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		wantCode codes.Code
+	}{
+		{
+			name:     "trusted proxy forwards allowed client",
+			ctx:      peerContext("127.0.0.1:54321", "x-forwarded-for", "10.1.2.3"),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "trusted proxy forwards disallowed client",
+			ctx:      peerContext("127.0.0.1:54321", "x-forwarded-for", "203.0.113.7"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "spoofed leftmost entry is ignored",
+			ctx:      peerContext("127.0.0.1:54321", "x-forwarded-for", "10.1.2.3, 203.0.113.7"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "trusted hops are skipped",
+			ctx:      peerContext("[::1]:54321", "x-forwarded-for", "203.0.113.7, 10.1.2.3, 127.0.0.1"),
+			wantCode: codes.OK,
+		},
+		{
+			name:     "malformed entry is denied",
+			ctx:      peerContext("127.0.0.1:54321", "x-forwarded-for", "not-an-ip"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "untrusted peer header is ignored",
+			ctx:      peerContext("203.0.113.7:54321", "x-forwarded-for", "10.1.2.3"),
+			wantCode: codes.PermissionDenied,
+		},
+		{
+			name:     "trusted proxy without header is checked itself",
+			ctx:      peerContext("127.0.0.1:54321"),
+			wantCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, _ := callThrough(filter, tt.ctx, listUsersMethod)
+
+			assert.Equal(t, tt.wantCode, code)
+		})
+	}
+}
+
+func TestNewIPFilterMiddleware_RejectsInvalidCIDR(t *testing.T) {
+	_, err := NewIPFilterMiddleware(zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), &config.Config{
+		AdminAllowedCIDRs: []string{"10.0.0.0/33"},
+	})
+
+	assert.ErrorContains(t, err, "invalid ADMIN_ALLOWED_CIDRS")
+}
@@ -2,31 +2,106 @@ package middleware
 
 import (
 	"context"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	zlog "packages/logger"
+	"packages/tracing"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 
 	"auth-service/config"
+	"auth-service/utils"
 )
 
-// MetricsMiddleware provides basic metrics collection
+// MetricsMiddleware provides basic metrics collection. Totals are kept in memory
+// and per-method counters and histograms are exported through a Prometheus registry.
 type MetricsMiddleware struct {
 	requestCount int64
 	errorCount   int64
-	responseTime time.Duration
+	responseTime time.Duration // cumulative across all requests
 	logger       *zlog.Logger
+	clientIPs    *ClientIPResolver
+
+	registry *prometheus.Registry
+	requests *prometheus.CounterVec
+	duration *prometheus.HistogramVec
 }
 
-// NewMetricsMiddleware creates a new metrics middleware
-func NewMetricsMiddleware(logger *zlog.Logger) *MetricsMiddleware {
-	return &MetricsMiddleware{
-		logger: logger,
+// NewMetricsMiddleware creates a new metrics middleware with its own registry. Callers are
+// identified through clientIPs; a nil resolver logs the peer address as is.
+func NewMetricsMiddleware(logger *zlog.Logger, clientIPs *ClientIPResolver) *MetricsMiddleware {
+	m := &MetricsMiddleware{
+		logger:    logger,
+		clientIPs: clientIPs,
+		registry:  prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of RPCs completed on the server, by method and status code.",
+		}, []string{"grpc_method", "grpc_code"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Histogram of RPC handling latency in seconds, by method.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_method"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests,
+		m.duration,
+		prometheus.NewGoCollector(),
+		prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{}),
+	)
+
+	return m
+}
+
+// Registry returns the Prometheus registry holding the collected metrics
+func (m *MetricsMiddleware) Registry() *prometheus.Registry {
+	return m.registry
+}
+
+// Handler returns an HTTP handler exposing the metrics in the Prometheus text format
+func (m *MetricsMiddleware) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// RequestCount returns the number of RPCs handled so far
+func (m *MetricsMiddleware) RequestCount() int64 {
+	return atomic.LoadInt64(&m.requestCount)
+}
+
+// ErrorCount returns the number of RPCs that returned an error
+func (m *MetricsMiddleware) ErrorCount() int64 {
+	return atomic.LoadInt64(&m.errorCount)
+}
+
+// ResponseTime returns the cumulative handling time of all RPCs
+func (m *MetricsMiddleware) ResponseTime() time.Duration {
+	return time.Duration(atomic.LoadInt64((*int64)(&m.responseTime)))
+}
+
+// record updates the totals and Prometheus collectors for a completed RPC
+func (m *MetricsMiddleware) record(method string, code codes.Code, duration time.Duration) {
+	atomic.AddInt64(&m.requestCount, 1)
+	if code != codes.OK {
+		atomic.AddInt64(&m.errorCount, 1)
 	}
+	atomic.AddInt64((*int64)(&m.responseTime), int64(duration))
+
+	m.requests.WithLabelValues(method, code.String()).Inc()
+	m.duration.WithLabelValues(method).Observe(duration.Seconds())
 }
 
 // UnaryMetricsInterceptor collects metrics for unary RPC calls
@@ -37,35 +112,38 @@ func (m *MetricsMiddleware) UnaryMetricsInterceptor() grpc.UnaryServerIntercepto
 		// Extract correlation ID from metadata
 		correlationID := extractCorrelationID(ctx)
 		ctx = zlog.WithCorrelationID(ctx, correlationID)
+		tracing.SetCorrelationID(ctx)
+		ctx, clientIP := m.withClientIP(ctx)
 
 		// Log request start
-		m.logger.Info(ctx, "gRPC request started", map[string]any{
+		m.logger.Info(ctx, "gRPC request started", withClientIPField(map[string]any{
 			"method":         info.FullMethod,
 			"correlation_id": correlationID,
-		})
+		}, clientIP))
 
 		// Handle the request
 		resp, err := handler(ctx, req)
 
 		// Calculate duration
 		duration := time.Since(start)
+		m.record(info.FullMethod, status.Code(err), duration)
 
 		// Log response
 		if err != nil {
 			st, _ := status.FromError(err)
-			m.logger.Error(ctx, err, "gRPC request failed", int(st.Code()), map[string]any{
+			m.logger.Error(ctx, err, "gRPC request failed", int(st.Code()), withClientIPField(map[string]any{
 				"method":         info.FullMethod,
 				"duration":       duration.String(),
 				"status_code":    st.Code(),
 				"correlation_id": correlationID,
-			})
+			}, clientIP))
 		} else {
-			m.logger.Info(ctx, "gRPC request completed", map[string]any{
+			m.logger.Info(ctx, "gRPC request completed", withClientIPField(map[string]any{
 				"method":         info.FullMethod,
 				"duration":       duration.String(),
 				"status_code":    codes.OK,
 				"correlation_id": correlationID,
-			})
+			}, clientIP))
 		}
 
 		return resp, err
@@ -80,6 +158,8 @@ func (m *MetricsMiddleware) StreamMetricsInterceptor() grpc.StreamServerIntercep
 		// Extract correlation ID from metadata
 		correlationID := extractCorrelationID(ss.Context())
 		ctx := zlog.WithCorrelationID(ss.Context(), correlationID)
+		tracing.SetCorrelationID(ctx)
+		ctx, clientIP := m.withClientIP(ctx)
 
 		// Create wrapped stream with correlation ID
 		wrappedStream := &wrappedServerStream{
@@ -88,39 +168,59 @@ func (m *MetricsMiddleware) StreamMetricsInterceptor() grpc.StreamServerIntercep
 		}
 
 		// Log stream start
-		m.logger.Info(ctx, "gRPC stream started", map[string]any{
+		m.logger.Info(ctx, "gRPC stream started", withClientIPField(map[string]any{
 			"method":         info.FullMethod,
 			"correlation_id": correlationID,
-		})
+		}, clientIP))
 
 		// Handle the stream
 		err := handler(srv, wrappedStream)
 
 		// Calculate duration
 		duration := time.Since(start)
+		m.record(info.FullMethod, status.Code(err), duration)
 
 		// Log stream completion
 		if err != nil {
 			st, _ := status.FromError(err)
-			m.logger.Error(ctx, err, "gRPC stream failed", int(st.Code()), map[string]any{
+			m.logger.Error(ctx, err, "gRPC stream failed", int(st.Code()), withClientIPField(map[string]any{
 				"method":         info.FullMethod,
 				"duration":       duration.String(),
 				"status_code":    st.Code(),
 				"correlation_id": correlationID,
-			})
+			}, clientIP))
 		} else {
-			m.logger.Info(ctx, "gRPC stream completed", map[string]any{
+			m.logger.Info(ctx, "gRPC stream completed", withClientIPField(map[string]any{
 				"method":         info.FullMethod,
 				"duration":       duration.String(),
 				"status_code":    codes.OK,
 				"correlation_id": correlationID,
-			})
+			}, clientIP))
 		}
 
 		return err
 	}
 }
 
+// withClientIP resolves the caller's IP address and stores it on ctx for the interceptors
+// after this one. The address is empty when the call carries no peer information.
+func (m *MetricsMiddleware) withClientIP(ctx context.Context) (context.Context, string) {
+	addr, ok := m.clientIPs.ClientIP(ctx)
+	if !ok {
+		return ctx, ""
+	}
+	ip := addr.String()
+	return WithClientIP(ctx, ip), ip
+}
+
+// withClientIPField adds the client_ip field to fields when the caller's address is known
+func withClientIPField(fields map[string]any, clientIP string) map[string]any {
+	if clientIP != "" {
+		fields["client_ip"] = clientIP
+	}
+	return fields
+}
+
 // RecoveryMiddleware provides panic recovery for gRPC calls
 type RecoveryMiddleware struct {
 	logger *zlog.Logger
@@ -165,27 +265,39 @@ func (r *RecoveryMiddleware) StreamRecoveryInterceptor() grpc.StreamServerInterc
 	}
 }
 
-// RateLimitMiddleware provides basic rate limiting (placeholder for future implementation)
+// RateLimitMiddleware provides sliding window rate limiting. Authenticated requests are
+// limited per user and anonymous requests per peer IP. Counters live in Redis when
+// REDIS_ADDR is configured so that all replicas share them, and in memory otherwise.
 type RateLimitMiddleware struct {
-	logger *zlog.Logger
-	config *config.Config
-	// In-memory rate limiter (for production, use Redis or similar)
-	clients map[string]*clientLimiter
-}
-
-// clientLimiter tracks rate limiting for a specific client
-type clientLimiter struct {
-	requests []time.Time
-	window   time.Duration
-	limit    int
+	logger  *zlog.Logger
+	config  *config.Config
+	limiter RateLimiter
 }
 
 // NewRateLimitMiddleware creates a new rate limit middleware
 func NewRateLimitMiddleware(logger *zlog.Logger, cfg *config.Config) *RateLimitMiddleware {
+	window := time.Duration(cfg.RateLimitWindow) * time.Second
+
+	var limiter RateLimiter
+	if cfg.RedisAddr != "" {
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.RedisAddr,
+			Password: cfg.RedisPassword,
+		})
+		limiter = NewRedisRateLimiter(client, cfg.RateLimitRequests, window)
+	} else {
+		limiter = NewMemoryRateLimiter(cfg.RateLimitRequests, window)
+	}
+
+	return NewRateLimitMiddlewareWithLimiter(logger, cfg, limiter)
+}
+
+// NewRateLimitMiddlewareWithLimiter creates a rate limit middleware using the given limiter
+func NewRateLimitMiddlewareWithLimiter(logger *zlog.Logger, cfg *config.Config, limiter RateLimiter) *RateLimitMiddleware {
 	return &RateLimitMiddleware{
 		logger:  logger,
 		config:  cfg,
-		clients: make(map[string]*clientLimiter),
+		limiter: limiter,
 	}
 }
 
@@ -196,13 +308,8 @@ func (rl *RateLimitMiddleware) UnaryRateLimitInterceptor() grpc.UnaryServerInter
 			return handler(ctx, req)
 		}
 
-		clientID := extractClientID(ctx)
-		if !rl.allowRequest(clientID) {
-			rl.logger.Warn(ctx, "Rate limit exceeded", map[string]any{
-				"client_id": clientID,
-				"method":    info.FullMethod,
-			})
-			return nil, status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		if err := rl.allowRequest(ctx, info.FullMethod); err != nil {
+			return nil, err
 		}
 
 		return handler(ctx, req)
@@ -216,65 +323,95 @@ func (rl *RateLimitMiddleware) StreamRateLimitInterceptor() grpc.StreamServerInt
 			return handler(srv, ss)
 		}
 
-		clientID := extractClientID(ss.Context())
-		if !rl.allowRequest(clientID) {
-			rl.logger.Warn(ss.Context(), "Rate limit exceeded for stream", map[string]any{
-				"client_id": clientID,
-				"method":    info.FullMethod,
-			})
-			return status.Error(codes.ResourceExhausted, "rate limit exceeded")
+		if err := rl.allowRequest(ss.Context(), info.FullMethod); err != nil {
+			return err
 		}
 
 		return handler(srv, ss)
 	}
 }
 
-// allowRequest checks if a request should be allowed based on rate limiting
-func (rl *RateLimitMiddleware) allowRequest(clientID string) bool {
-	now := time.Now()
-	window := time.Duration(rl.config.RateLimitWindow) * time.Second
-	limit := rl.config.RateLimitRequests
-
-	// Get or create client limiter
-	limiter, exists := rl.clients[clientID]
-	if !exists {
-		limiter = &clientLimiter{
-			requests: make([]time.Time, 0),
-			window:   window,
-			limit:    limit,
+// allowRequest checks the caller against the limiter and returns the gRPC error to send
+// when the request must be rejected. If the limiter is unavailable the request is allowed
+// when RATE_LIMIT_FAIL_OPEN is set and rejected otherwise.
+func (rl *RateLimitMiddleware) allowRequest(ctx context.Context, method string) error {
+	clientID := rl.extractClientID(ctx)
+
+	allowed, err := rl.limiter.Allow(ctx, clientID)
+	if err != nil {
+		rl.logger.Error(ctx, err, "Rate limiter unavailable", int(codes.Unavailable), map[string]any{
+			"client_id": clientID,
+			"method":    method,
+			"fail_open": rl.config.RateLimitFailOpen,
+		})
+		if rl.config.RateLimitFailOpen {
+			return nil
 		}
-		rl.clients[clientID] = limiter
+		return status.Error(codes.Unavailable, "rate limiter unavailable")
 	}
 
-	// Remove expired requests
-	var validRequests []time.Time
-	for _, reqTime := range limiter.requests {
-		if now.Sub(reqTime) <= window {
-			validRequests = append(validRequests, reqTime)
-		}
+	if !allowed {
+		rl.logger.Warn(ctx, "Rate limit exceeded", map[string]any{
+			"client_id": clientID,
+			"method":    method,
+		})
+		return status.Error(codes.ResourceExhausted, "rate limit exceeded")
 	}
 
-	// Check if we're under the limit
-	if len(validRequests) < limit {
-		validRequests = append(validRequests, now)
-		limiter.requests = validRequests
-		return true
-	}
+	return nil
+}
 
-	return false
+// Close releases the connection to the limiter's backing store, if any
+func (rl *RateLimitMiddleware) Close() error {
+	if closer, ok := rl.limiter.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
 }
 
-// extractClientID extracts client identifier from context
-func extractClientID(ctx context.Context) string {
-	// In a real implementation, this would extract from JWT token, IP, or other identifier
-	// For now, use a simple approach
-	if md, ok := metadata.FromIncomingContext(ctx); ok {
-		if clientIDs := md.Get("client-id"); len(clientIDs) > 0 {
-			return clientIDs[0]
-		}
-		if userAgents := md.Get("user-agent"); len(userAgents) > 0 {
-			return userAgents[0]
+// extractClientID identifies the caller for rate limiting. Requests carrying a valid
+// bearer access token are keyed by user ID, everything else by the client IP resolved by
+// the metrics interceptor, or the peer address when there is none.
+func (rl *RateLimitMiddleware) extractClientID(ctx context.Context) string {
+	if userID := rl.userIDFromToken(ctx); userID != "" {
+		return "user:" + userID
+	}
+
+	if ip := ClientIPFromContext(ctx); ip != "" {
+		return "ip:" + ip
+	}
+
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		addr := p.Addr.String()
+		if host, _, err := net.SplitHostPort(addr); err == nil {
+			return "ip:" + host
 		}
+		return "ip:" + addr
 	}
 	return "unknown"
 }
+
+// userIDFromToken returns the user ID of a valid bearer access token in the request
+// metadata, or an empty string when there is none
+func (rl *RateLimitMiddleware) userIDFromToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 || !strings.HasPrefix(tokens[0], "Bearer ") {
+		return ""
+	}
+
+	claims, err := utils.ValidateToken(strings.TrimPrefix(tokens[0], "Bearer "), rl.config.TokenScope(), rl.config.JWTAccessTokenSecret, rl.config.JWTPreviousSecrets...)
+	if err != nil {
+		return ""
+	}
+	if tokenType, _ := claims.String("type"); tokenType != "access" {
+		return ""
+	}
+
+	userID, _ := claims.String("user_id")
+	return userID
+}
@@ -2,15 +2,26 @@ package middleware
 
 import (
 	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
 	"auth-service/config"
+	"auth-service/utils"
 	zlog "packages/logger"
+	sharedmw "packages/middleware"
 
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
 )
 
@@ -26,7 +37,7 @@ func testConfig() *config.Config {
 func TestNewMetricsMiddleware(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
 
-	middleware := NewMetricsMiddleware(logger)
+	middleware := NewMetricsMiddleware(logger, nil)
 
 	assert.NotNil(t, middleware)
 	assert.Equal(t, logger, middleware.logger)
@@ -37,7 +48,7 @@ func TestNewMetricsMiddleware(t *testing.T) {
 
 func TestMetricsMiddleware_UnaryMetricsInterceptor(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
-	middleware := NewMetricsMiddleware(logger)
+	middleware := NewMetricsMiddleware(logger, nil)
 
 	interceptor := middleware.UnaryMetricsInterceptor()
 	assert.NotNil(t, interceptor)
@@ -63,7 +74,7 @@ func TestMetricsMiddleware_UnaryMetricsInterceptor(t *testing.T) {
 
 func TestMetricsMiddleware_UnaryMetricsInterceptor_Error(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
-	middleware := NewMetricsMiddleware(logger)
+	middleware := NewMetricsMiddleware(logger, nil)
 
 	interceptor := middleware.UnaryMetricsInterceptor()
 	assert.NotNil(t, interceptor)
@@ -94,7 +105,7 @@ func TestMetricsMiddleware_UnaryMetricsInterceptor_Error(t *testing.T) {
 
 func TestMetricsMiddleware_StreamMetricsInterceptor(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
-	middleware := NewMetricsMiddleware(logger)
+	middleware := NewMetricsMiddleware(logger, nil)
 
 	interceptor := middleware.StreamMetricsInterceptor()
 	assert.NotNil(t, interceptor)
@@ -259,7 +270,7 @@ func TestMiddleware_Structure(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
 
 	// Test MetricsMiddleware structure
-	metricsMiddleware := NewMetricsMiddleware(logger)
+	metricsMiddleware := NewMetricsMiddleware(logger, nil)
 	assert.NotNil(t, metricsMiddleware)
 	assert.NotNil(t, metricsMiddleware.logger)
 	assert.Equal(t, logger, metricsMiddleware.logger)
@@ -281,7 +292,7 @@ func TestMiddleware_MethodSignatures(t *testing.T) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
 
 	// Test MetricsMiddleware methods
-	metricsMiddleware := NewMetricsMiddleware(logger)
+	metricsMiddleware := NewMetricsMiddleware(logger, nil)
 	_ = metricsMiddleware.UnaryMetricsInterceptor
 	_ = metricsMiddleware.StreamMetricsInterceptor
 
@@ -299,6 +310,75 @@ func TestMiddleware_MethodSignatures(t *testing.T) {
 	assert.True(t, true)
 }
 
+func TestMetricsMiddleware_RecordsRequestsByMethodAndCode(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	middleware := NewMetricsMiddleware(logger, nil)
+	interceptor := middleware.UnaryMetricsInterceptor()
+
+	signIn := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+	ok := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+	unauthenticated := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Unauthenticated, "invalid credentials")
+	}
+
+	const successes, failures = 5, 3
+	for i := 0; i < successes; i++ {
+		_, _ = interceptor(context.Background(), "req", signIn, ok)
+	}
+	for i := 0; i < failures; i++ {
+		_, _ = interceptor(context.Background(), "req", signIn, unauthenticated)
+	}
+
+	streamInterceptor := middleware.StreamMetricsInterceptor()
+	streamInfo := &grpc.StreamServerInfo{FullMethod: "/auth.AuthService/Watch"}
+	_ = streamInterceptor(nil, &MockServerStream{ctx: context.Background()}, streamInfo, func(srv any, stream grpc.ServerStream) error {
+		return nil
+	})
+
+	assert.Equal(t, int64(successes+failures+1), middleware.RequestCount())
+	assert.Equal(t, int64(failures), middleware.ErrorCount())
+	assert.Greater(t, middleware.ResponseTime(), time.Duration(0))
+
+	assert.Equal(t, float64(successes), testutil.ToFloat64(middleware.requests.WithLabelValues("/auth.AuthService/SignIn", "OK")))
+	assert.Equal(t, float64(failures), testutil.ToFloat64(middleware.requests.WithLabelValues("/auth.AuthService/SignIn", "Unauthenticated")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(middleware.requests.WithLabelValues("/auth.AuthService/Watch", "OK")))
+
+	// The histogram observes every call of a method regardless of outcome
+	families, err := middleware.Registry().Gather()
+	require.NoError(t, err)
+	counts := map[string]uint64{}
+	for _, family := range families {
+		if family.GetName() != "grpc_server_handling_seconds" {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			counts[metric.GetLabel()[0].GetValue()] = metric.GetHistogram().GetSampleCount()
+		}
+	}
+	assert.Equal(t, map[string]uint64{
+		"/auth.AuthService/SignIn": successes + failures,
+		"/auth.AuthService/Watch":  1,
+	}, counts)
+}
+
+func TestMetricsMiddleware_Handler(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	middleware := NewMetricsMiddleware(logger, nil)
+	interceptor := middleware.UnaryMetricsInterceptor()
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignUp"}
+	_, _ = interceptor(context.Background(), "req", info, func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.AlreadyExists, "user exists")
+	})
+
+	rec := httptest.NewRecorder()
+	middleware.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `grpc_server_handled_total{grpc_code="AlreadyExists",grpc_method="/auth.AuthService/SignUp"} 1`)
+	assert.Contains(t, rec.Body.String(), `grpc_server_handling_seconds_count{grpc_method="/auth.AuthService/SignUp"} 1`)
+}
+
 // MockServerStream is a mock implementation for testing
 type MockServerStream struct {
 	grpc.ServerStream
@@ -312,7 +392,7 @@ func (m *MockServerStream) Context() context.Context {
 // Benchmark tests for performance
 func BenchmarkMetricsMiddleware_UnaryMetricsInterceptor(b *testing.B) {
 	logger := zlog.NewLogger(zlog.Config{Level: "debug"})
-	middleware := NewMetricsMiddleware(logger)
+	middleware := NewMetricsMiddleware(logger, nil)
 	interceptor := middleware.UnaryMetricsInterceptor()
 
 	ctx := context.Background()
@@ -351,3 +431,192 @@ func BenchmarkRecoveryMiddleware_UnaryRecoveryInterceptor(b *testing.B) {
 		_, _ = interceptor(ctx, req, info, handler)
 	}
 }
+
+const testAccessSecret = "this-is-a-very-long-secret-key-for-access-tokens-32"
+
+// newSingleRequestLimiter returns a rate limiter that allows one request per client and window
+func newSingleRequestLimiter() *RateLimitMiddleware {
+	cfg := testConfig()
+	cfg.RateLimitRequests = 1
+	cfg.JWTAccessTokenSecret = testAccessSecret
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	return NewRateLimitMiddleware(logger, cfg)
+}
+
+// peerContext returns a context for a request from the given address carrying the given metadata
+func peerContext(addr string, kv ...string) context.Context {
+	tcpAddr, _ := net.ResolveTCPAddr("tcp", addr)
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: tcpAddr})
+	if len(kv) > 0 {
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(kv...))
+	}
+	return ctx
+}
+
+// bearer returns an authorization header carrying a signed access token for userID
+func bearer(t *testing.T, userID string) string {
+	t.Helper()
+	token, err := utils.GenerateAccessTokenSimple(userID, userID+"@example.com", "Test", "user", testAccessSecret, utils.TokenScope{})
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+func TestRateLimitMiddleware_UsersHaveIndependentBuckets(t *testing.T) {
+	middleware := newSingleRequestLimiter()
+	interceptor := middleware.UnaryRateLimitInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/Profile"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	// Both users share an address, so only the token can tell them apart
+	alice := peerContext("10.0.0.1:5000", "authorization", bearer(t, "alice"))
+	bob := peerContext("10.0.0.1:5001", "authorization", bearer(t, "bob"))
+
+	_, err := interceptor(alice, "req", info, handler)
+	require.NoError(t, err)
+	_, err = interceptor(bob, "req", info, handler)
+	require.NoError(t, err)
+
+	_, err = interceptor(alice, "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	_, err = interceptor(bob, "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestRateLimitMiddleware_ExtractClientID(t *testing.T) {
+	middleware := newSingleRequestLimiter()
+
+	tests := []struct {
+		name     string
+		ctx      context.Context
+		expected string
+	}{
+		{
+			name:     "valid access token",
+			ctx:      peerContext("10.0.0.1:5000", "authorization", bearer(t, "alice")),
+			expected: "user:alice",
+		},
+		{
+			name:     "unauthenticated uses peer ip",
+			ctx:      peerContext("10.0.0.2:5000", "user-agent", "grpc-go"),
+			expected: "ip:10.0.0.2",
+		},
+		{
+			name:     "invalid token uses peer ip",
+			ctx:      peerContext("10.0.0.3:5000", "authorization", "Bearer not.a.token"),
+			expected: "ip:10.0.0.3",
+		},
+		{
+			name:     "resolved client ip preferred over peer",
+			ctx:      WithClientIP(peerContext("127.0.0.1:40000"), "198.51.100.4"),
+			expected: "ip:198.51.100.4",
+		},
+		{
+			name:     "no peer",
+			ctx:      context.Background(),
+			expected: "unknown",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, middleware.extractClientID(tt.ctx))
+		})
+	}
+}
+
+func TestRateLimitMiddleware_UnauthenticatedLimitedByIP(t *testing.T) {
+	middleware := newSingleRequestLimiter()
+	interceptor := middleware.UnaryRateLimitInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	// A different user agent from the same address shares the bucket
+	_, err := interceptor(peerContext("10.0.0.1:5000", "user-agent", "a"), "req", info, handler)
+	require.NoError(t, err)
+	_, err = interceptor(peerContext("10.0.0.1:5001", "user-agent", "b"), "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Another address has its own bucket
+	_, err = interceptor(peerContext("10.0.0.2:5000"), "req", info, handler)
+	assert.NoError(t, err)
+}
+
+func TestRateLimitMiddleware_ForwardedCallersHaveIndependentBuckets(t *testing.T) {
+	metrics := NewMetricsMiddleware(zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), newTestClientIPResolver(t))
+	limiter := newSingleRequestLimiter()
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+	// Chain the interceptors in the order the server registers them
+	call := func(ctx context.Context) error {
+		_, err := metrics.UnaryMetricsInterceptor()(ctx, "req", info, func(ctx context.Context, req any) (any, error) {
+			return limiter.UnaryRateLimitInterceptor()(ctx, req, info, func(ctx context.Context, req any) (any, error) { return "ok", nil })
+		})
+		return err
+	}
+
+	// Both callers come through the gateway on loopback, a trusted proxy
+	require.NoError(t, call(peerContext("127.0.0.1:40000", "x-forwarded-for", "198.51.100.4")))
+	require.NoError(t, call(peerContext("127.0.0.1:40001", "x-forwarded-for", "198.51.100.5")))
+
+	err := call(peerContext("127.0.0.1:40002", "x-forwarded-for", "198.51.100.4"))
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestMemoryRateLimiter_SweepsStaleClients(t *testing.T) {
+	limiter := NewMemoryRateLimiter(1, time.Minute).(*memoryRateLimiter)
+	ctx := context.Background()
+
+	for _, clientID := range []string{"ip:10.0.0.1", "ip:10.0.0.2"} {
+		allowed, err := limiter.Allow(ctx, clientID)
+		require.NoError(t, err)
+		require.True(t, allowed)
+	}
+	require.Len(t, limiter.clients, 2)
+
+	// Age the existing clients and the last sweep past the window
+	stale := time.Now().Add(-2 * time.Minute)
+	for _, client := range limiter.clients {
+		client.lastSeen = stale
+	}
+	limiter.lastSweep = stale
+
+	allowed, err := limiter.Allow(ctx, "ip:10.0.0.3")
+	require.NoError(t, err)
+	require.True(t, allowed)
+	assert.Len(t, limiter.clients, 1)
+	assert.Contains(t, limiter.clients, "ip:10.0.0.3")
+}
+
+func TestUnaryLoggingInterceptor_UsesForwardedRequestID(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	tests := []struct {
+		name string
+		md   metadata.MD
+		want string
+	}{
+		{name: "gateway request id", md: metadata.Pairs("x-request-id", "req-42", "x-correlation-id", "corr-1"), want: "req-42"},
+		{name: "client correlation id", md: metadata.Pairs("x-correlation-id", "corr-1"), want: "corr-1"},
+		{name: "unsafe request id skipped", md: metadata.Pairs("x-request-id", "bad id\r\nx", "x-correlation-id", "corr-1"), want: "corr-1"},
+		{name: "unsafe id replaced", md: metadata.Pairs("x-request-id", "bad id\r\nx")},
+		{name: "long id replaced", md: metadata.Pairs("x-correlation-id", strings.Repeat("a", sharedmw.MaxRequestIDLength+1))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			var seen string
+			_, err := UnaryLoggingInterceptor(logger)(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}, func(ctx context.Context, req any) (any, error) {
+				seen = zlog.CorrelationID(ctx)
+				return nil, nil
+			})
+
+			require.NoError(t, err)
+			if tt.want != "" {
+				assert.Equal(t, tt.want, seen)
+			} else {
+				assert.True(t, sharedmw.ValidRequestID(seen))
+			}
+		})
+	}
+}
@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+// RateLimiter decides whether a client may make another request within the current window
+type RateLimiter interface {
+	// Allow records a request for clientID and reports whether it is within the limit.
+	// An error means the limiter could not reach its backing store.
+	Allow(ctx context.Context, clientID string) (bool, error)
+}
+
+// memoryRateLimiter is a per-process sliding window limiter. It is the default when no
+// shared store is configured.
+type memoryRateLimiter struct {
+	mu        sync.Mutex
+	clients   map[string]*clientLimiter
+	window    time.Duration
+	limit     int
+	lastSweep time.Time
+}
+
+// clientLimiter tracks rate limiting for a specific client
+type clientLimiter struct {
+	requests []time.Time
+	lastSeen time.Time
+}
+
+// NewMemoryRateLimiter creates an in-memory limiter allowing limit requests per window
+func NewMemoryRateLimiter(limit int, window time.Duration) RateLimiter {
+	return &memoryRateLimiter{
+		clients:   make(map[string]*clientLimiter),
+		window:    window,
+		limit:     limit,
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow checks if a request should be allowed based on rate limiting
+func (m *memoryRateLimiter) Allow(_ context.Context, clientID string) (bool, error) {
+	now := time.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Drop clients idle for a full window so the map does not grow without bound
+	if now.Sub(m.lastSweep) >= m.window {
+		m.sweep(now)
+	}
+
+	// Get or create client limiter
+	limiter, exists := m.clients[clientID]
+	if !exists {
+		limiter = &clientLimiter{requests: make([]time.Time, 0)}
+		m.clients[clientID] = limiter
+	}
+	limiter.lastSeen = now
+
+	// Remove expired requests
+	var validRequests []time.Time
+	for _, reqTime := range limiter.requests {
+		if now.Sub(reqTime) <= m.window {
+			validRequests = append(validRequests, reqTime)
+		}
+	}
+
+	// Check if we're under the limit
+	if len(validRequests) < m.limit {
+		limiter.requests = append(validRequests, now)
+		return true, nil
+	}
+
+	limiter.requests = validRequests
+	return false, nil
+}
+
+// sweep removes clients that have not made a request within the window. Callers must hold m.mu.
+func (m *memoryRateLimiter) sweep(now time.Time) {
+	for clientID, limiter := range m.clients {
+		if now.Sub(limiter.lastSeen) > m.window {
+			delete(m.clients, clientID)
+		}
+	}
+	m.lastSweep = now
+}
+
+// redisKeyPrefix namespaces rate limit keys in Redis
+const redisKeyPrefix = "ratelimit:"
+
+// slidingWindowScript keeps one sorted set entry per request scored by its time in
+// milliseconds. Entries older than the window are trimmed before counting, and the key
+// expires once the client has been idle for a full window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+if redis.call('ZCARD', key) >= limit then
+	return 0
+end
+
+redis.call('ZADD', key, now, ARGV[4])
+redis.call('PEXPIRE', key, window)
+return 1
+`)
+
+// RedisRateLimiter is a sliding window limiter shared by every replica using the same Redis
+type RedisRateLimiter struct {
+	client redis.UniversalClient
+	window time.Duration
+	limit  int
+}
+
+// NewRedisRateLimiter creates a Redis backed limiter allowing limit requests per window
+func NewRedisRateLimiter(client redis.UniversalClient, limit int, window time.Duration) *RedisRateLimiter {
+	return &RedisRateLimiter{
+		client: client,
+		window: window,
+		limit:  limit,
+	}
+}
+
+// Allow records the request in Redis and reports whether the client is within the limit
+func (r *RedisRateLimiter) Allow(ctx context.Context, clientID string) (bool, error) {
+	now := time.Now().UnixMilli()
+	allowed, err := slidingWindowScript.Run(ctx, r.client,
+		[]string{redisKeyPrefix + clientID},
+		now, r.window.Milliseconds(), r.limit, uuid.NewString(),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to run rate limit script: %w", err)
+	}
+	return allowed == 1, nil
+}
+
+// Close closes the underlying Redis client
+func (r *RedisRateLimiter) Close() error {
+	return r.client.Close()
+}
@@ -0,0 +1,154 @@
+package middleware
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	zlog "packages/logger"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// newTestRedisLimiter starts a miniredis server and returns a limiter backed by it
+func newTestRedisLimiter(t *testing.T, limit int, window time.Duration) (*RedisRateLimiter, *miniredis.Miniredis) {
+	t.Helper()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	return NewRedisRateLimiter(client, limit, window), server
+}
+
+func TestMemoryRateLimiter_AllowAndDeny(t *testing.T) {
+	limiter := NewMemoryRateLimiter(2, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, err := limiter.Allow(ctx, "user:alice")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, err := limiter.Allow(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	allowed, err = limiter.Allow(ctx, "user:bob")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+}
+
+func TestRedisRateLimiter_AllowAndDeny(t *testing.T) {
+	limiter, server := newTestRedisLimiter(t, 2, time.Minute)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		allowed, err := limiter.Allow(ctx, "user:alice")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+	}
+
+	allowed, err := limiter.Allow(ctx, "user:alice")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Other clients have their own window
+	allowed, err = limiter.Allow(ctx, "user:bob")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Denied requests are not recorded and keys expire with the window
+	members, err := server.ZMembers(redisKeyPrefix + "user:alice")
+	require.NoError(t, err)
+	assert.Len(t, members, 2)
+	assert.Equal(t, time.Minute, server.TTL(redisKeyPrefix+"user:alice"))
+}
+
+func TestRedisRateLimiter_SharedAcrossInstances(t *testing.T) {
+	first, server := newTestRedisLimiter(t, 1, time.Minute)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { _ = client.Close() })
+	second := NewRedisRateLimiter(client, 1, time.Minute)
+	ctx := context.Background()
+
+	allowed, err := first.Allow(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	allowed, err = second.Allow(ctx, "ip:10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRedisRateLimiter_Unreachable(t *testing.T) {
+	limiter, server := newTestRedisLimiter(t, 1, time.Minute)
+	server.Close()
+
+	allowed, err := limiter.Allow(context.Background(), "ip:10.0.0.1")
+	assert.Error(t, err)
+	assert.False(t, allowed)
+}
+
+func TestRateLimitMiddleware_RedisUnavailable(t *testing.T) {
+	tests := []struct {
+		name     string
+		failOpen bool
+		wantCode codes.Code
+	}{
+		{name: "fail open allows the request", failOpen: true, wantCode: codes.OK},
+		{name: "fail closed rejects the request", failOpen: false, wantCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limiter, server := newTestRedisLimiter(t, 1, time.Minute)
+			server.Close()
+
+			cfg := testConfig()
+			cfg.RateLimitFailOpen = tt.failOpen
+			logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+			interceptor := NewRateLimitMiddlewareWithLimiter(logger, cfg, limiter).UnaryRateLimitInterceptor()
+
+			info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+			handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+			_, err := interceptor(peerContext("10.0.0.1:5000"), "req", info, handler)
+			assert.Equal(t, tt.wantCode, status.Code(err))
+		})
+	}
+}
+
+func TestRateLimitMiddleware_RedisDeniesOverLimit(t *testing.T) {
+	limiter, _ := newTestRedisLimiter(t, 1, time.Minute)
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	interceptor := NewRateLimitMiddlewareWithLimiter(logger, testConfig(), limiter).UnaryRateLimitInterceptor()
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+	handler := func(ctx context.Context, req any) (any, error) { return "ok", nil }
+
+	_, err := interceptor(peerContext("10.0.0.1:5000"), "req", info, handler)
+	require.NoError(t, err)
+
+	_, err = interceptor(peerContext("10.0.0.1:5000"), "req", info, handler)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestNewRateLimitMiddleware_SelectsLimiter(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	memory := NewRateLimitMiddleware(logger, testConfig())
+	assert.IsType(t, &memoryRateLimiter{}, memory.limiter)
+
+	cfg := testConfig()
+	cfg.RedisAddr = miniredis.RunT(t).Addr()
+	shared := NewRateLimitMiddleware(logger, cfg)
+	assert.IsType(t, &RedisRateLimiter{}, shared.limiter)
+	assert.NoError(t, shared.Close())
+}
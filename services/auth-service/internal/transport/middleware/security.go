@@ -5,20 +5,28 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"net/http"
 	"regexp"
+	"slices"
 	"strings"
 	"time"
 
+	"api/auth/v1/proto"
 	"auth-service/config"
 	"auth-service/internal/services"
+	"auth-service/models"
+	"auth-service/utils"
 
 	zlog "packages/logger"
 
+	"github.com/google/uuid"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/status"
+	protov2 "google.golang.org/protobuf/proto"
 )
 
 // SecurityMiddleware provides comprehensive security features
@@ -73,12 +81,15 @@ func (s *SecurityMiddleware) UnarySecurityInterceptor() grpc.UnaryServerIntercep
 			return nil, status.Error(codes.PermissionDenied, "insufficient permissions")
 		}
 
-		// Audit logging for sensitive operations
+		resp, err := handler(ctx, req)
+
+		// Audit logging for sensitive operations, after the call so the outcome and the
+		// signed in user are known
 		if s.isSensitiveMethod(info.FullMethod) {
-			s.logAuditEvent(ctx, "method_call", info.FullMethod, req)
+			s.logAuditEvent(ctx, auditEventType(info.FullMethod, err), info.FullMethod, req, resp)
 		}
 
-		return handler(ctx, req)
+		return resp, err
 	}
 }
 
@@ -129,6 +140,10 @@ func (s *SecurityMiddleware) isProtectedMethod(method string) bool {
 		"/auth.AuthService/RefreshToken",
 		"/auth.AuthService/RevokeToken",
 		"/auth.AuthService/ListUsers",
+		"/auth.AuthService/ListAuditEvents",
+		"/auth.AuthService/ListSessions",
+		"/auth.AuthService/RevokeSession",
+		"/auth.AuthService/RevokeAllUserTokens",
 		// Add other protected methods here
 	}
 
@@ -140,21 +155,38 @@ func (s *SecurityMiddleware) isProtectedMethod(method string) bool {
 	return false
 }
 
+// auditedMethods maps the methods that are audited to the event type they record
+var auditedMethods = map[string]string{
+	"/auth.AuthService/SignIn":               "sign_in",
+	"/auth.AuthService/SignUp":               "sign_up",
+	"/auth.AuthService/SignOut":              "sign_out",
+	"/auth.AuthService/RevokeToken":          "revoke_token",
+	"/auth.AuthService/RevokeSession":        "revoke_session",
+	"/auth.AuthService/RequestPasswordReset": "request_password_reset",
+	"/auth.AuthService/ConfirmPasswordReset": "confirm_password_reset",
+	"/auth.AuthService/RevokeAllUserTokens":  "revoke_all_user_tokens",
+}
+
+// adminMethods lists the methods only users in ADMIN_USER_IDS may call
+var adminMethods = map[string]bool{
+	"/auth.AuthService/ListAuditEvents":     true,
+	"/auth.AuthService/RevokeAllUserTokens": true,
+}
+
 // isSensitiveMethod checks if a method should be audited
 func (s *SecurityMiddleware) isSensitiveMethod(method string) bool {
-	sensitiveMethods := []string{
-		"/auth.AuthService/SignIn",
-		"/auth.AuthService/SignUp",
-		"/auth.AuthService/SignOut",
-		"/auth.AuthService/Revoke",
-	}
+	_, ok := auditedMethods[method]
+	return ok
+}
 
-	for _, sensitive := range sensitiveMethods {
-		if strings.Contains(method, sensitive) {
-			return true
-		}
+// auditEventType returns the event type recorded for a call to method. Failed calls get
+// a "_failed" suffix so that, for example, rejected sign ins can be listed on their own.
+func auditEventType(method string, err error) string {
+	eventType := auditedMethods[method]
+	if err != nil {
+		return eventType + "_failed"
 	}
-	return false
+	return eventType
 }
 
 // authenticateRequest validates the authentication token
@@ -199,7 +231,7 @@ func (s *SecurityMiddleware) validateJWTToken(token string) error {
 
 	// Use the auth service to validate the token
 	ctx := context.Background()
-	_, err := s.service.Auth.ValidateToken(ctx, token, s.config.JWTAccessTokenSecret)
+	_, err := s.service.Auth.ValidateToken(ctx, token, s.config.JWTAccessTokenSecret, s.config.JWTPreviousSecrets...)
 	if err != nil {
 		return fmt.Errorf("token validation failed: %w", err)
 	}
@@ -209,8 +241,23 @@ func (s *SecurityMiddleware) validateJWTToken(token string) error {
 
 // authorizeRequest checks if the user has permission to access the method
 func (s *SecurityMiddleware) authorizeRequest(ctx context.Context, method string) error {
-	// TODO: Implement role-based access control
-	// For now, just allow authenticated requests
+	if !adminMethods[method] {
+		return nil
+	}
+
+	token := bearerToken(ctx)
+	if token == "" {
+		return fmt.Errorf("no authorization token provided")
+	}
+
+	user, err := s.service.Auth.ValidateToken(ctx, token, s.config.JWTAccessTokenSecret, s.config.JWTPreviousSecrets...)
+	if err != nil {
+		return fmt.Errorf("invalid token: %w", err)
+	}
+
+	if !slices.Contains(s.config.AdminUserIDs, user.ID.String()) {
+		return fmt.Errorf("user %s is not an administrator", user.ID)
+	}
 	return nil
 }
 
@@ -221,49 +268,130 @@ func (s *SecurityMiddleware) validateInput(req any, method string) error {
 		return fmt.Errorf("request cannot be nil")
 	}
 
-	// Method-specific validation
-	switch method {
-	case "/auth.AuthService/SignUp":
-		return s.validateSignUpRequest(req)
-	case "/auth.AuthService/SignIn":
-		return s.validateSignInRequest(req)
+	// Field validation and sanitization driven by the message's validation rules
+	if msg, ok := req.(protov2.Message); ok {
+		return validateMessage(msg)
 	}
 
 	return nil
 }
 
-// validateSignUpRequest validates signup request data
-func (s *SecurityMiddleware) validateSignUpRequest(req any) error {
-	// TODO: Implement proper request validation using protobuf reflection
-	// For now, just basic checks
-	return nil
-}
-
-// validateSignInRequest validates signin request data
-func (s *SecurityMiddleware) validateSignInRequest(req any) error {
-	// TODO: Implement proper request validation using protobuf reflection
-	// For now, just basic checks
-	return nil
-}
+// logAuditEvent logs security-relevant events and persists them to the audit trail. A
+// failure to persist is logged but does not fail the call being audited.
+func (s *SecurityMiddleware) logAuditEvent(ctx context.Context, eventType, method string, req, resp any) {
+	event := &models.AuditEvent{
+		UserID:    s.auditUserID(ctx, req, resp),
+		EventType: eventType,
+		Method:    method,
+		IPAddress: peerAddress(ctx),
+		UserAgent: UserAgent(ctx),
+		CreatedAt: time.Now().UTC(),
+	}
 
-// logAuditEvent logs security-relevant events
-func (s *SecurityMiddleware) logAuditEvent(ctx context.Context, eventType, method string, data any) {
+	fields := map[string]any{
+		"event_type": eventType,
+		"method":     method,
+		"ip_address": event.IPAddress,
+		"timestamp":  event.CreatedAt,
+	}
+	if event.UserID != nil {
+		fields["user_id"] = event.UserID.String()
+	}
 	if s.config.LogSensitiveData {
-		s.logger.Info(ctx, "Security audit event", map[string]any{
-			"event_type": eventType,
-			"method":     method,
-			"data":       data,
-			"timestamp":  time.Now().UTC(),
-		})
-	} else {
-		s.logger.Info(ctx, "Security audit event", map[string]any{
+		fields["data"] = req
+	}
+	s.logger.Info(ctx, "Security audit event", fields)
+
+	if s.service == nil || s.service.Audit == nil {
+		return
+	}
+	if err := s.service.Audit.Record(ctx, event); err != nil {
+		s.logger.Warn(ctx, "Failed to persist audit event", map[string]any{
 			"event_type": eventType,
 			"method":     method,
-			"timestamp":  time.Now().UTC(),
+			"error":      err.Error(),
 		})
 	}
 }
 
+// auditUserID finds the user an audited call acted for: the signed in user of an auth
+// response, otherwise the owner of the access token sent with the call
+func (s *SecurityMiddleware) auditUserID(ctx context.Context, req, resp any) *uuid.UUID {
+	if authResp, ok := resp.(*proto.AuthResponse); ok && authResp.GetUser() != nil {
+		if id, err := uuid.Parse(authResp.GetUser().GetId()); err == nil {
+			return &id
+		}
+	}
+
+	token := bearerToken(ctx)
+	if tokenReq, ok := req.(interface{ GetAccessToken() string }); ok && token == "" {
+		token = tokenReq.GetAccessToken()
+	}
+	if token == "" {
+		return nil
+	}
+
+	// The signature is enough here: the token may already be revoked by the call itself
+	claims, err := utils.ValidateToken(token, s.config.TokenScope(), s.config.JWTAccessTokenSecret, s.config.JWTPreviousSecrets...)
+	if err != nil {
+		return nil
+	}
+	userID, _ := claims.String("user_id")
+	id, err := uuid.Parse(userID)
+	if err != nil {
+		return nil
+	}
+	return &id
+}
+
+// bearerToken returns the bearer token in the request metadata, or an empty string
+func bearerToken(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 || !strings.HasPrefix(tokens[0], "Bearer ") {
+		return ""
+	}
+	return strings.TrimPrefix(tokens[0], "Bearer ")
+}
+
+// peerAddress returns the IP address of the caller, preferring the one resolved through
+// trusted proxies by the metrics interceptor
+func peerAddress(ctx context.Context) string {
+	if ip := ClientIPFromContext(ctx); ip != "" {
+		return ip
+	}
+
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+
+	addr := p.Addr.String()
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// UserAgent returns the caller's user agent, preferring the one forwarded by the REST gateway
+func UserAgent(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+
+	for _, key := range []string{"grpcgateway-user-agent", "user-agent"} {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
 // generateCorrelationID generates a unique correlation ID
 func generateCorrelationID() string {
 	bytes := make([]byte, 16)
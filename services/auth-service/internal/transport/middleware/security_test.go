@@ -0,0 +1,230 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"api/auth/v1/proto"
+	"auth-service/config"
+	"auth-service/internal/repository"
+	"auth-service/internal/services"
+	"auth-service/models"
+	"auth-service/utils"
+	zlog "packages/logger"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// newMockSecurityMiddleware returns a security middleware whose services use a sqlmock database
+func newMockSecurityMiddleware(t *testing.T, cfg *config.Config) (*SecurityMiddleware, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	cfg.JWTAccessTokenSecret = testAccessSecret
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	db := repository.NewDBFromConn(sqlx.NewDb(mockDB, "postgres"), logger)
+	return NewSecurityMiddleware(logger, cfg, services.NewService(db, logger, cfg)), mock
+}
+
+// auditContext returns a context carrying the peer address and user agent of a caller
+func auditContext() context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 54321},
+	})
+	return metadata.NewIncomingContext(ctx, metadata.Pairs("user-agent", "test-client/1.0"))
+}
+
+// expectValidAccessToken expects the queries made when token is validated for user
+func expectValidAccessToken(mock sqlmock.Sqlmock, user *models.User, token string) {
+	now := time.Now()
+	mock.ExpectPrepare("FROM user_tokens").
+		ExpectQuery().
+		WithArgs(token).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "access_token", "refresh_token", "access_expires_at", "refresh_expires_at", "is_revoked", "family_id", "created_at"}).
+			AddRow(uuid.New(), user.ID, token, "refresh", now.Add(time.Hour), now.Add(24*time.Hour), false, nil, now))
+	mock.ExpectPrepare("FROM users").
+		ExpectQuery().
+		WithArgs(user.ID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name", "email", "password", "created_at", "updated_at"}).
+			AddRow(user.ID, user.Name, user.Email, "hash", now, now))
+}
+
+func TestSecurityMiddleware_SignInRecordsOneAuditEvent(t *testing.T) {
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+	userID := uuid.New()
+
+	mock.ExpectPrepare("INSERT INTO audit_events").
+		ExpectExec().
+		WithArgs(&userID, "sign_in", "/auth.AuthService/SignIn", "203.0.113.7", "test-client/1.0", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &proto.AuthResponse{User: &proto.User{Id: userID.String()}}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+
+	resp, err := middleware.UnarySecurityInterceptor()(auditContext(), &proto.Credentials{Email: "test@example.com", Password: "Secur3-Password!"}, info, handler)
+	require.NoError(t, err)
+	assert.NotNil(t, resp)
+
+	// sqlmock fails any statement that was not expected, so a second row would error here
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityMiddleware_FailedSignInIsAudited(t *testing.T) {
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+
+	mock.ExpectPrepare("INSERT INTO audit_events").
+		ExpectExec().
+		WithArgs(nil, "sign_in_failed", "/auth.AuthService/SignIn", "203.0.113.7", "test-client/1.0", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return nil, status.Error(codes.Unauthenticated, "signin failed")
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+
+	_, err := middleware.UnarySecurityInterceptor()(auditContext(), &proto.Credentials{Email: "test@example.com", Password: "Secur3-Password!"}, info, handler)
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityMiddleware_AuditFailureDoesNotFailCall(t *testing.T) {
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+
+	mock.ExpectPrepare("INSERT INTO audit_events").
+		ExpectExec().
+		WillReturnError(errors.New("connection reset"))
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &proto.AuthResponse{User: &proto.User{Id: uuid.NewString()}}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignIn"}
+
+	_, err := middleware.UnarySecurityInterceptor()(auditContext(), &proto.Credentials{Email: "test@example.com", Password: "Secur3-Password!"}, info, handler)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityMiddleware_SignOutAuditsTokenOwner(t *testing.T) {
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+	token, err := utils.GenerateAccessToken(user, testAccessSecret, utils.TokenScope{})
+	require.NoError(t, err)
+
+	// SignOut is protected, so the token is validated before the handler runs
+	expectValidAccessToken(mock, user, token)
+	mock.ExpectPrepare("INSERT INTO audit_events").
+		ExpectExec().
+		WithArgs(&user.ID, "sign_out", "/auth.AuthService/SignOut", "203.0.113.7", sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx := peer.NewContext(context.Background(), &peer.Peer{
+		Addr: &net.TCPAddr{IP: net.ParseIP("203.0.113.7"), Port: 54321},
+	})
+	ctx = metadata.NewIncomingContext(ctx, metadata.Pairs("authorization", "Bearer "+token))
+	handler := func(ctx context.Context, req any) (any, error) {
+		return &proto.Empty{}, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignOut"}
+
+	_, err = middleware.UnarySecurityInterceptor()(ctx, &proto.SignOutRequest{AccessToken: token}, info, handler)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSecurityMiddleware_ListAuditEventsRequiresAdmin(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	tests := []struct {
+		name     string
+		admins   []string
+		wantCode codes.Code
+	}{
+		{name: "administrator", admins: []string{uuid.NewString(), user.ID.String()}, wantCode: codes.OK},
+		{name: "regular user", admins: []string{uuid.NewString()}, wantCode: codes.PermissionDenied},
+		{name: "no administrators configured", wantCode: codes.PermissionDenied},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware, mock := newMockSecurityMiddleware(t, &config.Config{AdminUserIDs: tt.admins})
+			token, err := utils.GenerateAccessToken(user, testAccessSecret, utils.TokenScope{})
+			require.NoError(t, err)
+
+			// Validated once to authenticate and once to authorize
+			expectValidAccessToken(mock, user, token)
+			expectValidAccessToken(mock, user, token)
+
+			called := false
+			handler := func(ctx context.Context, req any) (any, error) {
+				called = true
+				return &proto.ListAuditEventsResponse{}, nil
+			}
+			ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+			info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/ListAuditEvents"}
+
+			_, err = middleware.UnarySecurityInterceptor()(ctx, &proto.ListAuditEventsRequest{}, info, handler)
+
+			assert.Equal(t, tt.wantCode, status.Code(err))
+			assert.Equal(t, tt.wantCode == codes.OK, called)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSecurityMiddleware_RevokeAllUserTokensRequiresAdmin(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{AdminUserIDs: []string{uuid.NewString()}})
+	token, err := utils.GenerateAccessToken(user, testAccessSecret, utils.TokenScope{})
+	require.NoError(t, err)
+
+	expectValidAccessToken(mock, user, token)
+	expectValidAccessToken(mock, user, token)
+	// The rejected attempt is still audited
+	mock.ExpectExec("INSERT INTO audit_events").WillReturnResult(sqlmock.NewResult(0, 1))
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		t.Fatal("handler must not be called")
+		return nil, nil
+	}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/RevokeAllUserTokens"}
+
+	_, err = middleware.UnarySecurityInterceptor()(ctx, &proto.RevokeAllUserTokensRequest{UserId: uuid.NewString()}, info, handler)
+
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestSecurityMiddleware_ListAuditEventsRequiresToken(t *testing.T) {
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+
+	handler := func(ctx context.Context, req any) (any, error) {
+		t.Fatal("handler must not be called")
+		return nil, nil
+	}
+	info := &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/ListAuditEvents"}
+
+	_, err := middleware.UnarySecurityInterceptor()(context.Background(), &proto.ListAuditEventsRequest{}, info, handler)
+
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/go-ozzo/ozzo-validation/is"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// defaultMaxStringLength caps string fields that have no rule of their own
+const defaultMaxStringLength = 4096
+
+// fieldRule describes how a single string field of a request is validated
+type fieldRule struct {
+	Required  bool
+	MaxLength int  // 0 means defaultMaxStringLength
+	Email     bool // must be a valid email address
+	Secret    bool // passed through untouched, never sanitized
+}
+
+// validationRules holds the field rules of each request message, keyed by the message's
+// full name. String fields without a rule are sanitized and capped at
+// defaultMaxStringLength.
+var validationRules = map[protoreflect.FullName]map[protoreflect.Name]fieldRule{
+	"auth.UserCreateRequest": {
+		"name":     {Required: true, MaxLength: 100},
+		"email":    {Required: true, MaxLength: 100, Email: true},
+		"password": {Required: true, MaxLength: 255, Secret: true},
+	},
+	"auth.Credentials": {
+		"email":    {Required: true, MaxLength: 100},
+		"password": {Required: true, MaxLength: 255, Secret: true},
+	},
+	"auth.ConfirmPasswordResetRequest": {
+		"token":        {Required: true},
+		"new_password": {Required: true, MaxLength: 255, Secret: true},
+	},
+	"auth.RevokeSessionRequest": {
+		"session_id": {Required: true, MaxLength: 36},
+	},
+	"auth.RevokeAllUserTokensRequest": {
+		"user_id": {Required: true, MaxLength: 36},
+	},
+}
+
+// validateMessage sanitizes the string fields of msg in place with SanitizeInput and
+// checks them against the rules registered for its type. Nested messages are validated
+// too.
+func validateMessage(msg proto.Message) error {
+	return validateReflectMessage(msg.ProtoReflect())
+}
+
+func validateReflectMessage(m protoreflect.Message) error {
+	desc := m.Descriptor()
+	rules := validationRules[desc.FullName()]
+
+	fields := desc.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.IsList() || fd.IsMap() {
+			continue
+		}
+
+		switch fd.Kind() {
+		case protoreflect.StringKind:
+			if err := validateStringField(m, fd, rules[fd.Name()]); err != nil {
+				return err
+			}
+		case protoreflect.MessageKind:
+			if m.Has(fd) {
+				if err := validateReflectMessage(m.Get(fd).Message()); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func validateStringField(m protoreflect.Message, fd protoreflect.FieldDescriptor, rule fieldRule) error {
+	value := m.Get(fd).String()
+	if !rule.Secret {
+		if sanitized := SanitizeInput(value); sanitized != value {
+			m.Set(fd, protoreflect.ValueOfString(sanitized))
+			value = sanitized
+		}
+	}
+
+	if rule.Required && value == "" {
+		return fmt.Errorf("%s is required", fd.Name())
+	}
+
+	maxLength := rule.MaxLength
+	if maxLength == 0 {
+		maxLength = defaultMaxStringLength
+	}
+	if len(value) > maxLength {
+		return fmt.Errorf("%s must be at most %d characters", fd.Name(), maxLength)
+	}
+
+	if rule.Email {
+		if err := is.Email.Validate(value); err != nil {
+			return fmt.Errorf("%s %w", fd.Name(), err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,121 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"api/auth/v1/proto"
+	"auth-service/config"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestSecurityMiddleware_RejectsMalformedRequests(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		req    any
+	}{
+		{
+			name:   "sign up without name",
+			method: "/auth.AuthService/SignUp",
+			req:    &proto.UserCreateRequest{Email: "test@example.com", Password: "Secur3-Password!"},
+		},
+		{
+			name:   "sign up with whitespace name",
+			method: "/auth.AuthService/SignUp",
+			req:    &proto.UserCreateRequest{Name: " \t\x00", Email: "test@example.com", Password: "Secur3-Password!"},
+		},
+		{
+			name:   "sign up with invalid email",
+			method: "/auth.AuthService/SignUp",
+			req:    &proto.UserCreateRequest{Name: "Test User", Email: "not-an-email", Password: "Secur3-Password!"},
+		},
+		{
+			name:   "sign up with overlong name",
+			method: "/auth.AuthService/SignUp",
+			req:    &proto.UserCreateRequest{Name: strings.Repeat("a", 101), Email: "test@example.com", Password: "Secur3-Password!"},
+		},
+		{
+			name:   "sign up without password",
+			method: "/auth.AuthService/SignUp",
+			req:    &proto.UserCreateRequest{Name: "Test User", Email: "test@example.com"},
+		},
+		{
+			name:   "sign in without email",
+			method: "/auth.AuthService/SignIn",
+			req:    &proto.Credentials{Password: "Secur3-Password!"},
+		},
+		{
+			name:   "sign in without password",
+			method: "/auth.AuthService/SignIn",
+			req:    &proto.Credentials{Email: "test@example.com"},
+		},
+		{
+			name:   "sign in with overlong password",
+			method: "/auth.AuthService/SignIn",
+			req:    &proto.Credentials{Email: "test@example.com", Password: strings.Repeat("a", 256)},
+		},
+		{
+			name:   "string field over the default limit",
+			method: "/auth.AuthService/RefreshToken",
+			req:    &proto.RefreshTokenRequest{RefreshToken: strings.Repeat("a", defaultMaxStringLength+1)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+			handler := func(ctx context.Context, req any) (any, error) {
+				t.Fatal("handler must not be called")
+				return nil, nil
+			}
+
+			_, err := middleware.UnarySecurityInterceptor()(context.Background(), tt.req, &grpc.UnaryServerInfo{FullMethod: tt.method}, handler)
+
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestSecurityMiddleware_SanitizesRequestsInPlace(t *testing.T) {
+	middleware, mock := newMockSecurityMiddleware(t, &config.Config{})
+	mock.ExpectPrepare("INSERT INTO audit_events").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	req := &proto.UserCreateRequest{
+		Name:     "  Test\x00 User\x1b ",
+		Email:    " test@example.com\n",
+		Password: " Secur3-Password!\t",
+	}
+	var received *proto.UserCreateRequest
+	handler := func(ctx context.Context, req any) (any, error) {
+		received = req.(*proto.UserCreateRequest)
+		return &proto.AuthResponse{}, nil
+	}
+
+	_, err := middleware.UnarySecurityInterceptor()(context.Background(), req, &grpc.UnaryServerInfo{FullMethod: "/auth.AuthService/SignUp"}, handler)
+	require.NoError(t, err)
+
+	assert.Equal(t, "Test User", received.Name)
+	assert.Equal(t, "test@example.com", received.Email)
+	assert.Equal(t, " Secur3-Password!\t", received.Password, "passwords must reach the handler untouched")
+}
+
+func TestValidateMessage_NestedMessages(t *testing.T) {
+	resp := &proto.AuthResponse{
+		User: &proto.User{Name: "\x00Test User "},
+	}
+
+	require.NoError(t, validateMessage(resp))
+
+	assert.Equal(t, "Test User", resp.User.Name)
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	stderrors "errors"
 	"fmt"
 	"time"
 
@@ -13,6 +14,7 @@ import (
 	"auth-service/internal/transport/middleware"
 	"auth-service/internal/transport/tls"
 
+	"packages/grpcutil"
 	zlog "packages/logger"
 )
 
@@ -24,6 +26,8 @@ type Dependencies struct {
 	Database        *repository.DB
 	Services        *services.Service
 	Middleware      *middleware.Registry
+	Metrics         *middleware.MetricsMiddleware
+	RateLimit       *middleware.RateLimitMiddleware
 	TLSManager      *tls.Manager
 	ErrorMapper     *errors.ErrorMapper
 }
@@ -45,10 +49,13 @@ func NewDependencies(cfg *config.Config, logger *zlog.Logger, db *repository.DB,
 	transportCfg.Server.GRPCPort = cfg.AuthServicePort
 	transportCfg.Server.ReadTimeout = time.Duration(cfg.ServerReadTimeout) * time.Second
 	transportCfg.Server.WriteTimeout = time.Duration(cfg.ServerWriteTimeout) * time.Second
-	transportCfg.Server.ShutdownTimeout = 5 * time.Second
+	transportCfg.Server.ShutdownTimeout = time.Duration(cfg.ShutdownTimeout) * time.Second
 
 	transportCfg.Gateway.RESTPort = cfg.RestGatewayPort
 	transportCfg.Gateway.AllowedOrigins = cfg.AllowedOrigins
+	transportCfg.Gateway.MaxAge = cfg.CORSMaxAge
+	transportCfg.Gateway.CompressionEnabled = cfg.CompressionEnabled
+	transportCfg.Gateway.CompressionMinBytes = cfg.CompressionMinBytes
 
 	transportCfg.Health.Timeout = time.Duration(cfg.HealthCheckTimeout) * time.Second
 	transportCfg.Health.ReadinessDelay = 100 * time.Millisecond
@@ -66,7 +73,7 @@ func NewDependencies(cfg *config.Config, logger *zlog.Logger, db *repository.DB,
 }
 
 // SetupMiddleware configures all middleware in the correct order
-func (d *Dependencies) SetupMiddleware() {
+func (d *Dependencies) SetupMiddleware() error {
 	// Create and register middleware in the correct order
 
 	// 1. Recovery middleware (catches panics)
@@ -74,24 +81,40 @@ func (d *Dependencies) SetupMiddleware() {
 	d.Middleware.AddUnary(recoveryMiddleware.UnaryRecoveryInterceptor())
 	d.Middleware.AddStream(recoveryMiddleware.StreamRecoveryInterceptor())
 
-	// 2. Metrics middleware (tracks performance)
-	metricsMiddleware := middleware.NewMetricsMiddleware(d.Logger)
-	d.Middleware.AddUnary(metricsMiddleware.UnaryMetricsInterceptor())
-	d.Middleware.AddStream(metricsMiddleware.StreamMetricsInterceptor())
+	// 2. Message size middleware (rejects oversized requests before any work is done)
+	d.Middleware.AddUnary(grpcutil.UnaryMessageSizeInterceptor(d.Config.GRPCMaxMessageBytes, d.Logger))
 
-	// 3. Rate limiting middleware
-	rateLimitMiddleware := middleware.NewRateLimitMiddleware(d.Logger, d.Config)
-	d.Middleware.AddUnary(rateLimitMiddleware.UnaryRateLimitInterceptor())
-	d.Middleware.AddStream(rateLimitMiddleware.StreamRateLimitInterceptor())
+	// 3. Metrics middleware (tracks performance and logs the caller's IP)
+	clientIPs, err := middleware.NewClientIPResolver(d.Config)
+	if err != nil {
+		return fmt.Errorf("failed to create client IP resolver: %w", err)
+	}
+	d.Metrics = middleware.NewMetricsMiddleware(d.Logger, clientIPs)
+	d.Middleware.AddUnary(d.Metrics.UnaryMetricsInterceptor())
+	d.Middleware.AddStream(d.Metrics.StreamMetricsInterceptor())
+
+	// 4. IP filter middleware (restricts admin methods to trusted networks)
+	ipFilter, err := middleware.NewIPFilterMiddleware(d.Logger, d.Config)
+	if err != nil {
+		return fmt.Errorf("failed to create IP filter: %w", err)
+	}
+	d.Middleware.AddUnary(ipFilter.UnaryIPFilterInterceptor())
+	d.Middleware.AddStream(ipFilter.StreamIPFilterInterceptor())
 
-	// 4. Security middleware (authentication and authorization)
+	// 5. Rate limiting middleware
+	d.RateLimit = middleware.NewRateLimitMiddleware(d.Logger, d.Config)
+	d.Middleware.AddUnary(d.RateLimit.UnaryRateLimitInterceptor())
+	d.Middleware.AddStream(d.RateLimit.StreamRateLimitInterceptor())
+
+	// 6. Security middleware (authentication and authorization)
 	securityMiddleware := middleware.NewSecurityMiddleware(d.Logger, d.Config, d.Services)
 	d.Middleware.AddUnary(securityMiddleware.UnarySecurityInterceptor())
 	d.Middleware.AddStream(securityMiddleware.StreamSecurityInterceptor())
 
 	d.Logger.Info(context.Background(), "Middleware setup completed", map[string]any{
-		"middlewares": []string{"recovery", "metrics", "rate_limit", "security"},
+		"middlewares": []string{"recovery", "message_size", "metrics", "ip_filter", "rate_limit", "security"},
 	})
+	return nil
 }
 
 // Validate checks if all required dependencies are present
@@ -120,12 +143,19 @@ func (d *Dependencies) Validate() error {
 	return nil
 }
 
-// Close closes all dependencies that need cleanup
+// Close closes all dependencies that need cleanup. Every dependency is closed even when an
+// earlier one fails, and all the failures are returned together.
 func (d *Dependencies) Close(ctx context.Context) error {
+	var errs []error
+	if d.RateLimit != nil {
+		if err := d.RateLimit.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to close rate limiter: %w", err))
+		}
+	}
 	if d.Database != nil {
 		if err := d.Database.Close(ctx); err != nil {
-			return fmt.Errorf("failed to close database: %w", err)
+			errs = append(errs, fmt.Errorf("failed to close database: %w", err))
 		}
 	}
-	return nil
+	return stderrors.Join(errs...)
 }
@@ -12,10 +12,14 @@ import (
 	"auth-service/internal/handler/http"
 	"auth-service/internal/repository"
 	"auth-service/internal/services"
+	auth "auth-service/internal/services/auth"
 	"auth-service/internal/transport/lifecycle"
 
+	"packages/grpcutil"
 	zlog "packages/logger"
+	"packages/tracing"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -28,8 +32,17 @@ type Server struct {
 	lifecycle    *lifecycle.Manager
 	grpcListener net.Listener
 	restListener net.Listener
+
+	// tokenJanitor prunes expired revoked tokens; nil when TokenCleanupInterval is 0
+	tokenJanitor *auth.TokenJanitor
+
+	// shutdownTracing flushes spans still waiting to be exported
+	shutdownTracing func(context.Context) error
 }
 
+// traceFlushTimeout bounds how long exporting the remaining spans may delay exit
+const traceFlushTimeout = 5 * time.Second
+
 // NewServer initializes both gRPC and REST servers with their dependencies
 func NewServer(ctx context.Context) (*Server, error) {
 	// Load configuration
@@ -39,17 +52,31 @@ func NewServer(ctx context.Context) (*Server, error) {
 	}
 
 	// Initialize logger
-	logger := zlog.NewLogger(zlog.Config{
-		Level:      cfg.LogLevel,
-		Output:     os.Stdout,
-		JSONFormat: cfg.LogJSONFormat,
-		AddCaller:  true,
-		TimeFormat: time.RFC3339,
+	logger := zlog.New(zlog.Config{
+		Level:       cfg.LogLevel,
+		Output:      os.Stdout,
+		JSONFormat:  cfg.LogJSONFormat,
+		AddCaller:   true,
+		TimeFormat:  time.RFC3339,
+		SampleRate:  cfg.LogSampleRate,
+		SampleBurst: cfg.LogSampleBurst,
 	})
 
 	// Create a context with correlation ID for initialization
 	ctx = zlog.WithCorrelationID(ctx, "")
 
+	// Initialize tracing before any instrumented client is created
+	shutdownTracing, err := tracing.Setup(ctx, cfg.OTelExporterEndpoint, "auth-service")
+	if err != nil {
+		logger.Error(ctx, err, "Failed to initialize tracing", 500)
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	if cfg.OTelExporterEndpoint != "" {
+		logger.Info(ctx, "Tracing enabled", map[string]any{
+			"endpoint": cfg.OTelExporterEndpoint,
+		})
+	}
+
 	// Initialize database
 	logger.Info(ctx, "Initializing database")
 	db, err := repository.InitDB(ctx, cfg, logger)
@@ -69,7 +96,9 @@ func NewServer(ctx context.Context) (*Server, error) {
 	}
 
 	// Setup middleware
-	deps.SetupMiddleware()
+	if err := deps.SetupMiddleware(); err != nil {
+		return nil, fmt.Errorf("middleware setup failed: %w", err)
+	}
 
 	// Create gRPC server with middleware
 	grpcServer := createGRPCServer(deps)
@@ -90,6 +119,7 @@ func NewServer(ctx context.Context) (*Server, error) {
 
 	// Create REST gateway
 	restGateway := http.NewRESTGateway(&deps.TransportConfig.Gateway, logger)
+	restGateway.SetMetricsHandler(deps.Metrics.Handler())
 	// In Docker, both gRPC and REST services run in the same container
 	// gRPC service runs on AuthServicePort, REST gateway connects to localhost:AuthServicePort
 	grpcAddr := "localhost:" + cfg.AuthServicePort
@@ -97,8 +127,14 @@ func NewServer(ctx context.Context) (*Server, error) {
 		return nil, fmt.Errorf("failed to create REST gateway: %w", err)
 	}
 
+	// Prune expired revoked tokens in the background
+	var tokenJanitor *auth.TokenJanitor
+	if cfg.TokenCleanupInterval > 0 {
+		tokenJanitor = auth.NewTokenJanitor(db, logger, time.Duration(cfg.TokenCleanupInterval)*time.Second)
+	}
+
 	// Create lifecycle manager
-	lifecycle := lifecycle.NewManager(logger, &deps.TransportConfig.Health)
+	lifecycle := lifecycle.NewManager(logger, &deps.TransportConfig.Health, &deps.TransportConfig.Server)
 	lifecycle.SetServers(grpcServer, restGateway.GetServer(), grpcListener, restGateway.GetListener())
 
 	return &Server{
@@ -108,12 +144,23 @@ func NewServer(ctx context.Context) (*Server, error) {
 		lifecycle:    lifecycle,
 		grpcListener: grpcListener,
 		restListener: restGateway.GetListener(),
+		tokenJanitor: tokenJanitor,
+
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
 // createGRPCServer creates a gRPC server with configured middleware
 func createGRPCServer(deps *Dependencies) *grpc.Server {
-	var serverOptions []grpc.ServerOption
+	// Continue the caller's trace and record a span for every RPC
+	serverOptions := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler())}
+	serverOptions = append(serverOptions, grpcutil.MessageSizeServerOptions(deps.Config.GRPCMaxMessageBytes)...)
+
+	// Disconnect clients that ping more often than the policy allows
+	serverOptions = append(serverOptions, grpcutil.KeepaliveEnforcementPolicy(
+		time.Duration(deps.Config.GRPCKeepaliveMinTime)*time.Second,
+		deps.Config.GRPCKeepalivePermitWithoutStream,
+	))
 
 	// Add unary interceptors if available
 	if unaryInterceptors := deps.Middleware.GetUnaryInterceptors(); len(unaryInterceptors) > 0 {
@@ -172,6 +219,7 @@ func registerServices(grpcServer *grpc.Server, deps *Dependencies) {
 
 // Start runs both gRPC and REST servers
 func (s *Server) Start(ctx context.Context) error {
+	s.startTokenJanitor(ctx)
 	return s.lifecycle.Start(ctx)
 }
 
@@ -182,15 +230,60 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		return fmt.Errorf("failed to shutdown lifecycle manager: %w", err)
 	}
 
+	// Stop the token janitor before the database it writes to is closed
+	s.stopTokenJanitor()
+
 	// Close dependencies
 	if err := s.deps.Close(ctx); err != nil {
 		return fmt.Errorf("failed to close dependencies: %w", err)
 	}
 
+	s.flushTraces()
 	return nil
 }
 
 // Run starts both servers and handles graceful shutdown
 func (s *Server) Run(ctx context.Context) error {
-	return s.lifecycle.Run(ctx)
+	s.startTokenJanitor(ctx)
+	err := s.lifecycle.Run(ctx)
+	s.stopTokenJanitor()
+	s.flushTraces()
+	return err
+}
+
+// startTokenJanitor starts pruning expired revoked tokens. The janitor runs until
+// stopTokenJanitor, not until ctx is done, since the startup context may carry a deadline.
+func (s *Server) startTokenJanitor(ctx context.Context) {
+	if s.tokenJanitor == nil {
+		return
+	}
+
+	s.deps.Logger.Info(ctx, "Starting revoked token cleanup", map[string]any{
+		"interval_seconds": s.deps.Config.TokenCleanupInterval,
+	})
+	s.tokenJanitor.Start(context.WithoutCancel(ctx))
+}
+
+// stopTokenJanitor stops the token janitor, waiting for a running pass to finish
+func (s *Server) stopTokenJanitor() {
+	if s.tokenJanitor != nil {
+		s.tokenJanitor.Stop()
+	}
+}
+
+// flushTraces exports the spans still buffered. It runs on its own deadline because the
+// context the server was started with may already be done by the time it exits.
+func (s *Server) flushTraces() {
+	if s.shutdownTracing == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), traceFlushTimeout)
+	defer cancel()
+
+	if err := s.shutdownTracing(ctx); err != nil {
+		s.deps.Logger.Warn(ctx, "Failed to flush traces", map[string]any{
+			"error": err.Error(),
+		})
+	}
 }
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEvent is a persisted record of a security-relevant call
+type AuditEvent struct {
+	ID        uuid.UUID  `db:"id" json:"id"`
+	UserID    *uuid.UUID `db:"user_id" json:"user_id,omitempty"`
+	EventType string     `db:"event_type" json:"event_type"`
+	Method    string     `db:"method" json:"method"`
+	IPAddress string     `db:"ip_address" json:"ip_address"`
+	UserAgent string     `db:"user_agent" json:"user_agent"`
+	CreatedAt time.Time  `db:"created_at" json:"created_at"`
+}
+
+// AuditEventFilter narrows a listing of audit events. Zero values match every event.
+type AuditEventFilter struct {
+	UserID    *uuid.UUID
+	EventType string
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Session is a signed in device. It shares its ID with the refresh token family started
+// at sign in, so it lives as long as the family is not revoked.
+type Session struct {
+	ID         uuid.UUID `db:"id" json:"id"`
+	UserID     uuid.UUID `db:"user_id" json:"user_id"`
+	UserAgent  string    `db:"user_agent" json:"user_agent"`
+	IPAddress  string    `db:"ip_address" json:"ip_address"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+	LastSeenAt time.Time `db:"last_seen_at" json:"last_seen_at"`
+}
+
+// ClientInfo describes the client tokens are issued to
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+}
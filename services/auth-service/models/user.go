@@ -18,8 +18,10 @@ type User struct {
 
 // Credentials represents user login credentials
 type Credentials struct {
-	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required"`
+	Email     string `json:"email" binding:"required,email"`
+	Password  string `json:"password" binding:"required"`
+	ClientIP  string `json:"-"` // caller address, used to throttle failed sign ins
+	UserAgent string `json:"-"` // caller user agent, recorded on the session
 }
 
 // UserCreateRequest represents user registration request
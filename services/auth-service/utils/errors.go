@@ -50,6 +50,16 @@ func (e *AppError) Unwrap() error {
 	return e.Err
 }
 
+// ErrorType returns the error type, logged as the error_type field
+func (e *AppError) ErrorType() string {
+	return string(e.Type)
+}
+
+// ErrorCode returns the error code, logged as the error_code field
+func (e *AppError) ErrorCode() string {
+	return e.Code
+}
+
 // NewValidationError creates a new validation error
 func NewValidationError(code, message string, err error) *AppError {
 	return &AppError{
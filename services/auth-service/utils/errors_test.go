@@ -383,3 +383,10 @@ func BenchmarkGetAppError(b *testing.B) {
 		GetAppError(regularError)
 	}
 }
+
+func TestAppError_LogFields(t *testing.T) {
+	err := NewNotFoundError("USER_NOT_FOUND", "user not found", nil)
+
+	assert.Equal(t, "not_found", err.ErrorType())
+	assert.Equal(t, "USER_NOT_FOUND", err.ErrorCode())
+}
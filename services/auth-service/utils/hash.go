@@ -23,12 +23,13 @@ const (
 	PasswordStrengthVeryStrong
 )
 
+// DefaultBcryptCost is the bcrypt cost used when none is configured
+// (12 is bcrypt's default, 14+ for high security)
+const DefaultBcryptCost = 14
+
 // HashPassword creates a secure hash of a password using bcrypt
 func HashPassword(password string) (string, error) {
-	// Use higher cost for production (12 is default, 14+ for high security)
-	cost := 14
-	bytes, err := bcrypt.GenerateFromPassword([]byte(password), cost)
-	return string(bytes), err
+	return HashPasswordWithCost(password, DefaultBcryptCost)
 }
 
 // HashPasswordWithCost creates a hash with a specific cost factor
@@ -46,6 +47,13 @@ func CheckPasswordHash(password, hash string) bool {
 	return err == nil
 }
 
+// NeedsRehash reports whether hash was made with a lower bcrypt cost than cost. bcrypt
+// records the cost in the hash itself, so no separate column is needed.
+func NeedsRehash(hash string, cost int) bool {
+	hashCost, err := bcrypt.Cost([]byte(hash))
+	return err == nil && hashCost < cost
+}
+
 // VerifyPassword is an alias for CheckPasswordHash for better readability
 func VerifyPassword(password, hash string) bool {
 	return CheckPasswordHash(password, hash)
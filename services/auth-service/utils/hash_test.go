@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestHashPassword(t *testing.T) {
@@ -47,6 +48,17 @@ func TestHashPassword(t *testing.T) {
 	}
 }
 
+func TestNeedsRehash(t *testing.T) {
+	hash, err := HashPasswordWithCost("testpassword123", bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("HashPasswordWithCost() error = %v", err)
+	}
+
+	assert.True(t, NeedsRehash(hash, bcrypt.MinCost+1))
+	assert.False(t, NeedsRehash(hash, bcrypt.MinCost))
+	assert.False(t, NeedsRehash("not-a-bcrypt-hash", bcrypt.MaxCost))
+}
+
 func TestCheckPasswordHash(t *testing.T) {
 	password := "testpassword123"
 	hash, err := HashPassword(password)
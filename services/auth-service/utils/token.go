@@ -12,7 +12,9 @@ import (
 
 	"auth-service/models"
 
-	"github.com/golang-jwt/jwt"
+	"github.com/google/uuid"
+
+	"packages/jwt"
 )
 
 // TokenConfig holds JWT configuration
@@ -22,9 +24,17 @@ type TokenConfig struct {
 	EncryptionKey      string
 }
 
+// ErrTokenScope is returned when a token's issuer or audience isn't the expected one
+var ErrTokenScope = jwt.ErrTokenScope
+
+// TokenScope is the issuer ("iss") and audience ("aud") tokens are generated with and
+// validated against, so a token minted for one service isn't accepted by another. An empty
+// field is neither set on generated tokens nor checked.
+type TokenScope = jwt.Scope
+
 // GenerateAccessTokenSimple creates a new access token for a user (simplified version)
-func GenerateAccessTokenSimple(userID string, email string, name string, role string, secret string) (string, error) {
-	claims := jwt.MapClaims{
+func GenerateAccessTokenSimple(userID string, email string, name string, role string, secret string, scope TokenScope) (string, error) {
+	claims := jwt.Claims{
 		"user_id": userID,
 		"name":    name,
 		"email":   email,
@@ -33,25 +43,24 @@ func GenerateAccessTokenSimple(userID string, email string, name string, role st
 		"iat":     time.Now().Unix(),
 		"type":    "access",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(scope.Apply(claims), secret)
 }
 
 // GenerateRefreshTokenSimple creates a new refresh token for a user (simplified version)
-func GenerateRefreshTokenSimple(userID string, secret string) (string, error) {
-	claims := jwt.MapClaims{
+func GenerateRefreshTokenSimple(userID string, secret string, scope TokenScope) (string, error) {
+	claims := jwt.Claims{
 		"user_id": userID,
 		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
 		"iat":     time.Now().Unix(),
+		"jti":     uuid.NewString(), // keeps tokens issued within the same second distinct for rotation
 		"type":    "refresh",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(scope.Apply(claims), secret)
 }
 
 // GenerateAccessToken creates a new access token for a user
-func GenerateAccessToken(user *models.User, secret string) (string, error) {
-	claims := jwt.MapClaims{
+func GenerateAccessToken(user *models.User, secret string, scope TokenScope) (string, error) {
+	claims := jwt.Claims{
 		"user_id": user.ID,
 		"name":    user.Name,
 		"email":   user.Email,
@@ -59,43 +68,35 @@ func GenerateAccessToken(user *models.User, secret string) (string, error) {
 		"iat":     time.Now().Unix(),
 		"type":    "access",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(scope.Apply(claims), secret)
 }
 
 // GenerateRefreshToken creates a new refresh token for a user
-func GenerateRefreshToken(user *models.User, secret string) (string, error) {
-	claims := jwt.MapClaims{
+func GenerateRefreshToken(user *models.User, secret string, scope TokenScope) (string, error) {
+	claims := jwt.Claims{
 		"user_id": user.ID,
 		"name":    user.Name,
 		"email":   user.Email,
 		"exp":     time.Now().Add(7 * 24 * time.Hour).Unix(),
 		"iat":     time.Now().Unix(),
+		"jti":     uuid.NewString(), // keeps tokens issued within the same second distinct for rotation
 		"type":    "refresh",
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return jwt.Sign(scope.Apply(claims), secret)
 }
 
-// ValidateToken validates a JWT token and returns the claims
-func ValidateToken(tokenString, secret string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (any, error) {
-		// Validate the signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(secret), nil
-	})
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse token: %w", err)
-	}
-
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		return claims, nil
-	}
+// KeyID identifies a signing secret in the "kid" header of the tokens it signs. It is a
+// truncated hash, so the secret itself is never exposed.
+func KeyID(secret string) string {
+	return jwt.KeyID(secret)
+}
 
-	return nil, fmt.Errorf("invalid token")
+// ValidateToken validates a JWT token and returns the claims. Tokens signed with secret or
+// any of previousSecrets are accepted, so rotating the signing secret doesn't invalidate
+// live tokens. Tokens without an expiry or past it are rejected, and tokens outside scope
+// are rejected with ErrTokenScope.
+func ValidateToken(tokenString string, scope TokenScope, secret string, previousSecrets ...string) (jwt.Claims, error) {
+	return jwt.Validate(tokenString, scope, append([]string{secret}, previousSecrets...)...)
 }
 
 // GenerateTimedCode creates a time-based encrypted code
@@ -6,8 +6,11 @@ import (
 
 	"auth-service/models"
 
+	gojwt "github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+
+	"packages/jwt"
 )
 
 func TestGenerateAccessTokenSimple(t *testing.T) {
@@ -51,7 +54,7 @@ func TestGenerateAccessTokenSimple(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := GenerateAccessTokenSimple(tt.userID, tt.email, tt.userName, tt.role, tt.secret)
+			token, err := GenerateAccessTokenSimple(tt.userID, tt.email, tt.userName, tt.role, tt.secret, TokenScope{})
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -91,7 +94,7 @@ func TestGenerateRefreshTokenSimple(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := GenerateRefreshTokenSimple(tt.userID, tt.secret)
+			token, err := GenerateRefreshTokenSimple(tt.userID, tt.secret, TokenScope{})
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -131,7 +134,7 @@ func TestGenerateAccessToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := GenerateAccessToken(tt.user, tt.secret)
+			token, err := GenerateAccessToken(tt.user, tt.secret, TokenScope{})
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -171,7 +174,7 @@ func TestGenerateRefreshToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			token, err := GenerateRefreshToken(tt.user, tt.secret)
+			token, err := GenerateRefreshToken(tt.user, tt.secret, TokenScope{})
 			if tt.wantErr {
 				assert.Error(t, err)
 				return
@@ -182,6 +185,18 @@ func TestGenerateRefreshToken(t *testing.T) {
 	}
 }
 
+func TestGenerateRefreshToken_Unique(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	// Rotation consumes tokens by hash, so tokens issued within the same second must differ
+	first, err := GenerateRefreshToken(user, "test-secret", TokenScope{})
+	assert.NoError(t, err)
+	second, err := GenerateRefreshToken(user, "test-secret", TokenScope{})
+	assert.NoError(t, err)
+
+	assert.NotEqual(t, first, second)
+}
+
 func TestValidateToken(t *testing.T) {
 	secret := "test-secret"
 	userID := "user123"
@@ -190,12 +205,12 @@ func TestValidateToken(t *testing.T) {
 	role := "user"
 
 	// Generate a valid token
-	accessToken, err := GenerateAccessTokenSimple(userID, email, name, role, secret)
+	accessToken, err := GenerateAccessTokenSimple(userID, email, name, role, secret, TokenScope{})
 	if err != nil {
 		t.Fatalf("Failed to generate test token: %v", err)
 	}
 
-	refreshToken, err := GenerateRefreshTokenSimple(userID, secret)
+	refreshToken, err := GenerateRefreshTokenSimple(userID, secret, TokenScope{})
 	if err != nil {
 		t.Fatalf("Failed to generate test refresh token: %v", err)
 	}
@@ -246,7 +261,7 @@ func TestValidateToken(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			claims, err := ValidateToken(tt.token, tt.secret)
+			claims, err := ValidateToken(tt.token, TokenScope{}, tt.secret)
 			if tt.wantErr {
 				assert.Error(t, err)
 				assert.Nil(t, claims)
@@ -273,6 +288,139 @@ func TestValidateToken(t *testing.T) {
 	}
 }
 
+func TestValidateToken_KeyRotation(t *testing.T) {
+	const (
+		current  = "current-secret-current-secret-12"
+		previous = "previous-secret-previous-secret1"
+		unknown  = "unknown-secret-unknown-secret-12"
+	)
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	oldToken, err := GenerateAccessToken(user, previous, TokenScope{})
+	assert.NoError(t, err)
+
+	// A token signed before the rotation keeps validating while its secret is listed
+	claims, err := ValidateToken(oldToken, TokenScope{}, current, previous)
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID.String(), claims["user_id"])
+
+	// Once the previous secret is retired, the token is rejected
+	_, err = ValidateToken(oldToken, TokenScope{}, current)
+	assert.Error(t, err)
+	_, err = ValidateToken(oldToken, TokenScope{}, current, unknown)
+	assert.Error(t, err)
+
+	// Legacy tokens without a kid header are still matched by trying each secret
+	legacy := gojwt.NewWithClaims(gojwt.SigningMethodHS256, gojwt.MapClaims{
+		"user_id": user.ID.String(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+		"type":    "access",
+	})
+	legacyToken, err := legacy.SignedString([]byte(previous))
+	assert.NoError(t, err)
+	_, err = ValidateToken(legacyToken, TokenScope{}, current, unknown, previous)
+	assert.NoError(t, err)
+}
+
+func TestValidateToken_ExpiredWithPreviousSecret(t *testing.T) {
+	const (
+		current  = "current-secret-current-secret-12"
+		previous = "previous-secret-previous-secret1"
+	)
+
+	expired, err := jwt.Sign(jwt.Claims{"user_id": "user123", "exp": time.Now().Add(-time.Minute).Unix()}, previous)
+	assert.NoError(t, err)
+
+	_, err = ValidateToken(expired, TokenScope{}, current, previous)
+
+	assert.ErrorIs(t, err, jwt.ErrTokenExpired)
+	assert.NotErrorIs(t, err, jwt.ErrSignatureInvalid)
+}
+
+func TestGenerateAccessToken_SignsWithCurrentKeyID(t *testing.T) {
+	const current = "current-secret-current-secret-12"
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	token, err := GenerateAccessToken(user, current, TokenScope{})
+	assert.NoError(t, err)
+
+	parsed, _, err := new(gojwt.Parser).ParseUnverified(token, gojwt.MapClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, KeyID(current), parsed.Header["kid"])
+	assert.NotContains(t, token, current)
+
+	// Only the current secret verifies the signature
+	_, err = jwt.Parse(token, current)
+	assert.NoError(t, err)
+	_, err = jwt.Parse(token, "previous-secret-previous-secret1")
+	assert.Error(t, err)
+}
+
+func TestValidateToken_Scope(t *testing.T) {
+	const secret = "current-secret-current-secret-12"
+	scope := TokenScope{Issuer: "auth-service", Audience: "chat-service"}
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	scoped, err := GenerateAccessToken(user, secret, scope)
+	assert.NoError(t, err)
+	unscoped, err := GenerateAccessToken(user, secret, TokenScope{})
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		token   string
+		scope   TokenScope
+		wantErr bool
+	}{
+		{name: "matching issuer and audience", token: scoped, scope: scope},
+		{name: "scope not enforced", token: scoped, scope: TokenScope{}},
+		{name: "wrong audience", token: scoped, scope: TokenScope{Issuer: "auth-service", Audience: "billing-service"}, wantErr: true},
+		{name: "wrong issuer", token: scoped, scope: TokenScope{Issuer: "other-issuer", Audience: "chat-service"}, wantErr: true},
+		{name: "missing claims", token: unscoped, scope: scope, wantErr: true},
+		{name: "missing audience only", token: unscoped, scope: TokenScope{Audience: "chat-service"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims, err := ValidateToken(tt.token, tt.scope, secret)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrTokenScope)
+				assert.Nil(t, claims)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, user.ID.String(), claims["user_id"])
+		})
+	}
+}
+
+func TestGenerateAccessToken_SetsScopeClaims(t *testing.T) {
+	const secret = "current-secret-current-secret-12"
+	user := &models.User{ID: uuid.New(), Name: "Test User", Email: "test@example.com"}
+
+	token, err := GenerateRefreshToken(user, secret, TokenScope{Issuer: "auth-service", Audience: "chat-service"})
+	assert.NoError(t, err)
+	claims, err := ValidateToken(token, TokenScope{}, secret)
+	assert.NoError(t, err)
+	assert.Equal(t, "auth-service", claims["iss"])
+	assert.Equal(t, "chat-service", claims["aud"])
+
+	// Empty scope fields are left out rather than set to empty strings
+	token, err = GenerateAccessToken(user, secret, TokenScope{})
+	assert.NoError(t, err)
+	claims, err = ValidateToken(token, TokenScope{}, secret)
+	assert.NoError(t, err)
+	assert.NotContains(t, claims, "iss")
+	assert.NotContains(t, claims, "aud")
+}
+
+func TestTokenScope_VerifyAudienceList(t *testing.T) {
+	scope := TokenScope{Audience: "chat-service"}
+
+	assert.NoError(t, scope.Verify(jwt.Claims{"aud": []any{"auth-service", "chat-service"}}))
+	assert.ErrorIs(t, scope.Verify(jwt.Claims{"aud": []any{"auth-service"}}), ErrTokenScope)
+}
+
 func TestTokenExpiration(t *testing.T) {
 	secret := "test-secret"
 	userID := "user123"
@@ -281,22 +429,22 @@ func TestTokenExpiration(t *testing.T) {
 	role := "user"
 
 	// Generate tokens
-	accessToken, err := GenerateAccessTokenSimple(userID, email, name, role, secret)
+	accessToken, err := GenerateAccessTokenSimple(userID, email, name, role, secret, TokenScope{})
 	if err != nil {
 		t.Fatalf("Failed to generate test token: %v", err)
 	}
 
-	refreshToken, err := GenerateRefreshTokenSimple(userID, secret)
+	refreshToken, err := GenerateRefreshTokenSimple(userID, secret, TokenScope{})
 	if err != nil {
 		t.Fatalf("Failed to generate test refresh token: %v", err)
 	}
 
 	// Validate tokens immediately
-	accessClaims, err := ValidateToken(accessToken, secret)
+	accessClaims, err := ValidateToken(accessToken, TokenScope{}, secret)
 	assert.NoError(t, err)
 	assert.NotNil(t, accessClaims)
 
-	refreshClaims, err := ValidateToken(refreshToken, secret)
+	refreshClaims, err := ValidateToken(refreshToken, TokenScope{}, secret)
 	assert.NoError(t, err)
 	assert.NotNil(t, refreshClaims)
 
@@ -325,13 +473,13 @@ func TestTokenClaims(t *testing.T) {
 	role := "user"
 
 	// Generate access token
-	accessToken, err := GenerateAccessTokenSimple(userID, email, name, role, secret)
+	accessToken, err := GenerateAccessTokenSimple(userID, email, name, role, secret, TokenScope{})
 	if err != nil {
 		t.Fatalf("Failed to generate test token: %v", err)
 	}
 
 	// Validate and check claims
-	claims, err := ValidateToken(accessToken, secret)
+	claims, err := ValidateToken(accessToken, TokenScope{}, secret)
 	assert.NoError(t, err)
 	assert.NotNil(t, claims)
 
@@ -357,7 +505,7 @@ func BenchmarkGenerateAccessTokenSimple(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := GenerateAccessTokenSimple(userID, email, name, role, secret)
+		_, err := GenerateAccessTokenSimple(userID, email, name, role, secret, TokenScope{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -370,7 +518,7 @@ func BenchmarkGenerateRefreshTokenSimple(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := GenerateRefreshTokenSimple(userID, secret)
+		_, err := GenerateRefreshTokenSimple(userID, secret, TokenScope{})
 		if err != nil {
 			b.Fatal(err)
 		}
@@ -384,14 +532,14 @@ func BenchmarkValidateToken(b *testing.B) {
 	name := "Test User"
 	role := "user"
 
-	token, err := GenerateAccessTokenSimple(userID, email, name, role, secret)
+	token, err := GenerateAccessTokenSimple(userID, email, name, role, secret, TokenScope{})
 	if err != nil {
 		b.Fatal(err)
 	}
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		_, err := ValidateToken(token, secret)
+		_, err := ValidateToken(token, TokenScope{}, secret)
 		if err != nil {
 			b.Fatal(err)
 		}
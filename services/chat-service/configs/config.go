@@ -3,9 +3,13 @@ package configs
 import (
 	"crypto/tls"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/joho/godotenv"
 )
@@ -16,6 +20,23 @@ const (
 	DEVELOPMENT_ENV = "development"
 )
 
+// Supported LLM providers
+const (
+	OPENAI_PROVIDER    = "openai"
+	ANTHROPIC_PROVIDER = "anthropic"
+)
+
+// maxGRPCMessageBytes is the largest accepted GRPC_MAX_MESSAGE_BYTES. The server lets the
+// transport receive twice the limit, which must still fit in an int32.
+const maxGRPCMessageBytes = 1 << 30
+
+// WebSocketMaxFrameBytes caps the size of a single incoming WebSocket frame
+const WebSocketMaxFrameBytes = 64 * 1024
+
+// messageEnvelopeBytes is the room a chat request needs besides its message: the JSON
+// field names, conversation ID and generation options
+const messageEnvelopeBytes = 1024
+
 // Config holds application configuration
 type Config struct {
 	Environment        string
@@ -23,9 +44,35 @@ type Config struct {
 	RestGatewayPort    string
 	LogLevel           string
 	LogJSONFormat      bool
+	LogSampleRate      int // keep one in every LogSampleRate INFO/DEBUG logs; 0 or 1 keeps all
+	LogSampleBurst     int // INFO/DEBUG logs per second kept before sampling applies
 	HealthCheckTimeout int // in seconds
 	ServerReadTimeout  int // in seconds
 	ServerWriteTimeout int // in seconds
+	// RequestTimeout bounds each unary RPC, including the REST calls relayed through the
+	// gateway, in seconds; 0 disables it
+	RequestTimeout int
+	// ShutdownTimeout is how long shutdown waits for in-flight requests and streams to
+	// finish before they are cut off, in seconds
+	ShutdownTimeout int
+	// MaxRequestBodyBytes caps the size of a REST request body
+	MaxRequestBodyBytes int64
+	// CompressionEnabled gzips REST responses for clients that accept it
+	CompressionEnabled bool
+	// CompressionMinBytes is the smallest REST response body worth compressing
+	CompressionMinBytes int
+	// HealthCheckLLM makes readiness also check that the LLM provider is reachable
+	HealthCheckLLM bool
+	// GRPCMaxMessageBytes caps the size of gRPC request and response messages
+	GRPCMaxMessageBytes int
+	// GRPCKeepaliveMinTime is the shortest interval, in seconds, clients may ping the gRPC
+	// server at; clients pinging more often are disconnected
+	GRPCKeepaliveMinTime int
+	// GRPCKeepalivePermitWithoutStream lets clients ping while they have no active calls
+	GRPCKeepalivePermitWithoutStream bool
+	// GRPCReflectionEnabled registers the gRPC reflection service; it defaults to on in
+	// development only
+	GRPCReflectionEnabled bool
 
 	// Security Configuration
 	TLSEnabled    bool
@@ -41,13 +88,62 @@ type Config struct {
 	AuthServiceCertFile string
 	AuthServiceKeyFile  string
 	AuthServiceCAFile   string
+	// AuthServiceTarget, when set, is the gRPC dial target for the auth service, such as
+	// "dns:///auth-service:8081", and takes precedence over AuthServiceHost and AuthServicePort
+	AuthServiceTarget string
+	// AuthLBPolicy balances calls over the auth replicas the target resolves to
+	// ("round_robin" or "pick_first")
+	AuthLBPolicy string
+	// TokenCacheTTL is how long REST token validations are cached, in seconds (0 disables the cache)
+	TokenCacheTTL int
+
+	// LLMProvider selects the chat completion backend ("openai" or "anthropic")
+	LLMProvider string
 
 	// OpenAI Configuration
 	OpenAIAPIKey      string
+	OpenAIBaseURL     string
+	OpenAIAPIVersion  string // api-version query parameter required by Azure OpenAI; empty for OpenAI
 	OpenAIModel       string
 	OpenAIMaxTokens   int
 	OpenAITemperature float64
 	OpenAITimeout     int // in seconds
+	// OpenAIMaxRetries is the number of times a rate-limited or failed OpenAI request is retried
+	OpenAIMaxRetries int
+	// OpenAIContextWindow is the number of prior conversation messages sent as context
+	OpenAIContextWindow int
+	// OpenAIContextMaxTokens is the model context limit the prompt plus completion must fit in
+	OpenAIContextMaxTokens int
+
+	// AllowedModels restricts the models clients may request; empty allows any model
+	AllowedModels []string
+	// ModelFallbackChain lists, in order, the models an AI chat falls back to when the
+	// provider reports the requested model as unavailable
+	ModelFallbackChain []string
+
+	// Anthropic Configuration
+	AnthropicAPIKey  string
+	AnthropicModel   string
+	AnthropicTimeout int // in seconds
+
+	// AutoTitleEnabled names new AI conversations from their first exchange
+	AutoTitleEnabled bool
+
+	// ModerationEnabled runs AI chat messages through the OpenAI moderation API before they
+	// are stored or sent to the provider
+	ModerationEnabled bool
+
+	// StartupOpenAICheck makes startup fail when OpenAI rejects OPENAI_API_KEY, instead of
+	// the first user request discovering it
+	StartupOpenAICheck bool
+
+	// WebhookURL receives a signed POST whenever an AI reply is stored; empty disables it
+	WebhookURL string
+	// WebhookSecret keys the HMAC-SHA256 signature sent with every webhook event
+	WebhookSecret string
+	// WebhookMaxRetries is the number of times a failed webhook delivery is retried
+	WebhookMaxRetries int
+	WebhookTimeout    int // in seconds
 
 	// Database Configuration (if needed for chat history)
 	PostgresUser         string
@@ -59,7 +155,38 @@ type Config struct {
 	DBMaxConnections     int
 	DBMaxIdleConnections int
 	DBConnectionTimeout  int // in seconds
+	DBConnMaxLifetime    int // in seconds
+	DBConnMaxIdleTime    int // in seconds
 	MigrationsDir        string
+	// RunMigrations applies pending migrations at startup; turn it off when the schema is
+	// migrated by a separate job
+	RunMigrations bool
+	// RestoreWindowHours is how long a deleted conversation can still be restored
+	RestoreWindowHours int
+	// SlowQueryThresholdMS logs a warning for every database query that takes longer than
+	// this many milliseconds (0 disables it)
+	SlowQueryThresholdMS int
+	// IdempotencyKeyTTLHours is how long a SendMessage idempotency key returns the original message
+	IdempotencyKeyTTLHours int
+	// ConversationArchiveDays archives conversations idle for longer than this many days
+	// (0 disables archival)
+	ConversationArchiveDays     int
+	ConversationArchiveInterval int // in seconds
+	// MaxConversationsPerUser caps how many conversations a user can have (0 means unlimited)
+	MaxConversationsPerUser int
+	// MaxConcurrentStreamsPerUser caps how many streams (gRPC streams and WebSockets) a user
+	// can hold open at once (0 means unlimited)
+	MaxConcurrentStreamsPerUser int
+	// DailyTokenBudget caps the AI tokens a user can spend per UTC day (0 means unlimited)
+	DailyTokenBudget int
+	// MaxHistoryOffset caps the offset of an offset-paged history request; deeper pages are
+	// read with the cursor (0 means unlimited)
+	MaxHistoryOffset int
+	// MaxHistoryResponseBytes caps the combined message content of a history page; a page is
+	// cut short once it is reached (0 means unlimited)
+	MaxHistoryResponseBytes int
+	// MaxMessageLength caps the characters of a user message, wherever it is sent from
+	MaxMessageLength int
 
 	// Rate Limiting
 	RateLimitEnabled  bool
@@ -75,6 +202,10 @@ type Config struct {
 	LogSensitiveData  bool
 	LogRequestHeaders bool
 	LogResponseBody   bool
+
+	// OTelExporterEndpoint is the OTLP gRPC collector URL spans are exported to; tracing
+	// export is disabled when it is empty
+	OTelExporterEndpoint string
 }
 
 // LoadConfig loads and validates configuration from environment variables
@@ -104,15 +235,31 @@ func LoadConfig() (*Config, error) {
 		openAITimeout = 30
 	}
 
+	environment := getEnv("APP_ENV", DEVELOPMENT_ENV)
+
 	cfg := &Config{
-		Environment:        getEnv("APP_ENV", "development"),
+		Environment:        environment,
 		ChatServicePort:    getEnv("APP_PORT", "8082"),
 		RestGatewayPort:    getEnv("REST_PORT", "8083"),
 		LogLevel:           getEnv("LOG_LEVEL", "debug"),
 		LogJSONFormat:      getEnvAsBool("LOG_JSON_FORMAT", false),
+		LogSampleRate:      getEnvAsInt("LOG_SAMPLE_RATE", 0),
+		LogSampleBurst:     getEnvAsInt("LOG_SAMPLE_BURST", 0),
 		HealthCheckTimeout: getEnvAsInt("HEALTH_CHECK_TIMEOUT", 30),
+		HealthCheckLLM:     getEnvAsBool("HEALTH_CHECK_LLM", false),
 		ServerReadTimeout:  getEnvAsInt("SERVER_READ_TIMEOUT", 30),
 		ServerWriteTimeout: getEnvAsInt("SERVER_WRITE_TIMEOUT", 30),
+		RequestTimeout:     getEnvAsInt("REQUEST_TIMEOUT", 25), // below the write timeout, so REST clients still get a response
+		ShutdownTimeout:    getEnvAsInt("SHUTDOWN_TIMEOUT_SECONDS", 5),
+
+		MaxRequestBodyBytes: int64(getEnvAsInt("MAX_REQUEST_BODY_BYTES", 1<<20)),
+		CompressionEnabled:  getEnvAsBool("COMPRESSION_ENABLED", true),
+		CompressionMinBytes: getEnvAsInt("COMPRESSION_MIN_BYTES", 1024),
+		GRPCMaxMessageBytes: getEnvAsInt("GRPC_MAX_MESSAGE_BYTES", 4<<20),
+
+		GRPCKeepaliveMinTime:             getEnvAsInt("GRPC_KEEPALIVE_MIN_TIME", 30), // half the keepalive time of our clients
+		GRPCKeepalivePermitWithoutStream: getEnvAsBool("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", true),
+		GRPCReflectionEnabled:            getEnvAsBool("GRPC_REFLECTION_ENABLED", environment == DEVELOPMENT_ENV),
 
 		// Security Configuration
 		TLSEnabled:    getEnvAsBool("TLS_ENABLED", false),
@@ -128,13 +275,43 @@ func LoadConfig() (*Config, error) {
 		AuthServiceCertFile: getEnv("AUTH_SERVICE_CERT_FILE", ""),
 		AuthServiceKeyFile:  getEnv("AUTH_SERVICE_KEY_FILE", ""),
 		AuthServiceCAFile:   getEnv("AUTH_SERVICE_CA_FILE", ""),
+		AuthServiceTarget:   getEnv("AUTH_SERVICE_TARGET", ""),
+		AuthLBPolicy:        getEnv("AUTH_LB_POLICY", "round_robin"),
+		TokenCacheTTL:       getEnvAsInt("TOKEN_CACHE_TTL", 30),
+
+		LLMProvider: strings.ToLower(getEnv("LLM_PROVIDER", OPENAI_PROVIDER)),
 
 		// OpenAI Configuration
 		OpenAIAPIKey:      getEnv("OPENAI_API_KEY", ""),
+		OpenAIBaseURL:     getEnv("OPENAI_BASE_URL", "https://api.openai.com/v1"),
+		OpenAIAPIVersion:  getEnv("OPENAI_API_VERSION", ""),
 		OpenAIModel:       getEnv("OPENAI_MODEL", "gpt-3.5-turbo"),
 		OpenAIMaxTokens:   openAIMaxTokens,
 		OpenAITemperature: openAITemp,
 		OpenAITimeout:     openAITimeout,
+		OpenAIMaxRetries:  getEnvAsInt("OPENAI_MAX_RETRIES", 3),
+
+		OpenAIContextWindow:    getEnvAsInt("OPENAI_CONTEXT_WINDOW", 10),
+		OpenAIContextMaxTokens: getEnvAsInt("OPENAI_CONTEXT_MAX_TOKENS", 4096),
+
+		AllowedModels:      getEnvAsList("ALLOWED_MODELS"),
+		ModelFallbackChain: getEnvAsList("MODEL_FALLBACK_CHAIN"),
+
+		// Anthropic Configuration
+		AnthropicAPIKey:  getEnv("ANTHROPIC_API_KEY", ""),
+		AnthropicModel:   getEnv("ANTHROPIC_MODEL", "claude-3-5-haiku-latest"),
+		AnthropicTimeout: getEnvAsInt("ANTHROPIC_TIMEOUT", 30),
+
+		AutoTitleEnabled: getEnvAsBool("AUTO_TITLE_ENABLED", false),
+
+		ModerationEnabled: getEnvAsBool("MODERATION_ENABLED", false),
+
+		StartupOpenAICheck: getEnvAsBool("STARTUP_OPENAI_CHECK", false),
+
+		WebhookURL:        getEnv("WEBHOOK_URL", ""),
+		WebhookSecret:     getEnv("WEBHOOK_SECRET", ""),
+		WebhookMaxRetries: getEnvAsInt("WEBHOOK_MAX_RETRIES", 3),
+		WebhookTimeout:    getEnvAsInt("WEBHOOK_TIMEOUT", 5),
 
 		// Database Configuration
 		PostgresUser:         getEnv("POSTGRES_USER", "postgres"),
@@ -146,7 +323,28 @@ func LoadConfig() (*Config, error) {
 		DBMaxConnections:     getEnvAsInt("DB_MAX_CONNECTIONS", 10),
 		DBMaxIdleConnections: getEnvAsInt("DB_MAX_IDLE_CONNECTIONS", 5),
 		DBConnectionTimeout:  getEnvAsInt("DB_CONNECTION_TIMEOUT", 30),
+		DBConnMaxLifetime:    getEnvAsInt("DB_CONN_MAX_LIFETIME", 1800),
+		DBConnMaxIdleTime:    getEnvAsInt("DB_CONN_MAX_IDLE_TIME", 300),
 		MigrationsDir:        getEnv("MIGRATIONS_DIR", "./storage/migrations"),
+		RunMigrations:        getEnvAsBool("RUN_MIGRATIONS", true),
+		RestoreWindowHours:   getEnvAsInt("RESTORE_WINDOW_HOURS", 720),
+		SlowQueryThresholdMS: getEnvAsInt("SLOW_QUERY_THRESHOLD_MS", 500),
+
+		IdempotencyKeyTTLHours: getEnvAsInt("IDEMPOTENCY_KEY_TTL_HOURS", 24),
+
+		ConversationArchiveDays:     getEnvAsInt("CONVERSATION_ARCHIVE_DAYS", 0),
+		ConversationArchiveInterval: getEnvAsInt("CONVERSATION_ARCHIVE_INTERVAL", 3600),
+
+		MaxConversationsPerUser: getEnvAsInt("MAX_CONVERSATIONS_PER_USER", 0),
+
+		MaxConcurrentStreamsPerUser: getEnvAsInt("MAX_CONCURRENT_STREAMS_PER_USER", 10),
+
+		DailyTokenBudget: getEnvAsInt("DAILY_TOKEN_BUDGET", 0),
+
+		MaxHistoryOffset:        getEnvAsInt("MAX_HISTORY_OFFSET", 10000),
+		MaxHistoryResponseBytes: getEnvAsInt("MAX_HISTORY_RESPONSE_BYTES", 1<<20),
+
+		MaxMessageLength: getEnvAsInt("MAX_MESSAGE_LENGTH", 4000),
 
 		// Rate Limiting
 		RateLimitEnabled:  getEnvAsBool("RATE_LIMIT_ENABLED", true),
@@ -162,6 +360,8 @@ func LoadConfig() (*Config, error) {
 		LogSensitiveData:  getEnvAsBool("LOG_SENSITIVE_DATA", false),
 		LogRequestHeaders: getEnvAsBool("LOG_REQUEST_HEADERS", false),
 		LogResponseBody:   getEnvAsBool("LOG_RESPONSE_BODY", false),
+
+		OTelExporterEndpoint: getEnv("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
 	}
 
 	// Validate required configuration
@@ -174,13 +374,154 @@ func LoadConfig() (*Config, error) {
 
 // validate checks if the configuration is valid
 func (c *Config) validate() error {
-	if c.OpenAIAPIKey == "" {
-		return fmt.Errorf("OPENAI_API_KEY is required")
+	switch c.LLMProvider {
+	case OPENAI_PROVIDER:
+		if c.OpenAIAPIKey == "" {
+			return fmt.Errorf("OPENAI_API_KEY is required")
+		}
+	case ANTHROPIC_PROVIDER:
+		if c.AnthropicAPIKey == "" {
+			return fmt.Errorf("ANTHROPIC_API_KEY is required")
+		}
+	default:
+		return fmt.Errorf("unsupported LLM_PROVIDER %q (must be %q or %q)", c.LLMProvider, OPENAI_PROVIDER, ANTHROPIC_PROVIDER)
+	}
+
+	// Moderation always goes through OpenAI, whichever provider serves completions
+	if c.ModerationEnabled && c.OpenAIAPIKey == "" {
+		return fmt.Errorf("OPENAI_API_KEY is required when MODERATION_ENABLED is set")
+	}
+	if c.StartupOpenAICheck && c.OpenAIAPIKey == "" {
+		return fmt.Errorf("OPENAI_API_KEY is required when STARTUP_OPENAI_CHECK is set")
+	}
+
+	// Requests without a model use the default, so it must be allowed too
+	if len(c.AllowedModels) > 0 && !c.ModelAllowed(c.DefaultModel()) {
+		return fmt.Errorf("ALLOWED_MODELS must include the default model %q", c.DefaultModel())
+	}
+	for _, model := range c.ModelFallbackChain {
+		if !c.ModelAllowed(model) {
+			return fmt.Errorf("MODEL_FALLBACK_CHAIN model %q is not in ALLOWED_MODELS", model)
+		}
 	}
 
 	if c.AuthServiceHost == "" {
 		return fmt.Errorf("AUTH_SERVICE_HOST is required")
 	}
+	if c.AuthLBPolicy != "round_robin" && c.AuthLBPolicy != "pick_first" {
+		return fmt.Errorf("AUTH_LB_POLICY must be %q or %q", "round_robin", "pick_first")
+	}
+
+	if c.LogSampleRate < 0 || c.LogSampleBurst < 0 {
+		return fmt.Errorf("LOG_SAMPLE_RATE and LOG_SAMPLE_BURST cannot be negative")
+	}
+
+	if c.RequestTimeout < 0 {
+		return fmt.Errorf("REQUEST_TIMEOUT must not be negative")
+	}
+
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("SHUTDOWN_TIMEOUT_SECONDS must be positive")
+	}
+
+	if c.MaxRequestBodyBytes <= 0 {
+		return fmt.Errorf("MAX_REQUEST_BODY_BYTES must be positive")
+	}
+
+	if c.CompressionMinBytes < 0 {
+		return fmt.Errorf("COMPRESSION_MIN_BYTES must not be negative")
+	}
+
+	if c.GRPCMaxMessageBytes <= 0 || c.GRPCMaxMessageBytes > maxGRPCMessageBytes {
+		return fmt.Errorf("GRPC_MAX_MESSAGE_BYTES must be between 1 and %d", maxGRPCMessageBytes)
+	}
+
+	if c.GRPCKeepaliveMinTime <= 0 {
+		return fmt.Errorf("GRPC_KEEPALIVE_MIN_TIME must be positive")
+	}
+
+	baseURL, err := url.Parse(c.OpenAIBaseURL)
+	if err != nil || (baseURL.Scheme != "http" && baseURL.Scheme != "https") || baseURL.Host == "" || baseURL.RawQuery != "" {
+		return fmt.Errorf("OPENAI_BASE_URL must be an http or https URL without a query; use OPENAI_API_VERSION for the api-version parameter")
+	}
+
+	if c.OTelExporterEndpoint != "" {
+		endpoint, err := url.Parse(c.OTelExporterEndpoint)
+		if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+			return fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT must be an http or https URL")
+		}
+	}
+
+	if c.WebhookURL != "" {
+		webhook, err := url.Parse(c.WebhookURL)
+		if err != nil || (webhook.Scheme != "http" && webhook.Scheme != "https") || webhook.Host == "" {
+			return fmt.Errorf("WEBHOOK_URL must be an http or https URL")
+		}
+		if c.WebhookSecret == "" {
+			return fmt.Errorf("WEBHOOK_SECRET is required when WEBHOOK_URL is set")
+		}
+		if c.WebhookMaxRetries < 0 {
+			return fmt.Errorf("WEBHOOK_MAX_RETRIES must not be negative")
+		}
+		if c.WebhookTimeout <= 0 {
+			return fmt.Errorf("WEBHOOK_TIMEOUT must be positive")
+		}
+	}
+
+	// Validate database connection pool limits
+	if c.DBMaxConnections <= 0 {
+		return fmt.Errorf("DB_MAX_CONNECTIONS must be positive")
+	}
+	if c.DBMaxIdleConnections <= 0 {
+		return fmt.Errorf("DB_MAX_IDLE_CONNECTIONS must be positive")
+	}
+	if c.DBMaxIdleConnections > c.DBMaxConnections {
+		return fmt.Errorf("DB_MAX_IDLE_CONNECTIONS cannot exceed DB_MAX_CONNECTIONS")
+	}
+	if c.DBConnMaxLifetime < 0 {
+		return fmt.Errorf("DB_CONN_MAX_LIFETIME must not be negative")
+	}
+	if c.DBConnMaxIdleTime < 0 {
+		return fmt.Errorf("DB_CONN_MAX_IDLE_TIME must not be negative")
+	}
+	if c.SlowQueryThresholdMS < 0 {
+		return fmt.Errorf("SLOW_QUERY_THRESHOLD_MS must not be negative")
+	}
+
+	if c.ConversationArchiveDays < 0 {
+		return fmt.Errorf("CONVERSATION_ARCHIVE_DAYS must not be negative")
+	}
+	if c.ConversationArchiveDays > 0 && c.ConversationArchiveInterval <= 0 {
+		return fmt.Errorf("CONVERSATION_ARCHIVE_INTERVAL must be positive when archival is enabled")
+	}
+
+	if c.MaxConversationsPerUser < 0 {
+		return fmt.Errorf("MAX_CONVERSATIONS_PER_USER must not be negative")
+	}
+	if c.MaxConcurrentStreamsPerUser < 0 {
+		return fmt.Errorf("MAX_CONCURRENT_STREAMS_PER_USER must not be negative")
+	}
+	if c.DailyTokenBudget < 0 {
+		return fmt.Errorf("DAILY_TOKEN_BUDGET must not be negative")
+	}
+	if c.MaxHistoryOffset < 0 {
+		return fmt.Errorf("MAX_HISTORY_OFFSET must not be negative")
+	}
+	if c.MaxHistoryResponseBytes < 0 {
+		return fmt.Errorf("MAX_HISTORY_RESPONSE_BYTES must not be negative")
+	}
+	if c.MaxMessageLength <= 0 {
+		return fmt.Errorf("MAX_MESSAGE_LENGTH must be positive")
+	}
+	// A message of MaxMessageLength characters, each up to utf8.UTFMax bytes, has to fit in
+	// a WebSocket frame and a REST request body, or it is rejected before it is validated
+	messageBytes := int64(c.MaxMessageLength)*utf8.UTFMax + messageEnvelopeBytes
+	if messageBytes > WebSocketMaxFrameBytes {
+		return fmt.Errorf("MAX_MESSAGE_LENGTH of %d characters does not fit in a %d byte WebSocket frame", c.MaxMessageLength, WebSocketMaxFrameBytes)
+	}
+	if messageBytes > c.MaxRequestBodyBytes {
+		return fmt.Errorf("MAX_MESSAGE_LENGTH of %d characters does not fit in MAX_REQUEST_BODY_BYTES (%d)", c.MaxMessageLength, c.MaxRequestBodyBytes)
+	}
 
 	// Only validate TLS certificates if TLS is actually enabled
 	if c.AuthServiceTLS && c.TLSEnabled {
@@ -195,6 +536,30 @@ func (c *Config) validate() error {
 	return nil
 }
 
+// DefaultModel returns the configured default model of the active LLM provider
+func (c *Config) DefaultModel() string {
+	if c.LLMProvider == ANTHROPIC_PROVIDER {
+		return c.AnthropicModel
+	}
+	return c.OpenAIModel
+}
+
+// ModelAllowed reports whether clients may request model. Every model is allowed when
+// ALLOWED_MODELS is unset.
+func (c *Config) ModelAllowed(model string) bool {
+	return len(c.AllowedModels) == 0 || slices.Contains(c.AllowedModels, model)
+}
+
+// AuthServiceDialTarget returns the gRPC target the auth service is dialed at. Without
+// AUTH_SERVICE_TARGET, AUTH_SERVICE_HOST is resolved through DNS, so a name backed by
+// several auth replicas balances calls across all of them.
+func (c *Config) AuthServiceDialTarget() string {
+	if c.AuthServiceTarget != "" {
+		return c.AuthServiceTarget
+	}
+	return "dns:///" + net.JoinHostPort(c.AuthServiceHost, c.AuthServicePort)
+}
+
 // GetAuthServiceEndpoint returns the full auth service endpoint
 func (c *Config) GetAuthServiceEndpoint() string {
 	protocol := "http"
@@ -230,6 +595,17 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+// getEnvAsList splits a comma-separated environment variable, skipping empty entries
+func getEnvAsList(key string) []string {
+	var values []string
+	for _, value := range strings.Split(os.Getenv(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
 func parseTLSVersion(version string) uint16 {
 	switch strings.ToLower(version) {
 	case "1.0":
@@ -0,0 +1,510 @@
+package configs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// setLLMEnv sets the provider related environment for a single test, clearing
+// anything not listed so the host environment can't leak in
+func setLLMEnv(t *testing.T, env map[string]string) {
+	t.Helper()
+	for _, key := range []string{"LLM_PROVIDER", "OPENAI_API_KEY", "OPENAI_MODEL", "ANTHROPIC_API_KEY", "ANTHROPIC_MODEL"} {
+		t.Setenv(key, env[key])
+	}
+}
+
+func TestLoadConfig_LLMProviderSelection(t *testing.T) {
+	tests := []struct {
+		name             string
+		env              map[string]string
+		expectedProvider string
+		expectedModel    string
+		expectedError    string
+	}{
+		{
+			name:             "defaults to openai",
+			env:              map[string]string{"OPENAI_API_KEY": "sk-test"},
+			expectedProvider: OPENAI_PROVIDER,
+			expectedModel:    "gpt-3.5-turbo",
+		},
+		{
+			name:             "openai with custom model",
+			env:              map[string]string{"LLM_PROVIDER": "openai", "OPENAI_API_KEY": "sk-test", "OPENAI_MODEL": "gpt-4o"},
+			expectedProvider: OPENAI_PROVIDER,
+			expectedModel:    "gpt-4o",
+		},
+		{
+			name:             "anthropic",
+			env:              map[string]string{"LLM_PROVIDER": "anthropic", "ANTHROPIC_API_KEY": "sk-ant-test"},
+			expectedProvider: ANTHROPIC_PROVIDER,
+			expectedModel:    "claude-3-5-haiku-latest",
+		},
+		{
+			name:             "provider name is case insensitive",
+			env:              map[string]string{"LLM_PROVIDER": "Anthropic", "ANTHROPIC_API_KEY": "sk-ant-test", "ANTHROPIC_MODEL": "claude-sonnet-4-0"},
+			expectedProvider: ANTHROPIC_PROVIDER,
+			expectedModel:    "claude-sonnet-4-0",
+		},
+		{
+			name:          "anthropic without api key",
+			env:           map[string]string{"LLM_PROVIDER": "anthropic", "OPENAI_API_KEY": "sk-test"},
+			expectedError: "ANTHROPIC_API_KEY is required",
+		},
+		{
+			name:          "openai without api key",
+			env:           map[string]string{"LLM_PROVIDER": "openai", "ANTHROPIC_API_KEY": "sk-ant-test"},
+			expectedError: "OPENAI_API_KEY is required",
+		},
+		{
+			name:          "unknown provider",
+			env:           map[string]string{"LLM_PROVIDER": "llama", "OPENAI_API_KEY": "sk-test"},
+			expectedError: `unsupported LLM_PROVIDER "llama"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setLLMEnv(t, tt.env)
+
+			cfg, err := LoadConfig()
+
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				assert.Nil(t, cfg)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedProvider, cfg.LLMProvider)
+			assert.Equal(t, tt.expectedModel, cfg.DefaultModel())
+		})
+	}
+}
+
+func TestLoadConfig_DBPoolValidation(t *testing.T) {
+	tests := []struct {
+		name          string
+		env           map[string]string
+		expectedError string
+	}{
+		{
+			name: "defaults",
+		},
+		{
+			name: "custom pool",
+			env:  map[string]string{"DB_MAX_CONNECTIONS": "50", "DB_MAX_IDLE_CONNECTIONS": "50", "DB_CONN_MAX_LIFETIME": "0"},
+		},
+		{
+			name:          "idle above max",
+			env:           map[string]string{"DB_MAX_CONNECTIONS": "5", "DB_MAX_IDLE_CONNECTIONS": "10"},
+			expectedError: "DB_MAX_IDLE_CONNECTIONS cannot exceed DB_MAX_CONNECTIONS",
+		},
+		{
+			name:          "zero max connections",
+			env:           map[string]string{"DB_MAX_CONNECTIONS": "0"},
+			expectedError: "DB_MAX_CONNECTIONS must be positive",
+		},
+		{
+			name:          "negative lifetime",
+			env:           map[string]string{"DB_CONN_MAX_LIFETIME": "-1"},
+			expectedError: "DB_CONN_MAX_LIFETIME must not be negative",
+		},
+		{
+			name:          "negative slow query threshold",
+			env:           map[string]string{"SLOW_QUERY_THRESHOLD_MS": "-1"},
+			expectedError: "SLOW_QUERY_THRESHOLD_MS must not be negative",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+			for _, key := range []string{"DB_MAX_CONNECTIONS", "DB_MAX_IDLE_CONNECTIONS", "DB_CONN_MAX_LIFETIME", "DB_CONN_MAX_IDLE_TIME", "SLOW_QUERY_THRESHOLD_MS"} {
+				t.Setenv(key, tt.env[key])
+			}
+
+			cfg, err := LoadConfig()
+
+			if tt.expectedError != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.expectedError)
+				return
+			}
+			require.NoError(t, err)
+			assert.LessOrEqual(t, cfg.DBMaxIdleConnections, cfg.DBMaxConnections)
+		})
+	}
+}
+
+func TestLoadConfig_RequestTimeout(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("REQUEST_TIMEOUT", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 25, cfg.RequestTimeout)
+
+	t.Setenv("REQUEST_TIMEOUT", "0")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.RequestTimeout)
+
+	t.Setenv("REQUEST_TIMEOUT", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "REQUEST_TIMEOUT must not be negative")
+}
+
+func TestLoadConfig_ShutdownTimeout(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("SHUTDOWN_TIMEOUT_SECONDS", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 5, cfg.ShutdownTimeout)
+
+	t.Setenv("SHUTDOWN_TIMEOUT_SECONDS", "60")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 60, cfg.ShutdownTimeout)
+
+	for _, value := range []string{"0", "-1"} {
+		t.Setenv("SHUTDOWN_TIMEOUT_SECONDS", value)
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "SHUTDOWN_TIMEOUT_SECONDS must be positive")
+	}
+}
+
+func TestLoadConfig_MaxConversationsPerUser(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("MAX_CONVERSATIONS_PER_USER", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.MaxConversationsPerUser)
+
+	t.Setenv("MAX_CONVERSATIONS_PER_USER", "100")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 100, cfg.MaxConversationsPerUser)
+
+	t.Setenv("MAX_CONVERSATIONS_PER_USER", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "MAX_CONVERSATIONS_PER_USER must not be negative")
+}
+
+func TestLoadConfig_MaxConcurrentStreamsPerUser(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("MAX_CONCURRENT_STREAMS_PER_USER", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.MaxConcurrentStreamsPerUser)
+
+	t.Setenv("MAX_CONCURRENT_STREAMS_PER_USER", "0")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.MaxConcurrentStreamsPerUser)
+
+	t.Setenv("MAX_CONCURRENT_STREAMS_PER_USER", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "MAX_CONCURRENT_STREAMS_PER_USER must not be negative")
+}
+
+func TestLoadConfig_DailyTokenBudget(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("DAILY_TOKEN_BUDGET", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 0, cfg.DailyTokenBudget)
+
+	t.Setenv("DAILY_TOKEN_BUDGET", "50000")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 50000, cfg.DailyTokenBudget)
+
+	t.Setenv("DAILY_TOKEN_BUDGET", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "DAILY_TOKEN_BUDGET must not be negative")
+}
+
+func TestLoadConfig_HistoryGuards(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("MAX_HISTORY_OFFSET", "")
+	t.Setenv("MAX_HISTORY_RESPONSE_BYTES", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 10000, cfg.MaxHistoryOffset)
+	assert.Equal(t, 1<<20, cfg.MaxHistoryResponseBytes)
+
+	t.Setenv("MAX_HISTORY_OFFSET", "500")
+	t.Setenv("MAX_HISTORY_RESPONSE_BYTES", "0")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 500, cfg.MaxHistoryOffset)
+	assert.Equal(t, 0, cfg.MaxHistoryResponseBytes)
+
+	t.Setenv("MAX_HISTORY_OFFSET", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "MAX_HISTORY_OFFSET must not be negative")
+
+	t.Setenv("MAX_HISTORY_OFFSET", "")
+	t.Setenv("MAX_HISTORY_RESPONSE_BYTES", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "MAX_HISTORY_RESPONSE_BYTES must not be negative")
+}
+
+func TestLoadConfig_MaxMessageLength(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("MAX_MESSAGE_LENGTH", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 4000, cfg.MaxMessageLength)
+
+	t.Setenv("MAX_MESSAGE_LENGTH", "8000")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 8000, cfg.MaxMessageLength)
+
+	for _, invalid := range []string{"0", "-1"} {
+		t.Setenv("MAX_MESSAGE_LENGTH", invalid)
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "MAX_MESSAGE_LENGTH must be positive")
+	}
+
+	// The longest message, at four bytes a character, must fit in a WebSocket frame
+	t.Setenv("MAX_MESSAGE_LENGTH", "16128")
+	_, err = LoadConfig()
+	require.NoError(t, err)
+
+	t.Setenv("MAX_MESSAGE_LENGTH", "16129")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "does not fit in a 65536 byte WebSocket frame")
+
+	// and in a REST request body
+	t.Setenv("MAX_MESSAGE_LENGTH", "4000")
+	t.Setenv("MAX_REQUEST_BODY_BYTES", "16384")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "does not fit in MAX_REQUEST_BODY_BYTES (16384)")
+}
+
+func TestLoadConfig_AllowedModels(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test", "OPENAI_MODEL": "gpt-3.5-turbo"})
+
+	t.Setenv("ALLOWED_MODELS", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.AllowedModels)
+	assert.True(t, cfg.ModelAllowed("any-model"), "every model is allowed when the list is unset")
+
+	t.Setenv("ALLOWED_MODELS", " gpt-3.5-turbo, gpt-4o-mini ,,")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-3.5-turbo", "gpt-4o-mini"}, cfg.AllowedModels)
+	assert.True(t, cfg.ModelAllowed("gpt-4o-mini"))
+	assert.False(t, cfg.ModelAllowed("gpt-4"))
+
+	t.Setenv("ALLOWED_MODELS", "gpt-4o-mini")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `ALLOWED_MODELS must include the default model "gpt-3.5-turbo"`)
+}
+
+func TestLoadConfig_ModelFallbackChain(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test", "OPENAI_MODEL": "gpt-4o"})
+	t.Setenv("ALLOWED_MODELS", "")
+
+	t.Setenv("MODEL_FALLBACK_CHAIN", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.ModelFallbackChain)
+
+	t.Setenv("MODEL_FALLBACK_CHAIN", "gpt-4o-mini, gpt-3.5-turbo")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o-mini", "gpt-3.5-turbo"}, cfg.ModelFallbackChain)
+
+	t.Setenv("ALLOWED_MODELS", "gpt-4o,gpt-4o-mini")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `MODEL_FALLBACK_CHAIN model "gpt-3.5-turbo" is not in ALLOWED_MODELS`)
+}
+
+func TestLoadConfig_OpenAIBaseURL(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("OPENAI_BASE_URL", "")
+	t.Setenv("OPENAI_API_VERSION", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://api.openai.com/v1", cfg.OpenAIBaseURL)
+	assert.Empty(t, cfg.OpenAIAPIVersion)
+
+	t.Setenv("OPENAI_BASE_URL", "https://example.openai.azure.com/openai/deployments/gpt-4o")
+	t.Setenv("OPENAI_API_VERSION", "2024-06-01")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://example.openai.azure.com/openai/deployments/gpt-4o", cfg.OpenAIBaseURL)
+	assert.Equal(t, "2024-06-01", cfg.OpenAIAPIVersion)
+
+	for _, invalid := range []string{"api.openai.com/v1", "ftp://proxy.internal/v1", "https://", "https://proxy.internal/v1?api-version=1"} {
+		t.Setenv("OPENAI_BASE_URL", invalid)
+		_, err = LoadConfig()
+		assert.ErrorContains(t, err, "OPENAI_BASE_URL must be an http or https URL", invalid)
+	}
+}
+
+func TestLoadConfig_AuthServiceTarget(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+	t.Setenv("AUTH_SERVICE_HOST", "auth-service")
+	t.Setenv("AUTH_SERVICE_PORT", "8081")
+
+	t.Setenv("AUTH_SERVICE_TARGET", "")
+	t.Setenv("AUTH_LB_POLICY", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "dns:///auth-service:8081", cfg.AuthServiceDialTarget())
+	assert.Equal(t, "round_robin", cfg.AuthLBPolicy)
+
+	t.Setenv("AUTH_SERVICE_TARGET", "dns:///auth-headless.default.svc:8081")
+	t.Setenv("AUTH_LB_POLICY", "pick_first")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "dns:///auth-headless.default.svc:8081", cfg.AuthServiceDialTarget())
+	assert.Equal(t, "pick_first", cfg.AuthLBPolicy)
+
+	t.Setenv("AUTH_LB_POLICY", "least_request")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, `AUTH_LB_POLICY must be "round_robin" or "pick_first"`)
+}
+
+func TestLoadConfig_ModerationEnabled(t *testing.T) {
+	setLLMEnv(t, map[string]string{"LLM_PROVIDER": "anthropic", "ANTHROPIC_API_KEY": "sk-ant-test"})
+
+	t.Setenv("MODERATION_ENABLED", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.ModerationEnabled)
+
+	t.Setenv("MODERATION_ENABLED", "true")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "OPENAI_API_KEY is required when MODERATION_ENABLED is set")
+
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.ModerationEnabled)
+}
+
+func TestLoadConfig_Compression(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("COMPRESSION_ENABLED", "")
+	t.Setenv("COMPRESSION_MIN_BYTES", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.CompressionEnabled)
+	assert.Equal(t, 1024, cfg.CompressionMinBytes)
+
+	t.Setenv("COMPRESSION_ENABLED", "false")
+	t.Setenv("COMPRESSION_MIN_BYTES", "256")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.CompressionEnabled)
+	assert.Equal(t, 256, cfg.CompressionMinBytes)
+
+	t.Setenv("COMPRESSION_MIN_BYTES", "-1")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "COMPRESSION_MIN_BYTES must not be negative")
+}
+
+func TestLoadConfig_StartupOpenAICheck(t *testing.T) {
+	setLLMEnv(t, map[string]string{"LLM_PROVIDER": ANTHROPIC_PROVIDER, "ANTHROPIC_API_KEY": "sk-ant-test"})
+
+	t.Setenv("STARTUP_OPENAI_CHECK", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.StartupOpenAICheck)
+
+	t.Setenv("STARTUP_OPENAI_CHECK", "true")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "OPENAI_API_KEY is required when STARTUP_OPENAI_CHECK is set")
+
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.StartupOpenAICheck)
+}
+
+func TestLoadConfig_GRPCKeepalivePolicy(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("GRPC_KEEPALIVE_MIN_TIME", "")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 30, cfg.GRPCKeepaliveMinTime)
+	assert.True(t, cfg.GRPCKeepalivePermitWithoutStream)
+
+	t.Setenv("GRPC_KEEPALIVE_MIN_TIME", "10")
+	t.Setenv("GRPC_KEEPALIVE_PERMIT_WITHOUT_STREAM", "false")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, 10, cfg.GRPCKeepaliveMinTime)
+	assert.False(t, cfg.GRPCKeepalivePermitWithoutStream)
+
+	t.Setenv("GRPC_KEEPALIVE_MIN_TIME", "0")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "GRPC_KEEPALIVE_MIN_TIME must be positive")
+}
+
+func TestLoadConfig_GRPCReflection(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("GRPC_REFLECTION_ENABLED", "")
+	t.Setenv("APP_ENV", DEVELOPMENT_ENV)
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.GRPCReflectionEnabled, "reflection defaults to on in development")
+
+	t.Setenv("APP_ENV", "staging")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.False(t, cfg.GRPCReflectionEnabled, "reflection defaults to off outside development")
+
+	t.Setenv("GRPC_REFLECTION_ENABLED", "true")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.True(t, cfg.GRPCReflectionEnabled)
+}
+
+func TestLoadConfig_Webhook(t *testing.T) {
+	setLLMEnv(t, map[string]string{"OPENAI_API_KEY": "sk-test"})
+
+	t.Setenv("WEBHOOK_URL", "")
+	t.Setenv("WEBHOOK_SECRET", "")
+	t.Setenv("WEBHOOK_MAX_RETRIES", "")
+	t.Setenv("WEBHOOK_TIMEOUT", "")
+	cfg, err := LoadConfig()
+	require.NoError(t, err)
+	assert.Empty(t, cfg.WebhookURL)
+	assert.Equal(t, 3, cfg.WebhookMaxRetries)
+	assert.Equal(t, 5, cfg.WebhookTimeout)
+
+	t.Setenv("WEBHOOK_URL", "https://hooks.example.com/chat")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "WEBHOOK_SECRET is required when WEBHOOK_URL is set")
+
+	t.Setenv("WEBHOOK_SECRET", "whsec")
+	cfg, err = LoadConfig()
+	require.NoError(t, err)
+	assert.Equal(t, "https://hooks.example.com/chat", cfg.WebhookURL)
+	assert.Equal(t, "whsec", cfg.WebhookSecret)
+
+	t.Setenv("WEBHOOK_URL", "hooks.example.com/chat")
+	_, err = LoadConfig()
+	assert.ErrorContains(t, err, "WEBHOOK_URL must be an http or https URL")
+}
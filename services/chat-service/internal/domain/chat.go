@@ -3,7 +3,9 @@ package domain
 import (
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -22,15 +24,101 @@ func ValidateUUID(id string) error {
 	return nil
 }
 
+// DefaultMaxMessageLength is the maximum number of characters allowed in a message unless
+// MAX_MESSAGE_LENGTH configures another limit
+const DefaultMaxMessageLength = 4000
+
+// ValidateMessageContent checks that message content is present. Its length is checked by
+// the chat service's MessageValidator, which holds the configured limit.
+func ValidateMessageContent(content string) error {
+	if reason := messageContentViolation(content); reason != "" {
+		return fmt.Errorf("message %s", reason)
+	}
+	return nil
+}
+
+// messageContentViolation returns why content is not a valid message, or an empty string
+func messageContentViolation(content string) string {
+	if content == "" {
+		return "cannot be empty"
+	}
+	return ""
+}
+
+// MessageValidator checks message content against a maximum length in characters
+type MessageValidator struct {
+	maxLength int
+}
+
+// NewMessageValidator returns a MessageValidator allowing maxLength characters; a limit
+// below 1, like the zero value, allows DefaultMaxMessageLength
+func NewMessageValidator(maxLength int) MessageValidator {
+	return MessageValidator{maxLength: maxLength}
+}
+
+// MaxLength returns the maximum number of characters allowed in a message
+func (v MessageValidator) MaxLength() int {
+	if v.maxLength > 0 {
+		return v.maxLength
+	}
+	return DefaultMaxMessageLength
+}
+
+// Validate checks that content is present and within the length limit, returning a
+// *ValidationError for the message field
+func (v MessageValidator) Validate(content string) error {
+	reason := messageContentViolation(content)
+	if limit := v.MaxLength(); reason == "" && utf8.RuneCountInString(content) > limit {
+		reason = fmt.Sprintf("too long (max %d characters)", limit)
+	}
+	if reason != "" {
+		return &ValidationError{Violations: []FieldViolation{{Field: "message", Reason: reason}}}
+	}
+	return nil
+}
+
+// MaxImportMessages is the maximum number of messages accepted in a single conversation import
+const MaxImportMessages = 10000
+
+// MessageRole identifies who wrote a message
+type MessageRole string
+
+// Roles a stored message can have
+const (
+	RoleUser      MessageRole = "user"
+	RoleAssistant MessageRole = "assistant"
+	RoleSystem    MessageRole = "system"
+)
+
+// Valid reports whether r is one of the known message roles
+func (r MessageRole) Valid() bool {
+	switch r {
+	case RoleUser, RoleAssistant, RoleSystem:
+		return true
+	default:
+		return false
+	}
+}
+
+// ValidateMessageRole checks that role is one of the roles a stored message can have
+func ValidateMessageRole(role MessageRole) error {
+	if !role.Valid() {
+		return fmt.Errorf("invalid role: %q", role)
+	}
+	return nil
+}
+
 // Message represents a chat message
 type Message struct {
-	ID             string    `json:"id" db:"id"`
-	UserID         string    `json:"user_id" db:"user_id"`
-	ConversationID string    `json:"conversation_id" db:"conversation_id"`
-	Content        string    `json:"content" db:"content"`
-	Role           string    `json:"role" db:"role"` // "user", "assistant", "system"
-	CreatedAt      time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at" db:"updated_at"`
+	ID             string      `json:"id" db:"id"`
+	UserID         string      `json:"user_id" db:"user_id"`
+	ConversationID string      `json:"conversation_id" db:"conversation_id"`
+	Content        string      `json:"content" db:"content"`
+	Role           MessageRole `json:"role" db:"role"`
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at" db:"updated_at"`
+	// Metadata holds optional client-supplied annotations; nil when none were given
+	Metadata Metadata `json:"metadata,omitempty" db:"metadata"`
 }
 
 // Conversation represents a chat conversation
@@ -40,85 +128,214 @@ type Conversation struct {
 	Title     string    `json:"title" db:"title"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	// Archived conversations are left out of the default conversation list
+	Archived bool `json:"archived" db:"archived"`
+	// Pinned conversations are listed before the others
+	Pinned bool `json:"pinned" db:"pinned"`
+	// MessageCount and LastMessageAt are only populated when conversations are read back
+	// from storage; LastMessageAt is nil for a conversation without messages
+	MessageCount  int        `json:"message_count" db:"message_count"`
+	LastMessageAt *time.Time `json:"last_message_at,omitempty" db:"last_message_at"`
 }
 
 // ChatRequest represents a request to send a message
 type ChatRequest struct {
 	UserID         string `json:"user_id" validate:"required"`
-	Message        string `json:"message" validate:"required,min=1,max=4000"`
+	Message        string `json:"message" validate:"required"`
 	ConversationID string `json:"conversation_id,omitempty"`
+	// Metadata is stored with the message and returned with it in the history
+	Metadata Metadata `json:"metadata,omitempty"`
+	// IdempotencyKey, when set, makes a repeated request return the message created by the first
+	IdempotencyKey string `json:"-"`
 }
 
-// Validate validates the ChatRequest
+// MaxIdempotencyKeyLength is the maximum number of characters allowed in an idempotency key
+const MaxIdempotencyKeyLength = 255
+
+// Validate validates the ChatRequest, returning a *ValidationError listing every invalid field
 func (r *ChatRequest) Validate() error {
-	if err := ValidateUUID(r.UserID); err != nil {
-		return fmt.Errorf("user_id: %w", err)
+	var v fieldValidator
+	v.check("user_id", ValidateUUID(r.UserID))
+	if reason := messageContentViolation(r.Message); reason != "" {
+		v.fail("message", reason)
 	}
-	if r.Message == "" {
-		return fmt.Errorf("message cannot be empty")
+	if r.ConversationID != "" {
+		v.check("conversation_id", ValidateUUID(r.ConversationID))
 	}
-	if len(r.Message) > 4000 {
-		return fmt.Errorf("message too long (max 4000 characters)")
+	if reason := metadataViolation(r.Metadata); reason != "" {
+		v.fail("metadata", reason)
 	}
-	if r.ConversationID != "" {
-		if err := ValidateUUID(r.ConversationID); err != nil {
-			return fmt.Errorf("conversation_id: %w", err)
-		}
+	if len(r.IdempotencyKey) > MaxIdempotencyKeyLength {
+		v.fail("idempotency_key", fmt.Sprintf("too long (max %d characters)", MaxIdempotencyKeyLength))
+	}
+	return v.err()
+}
+
+// Defaults applied to AI chat requests that leave the generation options unset
+const (
+	DefaultAITemperature = 0.7
+	DefaultAIMaxTokens   = 1000
+)
+
+// ChatWithAIRequest represents a request to chat with the AI. Temperature and MaxTokens are
+// pointers so an explicit zero can be told apart from an option the client left unset.
+type ChatWithAIRequest struct {
+	UserID         string `json:"-"`
+	Message        string `json:"message"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	// Model is left empty to use the active provider's default
+	Model       string   `json:"model,omitempty"`
+	Temperature *float64 `json:"temperature,omitempty"`
+	MaxTokens   *int     `json:"max_tokens,omitempty"`
+	// Metadata is stored with the user message
+	Metadata Metadata `json:"metadata,omitempty"`
+}
+
+// Validate validates the ChatWithAIRequest like a ChatRequest, returning a *ValidationError
+// listing every invalid field, and fills in the defaults for unset options. An explicit zero
+// is kept; the chat service checks the options against the model limits.
+func (r *ChatWithAIRequest) Validate() error {
+	chatReq := ChatRequest{
+		UserID:         r.UserID,
+		Message:        r.Message,
+		ConversationID: r.ConversationID,
+		Metadata:       r.Metadata,
+	}
+	if err := chatReq.Validate(); err != nil {
+		return err
+	}
+
+	if r.Temperature == nil {
+		temperature := DefaultAITemperature
+		r.Temperature = &temperature
+	}
+	if r.MaxTokens == nil {
+		maxTokens := DefaultAIMaxTokens
+		r.MaxTokens = &maxTokens
 	}
 	return nil
 }
 
+// TokenUsage represents the tokens consumed by an AI completion
+type TokenUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
 // ChatResponse represents a response from the chat
 type ChatResponse struct {
-	Message        *Message `json:"message"`
-	ConversationID string   `json:"conversation_id"`
-	IsAIResponse   bool     `json:"is_ai_response"`
+	Message        *Message    `json:"message"`
+	ConversationID string      `json:"conversation_id"`
+	IsAIResponse   bool        `json:"is_ai_response"`
+	Model          string      `json:"model,omitempty"` // Set for AI responses
+	Usage          *TokenUsage `json:"usage,omitempty"` // Set for AI responses
+	// FinishReason is why the AI reply stopped, e.g. "length" when it was truncated
+	FinishReason string `json:"finish_reason,omitempty"`
 }
 
 // GetHistoryRequest represents a request to get chat history
 type GetHistoryRequest struct {
-	UserID         string `json:"user_id" validate:"required"`
-	ConversationID string `json:"conversation_id" validate:"required"`
-	Limit          int    `json:"limit" validate:"min=1,max=100"`
-	Offset         int    `json:"offset" validate:"min=0"`
+	UserID         string  `json:"user_id" validate:"required"`
+	ConversationID string  `json:"conversation_id" validate:"required"`
+	Limit          int     `json:"limit" validate:"min=1,max=100"`
+	Offset         int     `json:"offset" validate:"min=0"`
+	Cursor         *string `json:"cursor,omitempty"` // Selects keyset paging when set
 }
 
-// Validate validates the GetHistoryRequest
+// Validate validates the GetHistoryRequest, returning a *ValidationError listing every invalid field
 func (r *GetHistoryRequest) Validate() error {
-	if err := ValidateUUID(r.UserID); err != nil {
-		return fmt.Errorf("user_id: %w", err)
-	}
-	if err := ValidateUUID(r.ConversationID); err != nil {
-		return fmt.Errorf("conversation_id: %w", err)
-	}
-	if r.Limit < 1 || r.Limit > 100 {
-		return fmt.Errorf("limit must be between 1 and 100")
-	}
-	if r.Offset < 0 {
-		return fmt.Errorf("offset must be non-negative")
-	}
-	return nil
+	var v fieldValidator
+	v.check("user_id", ValidateUUID(r.UserID))
+	v.check("conversation_id", ValidateUUID(r.ConversationID))
+	validatePage(&v, r.Limit, r.Offset)
+	validateCursor(&v, r.Cursor, r.Offset)
+	return v.err()
 }
 
 // ListConversationsRequest represents a request to list conversations
 type ListConversationsRequest struct {
+	UserID string  `json:"user_id" validate:"required"`
+	Limit  int     `json:"limit" validate:"min=1,max=100"`
+	Offset int     `json:"offset" validate:"min=0"`
+	Cursor *string `json:"cursor,omitempty"` // Selects keyset paging when set
+	// IncludeArchived lists archived conversations along with the others
+	IncludeArchived bool `json:"include_archived"`
+}
+
+// Validate validates the ListConversationsRequest, returning a *ValidationError listing every
+// invalid field
+func (r *ListConversationsRequest) Validate() error {
+	var v fieldValidator
+	v.check("user_id", ValidateUUID(r.UserID))
+	validatePage(&v, r.Limit, r.Offset)
+	validateCursor(&v, r.Cursor, r.Offset)
+	return v.err()
+}
+
+// ListUserMessagesRequest represents a request to list a user's messages across conversations
+type ListUserMessagesRequest struct {
 	UserID string `json:"user_id" validate:"required"`
 	Limit  int    `json:"limit" validate:"min=1,max=100"`
 	Offset int    `json:"offset" validate:"min=0"`
 }
 
-// Validate validates the ListConversationsRequest
-func (r *ListConversationsRequest) Validate() error {
-	if err := ValidateUUID(r.UserID); err != nil {
-		return fmt.Errorf("user_id: %w", err)
+// Validate validates the ListUserMessagesRequest, returning a *ValidationError listing every
+// invalid field
+func (r *ListUserMessagesRequest) Validate() error {
+	var v fieldValidator
+	v.check("user_id", ValidateUUID(r.UserID))
+	validatePage(&v, r.Limit, r.Offset)
+	return v.err()
+}
+
+// MaxSearchQueryLength is the maximum number of characters allowed in a search query
+const MaxSearchQueryLength = 200
+
+// SearchMessagesRequest represents a full-text search over the user's messages
+type SearchMessagesRequest struct {
+	UserID string `json:"user_id" validate:"required"`
+	Query  string `json:"query" validate:"required,max=200"`
+	Limit  int    `json:"limit" validate:"min=1,max=100"`
+	Offset int    `json:"offset" validate:"min=0"`
+}
+
+// Validate validates the SearchMessagesRequest, returning a *ValidationError listing every
+// invalid field
+func (r *SearchMessagesRequest) Validate() error {
+	var v fieldValidator
+	v.check("user_id", ValidateUUID(r.UserID))
+	if strings.TrimSpace(r.Query) == "" {
+		v.fail("query", "cannot be empty")
+	} else if len(r.Query) > MaxSearchQueryLength {
+		v.fail("query", fmt.Sprintf("too long (max %d characters)", MaxSearchQueryLength))
 	}
-	if r.Limit < 1 || r.Limit > 100 {
-		return fmt.Errorf("limit must be between 1 and 100")
+	validatePage(&v, r.Limit, r.Offset)
+	return v.err()
+}
+
+// validatePage checks that limit and offset select a valid page
+func validatePage(v *fieldValidator, limit, offset int) {
+	if limit < 1 || limit > 100 {
+		v.fail("limit", "must be between 1 and 100")
 	}
-	if r.Offset < 0 {
-		return fmt.Errorf("offset must be non-negative")
+	if offset < 0 {
+		v.fail("offset", "must be non-negative")
+	}
+}
+
+// validateCursor checks that a cursor decodes and is not combined with an offset
+func validateCursor(v *fieldValidator, cursor *string, offset int) {
+	if cursor == nil {
+		return
+	}
+	if offset != 0 {
+		v.fail("cursor", "cannot be combined with offset")
+		return
+	}
+	if _, err := ParseCursor(*cursor); err != nil {
+		v.check("cursor", err)
 	}
-	return nil
 }
 
 // GetHistoryResponse represents a response with chat history
@@ -126,16 +343,44 @@ type GetHistoryResponse struct {
 	Messages       []*Message `json:"messages"`
 	Total          int        `json:"total"`
 	ConversationID string     `json:"conversation_id"`
+	NextCursor     string     `json:"next_cursor,omitempty"`
+	PageInfo       *PageInfo  `json:"page_info"`
 }
 
 // ListConversationsResponse represents a response with conversations
 type ListConversationsResponse struct {
 	Conversations []*Conversation `json:"conversations"`
 	Total         int             `json:"total"`
+	NextCursor    string          `json:"next_cursor,omitempty"`
+	PageInfo      *PageInfo       `json:"page_info"`
+}
+
+// ListUserMessagesResponse represents a user's messages across conversations, newest first
+type ListUserMessagesResponse struct {
+	Messages []*Message `json:"messages"`
+	Total    int        `json:"total"`
+	PageInfo *PageInfo  `json:"page_info"`
+}
+
+// MessageSearchResult is a message matching a search. Snippet is a plain-text excerpt of
+// the content with the matched terms wrapped in «»; it is never HTML, since the content
+// is user input.
+type MessageSearchResult struct {
+	Message
+	Snippet string `json:"snippet" db:"snippet"`
 }
 
-// NewMessage creates a new message
-func NewMessage(userID, conversationID, content, role string) *Message {
+// SearchMessagesResponse represents a response with message search results
+type SearchMessagesResponse struct {
+	Results []*MessageSearchResult `json:"results"`
+}
+
+// NewMessage creates a new message, rejecting an unknown role
+func NewMessage(userID, conversationID, content string, role MessageRole) (*Message, error) {
+	if err := ValidateMessageRole(role); err != nil {
+		return nil, err
+	}
+
 	now := time.Now()
 	return &Message{
 		ID:             uuid.New().String(),
@@ -145,7 +390,7 @@ func NewMessage(userID, conversationID, content, role string) *Message {
 		Role:           role,
 		CreatedAt:      now,
 		UpdatedAt:      now,
-	}
+	}, nil
 }
 
 // NewConversation creates a new conversation
@@ -162,10 +407,11 @@ func NewConversation(userID, title string) *Conversation {
 
 // Standard error response structure
 type ErrorResponse struct {
-	Error   string            `json:"error"`
-	Message string            `json:"message"`
-	Code    string            `json:"code"`
-	Details map[string]string `json:"details,omitempty"`
+	Error         string            `json:"error"`
+	Message       string            `json:"message"`
+	Code          string            `json:"code"`
+	Details       map[string]string `json:"details,omitempty"`
+	CorrelationID string            `json:"correlation_id,omitempty"` // Matches the X-Correlation-ID response header
 }
 
 // NewErrorResponse creates a new error response
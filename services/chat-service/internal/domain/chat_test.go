@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewMessage_AcceptsKnownRoles(t *testing.T) {
+	for _, role := range []MessageRole{RoleUser, RoleAssistant, RoleSystem} {
+		t.Run(string(role), func(t *testing.T) {
+			message, err := NewMessage(testUserID, "conv-1", "hello", role)
+
+			require.NoError(t, err)
+			assert.Equal(t, role, message.Role)
+			assert.True(t, role.Valid())
+		})
+	}
+}
+
+func TestNewMessage_RejectsUnknownRole(t *testing.T) {
+	for _, role := range []MessageRole{"assisstant", "User", ""} {
+		t.Run(string(role), func(t *testing.T) {
+			message, err := NewMessage(testUserID, "conv-1", "hello", role)
+
+			assert.EqualError(t, err, `invalid role: "`+string(role)+`"`)
+			assert.Nil(t, message)
+			assert.False(t, role.Valid())
+		})
+	}
+}
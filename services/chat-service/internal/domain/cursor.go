@@ -0,0 +1,78 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor marks a position in a keyset-paginated listing. Rows are ordered by
+// created_at with the ID as tie-breaker, so a cursor stays valid while new rows
+// are inserted.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// cursorSeparator joins the cursor fields before encoding
+const cursorSeparator = "|"
+
+// NewCursor returns the cursor positioned at the given row
+func NewCursor(createdAt time.Time, id string) Cursor {
+	return Cursor{CreatedAt: createdAt, ID: id}
+}
+
+// StartCursor returns the cursor positioned before the first row
+func StartCursor() Cursor {
+	return Cursor{CreatedAt: time.Time{}, ID: uuid.Nil.String()}
+}
+
+// ParseCursor decodes a client supplied cursor, treating the empty string as
+// the start of the listing
+func ParseCursor(encoded string) (Cursor, error) {
+	if encoded == "" {
+		return StartCursor(), nil
+	}
+	return DecodeCursor(encoded)
+}
+
+// Encode returns the opaque string form of the cursor handed to clients
+func (c Cursor) Encode() string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + cursorSeparator + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// DecodeCursor parses a cursor previously produced by Encode
+func DecodeCursor(encoded string) (Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor encoding")
+	}
+
+	createdAt, id, ok := strings.Cut(string(raw), cursorSeparator)
+	if !ok {
+		return Cursor{}, fmt.Errorf("invalid cursor format")
+	}
+
+	t, err := time.Parse(time.RFC3339Nano, createdAt)
+	if err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor timestamp")
+	}
+	if err := ValidateUUID(id); err != nil {
+		return Cursor{}, fmt.Errorf("invalid cursor id: %w", err)
+	}
+
+	return Cursor{CreatedAt: t, ID: id}, nil
+}
+
+// Precedes reports whether the cursor sorts before the row at (createdAt, id),
+// matching the (created_at, id) > (...) predicate used by storage
+func (c Cursor) Precedes(createdAt time.Time, id string) bool {
+	if !createdAt.Equal(c.CreatedAt) {
+		return c.CreatedAt.Before(createdAt)
+	}
+	return c.ID < id
+}
@@ -0,0 +1,14 @@
+// Package domaintest builds domain values for tests
+package domaintest
+
+import "chat-service/internal/domain"
+
+// NewMessage creates a message whose role and content are known to be valid, panicking
+// otherwise
+func NewMessage(userID, conversationID, content string, role domain.MessageRole) *domain.Message {
+	message, err := domain.NewMessage(userID, conversationID, content, role)
+	if err != nil {
+		panic(err)
+	}
+	return message
+}
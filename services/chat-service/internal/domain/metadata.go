@@ -0,0 +1,102 @@
+package domain
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+)
+
+// MaxMetadataBytes is the maximum size of message metadata once encoded as JSON
+const MaxMetadataBytes = 4096
+
+// MaxMetadataDepth is the maximum nesting depth of message metadata; the top-level object
+// counts as one level
+const MaxMetadataDepth = 5
+
+// Metadata holds client-supplied annotations attached to a message. It is stored as a
+// JSONB column, with a nil map stored as NULL.
+type Metadata map[string]any
+
+// Value encodes the metadata as JSON for storage
+func (m Metadata) Value() (driver.Value, error) {
+	if m == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(map[string]any(m))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode metadata: %w", err)
+	}
+	return data, nil
+}
+
+// Scan decodes metadata read back from storage
+func (m *Metadata) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case nil:
+		*m = nil
+		return nil
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	default:
+		return fmt.Errorf("cannot scan %T into metadata", src)
+	}
+
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return fmt.Errorf("failed to decode metadata: %w", err)
+	}
+	*m = decoded
+	return nil
+}
+
+// ValidateMetadata checks that metadata is JSON encodable and within the size and
+// nesting limits. Nil metadata is valid.
+func ValidateMetadata(m Metadata) error {
+	if reason := metadataViolation(m); reason != "" {
+		return fmt.Errorf("metadata %s", reason)
+	}
+	return nil
+}
+
+// metadataViolation returns why m is not valid metadata, or an empty string
+func metadataViolation(m Metadata) string {
+	if m == nil {
+		return ""
+	}
+	if metadataDepth(map[string]any(m)) > MaxMetadataDepth {
+		return fmt.Sprintf("too deeply nested (max depth %d)", MaxMetadataDepth)
+	}
+	data, err := json.Marshal(map[string]any(m))
+	if err != nil {
+		return "must be JSON encodable"
+	}
+	if len(data) > MaxMetadataBytes {
+		return fmt.Sprintf("too large (max %d bytes)", MaxMetadataBytes)
+	}
+	return ""
+}
+
+// metadataDepth returns how many objects and arrays are nested in value, counting value
+// itself
+func metadataDepth(value any) int {
+	var children []any
+	switch v := value.(type) {
+	case map[string]any:
+		for _, child := range v {
+			children = append(children, child)
+		}
+	case []any:
+		children = v
+	default:
+		return 0
+	}
+
+	deepest := 0
+	for _, child := range children {
+		deepest = max(deepest, metadataDepth(child))
+	}
+	return deepest + 1
+}
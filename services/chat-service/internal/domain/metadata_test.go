@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetadata_ValueAndScanRoundTrip(t *testing.T) {
+	original := Metadata{
+		"source":   "mobile",
+		"priority": float64(2),
+		"labels":   []any{"draft", "pinned"},
+		"client":   map[string]any{"version": "1.4.0"},
+	}
+
+	value, err := original.Value()
+	require.NoError(t, err)
+
+	var scanned Metadata
+	require.NoError(t, scanned.Scan(value))
+	assert.Equal(t, original, scanned)
+
+	var fromString Metadata
+	require.NoError(t, fromString.Scan(string(value.([]byte))))
+	assert.Equal(t, original, fromString)
+}
+
+func TestMetadata_NilIsStoredAsNull(t *testing.T) {
+	value, err := Metadata(nil).Value()
+	require.NoError(t, err)
+	assert.Nil(t, value)
+
+	scanned := Metadata{"stale": true}
+	require.NoError(t, scanned.Scan(nil))
+	assert.Nil(t, scanned)
+}
+
+func TestMetadata_ScanRejectsInvalidInput(t *testing.T) {
+	var m Metadata
+	assert.Error(t, m.Scan([]byte("not json")))
+	assert.Error(t, m.Scan([]byte(`["not", "an", "object"]`)))
+	assert.Error(t, m.Scan(42))
+}
+
+// nestedMetadata returns metadata nested depth objects deep
+func nestedMetadata(depth int) Metadata {
+	var value any = "leaf"
+	for i := 1; i < depth; i++ {
+		value = map[string]any{"child": value}
+	}
+	return Metadata{"child": value}
+}
+
+func TestValidateMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata Metadata
+		wantErr  string
+	}{
+		{name: "nil", metadata: nil},
+		{name: "empty", metadata: Metadata{}},
+		{name: "flat", metadata: Metadata{"source": "web", "score": 0.5}},
+		{name: "at max depth", metadata: nestedMetadata(MaxMetadataDepth)},
+		{name: "arrays count towards depth", metadata: Metadata{"a": []any{[]any{[]any{[]any{[]any{}}}}}}, wantErr: "metadata too deeply nested (max depth 5)"},
+		{name: "too deep", metadata: nestedMetadata(MaxMetadataDepth + 1), wantErr: "metadata too deeply nested (max depth 5)"},
+		{name: "too large", metadata: Metadata{"note": strings.Repeat("x", MaxMetadataBytes)}, wantErr: "metadata too large (max 4096 bytes)"},
+		{name: "not JSON encodable", metadata: Metadata{"fn": func() {}}, wantErr: "metadata must be JSON encodable"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateMetadata(tt.metadata)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestChatRequest_Validate_RejectsOversizedMetadata(t *testing.T) {
+	req := &ChatRequest{
+		UserID:   testUserID,
+		Message:  "hello",
+		Metadata: nestedMetadata(MaxMetadataDepth + 1),
+	}
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(req.Validate(), &validationErr))
+	assert.Equal(t, map[string]string{"metadata": "too deeply nested (max depth 5)"}, validationErr.Details())
+}
@@ -0,0 +1,47 @@
+package domain
+
+// MaxPageLimit is the largest number of items a single page may hold
+const MaxPageLimit = 100
+
+// ClampPage makes a requested page valid: a limit of zero or less becomes defaultLimit, a
+// limit above maxLimit becomes maxLimit and a negative offset becomes zero
+func ClampPage(limit, offset, defaultLimit, maxLimit int) (int, int) {
+	switch {
+	case limit <= 0:
+		limit = defaultLimit
+	case limit > maxLimit:
+		limit = maxLimit
+	}
+	return limit, max(offset, 0)
+}
+
+// PageInfo describes where a page sits in a paginated listing. For offset pages HasMore
+// is offset+count < total and NextOffset is the offset of the following page; for cursor
+// pages HasMore mirrors whether a next cursor was returned and NextOffset is unused.
+type PageInfo struct {
+	Limit      int  `json:"limit"`
+	Offset     int  `json:"offset"`
+	HasMore    bool `json:"has_more"`
+	NextOffset int  `json:"next_offset,omitempty"` // Zero when there is no next page
+}
+
+// NewPageInfo builds the page info for count items read at offset out of total
+func NewPageInfo(limit, offset, count, total int) *PageInfo {
+	page := &PageInfo{
+		Limit:   limit,
+		Offset:  offset,
+		HasMore: offset+count < total,
+	}
+	if page.HasMore {
+		page.NextOffset = offset + count
+	}
+	return page
+}
+
+// NewCursorPageInfo builds the page info for a cursor page
+func NewCursorPageInfo(limit int, nextCursor string) *PageInfo {
+	return &PageInfo{
+		Limit:   limit,
+		HasMore: nextCursor != "",
+	}
+}
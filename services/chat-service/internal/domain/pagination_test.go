@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClampPage(t *testing.T) {
+	tests := []struct {
+		name                  string
+		limit, offset         int
+		wantLimit, wantOffset int
+	}{
+		{name: "unset limit uses default", limit: 0, offset: 0, wantLimit: 20, wantOffset: 0},
+		{name: "negative limit uses default", limit: -5, offset: 10, wantLimit: 20, wantOffset: 10},
+		{name: "within range kept", limit: 50, offset: 30, wantLimit: 50, wantOffset: 30},
+		{name: "over max clamped", limit: 1 << 30, offset: 0, wantLimit: MaxPageLimit, wantOffset: 0},
+		{name: "negative offset clamped", limit: 5, offset: -1, wantLimit: 5, wantOffset: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			limit, offset := ClampPage(tt.limit, tt.offset, 20, MaxPageLimit)
+			assert.Equal(t, tt.wantLimit, limit)
+			assert.Equal(t, tt.wantOffset, offset)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package domain
+
+import "strings"
+
+// FieldViolation describes why a single request field is invalid
+type FieldViolation struct {
+	Field  string `json:"field"`
+	Reason string `json:"reason"`
+}
+
+// ValidationError reports every invalid field of a request together, so clients can fix
+// them all in one round trip
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+// Error lists the violations as "field: reason" pairs
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, violation := range e.Violations {
+		parts[i] = violation.Field + ": " + violation.Reason
+	}
+	return strings.Join(parts, "; ")
+}
+
+// Details maps each invalid field to the reason it was rejected
+func (e *ValidationError) Details() map[string]string {
+	details := make(map[string]string, len(e.Violations))
+	for _, violation := range e.Violations {
+		details[violation.Field] = violation.Reason
+	}
+	return details
+}
+
+// fieldValidator collects the violations found while validating a request
+type fieldValidator struct {
+	violations []FieldViolation
+}
+
+// fail records that field is invalid for reason
+func (v *fieldValidator) fail(field, reason string) {
+	v.violations = append(v.violations, FieldViolation{Field: field, Reason: reason})
+}
+
+// check records err, if any, as the reason field is invalid
+func (v *fieldValidator) check(field string, err error) {
+	if err != nil {
+		v.fail(field, err.Error())
+	}
+}
+
+// err returns a ValidationError holding the recorded violations, or nil if there are none
+func (v *fieldValidator) err() error {
+	if len(v.violations) == 0 {
+		return nil
+	}
+	return &ValidationError{Violations: v.violations}
+}
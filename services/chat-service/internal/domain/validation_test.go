@@ -0,0 +1,150 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserID = "11111111-1111-1111-1111-111111111111"
+
+func TestChatRequest_Validate_ReportsAllInvalidFields(t *testing.T) {
+	req := &ChatRequest{
+		UserID:         "not-a-uuid",
+		Message:        "",
+		ConversationID: "also-not-a-uuid",
+		IdempotencyKey: strings.Repeat("k", MaxIdempotencyKeyLength+1),
+	}
+
+	err := req.Validate()
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, map[string]string{
+		"user_id":         "invalid UUID format: not-a-uuid",
+		"message":         "cannot be empty",
+		"conversation_id": "invalid UUID format: also-not-a-uuid",
+		"idempotency_key": "too long (max 255 characters)",
+	}, validationErr.Details())
+	assert.Equal(t, "user_id: invalid UUID format: not-a-uuid; message: cannot be empty; "+
+		"conversation_id: invalid UUID format: also-not-a-uuid; idempotency_key: too long (max 255 characters)", err.Error())
+}
+
+func TestChatWithAIRequest_Validate_DefaultsUnsetOptions(t *testing.T) {
+	req := &ChatWithAIRequest{UserID: testUserID, Message: "hello"}
+
+	require.NoError(t, req.Validate())
+	require.NotNil(t, req.Temperature)
+	require.NotNil(t, req.MaxTokens)
+	assert.Equal(t, DefaultAITemperature, *req.Temperature)
+	assert.Equal(t, DefaultAIMaxTokens, *req.MaxTokens)
+}
+
+func TestChatWithAIRequest_Validate_KeepsExplicitZero(t *testing.T) {
+	temperature, maxTokens := 0.0, 0
+	req := &ChatWithAIRequest{UserID: testUserID, Message: "hello", Temperature: &temperature, MaxTokens: &maxTokens}
+
+	require.NoError(t, req.Validate())
+	assert.Equal(t, 0.0, *req.Temperature)
+	assert.Equal(t, 0, *req.MaxTokens)
+}
+
+func TestChatWithAIRequest_Validate_ReportsInvalidFields(t *testing.T) {
+	req := &ChatWithAIRequest{UserID: testUserID, Message: "", ConversationID: "not-a-uuid"}
+
+	err := req.Validate()
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, map[string]string{
+		"message":         "cannot be empty",
+		"conversation_id": "invalid UUID format: not-a-uuid",
+	}, validationErr.Details())
+	assert.Nil(t, req.Temperature, "defaults are only applied to valid requests")
+}
+
+func TestValidate_PagingFields(t *testing.T) {
+	cursor := "not-a-cursor"
+
+	tests := []struct {
+		name string
+		req  interface{ Validate() error }
+		want map[string]string
+	}{
+		{
+			name: "history",
+			req:  &GetHistoryRequest{UserID: testUserID, ConversationID: "", Limit: 0, Offset: -1},
+			want: map[string]string{
+				"conversation_id": "UUID cannot be empty",
+				"limit":           "must be between 1 and 100",
+				"offset":          "must be non-negative",
+			},
+		},
+		{
+			name: "conversations with cursor and offset",
+			req:  &ListConversationsRequest{UserID: testUserID, Limit: 101, Offset: 5, Cursor: &cursor},
+			want: map[string]string{
+				"limit":  "must be between 1 and 100",
+				"cursor": "cannot be combined with offset",
+			},
+		},
+		{
+			name: "search",
+			req:  &SearchMessagesRequest{UserID: "", Query: " ", Limit: 10},
+			want: map[string]string{
+				"user_id": "UUID cannot be empty",
+				"query":   "cannot be empty",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var validationErr *ValidationError
+			require.True(t, errors.As(tt.req.Validate(), &validationErr))
+			assert.Equal(t, tt.want, validationErr.Details())
+		})
+	}
+}
+
+func TestValidate_ValidRequestReturnsNil(t *testing.T) {
+	assert.NoError(t, (&ChatRequest{UserID: testUserID, Message: "hello"}).Validate())
+	assert.NoError(t, (&ListUserMessagesRequest{UserID: testUserID, Limit: 10}).Validate())
+}
+
+func TestMessageValidator_Validate(t *testing.T) {
+	validator := NewMessageValidator(10)
+
+	tests := []struct {
+		name    string
+		message string
+		wantErr string
+	}{
+		// The limit counts characters, not bytes
+		{name: "exactly at limit", message: strings.Repeat("é", 10)},
+		{name: "one over limit", message: strings.Repeat("é", 11), wantErr: "too long (max 10 characters)"},
+		{name: "empty", message: "", wantErr: "cannot be empty"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validator.Validate(tt.message)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			var validationErr *ValidationError
+			require.True(t, errors.As(err, &validationErr))
+			assert.Equal(t, map[string]string{"message": tt.wantErr}, validationErr.Details())
+		})
+	}
+}
+
+func TestMessageValidator_DefaultsWhenUnset(t *testing.T) {
+	assert.Equal(t, DefaultMaxMessageLength, MessageValidator{}.MaxLength())
+	assert.Equal(t, DefaultMaxMessageLength, NewMessageValidator(-5).MaxLength())
+	assert.Equal(t, 8000, NewMessageValidator(8000).MaxLength())
+}
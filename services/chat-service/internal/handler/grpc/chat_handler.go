@@ -205,8 +205,8 @@ func (h *ChatHandler) ChatWithAI(ctx context.Context, req *proto.ChatWithAIReque
 		"user_id":         userID,
 		"conversation_id": req.ConversationId,
 		"model":           req.Model,
-		"temperature":     req.Temperature,
-		"max_tokens":      req.MaxTokens,
+		"temperature":     req.GetTemperature(),
+		"max_tokens":      req.GetMaxTokens(),
 	})
 
 	// Call chat service
@@ -216,8 +216,9 @@ func (h *ChatHandler) ChatWithAI(ctx context.Context, req *proto.ChatWithAIReque
 		req.Message,
 		req.ConversationId,
 		req.Model,
-		float64(req.Temperature),
-		int(req.MaxTokens),
+		float64(req.GetTemperature()),
+		int(req.GetMaxTokens()),
+		nil,
 	)
 	if err != nil {
 		h.logger.Error(ctx, err, "Failed to chat with AI", 500)
@@ -331,7 +332,7 @@ func (h *ChatHandler) convertMessageToProto(msg *domain.Message) *proto.Message
 		Id:        msg.ID,
 		UserId:    msg.UserID,
 		Content:   msg.Content,
-		Role:      msg.Role,
+		Role:      string(msg.Role),
 		CreatedAt: timestamppb.New(msg.CreatedAt),
 		UpdatedAt: timestamppb.New(msg.UpdatedAt),
 	}
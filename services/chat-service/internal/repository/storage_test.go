@@ -7,16 +7,18 @@ import (
 	"chat-service/internal/domain"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewMessage(t *testing.T) {
 	userID := "test-user-123"
 	conversationID := "test-conversation-456"
 	content := "Hello, world!"
-	role := "user"
+	role := domain.RoleUser
 
-	message := domain.NewMessage(userID, conversationID, content, role)
+	message, err := domain.NewMessage(userID, conversationID, content, role)
 
+	require.NoError(t, err)
 	assert.NotEmpty(t, message.ID)
 	assert.Equal(t, userID, message.UserID)
 	assert.Equal(t, conversationID, message.ConversationID)
@@ -6,7 +6,7 @@ import (
 
 	"chat-service/configs"
 	"chat-service/internal/domain"
-	"chat-service/internal/services/openai"
+	"chat-service/internal/services/llm"
 	"chat-service/storage"
 	zlog "packages/logger"
 )
@@ -22,14 +22,14 @@ type Service interface {
 
 // service implements the chat service
 type service struct {
-	openaiClient openai.Client
+	openaiClient llm.LLMProvider
 	logger       *zlog.Logger
 	config       *configs.Config
 	storage      storage.Repository
 }
 
 // NewService creates a new chat service
-func NewService(openaiClient openai.Client, logger *zlog.Logger, config *configs.Config, storage storage.Repository) Service {
+func NewService(openaiClient llm.LLMProvider, logger *zlog.Logger, config *configs.Config, storage storage.Repository) Service {
 	return &service{
 		openaiClient: openaiClient,
 		logger:       logger,
@@ -51,14 +51,14 @@ func (s *service) SendMessage(ctx context.Context, req *domain.ChatRequest) (*do
 	if conversationID == "" {
 		conversation := domain.NewConversation(req.UserID, "New Conversation")
 		// Store the conversation BEFORE creating the message
-		_, err := s.storage.CreateConversation(ctx, conversation)
+		_, err := s.storage.CreateConversation(ctx, conversation, 0)
 		if err != nil {
 			return nil, fmt.Errorf("failed to store conversation: %w", err)
 		}
 		conversationID = conversation.ID
 	} else {
 		// Validate that the provided conversation exists and belongs to the user
-		conversation, err := s.storage.GetConversationByID(ctx, conversationID)
+		conversation, err := s.storage.GetConversationByID(ctx, conversationID, req.UserID)
 		if err != nil {
 			return nil, fmt.Errorf("failed to get conversation: %w", err)
 		}
@@ -71,10 +71,13 @@ func (s *service) SendMessage(ctx context.Context, req *domain.ChatRequest) (*do
 	}
 
 	// Create a new message with the conversation ID
-	message := domain.NewMessage(req.UserID, conversationID, req.Message, "user")
+	message, err := domain.NewMessage(req.UserID, conversationID, req.Message, "user")
+	if err != nil {
+		return nil, err
+	}
 
 	// Store the message in the database
-	_, err := s.storage.CreateMessage(ctx, message)
+	_, err = s.storage.CreateMessage(ctx, message)
 	if err != nil {
 		return nil, fmt.Errorf("failed to store message: %w", err)
 	}
@@ -143,13 +146,13 @@ func (s *service) ListConversations(ctx context.Context, req *domain.ListConvers
 	})
 
 	// Retrieve conversations from the database
-	conversations, err := s.storage.GetConversationsByUserID(ctx, req.UserID, req.Limit, req.Offset)
+	conversations, err := s.storage.GetConversationsByUserID(ctx, req.UserID, req.Limit, req.Offset, req.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversations: %w", err)
 	}
 
 	// Get total count
-	total, err := s.storage.CountConversationsByUserID(ctx, req.UserID)
+	total, err := s.storage.CountConversationsByUserID(ctx, req.UserID, req.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversation count: %w", err)
 	}
@@ -183,7 +186,7 @@ func (s *service) CreateConversation(ctx context.Context, userID, title string)
 	conversation := domain.NewConversation(userID, title)
 
 	// Store the conversation in the database
-	_, err := s.storage.CreateConversation(ctx, conversation)
+	_, err := s.storage.CreateConversation(ctx, conversation, 0)
 	if err != nil {
 		return nil, fmt.Errorf("failed to store conversation: %w", err)
 	}
@@ -211,21 +214,24 @@ func (s *service) ChatWithAI(ctx context.Context, userID, message, conversationI
 		conversation := domain.NewConversation(userID, "AI Chat")
 		conversationID = conversation.ID
 		// Store the conversation
-		_, err := s.storage.CreateConversation(ctx, conversation)
+		_, err := s.storage.CreateConversation(ctx, conversation, 0)
 		if err != nil {
 			return nil, fmt.Errorf("failed to store conversation: %w", err)
 		}
 	}
 
 	// Store user message
-	userMsg := domain.NewMessage(userID, conversationID, message, "user")
-	_, err := s.storage.CreateMessage(ctx, userMsg)
+	userMsg, err := domain.NewMessage(userID, conversationID, message, "user")
+	if err != nil {
+		return nil, err
+	}
+	_, err = s.storage.CreateMessage(ctx, userMsg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to store user message: %w", err)
 	}
 
 	// Prepare messages for OpenAI
-	openaiMessages := []openai.Message{
+	openaiMessages := []llm.Message{
 		{
 			Role:    "user",
 			Content: message,
@@ -240,13 +246,16 @@ func (s *service) ChatWithAI(ctx context.Context, userID, message, conversationI
 	}
 
 	// Get AI message content
-	aiMessageContent := aiResponse.GetFirstChoiceContent()
+	aiMessageContent := aiResponse.Content
 	if aiMessageContent == "" {
 		return nil, fmt.Errorf("no AI response content received")
 	}
 
 	// Store AI message
-	aiMsg := domain.NewMessage(userID, conversationID, aiMessageContent, "assistant")
+	aiMsg, err := domain.NewMessage(userID, conversationID, aiMessageContent, "assistant")
+	if err != nil {
+		return nil, err
+	}
 	_, err = s.storage.CreateMessage(ctx, aiMsg)
 	if err != nil {
 		return nil, fmt.Errorf("failed to store AI message: %w", err)
@@ -260,7 +269,7 @@ func (s *service) ChatWithAI(ctx context.Context, userID, message, conversationI
 
 	s.logger.Info(ctx, "AI chat completed successfully", map[string]any{
 		"conversation_id": conversationID,
-		"tokens_used":     aiResponse.GetTotalTokens(),
+		"tokens_used":     aiResponse.Usage.TotalTokens,
 		"model_used":      aiResponse.Model,
 	})
 
@@ -0,0 +1,270 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-service/configs"
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
+)
+
+// apiVersion is the Anthropic API version the request and response types follow
+const apiVersion = "2023-06-01"
+
+// client implements llm.LLMProvider against the Anthropic messages API
+type client struct {
+	apiKey       string
+	baseURL      string
+	httpClient   *http.Client
+	logger       *zlog.Logger
+	defaultModel string
+}
+
+// MessagesRequest represents the request to the Anthropic messages API
+type MessagesRequest struct {
+	Model       string        `json:"model"`
+	System      string        `json:"system,omitempty"`
+	Messages    []llm.Message `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+	Stream      bool          `json:"stream,omitempty"`
+}
+
+// MessagesResponse represents the response from the Anthropic messages API
+type MessagesResponse struct {
+	ID      string `json:"id"`
+	Type    string `json:"type"`
+	Role    string `json:"role"`
+	Model   string `json:"model"`
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	StopReason string `json:"stop_reason"`
+	Usage      struct {
+		InputTokens  int `json:"input_tokens"`
+		OutputTokens int `json:"output_tokens"`
+	} `json:"usage"`
+}
+
+// StreamEvent represents a single server-sent event of a streamed message
+type StreamEvent struct {
+	Type  string `json:"type"`
+	Delta struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"delta"`
+	Error struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+// NewClient creates a new Anthropic client
+func NewClient(cfg *configs.Config, logger *zlog.Logger) llm.LLMProvider {
+	return &client{
+		apiKey:       cfg.AnthropicAPIKey,
+		baseURL:      "https://api.anthropic.com/v1",
+		defaultModel: cfg.AnthropicModel,
+		httpClient: &http.Client{
+			Timeout: time.Duration(cfg.AnthropicTimeout) * time.Second,
+		},
+		logger: logger,
+	}
+}
+
+// Name returns the provider identifier
+func (c *client) Name() string {
+	return configs.ANTHROPIC_PROVIDER
+}
+
+// ChatCompletion sends a messages request to Anthropic
+func (c *client) ChatCompletion(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int) (*llm.CompletionResponse, error) {
+	req, err := c.newRequest(ctx, messages, model, temperature, maxTokens, false)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		c.logger.Error(ctx, fmt.Errorf("Anthropic API error: %s", string(body)), "Anthropic API returned non-200 status", resp.StatusCode)
+		return nil, fmt.Errorf("Anthropic API error: %s (status: %d)", string(body), resp.StatusCode)
+	}
+
+	var response MessagesResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+
+	c.logger.Debug(ctx, "Received response from Anthropic", map[string]any{
+		"model":         response.Model,
+		"input_tokens":  response.Usage.InputTokens,
+		"output_tokens": response.Usage.OutputTokens,
+		"stop_reason":   response.StopReason,
+	})
+
+	return &llm.CompletionResponse{
+		Content:      response.GetText(),
+		FinishReason: finishReason(response.StopReason),
+		Model:        response.Model,
+		Usage: llm.Usage{
+			PromptTokens:     response.Usage.InputTokens,
+			CompletionTokens: response.Usage.OutputTokens,
+			TotalTokens:      response.Usage.InputTokens + response.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+// ChatCompletionStream sends a streaming messages request to Anthropic
+func (c *client) ChatCompletionStream(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	req, err := c.newRequest(ctx, messages, model, temperature, maxTokens, true)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Accept", "text/event-stream")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		c.logger.Error(ctx, fmt.Errorf("Anthropic API error: %s", string(body)), "Anthropic API returned non-200 status", resp.StatusCode)
+		return "", fmt.Errorf("Anthropic API error: %s (status: %d)", string(body), resp.StatusCode)
+	}
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+			return content.String(), fmt.Errorf("failed to unmarshal stream event: %w", err)
+		}
+
+		switch event.Type {
+		case "content_block_delta":
+			if event.Delta.Type != "text_delta" || event.Delta.Text == "" {
+				continue
+			}
+			content.WriteString(event.Delta.Text)
+			if err := onDelta(event.Delta.Text); err != nil {
+				return content.String(), fmt.Errorf("failed to handle stream delta: %w", err)
+			}
+		case "message_stop":
+			c.logger.Debug(ctx, "Anthropic stream completed", map[string]any{
+				"content_length": content.Len(),
+			})
+			return content.String(), nil
+		case "error":
+			return content.String(), fmt.Errorf("Anthropic stream error: %s: %s", event.Error.Type, event.Error.Message)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return content.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return content.String(), fmt.Errorf("stream ended before completion")
+}
+
+// newRequest builds a messages API request. Anthropic takes system prompts as a
+// separate field, so system messages are lifted out of the conversation.
+func (c *client) newRequest(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int, stream bool) (*http.Request, error) {
+	if model == "" {
+		model = c.defaultModel
+	}
+
+	var system []string
+	conversation := make([]llm.Message, 0, len(messages))
+	for _, msg := range messages {
+		if msg.Role == "system" {
+			system = append(system, msg.Content)
+			continue
+		}
+		conversation = append(conversation, msg)
+	}
+
+	requestBody := MessagesRequest{
+		Model:       model,
+		System:      strings.Join(system, "\n\n"),
+		Messages:    conversation,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Stream:      stream,
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/messages", bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", c.apiKey)
+	req.Header.Set("anthropic-version", apiVersion)
+
+	c.logger.Debug(ctx, "Sending request to Anthropic", map[string]any{
+		"model":       model,
+		"temperature": temperature,
+		"max_tokens":  maxTokens,
+		"messages":    len(conversation),
+		"stream":      stream,
+	})
+
+	return req, nil
+}
+
+// GetText returns the concatenated text content blocks of the response
+func (r *MessagesResponse) GetText() string {
+	var text strings.Builder
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String()
+}
+
+// finishReason maps an Anthropic stop_reason onto llm's FinishReason constants
+func finishReason(stopReason string) string {
+	switch stopReason {
+	case "end_turn", "stop_sequence":
+		return llm.FinishReasonStop
+	case "max_tokens":
+		return llm.FinishReasonLength
+	case "refusal":
+		return llm.FinishReasonContentFilter
+	default:
+		return stopReason
+	}
+}
@@ -0,0 +1,160 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chat-service/configs"
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(baseURL string) *client {
+	return &client{
+		apiKey:       "test-api-key",
+		baseURL:      baseURL,
+		defaultModel: "claude-3-5-haiku-latest",
+		httpClient:   http.DefaultClient,
+		logger:       zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}),
+	}
+}
+
+func TestNewClient(t *testing.T) {
+	cfg := &configs.Config{
+		AnthropicAPIKey:  "test-api-key",
+		AnthropicModel:   "claude-3-5-haiku-latest",
+		AnthropicTimeout: 30,
+	}
+
+	client := NewClient(cfg, nil)
+	require.NotNil(t, client)
+	assert.Equal(t, configs.ANTHROPIC_PROVIDER, client.Name())
+}
+
+func TestChatCompletion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/messages", r.URL.Path)
+		assert.Equal(t, "test-api-key", r.Header.Get("x-api-key"))
+		assert.Equal(t, apiVersion, r.Header.Get("anthropic-version"))
+
+		var req MessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "claude-3-5-haiku-latest", req.Model)
+		assert.Equal(t, "Be brief.", req.System)
+		assert.Equal(t, []llm.Message{
+			{Role: "user", Content: "Hi"},
+			{Role: "assistant", Content: "Hello!"},
+			{Role: "user", Content: "How are you?"},
+		}, req.Messages)
+		assert.False(t, req.Stream)
+
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-haiku-20241022","content":[{"type":"text","text":"Doing well"},{"type":"text","text":", thanks."}],"stop_reason":"end_turn","usage":{"input_tokens":12,"output_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	response, err := newTestClient(server.URL).ChatCompletion(context.Background(), []llm.Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Hi"},
+		{Role: "assistant", Content: "Hello!"},
+		{Role: "user", Content: "How are you?"},
+	}, "", 0.7, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Doing well, thanks.", response.Content)
+	assert.Equal(t, "claude-3-5-haiku-20241022", response.Model)
+	assert.Equal(t, llm.Usage{PromptTokens: 12, CompletionTokens: 5, TotalTokens: 17}, response.Usage)
+	assert.Equal(t, llm.FinishReasonStop, response.FinishReason)
+}
+
+func TestChatCompletion_MapsStopReason(t *testing.T) {
+	tests := []struct {
+		stopReason string
+		want       string
+	}{
+		{stopReason: "end_turn", want: llm.FinishReasonStop},
+		{stopReason: "stop_sequence", want: llm.FinishReasonStop},
+		{stopReason: "max_tokens", want: llm.FinishReasonLength},
+		{stopReason: "refusal", want: llm.FinishReasonContentFilter},
+		{stopReason: "tool_use", want: "tool_use"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.stopReason, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				fmt.Fprintf(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-3-5-haiku-20241022","content":[],"stop_reason":%q,"usage":{"input_tokens":12,"output_tokens":0}}`, tt.stopReason)
+			}))
+			defer server.Close()
+
+			response, err := newTestClient(server.URL).ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, response.FinishReason)
+		})
+	}
+}
+
+func TestChatCompletion_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"authentication_error","message":"invalid x-api-key"}}`)
+	}))
+	defer server.Close()
+
+	response, err := newTestClient(server.URL).ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status: 401")
+	assert.Nil(t, response)
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req MessagesRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\ndata: {\"type\":\"message_start\"}\n\n")
+		fmt.Fprint(w, "event: content_block_start\ndata: {\"type\":\"content_block_start\"}\n\n")
+		for _, delta := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":%q}}\n\n", delta)
+		}
+		fmt.Fprint(w, "event: message_stop\ndata: {\"type\":\"message_stop\"}\n\n")
+	}))
+	defer server.Close()
+
+	var deltas []string
+	content, err := newTestClient(server.URL).ChatCompletionStream(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello", ", ", "world"}, deltas)
+	assert.Equal(t, "Hello, world", content)
+}
+
+func TestChatCompletionStream_ErrorEventReturnsPartialContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"delta\":{\"type\":\"text_delta\",\"text\":\"partial\"}}\n\n")
+		fmt.Fprint(w, "event: error\ndata: {\"type\":\"error\",\"error\":{\"type\":\"overloaded_error\",\"message\":\"Overloaded\"}}\n\n")
+	}))
+	defer server.Close()
+
+	content, err := newTestClient(server.URL).ChatCompletionStream(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100, func(delta string) error {
+		return nil
+	})
+
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "overloaded_error")
+	assert.Equal(t, "partial", content)
+}
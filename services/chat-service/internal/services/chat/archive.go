@@ -0,0 +1,70 @@
+package chat
+
+import (
+	"context"
+	"time"
+
+	"chat-service/storage"
+	zlog "packages/logger"
+	"packages/periodic"
+)
+
+// archiveTimeout bounds a single archival pass
+const archiveTimeout = time.Minute
+
+// Archiver periodically archives conversations that have been idle for longer than its
+// idle period, so they drop out of the default conversation list
+type Archiver struct {
+	storage storage.Repository
+	logger  *zlog.Logger
+	idleFor time.Duration
+	now     func() time.Time // replaced in tests
+	job     *periodic.Job
+}
+
+// NewArchiver creates an archiver that, every interval, archives the conversations with no
+// activity for idleFor
+func NewArchiver(storage storage.Repository, logger *zlog.Logger, idleFor, interval time.Duration) *Archiver {
+	a := &Archiver{
+		storage: storage,
+		logger:  logger,
+		idleFor: idleFor,
+		now:     time.Now,
+	}
+	a.job = periodic.New(interval, archiveTimeout, a.runLogged)
+	return a
+}
+
+// Start runs an archival pass right away and then once per interval in the background,
+// until Stop is called or ctx is done
+func (a *Archiver) Start(ctx context.Context) {
+	a.job.Start(ctx)
+}
+
+// Stop stops the background job and waits for a running pass to finish
+func (a *Archiver) Stop() {
+	a.job.Stop()
+}
+
+// RunOnce archives the conversations with no activity since now minus the idle period and
+// returns how many were archived
+func (a *Archiver) RunOnce(ctx context.Context) (int64, error) {
+	cutoff := a.now().UTC().Add(-a.idleFor)
+	return a.storage.ArchiveIdleConversations(ctx, cutoff)
+}
+
+// runLogged runs one archival pass, logging its outcome
+func (a *Archiver) runLogged(ctx context.Context) {
+	archived, err := a.RunOnce(ctx)
+	if err != nil {
+		a.logger.Warn(ctx, "Failed to archive idle conversations", map[string]any{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	a.logger.Info(ctx, "Archived idle conversations", map[string]any{
+		"archived":  archived,
+		"idle_days": int(a.idleFor / (24 * time.Hour)),
+	})
+}
@@ -0,0 +1,87 @@
+package chat
+
+import (
+	"sync"
+
+	"chat-service/internal/domain"
+)
+
+// subscriberBufferSize is the number of published messages a subscriber may fall behind by
+// before it is dropped
+const subscriberBufferSize = 64
+
+// ErrSubscriberTooSlow is returned to a subscriber that did not keep up with the messages
+// published to its conversation. The client can subscribe again to resume from history.
+var ErrSubscriberTooSlow = &AppError{Type: ErrorTypeUnavailable, Code: "SUBSCRIBER_TOO_SLOW", Message: "subscriber fell behind and was dropped; subscribe again"}
+
+// subscription receives the messages published to one conversation. messages is closed
+// when the subscription is dropped for falling behind.
+type subscription struct {
+	conversationID string
+	messages       chan *domain.Message
+}
+
+// broker is an in-process pub/sub of stored messages, keyed by conversation ID. Publishing
+// never blocks: a subscriber whose buffer is full is dropped instead of holding up the
+// request that stored the message.
+type broker struct {
+	mu          sync.Mutex
+	subscribers map[string]map[*subscription]struct{}
+	bufferSize  int
+}
+
+// newBroker creates a broker whose subscribers buffer up to bufferSize messages
+func newBroker(bufferSize int) *broker {
+	return &broker{
+		subscribers: make(map[string]map[*subscription]struct{}),
+		bufferSize:  bufferSize,
+	}
+}
+
+// subscribe registers a subscription to the messages of conversationID. Callers must
+// unsubscribe once they are done with it.
+func (b *broker) subscribe(conversationID string) *subscription {
+	sub := &subscription{
+		conversationID: conversationID,
+		messages:       make(chan *domain.Message, b.bufferSize),
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.subscribers[conversationID] == nil {
+		b.subscribers[conversationID] = make(map[*subscription]struct{})
+	}
+	b.subscribers[conversationID][sub] = struct{}{}
+	return sub
+}
+
+// unsubscribe removes sub from the broker. It is safe to call for a dropped subscription.
+func (b *broker) unsubscribe(sub *subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// publish delivers message to every subscriber of its conversation, dropping the ones
+// whose buffer is full
+func (b *broker) publish(message *domain.Message) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers[message.ConversationID] {
+		select {
+		case sub.messages <- message:
+		default:
+			b.remove(sub)
+			close(sub.messages)
+		}
+	}
+}
+
+// remove deletes sub from its conversation's subscribers; b.mu must be held
+func (b *broker) remove(sub *subscription) {
+	subs := b.subscribers[sub.conversationID]
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.conversationID)
+	}
+}
@@ -0,0 +1,191 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
+)
+
+// ErrorType classifies the errors the service returns, so transports can report them with
+// a matching status instead of a generic internal error
+type ErrorType string
+
+const (
+	// ErrorTypeNotFound represents a conversation or message that does not exist
+	ErrorTypeNotFound ErrorType = "not_found"
+	// ErrorTypeValidation represents a request the service cannot act on as given
+	ErrorTypeValidation ErrorType = "validation"
+	// ErrorTypeForbidden represents a request for data the user may not access or change
+	ErrorTypeForbidden ErrorType = "forbidden"
+	// ErrorTypeInternal represents a failure that is not the caller's fault
+	ErrorTypeInternal ErrorType = "internal"
+	// ErrorTypeContentFiltered represents an AI reply withheld by the provider's content
+	// filter, or a user message rejected by content moderation
+	ErrorTypeContentFiltered ErrorType = "content_filtered"
+	// ErrorTypeTruncated represents an AI reply cut off by the max tokens limit before any content
+	ErrorTypeTruncated ErrorType = "truncated"
+	// ErrorTypeUnavailable represents an upstream provider that returned nothing usable; the
+	// request may succeed if sent again
+	ErrorTypeUnavailable ErrorType = "unavailable"
+	// ErrorTypeQuotaExceeded represents a request that would take the user over a limit
+	ErrorTypeQuotaExceeded ErrorType = "quota_exceeded"
+	// ErrorTypeRateLimited represents an upstream provider that rejected the request for
+	// exceeding its rate limits; the request may succeed once the limit resets
+	ErrorTypeRateLimited ErrorType = "rate_limited"
+	// ErrorTypeRejected represents an upstream provider that refused the request for a reason
+	// sending it again won't fix, such as an API key without access to the model
+	ErrorTypeRejected ErrorType = "rejected"
+)
+
+// AppError is a typed error returned by the chat service
+type AppError struct {
+	Type    ErrorType
+	Code    string
+	Message string
+	Err     error
+}
+
+// AppError reports its type and code as structured fields when logged
+var _ zlog.AppError = (*AppError)(nil)
+
+// Error implements the error interface
+func (e *AppError) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *AppError) Unwrap() error {
+	return e.Err
+}
+
+// ErrorType returns the error type, logged as the error_type field
+func (e *AppError) ErrorType() string {
+	return string(e.Type)
+}
+
+// ErrorCode returns the error code, logged as the error_code field
+func (e *AppError) ErrorCode() string {
+	return e.Code
+}
+
+// NewNotFoundError creates a new not found error
+func NewNotFoundError(code, message string, err error) *AppError {
+	return &AppError{Type: ErrorTypeNotFound, Code: code, Message: message, Err: err}
+}
+
+// NewValidationError creates a new validation error
+func NewValidationError(code, message string, err error) *AppError {
+	return &AppError{Type: ErrorTypeValidation, Code: code, Message: message, Err: err}
+}
+
+// NewForbiddenError creates a new forbidden error
+func NewForbiddenError(code, message string, err error) *AppError {
+	return &AppError{Type: ErrorTypeForbidden, Code: code, Message: message, Err: err}
+}
+
+// NewInternalError creates a new internal error
+func NewInternalError(code, message string, err error) *AppError {
+	return &AppError{Type: ErrorTypeInternal, Code: code, Message: message, Err: err}
+}
+
+// Errors returned when a conversation or message cannot be accessed by the requesting user.
+// They are wrapped with the ID involved, so compare them with errors.Is.
+var (
+	ErrConversationNotFound = NewNotFoundError("CONVERSATION_NOT_FOUND", "conversation not found", nil)
+	ErrMessageNotFound      = NewNotFoundError("MESSAGE_NOT_FOUND", "message not found", nil)
+	ErrAssistantMessageEdit = NewForbiddenError("ASSISTANT_MESSAGE_EDIT", "assistant messages cannot be edited", nil)
+	ErrInvalidImport        = NewValidationError("INVALID_IMPORT", "invalid conversation import", nil)
+	ErrUserMismatch         = NewForbiddenError("USER_MISMATCH", "requested user does not match authenticated user", nil)
+)
+
+// ErrConversationQuotaExceeded is returned when creating a conversation would take the user
+// over MAX_CONVERSATIONS_PER_USER
+var ErrConversationQuotaExceeded = &AppError{Type: ErrorTypeQuotaExceeded, Code: "CONVERSATION_QUOTA_EXCEEDED", Message: "conversation limit reached; delete a conversation to start a new one"}
+
+// ErrDailyTokenBudgetExceeded is returned when an AI chat is requested by a user whose
+// DAILY_TOKEN_BUDGET for today has too little left for it
+var ErrDailyTokenBudgetExceeded = &AppError{Type: ErrorTypeQuotaExceeded, Code: "DAILY_TOKEN_BUDGET_EXCEEDED", Message: "daily token budget used up; try again after midnight UTC"}
+
+// Errors returned when the AI provider answers without any content, by finish reason
+var (
+	ErrAIResponseFiltered  = &AppError{Type: ErrorTypeContentFiltered, Code: "AI_RESPONSE_FILTERED", Message: "AI response was blocked by the content filter"}
+	ErrAIResponseTruncated = &AppError{Type: ErrorTypeTruncated, Code: "AI_RESPONSE_TRUNCATED", Message: "AI response hit the max tokens limit before any content; increase max_tokens"}
+	ErrAIResponseEmpty     = &AppError{Type: ErrorTypeUnavailable, Code: "AI_RESPONSE_EMPTY", Message: "no AI response content received"}
+)
+
+// AIRequestError is returned when the AI provider fails after the user's message was stored.
+// It carries the conversation and the stored message so the client can retry in the same
+// conversation instead of starting a new one. It unwraps to an AppError classified by the
+// provider's answer: ErrorTypeRateLimited when it rate-limited the request, ErrorTypeValidation
+// when it rejected the request as invalid, ErrorTypeRejected for any other client error and
+// ErrorTypeUnavailable otherwise. Only the rate limited and unavailable ones are worth retrying.
+type AIRequestError struct {
+	ConversationID string
+	UserMessage    *domain.Message
+	// RetryAfter is the delay the provider asked for before retrying, zero if it gave none
+	RetryAfter time.Duration
+	Err        *AppError
+}
+
+// Error implements the error interface
+func (e *AIRequestError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying AppError
+func (e *AIRequestError) Unwrap() error {
+	return e.Err
+}
+
+// newAIRequestError returns the error for a provider failure that left userMsg stored
+// without a reply
+func newAIRequestError(userMsg *domain.Message, err error) *AIRequestError {
+	appErr := &AppError{Type: ErrorTypeUnavailable, Code: "AI_REQUEST_FAILED", Message: "failed to get AI response", Err: err}
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.RateLimited():
+			appErr = &AppError{Type: ErrorTypeRateLimited, Code: "AI_RATE_LIMITED", Message: "AI provider rate limit reached; retry later", Err: err}
+		case apiErr.InvalidRequest():
+			appErr = &AppError{Type: ErrorTypeValidation, Code: "AI_REQUEST_INVALID", Message: "AI provider rejected the request as invalid", Err: err}
+		case !apiErr.Retryable():
+			appErr = &AppError{Type: ErrorTypeRejected, Code: "AI_REQUEST_REJECTED", Message: "AI provider refused the request", Err: err}
+		}
+	}
+	return &AIRequestError{
+		ConversationID: userMsg.ConversationID,
+		UserMessage:    userMsg,
+		RetryAfter:     llm.RetryAfter(err),
+		Err:            appErr,
+	}
+}
+
+// messageFlaggedError returns the error for a user message rejected by content moderation
+// for categories
+func messageFlaggedError(categories []string) *AppError {
+	message := "message was flagged by content moderation"
+	if len(categories) > 0 {
+		message += ": " + strings.Join(categories, ", ")
+	}
+	return &AppError{Type: ErrorTypeContentFiltered, Code: "MESSAGE_FLAGGED", Message: message}
+}
+
+// emptyResponseError returns the error for an AI reply without content that stopped for
+// finishReason
+func emptyResponseError(finishReason string) error {
+	switch finishReason {
+	case llm.FinishReasonContentFilter:
+		return ErrAIResponseFiltered
+	case llm.FinishReasonLength:
+		return ErrAIResponseTruncated
+	default:
+		return ErrAIResponseEmpty
+	}
+}
@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/storage"
+)
+
+// ExportFormat selects how a conversation export is rendered
+type ExportFormat string
+
+const (
+	// ExportFormatJSONL writes one JSON message object per line
+	ExportFormatJSONL ExportFormat = "jsonl"
+	// ExportFormatMarkdown writes a readable transcript
+	ExportFormatMarkdown ExportFormat = "md"
+)
+
+// Export is an owned conversation ready to be written out. Its messages are read from
+// storage a batch at a time while writing, so a long conversation is never held in memory.
+type Export struct {
+	Conversation *domain.Conversation
+	storage      storage.Repository
+}
+
+// ExportConversation returns the export of a conversation after checking that it belongs
+// to userID. Nothing is read beyond the conversation itself until the export is written.
+func (s *service) ExportConversation(ctx context.Context, userID, conversationID string) (*Export, error) {
+	s.logger.Info(ctx, "Exporting conversation", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	conversation, err := s.ownedConversation(ctx, userID, conversationID)
+	if err != nil {
+		return nil, err
+	}
+	return NewExport(conversation, s.storage), nil
+}
+
+// NewExport creates the export of conversation, reading its messages from storage. It does
+// not check ownership; use ExportConversation for requests made on behalf of a user.
+func NewExport(conversation *domain.Conversation, storage storage.Repository) *Export {
+	return &Export{Conversation: conversation, storage: storage}
+}
+
+// Render writes the conversation's messages to w in format, oldest first, and returns the
+// number of messages written
+func (e *Export) Render(ctx context.Context, w io.Writer, format ExportFormat) (int, error) {
+	switch format {
+	case ExportFormatJSONL:
+		encoder := json.NewEncoder(w)
+		return e.each(ctx, func(message *domain.Message) error {
+			return encoder.Encode(message)
+		})
+	case ExportFormatMarkdown:
+		if _, err := fmt.Fprintf(w, "# %s\n\n", e.title()); err != nil {
+			return 0, err
+		}
+		return e.each(ctx, func(message *domain.Message) error {
+			_, err := fmt.Fprintf(w, "## %s · %s\n\n%s\n\n", roleHeading(string(message.Role)), message.CreatedAt.UTC().Format(time.RFC3339), message.Content)
+			return err
+		})
+	default:
+		return 0, NewValidationError("INVALID_EXPORT_FORMAT", fmt.Sprintf("unsupported export format %q", format), nil)
+	}
+}
+
+// each pages through the conversation's messages by cursor and passes each one to fn
+func (e *Export) each(ctx context.Context, fn func(*domain.Message) error) (int, error) {
+	messages, err := e.storage.GetMessagesByConversationID(ctx, e.Conversation.ID, batchSize, 0)
+	written := 0
+	for {
+		if err != nil {
+			return written, fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		for i := range messages {
+			if err := fn(&messages[i]); err != nil {
+				return written, fmt.Errorf("failed to write message: %w", err)
+			}
+			written++
+		}
+
+		if len(messages) < batchSize {
+			return written, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return written, err
+		}
+
+		last := messages[len(messages)-1]
+		messages, err = e.storage.GetMessagesAfterCursor(ctx, e.Conversation.ID, domain.NewCursor(last.CreatedAt, last.ID), batchSize)
+	}
+}
+
+// title returns the heading of a Markdown export
+func (e *Export) title() string {
+	if e.Conversation.Title != "" {
+		return e.Conversation.Title
+	}
+	return "Conversation " + e.Conversation.ID
+}
+
+// roleHeading capitalizes a message role for a Markdown heading
+func roleHeading(role string) string {
+	if role == "" {
+		return role
+	}
+	return strings.ToUpper(role[:1]) + role[1:]
+}
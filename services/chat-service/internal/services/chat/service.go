@@ -2,40 +2,98 @@ package chat
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"time"
 
 	"chat-service/configs"
 	"chat-service/internal/domain"
-	"chat-service/internal/services/openai"
+	"chat-service/internal/services/events"
+	"chat-service/internal/services/llm"
 	"chat-service/storage"
 	zlog "packages/logger"
 )
 
+// batchSize is the number of messages fetched per page when streaming a conversation
+const batchSize = 50
+
 // Service represents the chat service
 type Service interface {
 	SendMessage(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error)
 	GetHistory(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error)
+	StreamMessages(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error
+	SubscribeMessages(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error
 	ListConversations(ctx context.Context, req *domain.ListConversationsRequest) (*domain.ListConversationsResponse, error)
+	ListUserMessages(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error)
+	Search(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error)
 	CreateConversation(ctx context.Context, userID, title string) (*domain.Conversation, error)
-	ChatWithAI(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int) (*domain.ChatResponse, error)
+	GetConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	ImportConversation(ctx context.Context, userID, title string, messages []domain.Message) (*domain.Conversation, error)
+	ExportConversation(ctx context.Context, userID, conversationID string) (*Export, error)
+	DeleteConversation(ctx context.Context, userID, conversationID string) error
+	ClearConversationMessages(ctx context.Context, userID, conversationID string) (int, error)
+	RestoreConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	ArchiveConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	UnarchiveConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	PinConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	UnpinConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	EditMessage(ctx context.Context, userID, messageID, content string) (*domain.Message, error)
+	DeleteMessage(ctx context.Context, userID, messageID string) error
+	ChatWithAI(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error)
+	ChatWithAIStream(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata, onDelta func(conversationID, delta string) error) (*domain.ChatResponse, error)
 }
 
 // service implements the chat service
 type service struct {
-	openaiClient openai.Client
-	logger       *zlog.Logger
-	config       *configs.Config
-	storage      storage.Repository
+	provider  llm.LLMProvider
+	logger    *zlog.Logger
+	config    *configs.Config
+	storage   storage.Repository
+	messages  domain.MessageValidator
+	titles    *TitleGenerator // nil unless AutoTitleEnabled
+	moderator llm.Moderator   // nil unless set with WithModerator
+	events    events.EventSink
+	broker    *broker
+}
+
+// Option configures optional service components
+type Option func(*service)
+
+// WithModerator checks every AI chat message with m before it is stored or sent to the
+// provider, rejecting flagged messages
+func WithModerator(m llm.Moderator) Option {
+	return func(s *service) {
+		s.moderator = m
+	}
+}
+
+// WithEventSink emits an event to sink whenever an AI reply is stored
+func WithEventSink(sink events.EventSink) Option {
+	return func(s *service) {
+		s.events = sink
+	}
 }
 
 // NewService creates a new chat service
-func NewService(openaiClient openai.Client, logger *zlog.Logger, config *configs.Config, storage storage.Repository) Service {
-	return &service{
-		openaiClient: openaiClient,
-		logger:       logger,
-		config:       config,
-		storage:      storage,
+func NewService(provider llm.LLMProvider, logger *zlog.Logger, config *configs.Config, storage storage.Repository, opts ...Option) Service {
+	s := &service{
+		provider: provider,
+		logger:   logger,
+		config:   config,
+		storage:  storage,
+		messages: domain.NewMessageValidator(config.MaxMessageLength),
+		events:   events.NoopSink{},
+		broker:   newBroker(subscriberBufferSize),
 	}
+	if config.AutoTitleEnabled {
+		s.titles = NewTitleGenerator(provider, storage, logger, config.DefaultModel())
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // SendMessage sends a message and stores it
@@ -46,36 +104,43 @@ func (s *service) SendMessage(ctx context.Context, req *domain.ChatRequest) (*do
 		"message_length":  len(req.Message),
 	})
 
+	if err := s.messages.Validate(req.Message); err != nil {
+		return nil, err
+	}
+
+	if req.IdempotencyKey != "" {
+		return s.sendIdempotentMessage(ctx, req)
+	}
+
 	// Create or get conversation ID FIRST
 	conversationID := req.ConversationID
 	if conversationID == "" {
 		conversation := domain.NewConversation(req.UserID, "New Conversation")
 		// Store the conversation BEFORE creating the message
-		_, err := s.storage.CreateConversation(ctx, conversation)
+		_, err := s.storage.CreateConversation(ctx, conversation, s.config.MaxConversationsPerUser)
 		if err != nil {
+			if quotaErr := s.conversationQuotaError(ctx, req.UserID, err); quotaErr != nil {
+				return nil, quotaErr
+			}
 			return nil, fmt.Errorf("failed to store conversation: %w", err)
 		}
 		conversationID = conversation.ID
 	} else {
 		// Validate that the provided conversation exists and belongs to the user
-		conversation, err := s.storage.GetConversationByID(ctx, conversationID)
-		if err != nil {
-			return nil, fmt.Errorf("failed to get conversation: %w", err)
-		}
-		if conversation == nil {
-			return nil, fmt.Errorf("conversation not found: %s", conversationID)
-		}
-		if conversation.UserID != req.UserID {
-			return nil, fmt.Errorf("conversation does not belong to user: %s", conversationID)
+		if _, err := s.ownedConversation(ctx, req.UserID, conversationID); err != nil {
+			return nil, err
 		}
 	}
 
 	// Create a new message with the conversation ID
-	message := domain.NewMessage(req.UserID, conversationID, req.Message, "user")
+	message, err := domain.NewMessage(req.UserID, conversationID, req.Message, domain.RoleUser)
+	if err != nil {
+		return nil, err
+	}
+	message.Metadata = req.Metadata
 
 	// Store the message in the database
-	_, err := s.storage.CreateMessage(ctx, message)
-	if err != nil {
+	if err := s.createMessage(ctx, message); err != nil {
 		return nil, fmt.Errorf("failed to store message: %w", err)
 	}
 
@@ -93,6 +158,49 @@ func (s *service) SendMessage(ctx context.Context, req *domain.ChatRequest) (*do
 	return response, nil
 }
 
+// sendIdempotentMessage stores the message under the request's idempotency key. A key seen
+// within the TTL returns the message created by the first request instead of a new one.
+func (s *service) sendIdempotentMessage(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+	var conversation *domain.Conversation
+	conversationID := req.ConversationID
+	if conversationID == "" {
+		conversation = domain.NewConversation(req.UserID, "New Conversation")
+		conversationID = conversation.ID
+	} else if _, err := s.ownedConversation(ctx, req.UserID, conversationID); err != nil {
+		return nil, err
+	}
+
+	message, err := domain.NewMessage(req.UserID, conversationID, req.Message, domain.RoleUser)
+	if err != nil {
+		return nil, err
+	}
+	message.Metadata = req.Metadata
+	expiresBefore := time.Now().Add(-time.Duration(s.config.IdempotencyKeyTTLHours) * time.Hour)
+
+	stored, created, err := s.storage.CreateMessageWithIdempotencyKey(ctx, conversation, message, req.IdempotencyKey, expiresBefore, s.config.MaxConversationsPerUser)
+	if err != nil {
+		if quotaErr := s.conversationQuotaError(ctx, req.UserID, err); quotaErr != nil {
+			return nil, quotaErr
+		}
+		return nil, fmt.Errorf("failed to store message: %w", err)
+	}
+	if created {
+		s.broker.publish(stored)
+	}
+
+	s.logger.Info(ctx, "Message sent successfully", map[string]any{
+		"message_id":      stored.ID,
+		"conversation_id": stored.ConversationID,
+		"replayed":        !created,
+	})
+
+	return &domain.ChatResponse{
+		Message:        stored,
+		ConversationID: stored.ConversationID,
+		IsAIResponse:   false,
+	}, nil
+}
+
 // GetHistory retrieves chat history for a conversation
 func (s *service) GetHistory(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
 	s.logger.Info(ctx, "Getting chat history", map[string]any{
@@ -100,12 +208,39 @@ func (s *service) GetHistory(ctx context.Context, req *domain.GetHistoryRequest)
 		"conversation_id": req.ConversationID,
 		"limit":           req.Limit,
 		"offset":          req.Offset,
+		"cursor":          req.Cursor != nil,
 	})
 
-	// Retrieve messages from the database
-	messages, err := s.storage.GetMessagesByConversationID(ctx, req.ConversationID, req.Limit, req.Offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get messages: %w", err)
+	if limit := s.config.MaxHistoryOffset; limit > 0 && req.Offset > limit {
+		return nil, NewValidationError("HISTORY_OFFSET_TOO_LARGE", "history offset too large", &domain.ValidationError{
+			Violations: []domain.FieldViolation{{
+				Field:  "offset",
+				Reason: fmt.Sprintf("must not exceed %d; use cursor to page further", limit),
+			}},
+		})
+	}
+
+	if _, err := s.ownedConversation(ctx, req.UserID, req.ConversationID); err != nil {
+		return nil, err
+	}
+
+	// Retrieve messages from the database, seeking past the cursor when one is given
+	var messages []domain.Message
+	if req.Cursor != nil {
+		cursor, err := domain.ParseCursor(*req.Cursor)
+		if err != nil {
+			return nil, NewValidationError("INVALID_CURSOR", "invalid cursor", err)
+		}
+		messages, err = s.storage.GetMessagesAfterCursor(ctx, req.ConversationID, cursor, req.Limit)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
+	} else {
+		var err error
+		messages, err = s.storage.GetMessagesByConversationID(ctx, req.ConversationID, req.Limit, req.Offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get messages: %w", err)
+		}
 	}
 
 	// Get total count
@@ -114,6 +249,18 @@ func (s *service) GetHistory(ctx context.Context, req *domain.GetHistoryRequest)
 		return nil, fmt.Errorf("failed to get message count: %w", err)
 	}
 
+	// Cut the page short when its content would exceed MaxHistoryResponseBytes
+	fetched := len(messages)
+	messages = truncateToResponseBytes(messages, s.config.MaxHistoryResponseBytes)
+	if len(messages) < fetched {
+		s.logger.Warn(ctx, "History page cut short by response size limit", map[string]any{
+			"conversation_id": req.ConversationID,
+			"fetched":         fetched,
+			"returned":        len(messages),
+			"limit_bytes":     s.config.MaxHistoryResponseBytes,
+		})
+	}
+
 	// Convert []domain.Message to []*domain.Message
 	var messagePtrs []*domain.Message
 	for i := range messages {
@@ -126,6 +273,18 @@ func (s *service) GetHistory(ctx context.Context, req *domain.GetHistoryRequest)
 		ConversationID: req.ConversationID,
 	}
 
+	// A full or cut short cursor page may be followed by more messages
+	if req.Cursor != nil && (len(messages) == req.Limit || len(messages) < fetched) {
+		last := messages[len(messages)-1]
+		response.NextCursor = domain.NewCursor(last.CreatedAt, last.ID).Encode()
+	}
+
+	if req.Cursor != nil {
+		response.PageInfo = domain.NewCursorPageInfo(req.Limit, response.NextCursor)
+	} else {
+		response.PageInfo = domain.NewPageInfo(req.Limit, req.Offset, len(messages), total)
+	}
+
 	s.logger.Info(ctx, "Chat history retrieved", map[string]any{
 		"conversation_id": req.ConversationID,
 		"total_messages":  total,
@@ -134,22 +293,197 @@ func (s *service) GetHistory(ctx context.Context, req *domain.GetHistoryRequest)
 	return response, nil
 }
 
+// truncateToResponseBytes returns the leading messages whose combined content fits in
+// maxBytes. The first message is always kept, so a page is never empty while messages
+// remain. A maxBytes of 0 keeps every message.
+func truncateToResponseBytes(messages []domain.Message, maxBytes int) []domain.Message {
+	if maxBytes <= 0 {
+		return messages
+	}
+	size := 0
+	for i := range messages {
+		size += len(messages[i].Content)
+		if size > maxBytes && i > 0 {
+			return messages[:i]
+		}
+	}
+	return messages
+}
+
+// StreamMessages pages through the stored messages of a conversation in chronological
+// order and passes each one to send. It stops as soon as the context is cancelled.
+func (s *service) StreamMessages(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+	s.logger.Info(ctx, "Streaming conversation messages", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	if _, err := s.ownedConversation(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	streamed, err := s.replayMessages(ctx, conversationID, send)
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info(ctx, "Conversation messages streamed", map[string]any{
+		"conversation_id": conversationID,
+		"total_messages":  streamed,
+	})
+
+	return nil
+}
+
+// SubscribeMessages streams the stored messages of a conversation like StreamMessages, then
+// keeps passing each message stored in it afterwards to send until the context is cancelled.
+// A subscriber that falls too far behind is dropped with ErrSubscriberTooSlow.
+func (s *service) SubscribeMessages(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+	s.logger.Info(ctx, "Subscribing to conversation messages", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	if _, err := s.ownedConversation(ctx, userID, conversationID); err != nil {
+		return err
+	}
+
+	// Subscribe before replaying, so a message stored during the replay is not missed;
+	// one that shows up in both is only sent once
+	sub := s.broker.subscribe(conversationID)
+	defer s.broker.unsubscribe(sub)
+
+	replayed := make(map[string]bool)
+	if _, err := s.replayMessages(ctx, conversationID, func(msg *domain.Message) error {
+		replayed[msg.ID] = true
+		return send(msg)
+	}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case msg, ok := <-sub.messages:
+			if !ok {
+				s.logger.Warn(ctx, "Dropped slow conversation subscriber", map[string]any{
+					"user_id":         userID,
+					"conversation_id": conversationID,
+				})
+				return ErrSubscriberTooSlow
+			}
+			if replayed[msg.ID] {
+				continue
+			}
+			if err := send(msg); err != nil {
+				return fmt.Errorf("failed to send message: %w", err)
+			}
+		}
+	}
+}
+
+// replayMessages pages through the stored messages of a conversation in chronological
+// order, passes each one to send and returns how many were sent
+func (s *service) replayMessages(ctx context.Context, conversationID string, send func(*domain.Message) error) (int, error) {
+	streamed := 0
+	for offset := 0; ; offset += batchSize {
+		if err := ctx.Err(); err != nil {
+			return streamed, err
+		}
+
+		messages, err := s.storage.GetMessagesByConversationID(ctx, conversationID, batchSize, offset)
+		if err != nil {
+			return streamed, fmt.Errorf("failed to get messages: %w", err)
+		}
+
+		for i := range messages {
+			if err := ctx.Err(); err != nil {
+				return streamed, err
+			}
+			if err := send(&messages[i]); err != nil {
+				return streamed, fmt.Errorf("failed to send message: %w", err)
+			}
+			streamed++
+		}
+
+		if len(messages) < batchSize {
+			return streamed, nil
+		}
+	}
+}
+
+// createMessage stores message and publishes it to the conversation's subscribers
+func (s *service) createMessage(ctx context.Context, message *domain.Message) error {
+	if _, err := s.storage.CreateMessage(ctx, message); err != nil {
+		return err
+	}
+	s.broker.publish(message)
+	return nil
+}
+
+// conversationQuotaError returns ErrConversationQuotaExceeded when err, returned by storing
+// a new conversation of userID, reports that the user already has MaxConversationsPerUser
+// conversations, and nil otherwise. Archived conversations count, deleted ones do not. The
+// storage checks the quota in the same transaction as the insert, so concurrent creations
+// cannot overshoot it.
+func (s *service) conversationQuotaError(ctx context.Context, userID string, err error) error {
+	if !errors.Is(err, storage.ErrConversationQuotaExceeded) {
+		return nil
+	}
+	s.logger.Warn(ctx, "Conversation quota reached", map[string]any{
+		"user_id": userID,
+		"limit":   s.config.MaxConversationsPerUser,
+	})
+	return fmt.Errorf("%w (limit %d)", ErrConversationQuotaExceeded, s.config.MaxConversationsPerUser)
+}
+
+// ownedConversation returns the conversation when it exists and belongs to userID. It
+// returns ErrConversationNotFound otherwise, so callers never read another user's
+// conversation or learn that it exists.
+func (s *service) ownedConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	conversation, err := s.storage.GetConversationByID(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrConversationNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return conversation, nil
+}
+
 // ListConversations lists user conversations
 func (s *service) ListConversations(ctx context.Context, req *domain.ListConversationsRequest) (*domain.ListConversationsResponse, error) {
 	s.logger.Info(ctx, "Listing conversations", map[string]any{
-		"user_id": req.UserID,
-		"limit":   req.Limit,
-		"offset":  req.Offset,
+		"user_id":          req.UserID,
+		"limit":            req.Limit,
+		"offset":           req.Offset,
+		"cursor":           req.Cursor != nil,
+		"include_archived": req.IncludeArchived,
 	})
 
-	// Retrieve conversations from the database
-	conversations, err := s.storage.GetConversationsByUserID(ctx, req.UserID, req.Limit, req.Offset)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get conversations: %w", err)
+	// Retrieve conversations from the database. Cursor pages follow creation order so
+	// they stay stable while conversations are created or updated.
+	var conversations []domain.Conversation
+	if req.Cursor != nil {
+		cursor, err := domain.ParseCursor(*req.Cursor)
+		if err != nil {
+			return nil, NewValidationError("INVALID_CURSOR", "invalid cursor", err)
+		}
+		conversations, err = s.storage.GetConversationsAfterCursor(ctx, req.UserID, cursor, req.Limit, req.IncludeArchived)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conversations: %w", err)
+		}
+	} else {
+		var err error
+		conversations, err = s.storage.GetConversationsByUserID(ctx, req.UserID, req.Limit, req.Offset, req.IncludeArchived)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conversations: %w", err)
+		}
 	}
 
 	// Get total count
-	total, err := s.storage.CountConversationsByUserID(ctx, req.UserID)
+	total, err := s.storage.CountConversationsByUserID(ctx, req.UserID, req.IncludeArchived)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get conversation count: %w", err)
 	}
@@ -165,6 +499,18 @@ func (s *service) ListConversations(ctx context.Context, req *domain.ListConvers
 		Total:         total,
 	}
 
+	// A full cursor page may be followed by more conversations
+	if req.Cursor != nil && len(conversations) == req.Limit {
+		last := conversations[len(conversations)-1]
+		response.NextCursor = domain.NewCursor(last.CreatedAt, last.ID).Encode()
+	}
+
+	if req.Cursor != nil {
+		response.PageInfo = domain.NewCursorPageInfo(req.Limit, response.NextCursor)
+	} else {
+		response.PageInfo = domain.NewPageInfo(req.Limit, req.Offset, len(conversations), total)
+	}
+
 	s.logger.Info(ctx, "Conversations listed", map[string]any{
 		"user_id":             req.UserID,
 		"total_conversations": total,
@@ -173,6 +519,75 @@ func (s *service) ListConversations(ctx context.Context, req *domain.ListConvers
 	return response, nil
 }
 
+// ListUserMessages lists the user's most recent messages across all of their conversations.
+// When the context carries an authenticated user it must be userID.
+func (s *service) ListUserMessages(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error) {
+	s.logger.Info(ctx, "Listing user messages", map[string]any{
+		"user_id": userID,
+		"limit":   limit,
+		"offset":  offset,
+	})
+
+	if authUserID, ok := ctx.Value("user_id").(string); ok && authUserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrUserMismatch, userID)
+	}
+
+	messages, err := s.storage.GetMessagesByUserID(ctx, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get messages: %w", err)
+	}
+
+	total, err := s.storage.CountMessagesByUserID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message count: %w", err)
+	}
+
+	response := &domain.ListUserMessagesResponse{
+		Messages: make([]*domain.Message, len(messages)),
+		Total:    total,
+		PageInfo: domain.NewPageInfo(limit, offset, len(messages), total),
+	}
+	for i := range messages {
+		response.Messages[i] = &messages[i]
+	}
+
+	s.logger.Info(ctx, "User messages listed", map[string]any{
+		"user_id":        userID,
+		"total_messages": total,
+	})
+
+	return response, nil
+}
+
+// Search finds the user's messages matching a full-text query
+func (s *service) Search(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error) {
+	s.logger.Info(ctx, "Searching messages", map[string]any{
+		"user_id":      req.UserID,
+		"query_length": len(req.Query),
+		"limit":        req.Limit,
+		"offset":       req.Offset,
+	})
+
+	results, err := s.storage.SearchMessages(ctx, req.UserID, req.Query, req.Limit, req.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search messages: %w", err)
+	}
+
+	response := &domain.SearchMessagesResponse{
+		Results: make([]*domain.MessageSearchResult, len(results)),
+	}
+	for i := range results {
+		response.Results[i] = &results[i]
+	}
+
+	s.logger.Info(ctx, "Messages searched", map[string]any{
+		"user_id": req.UserID,
+		"results": len(results),
+	})
+
+	return response, nil
+}
+
 // CreateConversation creates a new conversation
 func (s *service) CreateConversation(ctx context.Context, userID, title string) (*domain.Conversation, error) {
 	s.logger.Info(ctx, "Creating conversation", map[string]any{
@@ -183,8 +598,11 @@ func (s *service) CreateConversation(ctx context.Context, userID, title string)
 	conversation := domain.NewConversation(userID, title)
 
 	// Store the conversation in the database
-	_, err := s.storage.CreateConversation(ctx, conversation)
+	_, err := s.storage.CreateConversation(ctx, conversation, s.config.MaxConversationsPerUser)
 	if err != nil {
+		if quotaErr := s.conversationQuotaError(ctx, userID, err); quotaErr != nil {
+			return nil, quotaErr
+		}
 		return nil, fmt.Errorf("failed to store conversation: %w", err)
 	}
 
@@ -196,73 +614,771 @@ func (s *service) CreateConversation(ctx context.Context, userID, title string)
 	return conversation, nil
 }
 
-// ChatWithAI sends a message to OpenAI and returns the AI response
-func (s *service) ChatWithAI(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int) (*domain.ChatResponse, error) {
+// ImportConversation creates a conversation together with all of its messages in one
+// transaction, so either the whole import is stored or nothing is. Messages keep their
+// order; those without a timestamp are placed just after the previous message.
+func (s *service) ImportConversation(ctx context.Context, userID, title string, messages []domain.Message) (*domain.Conversation, error) {
+	s.logger.Info(ctx, "Importing conversation", map[string]any{
+		"user_id":       userID,
+		"title":         title,
+		"message_count": len(messages),
+	})
+
+	if strings.TrimSpace(title) == "" {
+		return nil, fmt.Errorf("%w: title cannot be empty", ErrInvalidImport)
+	}
+	if len(messages) == 0 {
+		return nil, fmt.Errorf("%w: at least one message is required", ErrInvalidImport)
+	}
+	if len(messages) > domain.MaxImportMessages {
+		return nil, fmt.Errorf("%w: too many messages (max %d)", ErrInvalidImport, domain.MaxImportMessages)
+	}
+
+	conversation := domain.NewConversation(userID, title)
+
+	imported := make([]*domain.Message, len(messages))
+	var previous time.Time
+	for i, msg := range messages {
+		if err := s.messages.Validate(msg.Content); err != nil {
+			return nil, fmt.Errorf("%w: message %d: %v", ErrInvalidImport, i, err)
+		}
+
+		createdAt := msg.CreatedAt
+		switch {
+		case createdAt.IsZero() && i == 0:
+			createdAt = conversation.CreatedAt
+		case createdAt.IsZero():
+			createdAt = previous.Add(time.Microsecond)
+		case i > 0 && !createdAt.After(previous):
+			return nil, fmt.Errorf("%w: message %d: timestamps must be strictly increasing", ErrInvalidImport, i)
+		}
+		previous = createdAt
+
+		message, err := domain.NewMessage(userID, conversation.ID, msg.Content, msg.Role)
+		if err != nil {
+			return nil, fmt.Errorf("%w: message %d: %v", ErrInvalidImport, i, err)
+		}
+		imported[i] = message
+		imported[i].CreatedAt = createdAt
+		imported[i].UpdatedAt = createdAt
+	}
+
+	// Date the conversation by the history it holds
+	conversation.CreatedAt = imported[0].CreatedAt
+	conversation.UpdatedAt = previous
+
+	if _, err := s.storage.CreateConversationWithMessages(ctx, conversation, imported, s.config.MaxConversationsPerUser); err != nil {
+		if quotaErr := s.conversationQuotaError(ctx, userID, err); quotaErr != nil {
+			return nil, quotaErr
+		}
+		return nil, fmt.Errorf("failed to import conversation: %w", err)
+	}
+
+	s.logger.Info(ctx, "Conversation imported successfully", map[string]any{
+		"conversation_id": conversation.ID,
+		"user_id":         userID,
+		"message_count":   len(imported),
+	})
+
+	return conversation, nil
+}
+
+// GetConversation returns a conversation owned by the user with its message count.
+// Conversations that don't exist or belong to another user are both reported as not found.
+func (s *service) GetConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	s.logger.Info(ctx, "Getting conversation", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	conversation, err := s.storage.GetConversationWithActivity(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrConversationNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	return conversation, nil
+}
+
+// DeleteConversation soft deletes a conversation owned by the user along with its messages.
+// Conversations that don't exist or belong to another user are both reported as not found.
+func (s *service) DeleteConversation(ctx context.Context, userID, conversationID string) error {
+	s.logger.Info(ctx, "Deleting conversation", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	if err := s.storage.DeleteConversation(ctx, conversationID, userID); err != nil {
+		if errors.Is(err, storage.ErrConversationNotFound) {
+			return fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return fmt.Errorf("failed to delete conversation: %w", err)
+	}
+
+	s.logger.Info(ctx, "Conversation deleted successfully", map[string]any{
+		"conversation_id": conversationID,
+		"user_id":         userID,
+	})
+
+	return nil
+}
+
+// ClearConversationMessages deletes every message of a conversation owned by the user,
+// keeping the conversation, and returns how many were deleted. Conversations that don't
+// exist or belong to another user are both reported as not found.
+func (s *service) ClearConversationMessages(ctx context.Context, userID, conversationID string) (int, error) {
+	s.logger.Info(ctx, "Clearing conversation messages", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	deleted, err := s.storage.ClearConversationMessages(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrConversationNotFound) {
+			return 0, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return 0, fmt.Errorf("failed to clear conversation messages: %w", err)
+	}
+
+	s.logger.Info(ctx, "Conversation messages cleared successfully", map[string]any{
+		"conversation_id":  conversationID,
+		"user_id":          userID,
+		"messages_deleted": deleted,
+	})
+
+	return deleted, nil
+}
+
+// RestoreConversation undoes the deletion of a conversation owned by the user, provided
+// it was deleted within the restore window.
+func (s *service) RestoreConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	s.logger.Info(ctx, "Restoring conversation", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	conversation, err := s.storage.RestoreConversation(ctx, conversationID, userID)
+	if err != nil {
+		if errors.Is(err, storage.ErrConversationNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return nil, fmt.Errorf("failed to restore conversation: %w", err)
+	}
+
+	s.logger.Info(ctx, "Conversation restored successfully", map[string]any{
+		"conversation_id": conversationID,
+		"user_id":         userID,
+	})
+
+	return conversation, nil
+}
+
+// ArchiveConversation hides a conversation owned by the user from the default conversation list
+func (s *service) ArchiveConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setFlag(ctx, userID, conversationID, storage.ConversationArchived, true)
+}
+
+// UnarchiveConversation returns an archived conversation owned by the user to the default
+// conversation list
+func (s *service) UnarchiveConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setFlag(ctx, userID, conversationID, storage.ConversationArchived, false)
+}
+
+// PinConversation moves a conversation owned by the user to the top of the conversation list
+func (s *service) PinConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setFlag(ctx, userID, conversationID, storage.ConversationPinned, true)
+}
+
+// UnpinConversation returns a pinned conversation owned by the user to its place by update time
+func (s *service) UnpinConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setFlag(ctx, userID, conversationID, storage.ConversationPinned, false)
+}
+
+// setFlag sets or clears a flag of a conversation owned by the user. Conversations that
+// don't exist or belong to another user are both reported as not found.
+func (s *service) setFlag(ctx context.Context, userID, conversationID string, flag storage.ConversationFlag, value bool) (*domain.Conversation, error) {
+	s.logger.Info(ctx, "Setting conversation flag", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+		string(flag):      value,
+	})
+
+	conversation, err := s.storage.SetConversationFlag(ctx, conversationID, userID, flag, value)
+	if err != nil {
+		if errors.Is(err, storage.ErrConversationNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrConversationNotFound, conversationID)
+		}
+		return nil, fmt.Errorf("failed to update conversation: %w", err)
+	}
+
+	return conversation, nil
+}
+
+// EditMessage replaces the content of a message owned by the user. Assistant
+// messages are generated by the model and cannot be edited.
+func (s *service) EditMessage(ctx context.Context, userID, messageID, content string) (*domain.Message, error) {
+	s.logger.Info(ctx, "Editing message", map[string]any{
+		"user_id":        userID,
+		"message_id":     messageID,
+		"content_length": len(content),
+	})
+
+	if err := s.messages.Validate(content); err != nil {
+		return nil, err
+	}
+
+	message, err := s.storage.GetMessageByID(ctx, messageID)
+	if err != nil {
+		if errors.Is(err, storage.ErrMessageNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	if message.UserID != userID {
+		return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+	}
+	if message.Role == domain.RoleAssistant {
+		return nil, fmt.Errorf("%w: %s", ErrAssistantMessageEdit, messageID)
+	}
+
+	updated, err := s.storage.UpdateMessageContent(ctx, messageID, userID, content)
+	if err != nil {
+		if errors.Is(err, storage.ErrMessageNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+		return nil, fmt.Errorf("failed to update message: %w", err)
+	}
+
+	s.logger.Info(ctx, "Message edited successfully", map[string]any{
+		"message_id":      messageID,
+		"conversation_id": updated.ConversationID,
+	})
+
+	return updated, nil
+}
+
+// DeleteMessage deletes a message owned by the user
+func (s *service) DeleteMessage(ctx context.Context, userID, messageID string) error {
+	s.logger.Info(ctx, "Deleting message", map[string]any{
+		"user_id":    userID,
+		"message_id": messageID,
+	})
+
+	if err := s.storage.DeleteMessage(ctx, messageID, userID); err != nil {
+		if errors.Is(err, storage.ErrMessageNotFound) {
+			return fmt.Errorf("%w: %s", ErrMessageNotFound, messageID)
+		}
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	s.logger.Info(ctx, "Message deleted successfully", map[string]any{
+		"message_id": messageID,
+		"user_id":    userID,
+	})
+
+	return nil
+}
+
+// validateModel rejects models outside ALLOWED_MODELS
+func (s *service) validateModel(model string) error {
+	if s.config.ModelAllowed(model) {
+		return nil
+	}
+	return NewValidationError("MODEL_NOT_ALLOWED",
+		fmt.Sprintf("model %s is not allowed; allowed models: %s", model, strings.Join(s.config.AllowedModels, ", ")), nil)
+}
+
+// validateGenerationOptions checks temperature and maxTokens fall within what model accepts
+func validateGenerationOptions(model string, temperature float64, maxTokens int) error {
+	limits := llm.LimitsForModel(model)
+	if temperature < 0 || temperature > limits.MaxTemperature {
+		return NewValidationError("INVALID_TEMPERATURE",
+			fmt.Sprintf("temperature must be between 0 and %g for model %s", limits.MaxTemperature, model), nil)
+	}
+	if maxTokens < 1 || maxTokens > limits.MaxTokens {
+		return NewValidationError("INVALID_MAX_TOKENS",
+			fmt.Sprintf("max_tokens must be between 1 and %d for model %s", limits.MaxTokens, model), nil)
+	}
+	return nil
+}
+
+// ChatWithAI sends a message to the configured LLM provider and returns the AI response.
+// An empty model selects the provider's default model.
+func (s *service) ChatWithAI(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+	if model == "" {
+		model = s.config.DefaultModel()
+	}
+	if err := s.validateModel(model); err != nil {
+		return nil, err
+	}
+	if err := validateGenerationOptions(model, temperature, maxTokens); err != nil {
+		return nil, err
+	}
+	if err := s.messages.Validate(message); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateMetadata(metadata); err != nil {
+		return nil, NewValidationError("INVALID_METADATA", err.Error(), nil)
+	}
+
 	s.logger.Info(ctx, "Chatting with AI", map[string]any{
 		"user_id":         userID,
 		"conversation_id": conversationID,
+		"provider":        s.provider.Name(),
 		"model":           model,
 		"temperature":     temperature,
 		"max_tokens":      maxTokens,
 	})
 
-	// Create or get conversation ID
-	if conversationID == "" {
-		conversation := domain.NewConversation(userID, "AI Chat")
-		conversationID = conversation.ID
-		// Store the conversation
-		_, err := s.storage.CreateConversation(ctx, conversation)
-		if err != nil {
-			return nil, fmt.Errorf("failed to store conversation: %w", err)
-		}
+	reservation, err := s.reserveTokens(ctx, userID, estimateTokens(message)+maxTokens)
+	if err != nil {
+		return nil, err
 	}
+	usedTokens := 0
+	defer func() { s.settleTokens(ctx, reservation, usedTokens) }()
 
-	// Store user message
-	userMsg := domain.NewMessage(userID, conversationID, message, "user")
-	_, err := s.storage.CreateMessage(ctx, userMsg)
+	if err := s.moderate(ctx, userID, message); err != nil {
+		return nil, err
+	}
+
+	userMsg, created, err := s.storeAIUserMessage(ctx, userID, message, conversationID, metadata)
 	if err != nil {
-		return nil, fmt.Errorf("failed to store user message: %w", err)
+		return nil, err
 	}
+	conversationID = userMsg.ConversationID
 
-	// Prepare messages for OpenAI
-	openaiMessages := []openai.Message{
-		{
-			Role:    "user",
-			Content: message,
-		},
+	// Prepare messages for the provider, including prior conversation context
+	contextMessages, err := s.buildContextMessages(ctx, userMsg, maxTokens)
+	if err != nil {
+		return nil, err
 	}
 
-	// Call OpenAI API
-	aiResponse, err := s.openaiClient.ChatCompletion(ctx, openaiMessages, model, temperature, maxTokens)
+	// Call the LLM provider, falling back to other models if the requested one is unavailable
+	aiResponse, model, err := s.completeWithFallback(ctx, contextMessages, model, temperature, maxTokens)
 	if err != nil {
-		s.logger.Error(ctx, err, "Failed to get AI response", 500)
-		return nil, fmt.Errorf("failed to get AI response: %w", err)
+		// The user message stays stored; the error tells the client where to retry
+		s.logger.Error(ctx, err, "Failed to get AI response, user message left without a reply", 503, map[string]any{
+			"conversation_id": conversationID,
+			"message_id":      userMsg.ID,
+		})
+		return nil, newAIRequestError(userMsg, err)
 	}
 
-	// Get AI message content
-	aiMessageContent := aiResponse.GetFirstChoiceContent()
+	// The provider bills the tokens even when the reply turns out empty
+	usedTokens = aiResponse.Usage.TotalTokens
+
+	// Get AI message content. A reply without any tells the client why, by finish reason.
+	aiMessageContent := aiResponse.Content
 	if aiMessageContent == "" {
-		return nil, fmt.Errorf("no AI response content received")
+		s.logger.Warn(ctx, "AI response has no content", map[string]any{
+			"conversation_id": conversationID,
+			"finish_reason":   aiResponse.FinishReason,
+		})
+		return nil, emptyResponseError(aiResponse.FinishReason)
 	}
 
 	// Store AI message
-	aiMsg := domain.NewMessage(userID, conversationID, aiMessageContent, "assistant")
-	_, err = s.storage.CreateMessage(ctx, aiMsg)
+	aiMsg, err := domain.NewMessage(userID, conversationID, aiMessageContent, domain.RoleAssistant)
 	if err != nil {
+		return nil, err
+	}
+	if err := s.createMessage(ctx, aiMsg); err != nil {
 		return nil, fmt.Errorf("failed to store AI message: %w", err)
 	}
+	s.generateTitle(ctx, created, userMsg, aiMsg)
+	s.emitAIMessageCreated(ctx, aiMsg)
+
+	// Providers report the exact model version that served the request
+	if aiResponse.Model != "" {
+		model = aiResponse.Model
+	}
 
 	response := &domain.ChatResponse{
 		Message:        aiMsg,
 		ConversationID: conversationID,
 		IsAIResponse:   true,
+		Model:          model,
+		FinishReason:   aiResponse.FinishReason,
+		Usage: &domain.TokenUsage{
+			PromptTokens:     aiResponse.Usage.PromptTokens,
+			CompletionTokens: aiResponse.Usage.CompletionTokens,
+			TotalTokens:      aiResponse.Usage.TotalTokens,
+		},
 	}
 
 	s.logger.Info(ctx, "AI chat completed successfully", map[string]any{
 		"conversation_id": conversationID,
-		"tokens_used":     aiResponse.GetTotalTokens(),
-		"model_used":      aiResponse.Model,
+		"tokens_used":     aiResponse.Usage.TotalTokens,
+		"model_used":      model,
+		"finish_reason":   aiResponse.FinishReason,
+	})
+
+	return response, nil
+}
+
+// tokenReservation is the part of a user's daily token budget held for one AI chat until
+// its real usage is known
+type tokenReservation struct {
+	userID string
+	day    time.Time
+	tokens int
+}
+
+// reserveTokens holds estimate tokens of userID's DailyTokenBudget for the current UTC day,
+// or returns ErrDailyTokenBudgetExceeded when they don't fit in what is left. The
+// reservation is taken before the provider call and atomically, so concurrent chats cannot
+// overshoot the budget together. It returns nil when no budget is set.
+func (s *service) reserveTokens(ctx context.Context, userID string, estimate int) (*tokenReservation, error) {
+	budget := s.config.DailyTokenBudget
+	if budget <= 0 {
+		return nil, nil
+	}
+
+	reservation := &tokenReservation{userID: userID, day: time.Now(), tokens: estimate}
+	reserved, err := s.storage.ReserveDailyTokenUsage(ctx, userID, reservation.day, estimate, budget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to reserve token usage: %w", err)
+	}
+	if !reserved {
+		s.logger.Warn(ctx, "Daily token budget reached", map[string]any{
+			"user_id":  userID,
+			"estimate": estimate,
+			"budget":   budget,
+		})
+		return nil, fmt.Errorf("%w (budget %d)", ErrDailyTokenBudgetExceeded, budget)
+	}
+	return reservation, nil
+}
+
+// settleTokens replaces reservation by the used tokens, giving back what the chat didn't
+// use or adding what it used over the estimate. A chat that failed before the provider
+// billed it settles with no tokens. The reply has already been generated, so a failure is
+// only logged.
+func (s *service) settleTokens(ctx context.Context, reservation *tokenReservation, used int) {
+	if reservation == nil || used == reservation.tokens {
+		return
+	}
+
+	// The reservation's day is kept, so a chat running over midnight settles the day it was
+	// reserved on
+	if _, err := s.storage.AddDailyTokenUsage(context.WithoutCancel(ctx), reservation.userID, reservation.day, used-reservation.tokens); err != nil {
+		s.logger.Error(ctx, err, "Failed to settle token usage", 500, map[string]any{
+			"user_id":  reservation.userID,
+			"reserved": reservation.tokens,
+			"used":     used,
+		})
+	}
+}
+
+// estimateStreamTokens estimates the tokens of a streamed completion: the messages sent and
+// the content received
+func estimateStreamTokens(messages []llm.Message, content string) int {
+	tokens := estimateTokens(content)
+	for _, msg := range messages {
+		tokens += estimateTokens(msg.Content)
+	}
+	return tokens
+}
+
+// completeWithFallback requests a completion from model. While the provider reports the
+// model as unavailable, the request is retried with the next model of the fallback chain
+// that accepts the generation options. It returns the model that served the completion.
+func (s *service) completeWithFallback(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int) (*llm.CompletionResponse, string, error) {
+	response, err := s.provider.ChatCompletion(ctx, messages, model, temperature, maxTokens)
+	for _, fallback := range s.fallbackModels(model) {
+		if !llm.IsModelUnavailable(err) {
+			break
+		}
+		if validateGenerationOptions(fallback, temperature, maxTokens) != nil {
+			continue
+		}
+
+		s.logger.Warn(ctx, "Model unavailable, falling back to the next model", map[string]any{
+			"model":          model,
+			"fallback_model": fallback,
+			"error":          err.Error(),
+		})
+		model = fallback
+		response, err = s.provider.ChatCompletion(ctx, messages, model, temperature, maxTokens)
+	}
+	return response, model, err
+}
+
+// fallbackModels returns the models to try after model, in order: the rest of the
+// fallback chain when model is part of it, and the whole chain otherwise
+func (s *service) fallbackModels(model string) []string {
+	chain := s.config.ModelFallbackChain
+	if i := slices.Index(chain, model); i >= 0 {
+		return chain[i+1:]
+	}
+	return chain
+}
+
+// ChatWithAIStream streams the AI response to onDelta as it is generated, along with the
+// ID of the conversation it belongs to, which is new when conversationID is empty. It
+// stores the assembled assistant message once the stream completes. If the stream fails
+// midway, whatever was received is still stored so the conversation stays complete.
+func (s *service) ChatWithAIStream(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata, onDelta func(conversationID, delta string) error) (*domain.ChatResponse, error) {
+	if model == "" {
+		model = s.config.DefaultModel()
+	}
+	if err := s.validateModel(model); err != nil {
+		return nil, err
+	}
+	if err := validateGenerationOptions(model, temperature, maxTokens); err != nil {
+		return nil, err
+	}
+	if err := s.messages.Validate(message); err != nil {
+		return nil, err
+	}
+	if err := domain.ValidateMetadata(metadata); err != nil {
+		return nil, NewValidationError("INVALID_METADATA", err.Error(), nil)
+	}
+
+	s.logger.Info(ctx, "Streaming chat with AI", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+		"provider":        s.provider.Name(),
+		"model":           model,
+		"temperature":     temperature,
+		"max_tokens":      maxTokens,
+	})
+
+	reservation, err := s.reserveTokens(ctx, userID, estimateTokens(message)+maxTokens)
+	if err != nil {
+		return nil, err
+	}
+	usedTokens := 0
+	defer func() { s.settleTokens(ctx, reservation, usedTokens) }()
+
+	if err := s.moderate(ctx, userID, message); err != nil {
+		return nil, err
+	}
+
+	userMsg, created, err := s.storeAIUserMessage(ctx, userID, message, conversationID, metadata)
+	if err != nil {
+		return nil, err
+	}
+	conversationID = userMsg.ConversationID
+
+	// Prepare messages for the provider, including prior conversation context
+	contextMessages, err := s.buildContextMessages(ctx, userMsg, maxTokens)
+	if err != nil {
+		return nil, err
+	}
+
+	// Call the LLM provider's streaming API
+	content, streamErr := s.provider.ChatCompletionStream(ctx, contextMessages, model, temperature, maxTokens, func(delta string) error {
+		return onDelta(conversationID, delta)
+	})
+	if streamErr != nil && content == "" {
+		// The user message stays stored; the error tells the client where to retry
+		s.logger.Error(ctx, streamErr, "Failed to stream AI response, user message left without a reply", 503, map[string]any{
+			"conversation_id": conversationID,
+			"message_id":      userMsg.ID,
+		})
+		return nil, newAIRequestError(userMsg, streamErr)
+	}
+	if content == "" {
+		return nil, ErrAIResponseEmpty
+	}
+
+	// Streams report no usage, so the tokens of what was sent and received are estimated
+	usedTokens = estimateStreamTokens(contextMessages, content)
+
+	// Store AI message, even if only part of it was received. The request context
+	// may already be cancelled, so the partial message is stored without it.
+	storeCtx := ctx
+	if streamErr != nil {
+		storeCtx = context.WithoutCancel(ctx)
+	}
+	aiMsg, err := domain.NewMessage(userID, conversationID, content, domain.RoleAssistant)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.createMessage(storeCtx, aiMsg); err != nil {
+		return nil, fmt.Errorf("failed to store AI message: %w", err)
+	}
+
+	if streamErr != nil {
+		s.logger.Warn(ctx, "AI stream interrupted, partial response stored", map[string]any{
+			"conversation_id": conversationID,
+			"message_id":      aiMsg.ID,
+			"content_length":  len(content),
+			"partial":         true,
+			"error":           streamErr.Error(),
+		})
+		return nil, fmt.Errorf("failed to stream AI response: %w", streamErr)
+	}
+	s.generateTitle(ctx, created, userMsg, aiMsg)
+	s.emitAIMessageCreated(ctx, aiMsg)
+
+	response := &domain.ChatResponse{
+		Message:        aiMsg,
+		ConversationID: conversationID,
+		IsAIResponse:   true,
+		Model:          model,
+	}
+
+	s.logger.Info(ctx, "AI chat stream completed successfully", map[string]any{
+		"conversation_id": conversationID,
+		"message_id":      aiMsg.ID,
+		"content_length":  len(content),
 	})
 
 	return response, nil
 }
+
+// moderate rejects a message flagged by the moderator. Without a moderator every message
+// is accepted.
+func (s *service) moderate(ctx context.Context, userID, message string) error {
+	if s.moderator == nil {
+		return nil
+	}
+
+	result, err := s.moderator.Moderate(ctx, message)
+	if err != nil {
+		s.logger.Error(ctx, err, "Failed to moderate message", 500)
+		return fmt.Errorf("failed to moderate message: %w", err)
+	}
+	if !result.Flagged {
+		return nil
+	}
+
+	s.logger.Warn(ctx, "Message flagged by content moderation", map[string]any{
+		"user_id":    userID,
+		"categories": result.Categories,
+	})
+	return messageFlaggedError(result.Categories)
+}
+
+// storeAIUserMessage creates the conversation if needed and stores the user's
+// message, with its metadata, ahead of an AI call, returning the stored message and
+// whether the conversation was created
+func (s *service) storeAIUserMessage(ctx context.Context, userID, message, conversationID string, metadata domain.Metadata) (*domain.Message, bool, error) {
+	// Create or get conversation ID
+	created := false
+	if conversationID == "" {
+		conversation := domain.NewConversation(userID, defaultAIConversationTitle)
+		conversationID = conversation.ID
+		// Store the conversation
+		_, err := s.storage.CreateConversation(ctx, conversation, s.config.MaxConversationsPerUser)
+		if err != nil {
+			if quotaErr := s.conversationQuotaError(ctx, userID, err); quotaErr != nil {
+				return nil, false, quotaErr
+			}
+			return nil, false, fmt.Errorf("failed to store conversation: %w", err)
+		}
+		created = true
+	} else if _, err := s.ownedConversation(ctx, userID, conversationID); err != nil {
+		return nil, false, err
+	}
+
+	// Store user message
+	userMsg, err := domain.NewMessage(userID, conversationID, message, domain.RoleUser)
+	if err != nil {
+		return nil, false, err
+	}
+	userMsg.Metadata = metadata
+	if err := s.createMessage(ctx, userMsg); err != nil {
+		return nil, false, fmt.Errorf("failed to store user message: %w", err)
+	}
+
+	return userMsg, created, nil
+}
+
+// generateTitle names a conversation created by this AI exchange in the background.
+// Conversations the user created, and therefore titled, are left untouched.
+func (s *service) generateTitle(ctx context.Context, created bool, userMsg, aiMsg *domain.Message) {
+	if s.titles == nil || !created {
+		return
+	}
+	s.titles.GenerateAsync(ctx, userMsg.UserID, userMsg.ConversationID, userMsg.Content, aiMsg.Content)
+}
+
+// emitAIMessageCreated tells the event sink that an AI reply has been stored
+func (s *service) emitAIMessageCreated(ctx context.Context, aiMsg *domain.Message) {
+	s.events.Emit(ctx, events.Event{
+		Type:           events.TypeAIMessageCreated,
+		MessageID:      aiMsg.ID,
+		ConversationID: aiMsg.ConversationID,
+		UserID:         aiMsg.UserID,
+		Timestamp:      aiMsg.CreatedAt,
+	})
+}
+
+// buildContextMessages assembles the provider prompt from the most recent messages of
+// the conversation, oldest first, ending with the already stored user message.
+// Older messages are dropped until the estimated prompt fits the model context
+// alongside the requested completion tokens.
+func (s *service) buildContextMessages(ctx context.Context, userMsg *domain.Message, maxTokens int) ([]llm.Message, error) {
+	window := s.config.OpenAIContextWindow
+
+	var history []domain.Message
+	if window > 0 {
+		total, err := s.storage.CountMessagesByConversationID(ctx, userMsg.ConversationID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get message count: %w", err)
+		}
+
+		// Fetch one extra message since the count includes the new user message
+		offset := total - (window + 1)
+		if offset < 0 {
+			offset = 0
+		}
+		history, err = s.storage.GetMessagesByConversationID(ctx, userMsg.ConversationID, window+1, offset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get conversation history: %w", err)
+		}
+	}
+
+	var prior []llm.Message
+	for _, msg := range history {
+		if msg.ID == userMsg.ID {
+			continue
+		}
+		prior = append(prior, llm.Message{
+			Role:    string(msg.Role),
+			Content: msg.Content,
+		})
+	}
+	if len(prior) > window {
+		prior = prior[len(prior)-window:]
+	}
+
+	current := llm.Message{
+		Role:    string(userMsg.Role),
+		Content: userMsg.Content,
+	}
+
+	// Drop the oldest history until the prompt fits within the token budget
+	budget := s.config.OpenAIContextMaxTokens - maxTokens - estimateTokens(current.Content)
+	used := 0
+	for _, msg := range prior {
+		used += estimateTokens(msg.Content)
+	}
+	dropped := 0
+	for len(prior) > 0 && used > budget {
+		used -= estimateTokens(prior[0].Content)
+		prior = prior[1:]
+		dropped++
+	}
+
+	if dropped > 0 {
+		s.logger.Debug(ctx, "Trimmed conversation history to fit token budget", map[string]any{
+			"conversation_id": userMsg.ConversationID,
+			"dropped":         dropped,
+			"kept":            len(prior),
+		})
+	}
+
+	return append(prior, current), nil
+}
+
+// estimateTokens roughly estimates the token count of a message, assuming about
+// four characters per token plus a fixed per-message overhead
+func estimateTokens(content string) int {
+	return len(content)/4 + 4
+}
@@ -0,0 +1,3035 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"chat-service/configs"
+	"chat-service/internal/domain"
+	"chat-service/internal/domain/domaintest"
+	"chat-service/internal/services/events"
+	"chat-service/internal/services/llm"
+	"chat-service/storage"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRepository is an in-memory storage.Repository used by the service tests
+type fakeRepository struct {
+	mu             sync.Mutex
+	conversations  map[string]*domain.Conversation
+	messages       []domain.Message
+	pageCalls      int
+	titleUpdates   int
+	importErr      error // returned by CreateConversationWithMessages before anything is stored
+	keys           map[string]idempotencyKey
+	archiveCutoffs []time.Time    // cutoffs ArchiveIdleConversations was called with
+	usage          map[string]int // tokens by usageKey
+
+	// deleted holds soft deleted conversations with the messages deleted alongside them
+	deleted map[string]deletedConversation
+}
+
+// idempotencyKey is the message recorded under a user's idempotency key
+type idempotencyKey struct {
+	messageID string
+	createdAt time.Time
+}
+
+type deletedConversation struct {
+	conversation *domain.Conversation
+	messages     []domain.Message
+}
+
+var _ storage.Repository = (*fakeRepository)(nil)
+
+func newFakeRepository() *fakeRepository {
+	return &fakeRepository{
+		conversations: make(map[string]*domain.Conversation),
+		deleted:       make(map[string]deletedConversation),
+		keys:          make(map[string]idempotencyKey),
+		usage:         make(map[string]int),
+	}
+}
+
+// usageKey keys the token usage of userID on the UTC day of day
+func usageKey(userID string, day time.Time) string {
+	return userID + "/" + day.UTC().Format(time.DateOnly)
+}
+
+func (f *fakeRepository) ReserveDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens, budget int) (bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.usage[usageKey(userID, day)]+tokens > budget {
+		return false, nil
+	}
+	f.usage[usageKey(userID, day)] += tokens
+	return true, nil
+}
+
+// dailyUsage returns the tokens userID has spent today
+func (f *fakeRepository) dailyUsage(userID string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.usage[usageKey(userID, time.Now())]
+}
+
+func (f *fakeRepository) AddDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens int) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.usage[usageKey(userID, day)] += tokens
+	return f.usage[usageKey(userID, day)], nil
+}
+
+// overQuota reports whether userID already has maxConversations conversations. It must be
+// called with f.mu held, so that the check and the insert are atomic like in storage.
+func (f *fakeRepository) overQuota(userID string, maxConversations int) bool {
+	if maxConversations <= 0 {
+		return false
+	}
+	count := 0
+	for _, c := range f.conversations {
+		if c.UserID == userID {
+			count++
+		}
+	}
+	return count >= maxConversations
+}
+
+func (f *fakeRepository) CreateConversation(ctx context.Context, conversation *domain.Conversation, maxConversations int) (*domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.overQuota(conversation.UserID, maxConversations) {
+		return nil, storage.ErrConversationQuotaExceeded
+	}
+	c := *conversation
+	f.conversations[c.ID] = &c
+	return &c, nil
+}
+
+func (f *fakeRepository) GetConversationByID(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.conversations[id]
+	if !ok || c.UserID != userID {
+		return nil, storage.ErrConversationNotFound
+	}
+	copied := *c
+	return &copied, nil
+}
+
+func (f *fakeRepository) GetConversationWithActivity(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	c, err := f.GetConversationByID(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	copied := f.withActivity(*c)
+	return &copied, nil
+}
+
+func (f *fakeRepository) GetConversationsByUserID(ctx context.Context, userID string, limit, offset int, includeArchived bool) ([]domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []domain.Conversation
+	for _, c := range f.conversations {
+		if c.UserID == userID && (includeArchived || !c.Archived) {
+			result = append(result, f.withActivity(*c))
+		}
+	}
+	// Pinned first, then most recently updated, like the storage query
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Pinned != result[j].Pinned {
+			return result[i].Pinned
+		}
+		return result[i].UpdatedAt.After(result[j].UpdatedAt)
+	})
+	return paginate(result, limit, offset), nil
+}
+
+// withActivity fills in the message count and last activity of c, as storage reads do.
+// The caller must hold f.mu.
+func (f *fakeRepository) withActivity(c domain.Conversation) domain.Conversation {
+	for _, m := range f.messages {
+		if m.ConversationID != c.ID {
+			continue
+		}
+		c.MessageCount++
+		if c.LastMessageAt == nil || m.CreatedAt.After(*c.LastMessageAt) {
+			createdAt := m.CreatedAt
+			c.LastMessageAt = &createdAt
+		}
+	}
+	return c
+}
+
+func (f *fakeRepository) GetConversationsAfterCursor(ctx context.Context, userID string, cursor domain.Cursor, limit int, includeArchived bool) ([]domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []domain.Conversation
+	for _, c := range f.conversations {
+		if c.UserID == userID && (includeArchived || !c.Archived) && cursor.Precedes(c.CreatedAt, c.ID) {
+			result = append(result, f.withActivity(*c))
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return domain.NewCursor(result[i].CreatedAt, result[i].ID).Precedes(result[j].CreatedAt, result[j].ID)
+	})
+	return paginate(result, limit, 0), nil
+}
+
+func (f *fakeRepository) CountConversationsByUserID(ctx context.Context, userID string, includeArchived bool) (int, error) {
+	conversations, _ := f.GetConversationsByUserID(ctx, userID, 0, 0, includeArchived)
+	return len(conversations), nil
+}
+
+func (f *fakeRepository) UpdateConversationTitle(ctx context.Context, id, userID, title string) (*domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.conversations[id]
+	if !ok || c.UserID != userID {
+		return nil, errors.New("conversation not found or user not authorized")
+	}
+	f.titleUpdates++
+	c.Title = title
+	copied := *c
+	return &copied, nil
+}
+
+func (f *fakeRepository) DeleteConversation(ctx context.Context, id, userID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.conversations[id]
+	if !ok || c.UserID != userID {
+		return storage.ErrConversationNotFound
+	}
+	delete(f.conversations, id)
+
+	// Soft deleting a conversation also soft deletes its messages
+	removed := deletedConversation{conversation: c}
+	var kept []domain.Message
+	for _, m := range f.messages {
+		if m.ConversationID == id {
+			removed.messages = append(removed.messages, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	f.messages = kept
+	f.deleted[id] = removed
+	return nil
+}
+
+func (f *fakeRepository) ClearConversationMessages(ctx context.Context, id, userID string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.conversations[id]
+	if !ok || c.UserID != userID {
+		return 0, storage.ErrConversationNotFound
+	}
+	c.UpdatedAt = time.Now()
+
+	var kept []domain.Message
+	for _, m := range f.messages {
+		if m.ConversationID != id {
+			kept = append(kept, m)
+		}
+	}
+	deleted := len(f.messages) - len(kept)
+	f.messages = kept
+	return deleted, nil
+}
+
+func (f *fakeRepository) RestoreConversation(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	removed, ok := f.deleted[id]
+	if !ok || removed.conversation.UserID != userID {
+		return nil, storage.ErrConversationNotFound
+	}
+	delete(f.deleted, id)
+	f.conversations[id] = removed.conversation
+	f.messages = append(f.messages, removed.messages...)
+	copied := *removed.conversation
+	return &copied, nil
+}
+
+func (f *fakeRepository) SetConversationFlag(ctx context.Context, id, userID string, flag storage.ConversationFlag, value bool) (*domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c, ok := f.conversations[id]
+	if !ok || c.UserID != userID {
+		return nil, storage.ErrConversationNotFound
+	}
+	switch flag {
+	case storage.ConversationArchived:
+		c.Archived = value
+	case storage.ConversationPinned:
+		c.Pinned = value
+	}
+	copied := *c
+	return &copied, nil
+}
+
+// ArchiveIdleConversations archives the conversations that, like in the storage query, were
+// not updated and received no message since cutoff
+func (f *fakeRepository) ArchiveIdleConversations(ctx context.Context, cutoff time.Time) (int64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.archiveCutoffs = append(f.archiveCutoffs, cutoff)
+	var archived int64
+	for _, c := range f.conversations {
+		active := f.withActivity(*c)
+		if c.Archived || !c.UpdatedAt.Before(cutoff) || (active.LastMessageAt != nil && !active.LastMessageAt.Before(cutoff)) {
+			continue
+		}
+		c.Archived = true
+		archived++
+	}
+	return archived, nil
+}
+
+func (f *fakeRepository) CreateMessage(ctx context.Context, message *domain.Message) (*domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = append(f.messages, *message)
+	m := *message
+	return &m, nil
+}
+
+func (f *fakeRepository) CreateMessagesBatch(ctx context.Context, messages []*domain.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range messages {
+		f.messages = append(f.messages, *m)
+	}
+	return nil
+}
+
+func (f *fakeRepository) CreateMessageWithIdempotencyKey(ctx context.Context, conversation *domain.Conversation, message *domain.Message, key string, expiresBefore time.Time, maxConversations int) (*domain.Message, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if recorded, ok := f.keys[message.UserID+"/"+key]; ok && recorded.createdAt.After(expiresBefore) {
+		for _, m := range f.messages {
+			if m.ID == recorded.messageID {
+				return &m, false, nil
+			}
+		}
+	}
+	if conversation != nil {
+		if f.overQuota(conversation.UserID, maxConversations) {
+			return nil, false, storage.ErrConversationQuotaExceeded
+		}
+		c := *conversation
+		f.conversations[c.ID] = &c
+	}
+	f.messages = append(f.messages, *message)
+	f.keys[message.UserID+"/"+key] = idempotencyKey{messageID: message.ID, createdAt: time.Now()}
+	m := *message
+	return &m, true, nil
+}
+
+func (f *fakeRepository) CreateConversationWithMessages(ctx context.Context, conversation *domain.Conversation, messages []*domain.Message, maxConversations int) (*domain.Conversation, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.importErr != nil {
+		return nil, f.importErr
+	}
+	if f.overQuota(conversation.UserID, maxConversations) {
+		return nil, storage.ErrConversationQuotaExceeded
+	}
+	c := *conversation
+	f.conversations[c.ID] = &c
+	for _, m := range messages {
+		m.ConversationID = c.ID
+		f.messages = append(f.messages, *m)
+	}
+	return &c, nil
+}
+
+func (f *fakeRepository) GetMessageByID(ctx context.Context, id string) (*domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, m := range f.messages {
+		if m.ID == id {
+			copied := m
+			return &copied, nil
+		}
+	}
+	return nil, storage.ErrMessageNotFound
+}
+
+func (f *fakeRepository) GetMessagesByConversationID(ctx context.Context, conversationID string, limit, offset int) ([]domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.pageCalls++
+	var result []domain.Message
+	for _, m := range f.messages {
+		if m.ConversationID == conversationID {
+			result = append(result, m)
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool { return result[i].CreatedAt.Before(result[j].CreatedAt) })
+	return paginate(result, limit, offset), nil
+}
+
+func (f *fakeRepository) GetMessagesAfterCursor(ctx context.Context, conversationID string, cursor domain.Cursor, limit int) ([]domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []domain.Message
+	for _, m := range f.messages {
+		if m.ConversationID == conversationID && cursor.Precedes(m.CreatedAt, m.ID) {
+			result = append(result, m)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return domain.NewCursor(result[i].CreatedAt, result[i].ID).Precedes(result[j].CreatedAt, result[j].ID)
+	})
+	return paginate(result, limit, 0), nil
+}
+
+func (f *fakeRepository) CountMessagesByConversationID(ctx context.Context, conversationID string) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	count := 0
+	for _, m := range f.messages {
+		if m.ConversationID == conversationID {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (f *fakeRepository) GetMessagesByUserID(ctx context.Context, userID string, limit, offset int) ([]domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []domain.Message
+	for _, m := range f.messages {
+		if m.UserID == userID {
+			result = append(result, m)
+		}
+	}
+	return paginate(result, limit, offset), nil
+}
+
+func (f *fakeRepository) CountMessagesByUserID(ctx context.Context, userID string) (int, error) {
+	messages, _ := f.GetMessagesByUserID(ctx, userID, 0, 0)
+	return len(messages), nil
+}
+
+// SearchMessages matches messages containing the query, case-insensitively, in
+// conversations owned by userID
+func (f *fakeRepository) SearchMessages(ctx context.Context, userID, query string, limit, offset int) ([]domain.MessageSearchResult, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	var result []domain.MessageSearchResult
+	for _, m := range f.messages {
+		c, ok := f.conversations[m.ConversationID]
+		if !ok || c.UserID != userID || !strings.Contains(strings.ToLower(m.Content), strings.ToLower(query)) {
+			continue
+		}
+		result = append(result, domain.MessageSearchResult{Message: m, Snippet: m.Content})
+	}
+	return paginate(result, limit, offset), nil
+}
+
+func (f *fakeRepository) UpdateMessageContent(ctx context.Context, id, userID, content string) (*domain.Message, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.messages {
+		if f.messages[i].ID == id && f.messages[i].UserID == userID {
+			f.messages[i].Content = content
+			copied := f.messages[i]
+			return &copied, nil
+		}
+	}
+	return nil, storage.ErrMessageNotFound
+}
+
+func (f *fakeRepository) DeleteMessage(ctx context.Context, id, userID string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for i := range f.messages {
+		if f.messages[i].ID == id && f.messages[i].UserID == userID {
+			f.messages = append(f.messages[:i], f.messages[i+1:]...)
+			return nil
+		}
+	}
+	return storage.ErrMessageNotFound
+}
+
+// paginate applies limit/offset to a slice; a non-positive limit returns everything after offset
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// fakeProvider is an llm.LLMProvider that replays canned responses. Completions
+// return the queued responses in order, then fall back to response.
+type fakeProvider struct {
+	mu          sync.Mutex
+	response    *llm.CompletionResponse
+	responses   []*llm.CompletionResponse
+	deltas      []string
+	streamErr   error
+	err         error            // returned by ChatCompletion instead of a response
+	modelErrs   map[string]error // returned by ChatCompletion for the listed models
+	models      []string         // the model of every ChatCompletion call, in order
+	messages    []llm.Message
+	model       string
+	temperature float64
+	maxTokens   int
+	calls       int
+	delay       time.Duration // how long completions take unless ctx is done first
+}
+
+func (f *fakeProvider) Name() string {
+	return "fake"
+}
+
+func (f *fakeProvider) ChatCompletion(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int) (*llm.CompletionResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = messages
+	f.model = model
+	f.temperature = temperature
+	f.maxTokens = maxTokens
+	f.models = append(f.models, model)
+	f.calls++
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	if err := f.modelErrs[model]; err != nil {
+		return nil, err
+	}
+	if len(f.responses) > 0 {
+		response := f.responses[0]
+		f.responses = f.responses[1:]
+		return response, nil
+	}
+	return f.response, nil
+}
+
+func (f *fakeProvider) ChatCompletionStream(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages = messages
+	f.model = model
+	var content string
+	for _, delta := range f.deltas {
+		if err := onDelta(delta); err != nil {
+			return content, err
+		}
+		content += delta
+	}
+	return content, f.streamErr
+}
+
+// fakeModerator is an llm.Moderator that flags messages containing any of its blocked words
+type fakeModerator struct {
+	mu      sync.Mutex
+	blocked map[string]string // word to the category it is flagged for
+	err     error
+	inputs  []string
+}
+
+func (m *fakeModerator) Moderate(ctx context.Context, input string) (*llm.ModerationResult, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.inputs = append(m.inputs, input)
+	if m.err != nil {
+		return nil, m.err
+	}
+	result := &llm.ModerationResult{}
+	for word, category := range m.blocked {
+		if strings.Contains(input, word) {
+			result.Flagged = true
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
+
+const (
+	testUserID  = "11111111-1111-1111-1111-111111111111"
+	otherUserID = "22222222-2222-2222-2222-222222222222"
+)
+
+func TestNewService_AcceptsStorageDB(t *testing.T) {
+	// The server wires the service to *storage.DB; building it here locks that contract in
+	// the service's own tests instead of leaving it to the server wiring
+	var db *storage.DB
+	svc := NewService(&fakeProvider{}, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}), &configs.Config{}, db)
+
+	assert.NotNil(t, svc)
+}
+
+func newTestService(repo *fakeRepository) Service {
+	return newTestServiceWithProvider(repo, &fakeProvider{})
+}
+
+func newTestServiceWithProvider(repo *fakeRepository, provider llm.LLMProvider) Service {
+	return newTestServiceWithConfig(repo, provider, &configs.Config{
+		LLMProvider:            configs.OPENAI_PROVIDER,
+		OpenAIModel:            "gpt-3.5-turbo",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+	})
+}
+
+func newTestServiceWithConfig(repo *fakeRepository, provider llm.LLMProvider, cfg *configs.Config) Service {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	return NewService(provider, logger, cfg, repo)
+}
+
+// completionResponse builds a provider completion response with the given content
+func completionResponse(content string, totalTokens int) *llm.CompletionResponse {
+	return &llm.CompletionResponse{
+		Content: content,
+		Model:   "gpt-3.5-turbo",
+		Usage:   llm.Usage{TotalTokens: totalTokens},
+	}
+}
+
+// seedConversation stores a conversation with n messages created one second apart,
+// alternating between user and assistant roles
+func seedConversation(t *testing.T, repo *fakeRepository, userID string, n int) *domain.Conversation {
+	t.Helper()
+	conversation := domain.NewConversation(userID, "Test Conversation")
+	_, err := repo.CreateConversation(context.Background(), conversation, 0)
+	require.NoError(t, err)
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < n; i++ {
+		role := domain.RoleUser
+		if i%2 == 1 {
+			role = domain.RoleAssistant
+		}
+		msg := domaintest.NewMessage(userID, conversation.ID, fmt.Sprintf("message %d", i), role)
+		msg.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		_, err := repo.CreateMessage(context.Background(), msg)
+		require.NoError(t, err)
+	}
+	return conversation
+}
+
+func TestService_StreamMessages_Order(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, batchSize*2+7)
+	svc := newTestService(repo)
+
+	var received []string
+	err := svc.StreamMessages(context.Background(), testUserID, conversation.ID, func(msg *domain.Message) error {
+		received = append(received, msg.Content)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.Len(t, received, batchSize*2+7)
+	for i, content := range received {
+		assert.Equal(t, fmt.Sprintf("message %d", i), content)
+	}
+	assert.Equal(t, 3, repo.pageCalls)
+}
+
+func TestService_StreamMessages_EmptyConversation(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 0)
+	svc := newTestService(repo)
+
+	calls := 0
+	err := svc.StreamMessages(context.Background(), testUserID, conversation.ID, func(msg *domain.Message) error {
+		calls++
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, calls)
+}
+
+func TestService_StreamMessages_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	err := svc.StreamMessages(context.Background(), otherUserID, conversation.ID, func(msg *domain.Message) error {
+		t.Fatal("no message should be sent to a user who does not own the conversation")
+		return nil
+	})
+
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Equal(t, 0, repo.pageCalls)
+}
+
+func TestService_SubscribeMessages_DeliversNewMessages(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan *domain.Message, 4)
+	done := make(chan error, 1)
+	go func() {
+		done <- svc.SubscribeMessages(ctx, testUserID, conversation.ID, func(msg *domain.Message) error {
+			received <- msg
+			return nil
+		})
+	}()
+
+	// The stored history comes first, once the subscription is in place
+	select {
+	case msg := <-received:
+		assert.Equal(t, "message 0", msg.Content)
+	case <-time.After(time.Second):
+		t.Fatal("the stored history was not replayed")
+	}
+
+	response, err := svc.SendMessage(context.Background(), &domain.ChatRequest{
+		UserID:         testUserID,
+		ConversationID: conversation.ID,
+		Message:        "sent after subscribing",
+	})
+	require.NoError(t, err)
+
+	select {
+	case msg := <-received:
+		assert.Equal(t, response.Message.ID, msg.ID)
+		assert.Equal(t, "sent after subscribing", msg.Content)
+	case <-time.After(time.Second):
+		t.Fatal("a message created after subscribing was not delivered")
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, context.Canceled)
+	case <-time.After(time.Second):
+		t.Fatal("the subscription did not end when the context was cancelled")
+	}
+}
+
+func TestService_SubscribeMessages_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	err := svc.SubscribeMessages(context.Background(), otherUserID, conversation.ID, func(msg *domain.Message) error {
+		t.Fatal("no message should be sent to a user who does not own the conversation")
+		return nil
+	})
+
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Equal(t, 0, repo.pageCalls)
+}
+
+func TestBroker_DropsSlowSubscriber(t *testing.T) {
+	b := newBroker(2)
+	slow := b.subscribe("conversation")
+	other := b.subscribe("other-conversation")
+
+	for i := 0; i < 3; i++ {
+		b.publish(domaintest.NewMessage(testUserID, "conversation", fmt.Sprintf("message %d", i), domain.RoleUser))
+	}
+
+	// The buffered messages are still delivered, then the channel reports the drop
+	assert.Equal(t, "message 0", (<-slow.messages).Content)
+	assert.Equal(t, "message 1", (<-slow.messages).Content)
+	_, ok := <-slow.messages
+	assert.False(t, ok, "a subscriber with a full buffer must be dropped")
+
+	// Publishing again must not block on or panic over the dropped subscriber
+	b.publish(domaintest.NewMessage(testUserID, "conversation", "message 3", domain.RoleUser))
+	b.unsubscribe(slow)
+
+	assert.Empty(t, other.messages)
+	b.unsubscribe(other)
+	assert.Empty(t, b.subscribers)
+}
+
+func TestService_ExportConversation_JSONL(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, batchSize*2+7)
+	svc := newTestService(repo)
+
+	export, err := svc.ExportConversation(context.Background(), testUserID, conversation.ID)
+	require.NoError(t, err)
+
+	var out strings.Builder
+	written, err := export.Render(context.Background(), &out, ExportFormatJSONL)
+
+	require.NoError(t, err)
+	assert.Equal(t, batchSize*2+7, written)
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, lines, batchSize*2+7)
+	for i, line := range lines {
+		var message domain.Message
+		require.NoError(t, json.Unmarshal([]byte(line), &message))
+		assert.Equal(t, fmt.Sprintf("message %d", i), message.Content)
+		assert.Equal(t, conversation.ID, message.ConversationID)
+	}
+	assert.Equal(t, 1, repo.pageCalls, "later pages must be read by cursor")
+}
+
+func TestService_ExportConversation_Markdown(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 2)
+	svc := newTestService(repo)
+
+	export, err := svc.ExportConversation(context.Background(), testUserID, conversation.ID)
+	require.NoError(t, err)
+
+	var out strings.Builder
+	written, err := export.Render(context.Background(), &out, ExportFormatMarkdown)
+
+	require.NoError(t, err)
+	assert.Equal(t, 2, written)
+	assert.True(t, strings.HasPrefix(out.String(), "# Test Conversation\n\n"))
+	assert.Contains(t, out.String(), "## User · ")
+	assert.Contains(t, out.String(), "\n\nmessage 0\n\n")
+	assert.Contains(t, out.String(), "## Assistant · ")
+	assert.Contains(t, out.String(), "\n\nmessage 1\n\n")
+}
+
+func TestService_ExportConversation_UnsupportedFormat(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	export, err := svc.ExportConversation(context.Background(), testUserID, conversation.ID)
+	require.NoError(t, err)
+
+	_, err = export.Render(context.Background(), io.Discard, ExportFormat("pdf"))
+
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrorTypeValidation, appErr.Type)
+}
+
+func TestService_ExportConversation_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	export, err := svc.ExportConversation(context.Background(), otherUserID, conversation.ID)
+
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Nil(t, export)
+	assert.Equal(t, 0, repo.pageCalls)
+}
+
+func TestService_StreamMessages_StopsOnCancel(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, batchSize*3)
+	svc := newTestService(repo)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sent := 0
+	err := svc.StreamMessages(ctx, testUserID, conversation.ID, func(msg *domain.Message) error {
+		sent++
+		if sent == 5 {
+			cancel()
+		}
+		return nil
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 5, sent)
+	assert.Equal(t, 1, repo.pageCalls)
+}
+
+func TestService_StreamMessages_SendError(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	sendErr := errors.New("client went away")
+	err := svc.StreamMessages(context.Background(), testUserID, conversation.ID, func(msg *domain.Message) error {
+		return sendErr
+	})
+
+	assert.ErrorIs(t, err, sendErr)
+}
+
+func TestService_ChatWithAIStream(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 0)
+	client := &fakeProvider{deltas: []string{"Hello", " there", "!"}}
+	svc := newTestServiceWithProvider(repo, client)
+
+	var deltas []string
+	response, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil, func(_, delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello", " there", "!"}, deltas)
+	assert.True(t, response.IsAIResponse)
+	assert.Equal(t, conversation.ID, response.ConversationID)
+	assert.Equal(t, "Hello there!", response.Message.Content)
+
+	require.Len(t, repo.messages, 2)
+	assert.Equal(t, domain.RoleUser, repo.messages[0].Role)
+	assert.Equal(t, "Hi", repo.messages[0].Content)
+	assert.Equal(t, domain.RoleAssistant, repo.messages[1].Role)
+	assert.Equal(t, "Hello there!", repo.messages[1].Content)
+}
+
+func TestService_ChatWithAIStream_NewConversationIDOnEveryDelta(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithProvider(repo, &fakeProvider{deltas: []string{"Hello", " there"}})
+
+	var conversationIDs []string
+	response, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil, func(conversationID, _ string) error {
+		conversationIDs = append(conversationIDs, conversationID)
+		return nil
+	})
+
+	require.NoError(t, err)
+	require.NotEmpty(t, response.ConversationID)
+	assert.Equal(t, []string{response.ConversationID, response.ConversationID}, conversationIDs)
+}
+
+func TestService_ChatWithAIStream_PersistsPartialResponse(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 0)
+	client := &fakeProvider{
+		deltas:    []string{"Once", " upon", " a"},
+		streamErr: errors.New("connection reset"),
+	}
+	svc := newTestServiceWithProvider(repo, client)
+
+	response, err := svc.ChatWithAIStream(context.Background(), testUserID, "Tell me a story", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil, func(_, delta string) error {
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Nil(t, response)
+	require.Len(t, repo.messages, 2)
+	assert.Equal(t, domain.RoleAssistant, repo.messages[1].Role)
+	assert.Equal(t, "Once upon a", repo.messages[1].Content)
+}
+
+func TestService_ChatWithAIStream_NoContent(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 0)
+	client := &fakeProvider{streamErr: errors.New("upstream unavailable")}
+	svc := newTestServiceWithProvider(repo, client)
+
+	response, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil, func(_, delta string) error {
+		return nil
+	})
+
+	assert.Nil(t, response)
+	require.Len(t, repo.messages, 1, "only the user message should be stored")
+	assert.Equal(t, domain.RoleUser, repo.messages[0].Role)
+
+	var aiErr *AIRequestError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, conversation.ID, aiErr.ConversationID)
+	assert.Equal(t, repo.messages[0].ID, aiErr.UserMessage.ID)
+	assert.Equal(t, ErrorTypeUnavailable, aiErr.Err.Type)
+}
+
+func TestService_ChatWithAI_IncludesHistoryInOrder(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 4)
+	client := &fakeProvider{response: completionResponse("Sure!", 42)}
+	svc := newTestServiceWithProvider(repo, client)
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "And now?", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{
+		{Role: "user", Content: "message 0"},
+		{Role: "assistant", Content: "message 1"},
+		{Role: "user", Content: "message 2"},
+		{Role: "assistant", Content: "message 3"},
+		{Role: "user", Content: "And now?"},
+	}, client.messages)
+}
+
+func TestService_ChatWithAI_EmptyHistory(t *testing.T) {
+	repo := newFakeRepository()
+	client := &fakeProvider{response: completionResponse("Hello!", 10)}
+	svc := newTestServiceWithProvider(repo, client)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", response.Message.Content)
+	assert.Equal(t, []llm.Message{{Role: "user", Content: "Hi"}}, client.messages)
+}
+
+func TestService_ChatWithAI_LimitsHistoryToContextWindow(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 15)
+	client := &fakeProvider{response: completionResponse("Ok", 10)}
+	svc := newTestServiceWithConfig(repo, client, &configs.Config{
+		OpenAIContextWindow:    3,
+		OpenAIContextMaxTokens: 4096,
+	})
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Latest", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{
+		{Role: "user", Content: "message 12"},
+		{Role: "assistant", Content: "message 13"},
+		{Role: "user", Content: "message 14"},
+		{Role: "user", Content: "Latest"},
+	}, client.messages)
+}
+
+func TestService_ChatWithAI_TrimsHistoryToTokenBudget(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 6)
+	client := &fakeProvider{response: completionResponse("Ok", 10)}
+	// Each seeded message estimates to 6 tokens; leave room for the new message and two of them
+	svc := newTestServiceWithConfig(repo, client, &configs.Config{
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 100 + estimateTokens("Latest") + 12,
+	})
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Latest", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []llm.Message{
+		{Role: "user", Content: "message 4"},
+		{Role: "assistant", Content: "message 5"},
+		{Role: "user", Content: "Latest"},
+	}, client.messages)
+}
+
+func TestService_ChatWithAI_ReturnsTokenUsage(t *testing.T) {
+	repo := newFakeRepository()
+	aiResponse := completionResponse("The answer is 42.", 30)
+	aiResponse.Usage.PromptTokens = 12
+	aiResponse.Usage.CompletionTokens = 18
+	client := &fakeProvider{response: aiResponse}
+	svc := newTestServiceWithProvider(repo, client)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "What is the answer?", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	require.NotNil(t, response.Usage)
+	assert.Equal(t, domain.TokenUsage{PromptTokens: 12, CompletionTokens: 18, TotalTokens: 30}, *response.Usage)
+}
+
+func TestService_ChatWithAI_StopsAtDeadline(t *testing.T) {
+	repo := newFakeRepository()
+	client := &fakeProvider{response: completionResponse("too late", 10), delay: 5 * time.Second}
+	svc := newTestServiceWithProvider(repo, client)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	response, err := svc.ChatWithAI(ctx, testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, response)
+	assert.Less(t, time.Since(start), time.Second, "the call must give up at the deadline")
+}
+
+func TestService_ChatWithAI_EmptyResponseByFinishReason(t *testing.T) {
+	tests := []struct {
+		name         string
+		finishReason string
+		wantErr      error
+	}{
+		{name: "content filter", finishReason: llm.FinishReasonContentFilter, wantErr: ErrAIResponseFiltered},
+		{name: "length", finishReason: llm.FinishReasonLength, wantErr: ErrAIResponseTruncated},
+		{name: "stop", finishReason: llm.FinishReasonStop, wantErr: ErrAIResponseEmpty},
+		{name: "no finish reason", finishReason: "", wantErr: ErrAIResponseEmpty},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			conversation := seedConversation(t, repo, testUserID, 0)
+			aiResponse := completionResponse("", 5)
+			aiResponse.FinishReason = tt.finishReason
+			svc := newTestServiceWithProvider(repo, &fakeProvider{response: aiResponse})
+
+			response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", conversation.ID, "gpt-3.5-turbo", 0.7, 100, nil)
+
+			assert.ErrorIs(t, err, tt.wantErr)
+			assert.Nil(t, response)
+			require.Len(t, repo.messages, 1, "only the user message should be stored")
+		})
+	}
+}
+
+func TestService_ChatWithAI_ProviderFailureKeepsUserMessage(t *testing.T) {
+	repo := newFakeRepository()
+	providerErr := errors.New("upstream unavailable")
+	svc := newTestServiceWithProvider(repo, &fakeProvider{err: providerErr})
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	assert.Nil(t, response)
+	require.Len(t, repo.messages, 1, "only the user message is stored")
+	userMsg := repo.messages[0]
+	assert.Equal(t, domain.RoleUser, userMsg.Role)
+
+	var aiErr *AIRequestError
+	require.ErrorAs(t, err, &aiErr)
+	assert.Equal(t, userMsg.ConversationID, aiErr.ConversationID)
+	assert.Equal(t, userMsg.ID, aiErr.UserMessage.ID)
+	assert.ErrorIs(t, err, providerErr)
+
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrorTypeUnavailable, appErr.Type, "the failure is reported as retryable")
+	assert.Equal(t, "AI_REQUEST_FAILED", appErr.Code)
+}
+
+func TestService_ChatWithAI_ProviderRateLimitCarriesRetryAfter(t *testing.T) {
+	rateLimited := &llm.APIError{Provider: "fake", StatusCode: http.StatusTooManyRequests, Body: "rate limited", RetryAfter: 7 * time.Second}
+
+	tests := []struct {
+		name string
+		call func(svc Service) error
+	}{
+		{
+			name: "ChatWithAI",
+			call: func(svc Service) error {
+				_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+				return err
+			},
+		},
+		{
+			name: "ChatWithAIStream",
+			call: func(svc Service) error {
+				_, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil, func(string, string) error { return nil })
+				return err
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			svc := newTestServiceWithProvider(repo, &fakeProvider{err: rateLimited, streamErr: rateLimited})
+
+			err := tt.call(svc)
+
+			var aiErr *AIRequestError
+			require.ErrorAs(t, err, &aiErr)
+			assert.Equal(t, 7*time.Second, aiErr.RetryAfter)
+			assert.Equal(t, repo.messages[0].ID, aiErr.UserMessage.ID)
+			assert.Equal(t, ErrorTypeRateLimited, aiErr.Err.Type)
+			assert.Equal(t, "AI_RATE_LIMITED", aiErr.Err.Code)
+			assert.ErrorIs(t, err, rateLimited)
+		})
+	}
+}
+
+func TestService_ChatWithAI_ProviderErrorsClassifiedByStatus(t *testing.T) {
+	tests := []struct {
+		status   int
+		wantType ErrorType
+		wantCode string
+	}{
+		{status: http.StatusBadRequest, wantType: ErrorTypeValidation, wantCode: "AI_REQUEST_INVALID"},
+		{status: http.StatusRequestEntityTooLarge, wantType: ErrorTypeValidation, wantCode: "AI_REQUEST_INVALID"},
+		{status: http.StatusUnauthorized, wantType: ErrorTypeRejected, wantCode: "AI_REQUEST_REJECTED"},
+		{status: http.StatusForbidden, wantType: ErrorTypeRejected, wantCode: "AI_REQUEST_REJECTED"},
+		{status: http.StatusNotFound, wantType: ErrorTypeRejected, wantCode: "AI_REQUEST_REJECTED"},
+		{status: http.StatusTooManyRequests, wantType: ErrorTypeRateLimited, wantCode: "AI_RATE_LIMITED"},
+		{status: http.StatusInternalServerError, wantType: ErrorTypeUnavailable, wantCode: "AI_REQUEST_FAILED"},
+		{status: http.StatusServiceUnavailable, wantType: ErrorTypeUnavailable, wantCode: "AI_REQUEST_FAILED"},
+	}
+
+	calls := map[string]func(svc Service) error{
+		"ChatWithAI": func(svc Service) error {
+			_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+			return err
+		},
+		"ChatWithAIStream": func(svc Service) error {
+			_, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil, func(string, string) error { return nil })
+			return err
+		},
+	}
+
+	for name, call := range calls {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s/%d", name, tt.status), func(t *testing.T) {
+				providerErr := &llm.APIError{Provider: "fake", StatusCode: tt.status, Body: "failed"}
+				repo := newFakeRepository()
+				svc := newTestServiceWithProvider(repo, &fakeProvider{err: providerErr, streamErr: providerErr})
+
+				err := call(svc)
+
+				var aiErr *AIRequestError
+				require.ErrorAs(t, err, &aiErr)
+				assert.Equal(t, repo.messages[0].ID, aiErr.UserMessage.ID)
+				assert.Equal(t, tt.wantType, aiErr.Err.Type)
+				assert.Equal(t, tt.wantCode, aiErr.Err.Code)
+				assert.ErrorIs(t, err, providerErr)
+			})
+		}
+	}
+}
+
+func TestService_ChatWithAI_RetryAfterProviderFailureUsesSameConversation(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{err: errors.New("upstream unavailable")}
+	svc := newTestServiceWithProvider(repo, provider)
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+	var aiErr *AIRequestError
+	require.ErrorAs(t, err, &aiErr)
+
+	provider.err = nil
+	provider.response = completionResponse("Hello!", 10)
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", aiErr.ConversationID, "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, aiErr.ConversationID, response.ConversationID)
+	assert.Len(t, repo.conversations, 1)
+}
+
+func TestService_ChatWithAI_ReturnsFinishReason(t *testing.T) {
+	repo := newFakeRepository()
+	aiResponse := completionResponse("The answer is", 30)
+	aiResponse.FinishReason = llm.FinishReasonLength
+	svc := newTestServiceWithProvider(repo, &fakeProvider{response: aiResponse})
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "What is the answer?", "", "gpt-3.5-turbo", 0.7, 5, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "The answer is", response.Message.Content)
+	assert.Equal(t, llm.FinishReasonLength, response.FinishReason)
+}
+
+func TestService_ChatWithAI_ValidatesGenerationOptions(t *testing.T) {
+	tests := []struct {
+		name        string
+		model       string
+		temperature float64
+		maxTokens   int
+		wantErr     string
+	}{
+		{name: "zero temperature", model: "gpt-3.5-turbo", temperature: 0, maxTokens: 100},
+		{name: "max temperature", model: "gpt-3.5-turbo", temperature: 2, maxTokens: 100},
+		{name: "one max token", model: "gpt-3.5-turbo", temperature: 0.7, maxTokens: 1},
+		{name: "model max tokens", model: "gpt-3.5-turbo", temperature: 0.7, maxTokens: 4096},
+		{name: "negative temperature", model: "gpt-3.5-turbo", temperature: -0.1, maxTokens: 100, wantErr: "temperature must be between 0 and 2 for model gpt-3.5-turbo"},
+		{name: "temperature above range", model: "gpt-3.5-turbo", temperature: 2.1, maxTokens: 100, wantErr: "temperature must be between 0 and 2 for model gpt-3.5-turbo"},
+		{name: "temperature above model range", model: "claude-3-5-haiku-latest", temperature: 1.5, maxTokens: 100, wantErr: "temperature must be between 0 and 1 for model claude-3-5-haiku-latest"},
+		{name: "zero max tokens", model: "gpt-3.5-turbo", temperature: 0.7, maxTokens: 0, wantErr: "max_tokens must be between 1 and 4096 for model gpt-3.5-turbo"},
+		{name: "negative max tokens", model: "gpt-3.5-turbo", temperature: 0.7, maxTokens: -1, wantErr: "max_tokens must be between 1 and 4096 for model gpt-3.5-turbo"},
+		{name: "max tokens above model limit", model: "gpt-3.5-turbo", temperature: 0.7, maxTokens: 4097, wantErr: "max_tokens must be between 1 and 4096 for model gpt-3.5-turbo"},
+		{name: "default model limit", model: "", temperature: 0.7, maxTokens: 4097, wantErr: "max_tokens must be between 1 and 4096 for model gpt-3.5-turbo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			client := &fakeProvider{response: completionResponse("Hello!", 10)}
+			svc := newTestServiceWithProvider(repo, client)
+
+			response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", tt.model, tt.temperature, tt.maxTokens, nil)
+
+			if tt.wantErr == "" {
+				require.NoError(t, err)
+				assert.Equal(t, "Hello!", response.Message.Content)
+				assert.Equal(t, tt.temperature, client.temperature)
+				assert.Equal(t, tt.maxTokens, client.maxTokens)
+				return
+			}
+			var appErr *AppError
+			require.ErrorAs(t, err, &appErr)
+			assert.Equal(t, ErrorTypeValidation, appErr.Type)
+			assert.Equal(t, tt.wantErr, appErr.Message)
+			assert.Zero(t, client.calls, "invalid options should not reach the provider")
+			assert.Empty(t, repo.messages, "invalid options should not store the user message")
+		})
+	}
+}
+
+func TestService_ChatWithAI_AllowedModels(t *testing.T) {
+	tests := []struct {
+		name          string
+		model         string
+		expectedModel string
+		wantErr       bool
+	}{
+		{name: "allowed model", model: "gpt-4o-mini", expectedModel: "gpt-4o-mini"},
+		{name: "disallowed model", model: "gpt-4", wantErr: true},
+		{name: "empty model uses the default", model: "", expectedModel: "gpt-3.5-turbo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			client := &fakeProvider{response: completionResponse("Hello!", 10)}
+			svc := newTestServiceWithConfig(repo, client, &configs.Config{
+				LLMProvider:            configs.OPENAI_PROVIDER,
+				OpenAIModel:            "gpt-3.5-turbo",
+				OpenAIContextWindow:    10,
+				OpenAIContextMaxTokens: 4096,
+				AllowedModels:          []string{"gpt-3.5-turbo", "gpt-4o-mini"},
+			})
+
+			_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", tt.model, 0.7, 100, nil)
+
+			if tt.wantErr {
+				var appErr *AppError
+				require.ErrorAs(t, err, &appErr)
+				assert.Equal(t, ErrorTypeValidation, appErr.Type)
+				assert.Equal(t, "MODEL_NOT_ALLOWED", appErr.Code)
+				assert.Equal(t, "model gpt-4 is not allowed; allowed models: gpt-3.5-turbo, gpt-4o-mini", appErr.Message)
+				assert.Zero(t, client.calls)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedModel, client.model)
+		})
+	}
+}
+
+func TestService_ChatWithAIStream_ValidatesGenerationOptions(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithProvider(repo, &fakeProvider{deltas: []string{"Hello"}})
+
+	_, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 9, 100, nil, func(_, delta string) error {
+		return nil
+	})
+
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "INVALID_TEMPERATURE", appErr.Code)
+	assert.Empty(t, repo.messages)
+}
+
+func TestService_ChatWithAI_StoresMetadataOnUserMessage(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithProvider(repo, &fakeProvider{response: completionResponse("Hello!", 10)})
+	metadata := domain.Metadata{"source": "mobile"}
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, metadata)
+
+	require.NoError(t, err)
+	require.Len(t, repo.messages, 2)
+	assert.Equal(t, metadata, repo.messages[0].Metadata)
+	assert.Nil(t, repo.messages[1].Metadata, "the assistant reply carries no client metadata")
+	assert.Nil(t, response.Message.Metadata)
+}
+
+func TestService_ChatWithAIStream_RejectsInvalidMetadata(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithProvider(repo, &fakeProvider{deltas: []string{"Hello"}})
+	metadata := domain.Metadata{"note": strings.Repeat("x", domain.MaxMetadataBytes)}
+
+	_, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, metadata, func(_, delta string) error {
+		return nil
+	})
+
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrorTypeValidation, appErr.Type)
+	assert.Equal(t, "INVALID_METADATA", appErr.Code)
+	assert.Empty(t, repo.messages)
+}
+
+// newModeratedTestService creates a service that checks AI chat messages with moderator
+func newModeratedTestService(repo *fakeRepository, provider llm.LLMProvider, moderator llm.Moderator) Service {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	return NewService(provider, logger, &configs.Config{
+		LLMProvider:            configs.OPENAI_PROVIDER,
+		OpenAIModel:            "gpt-3.5-turbo",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+		ModerationEnabled:      true,
+	}, repo, WithModerator(moderator))
+}
+
+func TestService_ChatWithAI_RejectsFlaggedMessage(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: completionResponse("Hello!", 10)}
+	moderator := &fakeModerator{blocked: map[string]string{"punch": "violence", "idiot": "harassment"}}
+	svc := newModeratedTestService(repo, provider, moderator)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "I will punch you, idiot", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrorTypeContentFiltered, appErr.Type)
+	assert.Equal(t, "MESSAGE_FLAGGED", appErr.Code)
+	assert.Equal(t, "message was flagged by content moderation: harassment, violence", appErr.Message)
+	assert.Nil(t, response)
+	assert.Empty(t, repo.messages, "a flagged message must not be stored")
+	assert.Empty(t, repo.conversations, "no conversation is started for a flagged message")
+	assert.Equal(t, 0, provider.calls, "a flagged message must not reach the provider")
+}
+
+func TestService_ChatWithAI_AcceptsCleanMessage(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: completionResponse("Hello!", 10)}
+	moderator := &fakeModerator{blocked: map[string]string{"punch": "violence"}}
+	svc := newModeratedTestService(repo, provider, moderator)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi there", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", response.Message.Content)
+	assert.Equal(t, []string{"Hi there"}, moderator.inputs)
+	assert.Equal(t, 1, provider.calls)
+	assert.Len(t, repo.messages, 2)
+}
+
+func TestService_ChatWithAIStream_RejectsFlaggedMessage(t *testing.T) {
+	repo := newFakeRepository()
+	moderator := &fakeModerator{blocked: map[string]string{"punch": "violence"}}
+	svc := newModeratedTestService(repo, &fakeProvider{deltas: []string{"Hello"}}, moderator)
+
+	_, err := svc.ChatWithAIStream(context.Background(), testUserID, "punch", "", "gpt-3.5-turbo", 0.7, 100, nil, func(_, delta string) error {
+		t.Fatal("no delta expected for a flagged message")
+		return nil
+	})
+
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, "MESSAGE_FLAGGED", appErr.Code)
+	assert.Empty(t, repo.messages)
+}
+
+func TestService_ChatWithAI_ModerationFailureStoresNothing(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: completionResponse("Hello!", 10)}
+	svc := newModeratedTestService(repo, provider, &fakeModerator{err: errors.New("moderation unavailable")})
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+
+	assert.ErrorContains(t, err, "failed to moderate message: moderation unavailable")
+	assert.Empty(t, repo.messages)
+	assert.Equal(t, 0, provider.calls)
+}
+
+// newWebhookTestService creates a service that emits events to a webhook answering with status
+func newWebhookTestService(t *testing.T, repo *fakeRepository, provider llm.LLMProvider, status int) (Service, *events.WebhookSink, chan events.Event) {
+	t.Helper()
+	received := make(chan events.Event, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event events.Event
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&event))
+		received <- event
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	sink := events.NewWebhookSink(srv.URL, "whsec-test", time.Second, 0, logger)
+	return NewService(provider, logger, &configs.Config{
+		LLMProvider:            configs.OPENAI_PROVIDER,
+		OpenAIModel:            "gpt-3.5-turbo",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+	}, repo, WithEventSink(sink)), sink, received
+}
+
+func TestService_ChatWithAI_EmitsAIMessageCreated(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: completionResponse("Hello!", 10)}
+	svc, sink, received := newWebhookTestService(t, repo, provider, http.StatusNoContent)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+	require.NoError(t, err)
+	require.NoError(t, sink.Wait(context.Background()))
+
+	require.Len(t, received, 1)
+	event := <-received
+	assert.Equal(t, events.TypeAIMessageCreated, event.Type)
+	assert.Equal(t, response.Message.ID, event.MessageID)
+	assert.Equal(t, response.ConversationID, event.ConversationID)
+	assert.Equal(t, testUserID, event.UserID)
+	assert.True(t, response.Message.CreatedAt.Equal(event.Timestamp))
+}
+
+func TestService_ChatWithAI_WebhookFailureDoesNotFailRequest(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: completionResponse("Hello!", 10)}
+	svc, sink, received := newWebhookTestService(t, repo, provider, http.StatusInternalServerError)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+	require.NoError(t, sink.Wait(context.Background()))
+
+	require.NoError(t, err)
+	assert.Equal(t, "Hello!", response.Message.Content)
+	assert.Len(t, repo.messages, 2)
+	assert.Len(t, received, 1)
+}
+
+func TestService_ChatWithAI_NoEventWhenReplyFails(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{err: errors.New("provider down")}
+	svc, sink, received := newWebhookTestService(t, repo, provider, http.StatusNoContent)
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-3.5-turbo", 0.7, 100, nil)
+	require.NoError(t, sink.Wait(context.Background()))
+
+	assert.Error(t, err)
+	assert.Empty(t, received)
+}
+
+func TestService_SendMessage_StoresMetadata(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+	metadata := domain.Metadata{"tags": []any{"draft"}}
+
+	response, err := svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello", Metadata: metadata})
+
+	require.NoError(t, err)
+	assert.Equal(t, metadata, response.Message.Metadata)
+	require.Len(t, repo.messages, 1)
+	assert.Equal(t, metadata, repo.messages[0].Metadata)
+}
+
+func TestService_EnforcesConfiguredMaxMessageLength(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithConfig(repo, &fakeProvider{}, &configs.Config{
+		LLMProvider:            configs.OPENAI_PROVIDER,
+		OpenAIModel:            "gpt-3.5-turbo",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+		MaxMessageLength:       5,
+	})
+	overlong := strings.Repeat("a", 6)
+	var validationErr *domain.ValidationError
+
+	_, err := svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: overlong})
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, map[string]string{"message": "too long (max 5 characters)"}, validationErr.Details())
+
+	_, err = svc.ChatWithAI(context.Background(), testUserID, overlong, "", "", 0.7, 100, nil)
+	assert.ErrorAs(t, err, &validationErr)
+
+	_, err = svc.ChatWithAIStream(context.Background(), testUserID, overlong, "", "", 0.7, 100, nil, func(string, string) error { return nil })
+	assert.ErrorAs(t, err, &validationErr)
+
+	assert.Empty(t, repo.conversations, "no conversation may be created for a rejected message")
+	assert.Empty(t, repo.messages)
+
+	_, err = svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: strings.Repeat("a", 5)})
+	assert.NoError(t, err)
+}
+
+func TestService_GetConversation(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	got, err := svc.GetConversation(context.Background(), testUserID, conversation.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, conversation.ID, got.ID)
+	assert.Equal(t, conversation.Title, got.Title)
+	assert.Equal(t, 3, got.MessageCount)
+	assert.NotNil(t, got.LastMessageAt)
+}
+
+func TestService_GetConversation_OtherUserNotFound(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	got, err := svc.GetConversation(context.Background(), otherUserID, conversation.ID)
+
+	// Another user's conversation is indistinguishable from a missing one
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Nil(t, got)
+}
+
+func TestService_DeleteConversation(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	other := seedConversation(t, repo, testUserID, 2)
+	svc := newTestService(repo)
+
+	err := svc.DeleteConversation(context.Background(), testUserID, conversation.ID)
+
+	require.NoError(t, err)
+	assert.NotContains(t, repo.conversations, conversation.ID)
+	assert.Contains(t, repo.conversations, other.ID)
+	require.Len(t, repo.messages, 2, "only messages of the deleted conversation should be removed")
+	for _, m := range repo.messages {
+		assert.Equal(t, other.ID, m.ConversationID)
+	}
+}
+
+func TestService_DeleteConversation_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	err := svc.DeleteConversation(context.Background(), otherUserID, conversation.ID)
+
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Contains(t, repo.conversations, conversation.ID)
+	assert.Len(t, repo.messages, 3)
+}
+
+func TestService_DeleteConversation_NotFound(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	err := svc.DeleteConversation(context.Background(), testUserID, "33333333-3333-3333-3333-333333333333")
+
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+}
+
+func TestService_DeleteConversation_ExcludedFromListAndCount(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	other := seedConversation(t, repo, testUserID, 2)
+	svc := newTestService(repo)
+
+	require.NoError(t, svc.DeleteConversation(context.Background(), testUserID, conversation.ID))
+
+	response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, response.Conversations, 1)
+	assert.Equal(t, other.ID, response.Conversations[0].ID)
+	assert.Equal(t, 1, response.Total)
+
+	// A deleted conversation no longer exists as far as its history is concerned
+	_, err = svc.GetHistory(context.Background(), &domain.GetHistoryRequest{UserID: testUserID, ConversationID: conversation.ID, Limit: 10})
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+}
+
+func TestService_ClearConversationMessages(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	other := seedConversation(t, repo, testUserID, 2)
+	updatedAt := conversation.UpdatedAt
+	svc := newTestService(repo)
+
+	deleted, err := svc.ClearConversationMessages(context.Background(), testUserID, conversation.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, 3, deleted)
+	require.Contains(t, repo.conversations, conversation.ID, "the conversation itself must remain")
+	assert.True(t, repo.conversations[conversation.ID].UpdatedAt.After(updatedAt))
+	require.Len(t, repo.messages, 2, "only messages of the cleared conversation should be removed")
+	for _, m := range repo.messages {
+		assert.Equal(t, other.ID, m.ConversationID)
+	}
+
+	got, err := svc.GetConversation(context.Background(), testUserID, conversation.ID)
+	require.NoError(t, err)
+	assert.Zero(t, got.MessageCount)
+}
+
+func TestService_ClearConversationMessages_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	deleted, err := svc.ClearConversationMessages(context.Background(), otherUserID, conversation.ID)
+
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Zero(t, deleted)
+	assert.Len(t, repo.messages, 3)
+}
+
+func TestService_RestoreConversation(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+	require.NoError(t, svc.DeleteConversation(context.Background(), testUserID, conversation.ID))
+
+	restored, err := svc.RestoreConversation(context.Background(), testUserID, conversation.ID)
+
+	require.NoError(t, err)
+	assert.Equal(t, conversation.ID, restored.ID)
+	response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Total)
+	history, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{UserID: testUserID, ConversationID: conversation.ID, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, history.Messages, 3)
+}
+
+func TestService_RestoreConversation_NotFound(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+	require.NoError(t, svc.DeleteConversation(context.Background(), testUserID, conversation.ID))
+
+	tests := []struct {
+		name           string
+		userID         string
+		conversationID string
+	}{
+		{name: "other user", userID: otherUserID, conversationID: conversation.ID},
+		{name: "never deleted", userID: testUserID, conversationID: seedConversation(t, repo, testUserID, 0).ID},
+		{name: "unknown", userID: testUserID, conversationID: "33333333-3333-3333-3333-333333333333"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.RestoreConversation(context.Background(), tt.userID, tt.conversationID)
+			assert.ErrorIs(t, err, ErrConversationNotFound)
+		})
+	}
+}
+
+// seedIdleConversation stores a conversation last updated at updatedAt whose only message,
+// when lastMessageAt is set, was sent at lastMessageAt
+func seedIdleConversation(t *testing.T, repo *fakeRepository, updatedAt time.Time, lastMessageAt *time.Time) *domain.Conversation {
+	t.Helper()
+	conversation := domain.NewConversation(testUserID, "Idle Conversation")
+	conversation.CreatedAt = updatedAt
+	conversation.UpdatedAt = updatedAt
+	_, err := repo.CreateConversation(context.Background(), conversation, 0)
+	require.NoError(t, err)
+
+	if lastMessageAt != nil {
+		msg := domaintest.NewMessage(testUserID, conversation.ID, "hello", "user")
+		msg.CreatedAt = *lastMessageAt
+		_, err := repo.CreateMessage(context.Background(), msg)
+		require.NoError(t, err)
+	}
+	return conversation
+}
+
+func TestArchiver_RunOnce_RespectsCutoff(t *testing.T) {
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	old := now.Add(-40 * 24 * time.Hour)
+	recent := now.Add(-24 * time.Hour)
+
+	repo := newFakeRepository()
+	idle := seedIdleConversation(t, repo, old, &old)
+	empty := seedIdleConversation(t, repo, old, nil)
+	recentMessage := seedIdleConversation(t, repo, old, &recent)
+	recentUpdate := seedIdleConversation(t, repo, recent, &old)
+
+	archiver := NewArchiver(repo, zlog.New(zlog.Config{Level: "error", Output: io.Discard}), 30*24*time.Hour, time.Hour)
+	archiver.now = func() time.Time { return now }
+
+	archived, err := archiver.RunOnce(context.Background())
+
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), archived)
+	require.Len(t, repo.archiveCutoffs, 1)
+	assert.True(t, now.Add(-30*24*time.Hour).Equal(repo.archiveCutoffs[0]))
+	assert.True(t, repo.conversations[idle.ID].Archived)
+	assert.True(t, repo.conversations[empty.ID].Archived)
+	assert.False(t, repo.conversations[recentMessage.ID].Archived)
+	assert.False(t, repo.conversations[recentUpdate.ID].Archived)
+}
+
+func TestArchiver_StartAndStop(t *testing.T) {
+	repo := newFakeRepository()
+	archiver := NewArchiver(repo, zlog.New(zlog.Config{Level: "error", Output: io.Discard}), 24*time.Hour, time.Hour)
+
+	archiver.Start(context.Background())
+	require.Eventually(t, func() bool {
+		repo.mu.Lock()
+		defer repo.mu.Unlock()
+		return len(repo.archiveCutoffs) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Stop returns once the job has exited and is safe to call again
+	archiver.Stop()
+	archiver.Stop()
+}
+
+func TestService_ListConversations_ExcludesArchived(t *testing.T) {
+	repo := newFakeRepository()
+	kept := seedConversation(t, repo, testUserID, 1)
+	archived := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	conversation, err := svc.ArchiveConversation(context.Background(), testUserID, archived.ID)
+	require.NoError(t, err)
+	assert.True(t, conversation.Archived)
+
+	response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 1, response.Total)
+	require.Len(t, response.Conversations, 1)
+	assert.Equal(t, kept.ID, response.Conversations[0].ID)
+
+	response, err = svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10, IncludeArchived: true})
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Total)
+
+	conversation, err = svc.UnarchiveConversation(context.Background(), testUserID, archived.ID)
+	require.NoError(t, err)
+	assert.False(t, conversation.Archived)
+
+	response, err = svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+	require.NoError(t, err)
+	assert.Equal(t, 2, response.Total)
+}
+
+func TestService_ListConversations_PinnedFirst(t *testing.T) {
+	repo := newFakeRepository()
+	oldest := seedConversation(t, repo, testUserID, 0)
+	middle := seedConversation(t, repo, testUserID, 0)
+	newest := seedConversation(t, repo, testUserID, 0)
+	base := time.Now().Add(-time.Hour)
+	repo.conversations[oldest.ID].UpdatedAt = base
+	repo.conversations[middle.ID].UpdatedAt = base.Add(time.Minute)
+	repo.conversations[newest.ID].UpdatedAt = base.Add(2 * time.Minute)
+	svc := newTestService(repo)
+
+	listIDs := func() []string {
+		response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+		require.NoError(t, err)
+		ids := make([]string, len(response.Conversations))
+		for i, conversation := range response.Conversations {
+			ids[i] = conversation.ID
+		}
+		return ids
+	}
+	assert.Equal(t, []string{newest.ID, middle.ID, oldest.ID}, listIDs())
+
+	// The least recently updated conversation is listed first once pinned
+	conversation, err := svc.PinConversation(context.Background(), testUserID, oldest.ID)
+	require.NoError(t, err)
+	assert.True(t, conversation.Pinned)
+	assert.Equal(t, []string{oldest.ID, newest.ID, middle.ID}, listIDs())
+
+	_, err = svc.PinConversation(context.Background(), testUserID, middle.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{middle.ID, oldest.ID, newest.ID}, listIDs())
+
+	conversation, err = svc.UnpinConversation(context.Background(), testUserID, oldest.ID)
+	require.NoError(t, err)
+	assert.False(t, conversation.Pinned)
+	assert.Equal(t, []string{middle.ID, newest.ID, oldest.ID}, listIDs())
+}
+
+func TestService_SetConversationFlag_OtherUser(t *testing.T) {
+	tests := []struct {
+		name  string
+		set   func(svc Service, ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+		flag  func(c *domain.Conversation) *bool
+		start bool
+	}{
+		{name: "archive", set: Service.ArchiveConversation, flag: func(c *domain.Conversation) *bool { return &c.Archived }},
+		{name: "unarchive", set: Service.UnarchiveConversation, flag: func(c *domain.Conversation) *bool { return &c.Archived }, start: true},
+		{name: "pin", set: Service.PinConversation, flag: func(c *domain.Conversation) *bool { return &c.Pinned }},
+		{name: "unpin", set: Service.UnpinConversation, flag: func(c *domain.Conversation) *bool { return &c.Pinned }, start: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			conversation := seedConversation(t, repo, testUserID, 1)
+			*tt.flag(repo.conversations[conversation.ID]) = tt.start
+			svc := newTestService(repo)
+
+			_, err := tt.set(svc, context.Background(), otherUserID, conversation.ID)
+
+			assert.ErrorIs(t, err, ErrConversationNotFound)
+			assert.Equal(t, tt.start, *tt.flag(repo.conversations[conversation.ID]))
+		})
+	}
+}
+
+func TestService_EditMessage(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 2)
+	svc := newTestService(repo)
+	messageID := repo.messages[0].ID
+
+	message, err := svc.EditMessage(context.Background(), testUserID, messageID, "edited")
+
+	require.NoError(t, err)
+	assert.Equal(t, "edited", message.Content)
+	assert.Equal(t, conversation.ID, message.ConversationID)
+	assert.Equal(t, "edited", repo.messages[0].Content)
+}
+
+func TestService_EditMessage_LengthCountsCharacters(t *testing.T) {
+	repo := newFakeRepository()
+	seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	// Multi-byte characters count once each towards the limit
+	content := strings.Repeat("é", domain.DefaultMaxMessageLength)
+	message, err := svc.EditMessage(context.Background(), testUserID, repo.messages[0].ID, content)
+
+	require.NoError(t, err)
+	assert.Equal(t, content, message.Content)
+}
+
+func TestService_EditMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		userID  string
+		index   int
+		content string
+		wantErr error
+	}{
+		{
+			name:    "message owned by another user",
+			userID:  otherUserID,
+			index:   0,
+			content: "edited",
+			wantErr: ErrMessageNotFound,
+		},
+		{
+			name:    "assistant message",
+			userID:  testUserID,
+			index:   1,
+			content: "edited",
+			wantErr: ErrAssistantMessageEdit,
+		},
+		{
+			name:    "empty content",
+			userID:  testUserID,
+			index:   0,
+			content: "",
+		},
+		{
+			name:    "content too long",
+			userID:  testUserID,
+			index:   0,
+			content: strings.Repeat("a", domain.DefaultMaxMessageLength+1),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			seedConversation(t, repo, testUserID, 2)
+			svc := newTestService(repo)
+			original := repo.messages[tt.index]
+
+			message, err := svc.EditMessage(context.Background(), tt.userID, original.ID, tt.content)
+
+			require.Error(t, err)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			}
+			assert.Nil(t, message)
+			assert.Equal(t, original.Content, repo.messages[tt.index].Content)
+		})
+	}
+}
+
+func TestService_EditMessage_NotFound(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	_, err := svc.EditMessage(context.Background(), testUserID, "44444444-4444-4444-4444-444444444444", "edited")
+
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+}
+
+func TestService_DeleteMessage(t *testing.T) {
+	repo := newFakeRepository()
+	seedConversation(t, repo, testUserID, 2)
+	svc := newTestService(repo)
+	messageID := repo.messages[0].ID
+
+	err := svc.DeleteMessage(context.Background(), testUserID, messageID)
+
+	require.NoError(t, err)
+	require.Len(t, repo.messages, 1)
+	assert.NotEqual(t, messageID, repo.messages[0].ID)
+}
+
+func TestService_DeleteMessage_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	seedConversation(t, repo, testUserID, 2)
+	svc := newTestService(repo)
+
+	err := svc.DeleteMessage(context.Background(), otherUserID, repo.messages[0].ID)
+
+	assert.ErrorIs(t, err, ErrMessageNotFound)
+	assert.Len(t, repo.messages, 2)
+}
+
+func TestService_SendMessage_IdempotencyKey(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithConfig(repo, &fakeProvider{}, &configs.Config{IdempotencyKeyTTLHours: 24})
+
+	req := &domain.ChatRequest{UserID: testUserID, Message: "hello", IdempotencyKey: "retry-1"}
+	first, err := svc.SendMessage(context.Background(), req)
+	require.NoError(t, err)
+	second, err := svc.SendMessage(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.Equal(t, first.Message.ID, second.Message.ID)
+	assert.Equal(t, first.ConversationID, second.ConversationID)
+	assert.Len(t, repo.messages, 1)
+	assert.Len(t, repo.conversations, 1, "a replay must not create another conversation")
+
+	// Another key is a new request
+	third, err := svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello", ConversationID: first.ConversationID, IdempotencyKey: "retry-2"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Message.ID, third.Message.ID)
+	assert.Len(t, repo.messages, 2)
+
+	// Keys are scoped to the user
+	conversation := seedConversation(t, repo, otherUserID, 0)
+	other, err := svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: otherUserID, Message: "hello", ConversationID: conversation.ID, IdempotencyKey: "retry-1"})
+	require.NoError(t, err)
+	assert.NotEqual(t, first.Message.ID, other.Message.ID)
+}
+
+func TestService_SendMessage_ExpiredIdempotencyKey(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestServiceWithConfig(repo, &fakeProvider{}, &configs.Config{IdempotencyKeyTTLHours: 0})
+
+	req := &domain.ChatRequest{UserID: testUserID, Message: "hello", IdempotencyKey: "retry-1"}
+	first, err := svc.SendMessage(context.Background(), req)
+	require.NoError(t, err)
+	second, err := svc.SendMessage(context.Background(), req)
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first.Message.ID, second.Message.ID)
+	assert.Len(t, repo.messages, 2)
+}
+
+func TestService_SendMessage_ConversationErrors(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	for _, idempotencyKey := range []string{"", "retry-1"} {
+		_, err := svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: otherUserID, Message: "hello", ConversationID: conversation.ID, IdempotencyKey: idempotencyKey})
+		assert.ErrorIs(t, err, ErrConversationNotFound)
+
+		_, err = svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello", ConversationID: "55555555-5555-5555-5555-555555555555", IdempotencyKey: idempotencyKey})
+		assert.ErrorIs(t, err, ErrConversationNotFound)
+
+		var appErr *AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, ErrorTypeNotFound, appErr.Type)
+	}
+	assert.Len(t, repo.messages, 1, "no message may be stored in a missing or foreign conversation")
+}
+
+func TestService_ChatWithAI_RejectsOtherUsersConversation(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 1)
+	svc := newTestService(repo)
+
+	_, err := svc.ChatWithAI(context.Background(), otherUserID, "Hi", conversation.ID, "", 0.7, 100, nil)
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+
+	_, err = svc.ChatWithAIStream(context.Background(), otherUserID, "Hi", conversation.ID, "", 0.7, 100, nil, func(string, string) error { return nil })
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+
+	assert.Len(t, repo.messages, 1)
+}
+
+func TestService_ImportConversation_PreservesOrder(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	conv, err := svc.ImportConversation(context.Background(), testUserID, "Imported", []domain.Message{
+		{Content: "be brief", Role: "system", CreatedAt: start},
+		{Content: "hello", Role: "user"},
+		{Content: "hi there", Role: "assistant"},
+		{Content: "bye", Role: "user", CreatedAt: start.Add(time.Minute)},
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "Imported", conv.Title)
+	assert.Equal(t, start, conv.CreatedAt)
+	assert.Equal(t, start.Add(time.Minute), conv.UpdatedAt)
+
+	messages, err := repo.GetMessagesByConversationID(context.Background(), conv.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, messages, 4)
+	for i, want := range []string{"be brief", "hello", "hi there", "bye"} {
+		assert.Equal(t, want, messages[i].Content)
+		assert.Equal(t, testUserID, messages[i].UserID)
+		assert.Equal(t, conv.ID, messages[i].ConversationID)
+		if i > 0 {
+			assert.True(t, messages[i].CreatedAt.After(messages[i-1].CreatedAt), "message %d must sort after message %d", i, i-1)
+		}
+	}
+}
+
+func TestService_ImportConversation_InvalidLeavesNoRows(t *testing.T) {
+	valid := domain.Message{Content: "hello", Role: "user"}
+	start := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		title    string
+		messages []domain.Message
+	}{
+		{name: "empty title", title: " ", messages: []domain.Message{valid}},
+		{name: "no messages", title: "Imported"},
+		{name: "empty content", title: "Imported", messages: []domain.Message{valid, {Role: "user"}}},
+		{name: "overlong content", title: "Imported", messages: []domain.Message{valid, {Content: strings.Repeat("a", domain.DefaultMaxMessageLength+1), Role: "user"}}},
+		{name: "unknown role", title: "Imported", messages: []domain.Message{valid, {Content: "hello", Role: "tool"}}},
+		{
+			name:  "timestamps out of order",
+			title: "Imported",
+			messages: []domain.Message{
+				{Content: "second", Role: "user", CreatedAt: start.Add(time.Minute)},
+				{Content: "first", Role: "user", CreatedAt: start},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeRepository()
+			svc := newTestService(repo)
+
+			_, err := svc.ImportConversation(context.Background(), testUserID, tt.title, tt.messages)
+
+			assert.ErrorIs(t, err, ErrInvalidImport)
+			assert.Empty(t, repo.conversations)
+			assert.Empty(t, repo.messages)
+		})
+	}
+}
+
+func TestService_ImportConversation_StorageFailure(t *testing.T) {
+	repo := newFakeRepository()
+	repo.importErr = errors.New("insert failed")
+	svc := newTestService(repo)
+
+	_, err := svc.ImportConversation(context.Background(), testUserID, "Imported", []domain.Message{
+		{Content: "hello", Role: "user"},
+		{Content: "hi there", Role: "assistant"},
+	})
+
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidImport)
+	assert.Empty(t, repo.conversations)
+	assert.Empty(t, repo.messages)
+}
+
+func TestService_ChatWithAI_UsesProviderDefaultModel(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: &llm.CompletionResponse{Content: "Hello!"}}
+	svc := newTestServiceWithConfig(repo, provider, &configs.Config{
+		LLMProvider:            configs.ANTHROPIC_PROVIDER,
+		OpenAIModel:            "gpt-3.5-turbo",
+		AnthropicModel:         "claude-3-5-haiku-latest",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+	})
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "claude-3-5-haiku-latest", provider.model)
+	assert.Equal(t, "claude-3-5-haiku-latest", response.Model)
+}
+
+func TestService_ChatWithAI_ReportsModelFromProvider(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{response: &llm.CompletionResponse{Content: "Hello!", Model: "gpt-4o-2024-08-06"}}
+	svc := newTestServiceWithProvider(repo, provider)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-4o", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "gpt-4o", provider.model)
+	assert.Equal(t, "gpt-4o-2024-08-06", response.Model)
+}
+
+// modelNotFound is the error providers return for a model that does not exist
+func modelNotFound(model string) error {
+	return &llm.APIError{Provider: "fake", StatusCode: 404, Body: fmt.Sprintf("The model `%s` does not exist", model)}
+}
+
+func newFallbackTestService(repo *fakeRepository, provider llm.LLMProvider, chain ...string) Service {
+	return newTestServiceWithConfig(repo, provider, &configs.Config{
+		LLMProvider:            configs.OPENAI_PROVIDER,
+		OpenAIModel:            "gpt-4o",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+		ModelFallbackChain:     chain,
+	})
+}
+
+func TestService_ChatWithAI_FallsBackToNextModel(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{
+		response:  &llm.CompletionResponse{Content: "Hello!"},
+		modelErrs: map[string]error{"gpt-4o": modelNotFound("gpt-4o")},
+	}
+	svc := newFallbackTestService(repo, provider, "gpt-4o", "gpt-4o-mini", "gpt-3.5-turbo")
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"gpt-4o", "gpt-4o-mini"}, provider.models)
+	assert.Equal(t, "gpt-4o-mini", response.Model)
+	assert.Equal(t, "Hello!", response.Message.Content)
+}
+
+func TestService_ChatWithAI_FallbackChainExhausted(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{
+		response: completionResponse("Hello!", 5),
+		modelErrs: map[string]error{
+			"gpt-4":       modelNotFound("gpt-4"),
+			"gpt-4o":      modelNotFound("gpt-4o"),
+			"gpt-4o-mini": modelNotFound("gpt-4o-mini"),
+		},
+	}
+	svc := newFallbackTestService(repo, provider, "gpt-4o", "gpt-4o-mini")
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "gpt-4", 0.7, 100, nil)
+
+	var aiErr *AIRequestError
+	require.ErrorAs(t, err, &aiErr)
+	assert.True(t, llm.IsModelUnavailable(err))
+	assert.Equal(t, []string{"gpt-4", "gpt-4o", "gpt-4o-mini"}, provider.models)
+}
+
+func TestService_ChatWithAI_NoFallbackForOtherErrors(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{
+		response:  completionResponse("Hello!", 5),
+		modelErrs: map[string]error{"gpt-4o": &llm.APIError{Provider: "fake", StatusCode: 503}},
+	}
+	svc := newFallbackTestService(repo, provider, "gpt-4o", "gpt-4o-mini")
+
+	_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil)
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"gpt-4o"}, provider.models)
+}
+
+func TestService_GetHistory_CursorPagesAreStableAcrossInserts(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 5)
+	svc := newTestService(repo)
+
+	// Two messages share a timestamp so the ID tie-breaker decides their order
+	tied := repo.messages[4].CreatedAt
+	for _, id := range []string{"ffffffff-ffff-ffff-ffff-ffffffffffff", "00000000-0000-0000-0000-000000000001"} {
+		msg := domaintest.NewMessage(testUserID, conversation.ID, "tied "+id[:1], "user")
+		msg.ID = id
+		msg.CreatedAt = tied
+		_, err := repo.CreateMessage(context.Background(), msg)
+		require.NoError(t, err)
+	}
+
+	var received []string
+	cursor := ""
+	for page := 0; ; page++ {
+		require.Less(t, page, 10, "pagination did not terminate")
+
+		response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+			UserID:         testUserID,
+			ConversationID: conversation.ID,
+			Limit:          2,
+			Cursor:         &cursor,
+		})
+		require.NoError(t, err)
+		for _, msg := range response.Messages {
+			received = append(received, msg.Content)
+		}
+
+		// New messages arriving between pages must not shift or repeat earlier rows
+		if page == 0 {
+			msg := domaintest.NewMessage(testUserID, conversation.ID, "late", "user")
+			_, err := repo.CreateMessage(context.Background(), msg)
+			require.NoError(t, err)
+		}
+
+		if response.NextCursor == "" {
+			break
+		}
+		cursor = response.NextCursor
+	}
+
+	assert.Equal(t, []string{"message 0", "message 1", "message 2", "message 3", "tied 0", "message 4", "tied f", "late"}, received)
+}
+
+func TestService_GetHistory_OffsetPagesHaveNoCursor(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 4)
+	svc := newTestService(repo)
+
+	response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+		UserID:         testUserID,
+		ConversationID: conversation.ID,
+		Limit:          2,
+	})
+
+	require.NoError(t, err)
+	assert.Len(t, response.Messages, 2)
+	assert.Equal(t, 4, response.Total)
+	assert.Empty(t, response.NextCursor)
+}
+
+func TestService_GetHistory_OwnershipGuard(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+	otherUserID := "99999999-9999-9999-9999-999999999999"
+
+	tests := []struct {
+		name           string
+		userID         string
+		conversationID string
+		expectedErr    error
+	}{
+		{"owner", testUserID, conversation.ID, nil},
+		{"other user", otherUserID, conversation.ID, ErrConversationNotFound},
+		{"missing conversation", testUserID, "88888888-8888-8888-8888-888888888888", ErrConversationNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			firstPage := ""
+			for _, cursor := range []*string{nil, &firstPage} {
+				response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+					UserID:         tt.userID,
+					ConversationID: tt.conversationID,
+					Limit:          10,
+					Cursor:         cursor,
+				})
+
+				if tt.expectedErr != nil {
+					assert.ErrorIs(t, err, tt.expectedErr)
+					assert.Nil(t, response, "no messages may leak to other users")
+					continue
+				}
+				require.NoError(t, err)
+				assert.Len(t, response.Messages, 3)
+			}
+		})
+	}
+}
+
+func TestService_GetHistory_PageInfo(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 4)
+	empty := seedConversation(t, repo, testUserID, 0)
+	svc := newTestService(repo)
+
+	tests := []struct {
+		name           string
+		conversationID string
+		offset         int
+		expected       domain.PageInfo
+	}{
+		{"first page", conversation.ID, 0, domain.PageInfo{Limit: 2, Offset: 0, HasMore: true, NextOffset: 2}},
+		{"last page ends on total", conversation.ID, 2, domain.PageInfo{Limit: 2, Offset: 2, HasMore: false}},
+		{"past the end", conversation.ID, 4, domain.PageInfo{Limit: 2, Offset: 4, HasMore: false}},
+		{"empty conversation", empty.ID, 0, domain.PageInfo{Limit: 2, Offset: 0, HasMore: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+				UserID:         testUserID,
+				ConversationID: tt.conversationID,
+				Limit:          2,
+				Offset:         tt.offset,
+			})
+
+			require.NoError(t, err)
+			require.NotNil(t, response.PageInfo)
+			assert.Equal(t, tt.expected, *response.PageInfo)
+		})
+	}
+}
+
+func TestService_GetHistory_CursorPageInfo(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 3)
+	svc := newTestService(repo)
+
+	cursor := ""
+	response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+		UserID:         testUserID,
+		ConversationID: conversation.ID,
+		Limit:          2,
+		Cursor:         &cursor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PageInfo{Limit: 2, HasMore: true}, *response.PageInfo)
+
+	response, err = svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+		UserID:         testUserID,
+		ConversationID: conversation.ID,
+		Limit:          2,
+		Cursor:         &response.NextCursor,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PageInfo{Limit: 2, HasMore: false}, *response.PageInfo)
+}
+
+func TestService_GetHistory_OffsetCap(t *testing.T) {
+	repo := newFakeRepository()
+	conversation := seedConversation(t, repo, testUserID, 4)
+	svc := newTestServiceWithConfig(repo, &fakeProvider{}, &configs.Config{MaxHistoryOffset: 2})
+
+	// An offset at the cap is still served
+	response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+		UserID:         testUserID,
+		ConversationID: conversation.ID,
+		Limit:          10,
+		Offset:         2,
+	})
+	require.NoError(t, err)
+	assert.Len(t, response.Messages, 2)
+
+	response, err = svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+		UserID:         testUserID,
+		ConversationID: conversation.ID,
+		Limit:          10,
+		Offset:         3,
+	})
+	assert.Nil(t, response)
+	var appErr *AppError
+	require.ErrorAs(t, err, &appErr)
+	assert.Equal(t, ErrorTypeValidation, appErr.Type)
+	assert.Equal(t, "HISTORY_OFFSET_TOO_LARGE", appErr.Code)
+	var validationErr *domain.ValidationError
+	require.ErrorAs(t, err, &validationErr)
+	assert.Equal(t, map[string]string{"offset": "must not exceed 2; use cursor to page further"}, validationErr.Details())
+}
+
+func TestService_GetHistory_ResponseBytesCap(t *testing.T) {
+	repo := newFakeRepository()
+	// Each seeded message is 9 bytes ("message N"), so two fit in a page
+	conversation := seedConversation(t, repo, testUserID, 5)
+	svc := newTestServiceWithConfig(repo, &fakeProvider{}, &configs.Config{MaxHistoryResponseBytes: 20})
+
+	t.Run("offset paging continues after the cut", func(t *testing.T) {
+		var received []string
+		offset := 0
+		for page := 0; ; page++ {
+			require.Less(t, page, 10, "pagination did not terminate")
+
+			response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+				UserID:         testUserID,
+				ConversationID: conversation.ID,
+				Limit:          10,
+				Offset:         offset,
+			})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(response.Messages), 2)
+			for _, msg := range response.Messages {
+				received = append(received, msg.Content)
+			}
+			if !response.PageInfo.HasMore {
+				break
+			}
+			offset = response.PageInfo.NextOffset
+		}
+		assert.Equal(t, []string{"message 0", "message 1", "message 2", "message 3", "message 4"}, received)
+	})
+
+	t.Run("cursor paging continues after the cut", func(t *testing.T) {
+		var received []string
+		cursor := ""
+		for page := 0; ; page++ {
+			require.Less(t, page, 10, "pagination did not terminate")
+
+			response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+				UserID:         testUserID,
+				ConversationID: conversation.ID,
+				Limit:          10,
+				Cursor:         &cursor,
+			})
+			require.NoError(t, err)
+			assert.LessOrEqual(t, len(response.Messages), 2)
+			for _, msg := range response.Messages {
+				received = append(received, msg.Content)
+			}
+			if response.NextCursor == "" {
+				break
+			}
+			cursor = response.NextCursor
+		}
+		assert.Equal(t, []string{"message 0", "message 1", "message 2", "message 3", "message 4"}, received)
+	})
+
+	t.Run("an oversized message is still returned", func(t *testing.T) {
+		large := seedConversation(t, repo, testUserID, 0)
+		msg := domaintest.NewMessage(testUserID, large.ID, strings.Repeat("x", 50), domain.RoleUser)
+		_, err := repo.CreateMessage(context.Background(), msg)
+		require.NoError(t, err)
+
+		response, err := svc.GetHistory(context.Background(), &domain.GetHistoryRequest{
+			UserID:         testUserID,
+			ConversationID: large.ID,
+			Limit:          10,
+		})
+		require.NoError(t, err)
+		assert.Len(t, response.Messages, 1)
+	})
+}
+
+func TestService_ListConversations_PageInfo(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	// No conversations yet
+	response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 2})
+	require.NoError(t, err)
+	assert.Empty(t, response.Conversations)
+	assert.Equal(t, domain.PageInfo{Limit: 2, Offset: 0, HasMore: false}, *response.PageInfo)
+
+	for i := 0; i < 3; i++ {
+		seedConversation(t, repo, testUserID, 0)
+	}
+
+	response, err = svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, domain.PageInfo{Limit: 2, Offset: 0, HasMore: true, NextOffset: 2}, *response.PageInfo)
+
+	response, err = svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 2, Offset: 2})
+	require.NoError(t, err)
+	assert.Len(t, response.Conversations, 1)
+	assert.Equal(t, domain.PageInfo{Limit: 2, Offset: 2, HasMore: false}, *response.PageInfo)
+	assert.Equal(t, 3, response.Total)
+}
+
+func TestService_ListConversations_CursorPagesAreStableAcrossInserts(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	base := time.Now().Add(-time.Hour)
+	for i := 0; i < 5; i++ {
+		conversation := domain.NewConversation(testUserID, fmt.Sprintf("conversation %d", i))
+		conversation.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		_, err := repo.CreateConversation(context.Background(), conversation, 0)
+		require.NoError(t, err)
+	}
+	seedConversation(t, repo, "99999999-9999-9999-9999-999999999999", 0)
+
+	var received []string
+	cursor := ""
+	for page := 0; ; page++ {
+		require.Less(t, page, 10, "pagination did not terminate")
+
+		response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{
+			UserID: testUserID,
+			Limit:  2,
+			Cursor: &cursor,
+		})
+		require.NoError(t, err)
+		for _, conversation := range response.Conversations {
+			received = append(received, conversation.Title)
+		}
+
+		if page == 0 {
+			_, err := svc.CreateConversation(context.Background(), testUserID, "newest")
+			require.NoError(t, err)
+		}
+
+		if response.NextCursor == "" {
+			break
+		}
+		cursor = response.NextCursor
+	}
+
+	assert.Equal(t, []string{"conversation 0", "conversation 1", "conversation 2", "conversation 3", "conversation 4", "newest"}, received)
+}
+
+func TestService_ListConversations_IncludesMessageActivity(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	active := seedConversation(t, repo, testUserID, 3)
+	empty := seedConversation(t, repo, testUserID, 0)
+
+	response, err := svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, response.Conversations, 2)
+
+	byID := make(map[string]*domain.Conversation)
+	for _, conversation := range response.Conversations {
+		byID[conversation.ID] = conversation
+	}
+
+	messages, err := repo.GetMessagesByConversationID(context.Background(), active.ID, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, 3, byID[active.ID].MessageCount)
+	require.NotNil(t, byID[active.ID].LastMessageAt)
+	assert.Equal(t, messages[2].CreatedAt, *byID[active.ID].LastMessageAt)
+
+	assert.Equal(t, 0, byID[empty.ID].MessageCount)
+	assert.Nil(t, byID[empty.ID].LastMessageAt)
+
+	// A new message moves the activity forward
+	_, err = svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, ConversationID: active.ID, Message: "one more"})
+	require.NoError(t, err)
+
+	response, err = svc.ListConversations(context.Background(), &domain.ListConversationsRequest{UserID: testUserID, Limit: 10})
+	require.NoError(t, err)
+	for _, conversation := range response.Conversations {
+		if conversation.ID == active.ID {
+			assert.Equal(t, 4, conversation.MessageCount)
+			assert.True(t, conversation.LastMessageAt.After(messages[2].CreatedAt))
+		}
+	}
+}
+
+func TestService_ListUserMessages_AcrossConversations(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	first := seedConversation(t, repo, testUserID, 3)
+	second := seedConversation(t, repo, testUserID, 2)
+	seedConversation(t, repo, "99999999-9999-9999-9999-999999999999", 4)
+
+	response, err := svc.ListUserMessages(context.Background(), testUserID, 10, 0)
+	require.NoError(t, err)
+
+	assert.Equal(t, 5, response.Total)
+	require.Len(t, response.Messages, 5)
+	conversations := make(map[string]int)
+	for _, message := range response.Messages {
+		assert.Equal(t, testUserID, message.UserID)
+		conversations[message.ConversationID]++
+	}
+	assert.Equal(t, map[string]int{first.ID: 3, second.ID: 2}, conversations)
+}
+
+func TestService_ListUserMessages_Pagination(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+	seedConversation(t, repo, testUserID, 5)
+
+	tests := []struct {
+		name     string
+		offset   int
+		count    int
+		expected domain.PageInfo
+	}{
+		{"first page", 0, 2, domain.PageInfo{Limit: 2, Offset: 0, HasMore: true, NextOffset: 2}},
+		{"last page", 4, 1, domain.PageInfo{Limit: 2, Offset: 4, HasMore: false}},
+		{"past the end", 6, 0, domain.PageInfo{Limit: 2, Offset: 6, HasMore: false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			response, err := svc.ListUserMessages(context.Background(), testUserID, 2, tt.offset)
+
+			require.NoError(t, err)
+			assert.Len(t, response.Messages, tt.count)
+			assert.Equal(t, 5, response.Total)
+			require.NotNil(t, response.PageInfo)
+			assert.Equal(t, tt.expected, *response.PageInfo)
+		})
+	}
+}
+
+func TestService_ListUserMessages_RejectsOtherUser(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+	other := "99999999-9999-9999-9999-999999999999"
+	seedConversation(t, repo, other, 2)
+
+	ctx := context.WithValue(context.Background(), "user_id", testUserID)
+	_, err := svc.ListUserMessages(ctx, other, 10, 0)
+
+	assert.ErrorIs(t, err, ErrUserMismatch)
+}
+
+func TestService_Search_OnlyReturnsOwnMessages(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+
+	own := seedConversation(t, repo, testUserID, 3)
+	seedConversation(t, repo, "99999999-9999-9999-9999-999999999999", 3)
+
+	response, err := svc.Search(context.Background(), &domain.SearchMessagesRequest{
+		UserID: testUserID,
+		Query:  "message",
+		Limit:  10,
+	})
+	require.NoError(t, err)
+
+	require.Len(t, response.Results, 3)
+	for _, result := range response.Results {
+		assert.Equal(t, testUserID, result.UserID)
+		assert.Equal(t, own.ID, result.ConversationID)
+	}
+}
+
+func TestService_Search_NoMatches(t *testing.T) {
+	repo := newFakeRepository()
+	svc := newTestService(repo)
+	seedConversation(t, repo, testUserID, 3)
+
+	response, err := svc.Search(context.Background(), &domain.SearchMessagesRequest{
+		UserID: testUserID,
+		Query:  "nothing like this",
+		Limit:  10,
+	})
+	require.NoError(t, err)
+	assert.Empty(t, response.Results)
+}
+
+// newAutoTitleService returns a service with AutoTitleEnabled and a function that
+// waits for its background title generations
+func newAutoTitleService(repo *fakeRepository, provider llm.LLMProvider) (Service, func()) {
+	svc := newTestServiceWithConfig(repo, provider, &configs.Config{
+		LLMProvider:            configs.OPENAI_PROVIDER,
+		OpenAIModel:            "gpt-3.5-turbo",
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+		AutoTitleEnabled:       true,
+	})
+	return svc, svc.(*service).titles.Wait
+}
+
+func TestService_ChatWithAI_GeneratesTitleForNewConversation(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{responses: []*llm.CompletionResponse{
+		completionResponse("Goroutines are lightweight threads.", 20),
+		completionResponse("\"Understanding Go Goroutines.\"", 5),
+	}}
+	svc, wait := newAutoTitleService(repo, provider)
+
+	response, err := svc.ChatWithAI(context.Background(), testUserID, "What is a goroutine?", "", "", 0.7, 100, nil)
+	require.NoError(t, err)
+	wait()
+
+	assert.Equal(t, 1, repo.titleUpdates)
+	assert.Equal(t, 2, provider.calls)
+	assert.Equal(t, "system", provider.messages[0].Role)
+	assert.Contains(t, provider.messages[1].Content, "What is a goroutine?")
+	assert.Contains(t, provider.messages[1].Content, "Goroutines are lightweight threads.")
+
+	conversation, err := repo.GetConversationByID(context.Background(), response.ConversationID, testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "Understanding Go Goroutines", conversation.Title)
+}
+
+func TestService_ChatWithAIStream_GeneratesTitleForNewConversation(t *testing.T) {
+	repo := newFakeRepository()
+	provider := &fakeProvider{deltas: []string{"Hello", " there"}, response: completionResponse("Friendly Greeting", 5)}
+	svc, wait := newAutoTitleService(repo, provider)
+
+	response, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil, func(string, string) error { return nil })
+	require.NoError(t, err)
+	wait()
+
+	assert.Equal(t, 1, repo.titleUpdates)
+	conversation, err := repo.GetConversationByID(context.Background(), response.ConversationID, testUserID)
+	require.NoError(t, err)
+	assert.Equal(t, "Friendly Greeting", conversation.Title)
+}
+
+func TestService_ChatWithAI_SkipsTitleGeneration(t *testing.T) {
+	t.Run("existing conversation", func(t *testing.T) {
+		repo := newFakeRepository()
+		conversation, err := newTestService(repo).CreateConversation(context.Background(), testUserID, "My Title")
+		require.NoError(t, err)
+		provider := &fakeProvider{response: completionResponse("Hello!", 5)}
+		svc, wait := newAutoTitleService(repo, provider)
+
+		_, err = svc.ChatWithAI(context.Background(), testUserID, "Hi", conversation.ID, "", 0.7, 100, nil)
+		require.NoError(t, err)
+		wait()
+
+		assert.Equal(t, 0, repo.titleUpdates)
+		assert.Equal(t, 1, provider.calls)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		repo := newFakeRepository()
+		provider := &fakeProvider{response: completionResponse("Hello!", 5)}
+		svc := newTestServiceWithProvider(repo, provider)
+
+		_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil)
+		require.NoError(t, err)
+
+		assert.Nil(t, svc.(*service).titles)
+		assert.Equal(t, 0, repo.titleUpdates)
+		assert.Equal(t, 1, provider.calls)
+	})
+}
+
+func TestCleanTitle(t *testing.T) {
+	tests := map[string]string{
+		"Go Concurrency Basics":                         "Go Concurrency Basics",
+		"  \"Planning a Trip to Japan.\"  ":             "Planning a Trip to Japan",
+		"**Debugging Memory Leaks**\nExtra explanation": "Debugging Memory Leaks",
+		"One two three four five six seven eight":       "One two three four five six",
+		"...": "",
+	}
+	for raw, want := range tests {
+		assert.Equal(t, want, cleanTitle(raw), raw)
+	}
+}
+
+func TestService_DailyTokenBudget(t *testing.T) {
+	budgetConfig := &configs.Config{
+		OpenAIContextWindow:    10,
+		OpenAIContextMaxTokens: 4096,
+		DailyTokenBudget:       100,
+	}
+	// Each chat below reserves the tokens of "Hi" plus its 30 max tokens
+	estimate := estimateTokens("Hi") + 30
+
+	t.Run("crossing the budget rejects the next chat", func(t *testing.T) {
+		repo := newFakeRepository()
+		provider := &fakeProvider{response: completionResponse("Hello!", 60)}
+		svc := newTestServiceWithConfig(repo, provider, budgetConfig)
+
+		// 60 tokens used, still under the budget
+		_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 60, repo.dailyUsage(testUserID), "the reservation is settled with the real usage")
+
+		// The estimate still fits, so it is served and its real usage takes the day to 120
+		_, err = svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 120, repo.dailyUsage(testUserID))
+
+		messagesBefore := len(repo.messages)
+		_, err = svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+		assert.ErrorIs(t, err, ErrDailyTokenBudgetExceeded)
+		var appErr *AppError
+		require.ErrorAs(t, err, &appErr)
+		assert.Equal(t, ErrorTypeQuotaExceeded, appErr.Type)
+		assert.Equal(t, 2, provider.calls, "the rejected chat must not reach the provider")
+		assert.Len(t, repo.messages, messagesBefore, "the rejected message is not stored")
+		assert.Equal(t, 120, repo.dailyUsage(testUserID), "the rejected chat reserves nothing")
+
+		// Other users have their own budget
+		_, err = svc.ChatWithAI(context.Background(), otherUserID, "Hi", "", "", 0.7, 30, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("an estimate over what is left is rejected", func(t *testing.T) {
+		repo := newFakeRepository()
+		repo.usage[usageKey(testUserID, time.Now())] = 100 - estimate + 1
+		provider := &fakeProvider{response: completionResponse("Hello!", 1)}
+		svc := newTestServiceWithConfig(repo, provider, budgetConfig)
+
+		_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+
+		assert.ErrorIs(t, err, ErrDailyTokenBudgetExceeded)
+		assert.Equal(t, 0, provider.calls)
+	})
+
+	t.Run("concurrent chats cannot overshoot the budget", func(t *testing.T) {
+		repo := newFakeRepository()
+		provider := &fakeProvider{response: completionResponse("Hello!", estimate)}
+		svc := newTestServiceWithConfig(repo, provider, budgetConfig)
+
+		const attempts = 10
+		errs := make(chan error, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		served := 0
+		for err := range errs {
+			if err == nil {
+				served++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrDailyTokenBudgetExceeded)
+		}
+		assert.Equal(t, 100/estimate, served)
+		assert.Equal(t, provider.calls, served)
+		assert.LessOrEqual(t, repo.dailyUsage(testUserID), 100)
+	})
+
+	t.Run("a failed chat gives its reservation back", func(t *testing.T) {
+		repo := newFakeRepository()
+		svc := newTestServiceWithConfig(repo, &fakeProvider{err: errors.New("provider down")}, budgetConfig)
+
+		_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+		require.Error(t, err)
+
+		assert.Equal(t, 0, repo.dailyUsage(testUserID))
+	})
+
+	t.Run("budget resets with the UTC day", func(t *testing.T) {
+		repo := newFakeRepository()
+		_, err := repo.AddDailyTokenUsage(context.Background(), testUserID, time.Now().UTC().AddDate(0, 0, -1), 500)
+		require.NoError(t, err)
+		svc := newTestServiceWithConfig(repo, &fakeProvider{response: completionResponse("Hello!", 10)}, budgetConfig)
+
+		_, err = svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil)
+		require.NoError(t, err)
+	})
+
+	t.Run("stream usage is estimated", func(t *testing.T) {
+		repo := newFakeRepository()
+		svc := newTestServiceWithConfig(repo, &fakeProvider{deltas: []string{"Hello", " there"}}, budgetConfig)
+
+		_, err := svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil, func(string, string) error { return nil })
+		require.NoError(t, err)
+
+		assert.Equal(t, estimateTokens("Hi")+estimateTokens("Hello there"), repo.dailyUsage(testUserID))
+
+		repo.usage[usageKey(testUserID, time.Now())] = 100
+		_, err = svc.ChatWithAIStream(context.Background(), testUserID, "Hi", "", "", 0.7, 30, nil, func(string, string) error { return nil })
+		assert.ErrorIs(t, err, ErrDailyTokenBudgetExceeded)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		repo := newFakeRepository()
+		svc := newTestServiceWithProvider(repo, &fakeProvider{response: completionResponse("Hello!", 1_000_000)})
+
+		for i := 0; i < 3; i++ {
+			_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil)
+			require.NoError(t, err)
+		}
+		assert.Empty(t, repo.usage, "usage is not tracked without a budget")
+	})
+}
+
+func TestService_ConversationQuota(t *testing.T) {
+	quotaConfig := &configs.Config{
+		OpenAIContextWindow:     10,
+		OpenAIContextMaxTokens:  4096,
+		MaxConversationsPerUser: 2,
+	}
+
+	t.Run("create conversation", func(t *testing.T) {
+		repo := newFakeRepository()
+		svc := newTestServiceWithConfig(repo, &fakeProvider{}, quotaConfig)
+
+		for i := 0; i < 2; i++ {
+			_, err := svc.CreateConversation(context.Background(), testUserID, "Allowed")
+			require.NoError(t, err)
+		}
+		_, err := svc.CreateConversation(context.Background(), testUserID, "Rejected")
+		assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+
+		// Other users have their own quota
+		_, err = svc.CreateConversation(context.Background(), otherUserID, "Allowed")
+		require.NoError(t, err)
+	})
+
+	t.Run("send message", func(t *testing.T) {
+		repo := newFakeRepository()
+		existing := seedConversation(t, repo, testUserID, 0)
+		svc := newTestServiceWithConfig(repo, &fakeProvider{}, quotaConfig)
+
+		_, err := svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello"})
+		require.NoError(t, err)
+		_, err = svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello"})
+		assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+		_, err = svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello", IdempotencyKey: "key"})
+		assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+
+		// Existing conversations can still be written to
+		_, err = svc.SendMessage(context.Background(), &domain.ChatRequest{UserID: testUserID, Message: "hello", ConversationID: existing.ID})
+		require.NoError(t, err)
+
+		count, err := repo.CountConversationsByUserID(context.Background(), testUserID, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("chat with AI", func(t *testing.T) {
+		repo := newFakeRepository()
+		seedConversation(t, repo, testUserID, 0)
+		seedConversation(t, repo, testUserID, 0)
+		provider := &fakeProvider{response: completionResponse("Hello!", 5)}
+		svc := newTestServiceWithConfig(repo, provider, quotaConfig)
+
+		_, err := svc.ChatWithAI(context.Background(), testUserID, "Hi", "", "", 0.7, 100, nil)
+
+		assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+		assert.Equal(t, 0, provider.calls)
+	})
+
+	t.Run("import conversation", func(t *testing.T) {
+		repo := newFakeRepository()
+		seedConversation(t, repo, testUserID, 0)
+		seedConversation(t, repo, testUserID, 0)
+		svc := newTestServiceWithConfig(repo, &fakeProvider{}, quotaConfig)
+
+		_, err := svc.ImportConversation(context.Background(), testUserID, "Imported", []domain.Message{{Content: "hello", Role: "user"}})
+
+		assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+	})
+
+	t.Run("concurrent creations", func(t *testing.T) {
+		repo := newFakeRepository()
+		svc := newTestServiceWithConfig(repo, &fakeProvider{}, quotaConfig)
+
+		const attempts = 10
+		errs := make(chan error, attempts)
+		var wg sync.WaitGroup
+		for i := 0; i < attempts; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				_, err := svc.CreateConversation(context.Background(), testUserID, "Racing")
+				errs <- err
+			}()
+		}
+		wg.Wait()
+		close(errs)
+
+		created := 0
+		for err := range errs {
+			if err == nil {
+				created++
+				continue
+			}
+			assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+		}
+		assert.Equal(t, 2, created)
+
+		count, err := repo.CountConversationsByUserID(context.Background(), testUserID, true)
+		require.NoError(t, err)
+		assert.Equal(t, 2, count)
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		repo := newFakeRepository()
+		svc := newTestService(repo)
+
+		for i := 0; i < 5; i++ {
+			_, err := svc.CreateConversation(context.Background(), testUserID, "Allowed")
+			require.NoError(t, err)
+		}
+	})
+}
@@ -0,0 +1,112 @@
+package chat
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"chat-service/internal/services/llm"
+	"chat-service/storage"
+	zlog "packages/logger"
+)
+
+const (
+	// defaultAIConversationTitle is the placeholder title of conversations started by an AI chat
+	defaultAIConversationTitle = "AI Chat"
+	// titleTimeout bounds a single background title generation
+	titleTimeout = 30 * time.Second
+	// titleMaxWords is the longest title kept from the provider reply
+	titleMaxWords = 6
+	// titleMaxTokens is the completion budget for a title
+	titleMaxTokens = 20
+)
+
+// titlePrompt instructs the provider to name a conversation
+const titlePrompt = "Write a short title of 3 to 6 words for the following conversation. Reply with the title only, without quotes or punctuation at the end."
+
+// TitleGenerator names freshly created conversations from their first exchange. Titles
+// are generated in the background so they never delay the chat response.
+type TitleGenerator struct {
+	provider llm.LLMProvider
+	storage  storage.Repository
+	logger   *zlog.Logger
+	model    string
+	wg       sync.WaitGroup
+}
+
+// NewTitleGenerator creates a title generator that asks provider for titles using model
+func NewTitleGenerator(provider llm.LLMProvider, storage storage.Repository, logger *zlog.Logger, model string) *TitleGenerator {
+	return &TitleGenerator{
+		provider: provider,
+		storage:  storage,
+		logger:   logger,
+		model:    model,
+	}
+}
+
+// GenerateAsync generates a title for the conversation in the background and stores it.
+// The request context is only used for its values, so the title is still stored after
+// the response has been sent.
+func (g *TitleGenerator) GenerateAsync(ctx context.Context, userID, conversationID, userContent, aiContent string) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), titleTimeout)
+		defer cancel()
+
+		if err := g.Generate(ctx, userID, conversationID, userContent, aiContent); err != nil {
+			g.logger.Warn(ctx, "Failed to generate conversation title", map[string]any{
+				"conversation_id": conversationID,
+				"error":           err.Error(),
+			})
+		}
+	}()
+}
+
+// Generate asks the provider for a title and stores it on the conversation
+func (g *TitleGenerator) Generate(ctx context.Context, userID, conversationID, userContent, aiContent string) error {
+	messages := []llm.Message{
+		{Role: "system", Content: titlePrompt},
+		{Role: "user", Content: fmt.Sprintf("User: %s\n\nAssistant: %s", userContent, aiContent)},
+	}
+
+	response, err := g.provider.ChatCompletion(ctx, messages, g.model, 0.3, titleMaxTokens)
+	if err != nil {
+		return fmt.Errorf("failed to get title: %w", err)
+	}
+
+	title := cleanTitle(response.Content)
+	if title == "" {
+		return fmt.Errorf("provider returned an empty title")
+	}
+
+	if _, err := g.storage.UpdateConversationTitle(ctx, conversationID, userID, title); err != nil {
+		return fmt.Errorf("failed to update conversation title: %w", err)
+	}
+
+	g.logger.Info(ctx, "Conversation title generated", map[string]any{
+		"conversation_id": conversationID,
+		"title":           title,
+	})
+
+	return nil
+}
+
+// Wait blocks until all background title generations have finished
+func (g *TitleGenerator) Wait() {
+	g.wg.Wait()
+}
+
+// cleanTitle strips quotes and trailing punctuation from a provider reply and keeps
+// at most titleMaxWords words of its first line
+func cleanTitle(raw string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(raw), "\n")
+	words := strings.Fields(strings.Trim(line, "\"'`*#. "))
+	if len(words) > titleMaxWords {
+		words = words[:titleMaxWords]
+	}
+	return strings.TrimRight(strings.Join(words, " "), ".,:;!?")
+}
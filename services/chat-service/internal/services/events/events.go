@@ -0,0 +1,31 @@
+// Package events notifies integrators of chat activity, such as a new AI reply
+package events
+
+import (
+	"context"
+	"time"
+)
+
+// TypeAIMessageCreated is the type of the event emitted once an AI reply is stored
+const TypeAIMessageCreated = "ai_message.created"
+
+// Event describes something that happened in a conversation
+type Event struct {
+	Type           string    `json:"type"`
+	MessageID      string    `json:"message_id"`
+	ConversationID string    `json:"conversation_id"`
+	UserID         string    `json:"user_id"`
+	Timestamp      time.Time `json:"timestamp"`
+}
+
+// EventSink receives chat events. Emit must not block the caller or report delivery
+// failures, so a broken integration never fails a chat request.
+type EventSink interface {
+	Emit(ctx context.Context, event Event)
+}
+
+// NoopSink drops every event; it is used when no webhook is configured
+type NoopSink struct{}
+
+// Emit discards the event
+func (NoopSink) Emit(ctx context.Context, event Event) {}
@@ -0,0 +1,151 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	zlog "packages/logger"
+)
+
+const (
+	// SignatureHeader carries the hex HMAC-SHA256 of the request body, keyed with the
+	// webhook secret and prefixed with "sha256="
+	SignatureHeader = "X-Webhook-Signature"
+	// EventTypeHeader carries the event type, so receivers can route without parsing the body
+	EventTypeHeader = "X-Webhook-Event"
+
+	// defaultRetryDelay is the backoff before the first retry; it doubles on every further retry
+	defaultRetryDelay = 500 * time.Millisecond
+)
+
+// WebhookSink POSTs every event as signed JSON to a webhook URL. Events are delivered in
+// the background; rate limits, server errors and network failures are retried with
+// exponential backoff, and an event that still cannot be delivered is logged and dropped.
+type WebhookSink struct {
+	url        string
+	secret     []byte
+	client     *http.Client
+	maxRetries int
+	retryDelay time.Duration
+	logger     *zlog.Logger
+	wg         sync.WaitGroup
+}
+
+// NewWebhookSink creates a sink that delivers events to url, signed with secret. Each
+// attempt is bounded by timeout and a failed delivery is retried up to maxRetries times.
+func NewWebhookSink(url, secret string, timeout time.Duration, maxRetries int, logger *zlog.Logger) *WebhookSink {
+	return &WebhookSink{
+		url:        url,
+		secret:     []byte(secret),
+		client:     &http.Client{Timeout: timeout},
+		maxRetries: maxRetries,
+		retryDelay: defaultRetryDelay,
+		logger:     logger,
+	}
+}
+
+// Emit delivers the event in the background. The request context is only used for its
+// values, so delivery continues after the response has been sent.
+func (s *WebhookSink) Emit(ctx context.Context, event Event) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+
+		ctx := context.WithoutCancel(ctx)
+		if err := s.deliver(ctx, event); err != nil {
+			s.logger.Warn(ctx, "Failed to deliver webhook event", map[string]any{
+				"event":           event.Type,
+				"message_id":      event.MessageID,
+				"conversation_id": event.ConversationID,
+				"error":           err.Error(),
+			})
+		}
+	}()
+}
+
+// Wait blocks until every background delivery has finished, or returns ctx's error if the
+// deadline passes first
+func (s *WebhookSink) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver posts the event, retrying failures that may succeed on a later attempt
+func (s *WebhookSink) deliver(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to marshal event: %w", err)
+	}
+	signature := Sign(s.secret, body)
+
+	delay := s.retryDelay
+	for attempt := 0; ; attempt++ {
+		retryable, err := s.post(ctx, event.Type, body, signature)
+		if err == nil {
+			return nil
+		}
+		if !retryable || attempt >= s.maxRetries {
+			return fmt.Errorf("giving up after %d attempts: %w", attempt+1, err)
+		}
+
+		s.logger.Debug(ctx, "Retrying webhook delivery", map[string]any{
+			"message_id": event.MessageID,
+			"attempt":    attempt + 1,
+			"delay_ms":   delay.Milliseconds(),
+			"error":      err.Error(),
+		})
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+// post makes a single delivery attempt and reports whether a failure is worth retrying
+func (s *WebhookSink) post(ctx context.Context, eventType string, body []byte, signature string) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventTypeHeader, eventType)
+	req.Header.Set(SignatureHeader, signature)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return true, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("webhook responded with status %d", resp.StatusCode)
+}
+
+// Sign returns the SignatureHeader value for body: "sha256=" followed by the hex
+// HMAC-SHA256 of body keyed with secret. Receivers recompute it to authenticate events.
+func Sign(secret, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSecret = "whsec-test"
+
+// webhookRecorder is a webhook receiver that answers with the queued statuses, then 204,
+// and records every request it was sent
+type webhookRecorder struct {
+	mu         sync.Mutex
+	statuses   []int
+	bodies     [][]byte
+	signatures []string
+	eventTypes []string
+}
+
+func (rec *webhookRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.bodies = append(rec.bodies, body)
+	rec.signatures = append(rec.signatures, r.Header.Get(SignatureHeader))
+	rec.eventTypes = append(rec.eventTypes, r.Header.Get(EventTypeHeader))
+
+	status := http.StatusNoContent
+	if len(rec.statuses) > 0 {
+		status, rec.statuses = rec.statuses[0], rec.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func (rec *webhookRecorder) attempts() int {
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	return len(rec.bodies)
+}
+
+// newTestSink starts a receiver answering with statuses and returns a sink pointed at it
+func newTestSink(t *testing.T, maxRetries int, statuses ...int) (*WebhookSink, *webhookRecorder) {
+	t.Helper()
+	rec := &webhookRecorder{statuses: statuses}
+	srv := httptest.NewServer(rec)
+	t.Cleanup(srv.Close)
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	sink := NewWebhookSink(srv.URL, testSecret, time.Second, maxRetries, logger)
+	sink.retryDelay = time.Millisecond
+	return sink, rec
+}
+
+func testEvent() Event {
+	return Event{
+		Type:           TypeAIMessageCreated,
+		MessageID:      "msg-1",
+		ConversationID: "conv-1",
+		UserID:         "user-1",
+		Timestamp:      time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestWebhookSink_PostsSignedEvent(t *testing.T) {
+	sink, rec := newTestSink(t, 3)
+
+	sink.Emit(context.Background(), testEvent())
+	require.NoError(t, sink.Wait(context.Background()))
+
+	require.Equal(t, 1, rec.attempts())
+	assert.JSONEq(t, `{
+		"type": "ai_message.created",
+		"message_id": "msg-1",
+		"conversation_id": "conv-1",
+		"user_id": "user-1",
+		"timestamp": "2024-05-01T12:00:00Z"
+	}`, string(rec.bodies[0]))
+	assert.Equal(t, TypeAIMessageCreated, rec.eventTypes[0])
+	assert.Equal(t, Sign([]byte(testSecret), rec.bodies[0]), rec.signatures[0])
+	assert.NotEqual(t, Sign([]byte("other-secret"), rec.bodies[0]), rec.signatures[0])
+
+	var event Event
+	require.NoError(t, json.Unmarshal(rec.bodies[0], &event))
+	assert.Equal(t, testEvent(), event)
+}
+
+func TestWebhookSink_RetriesTransientFailures(t *testing.T) {
+	sink, rec := newTestSink(t, 3, http.StatusServiceUnavailable, http.StatusTooManyRequests)
+
+	sink.Emit(context.Background(), testEvent())
+	require.NoError(t, sink.Wait(context.Background()))
+
+	require.Equal(t, 3, rec.attempts())
+	assert.Equal(t, rec.bodies[0], rec.bodies[2])
+	assert.Equal(t, rec.signatures[0], rec.signatures[2])
+}
+
+func TestWebhookSink_GivesUp(t *testing.T) {
+	tests := []struct {
+		name         string
+		statuses     []int
+		wantAttempts int
+	}{
+		{name: "retries exhausted", statuses: []int{500, 500, 500, 500}, wantAttempts: 3},
+		{name: "client error not retried", statuses: []int{http.StatusBadRequest}, wantAttempts: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sink, rec := newTestSink(t, 2, tt.statuses...)
+
+			sink.Emit(context.Background(), testEvent())
+			require.NoError(t, sink.Wait(context.Background()))
+
+			assert.Equal(t, tt.wantAttempts, rec.attempts())
+		})
+	}
+}
+
+func TestWebhookSink_EmitOutlivesRequestContext(t *testing.T) {
+	sink, rec := newTestSink(t, 0)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sink.Emit(ctx, testEvent())
+	cancel()
+	require.NoError(t, sink.Wait(context.Background()))
+
+	assert.Equal(t, 1, rec.attempts())
+}
@@ -0,0 +1,91 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned when a provider answers with a non-200 status
+type APIError struct {
+	Provider   string
+	StatusCode int
+	Body       string
+	// RetryAfter is the delay requested by the provider's Retry-After header, zero if absent
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%s API error: %s (status: %d)", e.Provider, e.Body, e.StatusCode)
+}
+
+// Retryable reports whether the request may succeed if sent again: rate limits and
+// server-side failures are transient, anything else (bad request, auth) is not
+func (e *APIError) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// ModelUnavailable reports whether the provider rejected the request because the model
+// does not exist or the API key has no access to it. Both OpenAI and Anthropic answer
+// such requests with 404.
+func (e *APIError) ModelUnavailable() bool {
+	return e.StatusCode == http.StatusNotFound
+}
+
+// InvalidRequest reports whether the provider rejected the request itself as malformed or
+// too large, such as a prompt over the model's context window
+func (e *APIError) InvalidRequest() bool {
+	switch e.StatusCode {
+	case http.StatusBadRequest, http.StatusRequestEntityTooLarge, http.StatusUnprocessableEntity:
+		return true
+	default:
+		return false
+	}
+}
+
+// RateLimited reports whether the provider rejected the request for exceeding its rate limits
+func (e *APIError) RateLimited() bool {
+	return e.StatusCode == http.StatusTooManyRequests
+}
+
+// IsModelUnavailable reports whether err is an APIError for an unavailable model
+func IsModelUnavailable(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.ModelUnavailable()
+}
+
+// IsRateLimited reports whether err is an APIError for a rate-limited request
+func IsRateLimited(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.RateLimited()
+}
+
+// RetryAfter returns the delay the provider asked for before err's request is retried, zero
+// if err is not an APIError or the provider gave none
+func RetryAfter(err error) time.Duration {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		return 0
+	}
+	return apiErr.RetryAfter
+}
+
+// ParseRetryAfter decodes a Retry-After header given either as seconds or as an HTTP
+// date. It returns zero when the header is missing, malformed or already in the past.
+func ParseRetryAfter(header string, now time.Time) time.Duration {
+	if header == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(header); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
@@ -0,0 +1,40 @@
+package llm
+
+import "strings"
+
+// ModelLimits are the generation options a model accepts
+type ModelLimits struct {
+	// MaxTemperature is the highest sampling temperature; the lowest is always 0
+	MaxTemperature float64
+	// MaxTokens is the largest completion the model can produce in one request
+	MaxTokens int
+}
+
+// modelLimits maps model name prefixes to their limits. More specific prefixes come first,
+// since the first match wins.
+var modelLimits = []struct {
+	prefix string
+	limits ModelLimits
+}{
+	{"gpt-4o", ModelLimits{MaxTemperature: 2, MaxTokens: 16384}},
+	{"gpt-4-turbo", ModelLimits{MaxTemperature: 2, MaxTokens: 4096}},
+	{"gpt-4", ModelLimits{MaxTemperature: 2, MaxTokens: 8192}},
+	{"gpt-3.5-turbo", ModelLimits{MaxTemperature: 2, MaxTokens: 4096}},
+	{"claude-3-5", ModelLimits{MaxTemperature: 1, MaxTokens: 8192}},
+	{"claude-3-7", ModelLimits{MaxTemperature: 1, MaxTokens: 64000}},
+	{"claude-", ModelLimits{MaxTemperature: 1, MaxTokens: 4096}},
+}
+
+// defaultModelLimits applies to models missing from the table
+var defaultModelLimits = ModelLimits{MaxTemperature: 2, MaxTokens: 4096}
+
+// LimitsForModel returns the generation limits of model, falling back to conservative
+// defaults for models it does not know
+func LimitsForModel(model string) ModelLimits {
+	for _, entry := range modelLimits {
+		if strings.HasPrefix(model, entry.prefix) {
+			return entry.limits
+		}
+	}
+	return defaultModelLimits
+}
@@ -0,0 +1,28 @@
+package llm
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimitsForModel(t *testing.T) {
+	tests := []struct {
+		model    string
+		expected ModelLimits
+	}{
+		{model: "gpt-3.5-turbo", expected: ModelLimits{MaxTemperature: 2, MaxTokens: 4096}},
+		{model: "gpt-4", expected: ModelLimits{MaxTemperature: 2, MaxTokens: 8192}},
+		{model: "gpt-4-turbo-preview", expected: ModelLimits{MaxTemperature: 2, MaxTokens: 4096}},
+		{model: "gpt-4o-mini", expected: ModelLimits{MaxTemperature: 2, MaxTokens: 16384}},
+		{model: "claude-3-5-haiku-latest", expected: ModelLimits{MaxTemperature: 1, MaxTokens: 8192}},
+		{model: "claude-3-opus-20240229", expected: ModelLimits{MaxTemperature: 1, MaxTokens: 4096}},
+		{model: "unknown-model", expected: defaultModelLimits},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.model, func(t *testing.T) {
+			assert.Equal(t, tt.expected, LimitsForModel(tt.model))
+		})
+	}
+}
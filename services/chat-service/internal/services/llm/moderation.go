@@ -0,0 +1,14 @@
+package llm
+
+import "context"
+
+// Moderator checks user input against a content policy before it is sent to a provider
+type Moderator interface {
+	Moderate(ctx context.Context, input string) (*ModerationResult, error)
+}
+
+// ModerationResult is the verdict of a Moderator
+type ModerationResult struct {
+	Flagged    bool
+	Categories []string // the categories the input was flagged for, sorted
+}
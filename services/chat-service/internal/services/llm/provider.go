@@ -0,0 +1,53 @@
+package llm
+
+import "context"
+
+// LLMProvider is implemented by every chat completion backend the service can talk to
+type LLMProvider interface {
+	// Name returns the provider identifier, matching the LLM_PROVIDER config value
+	Name() string
+	// ChatCompletion sends the conversation and returns the complete reply. An empty
+	// model selects the provider's configured default.
+	ChatCompletion(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int) (*CompletionResponse, error)
+	// ChatCompletionStream streams the completion, calling onDelta for every content
+	// chunk. It returns the content received so far, even when the stream fails.
+	ChatCompletionStream(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error)
+}
+
+// Pinger is implemented by providers that can cheaply check the upstream API is reachable
+// without running a completion
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Message represents a single chat message sent to a provider
+type Message struct {
+	Role    string `json:"role"` // "user", "assistant", "system"
+	Content string `json:"content"`
+}
+
+// Usage represents the tokens consumed by a completion
+type Usage struct {
+	PromptTokens     int
+	CompletionTokens int
+	TotalTokens      int
+}
+
+// Finish reasons reported in CompletionResponse. Providers map their own stop reasons
+// onto these; reasons without an equivalent are passed through unchanged.
+const (
+	// FinishReasonStop means the model finished its reply
+	FinishReasonStop = "stop"
+	// FinishReasonLength means the reply was cut off by the max tokens limit
+	FinishReasonLength = "length"
+	// FinishReasonContentFilter means the reply was withheld or cut off by a content filter
+	FinishReasonContentFilter = "content_filter"
+)
+
+// CompletionResponse represents a provider-independent completion result
+type CompletionResponse struct {
+	Content      string
+	Model        string
+	Usage        Usage
+	FinishReason string // one of the FinishReason constants, empty if not reported
+}
@@ -0,0 +1,135 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	zlog "packages/logger"
+)
+
+// RetryPolicy bounds how transient provider failures are retried
+type RetryPolicy struct {
+	// MaxRetries is the number of attempts made after the first one fails
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles on every further retry
+	BaseDelay time.Duration
+	// MaxDelay caps a single backoff, including one requested by Retry-After
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy returns the backoff used for provider calls with the given retry budget
+func DefaultRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries: maxRetries,
+		BaseDelay:  500 * time.Millisecond,
+		MaxDelay:   30 * time.Second,
+	}
+}
+
+// retryingProvider retries the calls of a provider that fail with a retryable APIError
+type retryingProvider struct {
+	LLMProvider
+	policy RetryPolicy
+	logger *zlog.Logger
+}
+
+// WithRetry wraps provider so that rate limits and server errors are retried with
+// exponential backoff and jitter. Retries stop once the policy's budget is spent or the
+// context deadline would pass before the next attempt.
+func WithRetry(provider LLMProvider, policy RetryPolicy, logger *zlog.Logger) LLMProvider {
+	if policy.MaxRetries <= 0 {
+		return provider
+	}
+	return &retryingProvider{
+		LLMProvider: provider,
+		policy:      policy,
+		logger:      logger,
+	}
+}
+
+// ChatCompletion sends the completion request, retrying transient failures
+func (p *retryingProvider) ChatCompletion(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int) (*CompletionResponse, error) {
+	var response *CompletionResponse
+	err := p.do(ctx, func() error {
+		var err error
+		response, err = p.LLMProvider.ChatCompletion(ctx, messages, model, temperature, maxTokens)
+		return err
+	})
+	return response, err
+}
+
+// ChatCompletionStream starts the stream, retrying transient failures. A provider only
+// returns an APIError before the first delta, so a retried attempt never repeats content.
+func (p *retryingProvider) ChatCompletionStream(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	var content string
+	err := p.do(ctx, func() error {
+		var err error
+		content, err = p.LLMProvider.ChatCompletionStream(ctx, messages, model, temperature, maxTokens, onDelta)
+		return err
+	})
+	return content, err
+}
+
+// Ping checks the wrapped provider once, without retrying, so health checks report an
+// unreachable API promptly. Providers that cannot be pinged are reported as reachable.
+func (p *retryingProvider) Ping(ctx context.Context) error {
+	if pinger, ok := p.LLMProvider.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// do runs call until it succeeds, fails with a non-retryable error or runs out of retries
+func (p *retryingProvider) do(ctx context.Context, call func() error) error {
+	for attempt := 0; ; attempt++ {
+		err := call()
+		if err == nil {
+			return nil
+		}
+
+		var apiErr *APIError
+		if !errors.As(err, &apiErr) || !apiErr.Retryable() || attempt >= p.policy.MaxRetries {
+			return err
+		}
+
+		delay := p.backoff(attempt, apiErr.RetryAfter)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		p.logger.Warn(ctx, "Retrying provider request after transient error", map[string]any{
+			"provider":    p.Name(),
+			"status_code": apiErr.StatusCode,
+			"attempt":     attempt + 1,
+			"delay_ms":    delay.Milliseconds(),
+		})
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w (retry aborted: %w)", err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay before the given retry: the provider's Retry-After when it
+// sent one, otherwise the exponential backoff with up to half of it removed as jitter
+func (p *retryingProvider) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := retryAfter
+	if delay <= 0 {
+		delay = p.policy.BaseDelay << attempt
+		if delay <= 0 || delay > p.policy.MaxDelay {
+			delay = p.policy.MaxDelay
+		}
+		delay -= rand.N(delay/2 + 1)
+	}
+	if p.policy.MaxDelay > 0 && delay > p.policy.MaxDelay {
+		delay = p.policy.MaxDelay
+	}
+	return delay
+}
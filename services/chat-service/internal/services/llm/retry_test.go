@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// flakyProvider fails with errs in turn, then succeeds, counting every attempt
+type flakyProvider struct {
+	errs     []error
+	attempts atomic.Int32
+}
+
+func (f *flakyProvider) Name() string { return "flaky" }
+
+func (f *flakyProvider) ChatCompletion(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int) (*CompletionResponse, error) {
+	if err := f.next(); err != nil {
+		return nil, err
+	}
+	return &CompletionResponse{Content: "ok"}, nil
+}
+
+func (f *flakyProvider) ChatCompletionStream(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	if err := f.next(); err != nil {
+		return "", err
+	}
+	if err := onDelta("ok"); err != nil {
+		return "", err
+	}
+	return "ok", nil
+}
+
+func (f *flakyProvider) next() error {
+	n := int(f.attempts.Add(1))
+	if n <= len(f.errs) {
+		return f.errs[n-1]
+	}
+	return nil
+}
+
+func testRetryPolicy(maxRetries int) RetryPolicy {
+	return RetryPolicy{MaxRetries: maxRetries, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond}
+}
+
+func newRetryingProvider(provider LLMProvider, policy RetryPolicy) LLMProvider {
+	return WithRetry(provider, policy, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+}
+
+func statusError(code int) *APIError {
+	return &APIError{Provider: "flaky", StatusCode: code}
+}
+
+func TestWithRetry_SucceedsAfterTransientFailures(t *testing.T) {
+	provider := &flakyProvider{errs: []error{statusError(http.StatusTooManyRequests), statusError(http.StatusBadGateway)}}
+
+	response, err := newRetryingProvider(provider, testRetryPolicy(3)).ChatCompletion(context.Background(), nil, "", 0.7, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", response.Content)
+	assert.Equal(t, int32(3), provider.attempts.Load())
+}
+
+func TestWithRetry_StreamSucceedsAfterTransientFailures(t *testing.T) {
+	provider := &flakyProvider{errs: []error{statusError(http.StatusServiceUnavailable), statusError(http.StatusInternalServerError)}}
+
+	var deltas []string
+	content, err := newRetryingProvider(provider, testRetryPolicy(3)).ChatCompletionStream(context.Background(), nil, "", 0.7, 100, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "ok", content)
+	assert.Equal(t, []string{"ok"}, deltas)
+	assert.Equal(t, int32(3), provider.attempts.Load())
+}
+
+func TestWithRetry_GivesUpAfterMaxRetries(t *testing.T) {
+	provider := &flakyProvider{errs: []error{
+		statusError(http.StatusInternalServerError),
+		statusError(http.StatusInternalServerError),
+		statusError(http.StatusInternalServerError),
+	}}
+
+	_, err := newRetryingProvider(provider, testRetryPolicy(2)).ChatCompletion(context.Background(), nil, "", 0.7, 100)
+
+	var apiErr *APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusInternalServerError, apiErr.StatusCode)
+	assert.Equal(t, int32(3), provider.attempts.Load())
+}
+
+func TestWithRetry_NonRetryableErrorsReturnImmediately(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{name: "bad request", err: statusError(http.StatusBadRequest)},
+		{name: "unauthorized", err: statusError(http.StatusUnauthorized)},
+		{name: "forbidden", err: statusError(http.StatusForbidden)},
+		{name: "not an API error", err: errors.New("failed to marshal request body")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := &flakyProvider{errs: []error{tt.err}}
+
+			_, err := newRetryingProvider(provider, testRetryPolicy(3)).ChatCompletion(context.Background(), nil, "", 0.7, 100)
+
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, int32(1), provider.attempts.Load())
+		})
+	}
+}
+
+func TestWithRetry_StopsWhenContextCancelled(t *testing.T) {
+	provider := &flakyProvider{errs: []error{statusError(http.StatusServiceUnavailable), statusError(http.StatusServiceUnavailable)}}
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Second, MaxDelay: time.Second}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+
+	begin := time.Now()
+	_, err := newRetryingProvider(provider, policy).ChatCompletion(ctx, nil, "", 0.7, 100)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Less(t, time.Since(begin), 500*time.Millisecond, "backoff must end when the context is cancelled")
+	assert.Equal(t, int32(1), provider.attempts.Load())
+}
+
+func TestWithRetry_DoesNotWaitPastDeadline(t *testing.T) {
+	rateLimited := &APIError{Provider: "flaky", StatusCode: http.StatusTooManyRequests, RetryAfter: 5 * time.Second}
+	provider := &flakyProvider{errs: []error{rateLimited}}
+	policy := RetryPolicy{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: time.Minute}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	_, err := newRetryingProvider(provider, policy).ChatCompletion(ctx, nil, "", 0.7, 100)
+
+	assert.ErrorIs(t, err, rateLimited)
+	assert.Less(t, time.Since(begin), 50*time.Millisecond, "a Retry-After beyond the deadline should fail fast")
+	assert.Equal(t, int32(1), provider.attempts.Load())
+}
+
+func TestWithRetry_HonorsRetryAfter(t *testing.T) {
+	rateLimited := &APIError{Provider: "flaky", StatusCode: http.StatusTooManyRequests, RetryAfter: 50 * time.Millisecond}
+	provider := &flakyProvider{errs: []error{rateLimited}}
+	policy := RetryPolicy{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Second}
+
+	begin := time.Now()
+	_, err := newRetryingProvider(provider, policy).ChatCompletion(context.Background(), nil, "", 0.7, 100)
+
+	require.NoError(t, err)
+	assert.GreaterOrEqual(t, time.Since(begin), 50*time.Millisecond)
+	assert.Equal(t, int32(2), provider.attempts.Load())
+}
+
+func TestWithRetry_DisabledReturnsProvider(t *testing.T) {
+	provider := &flakyProvider{}
+
+	assert.Same(t, provider, newRetryingProvider(provider, testRetryPolicy(0)))
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("", now))
+	assert.Equal(t, 3*time.Second, ParseRetryAfter("3", now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("-1", now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter("soon", now))
+	assert.Equal(t, 10*time.Second, ParseRetryAfter(now.Add(10*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), ParseRetryAfter(now.Add(-time.Minute).Format(http.TimeFormat), now))
+}
@@ -0,0 +1,81 @@
+package llm
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName names the tracer of the provider spans
+const tracerName = "chat-service"
+
+// tracingProvider records a client span around every call of the wrapped provider
+type tracingProvider struct {
+	LLMProvider
+}
+
+// WithTracing wraps provider so that each completion is recorded as a span, a child of
+// the span active in the caller's context
+func WithTracing(provider LLMProvider) LLMProvider {
+	return &tracingProvider{LLMProvider: provider}
+}
+
+// ChatCompletion sends the completion request inside a span
+func (p *tracingProvider) ChatCompletion(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int) (*CompletionResponse, error) {
+	ctx, span := p.start(ctx, "llm.ChatCompletion", model, len(messages))
+	defer span.End()
+
+	response, err := p.LLMProvider.ChatCompletion(ctx, messages, model, temperature, maxTokens)
+	if err != nil {
+		recordError(span, err)
+		return nil, err
+	}
+
+	span.SetAttributes(
+		attribute.String("llm.response.model", response.Model),
+		attribute.Int("llm.usage.prompt_tokens", response.Usage.PromptTokens),
+		attribute.Int("llm.usage.completion_tokens", response.Usage.CompletionTokens),
+	)
+	return response, nil
+}
+
+// ChatCompletionStream streams the completion inside a span that ends with the stream
+func (p *tracingProvider) ChatCompletionStream(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	ctx, span := p.start(ctx, "llm.ChatCompletionStream", model, len(messages))
+	defer span.End()
+
+	content, err := p.LLMProvider.ChatCompletionStream(ctx, messages, model, temperature, maxTokens, onDelta)
+	if err != nil {
+		recordError(span, err)
+	}
+	return content, err
+}
+
+// Ping forwards to the wrapped provider without a span; health checks would only add noise
+func (p *tracingProvider) Ping(ctx context.Context) error {
+	if pinger, ok := p.LLMProvider.(Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+// start opens a client span describing the request
+func (p *tracingProvider) start(ctx context.Context, name, model string, messageCount int) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("llm.provider", p.Name()),
+			attribute.String("llm.request.model", model),
+			attribute.Int("llm.request.messages", messageCount),
+		),
+	)
+}
+
+// recordError marks span as failed with err
+func recordError(span trace.Span, err error) {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
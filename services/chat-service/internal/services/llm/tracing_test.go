@@ -0,0 +1,42 @@
+package llm
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestWithTracing_RecordsChildSpan(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	previous := otel.GetTracerProvider()
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	otel.SetTracerProvider(tp)
+
+	provider := WithTracing(&flakyProvider{errs: []error{statusError(http.StatusBadRequest)}})
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	_, err := provider.ChatCompletion(ctx, []Message{{Role: "user", Content: "hi"}}, "gpt-test", 0.5, 10)
+	parent.End()
+	require.Error(t, err)
+
+	_, err = provider.ChatCompletion(ctx, []Message{{Role: "user", Content: "hi"}}, "gpt-test", 0.5, 10)
+	require.NoError(t, err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+
+	failed, succeeded := spans[0], spans[2]
+	assert.Equal(t, "llm.ChatCompletion", failed.Name())
+	assert.Equal(t, parent.SpanContext().SpanID(), failed.Parent().SpanID())
+	assert.Equal(t, parent.SpanContext().TraceID(), failed.SpanContext().TraceID())
+	assert.Equal(t, codes.Error, failed.Status().Code)
+	assert.Equal(t, codes.Unset, succeeded.Status().Code)
+}
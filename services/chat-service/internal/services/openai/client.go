@@ -1,44 +1,47 @@
 package openai
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"chat-service/configs"
+	"chat-service/internal/services/llm"
 	zlog "packages/logger"
 )
 
-// Client represents an OpenAI API client
-type Client interface {
-	ChatCompletion(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int) (*ChatCompletionResponse, error)
-}
-
-// client implements the OpenAI API client
+// client implements llm.LLMProvider against the OpenAI chat completions API
 type client struct {
 	apiKey       string
 	baseURL      string
+	apiVersion   string // api-version query parameter required by Azure OpenAI; empty for OpenAI
 	httpClient   *http.Client
 	logger       *zlog.Logger
 	defaultModel string
-}
 
-// Message represents a chat message for OpenAI
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+	// logSensitiveData logs prompt and completion text; without it only counts and
+	// token usage are logged
+	logSensitiveData bool
+	// logRequestHeaders logs the request headers, with the API key redacted
+	logRequestHeaders bool
+	// logResponseBody logs the raw response body, which needs logSensitiveData as well
+	logResponseBody bool
 }
 
 // ChatCompletionRequest represents the request to OpenAI
 type ChatCompletionRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Temperature float64   `json:"temperature"`
-	MaxTokens   int       `json:"max_tokens"`
+	Model       string        `json:"model"`
+	Messages    []llm.Message `json:"messages"`
+	Temperature float64       `json:"temperature"`
+	MaxTokens   int           `json:"max_tokens"`
+	Stream      bool          `json:"stream,omitempty"`
 }
 
 // ChatCompletionResponse represents the response from OpenAI
@@ -62,21 +65,123 @@ type ChatCompletionResponse struct {
 	} `json:"usage"`
 }
 
+// ChatCompletionChunk represents a single server-sent event of a streamed completion
+type ChatCompletionChunk struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Choices []struct {
+		Index int `json:"index"`
+		Delta struct {
+			Role    string `json:"role"`
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+}
+
+// streamDoneMarker is the payload OpenAI sends to terminate a stream
+const streamDoneMarker = "[DONE]"
+
 // NewClient creates a new OpenAI client
-func NewClient(cfg *configs.Config, logger *zlog.Logger) Client {
+func NewClient(cfg *configs.Config, logger *zlog.Logger) llm.LLMProvider {
+	return newClient(cfg, logger)
+}
+
+// defaultBaseURL is the OpenAI API used when OPENAI_BASE_URL is not set
+const defaultBaseURL = "https://api.openai.com/v1"
+
+// newClient creates the client shared by the completion and moderation APIs
+func newClient(cfg *configs.Config, logger *zlog.Logger) *client {
+	baseURL := strings.TrimSuffix(cfg.OpenAIBaseURL, "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+
 	return &client{
 		apiKey:       cfg.OpenAIAPIKey,
-		baseURL:      "https://api.openai.com/v1",
+		baseURL:      baseURL,
+		apiVersion:   cfg.OpenAIAPIVersion,
 		defaultModel: cfg.OpenAIModel,
 		httpClient: &http.Client{
 			Timeout: time.Duration(cfg.OpenAITimeout) * time.Second,
 		},
-		logger: logger,
+		logger:            logger,
+		logSensitiveData:  cfg.LogSensitiveData,
+		logRequestHeaders: cfg.LogRequestHeaders,
+		logResponseBody:   cfg.LogResponseBody,
+	}
+}
+
+// endpoint returns the URL of the API path under the base URL, with the API version
+// query parameter when one is configured
+func (c *client) endpoint(path string) string {
+	return c.withAPIVersion(c.baseURL + path)
+}
+
+// withAPIVersion adds the API version query parameter to rawURL when one is configured
+func (c *client) withAPIVersion(rawURL string) string {
+	if c.apiVersion == "" {
+		return rawURL
 	}
+	return rawURL + "?api-version=" + url.QueryEscape(c.apiVersion)
+}
+
+// modelsEndpoint returns the URL Ping lists the models from. Azure OpenAI lists them per
+// resource rather than per deployment, so the deployment part of the base URL is dropped.
+func (c *client) modelsEndpoint() string {
+	if c.apiVersion == "" {
+		return c.endpoint("/models")
+	}
+	base := c.baseURL
+	if i := strings.LastIndex(base, "/deployments/"); i >= 0 {
+		base = base[:i]
+	}
+	return c.withAPIVersion(base + "/models")
+}
+
+// authorize adds the API key to req. Azure OpenAI, selected by an API version being
+// configured, reads it from the api-key header; OpenAI reads it as a bearer token.
+func (c *client) authorize(req *http.Request) {
+	if c.apiVersion != "" {
+		req.Header.Set("api-key", c.apiKey)
+		return
+	}
+	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+}
+
+// Name returns the provider identifier
+func (c *client) Name() string {
+	return configs.OPENAI_PROVIDER
+}
+
+// Ping lists the available models, which checks both reachability and the API key
+// without spending tokens
+func (c *client) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.modelsEndpoint(), nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp, body)
+	}
+	return nil
 }
 
 // ChatCompletion sends a chat completion request to OpenAI
-func (c *client) ChatCompletion(ctx context.Context, messages []Message, model string, temperature float64, maxTokens int) (*ChatCompletionResponse, error) {
+func (c *client) ChatCompletion(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int) (*llm.CompletionResponse, error) {
 	if model == "" {
 		model = c.defaultModel
 	}
@@ -93,20 +198,15 @@ func (c *client) ChatCompletion(ctx context.Context, messages []Message, model s
 		return nil, fmt.Errorf("failed to marshal request body: %w", err)
 	}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", c.baseURL+"/chat/completions", bytes.NewBuffer(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint("/chat/completions"), bytes.NewBuffer(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+c.apiKey)
+	c.authorize(req)
 
-	c.logger.Debug(ctx, "Sending request to OpenAI", map[string]any{
-		"model":       model,
-		"temperature": temperature,
-		"max_tokens":  maxTokens,
-		"messages":    len(messages),
-	})
+	c.logger.Debug(ctx, "Sending request to OpenAI", c.requestFields(req, messages, model, temperature, maxTokens))
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -121,7 +221,7 @@ func (c *client) ChatCompletion(ctx context.Context, messages []Message, model s
 
 	if resp.StatusCode != http.StatusOK {
 		c.logger.Error(ctx, fmt.Errorf("OpenAI API error: %s", string(body)), "OpenAI API returned non-200 status", resp.StatusCode)
-		return nil, fmt.Errorf("OpenAI API error: %s (status: %d)", string(body), resp.StatusCode)
+		return nil, newAPIError(resp, body)
 	}
 
 	var response ChatCompletionResponse
@@ -129,13 +229,186 @@ func (c *client) ChatCompletion(ctx context.Context, messages []Message, model s
 		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
 
-	c.logger.Debug(ctx, "Received response from OpenAI", map[string]any{
-		"model":        response.Model,
-		"total_tokens": response.Usage.TotalTokens,
-		"choices":      len(response.Choices),
-	})
+	fields := map[string]any{
+		"model":             response.Model,
+		"prompt_tokens":     response.Usage.PromptTokens,
+		"completion_tokens": response.Usage.CompletionTokens,
+		"total_tokens":      response.Usage.TotalTokens,
+		"choices":           len(response.Choices),
+		"finish_reason":     response.GetFirstChoiceFinishReason(),
+	}
+	if c.logSensitiveData {
+		fields["content"] = response.GetFirstChoiceContent()
+		if c.logResponseBody {
+			fields["response_body"] = string(body)
+		}
+	}
+	c.logger.Debug(ctx, "Received response from OpenAI", fields)
 
-	return &response, nil
+	return &llm.CompletionResponse{
+		Content:      response.GetFirstChoiceContent(),
+		FinishReason: response.GetFirstChoiceFinishReason(),
+		Model:        response.Model,
+		Usage: llm.Usage{
+			PromptTokens:     response.Usage.PromptTokens,
+			CompletionTokens: response.Usage.CompletionTokens,
+			TotalTokens:      response.GetTotalTokens(),
+		},
+	}, nil
+}
+
+// ChatCompletionStream sends a streaming chat completion request to OpenAI
+func (c *client) ChatCompletionStream(ctx context.Context, messages []llm.Message, model string, temperature float64, maxTokens int, onDelta func(delta string) error) (string, error) {
+	if model == "" {
+		model = c.defaultModel
+	}
+
+	requestBody := ChatCompletionRequest{
+		Model:       model,
+		Messages:    messages,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+		Stream:      true,
+	}
+
+	jsonBody, err := json.Marshal(requestBody)
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint("/chat/completions"), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "text/event-stream")
+	c.authorize(req)
+
+	c.logger.Debug(ctx, "Sending streaming request to OpenAI", c.requestFields(req, messages, model, temperature, maxTokens))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		c.logger.Error(ctx, fmt.Errorf("OpenAI API error: %s", string(body)), "OpenAI API returned non-200 status", resp.StatusCode)
+		return "", newAPIError(resp, body)
+	}
+
+	var content strings.Builder
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if !strings.HasPrefix(line, "data:") {
+			continue
+		}
+
+		data := strings.TrimSpace(strings.TrimPrefix(line, "data:"))
+		if data == streamDoneMarker {
+			fields := map[string]any{
+				"model":          model,
+				"content_length": content.Len(),
+			}
+			if c.logSensitiveData {
+				fields["content"] = content.String()
+			}
+			c.logger.Debug(ctx, "OpenAI stream completed", fields)
+			return content.String(), nil
+		}
+
+		var chunk ChatCompletionChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return content.String(), fmt.Errorf("failed to unmarshal stream chunk: %w", err)
+		}
+		if len(chunk.Choices) == 0 || chunk.Choices[0].Delta.Content == "" {
+			continue
+		}
+
+		delta := chunk.Choices[0].Delta.Content
+		content.WriteString(delta)
+		if err := onDelta(delta); err != nil {
+			return content.String(), fmt.Errorf("failed to handle stream delta: %w", err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return content.String(), fmt.Errorf("failed to read stream: %w", err)
+	}
+
+	return content.String(), fmt.Errorf("stream ended before completion")
+}
+
+// redactedValue replaces header values that must never reach the logs
+const redactedValue = "[REDACTED]"
+
+// requestFields describes an outgoing completion request for the logs. Message text is
+// only included when sensitive data logging is on, and the API key never is.
+func (c *client) requestFields(req *http.Request, messages []llm.Message, model string, temperature float64, maxTokens int) map[string]any {
+	fields := map[string]any{
+		"model":       model,
+		"temperature": temperature,
+		"max_tokens":  maxTokens,
+		"messages":    len(messages),
+	}
+	if c.logSensitiveData {
+		fields["prompt"] = messages
+	}
+	if c.logRequestHeaders {
+		fields["headers"] = redactHeaders(req.Header)
+	}
+	return fields
+}
+
+// redactHeaders returns the request headers with credentials masked
+func redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for name := range header {
+		if strings.EqualFold(name, "Authorization") || strings.EqualFold(name, "api-key") {
+			headers[name] = redactedValue
+			continue
+		}
+		headers[name] = header.Get(name)
+	}
+	return headers
+}
+
+// newAPIError describes a non-200 response, keeping the status and Retry-After so the
+// caller can decide whether to retry. A rate-limited response without Retry-After waits
+// for the exhausted limit to reset instead.
+func newAPIError(resp *http.Response, body []byte) *llm.APIError {
+	retryAfter := llm.ParseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	if retryAfter == 0 && resp.StatusCode == http.StatusTooManyRequests {
+		retryAfter = rateLimitReset(resp.Header)
+	}
+	return &llm.APIError{
+		Provider:   "OpenAI",
+		StatusCode: resp.StatusCode,
+		Body:       string(body),
+		RetryAfter: retryAfter,
+	}
+}
+
+// rateLimitReset returns how long until OpenAI's exhausted rate limits reset, read from
+// the x-ratelimit-remaining-* and x-ratelimit-reset-* headers of the request and token
+// limits. Resets are given as durations such as "1s" or "6m0s". It returns zero when no
+// limit is reported as exhausted.
+func rateLimitReset(header http.Header) time.Duration {
+	var reset time.Duration
+	for _, limit := range []string{"requests", "tokens"} {
+		if header.Get("X-Ratelimit-Remaining-"+limit) != "0" {
+			continue
+		}
+		d, err := time.ParseDuration(header.Get("X-Ratelimit-Reset-" + limit))
+		if err == nil && d > reset {
+			reset = d
+		}
+	}
+	return reset
 }
 
 // GetFirstChoiceContent returns the content of the first choice
@@ -146,6 +419,15 @@ func (r *ChatCompletionResponse) GetFirstChoiceContent() string {
 	return ""
 }
 
+// GetFirstChoiceFinishReason returns why the first choice stopped, which OpenAI reports
+// with the same values as llm's FinishReason constants
+func (r *ChatCompletionResponse) GetFirstChoiceFinishReason() string {
+	if len(r.Choices) > 0 {
+		return r.Choices[0].FinishReason
+	}
+	return ""
+}
+
 // GetTotalTokens returns the total tokens used
 func (r *ChatCompletionResponse) GetTotalTokens() int {
 	return r.Usage.TotalTokens
@@ -1,13 +1,23 @@
 package openai
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"net/http/httptest"
 	"testing"
+	"time"
 
 	"chat-service/configs"
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockHTTPClient is a mock implementation of the HTTP client
@@ -46,7 +56,7 @@ func TestChatCompletion(t *testing.T) {
 	assert.NotNil(t, client)
 
 	// Test with empty model (should use default)
-	_ = []Message{
+	_ = []llm.Message{
 		{
 			Role:    "user",
 			Content: "Hello, how are you?",
@@ -120,3 +130,360 @@ func TestGetFirstChoiceContentEmpty(t *testing.T) {
 	content := response.GetFirstChoiceContent()
 	assert.Equal(t, "", content)
 }
+
+func newTestClient(baseURL string) *client {
+	return &client{
+		apiKey:       "test-api-key",
+		baseURL:      baseURL,
+		defaultModel: "gpt-3.5-turbo",
+		httpClient:   http.DefaultClient,
+		logger:       zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}),
+	}
+}
+
+func TestChatCompletion_ReturnsProviderResponse(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "gpt-3.5-turbo", req.Model)
+		assert.False(t, req.Stream)
+
+		fmt.Fprint(w, `{"model":"gpt-3.5-turbo-0125","choices":[{"index":0,"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}],"usage":{"prompt_tokens":8,"completion_tokens":2,"total_tokens":10}}`)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	response, err := c.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+	require.NoError(t, err)
+	assert.Equal(t, configs.OPENAI_PROVIDER, c.Name())
+	assert.Equal(t, "Hello!", response.Content)
+	assert.Equal(t, "gpt-3.5-turbo-0125", response.Model)
+	assert.Equal(t, llm.Usage{PromptTokens: 8, CompletionTokens: 2, TotalTokens: 10}, response.Usage)
+	assert.Equal(t, llm.FinishReasonStop, response.FinishReason)
+}
+
+func TestChatCompletion_ReturnsContentFilterFinishReason(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"gpt-3.5-turbo-0125","choices":[{"index":0,"message":{"role":"assistant","content":""},"finish_reason":"content_filter"}],"usage":{"prompt_tokens":8,"completion_tokens":0,"total_tokens":8}}`)
+	}))
+	defer server.Close()
+
+	response, err := newTestClient(server.URL).ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+	require.NoError(t, err)
+	assert.Empty(t, response.Content)
+	assert.Equal(t, llm.FinishReasonContentFilter, response.FinishReason)
+}
+
+func TestChatCompletion_StopsAtContextDeadline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The server only notices the client going away once the body is consumed
+		io.Copy(io.Discard, r.Body)
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	response, err := newTestClient(server.URL).ChatCompletion(ctx, []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Nil(t, response)
+	assert.Less(t, time.Since(start), time.Second, "the request must be abandoned at the deadline")
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req ChatCompletionRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.True(t, req.Stream)
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"role\":\"assistant\"}}]}\n\n")
+		for _, delta := range []string{"Hello", ", ", "world"} {
+			fmt.Fprintf(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":%q}}]}\n\n", delta)
+		}
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var deltas []string
+	content, err := newTestClient(server.URL).ChatCompletionStream(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100, func(delta string) error {
+		deltas = append(deltas, delta)
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Hello", ", ", "world"}, deltas)
+	assert.Equal(t, "Hello, world", content)
+}
+
+func TestChatCompletionStream_InterruptedReturnsPartialContent(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"partial\"}}]}\n\n")
+	}))
+	defer server.Close()
+
+	content, err := newTestClient(server.URL).ChatCompletionStream(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100, func(delta string) error {
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, "partial", content)
+}
+
+func TestChatCompletionStream_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+	}))
+	defer server.Close()
+
+	content, err := newTestClient(server.URL).ChatCompletionStream(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100, func(delta string) error {
+		t.Fatal("no delta expected on API error")
+		return nil
+	})
+
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "status: 401")
+	assert.Empty(t, content)
+}
+
+func TestChatCompletion_APIErrorCarriesRetryAfter(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"error":{"message":"rate limited"}}`)
+	}))
+	defer server.Close()
+
+	_, err := newTestClient(server.URL).ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+	var apiErr *llm.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusTooManyRequests, apiErr.StatusCode)
+	assert.Equal(t, 2*time.Second, apiErr.RetryAfter)
+	assert.True(t, apiErr.Retryable())
+	assert.Contains(t, err.Error(), "status: 429")
+}
+
+func TestChatCompletion_RateLimitFallsBackToResetHeaders(t *testing.T) {
+	tests := []struct {
+		name           string
+		headers        map[string]string
+		wantRetryAfter time.Duration
+	}{
+		{
+			name: "Retry-After takes precedence",
+			headers: map[string]string{
+				"Retry-After":                    "3",
+				"x-ratelimit-remaining-requests": "0",
+				"x-ratelimit-reset-requests":     "20s",
+			},
+			wantRetryAfter: 3 * time.Second,
+		},
+		{
+			name: "longest reset of the exhausted limits",
+			headers: map[string]string{
+				"x-ratelimit-remaining-requests": "0",
+				"x-ratelimit-reset-requests":     "1.5s",
+				"x-ratelimit-remaining-tokens":   "0",
+				"x-ratelimit-reset-tokens":       "6m0s",
+			},
+			wantRetryAfter: 6 * time.Minute,
+		},
+		{
+			name: "limits with capacity left are ignored",
+			headers: map[string]string{
+				"x-ratelimit-remaining-requests": "0",
+				"x-ratelimit-reset-requests":     "120ms",
+				"x-ratelimit-remaining-tokens":   "4000",
+				"x-ratelimit-reset-tokens":       "1m",
+			},
+			wantRetryAfter: 120 * time.Millisecond,
+		},
+		{
+			name:           "no rate-limit headers",
+			headers:        map[string]string{},
+			wantRetryAfter: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				for name, value := range tt.headers {
+					w.Header().Set(name, value)
+				}
+				w.WriteHeader(http.StatusTooManyRequests)
+				fmt.Fprint(w, `{"error":{"message":"rate limited"}}`)
+			}))
+			defer server.Close()
+
+			_, err := newTestClient(server.URL).ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+
+			require.True(t, llm.IsRateLimited(err))
+			assert.Equal(t, tt.wantRetryAfter, llm.RetryAfter(err))
+		})
+	}
+}
+
+func TestPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodGet, r.Method)
+		assert.Equal(t, "/models", r.URL.Path)
+		if r.Header.Get("Authorization") != "Bearer test-api-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"object":"list","data":[]}`)
+	}))
+	defer server.Close()
+
+	c := newTestClient(server.URL)
+	require.NoError(t, c.Ping(context.Background()))
+
+	c.apiKey = "wrong"
+	var apiErr *llm.APIError
+	require.ErrorAs(t, c.Ping(context.Background()), &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+}
+
+// newLoggingTestServer answers every completion request with a fixed reply
+func newLoggingTestServer() *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"model":"gpt-3.5-turbo","choices":[{"index":0,"message":{"role":"assistant","content":"the secret reply"},"finish_reason":"stop"}],"usage":{"prompt_tokens":8,"completion_tokens":3,"total_tokens":11}}`)
+	}))
+}
+
+func TestChatCompletion_RedactsSensitiveDataByDefault(t *testing.T) {
+	server := newLoggingTestServer()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	c := newTestClient(server.URL)
+	c.logger = zlog.New(zlog.Config{Level: "debug", Output: &logs, JSONFormat: true})
+	c.logRequestHeaders = true
+	c.logResponseBody = true
+
+	_, err := c.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "my private prompt"}}, "", 0.7, 100)
+	require.NoError(t, err)
+
+	output := logs.String()
+	assert.NotContains(t, output, "my private prompt")
+	assert.NotContains(t, output, "the secret reply")
+	assert.NotContains(t, output, "test-api-key")
+	assert.Contains(t, output, `"total_tokens":11`)
+	assert.Contains(t, output, `"messages":1`)
+}
+
+func TestChatCompletion_LogsSensitiveDataWhenEnabled(t *testing.T) {
+	server := newLoggingTestServer()
+	defer server.Close()
+
+	var logs bytes.Buffer
+	c := newTestClient(server.URL)
+	c.logger = zlog.New(zlog.Config{Level: "debug", Output: &logs, JSONFormat: true})
+	c.logSensitiveData = true
+	c.logRequestHeaders = true
+
+	_, err := c.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "my private prompt"}}, "", 0.7, 100)
+	require.NoError(t, err)
+
+	output := logs.String()
+	assert.Contains(t, output, "my private prompt")
+	assert.Contains(t, output, "the secret reply")
+	assert.Contains(t, output, redactedValue)
+	assert.NotContains(t, output, "test-api-key")
+}
+
+func TestChatCompletionStream_RedactsContentByDefault(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"choices\":[{\"index\":0,\"delta\":{\"content\":\"the secret reply\"}}]}\n\n")
+		fmt.Fprint(w, "data: [DONE]\n\n")
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	c := newTestClient(server.URL)
+	c.logger = zlog.New(zlog.Config{Level: "debug", Output: &logs, JSONFormat: true})
+
+	_, err := c.ChatCompletionStream(context.Background(), []llm.Message{{Role: "user", Content: "my private prompt"}}, "", 0.7, 100, func(string) error { return nil })
+	require.NoError(t, err)
+
+	output := logs.String()
+	assert.NotContains(t, output, "my private prompt")
+	assert.NotContains(t, output, "the secret reply")
+	assert.Contains(t, output, `"content_length":16`)
+}
+
+func TestNewClient_ReadsLoggingConfig(t *testing.T) {
+	c := NewClient(&configs.Config{LogSensitiveData: true, LogRequestHeaders: true, LogResponseBody: true}, nil).(*client)
+
+	assert.True(t, c.logSensitiveData)
+	assert.True(t, c.logRequestHeaders)
+	assert.True(t, c.logResponseBody)
+}
+
+func TestNewClient_UsesConfiguredBaseURL(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		apiVersion string
+		modelsPath string
+	}{
+		{name: "proxy", path: "/proxy/v1", modelsPath: "/proxy/v1/models"},
+		// Azure lists models per resource, not per deployment
+		{name: "azure", path: "/openai/deployments/gpt-4o", apiVersion: "2024-06-01", modelsPath: "/openai/models"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests []*http.Request
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requests = append(requests, r)
+				if r.URL.Path == tt.modelsPath {
+					fmt.Fprint(w, `{"data":[]}`)
+					return
+				}
+				fmt.Fprint(w, `{"model":"gpt-4o","choices":[{"index":0,"message":{"role":"assistant","content":"Hello!"},"finish_reason":"stop"}],"usage":{"total_tokens":10}}`)
+			}))
+			defer server.Close()
+
+			c := NewClient(&configs.Config{
+				OpenAIAPIKey:     "test-api-key",
+				OpenAIBaseURL:    server.URL + tt.path + "/",
+				OpenAIAPIVersion: tt.apiVersion,
+				OpenAIModel:      "gpt-4o",
+				OpenAITimeout:    5,
+			}, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+
+			_, err := c.ChatCompletion(context.Background(), []llm.Message{{Role: "user", Content: "Hi"}}, "", 0.7, 100)
+			require.NoError(t, err)
+			require.NoError(t, c.(llm.Pinger).Ping(context.Background()))
+
+			require.Len(t, requests, 2)
+			assert.Equal(t, tt.path+"/chat/completions", requests[0].URL.Path)
+			assert.Equal(t, tt.modelsPath, requests[1].URL.Path)
+			for _, r := range requests {
+				assert.Equal(t, tt.apiVersion, r.URL.Query().Get("api-version"))
+				if tt.apiVersion != "" {
+					assert.Equal(t, "test-api-key", r.Header.Get("api-key"), "Azure reads the key from the api-key header")
+					assert.Empty(t, r.Header.Get("Authorization"), "the key is not sent to Azure a second time")
+				} else {
+					assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+					assert.Empty(t, r.Header.Get("api-key"))
+				}
+			}
+		})
+	}
+}
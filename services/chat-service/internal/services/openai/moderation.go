@@ -0,0 +1,91 @@
+package openai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+
+	"chat-service/configs"
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
+)
+
+// ModerationRequest represents the request to the OpenAI moderation API
+type ModerationRequest struct {
+	Input string `json:"input"`
+}
+
+// ModerationResponse represents the response from the OpenAI moderation API
+type ModerationResponse struct {
+	ID      string `json:"id"`
+	Model   string `json:"model"`
+	Results []struct {
+		Flagged    bool            `json:"flagged"`
+		Categories map[string]bool `json:"categories"`
+	} `json:"results"`
+}
+
+// NewModerator creates a moderator backed by the OpenAI moderation API. It uses the
+// OpenAI API key whichever provider serves completions.
+func NewModerator(cfg *configs.Config, logger *zlog.Logger) llm.Moderator {
+	return newClient(cfg, logger)
+}
+
+// Moderate runs input through the OpenAI moderation API
+func (c *client) Moderate(ctx context.Context, input string) (*llm.ModerationResult, error) {
+	jsonBody, err := json.Marshal(ModerationRequest{Input: input})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", c.endpoint("/moderations"), bytes.NewBuffer(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, newAPIError(resp, body)
+	}
+
+	var response ModerationResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	if len(response.Results) == 0 {
+		return nil, fmt.Errorf("moderation response has no results")
+	}
+
+	result := &llm.ModerationResult{Flagged: response.Results[0].Flagged}
+	for category, flagged := range response.Results[0].Categories {
+		if flagged {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+
+	c.logger.Debug(ctx, "Received moderation result from OpenAI", map[string]any{
+		"model":      response.Model,
+		"flagged":    result.Flagged,
+		"categories": result.Categories,
+	})
+
+	return result, nil
+}
@@ -0,0 +1,64 @@
+package openai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chat-service/internal/services/llm"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestModerate_ReturnsFlaggedCategories(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPost, r.Method)
+		assert.Equal(t, "/moderations", r.URL.Path)
+		assert.Equal(t, "Bearer test-api-key", r.Header.Get("Authorization"))
+
+		var req ModerationRequest
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "some text", req.Input)
+
+		fmt.Fprint(w, `{"id":"modr-1","model":"omni-moderation-latest","results":[{"flagged":true,"categories":{"violence":true,"harassment":true,"sexual":false}}]}`)
+	}))
+	defer server.Close()
+
+	result, err := newTestClient(server.URL).Moderate(context.Background(), "some text")
+
+	require.NoError(t, err)
+	assert.True(t, result.Flagged)
+	assert.Equal(t, []string{"harassment", "violence"}, result.Categories)
+}
+
+func TestModerate_CleanInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"id":"modr-2","model":"omni-moderation-latest","results":[{"flagged":false,"categories":{"violence":false}}]}`)
+	}))
+	defer server.Close()
+
+	result, err := newTestClient(server.URL).Moderate(context.Background(), "hello")
+
+	require.NoError(t, err)
+	assert.False(t, result.Flagged)
+	assert.Empty(t, result.Categories)
+}
+
+func TestModerate_APIError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		fmt.Fprint(w, `{"error":{"message":"invalid api key"}}`)
+	}))
+	defer server.Close()
+
+	result, err := newTestClient(server.URL).Moderate(context.Background(), "hello")
+
+	var apiErr *llm.APIError
+	require.ErrorAs(t, err, &apiErr)
+	assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+	assert.Nil(t, result)
+}
@@ -9,12 +9,13 @@ import (
 
 	"api/auth/v1/proto"
 	"chat-service/configs"
+	"packages/grpcutil"
 	zlog "packages/logger"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 )
@@ -55,16 +56,15 @@ func NewAuthInterceptor(logger *zlog.Logger, config *configs.Config) (*AuthInter
 	}
 
 	// Create gRPC connection to auth service
-	var creds credentials.TransportCredentials
+	dialCfg := grpcutil.DefaultConfig()
+	dialCfg.LoadBalancingPolicy = config.AuthLBPolicy
 	if config.AuthServiceTLS && config.TLSEnabled {
-		creds = credentials.NewTLS(tlsConfig)
-	} else {
-		creds = insecure.NewCredentials()
+		dialCfg.Credentials = credentials.NewTLS(tlsConfig)
 	}
 
 	authConn, err := grpc.Dial(
-		fmt.Sprintf("%s:%s", config.AuthServiceHost, config.AuthServicePort),
-		grpc.WithTransportCredentials(creds),
+		config.AuthServiceDialTarget(),
+		append(grpcutil.DialOptions(dialCfg), grpc.WithStatsHandler(otelgrpc.NewClientHandler()))...,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
@@ -2,18 +2,33 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strings"
 
 	"chat-service/internal/domain"
 	"chat-service/internal/services/chat"
 	"chat-service/proto"
 	zlog "packages/logger"
 
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// Defaults applied to requests that leave optional fields unset
+const (
+	defaultHistoryLimit       = 50
+	defaultConversationsLimit = 10
+	defaultSearchLimit        = 20
+	defaultUserMessagesLimit  = 50
+)
+
 // ChatHandler handles gRPC chat requests
 type ChatHandler struct {
 	proto.UnimplementedChatServiceServer
@@ -55,19 +70,20 @@ func (h *ChatHandler) SendMessage(ctx context.Context, req *proto.ChatRequest) (
 		UserID:         userID,
 		Message:        req.Message,
 		ConversationID: req.ConversationId,
+		Metadata:       metadataFromProto(req.GetMetadata()),
+		IdempotencyKey: idempotencyKey(ctx),
 	}
 
 	// Validate the domain request
 	if err := domainReq.Validate(); err != nil {
 		h.logger.Error(ctx, err, "Validation failed", 400)
-		return nil, status.Errorf(codes.InvalidArgument, "validation error: %v", err)
+		return nil, invalidArgument(err)
 	}
 
 	// Call chat service
 	response, err := h.chatService.SendMessage(ctx, domainReq)
 	if err != nil {
-		h.logger.Error(ctx, err, "Failed to send message", 500)
-		return nil, status.Errorf(codes.Internal, "failed to send message: %v", err)
+		return nil, h.serviceError(ctx, err, "send message")
 	}
 
 	// Convert domain response to proto response
@@ -85,7 +101,9 @@ func (h *ChatHandler) SendMessage(ctx context.Context, req *proto.ChatRequest) (
 	return protoResponse, nil
 }
 
-// StreamMessages handles streaming messages
+// StreamMessages streams the stored messages of a conversation in chronological order. In
+// subscribe mode the stream stays open afterwards and carries every new message stored in
+// the conversation until the client cancels it.
 func (h *ChatHandler) StreamMessages(req *proto.StreamMessageRequest, stream proto.ChatService_StreamMessagesServer) error {
 	ctx := stream.Context()
 
@@ -99,28 +117,36 @@ func (h *ChatHandler) StreamMessages(req *proto.StreamMessageRequest, stream pro
 	h.logger.Info(ctx, "Handling StreamMessages request", map[string]any{
 		"user_id":         userID,
 		"conversation_id": req.ConversationId,
+		"subscribe":       req.Subscribe,
 	})
 
-	// For now, we'll just send a single message to demonstrate the streaming
-	// In a real implementation, you would stream actual messages from the database or real-time updates
-
-	message := &proto.Message{
-		Id:        "stream-msg-1",
-		UserId:    userID,
-		Content:   "This is a streamed message",
-		Role:      "assistant",
-		CreatedAt: timestamppb.Now(),
-		UpdatedAt: timestamppb.Now(),
+	if err := domain.ValidateUUID(req.ConversationId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return status.Errorf(codes.InvalidArgument, "validation error: conversation_id: %v", err)
 	}
 
-	response := &proto.StreamMessageResponse{
-		Message: message,
-		IsEnd:   false,
+	streamMessages := h.chatService.StreamMessages
+	if req.Subscribe {
+		streamMessages = h.chatService.SubscribeMessages
 	}
-
-	if err := stream.Send(response); err != nil {
-		h.logger.Error(ctx, err, "Failed to send stream message", 500)
-		return status.Errorf(codes.Internal, "failed to send stream message: %v", err)
+	err := streamMessages(ctx, userID, req.ConversationId, func(msg *domain.Message) error {
+		return stream.Send(&proto.StreamMessageResponse{
+			Message: h.convertMessageToProto(msg),
+			IsEnd:   false,
+		})
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, chat.ErrConversationNotFound):
+			return status.Errorf(codes.NotFound, "conversation not found")
+		case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+			h.logger.Info(ctx, "Stream cancelled by client", map[string]any{
+				"conversation_id": req.ConversationId,
+			})
+			return status.FromContextError(err).Err()
+		default:
+			return h.serviceError(ctx, err, "stream messages")
+		}
 	}
 
 	// Send end message
@@ -157,19 +183,30 @@ func (h *ChatHandler) GetHistory(ctx context.Context, req *proto.GetHistoryReque
 		"offset":          req.Offset,
 	})
 
+	limit, offset := domain.ClampPage(int(req.Limit), int(req.Offset), defaultHistoryLimit, domain.MaxPageLimit)
+
 	// Convert proto request to domain request
 	domainReq := &domain.GetHistoryRequest{
 		UserID:         userID,
 		ConversationID: req.ConversationId,
-		Limit:          int(req.Limit),
-		Offset:         int(req.Offset),
+		Limit:          limit,
+		Offset:         offset,
+		Cursor:         req.Cursor,
+	}
+
+	// Validate the domain request
+	if err := domainReq.Validate(); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, invalidArgument(err)
 	}
 
 	// Call chat service
 	response, err := h.chatService.GetHistory(ctx, domainReq)
 	if err != nil {
-		h.logger.Error(ctx, err, "Failed to get chat history", 500)
-		return nil, status.Errorf(codes.Internal, "failed to get chat history: %v", err)
+		if errors.Is(err, chat.ErrConversationNotFound) {
+			return nil, status.Errorf(codes.NotFound, "conversation not found")
+		}
+		return nil, h.serviceError(ctx, err, "get chat history")
 	}
 
 	// Convert domain response to proto response
@@ -182,6 +219,8 @@ func (h *ChatHandler) GetHistory(ctx context.Context, req *proto.GetHistoryReque
 		Messages:       protoMessages,
 		Total:          int32(response.Total),
 		ConversationId: response.ConversationID,
+		NextCursor:     response.NextCursor,
+		PageInfo:       h.convertPageInfoToProto(response.PageInfo),
 	}
 
 	h.logger.Info(ctx, "Chat history retrieved", map[string]any{
@@ -205,42 +244,122 @@ func (h *ChatHandler) ChatWithAI(ctx context.Context, req *proto.ChatWithAIReque
 		"user_id":         userID,
 		"conversation_id": req.ConversationId,
 		"model":           req.Model,
-		"temperature":     req.Temperature,
-		"max_tokens":      req.MaxTokens,
+		"temperature":     req.GetTemperature(),
+		"max_tokens":      req.GetMaxTokens(),
 	})
 
+	aiReq, err := chatWithAIRequestFromProto(userID, req)
+	if err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, invalidArgument(err)
+	}
+
 	// Call chat service
 	response, err := h.chatService.ChatWithAI(
 		ctx,
-		userID,
-		req.Message,
-		req.ConversationId,
-		req.Model,
-		float64(req.Temperature),
-		int(req.MaxTokens),
+		aiReq.UserID,
+		aiReq.Message,
+		aiReq.ConversationID,
+		aiReq.Model,
+		*aiReq.Temperature,
+		*aiReq.MaxTokens,
+		aiReq.Metadata,
 	)
 	if err != nil {
-		h.logger.Error(ctx, err, "Failed to chat with AI", 500)
-		return nil, status.Errorf(codes.Internal, "failed to chat with AI: %v", err)
+		return nil, h.serviceError(ctx, err, "chat with AI")
+	}
+
+	var tokensUsed int32
+	if response.Usage != nil {
+		tokensUsed = int32(response.Usage.TotalTokens)
 	}
 
 	// Convert domain response to proto response
 	protoResponse := &proto.ChatWithAIResponse{
 		AiMessage:      response.Message.Content,
 		ConversationId: response.ConversationID,
-		ModelUsed:      req.Model,
-		TokensUsed:     int32(0), // This would come from OpenAI response in real implementation
+		ModelUsed:      response.Model,
+		TokensUsed:     tokensUsed,
 		CreatedAt:      timestamppb.Now(),
+		FinishReason:   response.FinishReason,
 	}
 
 	h.logger.Info(ctx, "AI chat completed successfully", map[string]any{
 		"conversation_id": response.ConversationID,
-		"model_used":      req.Model,
+		"model_used":      response.Model,
+		"tokens_used":     tokensUsed,
 	})
 
 	return protoResponse, nil
 }
 
+// ChatWithAIStream handles chatting with AI, streaming the response as it is generated
+func (h *ChatHandler) ChatWithAIStream(req *proto.ChatWithAIRequest, stream proto.ChatService_ChatWithAIStreamServer) error {
+	ctx := stream.Context()
+
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling ChatWithAIStream request", map[string]any{
+		"user_id":         userID,
+		"conversation_id": req.ConversationId,
+		"model":           req.Model,
+		"temperature":     req.GetTemperature(),
+		"max_tokens":      req.GetMaxTokens(),
+	})
+
+	aiReq, err := chatWithAIRequestFromProto(userID, req)
+	if err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return invalidArgument(err)
+	}
+
+	// Call chat service, forwarding each delta to the client
+	response, err := h.chatService.ChatWithAIStream(
+		ctx,
+		aiReq.UserID,
+		aiReq.Message,
+		aiReq.ConversationID,
+		aiReq.Model,
+		*aiReq.Temperature,
+		*aiReq.MaxTokens,
+		aiReq.Metadata,
+		func(conversationID, delta string) error {
+			return stream.Send(&proto.ChatWithAIStreamResponse{
+				Delta:          delta,
+				ConversationId: conversationID,
+				IsEnd:          false,
+			})
+		},
+	)
+	if err != nil {
+		return h.serviceError(ctx, err, "stream chat with AI")
+	}
+
+	// Send end message with the stored assistant message
+	endResponse := &proto.ChatWithAIStreamResponse{
+		ConversationId: response.ConversationID,
+		IsEnd:          true,
+		Message:        h.convertMessageToProto(response.Message),
+	}
+
+	if err := stream.Send(endResponse); err != nil {
+		h.logger.Error(ctx, err, "Failed to send end message", 500)
+		return status.Errorf(codes.Internal, "failed to send end message: %v", err)
+	}
+
+	h.logger.Info(ctx, "AI chat stream completed successfully", map[string]any{
+		"conversation_id": response.ConversationID,
+		"model_used":      response.Model,
+	})
+
+	return nil
+}
+
 // ListConversations handles listing conversations
 func (h *ChatHandler) ListConversations(ctx context.Context, req *proto.ListConversationsRequest) (*proto.ListConversationsResponse, error) {
 	// Extract user ID from context (set by auth interceptor)
@@ -251,23 +370,33 @@ func (h *ChatHandler) ListConversations(ctx context.Context, req *proto.ListConv
 	}
 
 	h.logger.Info(ctx, "Handling ListConversations request", map[string]any{
-		"user_id": userID,
-		"limit":   req.Limit,
-		"offset":  req.Offset,
+		"user_id":          userID,
+		"limit":            req.Limit,
+		"offset":           req.Offset,
+		"include_archived": req.IncludeArchived,
 	})
 
+	limit, offset := domain.ClampPage(int(req.Limit), int(req.Offset), defaultConversationsLimit, domain.MaxPageLimit)
+
 	// Convert proto request to domain request
 	domainReq := &domain.ListConversationsRequest{
-		UserID: userID,
-		Limit:  int(req.Limit),
-		Offset: int(req.Offset),
+		UserID:          userID,
+		Limit:           limit,
+		Offset:          offset,
+		Cursor:          req.Cursor,
+		IncludeArchived: req.IncludeArchived,
+	}
+
+	// Validate the domain request
+	if err := domainReq.Validate(); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, invalidArgument(err)
 	}
 
 	// Call chat service
 	response, err := h.chatService.ListConversations(ctx, domainReq)
 	if err != nil {
-		h.logger.Error(ctx, err, "Failed to list conversations", 500)
-		return nil, status.Errorf(codes.Internal, "failed to list conversations: %v", err)
+		return nil, h.serviceError(ctx, err, "list conversations")
 	}
 
 	// Convert domain response to proto response
@@ -279,6 +408,8 @@ func (h *ChatHandler) ListConversations(ctx context.Context, req *proto.ListConv
 	protoResponse := &proto.ListConversationsResponse{
 		Conversations: protoConversations,
 		Total:         int32(response.Total),
+		NextCursor:    response.NextCursor,
+		PageInfo:      h.convertPageInfoToProto(response.PageInfo),
 	}
 
 	h.logger.Info(ctx, "Conversations listed", map[string]any{
@@ -289,6 +420,121 @@ func (h *ChatHandler) ListConversations(ctx context.Context, req *proto.ListConv
 	return protoResponse, nil
 }
 
+// ListUserMessages handles listing the user's messages across all conversations
+func (h *ChatHandler) ListUserMessages(ctx context.Context, req *proto.ListUserMessagesRequest) (*proto.ListUserMessagesResponse, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling ListUserMessages request", map[string]any{
+		"user_id": userID,
+		"limit":   req.Limit,
+		"offset":  req.Offset,
+	})
+
+	limit, offset := domain.ClampPage(int(req.Limit), int(req.Offset), defaultUserMessagesLimit, domain.MaxPageLimit)
+
+	// Convert proto request to domain request
+	domainReq := &domain.ListUserMessagesRequest{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	// Validate the domain request
+	if err := domainReq.Validate(); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, invalidArgument(err)
+	}
+
+	// Call chat service
+	response, err := h.chatService.ListUserMessages(ctx, domainReq.UserID, domainReq.Limit, domainReq.Offset)
+	if err != nil {
+		if errors.Is(err, chat.ErrUserMismatch) {
+			h.logger.Warn(ctx, "User messages access denied", map[string]any{
+				"user_id": userID,
+			})
+			return nil, status.Errorf(codes.PermissionDenied, "messages do not belong to user")
+		}
+		return nil, h.serviceError(ctx, err, "list user messages")
+	}
+
+	// Convert domain response to proto response
+	protoMessages := make([]*proto.Message, len(response.Messages))
+	for i, msg := range response.Messages {
+		protoMessages[i] = h.convertMessageToProto(msg)
+	}
+
+	h.logger.Info(ctx, "User messages listed", map[string]any{
+		"user_id":        userID,
+		"total_messages": response.Total,
+	})
+
+	return &proto.ListUserMessagesResponse{
+		Messages: protoMessages,
+		Total:    int32(response.Total),
+		PageInfo: h.convertPageInfoToProto(response.PageInfo),
+	}, nil
+}
+
+// SearchMessages handles full-text search over the user's messages
+func (h *ChatHandler) SearchMessages(ctx context.Context, req *proto.SearchMessagesRequest) (*proto.SearchMessagesResponse, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling SearchMessages request", map[string]any{
+		"user_id": userID,
+		"limit":   req.Limit,
+		"offset":  req.Offset,
+	})
+
+	limit, offset := domain.ClampPage(int(req.Limit), int(req.Offset), defaultSearchLimit, domain.MaxPageLimit)
+
+	// Convert proto request to domain request
+	domainReq := &domain.SearchMessagesRequest{
+		UserID: userID,
+		Query:  req.Q,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	// Validate the domain request
+	if err := domainReq.Validate(); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, invalidArgument(err)
+	}
+
+	// Call chat service
+	response, err := h.chatService.Search(ctx, domainReq)
+	if err != nil {
+		return nil, h.serviceError(ctx, err, "search messages")
+	}
+
+	// Convert domain response to proto response
+	results := make([]*proto.MessageSearchResult, len(response.Results))
+	for i, result := range response.Results {
+		results[i] = &proto.MessageSearchResult{
+			Message:        h.convertMessageToProto(&result.Message),
+			ConversationId: result.ConversationID,
+			Snippet:        result.Snippet,
+		}
+	}
+
+	h.logger.Info(ctx, "Messages searched", map[string]any{
+		"user_id": userID,
+		"results": len(results),
+	})
+
+	return &proto.SearchMessagesResponse{Results: results}, nil
+}
+
 // CreateConversation handles creating a new conversation
 func (h *ChatHandler) CreateConversation(ctx context.Context, req *proto.Conversation) (*proto.Conversation, error) {
 	// Extract user ID from context (set by auth interceptor)
@@ -303,11 +549,14 @@ func (h *ChatHandler) CreateConversation(ctx context.Context, req *proto.Convers
 		"title":   req.Title,
 	})
 
+	if req.Title == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: title is required")
+	}
+
 	// Call chat service
 	conversation, err := h.chatService.CreateConversation(ctx, userID, req.Title)
 	if err != nil {
-		h.logger.Error(ctx, err, "Failed to create conversation", 500)
-		return nil, status.Errorf(codes.Internal, "failed to create conversation: %v", err)
+		return nil, h.serviceError(ctx, err, "create conversation")
 	}
 
 	// Convert domain response to proto response
@@ -321,6 +570,521 @@ func (h *ChatHandler) CreateConversation(ctx context.Context, req *proto.Convers
 	return protoResponse, nil
 }
 
+// ImportConversation handles importing a conversation together with its messages
+func (h *ChatHandler) ImportConversation(ctx context.Context, req *proto.ImportConversationRequest) (*proto.ImportConversationResponse, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling ImportConversation request", map[string]any{
+		"user_id":       userID,
+		"title":         req.Title,
+		"message_count": len(req.Messages),
+	})
+
+	messages := make([]domain.Message, len(req.Messages))
+	for i, msg := range req.Messages {
+		messages[i] = domain.Message{
+			Content: msg.Content,
+			Role:    domain.MessageRole(msg.Role),
+		}
+		if msg.CreatedAt != nil {
+			messages[i].CreatedAt = msg.CreatedAt.AsTime()
+		}
+	}
+
+	// Call chat service
+	conversation, err := h.chatService.ImportConversation(ctx, userID, req.Title, messages)
+	if err != nil {
+		if errors.Is(err, chat.ErrInvalidImport) {
+			h.logger.Error(ctx, err, "Validation failed", 400)
+			return nil, invalidArgument(err)
+		}
+		return nil, h.serviceError(ctx, err, "import conversation")
+	}
+
+	h.logger.Info(ctx, "Conversation imported successfully", map[string]any{
+		"conversation_id": conversation.ID,
+		"user_id":         userID,
+		"message_count":   len(messages),
+	})
+
+	return &proto.ImportConversationResponse{
+		Conversation: h.convertConversationToProto(conversation),
+		MessageCount: int32(len(messages)),
+	}, nil
+}
+
+// GetConversation handles fetching a single conversation
+func (h *ChatHandler) GetConversation(ctx context.Context, req *proto.GetConversationRequest) (*proto.Conversation, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling GetConversation request", map[string]any{
+		"user_id":         userID,
+		"conversation_id": req.ConversationId,
+	})
+
+	if err := domain.ValidateUUID(req.ConversationId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: conversation_id: %v", err)
+	}
+
+	// Call chat service
+	conversation, err := h.chatService.GetConversation(ctx, userID, req.ConversationId)
+	if err != nil {
+		if errors.Is(err, chat.ErrConversationNotFound) {
+			h.logger.Warn(ctx, "Conversation not found", map[string]any{
+				"user_id":         userID,
+				"conversation_id": req.ConversationId,
+			})
+			return nil, status.Errorf(codes.NotFound, "conversation not found")
+		}
+		return nil, h.serviceError(ctx, err, "get conversation")
+	}
+
+	return h.convertConversationToProto(conversation), nil
+}
+
+// DeleteConversation handles deleting a conversation and its messages
+func (h *ChatHandler) DeleteConversation(ctx context.Context, req *proto.DeleteConversationRequest) (*proto.Empty, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling DeleteConversation request", map[string]any{
+		"user_id":         userID,
+		"conversation_id": req.ConversationId,
+	})
+
+	if err := domain.ValidateUUID(req.ConversationId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: conversation_id: %v", err)
+	}
+
+	// Call chat service
+	if err := h.chatService.DeleteConversation(ctx, userID, req.ConversationId); err != nil {
+		if errors.Is(err, chat.ErrConversationNotFound) {
+			h.logger.Warn(ctx, "Conversation not found", map[string]any{
+				"user_id":         userID,
+				"conversation_id": req.ConversationId,
+			})
+			return nil, status.Errorf(codes.NotFound, "conversation not found")
+		}
+		return nil, h.serviceError(ctx, err, "delete conversation")
+	}
+
+	h.logger.Info(ctx, "Conversation deleted successfully", map[string]any{
+		"conversation_id": req.ConversationId,
+		"user_id":         userID,
+	})
+
+	return &proto.Empty{}, nil
+}
+
+// ClearConversationMessages handles deleting every message of a conversation while keeping
+// the conversation
+func (h *ChatHandler) ClearConversationMessages(ctx context.Context, req *proto.ClearConversationMessagesRequest) (*proto.ClearConversationMessagesResponse, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling ClearConversationMessages request", map[string]any{
+		"user_id":         userID,
+		"conversation_id": req.ConversationId,
+	})
+
+	if err := domain.ValidateUUID(req.ConversationId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: conversation_id: %v", err)
+	}
+
+	// Call chat service
+	deleted, err := h.chatService.ClearConversationMessages(ctx, userID, req.ConversationId)
+	if err != nil {
+		if errors.Is(err, chat.ErrConversationNotFound) {
+			h.logger.Warn(ctx, "Conversation not found", map[string]any{
+				"user_id":         userID,
+				"conversation_id": req.ConversationId,
+			})
+			return nil, status.Errorf(codes.NotFound, "conversation not found")
+		}
+		return nil, h.serviceError(ctx, err, "clear conversation messages")
+	}
+
+	return &proto.ClearConversationMessagesResponse{DeletedCount: int32(deleted)}, nil
+}
+
+// RestoreConversation handles restoring a deleted conversation and its messages
+func (h *ChatHandler) RestoreConversation(ctx context.Context, req *proto.RestoreConversationRequest) (*proto.Conversation, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling RestoreConversation request", map[string]any{
+		"user_id":         userID,
+		"conversation_id": req.ConversationId,
+	})
+
+	if err := domain.ValidateUUID(req.ConversationId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: conversation_id: %v", err)
+	}
+
+	// Call chat service
+	conversation, err := h.chatService.RestoreConversation(ctx, userID, req.ConversationId)
+	if err != nil {
+		if errors.Is(err, chat.ErrConversationNotFound) {
+			h.logger.Warn(ctx, "Deleted conversation not found", map[string]any{
+				"user_id":         userID,
+				"conversation_id": req.ConversationId,
+			})
+			return nil, status.Errorf(codes.NotFound, "conversation not found")
+		}
+		return nil, h.serviceError(ctx, err, "restore conversation")
+	}
+
+	h.logger.Info(ctx, "Conversation restored successfully", map[string]any{
+		"conversation_id": conversation.ID,
+		"user_id":         userID,
+	})
+
+	return h.convertConversationToProto(conversation), nil
+}
+
+// ArchiveConversation handles hiding a conversation from the default conversation list
+func (h *ChatHandler) ArchiveConversation(ctx context.Context, req *proto.ArchiveConversationRequest) (*proto.Conversation, error) {
+	return h.setConversationFlag(ctx, "ArchiveConversation", "archive conversation", req.ConversationId, h.chatService.ArchiveConversation)
+}
+
+// UnarchiveConversation handles returning an archived conversation to the default conversation list
+func (h *ChatHandler) UnarchiveConversation(ctx context.Context, req *proto.UnarchiveConversationRequest) (*proto.Conversation, error) {
+	return h.setConversationFlag(ctx, "UnarchiveConversation", "unarchive conversation", req.ConversationId, h.chatService.UnarchiveConversation)
+}
+
+// PinConversation handles listing a conversation before the others
+func (h *ChatHandler) PinConversation(ctx context.Context, req *proto.PinConversationRequest) (*proto.Conversation, error) {
+	return h.setConversationFlag(ctx, "PinConversation", "pin conversation", req.ConversationId, h.chatService.PinConversation)
+}
+
+// UnpinConversation handles returning a pinned conversation to its place by update time
+func (h *ChatHandler) UnpinConversation(ctx context.Context, req *proto.UnpinConversationRequest) (*proto.Conversation, error) {
+	return h.setConversationFlag(ctx, "UnpinConversation", "unpin conversation", req.ConversationId, h.chatService.UnpinConversation)
+}
+
+// setConversationFlag handles the requests that archive, unarchive, pin or unpin a
+// conversation: it validates the conversation ID and calls set, the matching service method.
+// method names the RPC and action the operation, in logs and errors.
+func (h *ChatHandler) setConversationFlag(ctx context.Context, method, action, conversationID string, set func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)) (*proto.Conversation, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling "+method+" request", map[string]any{
+		"user_id":         userID,
+		"conversation_id": conversationID,
+	})
+
+	if err := domain.ValidateUUID(conversationID); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: conversation_id: %v", err)
+	}
+
+	// Call chat service
+	conversation, err := set(ctx, userID, conversationID)
+	if err != nil {
+		return nil, h.serviceError(ctx, err, action)
+	}
+
+	h.logger.Info(ctx, "Conversation updated successfully", map[string]any{
+		"conversation_id": conversation.ID,
+		"user_id":         userID,
+		"action":          action,
+	})
+
+	return h.convertConversationToProto(conversation), nil
+}
+
+// EditMessage handles editing the content of a message
+func (h *ChatHandler) EditMessage(ctx context.Context, req *proto.EditMessageRequest) (*proto.Message, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling EditMessage request", map[string]any{
+		"user_id":        userID,
+		"message_id":     req.MessageId,
+		"content_length": len(req.Content),
+	})
+
+	if err := domain.ValidateUUID(req.MessageId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: message_id: %v", err)
+	}
+	if err := domain.ValidateMessageContent(req.Content); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, invalidArgument(err)
+	}
+
+	// Call chat service
+	message, err := h.chatService.EditMessage(ctx, userID, req.MessageId, req.Content)
+	if err != nil {
+		switch {
+		case errors.Is(err, chat.ErrMessageNotFound):
+			h.logger.Warn(ctx, "Message not found", map[string]any{
+				"user_id":    userID,
+				"message_id": req.MessageId,
+			})
+			return nil, status.Errorf(codes.NotFound, "message not found")
+		case errors.Is(err, chat.ErrAssistantMessageEdit):
+			h.logger.Warn(ctx, "Attempted to edit assistant message", map[string]any{
+				"user_id":    userID,
+				"message_id": req.MessageId,
+			})
+			return nil, status.Errorf(codes.PermissionDenied, "assistant messages cannot be edited")
+		default:
+			return nil, h.serviceError(ctx, err, "edit message")
+		}
+	}
+
+	h.logger.Info(ctx, "Message edited successfully", map[string]any{
+		"message_id": message.ID,
+		"user_id":    userID,
+	})
+
+	return h.convertMessageToProto(message), nil
+}
+
+// DeleteMessage handles deleting a message
+func (h *ChatHandler) DeleteMessage(ctx context.Context, req *proto.DeleteMessageRequest) (*proto.Empty, error) {
+	// Extract user ID from context (set by auth interceptor)
+	userID, ok := ctx.Value("user_id").(string)
+	if !ok {
+		h.logger.Error(ctx, fmt.Errorf("user_id not found in context"), "Failed to extract user_id from context", 500)
+		return nil, status.Errorf(codes.Internal, "authentication error")
+	}
+
+	h.logger.Info(ctx, "Handling DeleteMessage request", map[string]any{
+		"user_id":    userID,
+		"message_id": req.MessageId,
+	})
+
+	if err := domain.ValidateUUID(req.MessageId); err != nil {
+		h.logger.Error(ctx, err, "Validation failed", 400)
+		return nil, status.Errorf(codes.InvalidArgument, "validation error: message_id: %v", err)
+	}
+
+	// Call chat service
+	if err := h.chatService.DeleteMessage(ctx, userID, req.MessageId); err != nil {
+		if errors.Is(err, chat.ErrMessageNotFound) {
+			h.logger.Warn(ctx, "Message not found", map[string]any{
+				"user_id":    userID,
+				"message_id": req.MessageId,
+			})
+			return nil, status.Errorf(codes.NotFound, "message not found")
+		}
+		return nil, h.serviceError(ctx, err, "delete message")
+	}
+
+	h.logger.Info(ctx, "Message deleted successfully", map[string]any{
+		"message_id": req.MessageId,
+		"user_id":    userID,
+	})
+
+	return &proto.Empty{}, nil
+}
+
+// serviceError converts an error returned by the chat service into a gRPC status. Typed
+// service errors are reported with the matching code, anything else as Internal.
+func (h *ChatHandler) serviceError(ctx context.Context, err error, action string) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		h.logger.Warn(ctx, "Chat service call timed out", map[string]any{
+			"action": action,
+			"error":  err.Error(),
+		})
+		return status.Errorf(codes.DeadlineExceeded, "timed out trying to %s", action)
+	}
+
+	var appErr *chat.AppError
+	if !errors.As(err, &appErr) || appErr.Type == chat.ErrorTypeInternal {
+		h.logger.Error(ctx, err, "Failed to "+action, 500)
+		return status.Errorf(codes.Internal, "failed to %s: %v", action, err)
+	}
+
+	h.logger.Warn(ctx, "Chat service rejected request", map[string]any{
+		"action": action,
+		"code":   appErr.Code,
+		"error":  err.Error(),
+	})
+	return ServiceStatus(err).Err()
+}
+
+// ServiceStatus converts an error returned by the chat service into a gRPC status. Typed
+// service errors are reported with the matching code, anything else as Internal without
+// its details. The REST streaming endpoints report errors through it too, so a failure
+// gets the same status whichever transport served the request.
+func ServiceStatus(err error) *status.Status {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return status.New(codes.DeadlineExceeded, "request timed out")
+	}
+
+	var appErr *chat.AppError
+	if !errors.As(err, &appErr) {
+		return status.New(codes.Internal, "internal error")
+	}
+
+	switch appErr.Type {
+	case chat.ErrorTypeInternal:
+		return status.New(codes.Internal, "internal error")
+	case chat.ErrorTypeNotFound:
+		return status.New(codes.NotFound, appErr.Message)
+	case chat.ErrorTypeForbidden:
+		return status.New(codes.PermissionDenied, appErr.Message)
+	case chat.ErrorTypeContentFiltered:
+		return status.New(codes.FailedPrecondition, appErr.Message)
+	case chat.ErrorTypeTruncated:
+		// A reply cut off after some content is returned with its finish_reason. One cut off
+		// before any content needs a larger max_tokens from the caller; no quota was hit.
+		return status.New(codes.FailedPrecondition, appErr.Message)
+	case chat.ErrorTypeQuotaExceeded:
+		return status.New(codes.ResourceExhausted, appErr.Message)
+	case chat.ErrorTypeUnavailable:
+		return aiRequestFailed(codes.Unavailable, err, appErr.Message)
+	case chat.ErrorTypeRateLimited:
+		return aiRequestFailed(codes.ResourceExhausted, err, appErr.Message)
+	case chat.ErrorTypeRejected:
+		return aiRequestFailed(codes.FailedPrecondition, err, appErr.Message)
+	default:
+		// A request the provider rejected as invalid still left the user's message stored
+		if errors.As(err, new(*chat.AIRequestError)) {
+			return aiRequestFailed(codes.InvalidArgument, err, appErr.Message)
+		}
+		return validationStatus(err)
+	}
+}
+
+// aiRequestFailed converts an upstream failure to a status with code. When the user's
+// message was stored before the failure, a *chat.AIRequestError, an ErrorInfo detail names
+// the conversation and message so the client can retry in the same conversation, and a
+// RetryInfo detail carries the delay the provider asked for, if any.
+func aiRequestFailed(code codes.Code, err error, message string) *status.Status {
+	st := status.New(code, message)
+
+	var aiErr *chat.AIRequestError
+	if !errors.As(err, &aiErr) {
+		return st
+	}
+
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: aiErr.Err.Code,
+		Domain: "chat-service",
+		Metadata: map[string]string{
+			"conversation_id": aiErr.ConversationID,
+			"user_message_id": aiErr.UserMessage.ID,
+		},
+	}}
+	if aiErr.RetryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(aiErr.RetryAfter)})
+	}
+	if detailed, detailErr := st.WithDetails(details...); detailErr == nil {
+		return detailed
+	}
+	return st
+}
+
+// invalidArgument converts a rejected request to an InvalidArgument status error, see
+// validationStatus
+func invalidArgument(err error) error {
+	return validationStatus(err).Err()
+}
+
+// validationStatus converts a rejected request to an InvalidArgument status. A
+// *domain.ValidationError is attached as a BadRequest detail with one field violation per
+// invalid field, which the REST gateway turns into the response details.
+func validationStatus(err error) *status.Status {
+	st := status.Newf(codes.InvalidArgument, "validation error: %v", err)
+
+	var validationErr *domain.ValidationError
+	if !errors.As(err, &validationErr) {
+		return st
+	}
+
+	badRequest := &errdetails.BadRequest{}
+	for _, violation := range validationErr.Violations {
+		badRequest.FieldViolations = append(badRequest.FieldViolations, &errdetails.BadRequest_FieldViolation{
+			Field:       violation.Field,
+			Description: violation.Reason,
+		})
+	}
+	if detailed, detailErr := st.WithDetails(badRequest); detailErr == nil {
+		return detailed
+	}
+	return st
+}
+
+// chatWithAIRequestFromProto converts an AI chat request to its domain form, validates it
+// and fills in defaults for unset options. The model is left empty when unset so the service
+// picks the active provider's default.
+func chatWithAIRequestFromProto(userID string, req *proto.ChatWithAIRequest) (*domain.ChatWithAIRequest, error) {
+	aiReq := &domain.ChatWithAIRequest{
+		UserID:         userID,
+		Message:        req.Message,
+		ConversationID: req.ConversationId,
+		Model:          req.Model,
+		Metadata:       metadataFromProto(req.GetMetadata()),
+	}
+	if req.Temperature != nil {
+		temperature := float64(req.GetTemperature())
+		aiReq.Temperature = &temperature
+	}
+	if req.MaxTokens != nil {
+		maxTokens := int(req.GetMaxTokens())
+		aiReq.MaxTokens = &maxTokens
+	}
+	if err := aiReq.Validate(); err != nil {
+		return nil, err
+	}
+	return aiReq, nil
+}
+
+// IdempotencyKeyMetadata is the request metadata key, forwarded from the REST
+// Idempotency-Key header, that makes SendMessage safe to retry
+const IdempotencyKeyMetadata = "idempotency-key"
+
+// idempotencyKey returns the idempotency key sent with the request, if any
+func idempotencyKey(ctx context.Context) string {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return ""
+	}
+	if values := md.Get(IdempotencyKeyMetadata); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
+
 // Helper functions to convert between domain and proto types
 func (h *ChatHandler) convertMessageToProto(msg *domain.Message) *proto.Message {
 	if msg == nil {
@@ -328,13 +1092,37 @@ func (h *ChatHandler) convertMessageToProto(msg *domain.Message) *proto.Message
 	}
 
 	return &proto.Message{
-		Id:        msg.ID,
-		UserId:    msg.UserID,
-		Content:   msg.Content,
-		Role:      msg.Role,
-		CreatedAt: timestamppb.New(msg.CreatedAt),
-		UpdatedAt: timestamppb.New(msg.UpdatedAt),
+		Id:             msg.ID,
+		UserId:         msg.UserID,
+		ConversationId: msg.ConversationID,
+		Content:        msg.Content,
+		Role:           string(msg.Role),
+		CreatedAt:      timestamppb.New(msg.CreatedAt),
+		UpdatedAt:      timestamppb.New(msg.UpdatedAt),
+		Metadata:       metadataToProto(msg.Metadata),
+	}
+}
+
+// metadataFromProto converts request metadata to its domain form, leaving it nil when the
+// client sent none
+func metadataFromProto(fields *structpb.Struct) domain.Metadata {
+	if fields == nil {
+		return nil
 	}
+	return fields.AsMap()
+}
+
+// metadataToProto converts stored message metadata to a Struct. Stored metadata is decoded
+// JSON, which always converts, so a failure only drops the metadata.
+func metadataToProto(m domain.Metadata) *structpb.Struct {
+	if m == nil {
+		return nil
+	}
+	converted, err := structpb.NewStruct(m)
+	if err != nil {
+		return nil
+	}
+	return converted
 }
 
 func (h *ChatHandler) convertConversationToProto(conv *domain.Conversation) *proto.Conversation {
@@ -342,10 +1130,30 @@ func (h *ChatHandler) convertConversationToProto(conv *domain.Conversation) *pro
 		return nil
 	}
 
-	return &proto.Conversation{
-		Id:        conv.ID,
-		Title:     conv.Title,
-		CreatedAt: timestamppb.New(conv.CreatedAt),
-		UpdatedAt: timestamppb.New(conv.UpdatedAt),
+	protoConv := &proto.Conversation{
+		Id:           conv.ID,
+		Title:        conv.Title,
+		CreatedAt:    timestamppb.New(conv.CreatedAt),
+		UpdatedAt:    timestamppb.New(conv.UpdatedAt),
+		MessageCount: int32(conv.MessageCount),
+		Archived:     conv.Archived,
+		Pinned:       conv.Pinned,
+	}
+	if conv.LastMessageAt != nil {
+		protoConv.LastMessageAt = timestamppb.New(*conv.LastMessageAt)
+	}
+	return protoConv
+}
+
+func (h *ChatHandler) convertPageInfoToProto(page *domain.PageInfo) *proto.PageInfo {
+	if page == nil {
+		return nil
+	}
+
+	return &proto.PageInfo{
+		Limit:      int32(page.Limit),
+		Offset:     int32(page.Offset),
+		HasMore:    page.HasMore,
+		NextOffset: int32(page.NextOffset),
 	}
 }
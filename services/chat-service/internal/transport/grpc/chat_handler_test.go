@@ -0,0 +1,1408 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/internal/domain/domaintest"
+	"chat-service/internal/services/chat"
+	"chat-service/proto"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	testUserID         = "11111111-1111-1111-1111-111111111111"
+	testConversationID = "33333333-3333-3333-3333-333333333333"
+	testMessageID      = "44444444-4444-4444-4444-444444444444"
+)
+
+// stubChatService overrides the chat.Service methods exercised by a test
+type stubChatService struct {
+	chat.Service
+	streamMessages      func(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error
+	subscribeMessages   func(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error
+	chatWithAI          func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error)
+	chatWithAIStream    func(ctx context.Context, userID, message, conversationID string, onDelta func(conversationID, delta string) error) (*domain.ChatResponse, error)
+	getConversation     func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	deleteConversation  func(ctx context.Context, userID, conversationID string) error
+	clearConversation   func(ctx context.Context, userID, conversationID string) (int, error)
+	restoreConversation func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error)
+	setConversationFlag func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) // archive, unarchive, pin and unpin
+	editMessage         func(ctx context.Context, userID, messageID, content string) (*domain.Message, error)
+	getHistory          func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error)
+	search              func(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error)
+	listUserMessages    func(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error)
+	importConversation  func(ctx context.Context, userID, title string, messages []domain.Message) (*domain.Conversation, error)
+	sendMessage         func(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error)
+}
+
+func (s *stubChatService) ChatWithAI(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+	return s.chatWithAI(ctx, userID, message, conversationID, model, temperature, maxTokens, metadata)
+}
+
+func (s *stubChatService) ChatWithAIStream(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata, onDelta func(conversationID, delta string) error) (*domain.ChatResponse, error) {
+	return s.chatWithAIStream(ctx, userID, message, conversationID, onDelta)
+}
+
+func (s *stubChatService) GetConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.getConversation(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) DeleteConversation(ctx context.Context, userID, conversationID string) error {
+	return s.deleteConversation(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) ClearConversationMessages(ctx context.Context, userID, conversationID string) (int, error) {
+	return s.clearConversation(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) RestoreConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.restoreConversation(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) ArchiveConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setConversationFlag(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) UnarchiveConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setConversationFlag(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) PinConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setConversationFlag(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) UnpinConversation(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+	return s.setConversationFlag(ctx, userID, conversationID)
+}
+
+func (s *stubChatService) EditMessage(ctx context.Context, userID, messageID, content string) (*domain.Message, error) {
+	return s.editMessage(ctx, userID, messageID, content)
+}
+
+func (s *stubChatService) GetHistory(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+	return s.getHistory(ctx, req)
+}
+
+func (s *stubChatService) Search(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error) {
+	return s.search(ctx, req)
+}
+
+func (s *stubChatService) ListUserMessages(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error) {
+	return s.listUserMessages(ctx, userID, limit, offset)
+}
+
+func (s *stubChatService) SendMessage(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+	return s.sendMessage(ctx, req)
+}
+
+func (s *stubChatService) ImportConversation(ctx context.Context, userID, title string, messages []domain.Message) (*domain.Conversation, error) {
+	return s.importConversation(ctx, userID, title, messages)
+}
+
+func (s *stubChatService) StreamMessages(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+	return s.streamMessages(ctx, userID, conversationID, send)
+}
+
+func (s *stubChatService) SubscribeMessages(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+	return s.subscribeMessages(ctx, userID, conversationID, send)
+}
+
+// fakeStream records the responses sent on a StreamMessages server stream
+type fakeStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*proto.StreamMessageResponse
+}
+
+func (f *fakeStream) Context() context.Context { return f.ctx }
+
+func (f *fakeStream) Send(resp *proto.StreamMessageResponse) error {
+	f.sent = append(f.sent, resp)
+	return nil
+}
+
+// fakeAIStream records the responses sent on a ChatWithAIStream server stream
+type fakeAIStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*proto.ChatWithAIStreamResponse
+}
+
+func (f *fakeAIStream) Context() context.Context { return f.ctx }
+
+func (f *fakeAIStream) Send(resp *proto.ChatWithAIStreamResponse) error {
+	f.sent = append(f.sent, resp)
+	return nil
+}
+
+func newTestHandler(svc chat.Service) *ChatHandler {
+	return NewChatHandler(svc, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+}
+
+func userContext(userID string) context.Context {
+	return context.WithValue(context.Background(), "user_id", userID)
+}
+
+func TestChatHandler_StreamMessages_SendsMessagesThenEnd(t *testing.T) {
+	svc := &stubChatService{
+		streamMessages: func(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+			for _, content := range []string{"first", "second", "third"} {
+				if err := send(domaintest.NewMessage(userID, conversationID, content, "user")); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	stream := &fakeStream{ctx: userContext(testUserID)}
+
+	err := newTestHandler(svc).StreamMessages(&proto.StreamMessageRequest{ConversationId: testConversationID}, stream)
+
+	require.NoError(t, err)
+	require.Len(t, stream.sent, 4)
+	for i, content := range []string{"first", "second", "third"} {
+		assert.False(t, stream.sent[i].IsEnd)
+		assert.Equal(t, content, stream.sent[i].Message.Content)
+	}
+	assert.True(t, stream.sent[3].IsEnd)
+	assert.Nil(t, stream.sent[3].Message)
+}
+
+func TestChatHandler_StreamMessages_Subscribe(t *testing.T) {
+	ctx, cancel := context.WithCancel(userContext(testUserID))
+	svc := &stubChatService{
+		subscribeMessages: func(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+			if err := send(domaintest.NewMessage(userID, conversationID, "live", "assistant")); err != nil {
+				return err
+			}
+			cancel()
+			<-ctx.Done()
+			return ctx.Err()
+		},
+	}
+	stream := &fakeStream{ctx: ctx}
+
+	err := newTestHandler(svc).StreamMessages(&proto.StreamMessageRequest{ConversationId: testConversationID, Subscribe: true}, stream)
+
+	assert.Equal(t, codes.Canceled, status.Code(err))
+	require.Len(t, stream.sent, 1, "a subscription ends with the client, without an end message")
+	assert.Equal(t, "live", stream.sent[0].Message.Content)
+}
+
+func TestChatHandler_StreamMessages_SubscriberTooSlow(t *testing.T) {
+	svc := &stubChatService{
+		subscribeMessages: func(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+			return chat.ErrSubscriberTooSlow
+		},
+	}
+	stream := &fakeStream{ctx: userContext(testUserID)}
+
+	err := newTestHandler(svc).StreamMessages(&proto.StreamMessageRequest{ConversationId: testConversationID, Subscribe: true}, stream)
+
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Empty(t, stream.sent)
+}
+
+func TestChatHandler_StreamMessages_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+		serviceErr     error
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "invalid conversation id",
+			conversationID: "not-a-uuid",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "conversation not found",
+			conversationID: testConversationID,
+			serviceErr:     chat.ErrConversationNotFound,
+			expectedCode:   codes.NotFound,
+		},
+		{
+			name:           "client cancelled",
+			conversationID: testConversationID,
+			serviceErr:     context.Canceled,
+			expectedCode:   codes.Canceled,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				streamMessages: func(ctx context.Context, userID, conversationID string, send func(*domain.Message) error) error {
+					return tt.serviceErr
+				},
+			}
+			stream := &fakeStream{ctx: userContext(testUserID)}
+
+			err := newTestHandler(svc).StreamMessages(&proto.StreamMessageRequest{ConversationId: tt.conversationID}, stream)
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Empty(t, stream.sent, "no end marker should be sent on failure")
+		})
+	}
+}
+
+// TestChatHandler_MalformedConversationID checks that a malformed conversation ID is rejected
+// with InvalidArgument before it reaches the service, whose storage would fail with Internal
+func TestChatHandler_MalformedConversationID(t *testing.T) {
+	// The stub has no service methods set, so reaching the service panics
+	handler := newTestHandler(&stubChatService{})
+	ctx := userContext(testUserID)
+
+	tests := []struct {
+		name string
+		call func(conversationID string) error
+	}{
+		{
+			name: "SendMessage",
+			call: func(conversationID string) error {
+				_, err := handler.SendMessage(ctx, &proto.ChatRequest{Message: "hello", ConversationId: conversationID})
+				return err
+			},
+		},
+		{
+			name: "GetHistory",
+			call: func(conversationID string) error {
+				_, err := handler.GetHistory(ctx, &proto.GetHistoryRequest{ConversationId: conversationID})
+				return err
+			},
+		},
+		{
+			name: "ChatWithAI",
+			call: func(conversationID string) error {
+				_, err := handler.ChatWithAI(ctx, &proto.ChatWithAIRequest{Message: "hello", ConversationId: conversationID})
+				return err
+			},
+		},
+		{
+			name: "ChatWithAIStream",
+			call: func(conversationID string) error {
+				return handler.ChatWithAIStream(&proto.ChatWithAIRequest{Message: "hello", ConversationId: conversationID}, &fakeAIStream{ctx: ctx})
+			},
+		},
+		{
+			name: "StreamMessages",
+			call: func(conversationID string) error {
+				return handler.StreamMessages(&proto.StreamMessageRequest{ConversationId: conversationID}, &fakeStream{ctx: ctx})
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		for _, conversationID := range []string{"not-a-uuid", "33333333-3333-3333-3333-33333333333", "'; DROP TABLE messages; --"} {
+			t.Run(tt.name+"/"+conversationID, func(t *testing.T) {
+				err := tt.call(conversationID)
+
+				assert.Equal(t, codes.InvalidArgument, status.Code(err))
+				assert.Contains(t, status.Convert(err).Message(), "conversation_id")
+			})
+		}
+	}
+}
+
+func TestChatHandler_ChatWithAIStream_NewConversationIDOnEveryFrame(t *testing.T) {
+	svc := &stubChatService{
+		chatWithAIStream: func(ctx context.Context, userID, message, conversationID string, onDelta func(conversationID, delta string) error) (*domain.ChatResponse, error) {
+			// No conversation was given, so the service starts one before streaming
+			for _, delta := range []string{"Hello", " there"} {
+				if err := onDelta(testConversationID, delta); err != nil {
+					return nil, err
+				}
+			}
+			return &domain.ChatResponse{
+				Message:        domaintest.NewMessage(userID, testConversationID, "Hello there", "assistant"),
+				ConversationID: testConversationID,
+				IsAIResponse:   true,
+			}, nil
+		},
+	}
+	stream := &fakeAIStream{ctx: userContext(testUserID)}
+
+	err := newTestHandler(svc).ChatWithAIStream(&proto.ChatWithAIRequest{Message: "hello"}, stream)
+
+	require.NoError(t, err)
+	require.Len(t, stream.sent, 3)
+	for _, frame := range stream.sent {
+		assert.Equal(t, testConversationID, frame.ConversationId)
+	}
+	assert.Equal(t, "Hello", stream.sent[0].Delta)
+	assert.True(t, stream.sent[2].IsEnd)
+}
+
+func TestChatHandler_ChatWithAI_ReturnsTokensUsed(t *testing.T) {
+	svc := &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			return &domain.ChatResponse{
+				Message:        domaintest.NewMessage(userID, testConversationID, "The answer is 42.", "assistant"),
+				ConversationID: testConversationID,
+				IsAIResponse:   true,
+				Model:          model,
+				Usage:          &domain.TokenUsage{PromptTokens: 12, CompletionTokens: 18, TotalTokens: 30},
+				FinishReason:   "stop",
+			}, nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{
+		Message:        "What is the answer?",
+		ConversationId: testConversationID,
+		Model:          "gpt-3.5-turbo",
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(30), resp.TokensUsed)
+	assert.Equal(t, "The answer is 42.", resp.AiMessage)
+	assert.Equal(t, "gpt-3.5-turbo", resp.ModelUsed)
+	assert.Equal(t, "stop", resp.FinishReason)
+}
+
+func TestChatHandler_ChatWithAI_EmptyResponseErrors(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode codes.Code
+	}{
+		{name: "content filtered", serviceErr: chat.ErrAIResponseFiltered, expectedCode: codes.FailedPrecondition},
+		{name: "flagged by moderation", serviceErr: &chat.AppError{Type: chat.ErrorTypeContentFiltered, Code: "MESSAGE_FLAGGED", Message: "message was flagged by content moderation: violence"}, expectedCode: codes.FailedPrecondition},
+		{name: "truncated", serviceErr: chat.ErrAIResponseTruncated, expectedCode: codes.FailedPrecondition},
+		{name: "empty", serviceErr: chat.ErrAIResponseEmpty, expectedCode: codes.Unavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+					return nil, tt.serviceErr
+				},
+			}
+
+			_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{Message: "Hi", ConversationId: testConversationID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			assert.Equal(t, tt.serviceErr.(*chat.AppError).Message, status.Convert(err).Message())
+		})
+	}
+}
+
+func TestChatHandler_ChatWithAI_ProviderFailureCarriesConversation(t *testing.T) {
+	userMsg := &domain.Message{ID: "44444444-4444-4444-4444-444444444444", ConversationID: testConversationID, Role: "user"}
+	svc := &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			return nil, &chat.AIRequestError{
+				ConversationID: userMsg.ConversationID,
+				UserMessage:    userMsg,
+				Err:            &chat.AppError{Type: chat.ErrorTypeUnavailable, Code: "AI_REQUEST_FAILED", Message: "failed to get AI response", Err: errors.New("upstream unavailable")},
+			}
+		},
+	}
+
+	_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{Message: "Hi"})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.Unavailable, st.Code())
+	assert.Equal(t, "failed to get AI response", st.Message())
+	details := st.Details()
+	require.Len(t, details, 1)
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "AI_REQUEST_FAILED", info.Reason)
+	assert.Equal(t, map[string]string{
+		"conversation_id": testConversationID,
+		"user_message_id": userMsg.ID,
+	}, info.Metadata)
+}
+
+func TestChatHandler_ChatWithAI_ProviderRateLimitCarriesRetryDelay(t *testing.T) {
+	userMsg := &domain.Message{ID: "44444444-4444-4444-4444-444444444444", ConversationID: testConversationID, Role: "user"}
+	svc := &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			return nil, &chat.AIRequestError{
+				ConversationID: userMsg.ConversationID,
+				UserMessage:    userMsg,
+				RetryAfter:     7 * time.Second,
+				Err:            &chat.AppError{Type: chat.ErrorTypeRateLimited, Code: "AI_RATE_LIMITED", Message: "AI provider rate limit reached; retry later"},
+			}
+		},
+	}
+
+	_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{Message: "Hi"})
+
+	st := status.Convert(err)
+	assert.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, "AI provider rate limit reached; retry later", st.Message())
+	details := st.Details()
+	require.Len(t, details, 2)
+	info, ok := details[0].(*errdetails.ErrorInfo)
+	require.True(t, ok)
+	assert.Equal(t, "AI_RATE_LIMITED", info.Reason)
+	assert.Equal(t, testConversationID, info.Metadata["conversation_id"])
+	retryInfo, ok := details[1].(*errdetails.RetryInfo)
+	require.True(t, ok)
+	assert.Equal(t, 7*time.Second, retryInfo.RetryDelay.AsDuration())
+}
+
+func TestChatHandler_ChatWithAI_GenerationOptions(t *testing.T) {
+	zeroTemperature, temperature := float32(0), float32(1.5)
+	zeroMaxTokens, maxTokens := int32(0), int32(250)
+
+	tests := []struct {
+		name                string
+		temperature         *float32
+		maxTokens           *int32
+		expectedTemperature float64
+		expectedMaxTokens   int
+	}{
+		{name: "unset uses defaults", expectedTemperature: domain.DefaultAITemperature, expectedMaxTokens: domain.DefaultAIMaxTokens},
+		{name: "zero temperature is honored", temperature: &zeroTemperature, expectedTemperature: 0, expectedMaxTokens: domain.DefaultAIMaxTokens},
+		{name: "zero max tokens is passed on", maxTokens: &zeroMaxTokens, expectedTemperature: domain.DefaultAITemperature, expectedMaxTokens: 0},
+		{name: "explicit values", temperature: &temperature, maxTokens: &maxTokens, expectedTemperature: 1.5, expectedMaxTokens: 250},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotTemperature float64
+			var gotMaxTokens int
+			svc := &stubChatService{
+				chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+					gotTemperature, gotMaxTokens = temperature, maxTokens
+					return &domain.ChatResponse{
+						Message:        domaintest.NewMessage(userID, testConversationID, "Hello!", "assistant"),
+						ConversationID: testConversationID,
+						IsAIResponse:   true,
+					}, nil
+				},
+			}
+
+			_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{
+				Message:        "Hi",
+				ConversationId: testConversationID,
+				Temperature:    tt.temperature,
+				MaxTokens:      tt.maxTokens,
+			})
+
+			require.NoError(t, err)
+			assert.InDelta(t, tt.expectedTemperature, gotTemperature, 1e-6)
+			assert.Equal(t, tt.expectedMaxTokens, gotMaxTokens)
+		})
+	}
+}
+
+func TestChatHandler_ChatWithAI_InvalidGenerationOptions(t *testing.T) {
+	serviceErr := chat.NewValidationError("INVALID_TEMPERATURE", "temperature must be between 0 and 2 for model gpt-3.5-turbo", nil)
+	svc := &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			return nil, serviceErr
+		},
+	}
+	temperature := float32(9)
+
+	_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{
+		Message:        "Hi",
+		ConversationId: testConversationID,
+		Temperature:    &temperature,
+	})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Contains(t, status.Convert(err).Message(), "between 0 and 2")
+}
+
+func TestChatHandler_SendMessage_ConversationQuotaExceeded(t *testing.T) {
+	svc := &stubChatService{
+		sendMessage: func(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+			return nil, fmt.Errorf("%w (limit 2)", chat.ErrConversationQuotaExceeded)
+		},
+	}
+
+	_, err := newTestHandler(svc).SendMessage(userContext(testUserID), &proto.ChatRequest{Message: "hello"})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, chat.ErrConversationQuotaExceeded.Message, status.Convert(err).Message())
+}
+
+func TestChatHandler_ChatWithAI_DailyTokenBudgetExceeded(t *testing.T) {
+	svc := &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			return nil, fmt.Errorf("%w (budget 100)", chat.ErrDailyTokenBudgetExceeded)
+		},
+	}
+
+	_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{Message: "Hi"})
+
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, chat.ErrDailyTokenBudgetExceeded.Message, status.Convert(err).Message())
+}
+
+func TestChatHandler_SendMessage_PassesIdempotencyKey(t *testing.T) {
+	var got *domain.ChatRequest
+	svc := &stubChatService{
+		sendMessage: func(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+			got = req
+			message := domaintest.NewMessage(req.UserID, testConversationID, req.Message, "user")
+			return &domain.ChatResponse{Message: message, ConversationID: testConversationID}, nil
+		},
+	}
+	ctx := metadata.NewIncomingContext(userContext(testUserID), metadata.Pairs(IdempotencyKeyMetadata, "retry-1"))
+
+	_, err := newTestHandler(svc).SendMessage(ctx, &proto.ChatRequest{Message: "hello"})
+
+	require.NoError(t, err)
+	assert.Equal(t, "retry-1", got.IdempotencyKey)
+}
+
+func TestChatHandler_SendMessage_RejectsOverlongIdempotencyKey(t *testing.T) {
+	ctx := metadata.NewIncomingContext(userContext(testUserID), metadata.Pairs(IdempotencyKeyMetadata, strings.Repeat("a", domain.MaxIdempotencyKeyLength+1)))
+
+	_, err := newTestHandler(&stubChatService{}).SendMessage(ctx, &proto.ChatRequest{Message: "hello"})
+
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestChatHandler_SendMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode codes.Code
+	}{
+		{
+			name:         "conversation not found",
+			serviceErr:   fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID),
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "validation",
+			serviceErr:   chat.NewValidationError("INVALID_CURSOR", "invalid cursor", errors.New("bad encoding")),
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "typed internal error",
+			serviceErr:   chat.NewInternalError("STORAGE", "storage unavailable", errors.New("connection refused")),
+			expectedCode: codes.Internal,
+		},
+		{
+			name:         "untyped error",
+			serviceErr:   errors.New("connection refused"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				sendMessage: func(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+					return nil, tt.serviceErr
+				},
+			}
+
+			_, err := newTestHandler(svc).SendMessage(userContext(testUserID), &proto.ChatRequest{Message: "hello", ConversationId: testConversationID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_ChatWithAI_ConversationNotFound(t *testing.T) {
+	svc := &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			return nil, fmt.Errorf("%w: %s", chat.ErrConversationNotFound, conversationID)
+		},
+	}
+
+	_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), &proto.ChatWithAIRequest{
+		Message:        "Hi",
+		ConversationId: testConversationID,
+	})
+
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "conversation not found", status.Convert(err).Message())
+}
+
+func TestChatHandler_ChatWithAI_PassesMetadata(t *testing.T) {
+	tests := []struct {
+		name     string
+		metadata map[string]any
+		want     domain.Metadata
+	}{
+		{name: "set", metadata: map[string]any{"source": "mobile", "tags": []any{"draft"}}, want: domain.Metadata{"source": "mobile", "tags": []any{"draft"}}},
+		{name: "unset", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got domain.Metadata
+			svc := &stubChatService{
+				chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+					got = metadata
+					return &domain.ChatResponse{
+						Message:        domaintest.NewMessage(userID, testConversationID, "Hello!", "assistant"),
+						ConversationID: testConversationID,
+					}, nil
+				},
+			}
+			req := &proto.ChatWithAIRequest{Message: "Hi", ConversationId: testConversationID}
+			if tt.metadata != nil {
+				fields, err := structpb.NewStruct(tt.metadata)
+				require.NoError(t, err)
+				req.Metadata = fields
+			}
+
+			_, err := newTestHandler(svc).ChatWithAI(userContext(testUserID), req)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestChatHandler_SendMessage_RejectsOversizedMetadata(t *testing.T) {
+	svc := &stubChatService{
+		sendMessage: func(ctx context.Context, req *domain.ChatRequest) (*domain.ChatResponse, error) {
+			t.Fatal("service should not be called")
+			return nil, nil
+		},
+	}
+	fields, err := structpb.NewStruct(map[string]any{"note": strings.Repeat("x", domain.MaxMetadataBytes)})
+	require.NoError(t, err)
+
+	_, err = newTestHandler(svc).SendMessage(userContext(testUserID), &proto.ChatRequest{Message: "hello", Metadata: fields})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+	require.Len(t, badRequest.GetFieldViolations(), 1)
+	assert.Equal(t, "metadata", badRequest.GetFieldViolations()[0].GetField())
+	assert.Equal(t, "too large (max 4096 bytes)", badRequest.GetFieldViolations()[0].GetDescription())
+}
+
+func TestChatHandler_GetHistory_IncludesMetadata(t *testing.T) {
+	svc := &stubChatService{
+		getHistory: func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+			annotated := domaintest.NewMessage(req.UserID, req.ConversationID, "hello", "user")
+			annotated.Metadata = domain.Metadata{"source": "mobile"}
+			plain := domaintest.NewMessage(req.UserID, req.ConversationID, "Hi!", "assistant")
+			return &domain.GetHistoryResponse{
+				Messages:       []*domain.Message{annotated, plain},
+				Total:          2,
+				ConversationID: req.ConversationID,
+			}, nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).GetHistory(userContext(testUserID), &proto.GetHistoryRequest{ConversationId: testConversationID})
+
+	require.NoError(t, err)
+	require.Len(t, resp.Messages, 2)
+	assert.Equal(t, map[string]any{"source": "mobile"}, resp.Messages[0].GetMetadata().AsMap())
+	assert.Nil(t, resp.Messages[1].GetMetadata())
+}
+
+func TestChatHandler_GetConversation(t *testing.T) {
+	svc := &stubChatService{
+		getConversation: func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+			conversation := domain.NewConversation(userID, "Trip planning")
+			conversation.ID = conversationID
+			conversation.MessageCount = 4
+			return conversation, nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).GetConversation(userContext(testUserID), &proto.GetConversationRequest{ConversationId: testConversationID})
+
+	require.NoError(t, err)
+	assert.Equal(t, testConversationID, resp.Id)
+	assert.Equal(t, "Trip planning", resp.Title)
+	assert.Equal(t, int32(4), resp.MessageCount)
+}
+
+func TestChatHandler_GetConversation_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+		serviceErr     error
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "invalid conversation id",
+			conversationID: "not-a-uuid",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "conversation not found or owned by another user",
+			conversationID: testConversationID,
+			serviceErr:     fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID),
+			expectedCode:   codes.NotFound,
+		},
+		{
+			name:           "storage failure",
+			conversationID: testConversationID,
+			serviceErr:     errors.New("database unavailable"),
+			expectedCode:   codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				getConversation: func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+					return nil, tt.serviceErr
+				},
+			}
+
+			_, err := newTestHandler(svc).GetConversation(userContext(testUserID), &proto.GetConversationRequest{ConversationId: tt.conversationID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_DeleteConversation(t *testing.T) {
+	var gotUserID, gotConversationID string
+	svc := &stubChatService{
+		deleteConversation: func(ctx context.Context, userID, conversationID string) error {
+			gotUserID, gotConversationID = userID, conversationID
+			return nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).DeleteConversation(userContext(testUserID), &proto.DeleteConversationRequest{ConversationId: testConversationID})
+
+	require.NoError(t, err)
+	assert.NotNil(t, resp)
+	assert.Equal(t, testUserID, gotUserID)
+	assert.Equal(t, testConversationID, gotConversationID)
+}
+
+func TestChatHandler_DeleteConversation_Errors(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+		serviceErr     error
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "invalid conversation id",
+			conversationID: "not-a-uuid",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "conversation not found or owned by another user",
+			conversationID: testConversationID,
+			serviceErr:     fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID),
+			expectedCode:   codes.NotFound,
+		},
+		{
+			name:           "storage failure",
+			conversationID: testConversationID,
+			serviceErr:     errors.New("database unavailable"),
+			expectedCode:   codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				deleteConversation: func(ctx context.Context, userID, conversationID string) error {
+					return tt.serviceErr
+				},
+			}
+
+			_, err := newTestHandler(svc).DeleteConversation(userContext(testUserID), &proto.DeleteConversationRequest{ConversationId: tt.conversationID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_ClearConversationMessages(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+		serviceErr     error
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "cleared",
+			conversationID: testConversationID,
+			expectedCode:   codes.OK,
+		},
+		{
+			name:           "invalid conversation id",
+			conversationID: "not-a-uuid",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "conversation not found or owned by another user",
+			conversationID: testConversationID,
+			serviceErr:     fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID),
+			expectedCode:   codes.NotFound,
+		},
+		{
+			name:           "storage failure",
+			conversationID: testConversationID,
+			serviceErr:     errors.New("database unavailable"),
+			expectedCode:   codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var gotUserID string
+			svc := &stubChatService{
+				clearConversation: func(ctx context.Context, userID, conversationID string) (int, error) {
+					gotUserID = userID
+					if tt.serviceErr != nil {
+						return 0, tt.serviceErr
+					}
+					return 4, nil
+				},
+			}
+
+			resp, err := newTestHandler(svc).ClearConversationMessages(userContext(testUserID), &proto.ClearConversationMessagesRequest{ConversationId: tt.conversationID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Equal(t, testUserID, gotUserID)
+				assert.Equal(t, int32(4), resp.DeletedCount)
+			}
+		})
+	}
+}
+
+func TestChatHandler_ImportConversation(t *testing.T) {
+	createdAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	var gotUserID, gotTitle string
+	var gotMessages []domain.Message
+	svc := &stubChatService{
+		importConversation: func(ctx context.Context, userID, title string, messages []domain.Message) (*domain.Conversation, error) {
+			gotUserID, gotTitle, gotMessages = userID, title, messages
+			return domain.NewConversation(userID, title), nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).ImportConversation(userContext(testUserID), &proto.ImportConversationRequest{
+		Title: "Imported",
+		Messages: []*proto.Message{
+			{Content: "hello", Role: "user", CreatedAt: timestamppb.New(createdAt)},
+			{Content: "hi there", Role: "assistant"},
+		},
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "Imported", resp.Conversation.Title)
+	assert.Equal(t, int32(2), resp.MessageCount)
+	assert.Equal(t, testUserID, gotUserID)
+	assert.Equal(t, "Imported", gotTitle)
+	require.Len(t, gotMessages, 2)
+	assert.Equal(t, domain.Message{Content: "hello", Role: "user", CreatedAt: createdAt}, gotMessages[0])
+	assert.Equal(t, domain.Message{Content: "hi there", Role: "assistant"}, gotMessages[1])
+}
+
+func TestChatHandler_ImportConversation_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		serviceErr   error
+		expectedCode codes.Code
+	}{
+		{
+			name:         "invalid import",
+			serviceErr:   fmt.Errorf("%w: title cannot be empty", chat.ErrInvalidImport),
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "storage failure",
+			serviceErr:   errors.New("database unavailable"),
+			expectedCode: codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				importConversation: func(ctx context.Context, userID, title string, messages []domain.Message) (*domain.Conversation, error) {
+					return nil, tt.serviceErr
+				},
+			}
+
+			_, err := newTestHandler(svc).ImportConversation(userContext(testUserID), &proto.ImportConversationRequest{})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_RestoreConversation(t *testing.T) {
+	tests := []struct {
+		name           string
+		conversationID string
+		serviceErr     error
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "restored",
+			conversationID: testConversationID,
+			expectedCode:   codes.OK,
+		},
+		{
+			name:           "invalid conversation id",
+			conversationID: "not-a-uuid",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "not deleted or outside the restore window",
+			conversationID: testConversationID,
+			serviceErr:     fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID),
+			expectedCode:   codes.NotFound,
+		},
+		{
+			name:           "storage failure",
+			conversationID: testConversationID,
+			serviceErr:     errors.New("database unavailable"),
+			expectedCode:   codes.Internal,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				restoreConversation: func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+					if tt.serviceErr != nil {
+						return nil, tt.serviceErr
+					}
+					conversation := domain.NewConversation(userID, "Restored")
+					conversation.ID = conversationID
+					return conversation, nil
+				},
+			}
+
+			resp, err := newTestHandler(svc).RestoreConversation(userContext(testUserID), &proto.RestoreConversationRequest{ConversationId: tt.conversationID})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+			if tt.expectedCode == codes.OK {
+				assert.Equal(t, testConversationID, resp.Id)
+			}
+		})
+	}
+}
+
+func TestChatHandler_SetConversationFlag(t *testing.T) {
+	rpcs := map[string]func(h *ChatHandler, ctx context.Context, conversationID string) (*proto.Conversation, error){
+		"ArchiveConversation": func(h *ChatHandler, ctx context.Context, conversationID string) (*proto.Conversation, error) {
+			return h.ArchiveConversation(ctx, &proto.ArchiveConversationRequest{ConversationId: conversationID})
+		},
+		"UnarchiveConversation": func(h *ChatHandler, ctx context.Context, conversationID string) (*proto.Conversation, error) {
+			return h.UnarchiveConversation(ctx, &proto.UnarchiveConversationRequest{ConversationId: conversationID})
+		},
+		"PinConversation": func(h *ChatHandler, ctx context.Context, conversationID string) (*proto.Conversation, error) {
+			return h.PinConversation(ctx, &proto.PinConversationRequest{ConversationId: conversationID})
+		},
+		"UnpinConversation": func(h *ChatHandler, ctx context.Context, conversationID string) (*proto.Conversation, error) {
+			return h.UnpinConversation(ctx, &proto.UnpinConversationRequest{ConversationId: conversationID})
+		},
+	}
+
+	tests := []struct {
+		name           string
+		conversationID string
+		serviceErr     error
+		expectedCode   codes.Code
+	}{
+		{
+			name:           "updated",
+			conversationID: testConversationID,
+			expectedCode:   codes.OK,
+		},
+		{
+			name:           "invalid conversation id",
+			conversationID: "not-a-uuid",
+			expectedCode:   codes.InvalidArgument,
+		},
+		{
+			name:           "not found",
+			conversationID: testConversationID,
+			serviceErr:     fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID),
+			expectedCode:   codes.NotFound,
+		},
+		{
+			name:           "storage failure",
+			conversationID: testConversationID,
+			serviceErr:     errors.New("database unavailable"),
+			expectedCode:   codes.Internal,
+		},
+	}
+
+	for method, call := range rpcs {
+		for _, tt := range tests {
+			t.Run(method+"/"+tt.name, func(t *testing.T) {
+				svc := &stubChatService{
+					setConversationFlag: func(ctx context.Context, userID, conversationID string) (*domain.Conversation, error) {
+						if tt.serviceErr != nil {
+							return nil, tt.serviceErr
+						}
+						conversation := domain.NewConversation(userID, "Flagged")
+						conversation.ID = conversationID
+						conversation.Archived = true
+						conversation.Pinned = true
+						return conversation, nil
+					},
+				}
+
+				resp, err := call(newTestHandler(svc), userContext(testUserID), tt.conversationID)
+
+				assert.Equal(t, tt.expectedCode, status.Code(err))
+				if tt.expectedCode == codes.OK {
+					assert.Equal(t, testConversationID, resp.Id)
+					assert.True(t, resp.Archived)
+					assert.True(t, resp.Pinned)
+				}
+			})
+		}
+	}
+}
+
+func TestChatHandler_EditMessage_Errors(t *testing.T) {
+	tests := []struct {
+		name         string
+		messageID    string
+		content      string
+		serviceErr   error
+		expectedCode codes.Code
+	}{
+		{
+			name:         "invalid message id",
+			messageID:    "not-a-uuid",
+			content:      "edited",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "empty content",
+			messageID:    testMessageID,
+			content:      "",
+			expectedCode: codes.InvalidArgument,
+		},
+		{
+			name:         "message not found or owned by another user",
+			messageID:    testMessageID,
+			content:      "edited",
+			serviceErr:   fmt.Errorf("%w: %s", chat.ErrMessageNotFound, testMessageID),
+			expectedCode: codes.NotFound,
+		},
+		{
+			name:         "assistant message",
+			messageID:    testMessageID,
+			content:      "edited",
+			serviceErr:   fmt.Errorf("%w: %s", chat.ErrAssistantMessageEdit, testMessageID),
+			expectedCode: codes.PermissionDenied,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				editMessage: func(ctx context.Context, userID, messageID, content string) (*domain.Message, error) {
+					return nil, tt.serviceErr
+				},
+			}
+
+			_, err := newTestHandler(svc).EditMessage(userContext(testUserID), &proto.EditMessageRequest{MessageId: tt.messageID, Content: tt.content})
+
+			assert.Equal(t, tt.expectedCode, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_GetHistory_Cursor(t *testing.T) {
+	var got *domain.GetHistoryRequest
+	svc := &stubChatService{
+		getHistory: func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+			got = req
+			return &domain.GetHistoryResponse{ConversationID: req.ConversationID, NextCursor: "next"}, nil
+		},
+	}
+
+	cursor := ""
+	resp, err := newTestHandler(svc).GetHistory(userContext(testUserID), &proto.GetHistoryRequest{
+		ConversationId: testConversationID,
+		Cursor:         &cursor,
+	})
+
+	require.NoError(t, err)
+	require.NotNil(t, got.Cursor)
+	assert.Equal(t, "", *got.Cursor)
+	assert.Equal(t, "next", resp.NextCursor)
+}
+
+func TestChatHandler_GetHistory_PageInfo(t *testing.T) {
+	svc := &stubChatService{
+		getHistory: func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+			return &domain.GetHistoryResponse{
+				ConversationID: req.ConversationID,
+				Total:          5,
+				PageInfo:       domain.NewPageInfo(2, 2, 2, 5),
+			}, nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).GetHistory(userContext(testUserID), &proto.GetHistoryRequest{
+		ConversationId: testConversationID,
+		Limit:          2,
+		Offset:         2,
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, int32(5), resp.Total)
+	require.NotNil(t, resp.PageInfo)
+	assert.Equal(t, int32(2), resp.PageInfo.Limit)
+	assert.Equal(t, int32(2), resp.PageInfo.Offset)
+	assert.True(t, resp.PageInfo.HasMore)
+	assert.Equal(t, int32(4), resp.PageInfo.NextOffset)
+}
+
+func TestChatHandler_GetHistory_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		err      error
+		expected codes.Code
+	}{
+		{"missing conversation", fmt.Errorf("%w: %s", chat.ErrConversationNotFound, testConversationID), codes.NotFound},
+		{"offset beyond the cap", chat.NewValidationError("HISTORY_OFFSET_TOO_LARGE", "history offset too large", nil), codes.InvalidArgument},
+		{"storage failure", errors.New("db down"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				getHistory: func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+					return nil, tt.err
+				},
+			}
+
+			_, err := newTestHandler(svc).GetHistory(userContext(testUserID), &proto.GetHistoryRequest{ConversationId: testConversationID})
+			assert.Equal(t, tt.expected, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_GetHistory_InvalidCursor(t *testing.T) {
+	svc := &stubChatService{
+		getHistory: func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+			t.Fatal("service should not be called")
+			return nil, nil
+		},
+	}
+
+	for name, req := range map[string]*proto.GetHistoryRequest{
+		"malformed cursor":   {ConversationId: testConversationID, Cursor: stringPtr("not-a-cursor")},
+		"cursor with offset": {ConversationId: testConversationID, Cursor: stringPtr(""), Offset: 10},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestHandler(svc).GetHistory(userContext(testUserID), req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_SearchMessages(t *testing.T) {
+	var got *domain.SearchMessagesRequest
+	svc := &stubChatService{
+		search: func(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error) {
+			got = req
+			message := domaintest.NewMessage(req.UserID, testConversationID, "deploy to production", "user")
+			return &domain.SearchMessagesResponse{
+				Results: []*domain.MessageSearchResult{{Message: *message, Snippet: "«deploy» to production"}},
+			}, nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).SearchMessages(userContext(testUserID), &proto.SearchMessagesRequest{Q: "deploy"})
+	require.NoError(t, err)
+
+	// The search is always scoped to the authenticated user
+	assert.Equal(t, testUserID, got.UserID)
+	assert.Equal(t, "deploy", got.Query)
+	assert.Equal(t, defaultSearchLimit, got.Limit)
+
+	require.Len(t, resp.Results, 1)
+	assert.Equal(t, testConversationID, resp.Results[0].ConversationId)
+	assert.Equal(t, "«deploy» to production", resp.Results[0].Snippet)
+	assert.Equal(t, "deploy to production", resp.Results[0].Message.Content)
+}
+
+func TestChatHandler_SearchMessages_InvalidQuery(t *testing.T) {
+	svc := &stubChatService{
+		search: func(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error) {
+			t.Fatal("service should not be called")
+			return nil, nil
+		},
+	}
+
+	for name, req := range map[string]*proto.SearchMessagesRequest{
+		"empty query":    {Q: "   "},
+		"query too long": {Q: strings.Repeat("a", domain.MaxSearchQueryLength+1)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := newTestHandler(svc).SearchMessages(userContext(testUserID), req)
+			assert.Equal(t, codes.InvalidArgument, status.Code(err))
+		})
+	}
+}
+
+func TestChatHandler_SearchMessages_ReportsFieldViolations(t *testing.T) {
+	svc := &stubChatService{
+		search: func(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error) {
+			t.Fatal("service should not be called")
+			return nil, nil
+		},
+	}
+
+	_, err := newTestHandler(svc).SearchMessages(userContext(testUserID), &proto.SearchMessagesRequest{Q: "   ", Limit: 101, Offset: -1})
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	details := status.Convert(err).Details()
+	require.Len(t, details, 1)
+	badRequest, ok := details[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+
+	violations := make(map[string]string)
+	for _, violation := range badRequest.GetFieldViolations() {
+		violations[violation.GetField()] = violation.GetDescription()
+	}
+	// The paging fields are clamped rather than rejected, so only the query is reported
+	assert.Equal(t, map[string]string{
+		"query": "cannot be empty",
+	}, violations)
+}
+
+func TestChatHandler_ClampsPaging(t *testing.T) {
+	var historyLimit, historyOffset, searchLimit, searchOffset, messagesLimit, messagesOffset int
+	svc := &stubChatService{
+		getHistory: func(ctx context.Context, req *domain.GetHistoryRequest) (*domain.GetHistoryResponse, error) {
+			historyLimit, historyOffset = req.Limit, req.Offset
+			return &domain.GetHistoryResponse{}, nil
+		},
+		search: func(ctx context.Context, req *domain.SearchMessagesRequest) (*domain.SearchMessagesResponse, error) {
+			searchLimit, searchOffset = req.Limit, req.Offset
+			return &domain.SearchMessagesResponse{}, nil
+		},
+		listUserMessages: func(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error) {
+			messagesLimit, messagesOffset = limit, offset
+			return &domain.ListUserMessagesResponse{}, nil
+		},
+	}
+	handler := newTestHandler(svc)
+	ctx := userContext(testUserID)
+
+	_, err := handler.GetHistory(ctx, &proto.GetHistoryRequest{ConversationId: testConversationID, Limit: 1 << 30, Offset: -5})
+	require.NoError(t, err)
+	assert.Equal(t, domain.MaxPageLimit, historyLimit)
+	assert.Equal(t, 0, historyOffset)
+
+	_, err = handler.SearchMessages(ctx, &proto.SearchMessagesRequest{Q: "deploy", Limit: 101, Offset: -1})
+	require.NoError(t, err)
+	assert.Equal(t, domain.MaxPageLimit, searchLimit)
+	assert.Equal(t, 0, searchOffset)
+
+	_, err = handler.ListUserMessages(ctx, &proto.ListUserMessagesRequest{Limit: -1, Offset: 7})
+	require.NoError(t, err)
+	assert.Equal(t, defaultUserMessagesLimit, messagesLimit)
+	assert.Equal(t, 7, messagesOffset)
+}
+
+func TestChatHandler_ListUserMessages(t *testing.T) {
+	var gotUserID string
+	var gotLimit, gotOffset int
+	svc := &stubChatService{
+		listUserMessages: func(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error) {
+			gotUserID, gotLimit, gotOffset = userID, limit, offset
+			return &domain.ListUserMessagesResponse{
+				Messages: []*domain.Message{domaintest.NewMessage(userID, testConversationID, "hello", "user")},
+				Total:    3,
+				PageInfo: domain.NewPageInfo(limit, offset, 1, 3),
+			}, nil
+		},
+	}
+
+	resp, err := newTestHandler(svc).ListUserMessages(userContext(testUserID), &proto.ListUserMessagesRequest{Offset: 2})
+	require.NoError(t, err)
+
+	// The listing is always scoped to the authenticated user
+	assert.Equal(t, testUserID, gotUserID)
+	assert.Equal(t, defaultUserMessagesLimit, gotLimit)
+	assert.Equal(t, 2, gotOffset)
+
+	assert.Equal(t, int32(3), resp.Total)
+	require.Len(t, resp.Messages, 1)
+	assert.Equal(t, testConversationID, resp.Messages[0].ConversationId)
+	require.NotNil(t, resp.PageInfo)
+	assert.False(t, resp.PageInfo.HasMore)
+}
+
+func TestChatHandler_ListUserMessages_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      *proto.ListUserMessagesRequest
+		err      error
+		expected codes.Code
+	}{
+		{"user mismatch", &proto.ListUserMessagesRequest{}, chat.ErrUserMismatch, codes.PermissionDenied},
+		{"storage failure", &proto.ListUserMessagesRequest{}, errors.New("db down"), codes.Internal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &stubChatService{
+				listUserMessages: func(ctx context.Context, userID string, limit, offset int) (*domain.ListUserMessagesResponse, error) {
+					if tt.err == nil {
+						t.Fatal("service should not be called")
+					}
+					return nil, tt.err
+				},
+			}
+
+			_, err := newTestHandler(svc).ListUserMessages(userContext(testUserID), tt.req)
+			assert.Equal(t, tt.expected, status.Code(err))
+		})
+	}
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestConvertConversationToProto_MessageActivity(t *testing.T) {
+	h := newTestHandler(&stubChatService{})
+	lastMessageAt := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	conversation := domain.NewConversation(testUserID, "Active")
+	conversation.MessageCount = 3
+	conversation.LastMessageAt = &lastMessageAt
+
+	protoConv := h.convertConversationToProto(conversation)
+	assert.Equal(t, int32(3), protoConv.MessageCount)
+	assert.Equal(t, lastMessageAt, protoConv.LastMessageAt.AsTime())
+
+	protoConv = h.convertConversationToProto(domain.NewConversation(testUserID, "Empty"))
+	assert.Zero(t, protoConv.MessageCount)
+	assert.Nil(t, protoConv.LastMessageAt)
+}
@@ -0,0 +1,34 @@
+package grpc
+
+import (
+	"context"
+
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"google.golang.org/grpc"
+)
+
+// withRequestID stores the request ID sent in the x-request-id metadata as the context's
+// correlation ID, so the handler's logs line up with the gateway's. Calls without a
+// well-formed ID get a new one.
+func withRequestID(ctx context.Context) context.Context {
+	return zlog.WithCorrelationID(ctx, middleware.IncomingRequestID(ctx, middleware.RequestIDMetadata))
+}
+
+// UnaryRequestIDInterceptor tags each unary call with its request ID
+func UnaryRequestIDInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		return handler(withRequestID(ctx), req)
+	}
+}
+
+// StreamRequestIDInterceptor tags each stream with its request ID
+func StreamRequestIDInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &wrappedServerStream{
+			ServerStream: stream,
+			ctx:          withRequestID(stream.Context()),
+		})
+	}
+}
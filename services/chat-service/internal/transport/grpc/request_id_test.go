@@ -0,0 +1,59 @@
+package grpc
+
+import (
+	"context"
+	"testing"
+
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+func TestUnaryRequestIDInterceptor(t *testing.T) {
+	tests := []struct {
+		name     string
+		md       metadata.MD
+		wantKept string
+	}{
+		{name: "forwarded id kept", md: metadata.Pairs(middleware.RequestIDMetadata, "req-42"), wantKept: "req-42"},
+		{name: "missing id generated", md: metadata.MD{}},
+		{name: "unsafe id replaced", md: metadata.Pairs(middleware.RequestIDMetadata, "bad id\nx")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := metadata.NewIncomingContext(context.Background(), tt.md)
+
+			var seen string
+			_, err := UnaryRequestIDInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+				seen = zlog.CorrelationID(ctx)
+				return nil, nil
+			})
+			require.NoError(t, err)
+
+			if tt.wantKept != "" {
+				assert.Equal(t, tt.wantKept, seen)
+			} else {
+				assert.NotEmpty(t, seen)
+				assert.True(t, middleware.ValidRequestID(seen))
+			}
+		})
+	}
+}
+
+func TestStreamRequestIDInterceptor(t *testing.T) {
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(middleware.RequestIDMetadata, "req-42"))
+
+	var seen string
+	err := StreamRequestIDInterceptor()(nil, &wrappedServerStream{ctx: ctx}, &grpc.StreamServerInfo{}, func(srv any, stream grpc.ServerStream) error {
+		seen = zlog.CorrelationID(stream.Context())
+		return nil
+	})
+
+	require.NoError(t, err)
+	assert.Equal(t, "req-42", seen)
+}
@@ -0,0 +1,91 @@
+package grpc
+
+import (
+	"sync"
+
+	zlog "packages/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamLimiter caps how many streams each user can hold open at once, so a single user
+// cannot tie up the server with long-lived connections. A nil limiter or a zero maximum
+// leaves streams unlimited.
+type StreamLimiter struct {
+	max    int
+	mu     sync.Mutex
+	active map[string]int
+}
+
+// NewStreamLimiter creates a limiter allowing each user max concurrent streams
+func NewStreamLimiter(max int) *StreamLimiter {
+	return &StreamLimiter{
+		max:    max,
+		active: make(map[string]int),
+	}
+}
+
+// Acquire reserves a stream slot for userID. It reports false when the user already holds
+// the maximum; otherwise release must be called once the stream ends. Calling release more
+// than once has no further effect.
+func (l *StreamLimiter) Acquire(userID string) (release func(), ok bool) {
+	if l == nil || l.max <= 0 {
+		return func() {}, true
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.active[userID] >= l.max {
+		return nil, false
+	}
+	l.active[userID]++
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			defer l.mu.Unlock()
+			if l.active[userID]--; l.active[userID] <= 0 {
+				delete(l.active, userID)
+			}
+		})
+	}, true
+}
+
+// Active returns the number of streams userID currently holds open
+func (l *StreamLimiter) Active(userID string) int {
+	if l == nil {
+		return 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active[userID]
+}
+
+// StreamInterceptor rejects a stream with ResourceExhausted when its user already holds the
+// maximum number of streams. It must run after the auth interceptor, which puts the user ID
+// on the stream context; unauthenticated streams (health checks) are not counted. The slot
+// is released when the handler returns, even if it panics.
+func (l *StreamLimiter) StreamInterceptor(logger *zlog.Logger) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		userID, _ := stream.Context().Value("user_id").(string)
+		if userID == "" {
+			return handler(srv, stream)
+		}
+
+		release, ok := l.Acquire(userID)
+		if !ok {
+			logger.Warn(stream.Context(), "Concurrent stream limit reached", map[string]any{
+				"method":      info.FullMethod,
+				"user_id":     userID,
+				"max_streams": l.max,
+			})
+			return status.Errorf(codes.ResourceExhausted, "too many concurrent streams: at most %d per user", l.max)
+		}
+		defer release()
+
+		return handler(srv, stream)
+	}
+}
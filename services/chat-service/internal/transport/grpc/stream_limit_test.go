@@ -0,0 +1,132 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const otherUserID = "22222222-2222-2222-2222-222222222222"
+
+var streamInfo = &grpc.StreamServerInfo{FullMethod: "/chat.ChatService/StreamMessages", IsServerStream: true}
+
+// openStreams starts n streams for userID through interceptor, each held open until hold is
+// closed. It returns once every stream has been admitted or rejected, with the errors of
+// the rejected ones.
+func openStreams(t *testing.T, interceptor grpc.StreamServerInterceptor, userID string, n int, hold <-chan struct{}, done *sync.WaitGroup) []error {
+	t.Helper()
+
+	var mu sync.Mutex
+	var errs []error
+	var settled sync.WaitGroup
+	for range n {
+		settled.Add(1)
+		done.Add(1)
+		go func() {
+			defer done.Done()
+			admitted := false
+			err := interceptor(nil, &fakeStream{ctx: userContext(userID)}, streamInfo, func(srv any, stream grpc.ServerStream) error {
+				admitted = true
+				settled.Done()
+				<-hold
+				return nil
+			})
+			if !admitted {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+				settled.Done()
+			}
+		}()
+	}
+	settled.Wait()
+	return errs
+}
+
+func TestStreamLimiter_RejectsStreamsOverTheLimit(t *testing.T) {
+	const maxStreams = 3
+	limiter := NewStreamLimiter(maxStreams)
+	interceptor := limiter.StreamInterceptor(zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+
+	hold := make(chan struct{})
+	var done sync.WaitGroup
+
+	errs := openStreams(t, interceptor, testUserID, maxStreams+1, hold, &done)
+	require.Len(t, errs, 1, "exactly one stream should be rejected")
+	assert.Equal(t, codes.ResourceExhausted, status.Code(errs[0]))
+	assert.Equal(t, "too many concurrent streams: at most 3 per user", status.Convert(errs[0]).Message())
+	assert.Equal(t, maxStreams, limiter.Active(testUserID))
+
+	otherErrs := openStreams(t, interceptor, otherUserID, maxStreams, hold, &done)
+	assert.Empty(t, otherErrs, "another user's streams should not be affected")
+	assert.Equal(t, maxStreams, limiter.Active(otherUserID))
+
+	close(hold)
+	done.Wait()
+	assert.Zero(t, limiter.Active(testUserID), "slots should be released when streams complete")
+	assert.Zero(t, limiter.Active(otherUserID))
+
+	err := interceptor(nil, &fakeStream{ctx: userContext(testUserID)}, streamInfo, func(srv any, stream grpc.ServerStream) error {
+		return nil
+	})
+	assert.NoError(t, err, "a freed slot should admit a new stream")
+}
+
+func TestStreamLimiter_ReleasesSlotOnPanic(t *testing.T) {
+	limiter := NewStreamLimiter(1)
+	interceptor := limiter.StreamInterceptor(zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+
+	assert.Panics(t, func() {
+		interceptor(nil, &fakeStream{ctx: userContext(testUserID)}, streamInfo, func(srv any, stream grpc.ServerStream) error {
+			panic("handler failed")
+		})
+	})
+
+	assert.Zero(t, limiter.Active(testUserID))
+	release, ok := limiter.Acquire(testUserID)
+	require.True(t, ok)
+	release()
+}
+
+func TestStreamLimiter_ZeroIsUnlimited(t *testing.T) {
+	for _, limiter := range []*StreamLimiter{NewStreamLimiter(0), nil} {
+		for range 100 {
+			_, ok := limiter.Acquire(testUserID)
+			require.True(t, ok)
+		}
+	}
+}
+
+func TestStreamLimiter_ReleaseIsIdempotent(t *testing.T) {
+	limiter := NewStreamLimiter(2)
+
+	first, ok := limiter.Acquire(testUserID)
+	require.True(t, ok)
+	_, ok = limiter.Acquire(testUserID)
+	require.True(t, ok)
+
+	first()
+	first()
+	assert.Equal(t, 1, limiter.Active(testUserID))
+}
+
+func TestStreamLimiter_SkipsUnauthenticatedStreams(t *testing.T) {
+	limiter := NewStreamLimiter(1)
+	interceptor := limiter.StreamInterceptor(zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+
+	for range 3 {
+		err := interceptor(nil, &fakeStream{ctx: context.Background()}, &grpc.StreamServerInfo{FullMethod: "/health.Health/Watch"}, func(srv any, stream grpc.ServerStream) error {
+			return nil
+		})
+		require.NoError(t, err)
+	}
+}
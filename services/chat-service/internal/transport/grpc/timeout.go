@@ -0,0 +1,38 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	zlog "packages/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryTimeoutInterceptor gives each unary call at most timeout to complete. Storage and
+// LLM provider calls take their deadline from the request context, so they are abandoned
+// once it passes and the call fails with DeadlineExceeded. A shorter deadline set by the
+// client still applies. A zero timeout leaves calls unbounded.
+func UnaryTimeoutInterceptor(timeout time.Duration, logger *zlog.Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if timeout <= 0 {
+			return handler(ctx, req)
+		}
+
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		resp, err := handler(ctx, req)
+		if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			logger.Warn(ctx, "Request timed out", map[string]any{
+				"method":  info.FullMethod,
+				"timeout": timeout.String(),
+			})
+			return nil, status.Error(codes.DeadlineExceeded, "request timed out")
+		}
+		return resp, err
+	}
+}
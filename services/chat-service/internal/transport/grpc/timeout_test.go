@@ -0,0 +1,87 @@
+package grpc
+
+import (
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/internal/domain/domaintest"
+	"chat-service/proto"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// slowChatService answers ChatWithAI after delay, or fails with the context error once the
+// request deadline passes, like a provider call over HTTP
+func slowChatService(delay time.Duration) *stubChatService {
+	return &stubChatService{
+		chatWithAI: func(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata) (*domain.ChatResponse, error) {
+			select {
+			case <-time.After(delay):
+				return &domain.ChatResponse{
+					Message:        domaintest.NewMessage(userID, testConversationID, "done", "assistant"),
+					ConversationID: testConversationID,
+				}, nil
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		},
+	}
+}
+
+// callChatWithAI runs ChatWithAI through the timeout interceptor
+func callChatWithAI(ctx context.Context, timeout time.Duration, svc *stubChatService) (any, error) {
+	interceptor := UnaryTimeoutInterceptor(timeout, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+	info := &grpc.UnaryServerInfo{FullMethod: "/chat.ChatService/ChatWithAI"}
+	return interceptor(ctx, &proto.ChatWithAIRequest{Message: "Hi"}, info, func(ctx context.Context, req any) (any, error) {
+		return newTestHandler(svc).ChatWithAI(ctx, req.(*proto.ChatWithAIRequest))
+	})
+}
+
+func TestUnaryTimeoutInterceptor_SlowProviderTimesOut(t *testing.T) {
+	start := time.Now()
+	resp, err := callChatWithAI(userContext(testUserID), 50*time.Millisecond, slowChatService(5*time.Second))
+
+	assert.Nil(t, resp)
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Equal(t, "request timed out", status.Convert(err).Message())
+	assert.Less(t, time.Since(start), time.Second, "the call must give up at the deadline")
+}
+
+func TestUnaryTimeoutInterceptor_FastCallSucceeds(t *testing.T) {
+	resp, err := callChatWithAI(userContext(testUserID), time.Second, slowChatService(0))
+
+	require.NoError(t, err)
+	assert.Equal(t, "done", resp.(*proto.ChatWithAIResponse).AiMessage)
+}
+
+func TestUnaryTimeoutInterceptor_KeepsShorterClientDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(userContext(testUserID), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err := callChatWithAI(ctx, time.Minute, slowChatService(5*time.Second))
+
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestUnaryTimeoutInterceptor_ZeroDisables(t *testing.T) {
+	var deadlineSet bool
+	interceptor := UnaryTimeoutInterceptor(0, zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}))
+
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req any) (any, error) {
+		_, deadlineSet = ctx.Deadline()
+		return nil, nil
+	})
+
+	require.NoError(t, err)
+	assert.False(t, deadlineSet)
+}
@@ -325,7 +325,7 @@ func handleChatWithAI(w http.ResponseWriter, r *http.Request, chatService chat.S
 
 	// Call chat service
 	ctx := r.Context()
-	response, err := chatService.ChatWithAI(ctx, userID, req.Message, req.ConversationID, req.Model, req.Temperature, req.MaxTokens)
+	response, err := chatService.ChatWithAI(ctx, userID, req.Message, req.ConversationID, req.Model, req.Temperature, req.MaxTokens, nil)
 	if err != nil {
 		logger.Error(ctx, err, "Failed to chat with AI", 500)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
@@ -586,7 +586,7 @@ func NewServer(ctx context.Context) (*Server, error) {
 	}
 
 	// Initialize logger
-	logger := zlog.NewLogger(zlog.Config{
+	logger := zlog.New(zlog.Config{
 		Level:      cfg.LogLevel,
 		Output:     os.Stdout,
 		JSONFormat: cfg.LogJSONFormat,
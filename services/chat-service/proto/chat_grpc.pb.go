@@ -26,10 +26,38 @@ type ChatServiceClient interface {
 	GetHistory(ctx context.Context, in *GetHistoryRequest, opts ...grpc.CallOption) (*GetHistoryResponse, error)
 	// Chat with OpenAI AI
 	ChatWithAI(ctx context.Context, in *ChatWithAIRequest, opts ...grpc.CallOption) (*ChatWithAIResponse, error)
+	// Chat with OpenAI AI, streaming the response as it is generated
+	ChatWithAIStream(ctx context.Context, in *ChatWithAIRequest, opts ...grpc.CallOption) (ChatService_ChatWithAIStreamClient, error)
 	// List user conversations
 	ListConversations(ctx context.Context, in *ListConversationsRequest, opts ...grpc.CallOption) (*ListConversationsResponse, error)
+	// Search the user's messages by content
+	SearchMessages(ctx context.Context, in *SearchMessagesRequest, opts ...grpc.CallOption) (*SearchMessagesResponse, error)
+	// List the user's recent messages across all conversations
+	ListUserMessages(ctx context.Context, in *ListUserMessagesRequest, opts ...grpc.CallOption) (*ListUserMessagesResponse, error)
 	// Create new conversation
 	CreateConversation(ctx context.Context, in *Conversation, opts ...grpc.CallOption) (*Conversation, error)
+	// Import a conversation and all of its messages atomically
+	ImportConversation(ctx context.Context, in *ImportConversationRequest, opts ...grpc.CallOption) (*ImportConversationResponse, error)
+	// Get a single conversation with its message count
+	GetConversation(ctx context.Context, in *GetConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	// Delete a conversation and its messages
+	DeleteConversation(ctx context.Context, in *DeleteConversationRequest, opts ...grpc.CallOption) (*Empty, error)
+	// Delete every message of a conversation, keeping the conversation
+	ClearConversationMessages(ctx context.Context, in *ClearConversationMessagesRequest, opts ...grpc.CallOption) (*ClearConversationMessagesResponse, error)
+	// Restore a recently deleted conversation and its messages
+	RestoreConversation(ctx context.Context, in *RestoreConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	// Archive a conversation, hiding it from the default conversation list
+	ArchiveConversation(ctx context.Context, in *ArchiveConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	// Unarchive a conversation, returning it to the default conversation list
+	UnarchiveConversation(ctx context.Context, in *UnarchiveConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	// Pin a conversation, listing it before the others
+	PinConversation(ctx context.Context, in *PinConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	// Unpin a conversation, returning it to its place by update time
+	UnpinConversation(ctx context.Context, in *UnpinConversationRequest, opts ...grpc.CallOption) (*Conversation, error)
+	// Edit the content of a message
+	EditMessage(ctx context.Context, in *EditMessageRequest, opts ...grpc.CallOption) (*Message, error)
+	// Delete a message
+	DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*Empty, error)
 }
 
 type chatServiceClient struct {
@@ -99,6 +127,38 @@ func (c *chatServiceClient) ChatWithAI(ctx context.Context, in *ChatWithAIReques
 	return out, nil
 }
 
+func (c *chatServiceClient) ChatWithAIStream(ctx context.Context, in *ChatWithAIRequest, opts ...grpc.CallOption) (ChatService_ChatWithAIStreamClient, error) {
+	stream, err := c.cc.NewStream(ctx, &ChatService_ServiceDesc.Streams[1], "/chat.ChatService/ChatWithAIStream", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &chatServiceChatWithAIStreamClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type ChatService_ChatWithAIStreamClient interface {
+	Recv() (*ChatWithAIStreamResponse, error)
+	grpc.ClientStream
+}
+
+type chatServiceChatWithAIStreamClient struct {
+	grpc.ClientStream
+}
+
+func (x *chatServiceChatWithAIStreamClient) Recv() (*ChatWithAIStreamResponse, error) {
+	m := new(ChatWithAIStreamResponse)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 func (c *chatServiceClient) ListConversations(ctx context.Context, in *ListConversationsRequest, opts ...grpc.CallOption) (*ListConversationsResponse, error) {
 	out := new(ListConversationsResponse)
 	err := c.cc.Invoke(ctx, "/chat.ChatService/ListConversations", in, out, opts...)
@@ -108,6 +168,24 @@ func (c *chatServiceClient) ListConversations(ctx context.Context, in *ListConve
 	return out, nil
 }
 
+func (c *chatServiceClient) SearchMessages(ctx context.Context, in *SearchMessagesRequest, opts ...grpc.CallOption) (*SearchMessagesResponse, error) {
+	out := new(SearchMessagesResponse)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/SearchMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ListUserMessages(ctx context.Context, in *ListUserMessagesRequest, opts ...grpc.CallOption) (*ListUserMessagesResponse, error) {
+	out := new(ListUserMessagesResponse)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/ListUserMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *chatServiceClient) CreateConversation(ctx context.Context, in *Conversation, opts ...grpc.CallOption) (*Conversation, error) {
 	out := new(Conversation)
 	err := c.cc.Invoke(ctx, "/chat.ChatService/CreateConversation", in, out, opts...)
@@ -117,6 +195,105 @@ func (c *chatServiceClient) CreateConversation(ctx context.Context, in *Conversa
 	return out, nil
 }
 
+func (c *chatServiceClient) ImportConversation(ctx context.Context, in *ImportConversationRequest, opts ...grpc.CallOption) (*ImportConversationResponse, error) {
+	out := new(ImportConversationResponse)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/ImportConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) GetConversation(ctx context.Context, in *GetConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/GetConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) DeleteConversation(ctx context.Context, in *DeleteConversationRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/DeleteConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ClearConversationMessages(ctx context.Context, in *ClearConversationMessagesRequest, opts ...grpc.CallOption) (*ClearConversationMessagesResponse, error) {
+	out := new(ClearConversationMessagesResponse)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/ClearConversationMessages", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) RestoreConversation(ctx context.Context, in *RestoreConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/RestoreConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) ArchiveConversation(ctx context.Context, in *ArchiveConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/ArchiveConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) UnarchiveConversation(ctx context.Context, in *UnarchiveConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/UnarchiveConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) PinConversation(ctx context.Context, in *PinConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/PinConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) UnpinConversation(ctx context.Context, in *UnpinConversationRequest, opts ...grpc.CallOption) (*Conversation, error) {
+	out := new(Conversation)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/UnpinConversation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) EditMessage(ctx context.Context, in *EditMessageRequest, opts ...grpc.CallOption) (*Message, error) {
+	out := new(Message)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/EditMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *chatServiceClient) DeleteMessage(ctx context.Context, in *DeleteMessageRequest, opts ...grpc.CallOption) (*Empty, error) {
+	out := new(Empty)
+	err := c.cc.Invoke(ctx, "/chat.ChatService/DeleteMessage", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // ChatServiceServer is the server API for ChatService service.
 // All implementations must embed UnimplementedChatServiceServer
 // for forward compatibility
@@ -129,10 +306,38 @@ type ChatServiceServer interface {
 	GetHistory(context.Context, *GetHistoryRequest) (*GetHistoryResponse, error)
 	// Chat with OpenAI AI
 	ChatWithAI(context.Context, *ChatWithAIRequest) (*ChatWithAIResponse, error)
+	// Chat with OpenAI AI, streaming the response as it is generated
+	ChatWithAIStream(*ChatWithAIRequest, ChatService_ChatWithAIStreamServer) error
 	// List user conversations
 	ListConversations(context.Context, *ListConversationsRequest) (*ListConversationsResponse, error)
+	// Search the user's messages by content
+	SearchMessages(context.Context, *SearchMessagesRequest) (*SearchMessagesResponse, error)
+	// List the user's recent messages across all conversations
+	ListUserMessages(context.Context, *ListUserMessagesRequest) (*ListUserMessagesResponse, error)
 	// Create new conversation
 	CreateConversation(context.Context, *Conversation) (*Conversation, error)
+	// Import a conversation and all of its messages atomically
+	ImportConversation(context.Context, *ImportConversationRequest) (*ImportConversationResponse, error)
+	// Get a single conversation with its message count
+	GetConversation(context.Context, *GetConversationRequest) (*Conversation, error)
+	// Delete a conversation and its messages
+	DeleteConversation(context.Context, *DeleteConversationRequest) (*Empty, error)
+	// Delete every message of a conversation, keeping the conversation
+	ClearConversationMessages(context.Context, *ClearConversationMessagesRequest) (*ClearConversationMessagesResponse, error)
+	// Restore a recently deleted conversation and its messages
+	RestoreConversation(context.Context, *RestoreConversationRequest) (*Conversation, error)
+	// Archive a conversation, hiding it from the default conversation list
+	ArchiveConversation(context.Context, *ArchiveConversationRequest) (*Conversation, error)
+	// Unarchive a conversation, returning it to the default conversation list
+	UnarchiveConversation(context.Context, *UnarchiveConversationRequest) (*Conversation, error)
+	// Pin a conversation, listing it before the others
+	PinConversation(context.Context, *PinConversationRequest) (*Conversation, error)
+	// Unpin a conversation, returning it to its place by update time
+	UnpinConversation(context.Context, *UnpinConversationRequest) (*Conversation, error)
+	// Edit the content of a message
+	EditMessage(context.Context, *EditMessageRequest) (*Message, error)
+	// Delete a message
+	DeleteMessage(context.Context, *DeleteMessageRequest) (*Empty, error)
 	mustEmbedUnimplementedChatServiceServer()
 }
 
@@ -152,12 +357,54 @@ func (UnimplementedChatServiceServer) GetHistory(context.Context, *GetHistoryReq
 func (UnimplementedChatServiceServer) ChatWithAI(context.Context, *ChatWithAIRequest) (*ChatWithAIResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ChatWithAI not implemented")
 }
+func (UnimplementedChatServiceServer) ChatWithAIStream(*ChatWithAIRequest, ChatService_ChatWithAIStreamServer) error {
+	return status.Errorf(codes.Unimplemented, "method ChatWithAIStream not implemented")
+}
 func (UnimplementedChatServiceServer) ListConversations(context.Context, *ListConversationsRequest) (*ListConversationsResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ListConversations not implemented")
 }
+func (UnimplementedChatServiceServer) SearchMessages(context.Context, *SearchMessagesRequest) (*SearchMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SearchMessages not implemented")
+}
+func (UnimplementedChatServiceServer) ListUserMessages(context.Context, *ListUserMessagesRequest) (*ListUserMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserMessages not implemented")
+}
 func (UnimplementedChatServiceServer) CreateConversation(context.Context, *Conversation) (*Conversation, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method CreateConversation not implemented")
 }
+func (UnimplementedChatServiceServer) ImportConversation(context.Context, *ImportConversationRequest) (*ImportConversationResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ImportConversation not implemented")
+}
+func (UnimplementedChatServiceServer) GetConversation(context.Context, *GetConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetConversation not implemented")
+}
+func (UnimplementedChatServiceServer) DeleteConversation(context.Context, *DeleteConversationRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteConversation not implemented")
+}
+func (UnimplementedChatServiceServer) ClearConversationMessages(context.Context, *ClearConversationMessagesRequest) (*ClearConversationMessagesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ClearConversationMessages not implemented")
+}
+func (UnimplementedChatServiceServer) RestoreConversation(context.Context, *RestoreConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RestoreConversation not implemented")
+}
+func (UnimplementedChatServiceServer) ArchiveConversation(context.Context, *ArchiveConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ArchiveConversation not implemented")
+}
+func (UnimplementedChatServiceServer) UnarchiveConversation(context.Context, *UnarchiveConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnarchiveConversation not implemented")
+}
+func (UnimplementedChatServiceServer) PinConversation(context.Context, *PinConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method PinConversation not implemented")
+}
+func (UnimplementedChatServiceServer) UnpinConversation(context.Context, *UnpinConversationRequest) (*Conversation, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UnpinConversation not implemented")
+}
+func (UnimplementedChatServiceServer) EditMessage(context.Context, *EditMessageRequest) (*Message, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EditMessage not implemented")
+}
+func (UnimplementedChatServiceServer) DeleteMessage(context.Context, *DeleteMessageRequest) (*Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteMessage not implemented")
+}
 func (UnimplementedChatServiceServer) mustEmbedUnimplementedChatServiceServer() {}
 
 // UnsafeChatServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -246,6 +493,27 @@ func _ChatService_ChatWithAI_Handler(srv interface{}, ctx context.Context, dec f
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatService_ChatWithAIStream_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ChatWithAIRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(ChatServiceServer).ChatWithAIStream(m, &chatServiceChatWithAIStreamServer{stream})
+}
+
+type ChatService_ChatWithAIStreamServer interface {
+	Send(*ChatWithAIStreamResponse) error
+	grpc.ServerStream
+}
+
+type chatServiceChatWithAIStreamServer struct {
+	grpc.ServerStream
+}
+
+func (x *chatServiceChatWithAIStreamServer) Send(m *ChatWithAIStreamResponse) error {
+	return x.ServerStream.SendMsg(m)
+}
+
 func _ChatService_ListConversations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(ListConversationsRequest)
 	if err := dec(in); err != nil {
@@ -264,6 +532,42 @@ func _ChatService_ListConversations_Handler(srv interface{}, ctx context.Context
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatService_SearchMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SearchMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).SearchMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/SearchMessages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).SearchMessages(ctx, req.(*SearchMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ListUserMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ListUserMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/ListUserMessages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ListUserMessages(ctx, req.(*ListUserMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _ChatService_CreateConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(Conversation)
 	if err := dec(in); err != nil {
@@ -282,6 +586,204 @@ func _ChatService_CreateConversation_Handler(srv interface{}, ctx context.Contex
 	return interceptor(ctx, in, info, handler)
 }
 
+func _ChatService_ImportConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ImportConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ImportConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/ImportConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ImportConversation(ctx, req.(*ImportConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_GetConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).GetConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/GetConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).GetConversation(ctx, req.(*GetConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_DeleteConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).DeleteConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/DeleteConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).DeleteConversation(ctx, req.(*DeleteConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ClearConversationMessages_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ClearConversationMessagesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ClearConversationMessages(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/ClearConversationMessages",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ClearConversationMessages(ctx, req.(*ClearConversationMessagesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_RestoreConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RestoreConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).RestoreConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/RestoreConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).RestoreConversation(ctx, req.(*RestoreConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_ArchiveConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ArchiveConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).ArchiveConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/ArchiveConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).ArchiveConversation(ctx, req.(*ArchiveConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_UnarchiveConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnarchiveConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).UnarchiveConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/UnarchiveConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).UnarchiveConversation(ctx, req.(*UnarchiveConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_PinConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PinConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).PinConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/PinConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).PinConversation(ctx, req.(*PinConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_UnpinConversation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UnpinConversationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).UnpinConversation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/UnpinConversation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).UnpinConversation(ctx, req.(*UnpinConversationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_EditMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EditMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).EditMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/EditMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).EditMessage(ctx, req.(*EditMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ChatService_DeleteMessage_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteMessageRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ChatServiceServer).DeleteMessage(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/chat.ChatService/DeleteMessage",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ChatServiceServer).DeleteMessage(ctx, req.(*DeleteMessageRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // ChatService_ServiceDesc is the grpc.ServiceDesc for ChatService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -305,10 +807,62 @@ var ChatService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "ListConversations",
 			Handler:    _ChatService_ListConversations_Handler,
 		},
+		{
+			MethodName: "SearchMessages",
+			Handler:    _ChatService_SearchMessages_Handler,
+		},
+		{
+			MethodName: "ListUserMessages",
+			Handler:    _ChatService_ListUserMessages_Handler,
+		},
 		{
 			MethodName: "CreateConversation",
 			Handler:    _ChatService_CreateConversation_Handler,
 		},
+		{
+			MethodName: "ImportConversation",
+			Handler:    _ChatService_ImportConversation_Handler,
+		},
+		{
+			MethodName: "GetConversation",
+			Handler:    _ChatService_GetConversation_Handler,
+		},
+		{
+			MethodName: "DeleteConversation",
+			Handler:    _ChatService_DeleteConversation_Handler,
+		},
+		{
+			MethodName: "ClearConversationMessages",
+			Handler:    _ChatService_ClearConversationMessages_Handler,
+		},
+		{
+			MethodName: "RestoreConversation",
+			Handler:    _ChatService_RestoreConversation_Handler,
+		},
+		{
+			MethodName: "ArchiveConversation",
+			Handler:    _ChatService_ArchiveConversation_Handler,
+		},
+		{
+			MethodName: "UnarchiveConversation",
+			Handler:    _ChatService_UnarchiveConversation_Handler,
+		},
+		{
+			MethodName: "PinConversation",
+			Handler:    _ChatService_PinConversation_Handler,
+		},
+		{
+			MethodName: "UnpinConversation",
+			Handler:    _ChatService_UnpinConversation_Handler,
+		},
+		{
+			MethodName: "EditMessage",
+			Handler:    _ChatService_EditMessage_Handler,
+		},
+		{
+			MethodName: "DeleteMessage",
+			Handler:    _ChatService_DeleteMessage_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -316,6 +870,11 @@ var ChatService_ServiceDesc = grpc.ServiceDesc{
 			Handler:       _ChatService_StreamMessages_Handler,
 			ServerStreams: true,
 		},
+		{
+			StreamName:    "ChatWithAIStream",
+			Handler:       _ChatService_ChatWithAIStream_Handler,
+			ServerStreams: true,
+		},
 	},
 	Metadata: "proto/chat.proto",
 }
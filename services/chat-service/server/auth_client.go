@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+
+	authproto "api/auth/v1/proto"
+	"chat-service/configs"
+	"packages/grpcutil"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// AuthClient is a long-lived client for the auth service, created once at startup and
+// shared by the REST handlers
+type AuthClient struct {
+	conn   *grpc.ClientConn
+	client authproto.AuthServiceClient
+	health authproto.HealthClient
+}
+
+// NewAuthClient connects to the configured auth service, using mTLS when both
+// AUTH_SERVICE_TLS and TLS_ENABLED are set
+func NewAuthClient(cfg *configs.Config) (*AuthClient, error) {
+	creds, err := authServiceCredentials(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	dialCfg := grpcutil.DefaultConfig()
+	dialCfg.LoadBalancingPolicy = cfg.AuthLBPolicy
+	dialCfg.Credentials = creds
+
+	conn, err := grpc.Dial(
+		cfg.AuthServiceDialTarget(),
+		append(grpcutil.DialOptions(dialCfg), grpc.WithStatsHandler(otelgrpc.NewClientHandler()))...,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
+	}
+
+	return newAuthClientFromConn(conn), nil
+}
+
+// newAuthClientFromConn wraps an existing connection to the auth service
+func newAuthClientFromConn(conn *grpc.ClientConn) *AuthClient {
+	return &AuthClient{
+		conn:   conn,
+		client: authproto.NewAuthServiceClient(conn),
+		health: authproto.NewHealthClient(conn),
+	}
+}
+
+// ValidateToken asks the auth service whether token is valid
+func (c *AuthClient) ValidateToken(ctx context.Context, token string) (*authproto.ValidateTokenResponse, error) {
+	return c.client.ValidateToken(ctx, &authproto.ValidateTokenRequest{
+		Token: token,
+	})
+}
+
+// Ping checks that the auth service is reachable and reports itself as serving
+func (c *AuthClient) Ping(ctx context.Context) error {
+	resp, err := c.health.Check(ctx, &authproto.HealthCheckRequest{})
+	if err != nil {
+		return err
+	}
+	if resp.Status != authproto.HealthCheckResponse_SERVING {
+		return fmt.Errorf("auth service is %s", resp.Status)
+	}
+	return nil
+}
+
+// Close closes the connection to the auth service
+func (c *AuthClient) Close() error {
+	return c.conn.Close()
+}
+
+// authServiceCredentials builds the transport credentials for the auth service connection
+func authServiceCredentials(cfg *configs.Config) (credentials.TransportCredentials, error) {
+	if !cfg.AuthServiceTLS || !cfg.TLSEnabled {
+		return insecure.NewCredentials(), nil
+	}
+
+	// Load client certificates for mTLS
+	cert, err := tls.LoadX509KeyPair(cfg.AuthServiceCertFile, cfg.AuthServiceKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificates: %w", err)
+	}
+
+	// Load CA certificate
+	caCert, err := os.ReadFile(cfg.AuthServiceCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %w", err)
+	}
+
+	caCertPool := x509.NewCertPool()
+	if !caCertPool.AppendCertsFromPEM(caCert) {
+		return nil, fmt.Errorf("failed to append CA certificate")
+	}
+
+	return credentials.NewTLS(&tls.Config{
+		Certificates: []tls.Certificate{cert},
+		RootCAs:      caCertPool,
+		ServerName:   cfg.AuthServiceHost,
+	}), nil
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	authproto "api/auth/v1/proto"
+	"chat-service/configs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/resolver/manual"
+)
+
+// stubAuthServer accepts every token and counts ValidateToken calls
+type stubAuthServer struct {
+	authproto.UnimplementedAuthServiceServer
+	mu     sync.Mutex
+	tokens []string
+}
+
+func (s *stubAuthServer) ValidateToken(ctx context.Context, req *authproto.ValidateTokenRequest) (*authproto.ValidateTokenResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tokens = append(s.tokens, req.Token)
+	return &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}, nil
+}
+
+func (s *stubAuthServer) callCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.tokens)
+}
+
+// countingListener counts the connections accepted by the wrapped listener
+type countingListener struct {
+	net.Listener
+	accepted atomic.Int32
+}
+
+func (l *countingListener) Accept() (net.Conn, error) {
+	conn, err := l.Listener.Accept()
+	if err == nil {
+		l.accepted.Add(1)
+	}
+	return conn, err
+}
+
+// newStubAuthService serves stub on a local port and returns a config pointing at it
+func newStubAuthService(t *testing.T, stub *stubAuthServer) (*configs.Config, *countingListener) {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	counting := &countingListener{Listener: lis}
+
+	grpcServer := grpc.NewServer()
+	authproto.RegisterAuthServiceServer(grpcServer, stub)
+	go grpcServer.Serve(counting)
+	t.Cleanup(grpcServer.Stop)
+
+	host, port, err := net.SplitHostPort(lis.Addr().String())
+	require.NoError(t, err)
+	return &configs.Config{AuthServiceHost: host, AuthServicePort: port}, counting
+}
+
+func TestAuthClient_ReusesConnection(t *testing.T) {
+	stub := &stubAuthServer{}
+	cfg, lis := newStubAuthService(t, stub)
+
+	client, err := NewAuthClient(cfg)
+	require.NoError(t, err)
+	defer client.Close()
+
+	for _, token := range []string{"first", "second", "third"} {
+		resp, err := client.ValidateToken(context.Background(), token)
+		require.NoError(t, err)
+		assert.True(t, resp.Valid)
+		assert.Equal(t, testUserID, resp.UserId)
+	}
+
+	assert.Equal(t, []string{"first", "second", "third"}, stub.tokens)
+	assert.Equal(t, int32(1), lis.accepted.Load(), "all calls should share one connection")
+}
+
+func TestAuthClient_FailsAfterClose(t *testing.T) {
+	cfg, _ := newStubAuthService(t, &stubAuthServer{})
+
+	client, err := NewAuthClient(cfg)
+	require.NoError(t, err)
+	require.NoError(t, client.Close())
+
+	_, err = client.ValidateToken(context.Background(), "token")
+	assert.Error(t, err)
+}
+
+// stubHealthServer answers auth service health checks with status
+type stubHealthServer struct {
+	authproto.UnimplementedHealthServer
+	status authproto.HealthCheckResponse_ServingStatus
+}
+
+func (s *stubHealthServer) Check(ctx context.Context, req *authproto.HealthCheckRequest) (*authproto.HealthCheckResponse, error) {
+	return &authproto.HealthCheckResponse{Status: s.status}, nil
+}
+
+func TestAuthClient_Ping(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  authproto.HealthCheckResponse_ServingStatus
+		wantErr bool
+	}{
+		{"serving", authproto.HealthCheckResponse_SERVING, false},
+		{"not serving", authproto.HealthCheckResponse_NOT_SERVING, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lis, err := net.Listen("tcp", "127.0.0.1:0")
+			require.NoError(t, err)
+			grpcServer := grpc.NewServer()
+			authproto.RegisterHealthServer(grpcServer, &stubHealthServer{status: tt.status})
+			go grpcServer.Serve(lis)
+			t.Cleanup(grpcServer.Stop)
+
+			host, port, err := net.SplitHostPort(lis.Addr().String())
+			require.NoError(t, err)
+			client, err := NewAuthClient(&configs.Config{AuthServiceHost: host, AuthServicePort: port})
+			require.NoError(t, err)
+			defer client.Close()
+
+			err = client.Ping(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestAuthClient_PingUnreachable(t *testing.T) {
+	cfg, _ := newStubAuthService(t, &stubAuthServer{})
+
+	client, err := NewAuthClient(cfg)
+	require.NoError(t, err)
+	defer client.Close()
+
+	// The stub serves no Health service, so the check is rejected
+	assert.Error(t, client.Ping(context.Background()))
+}
+
+// newReplicaTarget registers a resolver returning the given auth replicas and returns a
+// dial target using it
+func newReplicaTarget(t *testing.T, replicas ...*configs.Config) string {
+	t.Helper()
+	addresses := make([]resolver.Address, len(replicas))
+	for i, replica := range replicas {
+		addresses[i] = resolver.Address{Addr: net.JoinHostPort(replica.AuthServiceHost, replica.AuthServicePort)}
+	}
+
+	scheme := fmt.Sprintf("auth-replicas-%d", replicaSchemes.Add(1))
+	r := manual.NewBuilderWithScheme(scheme)
+	r.InitialState(resolver.State{Addresses: addresses})
+	resolver.Register(r)
+	return scheme + ":///auth-service"
+}
+
+// replicaSchemes keeps the resolver scheme of each test unique
+var replicaSchemes atomic.Int32
+
+func TestAuthClient_BalancesAcrossReplicas(t *testing.T) {
+	first, second := &stubAuthServer{}, &stubAuthServer{}
+	firstCfg, _ := newStubAuthService(t, first)
+	secondCfg, _ := newStubAuthService(t, second)
+
+	client, err := NewAuthClient(&configs.Config{
+		AuthServiceTarget: newReplicaTarget(t, firstCfg, secondCfg),
+		AuthLBPolicy:      "round_robin",
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	// Calls go to whichever replica is ready first until both are connected
+	require.Eventually(t, func() bool {
+		if _, err := client.ValidateToken(context.Background(), "token"); err != nil {
+			return false
+		}
+		return first.callCount() > 0 && second.callCount() > 0
+	}, 5*time.Second, time.Millisecond)
+
+	before := first.callCount()
+	for range 10 {
+		_, err := client.ValidateToken(context.Background(), "token")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 5, first.callCount()-before, "round robin should alternate between the replicas")
+}
+
+func TestAuthClient_PickFirstUsesOneReplica(t *testing.T) {
+	first, second := &stubAuthServer{}, &stubAuthServer{}
+	firstCfg, _ := newStubAuthService(t, first)
+	secondCfg, _ := newStubAuthService(t, second)
+
+	client, err := NewAuthClient(&configs.Config{
+		AuthServiceTarget: newReplicaTarget(t, firstCfg, secondCfg),
+		AuthLBPolicy:      "pick_first",
+	})
+	require.NoError(t, err)
+	defer client.Close()
+
+	for range 10 {
+		_, err := client.ValidateToken(context.Background(), "token")
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 10, first.callCount())
+	assert.Zero(t, second.callCount())
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	zlog "packages/logger"
+)
+
+// limitRequestBody rejects request bodies larger than maxBytes with 413 before they reach
+// next. Accepted bodies are buffered, so handlers never read past the limit.
+func limitRequestBody(next http.Handler, maxBytes int64, logger *zlog.Logger) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength > maxBytes {
+			writeBodyTooLarge(w, r, maxBytes, logger)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBytes))
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				writeBodyTooLarge(w, r, maxBytes, logger)
+				return
+			}
+			writeJSONError(w, r, logger, "INVALID_REQUEST", "Invalid request body", http.StatusBadRequest)
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// writeBodyTooLarge writes the structured 413 response for an oversized request body
+func writeBodyTooLarge(w http.ResponseWriter, r *http.Request, maxBytes int64, logger *zlog.Logger) {
+	logger.Warn(r.Context(), "Request body too large", map[string]any{
+		"path":           r.URL.Path,
+		"method":         r.Method,
+		"content_length": r.ContentLength,
+		"max_bytes":      maxBytes,
+	})
+
+	w.Header().Set("Connection", "close")
+	writeJSONError(w, r, logger, "PAYLOAD_TOO_LARGE", fmt.Sprintf("Request body exceeds the %d byte limit", maxBytes), http.StatusRequestEntityTooLarge)
+}
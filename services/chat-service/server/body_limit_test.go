@@ -0,0 +1,74 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"chat-service/internal/domain"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newLimitedTestGateway fronts a stub-backed gateway with a body limit of maxBytes
+func newLimitedTestGateway(t *testing.T, maxBytes int64) *httptest.Server {
+	t.Helper()
+	gateway := newTestGateway(t, &stubChatServer{})
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	srv := httptest.NewServer(limitRequestBody(gateway.Config.Handler, maxBytes, logger))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestLimitRequestBody_RejectsOversizedBody(t *testing.T) {
+	srv := newLimitedTestGateway(t, 64)
+	body := `{"message":"` + strings.Repeat("a", 100) + `"}`
+
+	tests := []struct {
+		name string
+		body io.Reader
+	}{
+		{name: "declared length", body: strings.NewReader(body)},
+		// Without a Content-Length the body is streamed and cut off at the limit
+		{name: "chunked", body: io.MultiReader(strings.NewReader(body))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp, err := http.Post(srv.URL+"/v1/chat/message", "application/json", tt.body)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusRequestEntityTooLarge, resp.StatusCode)
+
+			var errResp domain.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+			assert.Equal(t, "PAYLOAD_TOO_LARGE", errResp.Error)
+			assert.Equal(t, "413", errResp.Code)
+		})
+	}
+}
+
+func TestLimitRequestBody_AllowsBodyWithinLimit(t *testing.T) {
+	srv := newLimitedTestGateway(t, 64)
+
+	resp, err := http.Post(srv.URL+"/v1/chat/message", "application/json", strings.NewReader(`{"message":"hello"}`))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Message struct {
+			Content string `json:"content"`
+		} `json:"message"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "hello", body.Message.Content)
+}
@@ -0,0 +1,130 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"chat-service/internal/domain"
+	grpchandler "chat-service/internal/transport/grpc"
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// requestIDHeader carries the request ID in both directions
+	requestIDHeader = "X-Request-ID"
+	// correlationIDHeader is the older name of requestIDHeader, still accepted and echoed
+	correlationIDHeader = "X-Correlation-ID"
+)
+
+// withCorrelationID tags every REST request with a request ID, used as the correlation ID
+// of its logs, and echoes it in the X-Request-ID and X-Correlation-ID response headers.
+// A well-formed ID sent by the client in either header is kept so its logs line up with
+// ours; otherwise a new one is generated. The gateway forwards the ID to the gRPC server.
+func withCorrelationID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		correlationID := r.Header.Get(requestIDHeader)
+		if correlationID == "" {
+			correlationID = r.Header.Get(correlationIDHeader)
+		}
+		if !middleware.ValidRequestID(correlationID) {
+			correlationID = ""
+		}
+
+		ctx := zlog.WithCorrelationID(r.Context(), correlationID)
+		w.Header().Set(requestIDHeader, zlog.CorrelationID(ctx))
+		w.Header().Set(correlationIDHeader, zlog.CorrelationID(ctx))
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// newErrorResponse builds the standard error envelope for status, tagged with the
+// request's correlation ID
+func newErrorResponse(ctx context.Context, errorType, message string, status int) *domain.ErrorResponse {
+	response := domain.NewErrorResponse(errorType, message, strconv.Itoa(status))
+	response.CorrelationID = zlog.CorrelationID(ctx)
+	return response
+}
+
+// serviceErrorResponse builds the error envelope and HTTP status for an error returned by
+// the chat service while serving a request with ctx. The error is mapped to a status like
+// the gRPC handlers map it and that status to HTTP like the REST gateway does, so the
+// endpoints served outside the gateway report failures the same way. It also returns the
+// Retry-After value, empty when there is none. A cancelled or timed out request is
+// reported as such rather than as a server failure.
+func serviceErrorResponse(ctx context.Context, err error) (*domain.ErrorResponse, string, int) {
+	if ctx.Err() == context.Canceled {
+		return newErrorResponse(ctx, "CLIENT_CLOSED_REQUEST", "Request cancelled by client", statusClientClosedRequest), "", statusClientClosedRequest
+	}
+
+	st := grpchandler.ServiceStatus(err)
+	if ctx.Err() == context.DeadlineExceeded {
+		st = status.New(codes.DeadlineExceeded, "request timed out")
+	}
+
+	statusCode := runtime.HTTPStatusFromCode(st.Code())
+	if st.Code() == codes.DeadlineExceeded {
+		statusCode = http.StatusRequestTimeout
+	}
+
+	response, retryAfter := statusErrorResponse(ctx, st, statusCode)
+	return response, retryAfter, statusCode
+}
+
+// writeJSONError writes the standard error envelope with the given HTTP status. Every REST
+// error response goes through here so clients can always decode the same JSON shape.
+func writeJSONError(w http.ResponseWriter, r *http.Request, logger *zlog.Logger, errorType, message string, status int) {
+	writeErrorResponse(w, r, logger, newErrorResponse(r.Context(), errorType, message, status), status)
+}
+
+// writeErrorResponse writes an error envelope built by newErrorResponse, for errors that
+// carry details, with the given HTTP status
+func writeErrorResponse(w http.ResponseWriter, r *http.Request, logger *zlog.Logger, response *domain.ErrorResponse, status int) {
+	writeJSON(w, r, logger, status, response)
+}
+
+// writeJSON writes v as the JSON body of a response with status. v is encoded before
+// anything is sent, so a value that cannot be encoded is logged and answered with a 500
+// error envelope instead of a truncated body.
+func writeJSON(w http.ResponseWriter, r *http.Request, logger *zlog.Logger, status int, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		logger.Error(r.Context(), err, "Failed to encode response", http.StatusInternalServerError)
+		status = http.StatusInternalServerError
+		body, _ = json.Marshal(newErrorResponse(r.Context(), "INTERNAL_ERROR", "Internal server error", status))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if _, err := w.Write(append(body, '\n')); err != nil {
+		logger.Error(r.Context(), err, "Failed to write response", status)
+	}
+}
+
+// logRequestError logs a failed REST request at ERROR, except when the client went away
+// (499) or the request ran out of time (408); those are not server faults and are logged
+// as warnings so they don't drown out real failures
+func logRequestError(ctx context.Context, logger *zlog.Logger, err error, message string, status int, fields ...map[string]any) {
+	if status != statusClientClosedRequest && status != http.StatusRequestTimeout {
+		logger.Error(ctx, err, message, status, fields...)
+		return
+	}
+
+	warnFields := map[string]any{
+		"error":       err.Error(),
+		"status_code": status,
+	}
+	if len(fields) > 0 {
+		for key, value := range fields[0] {
+			warnFields[key] = value
+		}
+	}
+	logger.Warn(ctx, message, warnFields)
+}
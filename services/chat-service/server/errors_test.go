@@ -0,0 +1,359 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/internal/services/chat"
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// assertJSONError checks resp carries the standard error envelope and that its
+// correlation ID matches the response header
+func assertJSONError(t *testing.T, resp *http.Response, wantStatus int, wantError string) {
+	t.Helper()
+
+	assert.Equal(t, wantStatus, resp.StatusCode)
+	assert.Equal(t, "application/json", resp.Header.Get("Content-Type"))
+
+	var errResp domain.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&errResp))
+	assert.Equal(t, wantError, errResp.Error)
+	assert.NotEmpty(t, errResp.Message)
+	assert.Equal(t, strconv.Itoa(wantStatus), errResp.Code)
+	assert.NotEmpty(t, errResp.CorrelationID)
+	assert.Equal(t, resp.Header.Get(correlationIDHeader), errResp.CorrelationID)
+}
+
+// newErrorTestServer serves the REST handler the way createRESTGateway does, with a
+// 64 byte body limit and the SSE stream accepting only "good-token"
+func newErrorTestServer(t *testing.T, stub *stubChatServer) *httptest.Server {
+	t.Helper()
+	gateway := newTestGateway(t, stub)
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	tokens := newGoodTokenValidator()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/ai/stream", func(w http.ResponseWriter, r *http.Request) {
+		handleChatWithAIStream(w, r, &streamingChatService{}, logger, tokens)
+	})
+	mux.Handle("/", gateway.Config.Handler)
+
+	srv := httptest.NewServer(withCorrelationID(limitRequestBody(mux, 64, logger)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestWriteJSON(t *testing.T) {
+	var logs bytes.Buffer
+	logger := zlog.New(zlog.Config{Level: "debug", Output: &logs})
+
+	rec := httptest.NewRecorder()
+	writeJSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), logger, http.StatusCreated, map[string]any{"id": "abc"})
+
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"id":"abc"}`, rec.Body.String())
+	assert.Empty(t, logs.String())
+}
+
+func TestWriteJSON_UnencodableValue(t *testing.T) {
+	var logs bytes.Buffer
+	logger := zlog.New(zlog.Config{Level: "debug", Output: &logs})
+
+	rec := httptest.NewRecorder()
+	writeJSON(rec, httptest.NewRequest(http.MethodGet, "/", nil), logger, http.StatusOK, map[string]any{"value": math.Inf(1)})
+
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	var body domain.ErrorResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	assert.Equal(t, "INTERNAL_ERROR", body.Error)
+	assert.Equal(t, "500", body.Code)
+	assert.Contains(t, logs.String(), "Failed to encode response")
+}
+
+func TestRESTErrors_UseJSONEnvelope(t *testing.T) {
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		auth       string
+		body       string
+		grpcErr    error
+		wantStatus int
+		wantError  string
+	}{
+		{name: "gateway unknown route", method: http.MethodGet, path: "/v1/unknown", wantStatus: http.StatusNotFound, wantError: "NOT_FOUND"},
+		{name: "gateway grpc error", method: http.MethodGet, path: "/v1/chat/conversations", grpcErr: status.Error(codes.Unauthenticated, "invalid token"), wantStatus: http.StatusUnauthorized, wantError: "UNAUTHORIZED"},
+		{name: "body too large", method: http.MethodPost, path: "/v1/chat/message", body: `{"message":"` + strings.Repeat("a", 100) + `"}`, wantStatus: http.StatusRequestEntityTooLarge, wantError: "PAYLOAD_TOO_LARGE"},
+		{name: "stream wrong method", method: http.MethodGet, path: "/v1/chat/ai/stream", wantStatus: http.StatusMethodNotAllowed, wantError: "METHOD_NOT_ALLOWED"},
+		{name: "stream unauthorized", method: http.MethodPost, path: "/v1/chat/ai/stream", body: `{"message":"Hi"}`, wantStatus: http.StatusUnauthorized, wantError: "UNAUTHORIZED"},
+		{name: "stream invalid body", method: http.MethodPost, path: "/v1/chat/ai/stream", auth: "Bearer good-token", body: `{`, wantStatus: http.StatusBadRequest, wantError: "INVALID_REQUEST"},
+		{name: "stream validation", method: http.MethodPost, path: "/v1/chat/ai/stream", auth: "Bearer good-token", body: `{"message":""}`, wantStatus: http.StatusBadRequest, wantError: "VALIDATION_ERROR"},
+		{name: "stream bad conversation id", method: http.MethodPost, path: "/v1/chat/ai/stream", auth: "Bearer good-token", body: `{"message":"Hi","conversation_id":"nope"}`, wantStatus: http.StatusBadRequest, wantError: "VALIDATION_ERROR"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newErrorTestServer(t, &stubChatServer{err: tt.grpcErr})
+
+			req, err := http.NewRequest(tt.method, srv.URL+tt.path, strings.NewReader(tt.body))
+			require.NoError(t, err)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assertJSONError(t, resp, tt.wantStatus, tt.wantError)
+		})
+	}
+}
+
+func TestRESTErrors_WebSocketUpgradeFailure(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	srv := httptest.NewServer(withCorrelationID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleChatWebSocket(w, r, t.Context(), &streamingChatService{}, logger, newGoodTokenValidator(), nil)
+	})))
+	defer srv.Close()
+
+	// A plain GET is not a WebSocket handshake
+	resp, err := http.Get(srv.URL + "/v1/chat/ws")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assertJSONError(t, resp, http.StatusBadRequest, "INVALID_REQUEST")
+}
+
+func TestWithCorrelationID(t *testing.T) {
+	tests := []struct {
+		name       string
+		headerName string
+		header     string
+		wantKept   bool
+	}{
+		{name: "client id kept", headerName: correlationIDHeader, header: "req-42_a.b", wantKept: true},
+		{name: "client request id kept", headerName: requestIDHeader, header: "req-43", wantKept: true},
+		{name: "missing id generated", headerName: requestIDHeader, header: ""},
+		{name: "unsafe id replaced", headerName: requestIDHeader, header: "bad id\r\nx"},
+		{name: "long id replaced", headerName: correlationIDHeader, header: strings.Repeat("a", middleware.MaxRequestIDLength+1)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var seen string
+			handler := withCorrelationID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				seen = zlog.CorrelationID(r.Context())
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(tt.headerName, tt.header)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			assert.NotEmpty(t, seen)
+			assert.Equal(t, seen, rec.Header().Get(requestIDHeader))
+			assert.Equal(t, seen, rec.Header().Get(correlationIDHeader))
+			if tt.wantKept {
+				assert.Equal(t, tt.header, seen)
+			} else {
+				assert.NotEqual(t, tt.header, seen)
+			}
+		})
+	}
+}
+
+// cancelledRequest returns a request whose context has already been cancelled, as when
+// the client disconnects mid-request
+func cancelledRequest(t *testing.T, method, target, body string) *http.Request {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	req := httptest.NewRequest(method, target, strings.NewReader(body)).WithContext(ctx)
+	req.Header.Set("Authorization", "Bearer good-token")
+	return req
+}
+
+func TestRESTErrors_ContextErrorsAreNotServerErrors(t *testing.T) {
+	tests := []struct {
+		name       string
+		serve      func(w http.ResponseWriter, logger *zlog.Logger)
+		wantStatus int
+	}{
+		{
+			name: "gateway client cancelled",
+			serve: func(w http.ResponseWriter, logger *zlog.Logger) {
+				r := cancelledRequest(t, http.MethodGet, "/v1/chat/conversations", "")
+				gatewayErrorHandler(logger)(r.Context(), nil, nil, w, r, status.Error(codes.Canceled, "context canceled"))
+			},
+			wantStatus: statusClientClosedRequest,
+		},
+		{
+			name: "gateway timeout",
+			serve: func(w http.ResponseWriter, logger *zlog.Logger) {
+				r := httptest.NewRequest(http.MethodGet, "/v1/chat/conversations", nil)
+				gatewayErrorHandler(logger)(r.Context(), nil, nil, w, r, status.Error(codes.DeadlineExceeded, "request timed out"))
+			},
+			wantStatus: http.StatusRequestTimeout,
+		},
+		{
+			name: "export client cancelled",
+			serve: func(w http.ResponseWriter, logger *zlog.Logger) {
+				r := cancelledRequest(t, http.MethodGet, "/v1/chat/conversations/"+exportConversationID+"/export", "")
+				r.SetPathValue("id", exportConversationID)
+				handleExportConversation(w, r, &exportChatService{err: context.Canceled}, logger, newGoodTokenValidator())
+			},
+			wantStatus: statusClientClosedRequest,
+		},
+		{
+			name: "stream client cancelled",
+			serve: func(w http.ResponseWriter, logger *zlog.Logger) {
+				r := cancelledRequest(t, http.MethodPost, "/v1/chat/ai/stream", `{"message":"Hi"}`)
+				handleChatWithAIStream(w, r, &streamingChatService{err: context.Canceled}, logger, newGoodTokenValidator())
+			},
+			// The stream has already started, so the client closing is only seen in the logs
+			wantStatus: http.StatusOK,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			logger := zlog.New(zlog.Config{Level: "debug", Output: &logs, JSONFormat: true})
+			w := httptest.NewRecorder()
+
+			tt.serve(w, logger)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.NotContains(t, w.Body.String(), "INTERNAL_ERROR")
+			assert.NotContains(t, logs.String(), `"level":"error"`)
+			assert.Contains(t, logs.String(), `"level":"warn"`)
+		})
+	}
+}
+
+// streamingServiceErrors are the typed chat service errors the streaming endpoints report,
+// with the HTTP status, error type and details the REST gateway reports them with
+var streamingServiceErrors = []struct {
+	name        string
+	err         error
+	wantStatus  int
+	wantError   string
+	wantDetails map[string]string
+}{
+	{name: "not found", err: chat.ErrConversationNotFound, wantStatus: http.StatusNotFound, wantError: "NOT_FOUND"},
+	{name: "forbidden", err: chat.ErrAssistantMessageEdit, wantStatus: http.StatusForbidden, wantError: "FORBIDDEN"},
+	{name: "validation", err: chat.NewValidationError("INVALID_TEMPERATURE", "temperature too high", nil), wantStatus: http.StatusBadRequest, wantError: "VALIDATION_ERROR"},
+	{name: "content filtered", err: chat.ErrAIResponseFiltered, wantStatus: http.StatusBadRequest, wantError: "FAILED_PRECONDITION"},
+	{name: "conversation quota", err: chat.ErrConversationQuotaExceeded, wantStatus: http.StatusTooManyRequests, wantError: "RESOURCE_EXHAUSTED"},
+	{name: "daily token budget", err: chat.ErrDailyTokenBudgetExceeded, wantStatus: http.StatusTooManyRequests, wantError: "RESOURCE_EXHAUSTED"},
+	{name: "truncated", err: chat.ErrAIResponseTruncated, wantStatus: http.StatusBadRequest, wantError: "FAILED_PRECONDITION"},
+	{
+		name: "unavailable",
+		err: &chat.AIRequestError{
+			ConversationID: exportConversationID,
+			UserMessage:    &domain.Message{ID: "msg-1"},
+			Err:            &chat.AppError{Type: chat.ErrorTypeUnavailable, Code: "AI_REQUEST_FAILED", Message: "failed to get AI response"},
+		},
+		wantStatus:  http.StatusServiceUnavailable,
+		wantError:   "SERVICE_UNAVAILABLE",
+		wantDetails: map[string]string{"conversation_id": exportConversationID, "user_message_id": "msg-1"},
+	},
+	{
+		name: "rate limited",
+		err: &chat.AIRequestError{
+			ConversationID: exportConversationID,
+			UserMessage:    &domain.Message{ID: "msg-1"},
+			RetryAfter:     1500 * time.Millisecond,
+			Err:            &chat.AppError{Type: chat.ErrorTypeRateLimited, Code: "AI_RATE_LIMITED", Message: "AI provider rate limit reached; retry later"},
+		},
+		wantStatus:  http.StatusTooManyRequests,
+		wantError:   "RESOURCE_EXHAUSTED",
+		wantDetails: map[string]string{"conversation_id": exportConversationID, "user_message_id": "msg-1", "retry_after_seconds": "2"},
+	},
+	{
+		name: "provider rejected request as invalid",
+		err: &chat.AIRequestError{
+			ConversationID: exportConversationID,
+			UserMessage:    &domain.Message{ID: "msg-1"},
+			Err:            &chat.AppError{Type: chat.ErrorTypeValidation, Code: "AI_REQUEST_INVALID", Message: "AI provider rejected the request as invalid"},
+		},
+		wantStatus:  http.StatusBadRequest,
+		wantError:   "VALIDATION_ERROR",
+		wantDetails: map[string]string{"conversation_id": exportConversationID, "user_message_id": "msg-1"},
+	},
+	{
+		name: "provider refused request",
+		err: &chat.AIRequestError{
+			ConversationID: exportConversationID,
+			UserMessage:    &domain.Message{ID: "msg-1"},
+			Err:            &chat.AppError{Type: chat.ErrorTypeRejected, Code: "AI_REQUEST_REJECTED", Message: "AI provider refused the request"},
+		},
+		wantStatus:  http.StatusBadRequest,
+		wantError:   "FAILED_PRECONDITION",
+		wantDetails: map[string]string{"conversation_id": exportConversationID, "user_message_id": "msg-1"},
+	},
+	{name: "unexpected", err: errors.New("db down"), wantStatus: http.StatusInternalServerError, wantError: "INTERNAL_ERROR"},
+}
+
+func TestChatWithAIStream_ReportsServiceErrorsLikeGateway(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	for _, tt := range streamingServiceErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/v1/chat/ai/stream", strings.NewReader(`{"message":"Hi"}`))
+			r.Header.Set("Authorization", "Bearer good-token")
+			w := httptest.NewRecorder()
+
+			handleChatWithAIStream(w, r, &streamingChatService{err: tt.err}, logger, newGoodTokenValidator())
+
+			data, ok := strings.CutPrefix(w.Body.String(), "event: error\ndata: ")
+			require.True(t, ok, "expected an error event, got %q", w.Body.String())
+			var event domain.ErrorResponse
+			require.NoError(t, json.Unmarshal([]byte(data), &event))
+			assert.Equal(t, tt.wantError, event.Error)
+			assert.Equal(t, strconv.Itoa(tt.wantStatus), event.Code)
+			assert.Equal(t, tt.wantDetails, event.Details)
+		})
+	}
+}
+
+func TestChatWebSocket_ReportsServiceErrorsLikeGateway(t *testing.T) {
+	for _, tt := range streamingServiceErrors {
+		t.Run(tt.name, func(t *testing.T) {
+			wsURL := newTestWebSocketServer(t, context.Background(), &streamingChatService{err: tt.err})
+			conn := dialTestWebSocket(t, wsURL, wsBearerProtocol, "good-token")
+
+			require.NoError(t, conn.WriteJSON(map[string]any{"message": "Hi"}))
+			var frame struct {
+				Type string `json:"type"`
+				domain.ErrorResponse
+			}
+			require.NoError(t, conn.ReadJSON(&frame))
+			assert.Equal(t, "error", frame.Type)
+			assert.Equal(t, tt.wantError, frame.Error)
+			assert.Equal(t, strconv.Itoa(tt.wantStatus), frame.Code)
+			assert.Equal(t, tt.wantDetails, frame.Details)
+		})
+	}
+}
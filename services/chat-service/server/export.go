@@ -0,0 +1,106 @@
+package server
+
+import (
+	"fmt"
+	"mime"
+	"net/http"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/internal/services/chat"
+	zlog "packages/logger"
+)
+
+// exportContentTypes maps each export format to the content type it is served with
+var exportContentTypes = map[chat.ExportFormat]string{
+	chat.ExportFormatJSONL:    "application/x-ndjson",
+	chat.ExportFormatMarkdown: "text/markdown; charset=utf-8",
+}
+
+// exportWriteWait bounds how long a single write of the export may block, so a client that
+// stops reading cannot hold the connection open
+const exportWriteWait = 10 * time.Second
+
+// deadlineWriter pushes the connection's write deadline forward before each write. A long
+// export can outlive the server write timeout, but every write still has to finish in time.
+type deadlineWriter struct {
+	w     http.ResponseWriter
+	rc    *http.ResponseController
+	limit time.Duration
+}
+
+func newDeadlineWriter(w http.ResponseWriter, limit time.Duration) *deadlineWriter {
+	return &deadlineWriter{w: w, rc: http.NewResponseController(w), limit: limit}
+}
+
+func (d *deadlineWriter) Write(p []byte) (int, error) {
+	_ = d.rc.SetWriteDeadline(time.Now().Add(d.limit))
+	return d.w.Write(p)
+}
+
+// handleExportConversation handles GET /v1/chat/conversations/{id}/export, streaming the
+// conversation as a JSONL attachment, or Markdown with ?format=md. The gateway cannot set
+// Content-Disposition or stream a plain body, so the handler is hand-written.
+func handleExportConversation(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, tokens *tokenValidator) {
+	if r.Method != http.MethodGet {
+		writeJSONError(w, r, logger, "METHOD_NOT_ALLOWED", "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	userID, err := extractUserIDFromToken(r, tokens)
+	if err != nil {
+		logger.Error(r.Context(), err, "Failed to extract user ID from token", 401)
+		writeJSONError(w, r, logger, "UNAUTHORIZED", "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	conversationID := r.PathValue("id")
+	if err := domain.ValidateUUID(conversationID); err != nil {
+		writeJSONError(w, r, logger, "VALIDATION_ERROR", fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+		return
+	}
+
+	format := chat.ExportFormatJSONL
+	if value := r.URL.Query().Get("format"); value != "" {
+		format = chat.ExportFormat(value)
+	}
+	contentType, ok := exportContentTypes[format]
+	if !ok {
+		writeJSONError(w, r, logger, "VALIDATION_ERROR", fmt.Sprintf("Validation error: unsupported export format %q", format), http.StatusBadRequest)
+		return
+	}
+
+	ctx := r.Context()
+	export, err := chatService.ExportConversation(ctx, userID, conversationID)
+	if err != nil {
+		response, retryAfter, status := serviceErrorResponse(ctx, err)
+		logRequestError(ctx, logger, err, "Failed to export conversation", status)
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		writeErrorResponse(w, r, logger, response, status)
+		return
+	}
+
+	filename := fmt.Sprintf("conversation-%s.%s", conversationID, format)
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	w.WriteHeader(http.StatusOK)
+
+	// The status is already sent, so a failure part way leaves the client a truncated file
+	written, err := export.Render(ctx, newDeadlineWriter(w, exportWriteWait), format)
+	if err != nil {
+		_, _, status := serviceErrorResponse(ctx, err)
+		logRequestError(ctx, logger, err, "Failed to write conversation export", status, map[string]any{
+			"conversation_id": conversationID,
+			"written":         written,
+		})
+		return
+	}
+
+	logger.Info(ctx, "Conversation exported", map[string]any{
+		"conversation_id": conversationID,
+		"format":          format,
+		"messages":        written,
+	})
+}
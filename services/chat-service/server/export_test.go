@@ -0,0 +1,210 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-service/internal/domain"
+	"chat-service/internal/services/chat"
+	"chat-service/storage"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const exportConversationID = "33333333-3333-3333-3333-333333333333"
+
+// exportChatService returns a fixed export, or err, from ExportConversation; other methods
+// are left unimplemented
+type exportChatService struct {
+	chat.Service
+	messages []domain.Message
+	err      error
+	userID   string
+}
+
+func (s *exportChatService) ExportConversation(ctx context.Context, userID, conversationID string) (*chat.Export, error) {
+	s.userID = userID
+	if s.err != nil {
+		return nil, s.err
+	}
+	conversation := &domain.Conversation{ID: conversationID, UserID: userID, Title: "Trip plans"}
+	return chat.NewExport(conversation, &messageStorage{messages: s.messages}), nil
+}
+
+// messageStorage serves one page of messages; other methods are left unimplemented
+type messageStorage struct {
+	storage.Repository
+	messages []domain.Message
+}
+
+func (s *messageStorage) GetMessagesByConversationID(ctx context.Context, conversationID string, limit, offset int) ([]domain.Message, error) {
+	return s.messages, nil
+}
+
+// newTestExportServer serves handleExportConversation on its route, accepting only "good-token"
+func newTestExportServer(t *testing.T, chatService chat.Service) string {
+	t.Helper()
+
+	validator := newGoodTokenValidator()
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/chat/conversations/{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		handleExportConversation(w, r, chatService, logger, validator)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	return srv.URL + "/v1/chat/conversations/"
+}
+
+func getExport(t *testing.T, url, token string) *http.Response {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	require.NoError(t, err)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func exportMessages(n int) []domain.Message {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	messages := make([]domain.Message, n)
+	for i := range messages {
+		messages[i] = domain.Message{
+			ID:             fmt.Sprintf("msg-%d", i),
+			ConversationID: exportConversationID,
+			Content:        fmt.Sprintf("message %d", i),
+			Role:           "user",
+			CreatedAt:      base.Add(time.Duration(i) * time.Second),
+		}
+	}
+	return messages
+}
+
+func TestHandleExportConversation_JSONL(t *testing.T) {
+	svc := &exportChatService{messages: exportMessages(3)}
+	baseURL := newTestExportServer(t, svc)
+
+	resp := getExport(t, baseURL+exportConversationID+"/export", "good-token")
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "application/x-ndjson", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=conversation-`+exportConversationID+`.jsonl`, resp.Header.Get("Content-Disposition"))
+	assert.Equal(t, testUserID, svc.userID)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	lines := strings.Split(strings.TrimSuffix(string(body), "\n"), "\n")
+	require.Len(t, lines, 3)
+	assert.Contains(t, lines[0], `"content":"message 0"`)
+}
+
+func TestHandleExportConversation_Markdown(t *testing.T) {
+	baseURL := newTestExportServer(t, &exportChatService{messages: exportMessages(1)})
+
+	resp := getExport(t, baseURL+exportConversationID+"/export?format=md", "good-token")
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/markdown; charset=utf-8", resp.Header.Get("Content-Type"))
+	assert.Equal(t, `attachment; filename=conversation-`+exportConversationID+`.md`, resp.Header.Get("Content-Disposition"))
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Equal(t, "# Trip plans\n\n## User · 2024-01-01T12:00:00Z\n\nmessage 0\n\n", string(body))
+}
+
+func TestHandleExportConversation_Errors(t *testing.T) {
+	tests := []struct {
+		name       string
+		path       string
+		token      string
+		serviceErr error
+		wantStatus int
+		wantError  string
+	}{
+		{
+			name:       "missing token",
+			path:       exportConversationID + "/export",
+			wantStatus: http.StatusUnauthorized,
+			wantError:  "UNAUTHORIZED",
+		},
+		{
+			name:       "invalid conversation ID",
+			path:       "not-a-uuid/export",
+			token:      "good-token",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "VALIDATION_ERROR",
+		},
+		{
+			name:       "unsupported format",
+			path:       exportConversationID + "/export?format=pdf",
+			token:      "good-token",
+			wantStatus: http.StatusBadRequest,
+			wantError:  "VALIDATION_ERROR",
+		},
+		{
+			name:       "conversation not found",
+			path:       exportConversationID + "/export",
+			token:      "good-token",
+			serviceErr: fmt.Errorf("%w: %s", chat.ErrConversationNotFound, exportConversationID),
+			wantStatus: http.StatusNotFound,
+			wantError:  "NOT_FOUND",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baseURL := newTestExportServer(t, &exportChatService{err: tt.serviceErr})
+
+			resp := getExport(t, baseURL+tt.path, tt.token)
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+			assert.Empty(t, resp.Header.Get("Content-Disposition"))
+			body, err := io.ReadAll(resp.Body)
+			require.NoError(t, err)
+			assert.Contains(t, string(body), `"error":"`+tt.wantError+`"`)
+		})
+	}
+}
+
+// deadlineRecorder records the write deadline set through http.ResponseController
+type deadlineRecorder struct {
+	*httptest.ResponseRecorder
+	deadlines []time.Time
+}
+
+func (r *deadlineRecorder) SetWriteDeadline(deadline time.Time) error {
+	r.deadlines = append(r.deadlines, deadline)
+	return nil
+}
+
+func TestDeadlineWriter_SetsDeadlineBeforeEachWrite(t *testing.T) {
+	rec := &deadlineRecorder{ResponseRecorder: httptest.NewRecorder()}
+	w := newDeadlineWriter(rec, exportWriteWait)
+
+	before := time.Now()
+	_, err := w.Write([]byte("first\n"))
+	require.NoError(t, err)
+	_, err = w.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "first\nsecond\n", rec.Body.String())
+	require.Len(t, rec.deadlines, 2)
+	for _, deadline := range rec.deadlines {
+		assert.False(t, deadline.IsZero(), "each write must carry a deadline")
+		assert.WithinDuration(t, before.Add(exportWriteWait), deadline, time.Second)
+	}
+}
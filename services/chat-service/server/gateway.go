@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"chat-service/configs"
+	"chat-service/internal/domain"
+	"chat-service/internal/services/chat"
+	grpchandler "chat-service/internal/transport/grpc"
+	chatproto "chat-service/proto"
+	"packages/grpcutil"
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+)
+
+// statusClientClosedRequest is the non-standard status used when the client goes away mid-request
+const statusClientClosedRequest = 499
+
+// newGatewayConn creates the client connection the REST gateway uses to reach the local gRPC server
+func newGatewayConn(cfg *configs.Config) (*grpc.ClientConn, error) {
+	dialCfg := grpcutil.DefaultConfig()
+	// Responses can be as large as the server is allowed to send
+	dialCfg.MaxRecvMsgSize = cfg.GRPCMaxMessageBytes
+	if cfg.TLSEnabled {
+		// Trust the server's own certificate when dialing it over loopback
+		tlsCreds, err := credentials.NewClientTLSFromFile(cfg.TLSCertFile, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to load gateway TLS credentials: %w", err)
+		}
+		dialCfg.Credentials = tlsCreds
+	}
+
+	return grpc.Dial(
+		"localhost:"+cfg.ChatServicePort,
+		append(grpcutil.DialOptions(dialCfg), grpc.WithStatsHandler(otelgrpc.NewClientHandler()))...,
+	)
+}
+
+// newRESTHandler builds the REST handler: custom health and SSE endpoints plus the
+// generated gateway for everything under /v1/chat
+func newRESTHandler(ctx context.Context, cfg *configs.Config, logger *zlog.Logger, conn *grpc.ClientConn, chatService chat.Service, authClient *AuthClient, checks []HealthChecker, streams *grpchandler.StreamLimiter) (http.Handler, error) {
+	gwMux := runtime.NewServeMux(
+		runtime.WithMarshalerOption(runtime.MIMEWildcard, &runtime.JSONPb{
+			MarshalOptions: protojson.MarshalOptions{
+				UseProtoNames:   true,
+				EmitUnpopulated: true,
+			},
+			UnmarshalOptions: protojson.UnmarshalOptions{
+				DiscardUnknown: true,
+			},
+		}),
+		runtime.WithErrorHandler(gatewayErrorHandler(logger)),
+		runtime.WithIncomingHeaderMatcher(gatewayHeaderMatcher),
+		runtime.WithMetadata(middleware.GatewayRequestIDMetadata),
+	)
+
+	if err := chatproto.RegisterChatServiceHandler(ctx, gwMux, conn); err != nil {
+		return nil, fmt.Errorf("failed to register chat service handler: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	registerHealthEndpoints(mux)
+
+	readinessTimeout := time.Duration(cfg.HealthCheckTimeout) * time.Second
+	if readinessTimeout <= 0 {
+		readinessTimeout = defaultReadinessTimeout
+	}
+	mux.HandleFunc("/v1/health/ready", handleReadiness(checks, readinessTimeout, logger))
+
+	// Server-sent events are not expressible through the gateway, so the stream stays hand-written
+	tokens := newTokenValidator(cfg, authClient)
+	mux.HandleFunc("/v1/chat/ai/stream", func(w http.ResponseWriter, r *http.Request) {
+		handleChatWithAIStream(w, r, chatService, logger, tokens)
+	})
+
+	// WebSockets outlive ordinary requests; cancelling ctx closes them
+	mux.HandleFunc("/v1/chat/ws", func(w http.ResponseWriter, r *http.Request) {
+		handleChatWebSocket(w, r, ctx, chatService, logger, tokens, streams)
+	})
+
+	// Exports are served as file downloads, which the gateway cannot produce
+	mux.HandleFunc("/v1/chat/conversations/{id}/export", func(w http.ResponseWriter, r *http.Request) {
+		handleExportConversation(w, r, chatService, logger, tokens)
+	})
+
+	// Subscriptions stay open until the client leaves, so they must outlive the write timeout
+	mux.Handle("/v1/chat/stream/", liftSubscriptionWriteDeadline(gwMux))
+
+	mux.Handle("/", gwMux)
+
+	return mux, nil
+}
+
+// liftSubscriptionWriteDeadline lifts the server write timeout for StreamMessages requests
+// that subscribe to new messages, as is done for the SSE stream; history-only replays keep it
+func liftSubscriptionWriteDeadline(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subscribe, _ := strconv.ParseBool(r.URL.Query().Get("subscribe")); subscribe {
+			_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// registerHealthEndpoints registers the liveness endpoints. They only report that the
+// process is serving; dependencies are checked by /v1/health/ready.
+func registerHealthEndpoints(mux *http.ServeMux) {
+	health := func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"status":"SERVING","service":"chat-service","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
+	}
+
+	mux.HandleFunc("/v1/health/live", health)
+	mux.HandleFunc("/v1/health/direct", health)
+	mux.HandleFunc("/v1/health", health)
+	mux.HandleFunc("/health", health)
+}
+
+// gatewayHeaderMatcher forwards the Idempotency-Key header as gRPC metadata, alongside the
+// headers the gateway forwards by default
+func gatewayHeaderMatcher(key string) (string, bool) {
+	if http.CanonicalHeaderKey(key) == "Idempotency-Key" {
+		return grpchandler.IdempotencyKeyMetadata, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// gatewayErrorHandler maps gRPC errors returned through the gateway to HTTP error responses
+func gatewayErrorHandler(logger *zlog.Logger) runtime.ErrorHandlerFunc {
+	return func(ctx context.Context, _ *runtime.ServeMux, _ runtime.Marshaler, w http.ResponseWriter, r *http.Request, err error) {
+		st := status.Convert(err)
+		statusCode := runtime.HTTPStatusFromCode(st.Code())
+
+		// Routing errors (unknown path, wrong method) carry their own HTTP status
+		var httpErr *runtime.HTTPStatusError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.HTTPStatus
+			st = status.Convert(httpErr.Err)
+		}
+
+		// A call that ran past REQUEST_TIMEOUT is the server giving up on the request
+		if st.Code() == codes.DeadlineExceeded {
+			statusCode = http.StatusRequestTimeout
+		}
+
+		if st.Code() == codes.Canceled && r.Context().Err() == context.Canceled {
+			statusCode = statusClientClosedRequest
+		}
+
+		logRequestError(ctx, logger, err, "REST gateway error", statusCode, map[string]any{
+			"path":      r.URL.Path,
+			"method":    r.Method,
+			"grpc_code": st.Code().String(),
+		})
+
+		response, retryAfter := statusErrorResponse(ctx, st, statusCode)
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+		}
+		writeErrorResponse(w, r, logger, response, statusCode)
+	}
+}
+
+// statusErrorResponse builds the error envelope for a gRPC status reported with the HTTP
+// statusCode. Field violations, or else ErrorInfo metadata, become the details, along with
+// the retry delay. The delay is also returned in whole seconds for a Retry-After header,
+// empty when there is none.
+func statusErrorResponse(ctx context.Context, st *status.Status, statusCode int) (*domain.ErrorResponse, string) {
+	errorType, message := describeGatewayError(st, statusCode)
+
+	response := newErrorResponse(ctx, errorType, message, statusCode)
+	response.Details = fieldViolations(st)
+	if response.Details == nil {
+		response.Details = errorInfoMetadata(st)
+	}
+
+	delay := retryDelay(st)
+	if delay <= 0 {
+		return response, ""
+	}
+	seconds := strconv.FormatInt(int64(math.Ceil(delay.Seconds())), 10)
+	if response.Details == nil {
+		response.Details = make(map[string]string)
+	}
+	response.Details["retry_after_seconds"] = seconds
+	return response, seconds
+}
+
+// fieldViolations maps each field named in the BadRequest details of an InvalidArgument
+// status to why it was rejected. It returns nil when there are none.
+func fieldViolations(st *status.Status) map[string]string {
+	if st.Code() != codes.InvalidArgument {
+		return nil
+	}
+
+	var violations map[string]string
+	for _, detail := range st.Details() {
+		badRequest, ok := detail.(*errdetails.BadRequest)
+		if !ok {
+			continue
+		}
+		for _, violation := range badRequest.GetFieldViolations() {
+			if violations == nil {
+				violations = make(map[string]string)
+			}
+			violations[violation.GetField()] = violation.GetDescription()
+		}
+	}
+	return violations
+}
+
+// errorInfoMetadata returns the metadata of the ErrorInfo detail of st, such as the
+// conversation to retry in after a failed AI call. It returns nil when there is none.
+func errorInfoMetadata(st *status.Status) map[string]string {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && len(info.GetMetadata()) > 0 {
+			return info.GetMetadata()
+		}
+	}
+	return nil
+}
+
+// retryDelay returns the delay in the RetryInfo detail of st, such as the one requested by
+// a rate-limited AI provider. It returns zero when there is none.
+func retryDelay(st *status.Status) time.Duration {
+	for _, detail := range st.Details() {
+		if info, ok := detail.(*errdetails.RetryInfo); ok && info.GetRetryDelay() != nil {
+			return info.GetRetryDelay().AsDuration()
+		}
+	}
+	return 0
+}
+
+// describeGatewayError returns the error type and client-facing message for a gateway error.
+// Server-side failures are reported generically so internal details don't leak to clients.
+func describeGatewayError(st *status.Status, statusCode int) (string, string) {
+	switch {
+	case statusCode == statusClientClosedRequest:
+		return "CLIENT_CLOSED_REQUEST", "Request cancelled by client"
+	case statusCode == http.StatusMethodNotAllowed:
+		return "METHOD_NOT_ALLOWED", "Method not allowed"
+	}
+
+	switch st.Code() {
+	case codes.InvalidArgument:
+		return "VALIDATION_ERROR", st.Message()
+	case codes.Unauthenticated:
+		return "UNAUTHORIZED", "Unauthorized"
+	case codes.PermissionDenied:
+		return "FORBIDDEN", st.Message()
+	case codes.NotFound:
+		return "NOT_FOUND", st.Message()
+	case codes.AlreadyExists:
+		return "CONFLICT", st.Message()
+	case codes.FailedPrecondition:
+		return "FAILED_PRECONDITION", st.Message()
+	case codes.ResourceExhausted:
+		return "RESOURCE_EXHAUSTED", st.Message()
+	case codes.DeadlineExceeded:
+		return "TIMEOUT", "Request timeout"
+	case codes.Unavailable:
+		return "SERVICE_UNAVAILABLE", "Service temporarily unavailable"
+	case codes.Unimplemented:
+		return "NOT_IMPLEMENTED", st.Message()
+	default:
+		return "INTERNAL_ERROR", "Internal server error"
+	}
+}
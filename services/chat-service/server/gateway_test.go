@@ -0,0 +1,613 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"chat-service/configs"
+	"chat-service/internal/domain"
+	chatproto "chat-service/proto"
+	zlog "packages/logger"
+	"packages/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// stubChatServer is a ChatServiceServer that records the incoming authorization
+// metadata and returns a canned result or error
+type stubChatServer struct {
+	chatproto.UnimplementedChatServiceServer
+	authorization string
+	err           error
+	deletedID     string
+	clearedID     string
+	edited        *chatproto.EditMessageRequest
+	listed        *chatproto.ListConversationsRequest
+	searched      *chatproto.SearchMessagesRequest
+	idempotency   string
+	requestID     string
+	streamPause   time.Duration // how long StreamMessages waits between its two messages
+}
+
+func (s *stubChatServer) ListConversations(ctx context.Context, req *chatproto.ListConversationsRequest) (*chatproto.ListConversationsResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			s.authorization = values[0]
+		}
+		if values := md.Get(middleware.RequestIDMetadata); len(values) > 0 {
+			s.requestID = values[0]
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.listed = req
+	return &chatproto.ListConversationsResponse{
+		Conversations: []*chatproto.Conversation{{Id: "conv-1", Title: "First"}},
+		Total:         1,
+		NextCursor:    req.GetCursor() + "-next",
+	}, nil
+}
+
+func (s *stubChatServer) SendMessage(ctx context.Context, req *chatproto.ChatRequest) (*chatproto.ChatResponse, error) {
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("idempotency-key"); len(values) > 0 {
+			s.idempotency = values[0]
+		}
+	}
+	return &chatproto.ChatResponse{Message: &chatproto.Message{Id: "msg-1", Content: req.Message}, ConversationId: req.ConversationId}, nil
+}
+
+func (s *stubChatServer) GetConversation(ctx context.Context, req *chatproto.GetConversationRequest) (*chatproto.Conversation, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &chatproto.Conversation{Id: req.ConversationId, Title: "First", MessageCount: 2}, nil
+}
+
+func (s *stubChatServer) DeleteConversation(ctx context.Context, req *chatproto.DeleteConversationRequest) (*chatproto.Empty, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.deletedID = req.ConversationId
+	return &chatproto.Empty{}, nil
+}
+
+func (s *stubChatServer) ClearConversationMessages(ctx context.Context, req *chatproto.ClearConversationMessagesRequest) (*chatproto.ClearConversationMessagesResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.clearedID = req.ConversationId
+	return &chatproto.ClearConversationMessagesResponse{DeletedCount: 3}, nil
+}
+
+func (s *stubChatServer) EditMessage(ctx context.Context, req *chatproto.EditMessageRequest) (*chatproto.Message, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.edited = req
+	return &chatproto.Message{Id: req.MessageId, Content: req.Content, Role: "user"}, nil
+}
+
+func (s *stubChatServer) SearchMessages(ctx context.Context, req *chatproto.SearchMessagesRequest) (*chatproto.SearchMessagesResponse, error) {
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.searched = req
+	return &chatproto.SearchMessagesResponse{
+		Results: []*chatproto.MessageSearchResult{{
+			Message:        &chatproto.Message{Id: "msg-1", Content: "deploy to production"},
+			ConversationId: "conv-1",
+			Snippet:        "«deploy» to production",
+		}},
+	}, nil
+}
+
+func (s *stubChatServer) StreamMessages(req *chatproto.StreamMessageRequest, stream chatproto.ChatService_StreamMessagesServer) error {
+	if err := stream.Send(&chatproto.StreamMessageResponse{Message: &chatproto.Message{Id: "msg-1", ConversationId: req.ConversationId}}); err != nil {
+		return err
+	}
+	time.Sleep(s.streamPause)
+	return stream.Send(&chatproto.StreamMessageResponse{Message: &chatproto.Message{Id: "msg-2", ConversationId: req.ConversationId}})
+}
+
+// newTestGateway starts a gRPC server backed by stub and returns an HTTP server
+// fronting it with the REST gateway
+func newTestGateway(t *testing.T, stub *stubChatServer) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(newTestGatewayHandler(t, stub))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// newTestGatewayHandler starts a gRPC server backed by stub and returns the REST gateway
+// handler fronting it
+func newTestGatewayHandler(t *testing.T, stub *stubChatServer) http.Handler {
+	t.Helper()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer()
+	chatproto.RegisterChatServiceServer(grpcServer, stub)
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	handler, err := newRESTHandler(context.Background(), &configs.Config{}, logger, conn, nil, nil, nil, nil)
+	require.NoError(t, err)
+	return withCorrelationID(handler)
+}
+
+func TestGateway_SubscriptionOutlivesWriteTimeout(t *testing.T) {
+	const writeTimeout = 200 * time.Millisecond
+	srv := httptest.NewUnstartedServer(newTestGatewayHandler(t, &stubChatServer{streamPause: 2 * writeTimeout}))
+	srv.Config.WriteTimeout = writeTimeout
+	srv.Start()
+	t.Cleanup(srv.Close)
+
+	resp, err := http.Get(srv.URL + "/v1/chat/stream/conv-1?subscribe=true")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	assert.Contains(t, string(body), `"id":"msg-1"`)
+	assert.Contains(t, string(body), `"id":"msg-2"`, "the message sent after the write timeout must arrive")
+}
+
+func TestGateway_EchoesAndForwardsRequestID(t *testing.T) {
+	tests := []struct {
+		name      string
+		header    string
+		requestID string
+	}{
+		{name: "client request id", header: requestIDHeader, requestID: "support-ticket-42"},
+		{name: "legacy correlation id", header: correlationIDHeader, requestID: "support-ticket-43"},
+		{name: "generated when absent"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubChatServer{}
+			srv := newTestGateway(t, stub)
+
+			req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/chat/conversations", nil)
+			require.NoError(t, err)
+			if tt.header != "" {
+				req.Header.Set(tt.header, tt.requestID)
+			}
+
+			resp, err := http.DefaultClient.Do(req)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			requestID := resp.Header.Get(requestIDHeader)
+			if tt.requestID != "" {
+				assert.Equal(t, tt.requestID, requestID)
+			} else {
+				assert.NotEmpty(t, requestID)
+			}
+			assert.Equal(t, requestID, resp.Header.Get(correlationIDHeader))
+			assert.Equal(t, requestID, stub.requestID, "the request ID must reach the gRPC server")
+		})
+	}
+}
+
+func TestGateway_ForwardsRequestsToGRPC(t *testing.T) {
+	stub := &stubChatServer{}
+	srv := newTestGateway(t, stub)
+
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/chat/conversations?limit=5", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-token")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "Bearer test-token", stub.authorization)
+
+	var body struct {
+		Conversations []struct {
+			ID    string `json:"id"`
+			Title string `json:"title"`
+		} `json:"conversations"`
+		Total int `json:"total"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Conversations, 1)
+	assert.Equal(t, "conv-1", body.Conversations[0].ID)
+	assert.Equal(t, "First", body.Conversations[0].Title)
+	assert.Equal(t, 1, body.Total)
+}
+
+func TestGateway_ForwardsIdempotencyKey(t *testing.T) {
+	stub := &stubChatServer{}
+	srv := newTestGateway(t, stub)
+
+	req, err := http.NewRequest(http.MethodPost, srv.URL+"/v1/chat/message", strings.NewReader(`{"message":"hello"}`))
+	require.NoError(t, err)
+	req.Header.Set("Idempotency-Key", "retry-1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "retry-1", stub.idempotency)
+}
+
+func TestGateway_MapsGRPCErrorsToHTTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantError   string
+		wantMessage string
+	}{
+		{
+			name:        "invalid argument",
+			err:         status.Error(codes.InvalidArgument, "validation error: limit must be positive"),
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "VALIDATION_ERROR",
+			wantMessage: "validation error: limit must be positive",
+		},
+		{
+			name:        "unauthenticated",
+			err:         status.Error(codes.Unauthenticated, "invalid token"),
+			wantStatus:  http.StatusUnauthorized,
+			wantError:   "UNAUTHORIZED",
+			wantMessage: "Unauthorized",
+		},
+		{
+			name:        "permission denied",
+			err:         status.Error(codes.PermissionDenied, "access denied"),
+			wantStatus:  http.StatusForbidden,
+			wantError:   "FORBIDDEN",
+			wantMessage: "access denied",
+		},
+		{
+			name:        "not found",
+			err:         status.Error(codes.NotFound, "conversation not found"),
+			wantStatus:  http.StatusNotFound,
+			wantError:   "NOT_FOUND",
+			wantMessage: "conversation not found",
+		},
+		{
+			name:        "failed precondition",
+			err:         status.Error(codes.FailedPrecondition, "AI response was blocked by the content filter"),
+			wantStatus:  http.StatusBadRequest,
+			wantError:   "FAILED_PRECONDITION",
+			wantMessage: "AI response was blocked by the content filter",
+		},
+		{
+			name:        "deadline exceeded",
+			err:         status.Error(codes.DeadlineExceeded, "request timed out"),
+			wantStatus:  http.StatusRequestTimeout,
+			wantError:   "TIMEOUT",
+			wantMessage: "Request timeout",
+		},
+		{
+			name:        "internal hides details",
+			err:         status.Error(codes.Internal, "pq: connection refused"),
+			wantStatus:  http.StatusInternalServerError,
+			wantError:   "INTERNAL_ERROR",
+			wantMessage: "Internal server error",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newTestGateway(t, &stubChatServer{err: tt.err})
+
+			resp, err := http.Get(srv.URL + "/v1/chat/conversations")
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, tt.wantStatus, resp.StatusCode)
+
+			var body domain.ErrorResponse
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.wantError, body.Error)
+			assert.Equal(t, tt.wantMessage, body.Message)
+		})
+	}
+}
+
+func TestGateway_ReportsFieldViolationsAsDetails(t *testing.T) {
+	st, err := status.New(codes.InvalidArgument, "validation error: limit: must be between 1 and 100; cursor: cannot be combined with offset").
+		WithDetails(&errdetails.BadRequest{FieldViolations: []*errdetails.BadRequest_FieldViolation{
+			{Field: "limit", Description: "must be between 1 and 100"},
+			{Field: "cursor", Description: "cannot be combined with offset"},
+		}})
+	require.NoError(t, err)
+	srv := newTestGateway(t, &stubChatServer{err: st.Err()})
+
+	resp, err := http.Get(srv.URL + "/v1/chat/conversations")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	var body domain.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "VALIDATION_ERROR", body.Error)
+	assert.Equal(t, map[string]string{
+		"limit":  "must be between 1 and 100",
+		"cursor": "cannot be combined with offset",
+	}, body.Details)
+}
+
+func TestGateway_ReportsRetryConversationAsDetails(t *testing.T) {
+	st, err := status.New(codes.Unavailable, "failed to get AI response").
+		WithDetails(&errdetails.ErrorInfo{
+			Reason: "AI_REQUEST_FAILED",
+			Domain: "chat-service",
+			Metadata: map[string]string{
+				"conversation_id": "33333333-3333-3333-3333-333333333333",
+				"user_message_id": "44444444-4444-4444-4444-444444444444",
+			},
+		})
+	require.NoError(t, err)
+	srv := newTestGateway(t, &stubChatServer{err: st.Err()})
+
+	resp, err := http.Get(srv.URL + "/v1/chat/conversations")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	var body domain.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "SERVICE_UNAVAILABLE", body.Error)
+	assert.Equal(t, map[string]string{
+		"conversation_id": "33333333-3333-3333-3333-333333333333",
+		"user_message_id": "44444444-4444-4444-4444-444444444444",
+	}, body.Details)
+}
+
+func TestGateway_ReportsProviderRateLimitWithRetryAfter(t *testing.T) {
+	st, err := status.New(codes.ResourceExhausted, "AI provider rate limit reached; retry later").
+		WithDetails(
+			&errdetails.ErrorInfo{
+				Reason: "AI_RATE_LIMITED",
+				Domain: "chat-service",
+				Metadata: map[string]string{
+					"conversation_id": "33333333-3333-3333-3333-333333333333",
+					"user_message_id": "44444444-4444-4444-4444-444444444444",
+				},
+			},
+			&errdetails.RetryInfo{RetryDelay: durationpb.New(1500 * time.Millisecond)},
+		)
+	require.NoError(t, err)
+	srv := newTestGateway(t, &stubChatServer{err: st.Err()})
+
+	resp, err := http.Get(srv.URL + "/v1/chat/conversations")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusTooManyRequests, resp.StatusCode)
+	assert.Equal(t, "2", resp.Header.Get("Retry-After"), "a partial second is rounded up")
+
+	var body domain.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "RESOURCE_EXHAUSTED", body.Error)
+	assert.Equal(t, "AI provider rate limit reached; retry later", body.Message)
+	assert.Equal(t, map[string]string{
+		"conversation_id":     "33333333-3333-3333-3333-333333333333",
+		"user_message_id":     "44444444-4444-4444-4444-444444444444",
+		"retry_after_seconds": "2",
+	}, body.Details)
+}
+
+func TestGateway_GetConversation(t *testing.T) {
+	srv := newTestGateway(t, &stubChatServer{})
+
+	resp, err := http.Get(srv.URL + "/v1/chat/conversations/33333333-3333-3333-3333-333333333333")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var body struct {
+		ID           string `json:"id"`
+		MessageCount int    `json:"message_count"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "33333333-3333-3333-3333-333333333333", body.ID)
+	assert.Equal(t, 2, body.MessageCount)
+}
+
+func TestGateway_GetConversation_NotFound(t *testing.T) {
+	srv := newTestGateway(t, &stubChatServer{err: status.Error(codes.NotFound, "conversation not found")})
+
+	resp, err := http.Get(srv.URL + "/v1/chat/conversations/33333333-3333-3333-3333-333333333333")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestGateway_DeleteConversation(t *testing.T) {
+	stub := &stubChatServer{}
+	srv := newTestGateway(t, stub)
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+"/v1/chat/conversations/33333333-3333-3333-3333-333333333333", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "33333333-3333-3333-3333-333333333333", stub.deletedID)
+}
+
+func TestGateway_DeleteConversation_NotFound(t *testing.T) {
+	srv := newTestGateway(t, &stubChatServer{err: status.Error(codes.NotFound, "conversation not found")})
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+"/v1/chat/conversations/33333333-3333-3333-3333-333333333333", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	var body domain.ErrorResponse
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "NOT_FOUND", body.Error)
+}
+
+func TestGateway_ClearConversationMessages(t *testing.T) {
+	stub := &stubChatServer{}
+	srv := newTestGateway(t, stub)
+
+	req, err := http.NewRequest(http.MethodDelete, srv.URL+"/v1/chat/conversations/33333333-3333-3333-3333-333333333333/messages", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "33333333-3333-3333-3333-333333333333", stub.clearedID)
+	assert.Empty(t, stub.deletedID, "clearing must not delete the conversation")
+
+	var body struct {
+		DeletedCount int `json:"deleted_count"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, 3, body.DeletedCount)
+}
+
+func TestGateway_EditMessage(t *testing.T) {
+	stub := &stubChatServer{}
+	srv := newTestGateway(t, stub)
+
+	req, err := http.NewRequest(http.MethodPatch, srv.URL+"/v1/chat/message/44444444-4444-4444-4444-444444444444", strings.NewReader(`{"content":"edited"}`))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, stub.edited)
+	assert.Equal(t, "44444444-4444-4444-4444-444444444444", stub.edited.MessageId)
+	assert.Equal(t, "edited", stub.edited.Content)
+
+	var body struct {
+		ID      string `json:"id"`
+		Content string `json:"content"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, "44444444-4444-4444-4444-444444444444", body.ID)
+	assert.Equal(t, "edited", body.Content)
+}
+
+func TestGateway_ListConversationsCursor(t *testing.T) {
+	tests := []struct {
+		name       string
+		query      string
+		wantCursor *string
+		wantNext   string
+	}{
+		{name: "offset paging", query: "?limit=5", wantCursor: nil, wantNext: "-next"},
+		{name: "first cursor page", query: "?limit=5&cursor=", wantCursor: stringPtr(""), wantNext: "-next"},
+		{name: "next cursor page", query: "?limit=5&cursor=abc", wantCursor: stringPtr("abc"), wantNext: "abc-next"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &stubChatServer{}
+			srv := newTestGateway(t, stub)
+
+			resp, err := http.Get(srv.URL + "/v1/chat/conversations" + tt.query)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+			require.NotNil(t, stub.listed)
+			assert.Equal(t, tt.wantCursor, stub.listed.Cursor)
+
+			var body struct {
+				NextCursor string `json:"next_cursor"`
+			}
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.wantNext, body.NextCursor)
+		})
+	}
+}
+
+func TestGateway_SearchMessages(t *testing.T) {
+	stub := &stubChatServer{}
+	srv := newTestGateway(t, stub)
+
+	resp, err := http.Get(srv.URL + "/v1/chat/search?q=deploy+production&limit=5")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NotNil(t, stub.searched)
+	assert.Equal(t, "deploy production", stub.searched.Q)
+	assert.Equal(t, int32(5), stub.searched.Limit)
+
+	var body struct {
+		Results []struct {
+			ConversationID string `json:"conversation_id"`
+			Snippet        string `json:"snippet"`
+		} `json:"results"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	require.Len(t, body.Results, 1)
+	assert.Equal(t, "conv-1", body.Results[0].ConversationID)
+	assert.Equal(t, "«deploy» to production", body.Results[0].Snippet)
+}
+
+func stringPtr(s string) *string {
+	return &s
+}
+
+func TestGateway_HealthEndpointsBypassGRPC(t *testing.T) {
+	srv := newTestGateway(t, &stubChatServer{})
+
+	for _, path := range []string{"/health", "/v1/health", "/v1/health/direct"} {
+		t.Run(path, func(t *testing.T) {
+			resp, err := http.Get(srv.URL + path)
+			require.NoError(t, err)
+			defer resp.Body.Close()
+
+			assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+			var body map[string]string
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, "SERVING", body["status"])
+			assert.Equal(t, "chat-service", body["service"])
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
+)
+
+// defaultReadinessTimeout bounds the readiness checks when HEALTH_CHECK_TIMEOUT is unset
+const defaultReadinessTimeout = 5 * time.Second
+
+// HealthChecker is a dependency the readiness endpoint verifies. Check returns nil
+// while the dependency is usable.
+type HealthChecker struct {
+	Name  string
+	Check func(ctx context.Context) error
+}
+
+// dependencyStatus is the readiness result of a single dependency
+type dependencyStatus struct {
+	Status string `json:"status"` // "up" or "down"
+	// LatencyMS is how long the check took, in milliseconds
+	LatencyMS float64 `json:"latency_ms"`
+}
+
+// readinessResponse is the body of /v1/health/ready
+type readinessResponse struct {
+	Status       string                      `json:"status"`
+	Service      string                      `json:"service"`
+	Timestamp    string                      `json:"timestamp"`
+	Dependencies map[string]dependencyStatus `json:"dependencies"`
+}
+
+// databaseHealthCheck pings the database connection pool
+func databaseHealthCheck(ping func(ctx context.Context) error) HealthChecker {
+	return HealthChecker{Name: "database", Check: ping}
+}
+
+// authServiceHealthCheck checks the auth service answers its health check
+func authServiceHealthCheck(client *AuthClient) HealthChecker {
+	return HealthChecker{Name: "auth-service", Check: client.Ping}
+}
+
+// llmHealthCheck checks the provider API is reachable, if the provider supports it
+func llmHealthCheck(provider llm.LLMProvider) (HealthChecker, bool) {
+	pinger, ok := provider.(llm.Pinger)
+	if !ok {
+		return HealthChecker{}, false
+	}
+	return HealthChecker{Name: provider.Name(), Check: pinger.Ping}, true
+}
+
+// handleReadiness runs every check concurrently, bounded by timeout, and answers 200 when
+// all of them pass or 503 when any fails, with the status and check latency of each
+// dependency. Failure details are logged rather than returned, since the endpoint is
+// unauthenticated.
+func handleReadiness(checks []HealthChecker, timeout time.Duration, logger *zlog.Logger) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+
+		errs := make([]error, len(checks))
+		latencies := make([]time.Duration, len(checks))
+		var wg sync.WaitGroup
+		for i, check := range checks {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				start := time.Now()
+				errs[i] = check.Check(ctx)
+				latencies[i] = time.Since(start)
+			}()
+		}
+		wg.Wait()
+
+		response := readinessResponse{
+			Status:       "SERVING",
+			Service:      "chat-service",
+			Timestamp:    time.Now().Format(time.RFC3339),
+			Dependencies: make(map[string]dependencyStatus, len(checks)),
+		}
+		statusCode := http.StatusOK
+		for i, check := range checks {
+			latency := float64(latencies[i].Microseconds()) / 1000
+			if errs[i] != nil {
+				logger.Error(r.Context(), fmt.Errorf("%s: %w", check.Name, errs[i]), "Readiness check failed", http.StatusServiceUnavailable)
+				response.Dependencies[check.Name] = dependencyStatus{Status: "down", LatencyMS: latency}
+				response.Status = "NOT_SERVING"
+				statusCode = http.StatusServiceUnavailable
+				continue
+			}
+			response.Dependencies[check.Name] = dependencyStatus{Status: "up", LatencyMS: latency}
+		}
+
+		writeJSON(w, r, logger, statusCode, response)
+	}
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chat-service/configs"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubPing returns a health check function that always answers err
+func stubPing(err error) func(ctx context.Context) error {
+	return func(ctx context.Context) error { return err }
+}
+
+func newTestHealthHandler(t *testing.T, checks []HealthChecker) http.Handler {
+	t.Helper()
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	handler, err := newRESTHandler(context.Background(), &configs.Config{}, logger, nil, nil, nil, checks, nil)
+	require.NoError(t, err)
+	return handler
+}
+
+func getReadiness(t *testing.T, handler http.Handler, path string) (*httptest.ResponseRecorder, readinessResponse) {
+	t.Helper()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var body readinessResponse
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&body))
+	return rec, body
+}
+
+func TestReadiness_HealthyDependencies(t *testing.T) {
+	handler := newTestHealthHandler(t, []HealthChecker{
+		databaseHealthCheck(stubPing(nil)),
+		{Name: "openai", Check: stubPing(nil)},
+	})
+
+	rec, body := getReadiness(t, handler, "/v1/health/ready")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	assert.Equal(t, "SERVING", body.Status)
+	assert.Equal(t, map[string]string{
+		"database": "up",
+		"openai":   "up",
+	}, dependencyStatuses(body))
+}
+
+func TestReadiness_FailingDatabase(t *testing.T) {
+	handler := newTestHealthHandler(t, []HealthChecker{
+		databaseHealthCheck(stubPing(errors.New("dial tcp: connection refused"))),
+		{Name: "openai", Check: stubPing(nil)},
+	})
+
+	rec, body := getReadiness(t, handler, "/v1/health/ready")
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "NOT_SERVING", body.Status)
+	assert.Equal(t, map[string]string{
+		"database": "down",
+		"openai":   "up",
+	}, dependencyStatuses(body))
+	assert.NotContains(t, rec.Body.String(), "connection refused")
+}
+
+func TestReadiness_ReportsCheckLatency(t *testing.T) {
+	slowAuth := HealthChecker{Name: "auth-service", Check: func(ctx context.Context) error {
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	}}
+	handler := newTestHealthHandler(t, []HealthChecker{databaseHealthCheck(stubPing(nil)), slowAuth})
+
+	rec, body := getReadiness(t, handler, "/v1/health/ready")
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, body.Dependencies, "auth-service")
+	assert.GreaterOrEqual(t, body.Dependencies["auth-service"].LatencyMS, 20.0)
+	assert.Less(t, body.Dependencies["database"].LatencyMS, body.Dependencies["auth-service"].LatencyMS)
+}
+
+func TestReadiness_FailingAuthService(t *testing.T) {
+	handler := newTestHealthHandler(t, []HealthChecker{
+		databaseHealthCheck(stubPing(nil)),
+		{Name: "auth-service", Check: stubPing(errors.New("connection refused"))},
+	})
+
+	rec, body := getReadiness(t, handler, "/v1/health/ready")
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+	assert.Equal(t, "NOT_SERVING", body.Status)
+	assert.Equal(t, "down", body.Dependencies["auth-service"].Status)
+	assert.Equal(t, "up", body.Dependencies["database"].Status)
+}
+
+func TestReadiness_SlowCheckTimesOut(t *testing.T) {
+	slow := HealthChecker{Name: "database", Check: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}}
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	rec := httptest.NewRecorder()
+	handleReadiness([]HealthChecker{slow}, 10*time.Millisecond, logger)(rec, httptest.NewRequest(http.MethodGet, "/v1/health/ready", nil))
+
+	assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
+
+func TestLiveness_IgnoresDependencies(t *testing.T) {
+	handler := newTestHealthHandler(t, []HealthChecker{
+		databaseHealthCheck(stubPing(errors.New("down"))),
+	})
+
+	for _, path := range []string{"/v1/health/live", "/v1/health", "/health"} {
+		t.Run(path, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+		})
+	}
+}
+
+// dependencyStatuses maps each dependency in body to its status
+func dependencyStatuses(body readinessResponse) map[string]string {
+	statuses := make(map[string]string, len(body.Dependencies))
+	for name, dependency := range body.Dependencies {
+		statuses[name] = dependency.Status
+	}
+	return statuses
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// inFlightTracker counts the REST requests being served so shutdown can wait for them
+type inFlightTracker struct {
+	wg     sync.WaitGroup
+	active atomic.Int64
+}
+
+// Middleware tracks each request from arrival until its handler returns
+func (t *inFlightTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.wg.Add(1)
+		t.active.Add(1)
+		defer func() {
+			t.active.Add(-1)
+			t.wg.Done()
+		}()
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Active returns the number of requests currently being served
+func (t *inFlightTracker) Active() int64 {
+	return t.active.Load()
+}
+
+// Wait blocks until every tracked request has finished, or returns ctx's error if the
+// deadline passes first
+func (t *inFlightTracker) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		t.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
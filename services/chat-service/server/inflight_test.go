@@ -0,0 +1,135 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+)
+
+// newShutdownTestServer serves handler behind the in-flight tracker, alongside an empty
+// gRPC server, and returns the server with the REST base URL
+func newShutdownTestServer(t *testing.T, handler http.Handler) (*Server, string) {
+	t.Helper()
+
+	restLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	grpcLis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	inFlight := &inFlightTracker{}
+	s := &Server{
+		logger:     zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard}),
+		grpcServer: grpc.NewServer(),
+		grpcLis:    grpcLis,
+		restServer: &http.Server{Handler: inFlight.Middleware(handler)},
+		restLis:    restLis,
+		inFlight:   inFlight,
+	}
+	go s.grpcServer.Serve(grpcLis)
+	go s.restServer.Serve(restLis)
+
+	return s, "http://" + restLis.Addr().String()
+}
+
+// slowHandler signals started when a request arrives and answers after delay
+func slowHandler(started chan<- struct{}, delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(delay)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("done"))
+	})
+}
+
+func TestServer_ShutdownDrainsInFlightRequests(t *testing.T) {
+	started := make(chan struct{})
+	s, url := newShutdownTestServer(t, slowHandler(started, 300*time.Millisecond))
+
+	type result struct {
+		status int
+		body   string
+		err    error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		results <- result{status: resp.StatusCode, body: string(body), err: err}
+	}()
+
+	<-started
+	assert.Equal(t, int64(1), s.inFlight.Active())
+
+	err := s.Shutdown(context.Background())
+	require.NoError(t, err)
+
+	res := <-results
+	require.NoError(t, res.err, "the in-flight request must complete")
+	assert.Equal(t, http.StatusOK, res.status)
+	assert.Equal(t, "done", res.body)
+	assert.Equal(t, int64(0), s.inFlight.Active())
+
+	// New requests are refused once shutdown has started
+	_, err = http.Get(url + "/slow")
+	assert.Error(t, err)
+}
+
+func TestServer_ShutdownReturnsErrorPastDeadline(t *testing.T) {
+	started := make(chan struct{})
+	s, url := newShutdownTestServer(t, slowHandler(started, 2*time.Second))
+
+	go func() {
+		if resp, err := http.Get(url + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	begin := time.Now()
+	err := s.Shutdown(ctx)
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(begin), time.Second, "shutdown should give up at the deadline")
+}
+
+func TestServer_ShutdownUsesConfiguredTimeout(t *testing.T) {
+	started := make(chan struct{})
+	s, url := newShutdownTestServer(t, slowHandler(started, 2*DefaultShutdownTimeout))
+	s.shutdownTimeout = 200 * time.Millisecond
+
+	go func() {
+		if resp, err := http.Get(url + "/slow"); err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-started
+
+	begin := time.Now()
+	err := s.Shutdown(context.Background())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(begin), DefaultShutdownTimeout, "shutdown should give up at the configured timeout, not the default")
+}
+
+func TestInFlightTracker_WaitWithoutRequests(t *testing.T) {
+	tracker := &inFlightTracker{}
+
+	assert.NoError(t, tracker.Wait(context.Background()))
+}
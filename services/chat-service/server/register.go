@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+
+	"chat-service/configs"
+	"chat-service/internal/services/chat"
+	grpchandler "chat-service/internal/transport/grpc"
+	chatproto "chat-service/proto"
+	zlog "packages/logger"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/reflection"
+)
+
+// registerServices registers the chat gRPC services on grpcServer, along with the
+// reflection service when GRPC_REFLECTION_ENABLED is set
+func registerServices(grpcServer *grpc.Server, chatService chat.Service, logger *zlog.Logger, cfg *configs.Config) {
+	chatproto.RegisterChatServiceServer(grpcServer, grpchandler.NewChatHandler(chatService, logger))
+
+	if cfg.GRPCReflectionEnabled {
+		reflection.Register(grpcServer)
+		logger.Info(context.Background(), "gRPC reflection enabled", map[string]any{
+			"environment": cfg.Environment,
+		})
+	}
+
+	// Note: REST gateway handlers are registered in the server.go file
+	// when creating the REST gateway
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"io"
+	"testing"
+
+	"chat-service/configs"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+)
+
+func TestRegisterServices_Reflection(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	for _, enabled := range []bool{true, false} {
+		grpcServer := grpc.NewServer()
+		registerServices(grpcServer, nil, logger, &configs.Config{GRPCReflectionEnabled: enabled})
+
+		services := grpcServer.GetServiceInfo()
+		assert.Contains(t, services, "chat.ChatService")
+		if enabled {
+			assert.Contains(t, services, reflectionpb.ServerReflection_ServiceDesc.ServiceName, "reflection must be registered when enabled")
+		} else {
+			assert.NotContains(t, services, reflectionpb.ServerReflection_ServiceDesc.ServiceName, "reflection must not be registered when disabled")
+		}
+	}
+}
@@ -3,105 +3,80 @@ package server
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io/ioutil"
 	"net"
 	"net/http"
 	"os"
 	"os/signal"
-	"strconv"
-	"strings"
 	"syscall"
 	"time"
 
-	authproto "api/auth/v1/proto"
 	"chat-service/configs"
 	"chat-service/internal/domain"
+	"chat-service/internal/services/anthropic"
 	"chat-service/internal/services/chat"
+	"chat-service/internal/services/events"
+	"chat-service/internal/services/llm"
 	"chat-service/internal/services/openai"
 	grpchandler "chat-service/internal/transport/grpc"
-	chatproto "chat-service/proto"
 	"chat-service/storage"
+	"packages/grpcutil"
 	zlog "packages/logger"
+	"packages/tracing"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials"
-	"google.golang.org/grpc/credentials/insecure"
 	"google.golang.org/grpc/keepalive"
-	"google.golang.org/grpc/reflection"
 )
 
 const (
-	// DefaultShutdownTimeout is the default timeout for graceful shutdown
+	// DefaultShutdownTimeout is the timeout for graceful shutdown when none is configured
 	DefaultShutdownTimeout = 5 * time.Second
 )
 
-// createRESTGateway creates the REST gateway server
-func createRESTGateway(ctx context.Context, cfg *configs.Config, logger *zlog.Logger, grpcServer *grpc.Server, chatService chat.Service) (*http.Server, net.Listener, error) {
+// createRESTGateway creates the REST gateway server. Chat endpoints are served by the
+// generated grpc-gateway handlers over conn, so every request passes through the gRPC
+// interceptors; only health checks, the SSE stream and the WebSocket are handled directly.
+func createRESTGateway(ctx context.Context, cfg *configs.Config, logger *zlog.Logger, conn *grpc.ClientConn, chatService chat.Service, authClient *AuthClient, checks []HealthChecker, inFlight *inFlightTracker, streams *grpchandler.StreamLimiter) (*http.Server, net.Listener, error) {
 	// Create REST listener
 	restLis, err := net.Listen("tcp", ":"+cfg.RestGatewayPort)
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create REST listener: %w", err)
 	}
 
-	// Create a simple HTTP mux for now
-	mux := http.NewServeMux()
+	// Hijacked WebSocket connections are invisible to Shutdown, so they are closed through
+	// this context once shutdown begins
+	streamsCtx, cancelStreams := context.WithCancel(ctx)
 
-	// Add direct health check endpoint as fallback
-	mux.HandleFunc("/v1/health/direct", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"SERVING","service":"chat-service","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
-	})
-
-	// Add a simple health endpoint that doesn't depend on gRPC
-	mux.HandleFunc("/v1/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"SERVING","service":"chat-service","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
-	})
-
-	// Add a root health endpoint for basic connectivity testing
-	mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusOK)
-		w.Write([]byte(`{"status":"SERVING","service":"chat-service","timestamp":"` + time.Now().Format(time.RFC3339) + `"}`))
-	})
-
-	// Chat endpoints
-	mux.HandleFunc("/v1/chat/message", func(w http.ResponseWriter, r *http.Request) {
-		handleSendMessage(w, r, chatService, logger, cfg)
-	})
-
-	mux.HandleFunc("/v1/chat/ai", func(w http.ResponseWriter, r *http.Request) {
-		handleChatWithAI(w, r, chatService, logger, cfg)
-	})
-
-	mux.HandleFunc("/v1/chat/conversations", func(w http.ResponseWriter, r *http.Request) {
-		handleConversations(w, r, chatService, logger, cfg)
-	})
+	handler, err := newRESTHandler(streamsCtx, cfg, logger, conn, chatService, authClient, checks, streams)
+	if err != nil {
+		cancelStreams()
+		restLis.Close()
+		return nil, nil, err
+	}
 
-	mux.HandleFunc("/v1/chat/history/", func(w http.ResponseWriter, r *http.Request) {
-		handleGetHistory(w, r, chatService, logger, cfg)
-	})
+	if cfg.CompressionEnabled {
+		handler = grpcutil.CompressResponses(handler, cfg.CompressionMinBytes)
+	}
 
 	// Create HTTP server with proper timeout configurations
 	restServer := &http.Server{
-		Handler:           mux,
+		Handler:           withCorrelationID(withTracing(inFlight.Middleware(limitRequestBody(handler, cfg.MaxRequestBodyBytes, logger)))),
 		Addr:              restLis.Addr().String(),
 		ReadTimeout:       time.Duration(cfg.ServerReadTimeout) * time.Second,
 		WriteTimeout:      time.Duration(cfg.ServerWriteTimeout) * time.Second,
 		IdleTimeout:       60 * time.Second,
 		ReadHeaderTimeout: 10 * time.Second,
 	}
+	restServer.RegisterOnShutdown(cancelStreams)
 
 	return restServer, restLis, nil
 }
 
 // extractUserIDFromToken extracts user ID from JWT token in REST requests
-func extractUserIDFromToken(r *http.Request, config *configs.Config) (string, error) {
+func extractUserIDFromToken(r *http.Request, tokens *tokenValidator) (string, error) {
 	// Get Authorization header
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
@@ -113,456 +88,111 @@ func extractUserIDFromToken(r *http.Request, config *configs.Config) (string, er
 		return "", fmt.Errorf("invalid authorization header format")
 	}
 
-	token := authHeader[7:]
-
-	// Create a context for the gRPC call
-	ctx := r.Context()
-
-	// Create gRPC connection to auth service
-	var authConn *grpc.ClientConn
-	var err error
-
-	if config.AuthServiceTLS && config.TLSEnabled {
-		// Load client certificates for mTLS
-		cert, err := tls.LoadX509KeyPair(config.AuthServiceCertFile, config.AuthServiceKeyFile)
-		if err != nil {
-			return "", fmt.Errorf("failed to load client certificates: %w", err)
-		}
-
-		// Load CA certificate
-		caCert, err := ioutil.ReadFile(config.AuthServiceCAFile)
-		if err != nil {
-			return "", fmt.Errorf("failed to read CA certificate: %w", err)
-		}
-
-		caCertPool := x509.NewCertPool()
-		if !caCertPool.AppendCertsFromPEM(caCert) {
-			return "", fmt.Errorf("failed to append CA certificate")
-		}
-
-		tlsConfig := &tls.Config{
-			Certificates: []tls.Certificate{cert},
-			RootCAs:      caCertPool,
-			ServerName:   config.AuthServiceHost,
-		}
-
-		creds := credentials.NewTLS(tlsConfig)
-		authConn, err = grpc.Dial(
-			fmt.Sprintf("%s:%s", config.AuthServiceHost, config.AuthServicePort),
-			grpc.WithTransportCredentials(creds),
-		)
-	} else {
-		authConn, err = grpc.Dial(
-			fmt.Sprintf("%s:%s", config.AuthServiceHost, config.AuthServicePort),
-			grpc.WithTransportCredentials(insecure.NewCredentials()),
-		)
-	}
-
-	if err != nil {
-		return "", fmt.Errorf("failed to connect to auth service: %w", err)
-	}
-	defer authConn.Close()
-
-	// Create auth service client and validate token
-	authClient := authproto.NewAuthServiceClient(authConn)
-	resp, err := authClient.ValidateToken(ctx, &authproto.ValidateTokenRequest{
-		Token: token,
-	})
-	if err != nil {
-		return "", fmt.Errorf("auth service error: %w", err)
-	}
-
-	if !resp.Valid {
-		return "", fmt.Errorf("token validation failed: %s", resp.ErrorMessage)
-	}
-
-	return resp.UserId, nil
+	return tokens.UserID(r.Context(), authHeader[7:])
 }
 
-// REST endpoint handlers
-
-// handleSendMessage handles POST /v1/chat/message
-func handleSendMessage(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, config *configs.Config) {
+// handleChatWithAIStream handles POST /v1/chat/ai/stream, relaying the AI response as server-sent events
+func handleChatWithAIStream(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, tokens *tokenValidator) {
 	if r.Method != http.MethodPost {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusMethodNotAllowed)
-		json.NewEncoder(w).Encode(domain.NewErrorResponse("METHOD_NOT_ALLOWED", "Method not allowed", "405"))
-		return
-	}
-
-	// Extract user ID from JWT token
-	userID, err := extractUserIDFromToken(r, config)
-	if err != nil {
-		logger.Error(r.Context(), err, "Failed to extract user ID from token", 401)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusUnauthorized)
-		json.NewEncoder(w).Encode(domain.NewErrorResponse("UNAUTHORIZED", "Unauthorized", "401"))
-		return
-	}
-
-	// Parse request body
-	var req struct {
-		Message        string `json:"message"`
-		ConversationID string `json:"conversation_id,omitempty"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.NewErrorResponse("INVALID_REQUEST", "Invalid request body", "400"))
-		return
-	}
-
-	// Validate required fields
-	if req.Message == "" {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.NewErrorResponse("VALIDATION_ERROR", "message is required", "400"))
-		return
-	}
-
-	// Create domain request
-	domainReq := &domain.ChatRequest{
-		UserID:         userID,
-		Message:        req.Message,
-		ConversationID: req.ConversationID,
-	}
-
-	// Validate the request
-	if err := domainReq.Validate(); err != nil {
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(domain.NewErrorResponseWithDetails("VALIDATION_ERROR", "Validation error", "400", map[string]string{
-			"details": err.Error(),
-		}))
-		return
-	}
-
-	// Call chat service
-	ctx := r.Context()
-	response, err := chatService.SendMessage(ctx, domainReq)
-	if err != nil {
-		logger.Error(ctx, err, "Failed to send message", 500)
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(domain.NewErrorResponse("INTERNAL_ERROR", "Internal server error", "500"))
+		writeJSONError(w, r, logger, "METHOD_NOT_ALLOWED", "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
-		"message": map[string]any{
-			"id":         response.Message.ID,
-			"user_id":    response.Message.UserID,
-			"content":    response.Message.Content,
-			"role":       response.Message.Role,
-			"created_at": response.Message.CreatedAt,
-		},
-		"conversation_id": response.ConversationID,
-		"is_ai_response":  response.IsAIResponse,
-	})
-}
-
-// handleChatWithAI handles POST /v1/chat/ai
-func handleChatWithAI(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, config *configs.Config) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeJSONError(w, r, logger, "INTERNAL_ERROR", "Streaming unsupported", http.StatusInternalServerError)
 		return
 	}
 
 	// Extract user ID from JWT token
-	userID, err := extractUserIDFromToken(r, config)
+	userID, err := extractUserIDFromToken(r, tokens)
 	if err != nil {
 		logger.Error(r.Context(), err, "Failed to extract user ID from token", 401)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		writeJSONError(w, r, logger, "UNAUTHORIZED", "Unauthorized", http.StatusUnauthorized)
 		return
 	}
 
 	// Parse request body
-	var req struct {
-		Message        string  `json:"message"`
-		ConversationID string  `json:"conversation_id,omitempty"`
-		Model          string  `json:"model,omitempty"`
-		Temperature    float64 `json:"temperature,omitempty"`
-		MaxTokens      int     `json:"max_tokens,omitempty"`
-	}
-
+	var req domain.ChatWithAIRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeJSONError(w, r, logger, "INVALID_REQUEST", "Invalid request body", http.StatusBadRequest)
 		return
 	}
+	req.UserID = userID
 
-	// Validate required fields
-	if req.Message == "" {
-		http.Error(w, "message is required", http.StatusBadRequest)
+	// Validate the request and set defaults for unset options; an empty model selects the
+	// active provider's default
+	if err := req.Validate(); err != nil {
+		writeJSONError(w, r, logger, "VALIDATION_ERROR", fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
 		return
 	}
 
-	// Validate UUIDs
-	if err := domain.ValidateUUID(userID); err != nil {
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
-		return
-	}
-	if req.ConversationID != "" {
-		if err := domain.ValidateUUID(req.ConversationID); err != nil {
-			http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
-			return
-		}
-	}
+	// Streams can outlive the server write timeout, so lift it for this response
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
 
-	// Set defaults
-	if req.Model == "" {
-		req.Model = "gpt-3.5-turbo"
-	}
-	if req.Temperature == 0 {
-		req.Temperature = 0.7
-	}
-	if req.MaxTokens == 0 {
-		req.MaxTokens = 1000
-	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
 
-	// Call chat service
+	// Call chat service, writing each delta as an SSE data event
 	ctx := r.Context()
-	response, err := chatService.ChatWithAI(ctx, userID, req.Message, req.ConversationID, req.Model, req.Temperature, req.MaxTokens)
+	response, err := chatService.ChatWithAIStream(ctx, req.UserID, req.Message, req.ConversationID, req.Model, *req.Temperature, *req.MaxTokens, req.Metadata, func(_, delta string) error {
+		if err := writeSSEEvent(w, "", map[string]any{"delta": delta}); err != nil {
+			return err
+		}
+		flusher.Flush()
+		return nil
+	})
 	if err != nil {
-		logger.Error(ctx, err, "Failed to chat with AI", 500)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		response, _, status := serviceErrorResponse(ctx, err)
+		logRequestError(ctx, logger, err, "Failed to stream chat with AI", status)
+		if status == statusClientClosedRequest {
+			// The client has gone away; there is nobody left to tell
+			return
+		}
+		// The status line is already sent, so a retry delay is only reported in the details
+		writeSSEEvent(w, "error", response)
+		flusher.Flush()
 		return
 	}
 
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
+	writeSSEEvent(w, "done", map[string]any{
 		"ai_message":      response.Message.Content,
+		"message_id":      response.Message.ID,
 		"conversation_id": response.ConversationID,
-		"model_used":      req.Model,
-		"tokens_used":     0, // Would come from OpenAI response
+		"model_used":      response.Model,
 		"created_at":      response.Message.CreatedAt,
 	})
+	flusher.Flush()
 }
 
-// handleConversations handles GET/POST /v1/chat/conversations
-func handleConversations(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, config *configs.Config) {
-	switch r.Method {
-	case http.MethodGet:
-		handleListConversations(w, r, chatService, logger, config)
-	case http.MethodPost:
-		handleCreateConversation(w, r, chatService, logger, config)
-	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-	}
-}
-
-// handleListConversations handles GET /v1/chat/conversations
-func handleListConversations(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, config *configs.Config) {
-	// Extract user ID from JWT token
-	userID, err := extractUserIDFromToken(r, config)
-	if err != nil {
-		logger.Error(r.Context(), err, "Failed to extract user ID from token", 401)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	limit := 10 // default limit
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	offset := 0 // default offset
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
-	}
-
-	// Create domain request
-	domainReq := &domain.ListConversationsRequest{
-		UserID: userID,
-		Limit:  limit,
-		Offset: offset,
-	}
-
-	// Validate the request
-	if err := domainReq.Validate(); err != nil {
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Call chat service
-	ctx := r.Context()
-	response, err := chatService.ListConversations(ctx, domainReq)
+// writeSSEEvent writes a single server-sent event with a JSON payload
+func writeSSEEvent(w http.ResponseWriter, event string, data any) error {
+	payload, err := json.Marshal(data)
 	if err != nil {
-		logger.Error(ctx, err, "Failed to list conversations", 500)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
+		return fmt.Errorf("failed to marshal event: %w", err)
 	}
-
-	// Convert to response format
-	conversations := make([]map[string]any, len(response.Conversations))
-	for i, conv := range response.Conversations {
-		conversations[i] = map[string]any{
-			"id":         conv.ID,
-			"user_id":    conv.UserID,
-			"title":      conv.Title,
-			"created_at": conv.CreatedAt,
-			"updated_at": conv.UpdatedAt,
+	if event != "" {
+		if _, err := fmt.Fprintf(w, "event: %s\n", event); err != nil {
+			return err
 		}
 	}
-
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
-		"conversations": conversations,
-		"total":         response.Total,
-	})
+	_, err = fmt.Fprintf(w, "data: %s\n\n", payload)
+	return err
 }
 
-// handleCreateConversation handles POST /v1/chat/conversations
-func handleCreateConversation(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, config *configs.Config) {
-	// Extract user ID from JWT token
-	userID, err := extractUserIDFromToken(r, config)
-	if err != nil {
-		logger.Error(r.Context(), err, "Failed to extract user ID from token", 401)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	// Parse request body
-	var req struct {
-		Title string `json:"title"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
-		return
-	}
-
-	// Validate required fields
-	if req.Title == "" {
-		http.Error(w, "title is required", http.StatusBadRequest)
-		return
-	}
-
-	// Validate UUID
-	if err := domain.ValidateUUID(userID); err != nil {
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Call chat service
-	ctx := r.Context()
-	conversation, err := chatService.CreateConversation(ctx, userID, req.Title)
-	if err != nil {
-		logger.Error(ctx, err, "Failed to create conversation", 500)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(map[string]any{
-		"id":         conversation.ID,
-		"user_id":    conversation.UserID,
-		"title":      conversation.Title,
-		"created_at": conversation.CreatedAt,
-		"updated_at": conversation.UpdatedAt,
-	})
-}
-
-// handleGetHistory handles GET /v1/chat/history/{conversation_id}
-func handleGetHistory(w http.ResponseWriter, r *http.Request, chatService chat.Service, logger *zlog.Logger, config *configs.Config) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
-
-	// Extract conversation ID from URL path
-	pathParts := strings.Split(strings.TrimPrefix(r.URL.Path, "/v1/chat/history/"), "/")
-	if len(pathParts) == 0 || pathParts[0] == "" {
-		http.Error(w, "conversation_id is required in URL path", http.StatusBadRequest)
-		return
-	}
-	conversationID := pathParts[0]
-
-	// Validate conversation ID UUID
-	if err := domain.ValidateUUID(conversationID); err != nil {
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Extract user ID from JWT token
-	userID, err := extractUserIDFromToken(r, config)
-	if err != nil {
-		logger.Error(r.Context(), err, "Failed to extract user ID from token", 401)
-		http.Error(w, "Unauthorized", http.StatusUnauthorized)
-		return
-	}
-
-	limit := 50 // default limit
-	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
-	}
-
-	offset := 0 // default offset
-	if offsetStr := r.URL.Query().Get("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
-	}
-
-	// Create domain request
-	domainReq := &domain.GetHistoryRequest{
-		UserID:         userID,
-		ConversationID: conversationID,
-		Limit:          limit,
-		Offset:         offset,
-	}
-
-	// Validate the request
-	if err := domainReq.Validate(); err != nil {
-		http.Error(w, fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
-		return
-	}
-
-	// Call chat service
-	ctx := r.Context()
-	response, err := chatService.GetHistory(ctx, domainReq)
-	if err != nil {
-		logger.Error(ctx, err, "Failed to get chat history", 500)
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
-		return
-	}
-
-	// Convert to response format
-	messages := make([]map[string]any, len(response.Messages))
-	for i, msg := range response.Messages {
-		messages[i] = map[string]any{
-			"id":         msg.ID,
-			"user_id":    msg.UserID,
-			"content":    msg.Content,
-			"role":       msg.Role,
-			"created_at": msg.CreatedAt,
-			"updated_at": msg.UpdatedAt,
-		}
+// newLLMProvider creates the chat completion provider selected by LLM_PROVIDER
+func newLLMProvider(cfg *configs.Config, logger *zlog.Logger) (llm.LLMProvider, error) {
+	switch cfg.LLMProvider {
+	case configs.OPENAI_PROVIDER:
+		return llm.WithTracing(llm.WithRetry(openai.NewClient(cfg, logger), llm.DefaultRetryPolicy(cfg.OpenAIMaxRetries), logger)), nil
+	case configs.ANTHROPIC_PROVIDER:
+		return llm.WithTracing(anthropic.NewClient(cfg, logger)), nil
+	default:
+		return nil, fmt.Errorf("unsupported LLM provider: %q", cfg.LLMProvider)
 	}
-
-	// Return response
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(map[string]any{
-		"messages":        messages,
-		"total":           response.Total,
-		"conversation_id": response.ConversationID,
-	})
 }
 
 // Server holds the gRPC server and its dependencies
@@ -573,8 +203,15 @@ type Server struct {
 	grpcLis         net.Listener
 	restServer      *http.Server
 	restLis         net.Listener
+	inFlight        *inFlightTracker
+	gatewayConn     *grpc.ClientConn
+	authClient      *AuthClient
 	authInterceptor *grpchandler.AuthInterceptor
 	db              *storage.DB
+	archiver        *chat.Archiver      // nil unless ConversationArchiveDays is set
+	webhooks        *events.WebhookSink // nil unless WebhookURL is set
+	shutdownTimeout time.Duration
+	shutdownTracing func(context.Context) error
 }
 
 // NewServer initializes the gRPC server with its dependencies
@@ -586,20 +223,49 @@ func NewServer(ctx context.Context) (*Server, error) {
 	}
 
 	// Initialize logger
-	logger := zlog.NewLogger(zlog.Config{
-		Level:      cfg.LogLevel,
-		Output:     os.Stdout,
-		JSONFormat: cfg.LogJSONFormat,
-		AddCaller:  true,
-		TimeFormat: time.RFC3339,
+	logger := zlog.New(zlog.Config{
+		Level:       cfg.LogLevel,
+		Output:      os.Stdout,
+		JSONFormat:  cfg.LogJSONFormat,
+		AddCaller:   true,
+		TimeFormat:  time.RFC3339,
+		SampleRate:  cfg.LogSampleRate,
+		SampleBurst: cfg.LogSampleBurst,
 	})
 
 	// Create a context with correlation ID for initialization
 	ctx = zlog.WithCorrelationID(ctx, "")
 
-	// Initialize OpenAI client
-	logger.Info(ctx, "Initializing OpenAI client")
-	openaiClient := openai.NewClient(cfg, logger)
+	// Initialize tracing before any instrumented client is created
+	shutdownTracing, err := tracing.Setup(ctx, cfg.OTelExporterEndpoint, "chat-service")
+	if err != nil {
+		logger.Error(ctx, err, "Failed to initialize tracing", 500)
+		return nil, fmt.Errorf("failed to initialize tracing: %w", err)
+	}
+	if cfg.OTelExporterEndpoint != "" {
+		logger.Info(ctx, "Tracing enabled", map[string]any{
+			"endpoint": cfg.OTelExporterEndpoint,
+		})
+	}
+
+	// Initialize LLM provider
+	provider, err := newLLMProvider(cfg, logger)
+	if err != nil {
+		logger.Error(ctx, err, "Failed to initialize LLM provider", 500)
+		return nil, fmt.Errorf("failed to initialize LLM provider: %w", err)
+	}
+	logger.Info(ctx, "Initialized LLM provider", map[string]any{
+		"provider":      provider.Name(),
+		"default_model": cfg.DefaultModel(),
+	})
+
+	// Fail fast on an invalid OpenAI key, before anything else is started
+	if cfg.StartupOpenAICheck {
+		if err := checkOpenAICredentials(ctx, openai.NewClient(cfg, logger).(llm.Pinger), logger); err != nil {
+			logger.Error(ctx, err, "OpenAI credentials check failed", 500)
+			return nil, fmt.Errorf("OpenAI credentials check failed: %w", err)
+		}
+	}
 
 	// Initialize storage
 	logger.Info(ctx, "Initializing database storage")
@@ -611,7 +277,19 @@ func NewServer(ctx context.Context) (*Server, error) {
 
 	// Initialize chat service
 	logger.Info(ctx, "Creating chat service")
-	chatService := chat.NewService(openaiClient, logger, cfg, db)
+	var chatOptions []chat.Option
+	if cfg.ModerationEnabled {
+		chatOptions = append(chatOptions, chat.WithModerator(openai.NewModerator(cfg, logger)))
+		logger.Info(ctx, "Content moderation enabled")
+	}
+	var webhooks *events.WebhookSink
+	if cfg.WebhookURL != "" {
+		webhooks = events.NewWebhookSink(cfg.WebhookURL, cfg.WebhookSecret,
+			time.Duration(cfg.WebhookTimeout)*time.Second, cfg.WebhookMaxRetries, logger)
+		chatOptions = append(chatOptions, chat.WithEventSink(webhooks))
+		logger.Info(ctx, "Webhook events enabled")
+	}
+	chatService := chat.NewService(provider, logger, cfg, db, chatOptions...)
 
 	// Initialize auth interceptor
 	logger.Info(ctx, "Initializing auth interceptor")
@@ -621,13 +299,22 @@ func NewServer(ctx context.Context) (*Server, error) {
 		return nil, fmt.Errorf("failed to initialize auth interceptor: %w", err)
 	}
 
+	// gRPC streams and WebSockets share one per-user stream budget
+	streamLimiter := grpchandler.NewStreamLimiter(cfg.MaxConcurrentStreamsPerUser)
+
 	// Create gRPC server with interceptors
-	grpcServer := grpc.NewServer(
+	serverOptions := []grpc.ServerOption{
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 		grpc.ChainUnaryInterceptor(
+			grpchandler.UnaryRequestIDInterceptor(),
+			grpcutil.UnaryMessageSizeInterceptor(cfg.GRPCMaxMessageBytes, logger),
 			authInterceptor.UnaryAuthInterceptor(),
+			grpchandler.UnaryTimeoutInterceptor(time.Duration(cfg.RequestTimeout)*time.Second, logger),
 		),
 		grpc.ChainStreamInterceptor(
+			grpchandler.StreamRequestIDInterceptor(),
 			authInterceptor.StreamAuthInterceptor(),
+			streamLimiter.StreamInterceptor(logger),
 		),
 		grpc.KeepaliveParams(keepalive.ServerParameters{
 			MaxConnectionIdle: 5 * time.Minute,
@@ -635,17 +322,14 @@ func NewServer(ctx context.Context) (*Server, error) {
 			Time:              2 * time.Minute,
 			Timeout:           20 * time.Second,
 		}),
-	)
+		grpcutil.KeepaliveEnforcementPolicy(time.Duration(cfg.GRPCKeepaliveMinTime)*time.Second, cfg.GRPCKeepalivePermitWithoutStream),
+	}
+	serverOptions = append(serverOptions, grpcutil.MessageSizeServerOptions(cfg.GRPCMaxMessageBytes)...)
+	grpcServer := grpc.NewServer(serverOptions...)
 
 	// Register services
 	logger.Info(ctx, "Registering gRPC services")
-	chatproto.RegisterChatServiceServer(grpcServer, grpchandler.NewChatHandler(chatService, logger))
-
-	// Enable reflection for development
-	if cfg.Environment == configs.DEVELOPMENT_ENV {
-		reflection.Register(grpcServer)
-		logger.Info(ctx, "gRPC reflection enabled for development")
-	}
+	registerServices(grpcServer, chatService, logger, cfg)
 
 	// Create gRPC listener
 	var grpcLis net.Listener
@@ -684,13 +368,51 @@ func NewServer(ctx context.Context) (*Server, error) {
 		})
 	}
 
+	// Connect the REST gateway to the local gRPC server
+	gatewayConn, err := newGatewayConn(cfg)
+	if err != nil {
+		logger.Error(ctx, err, "Failed to connect REST gateway to gRPC server", 500)
+		return nil, fmt.Errorf("failed to connect REST gateway to gRPC server: %w", err)
+	}
+
+	// Connect to the auth service once; the REST handlers share this connection
+	authClient, err := NewAuthClient(cfg)
+	if err != nil {
+		gatewayConn.Close()
+		logger.Error(ctx, err, "Failed to connect to auth service", 500)
+		return nil, fmt.Errorf("failed to connect to auth service: %w", err)
+	}
+
+	// Dependencies verified by the readiness endpoint
+	checks := []HealthChecker{databaseHealthCheck(db.PingContext), authServiceHealthCheck(authClient)}
+	if cfg.HealthCheckLLM {
+		if check, ok := llmHealthCheck(provider); ok {
+			checks = append(checks, check)
+		} else {
+			logger.Warn(ctx, "LLM provider does not support health checks", map[string]any{
+				"provider": provider.Name(),
+			})
+		}
+	}
+
 	// Create REST gateway
-	restServer, restLis, err := createRESTGateway(ctx, cfg, logger, grpcServer, chatService)
+	inFlight := &inFlightTracker{}
+	restServer, restLis, err := createRESTGateway(ctx, cfg, logger, gatewayConn, chatService, authClient, checks, inFlight, streamLimiter)
 	if err != nil {
+		authClient.Close()
+		gatewayConn.Close()
 		logger.Error(ctx, err, "Failed to create REST gateway", 500)
 		return nil, fmt.Errorf("failed to create REST gateway: %w", err)
 	}
 
+	// Archive idle conversations in the background when enabled
+	var archiver *chat.Archiver
+	if cfg.ConversationArchiveDays > 0 {
+		archiver = chat.NewArchiver(db, logger,
+			time.Duration(cfg.ConversationArchiveDays)*24*time.Hour,
+			time.Duration(cfg.ConversationArchiveInterval)*time.Second)
+	}
+
 	return &Server{
 		logger:          logger,
 		config:          cfg,
@@ -698,8 +420,15 @@ func NewServer(ctx context.Context) (*Server, error) {
 		grpcLis:         grpcLis,
 		restServer:      restServer,
 		restLis:         restLis,
+		inFlight:        inFlight,
+		gatewayConn:     gatewayConn,
+		authClient:      authClient,
 		authInterceptor: authInterceptor,
 		db:              db,
+		archiver:        archiver,
+		webhooks:        webhooks,
+		shutdownTimeout: time.Duration(cfg.ShutdownTimeout) * time.Second,
+		shutdownTracing: shutdownTracing,
 	}, nil
 }
 
@@ -722,11 +451,20 @@ func (s *Server) Run(ctx context.Context) error {
 			"port": s.config.RestGatewayPort,
 		})
 
-		if err := s.restServer.Serve(s.restLis); err != nil {
+		if err := s.restServer.Serve(s.restLis); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			s.logger.Error(ctx, err, "Failed to serve REST", 500)
 		}
 	}()
 
+	// Start the idle conversation archiver
+	if s.archiver != nil {
+		s.logger.Info(ctx, "Starting conversation archiver", map[string]any{
+			"archive_days": s.config.ConversationArchiveDays,
+			"interval":     s.config.ConversationArchiveInterval,
+		})
+		s.archiver.Start(ctx)
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -738,12 +476,77 @@ func (s *Server) Run(ctx context.Context) error {
 	return s.Shutdown(ctx)
 }
 
-// Shutdown gracefully shuts down the server
+// Shutdown gracefully shuts down the server. In-flight REST requests and gRPC calls are
+// drained before the connections they depend on are closed. If they do not finish before
+// the deadline the servers are stopped forcibly and an error is returned.
 func (s *Server) Shutdown(ctx context.Context) error {
 	// Create a deadline for server shutdown
-	ctx, cancel := context.WithTimeout(ctx, DefaultShutdownTimeout)
+	timeout := s.shutdownTimeout
+	if timeout <= 0 {
+		timeout = DefaultShutdownTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
 	defer cancel()
 
+	var shutdownErr error
+
+	// Stop accepting REST requests and let active ones finish. REST requests are proxied
+	// to the gRPC server, so this has to happen before it stops.
+	if s.restServer != nil {
+		if s.inFlight != nil {
+			s.logger.Info(ctx, "Draining REST requests", map[string]any{
+				"in_flight": s.inFlight.Active(),
+			})
+		}
+
+		err := s.restServer.Shutdown(ctx)
+		if err == nil && s.inFlight != nil {
+			err = s.inFlight.Wait(ctx)
+		}
+		if err != nil {
+			s.logger.Warn(ctx, "REST server shutdown timed out, forcing close", map[string]any{
+				"error": err.Error(),
+			})
+			s.restServer.Close()
+			shutdownErr = fmt.Errorf("REST server did not drain before the shutdown deadline: %w", err)
+		}
+	}
+
+	// Gracefully stop the gRPC server
+	if s.grpcServer != nil {
+		done := make(chan struct{})
+		go func() {
+			s.grpcServer.GracefulStop()
+			close(done)
+		}()
+
+		// Wait for either graceful stop or timeout
+		select {
+		case <-done:
+			s.logger.Info(ctx, "gRPC server stopped gracefully")
+		case <-ctx.Done():
+			s.logger.Warn(ctx, "gRPC server shutdown timed out, forcing stop")
+			s.grpcServer.Stop()
+			if shutdownErr == nil {
+				shutdownErr = fmt.Errorf("gRPC server did not drain before the shutdown deadline: %w", ctx.Err())
+			}
+		}
+	}
+
+	// Stop the archiver before the database it writes to is closed
+	if s.archiver != nil {
+		s.archiver.Stop()
+	}
+
+	// Give webhook deliveries of the last replies the rest of the deadline
+	if s.webhooks != nil {
+		if err := s.webhooks.Wait(ctx); err != nil {
+			s.logger.Warn(ctx, "Webhook deliveries did not finish before the shutdown deadline", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	// Close auth interceptor
 	if s.authInterceptor != nil {
 		if err := s.authInterceptor.Close(); err != nil {
@@ -753,6 +556,24 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
+	// Close auth service client
+	if s.authClient != nil {
+		if err := s.authClient.Close(); err != nil {
+			s.logger.Warn(ctx, "Failed to close auth service client", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
+
+	// Close REST gateway connection
+	if s.gatewayConn != nil {
+		if err := s.gatewayConn.Close(); err != nil {
+			s.logger.Warn(ctx, "Failed to close REST gateway connection", map[string]any{
+				"error": err.Error(),
+			})
+		}
+	}
+
 	// Close database connection
 	if s.db != nil {
 		if err := s.db.Close(ctx); err != nil {
@@ -762,25 +583,19 @@ func (s *Server) Shutdown(ctx context.Context) error {
 		}
 	}
 
-	// Gracefully stop the gRPC server
-	done := make(chan struct{})
-	go func() {
-		s.grpcServer.GracefulStop()
-		close(done)
-	}()
-
-	// Wait for either graceful stop or timeout
-	select {
-	case <-done:
-		s.logger.Info(ctx, "Server stopped gracefully")
-	case <-ctx.Done():
-		s.logger.Warn(ctx, "Server shutdown timed out, forcing stop")
-		s.grpcServer.Stop()
+	// Flush spans still waiting to be exported
+	if s.shutdownTracing != nil {
+		if err := s.shutdownTracing(ctx); err != nil {
+			s.logger.Warn(ctx, "Failed to flush traces", map[string]any{
+				"error": err.Error(),
+			})
+		}
 	}
 
-	// Close listeners
+	// Close listeners. The servers close the ones they were serving, so only report
+	// listeners that were never served.
 	if s.grpcLis != nil {
-		if err := s.grpcLis.Close(); err != nil {
+		if err := s.grpcLis.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			s.logger.Warn(ctx, "Failed to close gRPC listener", map[string]any{
 				"error": err.Error(),
 			})
@@ -788,13 +603,18 @@ func (s *Server) Shutdown(ctx context.Context) error {
 	}
 
 	if s.restLis != nil {
-		if err := s.restLis.Close(); err != nil {
+		if err := s.restLis.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
 			s.logger.Warn(ctx, "Failed to close REST listener", map[string]any{
 				"error": err.Error(),
 			})
 		}
 	}
 
+	if shutdownErr != nil {
+		s.logger.Error(ctx, shutdownErr, "Server shutdown did not complete gracefully", 500)
+		return shutdownErr
+	}
+
 	s.logger.Info(ctx, "Server shutdown completed")
 	return nil
 }
@@ -0,0 +1,32 @@
+package server
+
+import (
+	"io"
+	"testing"
+
+	"chat-service/configs"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewLLMProvider(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	for _, name := range []string{configs.OPENAI_PROVIDER, configs.ANTHROPIC_PROVIDER} {
+		t.Run(name, func(t *testing.T) {
+			provider, err := newLLMProvider(&configs.Config{LLMProvider: name}, logger)
+
+			require.NoError(t, err)
+			assert.Equal(t, name, provider.Name())
+		})
+	}
+
+	t.Run("unknown", func(t *testing.T) {
+		provider, err := newLLMProvider(&configs.Config{LLMProvider: "llama"}, logger)
+
+		assert.Error(t, err)
+		assert.Nil(t, provider)
+	})
+}
@@ -0,0 +1,40 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"chat-service/internal/services/llm"
+	zlog "packages/logger"
+)
+
+// startupCheckTimeout bounds the OpenAI credentials check made at startup
+const startupCheckTimeout = 10 * time.Second
+
+// checkOpenAICredentials makes one authenticated OpenAI call so an invalid OPENAI_API_KEY
+// stops the server at startup rather than failing the first user request. Only a rejected
+// key is fatal; other failures, such as OpenAI being unreachable, are logged and startup
+// continues.
+func checkOpenAICredentials(ctx context.Context, pinger llm.Pinger, logger *zlog.Logger) error {
+	ctx, cancel := context.WithTimeout(ctx, startupCheckTimeout)
+	defer cancel()
+
+	err := pinger.Ping(ctx)
+	if err == nil {
+		logger.Info(ctx, "OpenAI credentials verified")
+		return nil
+	}
+
+	var apiErr *llm.APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusUnauthorized {
+		return fmt.Errorf("OpenAI rejected OPENAI_API_KEY (status 401): check the key, or unset STARTUP_OPENAI_CHECK to start without verifying it")
+	}
+
+	logger.Warn(ctx, "Could not verify OpenAI credentials at startup", map[string]any{
+		"error": err.Error(),
+	})
+	return nil
+}
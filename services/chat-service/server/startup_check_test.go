@@ -0,0 +1,97 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"chat-service/configs"
+	"chat-service/internal/services/llm"
+	"chat-service/internal/services/openai"
+	zlog "packages/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newModelsServer mocks the OpenAI models endpoint, answering with status and recording
+// the Authorization header it was sent
+func newModelsServer(t *testing.T, status int, authorization *string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/models", r.URL.Path)
+		*authorization = r.Header.Get("Authorization")
+		w.WriteHeader(status)
+		w.Write([]byte(`{"object":"list","data":[]}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// pingerFunc adapts a function to llm.Pinger
+type pingerFunc func(ctx context.Context) error
+
+func (f pingerFunc) Ping(ctx context.Context) error {
+	return f(ctx)
+}
+
+func TestNewServer_FailsWhenOpenAIRejectsKey(t *testing.T) {
+	var authorization string
+	srv := newModelsServer(t, http.StatusUnauthorized, &authorization)
+
+	t.Setenv("LLM_PROVIDER", configs.OPENAI_PROVIDER)
+	t.Setenv("OPENAI_API_KEY", "sk-revoked")
+	t.Setenv("OPENAI_BASE_URL", srv.URL)
+	t.Setenv("STARTUP_OPENAI_CHECK", "true")
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+
+	server, err := NewServer(context.Background())
+
+	require.Error(t, err)
+	assert.Nil(t, server)
+	assert.Contains(t, err.Error(), "OpenAI rejected OPENAI_API_KEY (status 401)")
+	assert.Equal(t, "Bearer sk-revoked", authorization)
+}
+
+func TestCheckOpenAICredentials_PassesWithValidKey(t *testing.T) {
+	var authorization string
+	srv := newModelsServer(t, http.StatusOK, &authorization)
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	client := openai.NewClient(&configs.Config{OpenAIAPIKey: "sk-valid", OpenAIBaseURL: srv.URL, OpenAITimeout: 5}, logger)
+
+	err := checkOpenAICredentials(context.Background(), client.(llm.Pinger), logger)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer sk-valid", authorization)
+}
+
+func TestCheckOpenAICredentials_OnlyRejectedKeyIsFatal(t *testing.T) {
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+
+	tests := []struct {
+		name    string
+		pingErr error
+		wantErr bool
+	}{
+		{name: "unauthorized", pingErr: &llm.APIError{Provider: configs.OPENAI_PROVIDER, StatusCode: http.StatusUnauthorized}, wantErr: true},
+		{name: "server error", pingErr: &llm.APIError{Provider: configs.OPENAI_PROVIDER, StatusCode: http.StatusServiceUnavailable}},
+		{name: "unreachable", pingErr: errors.New("failed to send request: connection refused")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pinger := pingerFunc(func(ctx context.Context) error { return tt.pingErr })
+
+			err := checkOpenAICredentials(context.Background(), pinger, logger)
+
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package server
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	authproto "api/auth/v1/proto"
+	"chat-service/configs"
+)
+
+// defaultTokenCacheSize bounds the number of validated tokens kept in memory
+const defaultTokenCacheSize = 10000
+
+// validateTokenFunc asks the auth service whether a token is valid
+type validateTokenFunc func(ctx context.Context, token string) (*authproto.ValidateTokenResponse, error)
+
+// tokenValidator resolves REST bearer tokens to user IDs, memoizing the auth service's
+// answers so repeated requests with the same token skip the round-trip
+type tokenValidator struct {
+	cache    *tokenCache
+	validate validateTokenFunc
+}
+
+// newTokenValidator creates a validator that checks tokens against the auth service
+func newTokenValidator(cfg *configs.Config, authClient *AuthClient) *tokenValidator {
+	return &tokenValidator{
+		cache:    newTokenCache(defaultTokenCacheSize, time.Duration(cfg.TokenCacheTTL)*time.Second),
+		validate: authClient.ValidateToken,
+	}
+}
+
+// UserID returns the user the token belongs to. Both valid and rejected tokens are cached;
+// auth service failures are not, so the next request retries.
+func (v *tokenValidator) UserID(ctx context.Context, token string) (string, error) {
+	if entry, ok := v.cache.get(token); ok {
+		return entry.userID, entry.err
+	}
+
+	resp, err := v.validate(ctx, token)
+	if err != nil {
+		return "", fmt.Errorf("auth service error: %w", err)
+	}
+
+	if !resp.Valid {
+		err := fmt.Errorf("token validation failed: %s", resp.ErrorMessage)
+		v.cache.put(token, "", err)
+		return "", err
+	}
+
+	v.cache.put(token, resp.UserId, nil)
+	return resp.UserId, nil
+}
+
+// tokenCache is an LRU cache of token validation results keyed by token hash
+type tokenCache struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	capacity int
+	entries  map[string]*list.Element
+	order    *list.List // front is the most recently used entry
+	now      func() time.Time
+}
+
+type tokenCacheEntry struct {
+	key       string
+	userID    string
+	err       error // set for tokens the auth service rejected
+	expiresAt time.Time
+}
+
+// newTokenCache creates a cache holding up to capacity results for ttl each. A
+// non-positive ttl disables caching.
+func newTokenCache(capacity int, ttl time.Duration) *tokenCache {
+	return &tokenCache{
+		ttl:      ttl,
+		capacity: capacity,
+		entries:  make(map[string]*list.Element),
+		order:    list.New(),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached result for token; ok is false when there is no live entry
+func (c *tokenCache) get(token string) (*tokenCacheEntry, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+
+	key := hashToken(token)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, found := c.entries[key]
+	if !found {
+		return nil, false
+	}
+
+	entry := elem.Value.(*tokenCacheEntry)
+	if !c.now().Before(entry.expiresAt) {
+		c.order.Remove(elem)
+		delete(c.entries, key)
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	return entry, true
+}
+
+// put records a validation result. Successful results never outlive the token's own expiry.
+func (c *tokenCache) put(token, userID string, err error) {
+	if c.ttl <= 0 {
+		return
+	}
+
+	now := c.now()
+	expiresAt := now.Add(c.ttl)
+	if err == nil {
+		if exp, ok := tokenExpiry(token); ok && exp.Before(expiresAt) {
+			expiresAt = exp
+		}
+		if !now.Before(expiresAt) {
+			return
+		}
+	}
+
+	key := hashToken(token)
+	entry := &tokenCacheEntry{key: key, userID: userID, err: err, expiresAt: expiresAt}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, found := c.entries[key]; found {
+		elem.Value = entry
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*tokenCacheEntry).key)
+	}
+}
+
+// hashToken keys the cache so raw tokens are not kept in memory
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// tokenExpiry reads the exp claim of a JWT without verifying it; verification is
+// left to the auth service
+func tokenExpiry(token string) (time.Time, bool) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, false
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	var claims struct {
+		Exp *float64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp == nil {
+		return time.Time{}, false
+	}
+
+	return time.Unix(int64(*claims.Exp), 0), true
+}
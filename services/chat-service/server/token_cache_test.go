@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	authproto "api/auth/v1/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testUserID = "11111111-1111-1111-1111-111111111111"
+
+// fakeClock is a controllable time source for the token cache
+type fakeClock struct {
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time { return c.now }
+
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// countingAuth answers ValidateToken with a canned response and counts the calls
+type countingAuth struct {
+	calls int
+	resp  *authproto.ValidateTokenResponse
+	err   error
+}
+
+func (a *countingAuth) validate(ctx context.Context, token string) (*authproto.ValidateTokenResponse, error) {
+	a.calls++
+	return a.resp, a.err
+}
+
+func newTestTokenValidator(auth *countingAuth, ttl time.Duration) (*tokenValidator, *fakeClock) {
+	clock := &fakeClock{now: time.Unix(1700000000, 0)}
+	cache := newTokenCache(defaultTokenCacheSize, ttl)
+	cache.now = clock.Now
+	return &tokenValidator{cache: cache, validate: auth.validate}, clock
+}
+
+// jwtWithExpiry builds an unsigned JWT carrying only an exp claim
+func jwtWithExpiry(exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"HS256","typ":"JWT"}`)) + "." +
+		encode([]byte(fmt.Sprintf(`{"exp":%d}`, exp.Unix()))) + ".signature"
+}
+
+func TestTokenValidator_CachesWithinTTL(t *testing.T) {
+	auth := &countingAuth{resp: &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}}
+	validator, clock := newTestTokenValidator(auth, 30*time.Second)
+
+	for i := 0; i < 3; i++ {
+		userID, err := validator.UserID(context.Background(), "token")
+		require.NoError(t, err)
+		assert.Equal(t, testUserID, userID)
+		clock.Advance(5 * time.Second)
+	}
+
+	assert.Equal(t, 1, auth.calls)
+}
+
+func TestTokenValidator_RevalidatesAfterTTL(t *testing.T) {
+	auth := &countingAuth{resp: &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}}
+	validator, clock := newTestTokenValidator(auth, 30*time.Second)
+
+	_, err := validator.UserID(context.Background(), "token")
+	require.NoError(t, err)
+
+	clock.Advance(30 * time.Second)
+	_, err = validator.UserID(context.Background(), "token")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestTokenValidator_RevalidatesWhenTokenExpires(t *testing.T) {
+	auth := &countingAuth{resp: &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}}
+	validator, clock := newTestTokenValidator(auth, 30*time.Second)
+	token := jwtWithExpiry(clock.Now().Add(10 * time.Second))
+
+	_, err := validator.UserID(context.Background(), token)
+	require.NoError(t, err)
+
+	clock.Advance(5 * time.Second)
+	_, err = validator.UserID(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, 1, auth.calls)
+
+	// Still inside the cache TTL, but the token itself has expired
+	clock.Advance(5 * time.Second)
+	auth.resp = &authproto.ValidateTokenResponse{Valid: false, ErrorMessage: "token expired"}
+	_, err = validator.UserID(context.Background(), token)
+	assert.ErrorContains(t, err, "token expired")
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestTokenValidator_CachesRejectedTokens(t *testing.T) {
+	auth := &countingAuth{resp: &authproto.ValidateTokenResponse{Valid: false, ErrorMessage: "invalid signature"}}
+	validator, _ := newTestTokenValidator(auth, 30*time.Second)
+
+	for i := 0; i < 2; i++ {
+		_, err := validator.UserID(context.Background(), "forged")
+		assert.ErrorContains(t, err, "invalid signature")
+	}
+
+	assert.Equal(t, 1, auth.calls)
+}
+
+func TestTokenValidator_DoesNotCacheAuthServiceErrors(t *testing.T) {
+	auth := &countingAuth{err: errors.New("connection refused")}
+	validator, _ := newTestTokenValidator(auth, 30*time.Second)
+
+	_, err := validator.UserID(context.Background(), "token")
+	assert.ErrorContains(t, err, "auth service error")
+
+	auth.err = nil
+	auth.resp = &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}
+	userID, err := validator.UserID(context.Background(), "token")
+	require.NoError(t, err)
+	assert.Equal(t, testUserID, userID)
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestTokenValidator_ZeroTTLDisablesCache(t *testing.T) {
+	auth := &countingAuth{resp: &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}}
+	validator, _ := newTestTokenValidator(auth, 0)
+
+	for i := 0; i < 2; i++ {
+		_, err := validator.UserID(context.Background(), "token")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 2, auth.calls)
+}
+
+func TestTokenCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := newTokenCache(2, time.Minute)
+
+	cache.put("a", "user-a", nil)
+	cache.put("b", "user-b", nil)
+	_, ok := cache.get("a")
+	require.True(t, ok)
+	cache.put("c", "user-c", nil)
+
+	_, ok = cache.get("b")
+	assert.False(t, ok, "least recently used entry should be evicted")
+	for _, token := range []string{"a", "c"} {
+		_, ok := cache.get(token)
+		assert.True(t, ok, token)
+	}
+}
@@ -0,0 +1,29 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"packages/tracing"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+)
+
+// withTracing starts a server span for every REST request, continuing the caller's trace
+// when a traceparent header is sent, and tags it with the request's correlation ID. It must
+// run inside withCorrelationID. Health probes are not traced.
+func withTracing(next http.Handler) http.Handler {
+	tagged := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tracing.SetCorrelationID(r.Context())
+		next.ServeHTTP(w, r)
+	})
+
+	return otelhttp.NewHandler(tagged, "rest",
+		otelhttp.WithFilter(func(r *http.Request) bool {
+			return !strings.HasPrefix(r.URL.Path, "/v1/health") && r.URL.Path != "/health"
+		}),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return r.Method
+		}),
+	)
+}
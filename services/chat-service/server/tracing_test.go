@@ -0,0 +1,125 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"chat-service/configs"
+	chatproto "chat-service/proto"
+	zlog "packages/logger"
+	"packages/tracing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// recordSpans installs a global tracer provider recording the spans ended during the test,
+// restoring the previous provider and propagator once the test ends
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	provider, propagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	t.Cleanup(func() {
+		otel.SetTracerProvider(provider)
+		otel.SetTextMapPropagator(propagator)
+	})
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	return recorder
+}
+
+// findSpan returns the recorded span with the given kind and name, or nil
+func findSpan(spans []sdktrace.ReadOnlySpan, kind trace.SpanKind, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.SpanKind() == kind && span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestTracing_RESTRequestSpansGRPCHop(t *testing.T) {
+	recorder := recordSpans(t)
+	_, err := tracing.Setup(context.Background(), "", "chat-service")
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	grpcServer := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+	chatproto.RegisterChatServiceServer(grpcServer, &stubChatServer{})
+	go grpcServer.Serve(lis)
+	t.Cleanup(grpcServer.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	handler, err := newRESTHandler(context.Background(), &configs.Config{}, logger, conn, nil, nil, nil, nil)
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(withCorrelationID(withTracing(handler)))
+	t.Cleanup(srv.Close)
+
+	// The caller's trace is continued rather than a new one started
+	const callerTraceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req, err := http.NewRequest(http.MethodGet, srv.URL+"/v1/chat/conversations", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer test-token")
+	req.Header.Set(correlationIDHeader, "trace-test-1")
+	req.Header.Set("traceparent", "00-"+callerTraceID+"-00f067aa0ba902b7-01")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	// The gRPC server span may end just after the response reaches the client
+	const method = "chat.ChatService/ListConversations"
+	require.Eventually(t, func() bool {
+		return findSpan(recorder.Ended(), trace.SpanKindServer, method) != nil
+	}, time.Second, 10*time.Millisecond)
+
+	spans := recorder.Ended()
+	restSpan := findSpan(spans, trace.SpanKindServer, http.MethodGet)
+	clientSpan := findSpan(spans, trace.SpanKindClient, method)
+	serverSpan := findSpan(spans, trace.SpanKindServer, method)
+	require.NotNil(t, restSpan)
+	require.NotNil(t, clientSpan)
+
+	assert.Equal(t, callerTraceID, restSpan.SpanContext().TraceID().String())
+	assert.Equal(t, restSpan.SpanContext().SpanID(), clientSpan.Parent().SpanID())
+	assert.Equal(t, clientSpan.SpanContext().SpanID(), serverSpan.Parent().SpanID())
+	assert.Equal(t, restSpan.SpanContext().TraceID(), serverSpan.SpanContext().TraceID())
+	assert.Contains(t, restSpan.Attributes(), tracing.CorrelationIDKey.String("trace-test-1"))
+}
+
+func TestWithTracing_SkipsHealthProbes(t *testing.T) {
+	recorder := recordSpans(t)
+
+	handler := withTracing(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/health/ready", nil))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/v1/chat/conversations", nil))
+
+	require.Len(t, recorder.Ended(), 1)
+	assert.Equal(t, http.MethodGet, recorder.Ended()[0].Name())
+}
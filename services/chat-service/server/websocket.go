@@ -0,0 +1,234 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"chat-service/configs"
+	"chat-service/internal/domain"
+	"chat-service/internal/services/chat"
+	grpchandler "chat-service/internal/transport/grpc"
+	zlog "packages/logger"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// wsBearerProtocol is the subprotocol browsers offer to pass the access token, since they
+	// cannot set headers on the upgrade request: Sec-WebSocket-Protocol: bearer, <token>
+	wsBearerProtocol = "bearer"
+
+	// wsWriteWait bounds how long a single frame write may block
+	wsWriteWait = 10 * time.Second
+
+	// wsPongWait is how long the connection may stay silent before it is considered dead
+	wsPongWait = 60 * time.Second
+
+	// wsPingPeriod must be shorter than wsPongWait so a healthy peer always answers in time
+	wsPingPeriod = wsPongWait * 9 / 10
+)
+
+var wsUpgrader = websocket.Upgrader{
+	Subprotocols: []string{wsBearerProtocol},
+	// Clients authenticate with a bearer token rather than cookies, so cross-origin
+	// upgrades carry no ambient credentials worth protecting
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// wsErrorFrame reports a failed chat turn; the socket stays open for the next one
+type wsErrorFrame struct {
+	Type string `json:"type"`
+	*domain.ErrorResponse
+}
+
+// handleChatWebSocket handles GET /v1/chat/ws. Each JSON frame the client sends is answered
+// with "delta" frames carrying the streamed AI response, followed by a "done" or "error"
+// frame. Turns are processed one at a time. The socket is closed when the client goes away,
+// when keepalive pings go unanswered, or when shutdown is cancelled.
+func handleChatWebSocket(w http.ResponseWriter, r *http.Request, shutdown context.Context, chatService chat.Service, logger *zlog.Logger, tokens *tokenValidator, streams *grpchandler.StreamLimiter) {
+	upgrader := wsUpgrader
+	upgrader.Error = func(w http.ResponseWriter, r *http.Request, status int, reason error) {
+		writeJSONError(w, r, logger, "INVALID_REQUEST", reason.Error(), status)
+	}
+
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader has already written the error response
+		logger.Error(r.Context(), err, "WebSocket upgrade failed", 400)
+		return
+	}
+	defer conn.Close()
+
+	userID, err := extractUserIDFromWebSocket(r, tokens)
+	if err != nil {
+		logger.Error(r.Context(), err, "Failed to authenticate WebSocket", 401)
+		closeWebSocket(conn, websocket.ClosePolicyViolation, "unauthorized")
+		return
+	}
+
+	// Each open socket holds one of the user's stream slots until it closes
+	release, ok := streams.Acquire(userID)
+	if !ok {
+		logger.Warn(r.Context(), "Concurrent stream limit reached", map[string]any{
+			"user_id": userID,
+		})
+		closeWebSocket(conn, websocket.CloseTryAgainLater, "too many concurrent streams")
+		return
+	}
+	defer release()
+
+	ctx, cancel := context.WithCancel(r.Context())
+	defer cancel()
+
+	stopShutdown := context.AfterFunc(shutdown, func() {
+		closeWebSocket(conn, websocket.CloseGoingAway, "server shutting down")
+		cancel()
+	})
+	defer stopShutdown()
+
+	conn.SetReadLimit(configs.WebSocketMaxFrameBytes)
+	conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wsPongWait))
+	})
+
+	// Frames are read on their own goroutine so pongs and close frames are handled while
+	// a response is streaming. A read error means the peer is gone and cancels the turn.
+	requests := make(chan []byte)
+	go func() {
+		defer cancel()
+		for {
+			_, payload, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			select {
+			case requests <- payload:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	// Control frames may be written concurrently with data frames, so pings need no locking
+	go func() {
+		ticker := time.NewTicker(wsPingPeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(wsWriteWait)); err != nil {
+					cancel()
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case payload := <-requests:
+			if err := handleWebSocketTurn(ctx, conn, chatService, logger, userID, payload); err != nil {
+				logger.Error(ctx, err, "Failed to write WebSocket frame", 500)
+				return
+			}
+		}
+	}
+}
+
+// handleWebSocketTurn answers one client frame. Only write failures are returned; request
+// and chat errors are reported to the client as error frames.
+func handleWebSocketTurn(ctx context.Context, conn *websocket.Conn, chatService chat.Service, logger *zlog.Logger, userID string, payload []byte) error {
+	// Each frame is a single chat turn
+	var req domain.ChatWithAIRequest
+	if err := json.Unmarshal(payload, &req); err != nil {
+		return writeWebSocketError(ctx, conn, "VALIDATION_ERROR", "Invalid request frame", http.StatusBadRequest)
+	}
+	req.UserID = userID
+
+	// Validate the request and set defaults for unset options; an empty model selects the
+	// active provider's default
+	if err := req.Validate(); err != nil {
+		return writeWebSocketError(ctx, conn, "VALIDATION_ERROR", fmt.Sprintf("Validation error: %v", err), http.StatusBadRequest)
+	}
+
+	response, err := chatService.ChatWithAIStream(ctx, req.UserID, req.Message, req.ConversationID, req.Model, *req.Temperature, *req.MaxTokens, req.Metadata, func(_, delta string) error {
+		return writeWebSocketFrame(conn, map[string]any{"type": "delta", "delta": delta})
+	})
+	if err != nil {
+		if ctx.Err() != nil {
+			// The socket is going away; there is nobody left to tell
+			return nil
+		}
+		response, _, status := serviceErrorResponse(ctx, err)
+		logRequestError(ctx, logger, err, "Failed to stream chat with AI", status)
+		return writeWebSocketErrorResponse(conn, response)
+	}
+
+	return writeWebSocketFrame(conn, map[string]any{
+		"type":            "done",
+		"ai_message":      response.Message.Content,
+		"message_id":      response.Message.ID,
+		"conversation_id": response.ConversationID,
+		"model_used":      response.Model,
+		"created_at":      response.Message.CreatedAt,
+	})
+}
+
+// extractUserIDFromWebSocket authenticates the upgrade request from the Authorization header,
+// the authorization query parameter, or the bearer subprotocol, in that order
+func extractUserIDFromWebSocket(r *http.Request, tokens *tokenValidator) (string, error) {
+	if r.Header.Get("Authorization") != "" {
+		return extractUserIDFromToken(r, tokens)
+	}
+
+	if auth := r.URL.Query().Get("authorization"); auth != "" {
+		token, ok := strings.CutPrefix(auth, "Bearer ")
+		if !ok {
+			return "", fmt.Errorf("invalid authorization parameter format")
+		}
+		return tokens.UserID(r.Context(), token)
+	}
+
+	protocols := websocket.Subprotocols(r)
+	for i, protocol := range protocols {
+		if protocol == wsBearerProtocol && i+1 < len(protocols) {
+			return tokens.UserID(r.Context(), protocols[i+1])
+		}
+	}
+
+	return "", fmt.Errorf("no authorization found")
+}
+
+// writeWebSocketFrame writes data as a single JSON text frame
+func writeWebSocketFrame(conn *websocket.Conn, data any) error {
+	conn.SetWriteDeadline(time.Now().Add(wsWriteWait))
+	return conn.WriteJSON(data)
+}
+
+// writeWebSocketError writes an error frame in the same shape as REST error responses
+func writeWebSocketError(ctx context.Context, conn *websocket.Conn, errorType, message string, status int) error {
+	return writeWebSocketErrorResponse(conn, newErrorResponse(ctx, errorType, message, status))
+}
+
+// writeWebSocketErrorResponse writes an error envelope, such as one built by
+// serviceErrorResponse, as an error frame
+func writeWebSocketErrorResponse(conn *websocket.Conn, response *domain.ErrorResponse) error {
+	return writeWebSocketFrame(conn, wsErrorFrame{
+		Type:          "error",
+		ErrorResponse: response,
+	})
+}
+
+// closeWebSocket sends a close frame; the caller still closes the underlying connection
+func closeWebSocket(conn *websocket.Conn, code int, reason string) {
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), time.Now().Add(wsWriteWait))
+}
@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	authproto "api/auth/v1/proto"
+	"chat-service/internal/domain"
+	"chat-service/internal/services/chat"
+	grpchandler "chat-service/internal/transport/grpc"
+	zlog "packages/logger"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// streamingChatService streams a fixed set of deltas from ChatWithAIStream, then fails with
+// err when it is set; other methods are left unimplemented
+type streamingChatService struct {
+	chat.Service
+	deltas      []string
+	message     string
+	temperature float64
+	maxTokens   int
+	err         error
+}
+
+func (s *streamingChatService) ChatWithAIStream(ctx context.Context, userID, message, conversationID, model string, temperature float64, maxTokens int, metadata domain.Metadata, onDelta func(conversationID, delta string) error) (*domain.ChatResponse, error) {
+	s.message = message
+	s.temperature = temperature
+	s.maxTokens = maxTokens
+	for _, delta := range s.deltas {
+		if err := onDelta("conv-1", delta); err != nil {
+			return nil, err
+		}
+	}
+	if s.err != nil {
+		return nil, s.err
+	}
+	return &domain.ChatResponse{
+		Message:        &domain.Message{ID: "msg-1", Content: strings.Join(s.deltas, ""), CreatedAt: time.Now()},
+		ConversationID: "conv-1",
+		Model:          "gpt-4",
+	}, nil
+}
+
+// newGoodTokenValidator returns a validator that accepts only "good-token", as testUserID
+func newGoodTokenValidator() *tokenValidator {
+	validator, _ := newTestTokenValidator(&countingAuth{}, time.Minute)
+	validator.validate = func(ctx context.Context, token string) (*authproto.ValidateTokenResponse, error) {
+		if token != "good-token" {
+			return &authproto.ValidateTokenResponse{Valid: false, ErrorMessage: "bad token"}, nil
+		}
+		return &authproto.ValidateTokenResponse{Valid: true, UserId: testUserID}, nil
+	}
+	return validator
+}
+
+// newTestWebSocketServer serves handleChatWebSocket, accepting only "good-token"
+func newTestWebSocketServer(t *testing.T, shutdown context.Context, chatService chat.Service) string {
+	t.Helper()
+	return newTestWebSocketServerWithLimiter(t, shutdown, chatService, nil)
+}
+
+// newTestWebSocketServerWithLimiter is newTestWebSocketServer with a per-user stream limit
+func newTestWebSocketServerWithLimiter(t *testing.T, shutdown context.Context, chatService chat.Service, streams *grpchandler.StreamLimiter) string {
+	t.Helper()
+
+	validator := newGoodTokenValidator()
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		handleChatWebSocket(w, r, shutdown, chatService, logger, validator, streams)
+	}))
+	t.Cleanup(srv.Close)
+
+	return "ws" + strings.TrimPrefix(srv.URL, "http") + "/v1/chat/ws"
+}
+
+func dialTestWebSocket(t *testing.T, wsURL string, protocols ...string) *websocket.Conn {
+	t.Helper()
+
+	dialer := websocket.Dialer{Subprotocols: protocols, HandshakeTimeout: 5 * time.Second}
+	conn, _, err := dialer.Dial(wsURL, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	return conn
+}
+
+func TestChatWebSocket_StreamsDeltas(t *testing.T) {
+	svc := &streamingChatService{deltas: []string{"Hel", "lo"}}
+	wsURL := newTestWebSocketServer(t, context.Background(), svc)
+
+	conn := dialTestWebSocket(t, wsURL, wsBearerProtocol, "good-token")
+	assert.Equal(t, wsBearerProtocol, conn.Subprotocol())
+
+	require.NoError(t, conn.WriteJSON(map[string]any{"message": "Hi"}))
+
+	var frames []map[string]any
+	for {
+		var frame map[string]any
+		require.NoError(t, conn.ReadJSON(&frame))
+		frames = append(frames, frame)
+		if frame["type"] != "delta" {
+			break
+		}
+	}
+
+	require.Len(t, frames, 3)
+	assert.Equal(t, "Hel", frames[0]["delta"])
+	assert.Equal(t, "lo", frames[1]["delta"])
+	assert.Equal(t, "done", frames[2]["type"])
+	assert.Equal(t, "Hello", frames[2]["ai_message"])
+	assert.Equal(t, "conv-1", frames[2]["conversation_id"])
+	assert.Equal(t, "Hi", svc.message)
+}
+
+func TestChatWebSocket_InvalidFrameKeepsSocketOpen(t *testing.T) {
+	svc := &streamingChatService{deltas: []string{"ok"}}
+	wsURL := newTestWebSocketServer(t, context.Background(), svc)
+
+	conn := dialTestWebSocket(t, wsURL+"?authorization="+url.QueryEscape("Bearer good-token"))
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+	var frame map[string]any
+	require.NoError(t, conn.ReadJSON(&frame))
+	assert.Equal(t, "error", frame["type"])
+	assert.Equal(t, "VALIDATION_ERROR", frame["error"])
+	assert.Equal(t, "400", frame["code"])
+
+	require.NoError(t, conn.WriteJSON(map[string]any{"message": "Hi"}))
+	require.NoError(t, conn.ReadJSON(&frame))
+	assert.Equal(t, "delta", frame["type"])
+}
+
+func TestChatWebSocket_GenerationOptions(t *testing.T) {
+	svc := &streamingChatService{deltas: []string{"ok"}}
+	wsURL := newTestWebSocketServer(t, context.Background(), svc)
+	conn := dialTestWebSocket(t, wsURL, wsBearerProtocol, "good-token")
+
+	readUntilDone := func() map[string]any {
+		var frame map[string]any
+		for {
+			require.NoError(t, conn.ReadJSON(&frame))
+			if frame["type"] != "delta" {
+				return frame
+			}
+		}
+	}
+
+	require.NoError(t, conn.WriteJSON(map[string]any{"message": "Hi"}))
+	assert.Equal(t, "done", readUntilDone()["type"])
+	assert.Equal(t, domain.DefaultAITemperature, svc.temperature)
+	assert.Equal(t, domain.DefaultAIMaxTokens, svc.maxTokens)
+
+	require.NoError(t, conn.WriteJSON(map[string]any{"message": "Hi", "temperature": 0, "max_tokens": 50}))
+	assert.Equal(t, "done", readUntilDone()["type"])
+	assert.Equal(t, 0.0, svc.temperature, "an explicit zero temperature should be honored")
+	assert.Equal(t, 50, svc.maxTokens)
+
+	svc.err = chat.NewValidationError("INVALID_TEMPERATURE", "temperature must be between 0 and 2 for model gpt-4", nil)
+	require.NoError(t, conn.WriteJSON(map[string]any{"message": "Hi", "temperature": 9}))
+	frame := readUntilDone()
+	assert.Equal(t, "error", frame["type"])
+	assert.Equal(t, "VALIDATION_ERROR", frame["error"])
+	assert.Equal(t, "validation error: temperature must be between 0 and 2 for model gpt-4", frame["message"])
+}
+
+func TestChatWebSocket_ClosesOnAuthFailure(t *testing.T) {
+	wsURL := newTestWebSocketServer(t, context.Background(), &streamingChatService{})
+
+	conn := dialTestWebSocket(t, wsURL+"?authorization="+url.QueryEscape("Bearer bad-token"))
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.True(t, errors.As(err, &closeErr), "expected close error, got %v", err)
+	assert.Equal(t, websocket.ClosePolicyViolation, closeErr.Code)
+}
+
+func TestChatWebSocket_ClosesOnShutdown(t *testing.T) {
+	shutdown, cancel := context.WithCancel(context.Background())
+	wsURL := newTestWebSocketServer(t, shutdown, &streamingChatService{})
+
+	conn := dialTestWebSocket(t, wsURL, wsBearerProtocol, "good-token")
+	cancel()
+
+	_, _, err := conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.True(t, errors.As(err, &closeErr), "expected close error, got %v", err)
+	assert.Equal(t, websocket.CloseGoingAway, closeErr.Code)
+}
+
+func TestChatWebSocket_RejectsSocketsOverStreamLimit(t *testing.T) {
+	streams := grpchandler.NewStreamLimiter(1)
+	wsURL := newTestWebSocketServerWithLimiter(t, context.Background(), &streamingChatService{deltas: []string{"ok"}}, streams)
+
+	first := dialTestWebSocket(t, wsURL, wsBearerProtocol, "good-token")
+	// A completed turn proves the first socket is authenticated and holds its slot
+	require.NoError(t, first.WriteJSON(map[string]any{"message": "Hi"}))
+	var frame map[string]any
+	require.NoError(t, first.ReadJSON(&frame))
+
+	second := dialTestWebSocket(t, wsURL, wsBearerProtocol, "good-token")
+	_, _, err := second.ReadMessage()
+	var closeErr *websocket.CloseError
+	require.True(t, errors.As(err, &closeErr), "expected close error, got %v", err)
+	assert.Equal(t, websocket.CloseTryAgainLater, closeErr.Code)
+	assert.Equal(t, "too many concurrent streams", closeErr.Text)
+
+	first.Close()
+	require.Eventually(t, func() bool { return streams.Active(testUserID) == 0 }, time.Second, 10*time.Millisecond,
+		"closing the socket should release its slot")
+}
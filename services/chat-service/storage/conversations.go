@@ -4,11 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
 	"net/http"
+	"time"
 
 	"chat-service/internal/domain"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
 // Named queries
@@ -27,18 +30,69 @@ const (
 			:created_at,
 			:updated_at
 		)
-		RETURNING id, user_id, title, created_at, updated_at
+		RETURNING id, user_id, title, created_at, updated_at, archived, pinned
 	`
 
-	getConversationByIDQuery = `
-		SELECT 
+	// Holds back the conversation inserts of the same user until the transaction ends, so the
+	// quota count of insertConversationWithinQuotaQuery cannot be raced by a concurrent insert
+	lockUserConversationsQuery = `
+		SELECT pg_advisory_xact_lock(hashtext('conversations/' || CAST(:user_id AS TEXT)))
+	`
+
+	// Inserts nothing once the user has max_conversations conversations; archived
+	// conversations count, deleted ones do not
+	insertConversationWithinQuotaQuery = `
+		INSERT INTO conversations (
 			id,
 			user_id,
 			title,
 			created_at,
 			updated_at
+		)
+		SELECT
+			CAST(:id AS UUID),
+			CAST(:user_id AS UUID),
+			CAST(:title AS VARCHAR),
+			CAST(:created_at AS TIMESTAMPTZ),
+			CAST(:updated_at AS TIMESTAMPTZ)
+		WHERE (
+			SELECT COUNT(*) FROM conversations
+			WHERE user_id = CAST(:user_id AS UUID) AND deleted_at IS NULL
+		) < :max_conversations
+		RETURNING id, user_id, title, created_at, updated_at, archived, pinned
+	`
+
+	// conversationActivityJoin aggregates the live messages of each selected conversation,
+	// served by idx_messages_conversation_id_created_at_active
+	conversationActivityJoin = `
+		LEFT JOIN LATERAL (
+			SELECT
+				COUNT(*) AS message_count,
+				MAX(m.created_at) AS last_message_at
+			FROM messages m
+			WHERE m.conversation_id = conversations.id AND m.deleted_at IS NULL
+		) activity ON TRUE
+	`
+
+	getConversationByIDQuery = `
+		SELECT id, user_id, title, created_at, updated_at, archived, pinned
 		FROM conversations
-		WHERE id = :id
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
+	`
+
+	getConversationWithActivityQuery = `
+		SELECT 
+			id,
+			user_id,
+			title,
+			created_at,
+			updated_at,
+			archived,
+			pinned,
+			activity.message_count,
+			activity.last_message_at
+		FROM conversations` + conversationActivityJoin + `
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
 	`
 
 	getConversationsByUserIDQuery = `
@@ -47,35 +101,131 @@ const (
 			user_id,
 			title,
 			created_at,
-			updated_at
-		FROM conversations
-		WHERE user_id = :user_id
-		ORDER BY updated_at DESC
+			updated_at,
+			archived,
+			pinned,
+			activity.message_count,
+			activity.last_message_at
+		FROM conversations` + conversationActivityJoin + `
+		WHERE user_id = :user_id AND deleted_at IS NULL
+			AND (:include_archived OR NOT archived)
+		ORDER BY pinned DESC, updated_at DESC
 		LIMIT :limit OFFSET :offset
 	`
 
+	getConversationsAfterCursorQuery = `
+		SELECT 
+			id,
+			user_id,
+			title,
+			created_at,
+			updated_at,
+			archived,
+			pinned,
+			activity.message_count,
+			activity.last_message_at
+		FROM conversations` + conversationActivityJoin + `
+		WHERE user_id = :user_id AND deleted_at IS NULL
+			AND (:include_archived OR NOT archived)
+			AND (created_at, id) > (:cursor_created_at, :cursor_id)
+		ORDER BY created_at ASC, id ASC
+		LIMIT :limit
+	`
+
 	countConversationsByUserIDQuery = `
-		SELECT COUNT(*) FROM conversations WHERE user_id = :user_id
+		SELECT COUNT(*) FROM conversations
+		WHERE user_id = :user_id AND deleted_at IS NULL
+			AND (:include_archived OR NOT archived)
 	`
 
 	updateConversationTitleQuery = `
 		UPDATE conversations 
 		SET title = :title, updated_at = :updated_at
-		WHERE id = :id AND user_id = :user_id
-		RETURNING id, user_id, title, created_at, updated_at
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
+		RETURNING id, user_id, title, created_at, updated_at, archived, pinned
 	`
 
 	deleteConversationQuery = `
-		DELETE FROM conversations 
-		WHERE id = :id AND user_id = :user_id
+		UPDATE conversations 
+		SET deleted_at = :deleted_at
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
+	`
+
+	// Messages deleted with their conversation share its deleted_at, which lets a
+	// restore bring back exactly those messages
+	deleteConversationMessagesQuery = `
+		UPDATE messages 
+		SET deleted_at = :deleted_at
+		WHERE conversation_id = :id AND deleted_at IS NULL
+	`
+
+	// touchConversationQuery bumps updated_at of a live conversation owned by the user
+	touchConversationQuery = `
+		UPDATE conversations 
+		SET updated_at = :updated_at
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
+	`
+
+	restoreConversationMessagesQuery = `
+		UPDATE messages m
+		SET deleted_at = NULL
+		FROM conversations c
+		WHERE c.id = :id AND c.user_id = :user_id
+			AND c.deleted_at > :restorable_after
+			AND m.conversation_id = c.id AND m.deleted_at = c.deleted_at
+	`
+
+	restoreConversationQuery = `
+		UPDATE conversations 
+		SET deleted_at = NULL
+		WHERE id = :id AND user_id = :user_id AND deleted_at > :restorable_after
+		RETURNING id, user_id, title, created_at, updated_at, archived, pinned
+	`
+
+	// setConversationFlagQuery is completed with the column of a ConversationFlag
+	setConversationFlagQuery = `
+		UPDATE conversations 
+		SET %s = :value
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
+		RETURNING id, user_id, title, created_at, updated_at, archived, pinned
+	`
+
+	// A conversation is idle when neither it nor any of its live messages changed since
+	// the cutoff. The message check is served by idx_messages_conversation_id_created_at_active.
+	archiveIdleConversationsQuery = `
+		UPDATE conversations 
+		SET archived = TRUE
+		WHERE deleted_at IS NULL AND NOT archived
+			AND updated_at < :cutoff
+			AND NOT EXISTS (
+				SELECT 1 FROM messages m
+				WHERE m.conversation_id = conversations.id AND m.deleted_at IS NULL
+					AND m.created_at >= :cutoff
+			)
 	`
 )
 
-// CreateConversation inserts a new conversation into the database
-func (db *DB) CreateConversation(ctx context.Context, conversation *domain.Conversation) (*domain.Conversation, error) {
+// CreateConversation inserts a new conversation into the database. If its ID is already
+// taken, the conversation is given a new one and inserted again, once. When
+// maxConversations is positive and the user already has that many conversations, nothing
+// is inserted and ErrConversationQuotaExceeded is returned.
+func (db *DB) CreateConversation(ctx context.Context, conversation *domain.Conversation, maxConversations int) (*domain.Conversation, error) {
+	regenerateID := func() { conversation.ID = uuid.New().String() }
+	return retryOnIDCollision(ctx, db, "create conversation", regenerateID, func() (*domain.Conversation, error) {
+		return retryWrite(ctx, db, "create conversation", func() (*domain.Conversation, error) {
+			return db.createConversation(ctx, conversation, maxConversations)
+		})
+	})
+}
+
+// createConversation runs one attempt of CreateConversation
+func (db *DB) createConversation(ctx context.Context, conversation *domain.Conversation, maxConversations int) (*domain.Conversation, error) {
 	if conversation.ID == "" {
 		conversation.ID = uuid.New().String()
 	}
+	if maxConversations > 0 {
+		return db.createConversationWithinQuota(ctx, conversation, maxConversations)
+	}
 
 	stmt, err := db.PrepareNamedContext(ctx, insertConversationQuery)
 	if err != nil {
@@ -85,7 +235,9 @@ func (db *DB) CreateConversation(ctx context.Context, conversation *domain.Conve
 	defer stmt.Close()
 
 	var newConversation domain.Conversation
-	if err := stmt.GetContext(ctx, &newConversation, conversation); err != nil {
+	if err := db.timeQuery(ctx, "insert conversation", func() error {
+		return stmt.GetContext(ctx, &newConversation, conversation)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "insert failed", status)
 		return nil, mappedErr
@@ -100,26 +252,165 @@ func (db *DB) CreateConversation(ctx context.Context, conversation *domain.Conve
 	return &newConversation, nil
 }
 
-// GetConversationByID retrieves a conversation by ID
-func (db *DB) GetConversationByID(ctx context.Context, id string) (*domain.Conversation, error) {
+// createConversationWithinQuota inserts a conversation in a transaction of its own, so the
+// quota is checked and the conversation inserted while the user's inserts are held back
+func (db *DB) createConversationWithinQuota(ctx context.Context, conversation *domain.Conversation, maxConversations int) (*domain.Conversation, error) {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin insert failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	newConversation, err := db.insertConversation(ctx, tx, conversation, maxConversations)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit insert failed", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	db.logger.Info(ctx, "conversation created successfully", map[string]any{
+		"conversation_id": newConversation.ID,
+		"user_id":         newConversation.UserID,
+		"title":           newConversation.Title,
+	})
+
+	return newConversation, nil
+}
+
+// insertConversation inserts a conversation within tx. When maxConversations is positive,
+// the user's conversation inserts are serialized for the rest of tx and the conversation is
+// only inserted while the user has fewer than maxConversations, so concurrent inserts
+// cannot overshoot the quota; ErrConversationQuotaExceeded is returned otherwise. Other
+// errors are logged and mapped with HandlePgError.
+func (db *DB) insertConversation(ctx context.Context, tx *sqlx.Tx, conversation *domain.Conversation, maxConversations int) (*domain.Conversation, error) {
+	var newConversation domain.Conversation
+	if maxConversations <= 0 {
+		if err := db.timeQuery(ctx, "insert conversation", func() error {
+			return insertNamed(ctx, tx, insertConversationQuery, conversation, &newConversation)
+		}); err != nil {
+			status, mappedErr := HandlePgError(err)
+			db.logger.Error(ctx, mappedErr, "insert failed", status)
+			return nil, mappedErr
+		}
+		return &newConversation, nil
+	}
+
 	params := map[string]any{
-		"id": id,
+		"id":                conversation.ID,
+		"user_id":           conversation.UserID,
+		"title":             conversation.Title,
+		"created_at":        conversation.CreatedAt,
+		"updated_at":        conversation.UpdatedAt,
+		"max_conversations": maxConversations,
+	}
+	if _, err := db.execNamed(ctx, tx, "lock user conversations", lockUserConversationsQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "lock user conversations failed", status)
+		return nil, mappedErr
+	}
+
+	err := db.timeQuery(ctx, "insert conversation", func() error {
+		return insertNamed(ctx, tx, insertConversationWithinQuotaQuery, params, &newConversation)
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrConversationQuotaExceeded
+	}
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "insert failed", status)
+		return nil, mappedErr
+	}
+	return &newConversation, nil
+}
+
+// CreateConversationWithMessages inserts a conversation and its messages in one
+// transaction, so a failure on any row leaves neither behind. The conversation is subject
+// to maxConversations like in CreateConversation.
+func (db *DB) CreateConversationWithMessages(ctx context.Context, conversation *domain.Conversation, messages []*domain.Message, maxConversations int) (*domain.Conversation, error) {
+	return retryWrite(ctx, db, "import conversation", func() (*domain.Conversation, error) {
+		return db.createConversationWithMessages(ctx, conversation, messages, maxConversations)
+	})
+}
+
+// createConversationWithMessages runs one attempt of CreateConversationWithMessages
+func (db *DB) createConversationWithMessages(ctx context.Context, conversation *domain.Conversation, messages []*domain.Message, maxConversations int) (*domain.Conversation, error) {
+	if conversation.ID == "" {
+		conversation.ID = uuid.New().String()
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin import failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	newConversation, err := db.insertConversation(ctx, tx, conversation, maxConversations)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, message := range messages {
+		message.ConversationID = newConversation.ID
+	}
+	if err := db.insertMessagesBatch(ctx, tx, messages); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit import failed", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	db.logger.Info(ctx, "conversation imported successfully", map[string]any{
+		"conversation_id": newConversation.ID,
+		"user_id":         newConversation.UserID,
+		"message_count":   len(messages),
+	})
+
+	return newConversation, nil
+}
+
+// GetConversationByID retrieves a conversation owned by userID, without its message
+// activity. A conversation that belongs to another user is reported as not found.
+func (db *DB) GetConversationByID(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	return db.getConversation(ctx, "get conversation by id", getConversationByIDQuery, id, userID)
+}
+
+// GetConversationWithActivity retrieves a conversation owned by userID with its message
+// activity. A conversation that belongs to another user is reported as not found.
+func (db *DB) GetConversationWithActivity(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	return db.getConversation(ctx, "get conversation with activity", getConversationWithActivityQuery, id, userID)
+}
+
+// getConversation runs query, a lookup of one conversation by id and owner
+func (db *DB) getConversation(ctx context.Context, operation, query, id, userID string) (*domain.Conversation, error) {
+	params := map[string]any{
+		"id":      id,
+		"user_id": userID,
 	}
 
 	var conversation domain.Conversation
-	stmt, err := db.PrepareNamedContext(ctx, getConversationByIDQuery)
+	stmt, err := db.PrepareNamedContext(ctx, query)
 	if err != nil {
 		db.logger.Error(ctx, err, "prepare select failed", http.StatusInternalServerError)
 		return nil, err
 	}
 	defer stmt.Close()
 
-	if err := stmt.GetContext(ctx, &conversation, params); err != nil {
+	if err := db.timeQuery(ctx, operation, func() error {
+		return stmt.GetContext(ctx, &conversation, params)
+	}); err != nil {
 		if err == sql.ErrNoRows {
 			db.logger.Info(ctx, "conversation not found", map[string]any{
 				"conversation_id": id,
+				"user_id":         userID,
 			})
-			return nil, errors.New("conversation not found")
+			return nil, ErrConversationNotFound
 		}
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
@@ -129,12 +420,14 @@ func (db *DB) GetConversationByID(ctx context.Context, id string) (*domain.Conve
 	return &conversation, nil
 }
 
-// GetConversationsByUserID retrieves conversations for a specific user with pagination
-func (db *DB) GetConversationsByUserID(ctx context.Context, userID string, limit, offset int) ([]domain.Conversation, error) {
+// GetConversationsByUserID retrieves conversations for a specific user with pagination.
+// Archived conversations are only included when includeArchived is set.
+func (db *DB) GetConversationsByUserID(ctx context.Context, userID string, limit, offset int, includeArchived bool) ([]domain.Conversation, error) {
 	params := map[string]any{
-		"user_id": userID,
-		"limit":   limit,
-		"offset":  offset,
+		"user_id":          userID,
+		"limit":            limit,
+		"offset":           offset,
+		"include_archived": includeArchived,
 	}
 
 	var conversations []domain.Conversation
@@ -145,7 +438,9 @@ func (db *DB) GetConversationsByUserID(ctx context.Context, userID string, limit
 	}
 	defer stmt.Close()
 
-	if err := stmt.SelectContext(ctx, &conversations, params); err != nil {
+	if err := db.timeQuery(ctx, "get conversations by user id", func() error {
+		return stmt.SelectContext(ctx, &conversations, params)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
 		return nil, mappedErr
@@ -161,10 +456,49 @@ func (db *DB) GetConversationsByUserID(ctx context.Context, userID string, limit
 	return conversations, nil
 }
 
-// CountConversationsByUserID returns the total number of conversations for a user
-func (db *DB) CountConversationsByUserID(ctx context.Context, userID string) (int, error) {
+// GetConversationsAfterCursor retrieves the conversations of a user that follow the cursor,
+// ordered by creation time. Archived conversations are only included when includeArchived is set.
+func (db *DB) GetConversationsAfterCursor(ctx context.Context, userID string, cursor domain.Cursor, limit int, includeArchived bool) ([]domain.Conversation, error) {
 	params := map[string]any{
-		"user_id": userID,
+		"user_id":           userID,
+		"cursor_created_at": cursor.CreatedAt,
+		"cursor_id":         cursor.ID,
+		"limit":             limit,
+		"include_archived":  includeArchived,
+	}
+
+	var conversations []domain.Conversation
+	stmt, err := db.PrepareNamedContext(ctx, getConversationsAfterCursorQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare select failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := db.timeQuery(ctx, "get conversations after cursor", func() error {
+		return stmt.SelectContext(ctx, &conversations, params)
+	}); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "select failed", status)
+		return nil, mappedErr
+	}
+
+	db.logger.Info(ctx, "conversations retrieved successfully", map[string]any{
+		"user_id":   userID,
+		"count":     len(conversations),
+		"limit":     limit,
+		"cursor_id": cursor.ID,
+	})
+
+	return conversations, nil
+}
+
+// CountConversationsByUserID returns the total number of conversations for a user,
+// counting archived ones only when includeArchived is set
+func (db *DB) CountConversationsByUserID(ctx context.Context, userID string, includeArchived bool) (int, error) {
+	params := map[string]any{
+		"user_id":          userID,
+		"include_archived": includeArchived,
 	}
 
 	var count int
@@ -175,7 +509,9 @@ func (db *DB) CountConversationsByUserID(ctx context.Context, userID string) (in
 	}
 	defer stmt.Close()
 
-	if err := stmt.GetContext(ctx, &count, params); err != nil {
+	if err := db.timeQuery(ctx, "count conversations by user id", func() error {
+		return stmt.GetContext(ctx, &count, params)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "count failed", status)
 		return 0, mappedErr
@@ -186,12 +522,18 @@ func (db *DB) CountConversationsByUserID(ctx context.Context, userID string) (in
 
 // UpdateConversationTitle updates the title of a conversation
 func (db *DB) UpdateConversationTitle(ctx context.Context, id, userID, title string) (*domain.Conversation, error) {
-	params := map[string]any{
-		"id":         id,
-		"user_id":    userID,
-		"title":      title,
-		"updated_at": domain.NewConversation(userID, title).UpdatedAt, // This will be overwritten
-	}
+	return retryWrite(ctx, db, "update conversation title", func() (*domain.Conversation, error) {
+		return db.updateConversationTitle(ctx, id, userID, title)
+	})
+}
+
+// updateConversationTitle runs one attempt of UpdateConversationTitle
+func (db *DB) updateConversationTitle(ctx context.Context, id, userID, title string) (*domain.Conversation, error) {
+	params := touchUpdatedAt(map[string]any{
+		"id":      id,
+		"user_id": userID,
+		"title":   title,
+	})
 
 	stmt, err := db.PrepareNamedContext(ctx, updateConversationTitleQuery)
 	if err != nil {
@@ -201,7 +543,9 @@ func (db *DB) UpdateConversationTitle(ctx context.Context, id, userID, title str
 	defer stmt.Close()
 
 	var conversation domain.Conversation
-	if err := stmt.GetContext(ctx, &conversation, params); err != nil {
+	if err := db.timeQuery(ctx, "update conversation title", func() error {
+		return stmt.GetContext(ctx, &conversation, params)
+	}); err != nil {
 		if err == sql.ErrNoRows {
 			db.logger.Info(ctx, "conversation not found or user not authorized", map[string]any{
 				"conversation_id": id,
@@ -223,46 +567,285 @@ func (db *DB) UpdateConversationTitle(ctx context.Context, id, userID, title str
 	return &conversation, nil
 }
 
-// DeleteConversation deletes a conversation (this will cascade delete messages)
+// DeleteConversation soft deletes a conversation together with its messages
 func (db *DB) DeleteConversation(ctx context.Context, id, userID string) error {
+	return db.withRetry(ctx, "delete conversation", func() error {
+		return db.deleteConversation(ctx, id, userID)
+	})
+}
+
+// deleteConversation runs one attempt of DeleteConversation
+func (db *DB) deleteConversation(ctx context.Context, id, userID string) error {
 	params := map[string]any{
-		"id":      id,
-		"user_id": userID,
+		"id":         id,
+		"user_id":    userID,
+		"deleted_at": time.Now().UTC(),
 	}
 
-	stmt, err := db.PrepareNamedContext(ctx, deleteConversationQuery)
+	tx, err := db.BeginTxx(ctx, nil)
 	if err != nil {
-		db.logger.Error(ctx, err, "prepare delete failed", http.StatusInternalServerError)
+		db.logger.Error(ctx, err, "begin delete failed", http.StatusInternalServerError)
 		return err
 	}
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	result, err := stmt.ExecContext(ctx, params)
+	rowsAffected, err := db.execNamed(ctx, tx, "delete conversation", deleteConversationQuery, params)
 	if err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "delete failed", status)
 		return mappedErr
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	if rowsAffected == 0 {
+		db.logger.Info(ctx, "conversation not found or user not authorized", map[string]any{
+			"conversation_id": id,
+			"user_id":         userID,
+		})
+		return ErrConversationNotFound
+	}
+
+	messagesAffected, err := db.execNamed(ctx, tx, "delete conversation messages", deleteConversationMessagesQuery, params)
 	if err != nil {
-		db.logger.Error(ctx, err, "failed to get rows affected", http.StatusInternalServerError)
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "delete messages failed", status)
+		return mappedErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit delete failed", http.StatusInternalServerError)
 		return err
 	}
 
+	db.logger.Info(ctx, "conversation deleted successfully", map[string]any{
+		"conversation_id":  id,
+		"user_id":          userID,
+		"messages_deleted": messagesAffected,
+	})
+
+	return nil
+}
+
+// ClearConversationMessages soft deletes every message of a conversation owned by the user,
+// keeping the conversation itself, and returns how many messages were deleted
+func (db *DB) ClearConversationMessages(ctx context.Context, id, userID string) (int, error) {
+	return retryWrite(ctx, db, "clear conversation messages", func() (int, error) {
+		return db.clearConversationMessages(ctx, id, userID)
+	})
+}
+
+// clearConversationMessages runs one attempt of ClearConversationMessages
+func (db *DB) clearConversationMessages(ctx context.Context, id, userID string) (int, error) {
+	now := time.Now().UTC()
+	params := map[string]any{
+		"id":         id,
+		"user_id":    userID,
+		"updated_at": now,
+		"deleted_at": now,
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin clear failed", http.StatusInternalServerError)
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// Touching the conversation first checks that the user owns it
+	rowsAffected, err := db.execNamed(ctx, tx, "touch conversation", touchConversationQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "update failed", status)
+		return 0, mappedErr
+	}
+
 	if rowsAffected == 0 {
 		db.logger.Info(ctx, "conversation not found or user not authorized", map[string]any{
 			"conversation_id": id,
 			"user_id":         userID,
 		})
-		return errors.New("conversation not found or user not authorized")
+		return 0, ErrConversationNotFound
 	}
 
-	db.logger.Info(ctx, "conversation deleted successfully", map[string]any{
+	messagesAffected, err := db.execNamed(ctx, tx, "delete conversation messages", deleteConversationMessagesQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "delete messages failed", status)
+		return 0, mappedErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit clear failed", http.StatusInternalServerError)
+		return 0, err
+	}
+
+	db.logger.Info(ctx, "conversation messages cleared successfully", map[string]any{
+		"conversation_id":  id,
+		"user_id":          userID,
+		"messages_deleted": messagesAffected,
+	})
+
+	return int(messagesAffected), nil
+}
+
+// RestoreConversation undoes the soft delete of a conversation and the messages deleted
+// with it. Conversations deleted longer ago than the restore window are not restored.
+func (db *DB) RestoreConversation(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	return retryWrite(ctx, db, "restore conversation", func() (*domain.Conversation, error) {
+		return db.restoreConversation(ctx, id, userID)
+	})
+}
+
+// restoreConversation runs one attempt of RestoreConversation
+func (db *DB) restoreConversation(ctx context.Context, id, userID string) (*domain.Conversation, error) {
+	params := map[string]any{
+		"id":               id,
+		"user_id":          userID,
+		"restorable_after": time.Now().UTC().Add(-db.restoreWindow),
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin restore failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Messages are restored first, while the conversation still carries its deleted_at
+	messagesRestored, err := db.execNamed(ctx, tx, "restore conversation messages", restoreConversationMessagesQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "restore messages failed", status)
+		return nil, mappedErr
+	}
+
+	stmt, err := tx.PrepareNamedContext(ctx, restoreConversationQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare restore failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var conversation domain.Conversation
+	if err := db.timeQuery(ctx, "restore conversation", func() error {
+		return stmt.GetContext(ctx, &conversation, params)
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			db.logger.Info(ctx, "deleted conversation not found or no longer restorable", map[string]any{
+				"conversation_id": id,
+				"user_id":         userID,
+			})
+			return nil, ErrConversationNotFound
+		}
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "restore failed", status)
+		return nil, mappedErr
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit restore failed", http.StatusInternalServerError)
+		return nil, err
+	}
+
+	db.logger.Info(ctx, "conversation restored successfully", map[string]any{
+		"conversation_id":   id,
+		"user_id":           userID,
+		"messages_restored": messagesRestored,
+	})
+
+	return &conversation, nil
+}
+
+// ConversationFlag names a boolean column of a conversation its owner can set
+type ConversationFlag string
+
+const (
+	// ConversationArchived hides a conversation from the default conversation list
+	ConversationArchived ConversationFlag = "archived"
+	// ConversationPinned lists a conversation before the others
+	ConversationPinned ConversationFlag = "pinned"
+)
+
+// setConversationFlagQueries holds the update query of each ConversationFlag, so only
+// known columns are ever written
+var setConversationFlagQueries = map[ConversationFlag]string{
+	ConversationArchived: fmt.Sprintf(setConversationFlagQuery, ConversationArchived),
+	ConversationPinned:   fmt.Sprintf(setConversationFlagQuery, ConversationPinned),
+}
+
+// SetConversationFlag sets or clears flag on a conversation owned by the user
+func (db *DB) SetConversationFlag(ctx context.Context, id, userID string, flag ConversationFlag, value bool) (*domain.Conversation, error) {
+	query, ok := setConversationFlagQueries[flag]
+	if !ok {
+		return nil, fmt.Errorf("unknown conversation flag %q", flag)
+	}
+
+	operation := "set conversation " + string(flag)
+	return retryWrite(ctx, db, operation, func() (*domain.Conversation, error) {
+		return db.setConversationFlag(ctx, operation, query, id, userID, flag, value)
+	})
+}
+
+// setConversationFlag runs one attempt of SetConversationFlag
+func (db *DB) setConversationFlag(ctx context.Context, operation, query, id, userID string, flag ConversationFlag, value bool) (*domain.Conversation, error) {
+	params := map[string]any{
+		"id":      id,
+		"user_id": userID,
+		"value":   value,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, query)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare update failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var conversation domain.Conversation
+	if err := db.timeQuery(ctx, operation, func() error {
+		return stmt.GetContext(ctx, &conversation, params)
+	}); err != nil {
+		if err == sql.ErrNoRows {
+			db.logger.Info(ctx, "conversation not found or user not authorized", map[string]any{
+				"conversation_id": id,
+				"user_id":         userID,
+			})
+			return nil, ErrConversationNotFound
+		}
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "update failed", status)
+		return nil, mappedErr
+	}
+
+	db.logger.Info(ctx, "conversation flag updated successfully", map[string]any{
 		"conversation_id": id,
 		"user_id":         userID,
-		"rows_affected":   rowsAffected,
+		string(flag):      value,
 	})
 
-	return nil
+	return &conversation, nil
+}
+
+// ArchiveIdleConversations archives every live conversation with no activity since cutoff
+// and returns how many were archived
+func (db *DB) ArchiveIdleConversations(ctx context.Context, cutoff time.Time) (int64, error) {
+	var archived int64
+	err := db.withRetry(ctx, "archive idle conversations", func() error {
+		var err error
+		archived, err = db.execNamed(ctx, db, "archive idle conversations", archiveIdleConversationsQuery, map[string]any{
+			"cutoff": cutoff,
+		})
+		return err
+	})
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "archive idle conversations failed", status)
+		return 0, mappedErr
+	}
+
+	db.logger.Info(ctx, "idle conversations archived", map[string]any{
+		"cutoff":   cutoff,
+		"archived": archived,
+	})
+
+	return archived, nil
 }
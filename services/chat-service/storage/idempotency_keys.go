@@ -0,0 +1,176 @@
+package storage
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"chat-service/internal/domain"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+// Named queries
+const (
+	deleteExpiredIdempotencyKeyQuery = `
+		DELETE FROM idempotency_keys
+		WHERE user_id = :user_id AND key = :key AND created_at <= :expires_before
+	`
+
+	// A key already held by a concurrent or earlier request inserts nothing
+	insertIdempotencyKeyQuery = `
+		INSERT INTO idempotency_keys (
+			user_id,
+			key,
+			message_id,
+			created_at
+		) VALUES (
+			:user_id,
+			:key,
+			:message_id,
+			:created_at
+		)
+		ON CONFLICT (user_id, key) DO NOTHING
+	`
+
+	getMessageByIdempotencyKeyQuery = `
+		SELECT
+			m.id,
+			m.user_id,
+			m.conversation_id,
+			m.content,
+			m.role,
+			m.created_at,
+			m.updated_at,
+			m.metadata
+		FROM idempotency_keys k
+		JOIN messages m ON m.id = k.message_id
+		WHERE k.user_id = :user_id AND k.key = :key
+	`
+)
+
+// CreateMessageWithIdempotencyKey stores message and records it under the user's idempotency
+// key. If the key was used after expiresBefore, nothing is inserted and the message stored
+// then is returned with created set to false. conversation, when not nil, is created in the
+// same transaction so a repeated request doesn't leave an empty conversation behind; it is
+// subject to maxConversations like in CreateConversation.
+func (db *DB) CreateMessageWithIdempotencyKey(ctx context.Context, conversation *domain.Conversation, message *domain.Message, key string, expiresBefore time.Time, maxConversations int) (stored *domain.Message, created bool, err error) {
+	err = db.withRetry(ctx, "create message with idempotency key", func() error {
+		stored, created, err = db.createMessageWithIdempotencyKey(ctx, conversation, message, key, expiresBefore, maxConversations)
+		return err
+	})
+	return stored, created, err
+}
+
+// createMessageWithIdempotencyKey runs one attempt of CreateMessageWithIdempotencyKey
+func (db *DB) createMessageWithIdempotencyKey(ctx context.Context, conversation *domain.Conversation, message *domain.Message, key string, expiresBefore time.Time, maxConversations int) (stored *domain.Message, created bool, err error) {
+	if message.ID == "" {
+		message.ID = uuid.New().String()
+	}
+
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin idempotent insert failed", http.StatusInternalServerError)
+		return nil, false, err
+	}
+	defer tx.Rollback()
+
+	params := map[string]any{
+		"user_id":        message.UserID,
+		"key":            key,
+		"expires_before": expiresBefore,
+	}
+	if _, err := db.execNamed(ctx, tx, "delete expired idempotency key", deleteExpiredIdempotencyKeyQuery, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "delete expired idempotency key failed", status)
+		return nil, false, mappedErr
+	}
+
+	if conversation != nil {
+		if _, err := db.insertConversation(ctx, tx, conversation, maxConversations); err != nil {
+			return nil, false, err
+		}
+	}
+
+	var newMessage domain.Message
+	if err := insertNamed(ctx, tx, insertMessageQuery, message, &newMessage); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "insert failed", status)
+		return nil, false, mappedErr
+	}
+
+	params["message_id"] = newMessage.ID
+	params["created_at"] = time.Now().UTC()
+	inserted, err := db.execNamed(ctx, tx, "insert idempotency key", insertIdempotencyKeyQuery, params)
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "insert idempotency key failed", status)
+		return nil, false, mappedErr
+	}
+
+	if inserted == 0 {
+		// The key is taken: drop this attempt and answer with the original message
+		if err := tx.Rollback(); err != nil {
+			db.logger.Error(ctx, err, "rollback idempotent insert failed", http.StatusInternalServerError)
+			return nil, false, err
+		}
+		existing, err := db.getMessageByIdempotencyKey(ctx, message.UserID, key)
+		if err != nil {
+			return nil, false, err
+		}
+
+		db.logger.Info(ctx, "idempotency key replayed", map[string]any{
+			"user_id":    message.UserID,
+			"message_id": existing.ID,
+		})
+		return existing, false, nil
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit idempotent insert failed", http.StatusInternalServerError)
+		return nil, false, err
+	}
+
+	db.logger.Info(ctx, "message created successfully", map[string]any{
+		"message_id":      newMessage.ID,
+		"conversation_id": newMessage.ConversationID,
+	})
+
+	return &newMessage, true, nil
+}
+
+// getMessageByIdempotencyKey returns the message recorded under the user's idempotency key
+func (db *DB) getMessageByIdempotencyKey(ctx context.Context, userID, key string) (*domain.Message, error) {
+	params := map[string]any{
+		"user_id": userID,
+		"key":     key,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, getMessageByIdempotencyKeyQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare select failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	var message domain.Message
+	if err := stmt.GetContext(ctx, &message, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "select failed", status)
+		return nil, mappedErr
+	}
+
+	return &message, nil
+}
+
+// insertNamed runs a named INSERT ... RETURNING inside tx and scans the row into dest
+func insertNamed(ctx context.Context, tx *sqlx.Tx, query string, arg, dest any) error {
+	stmt, err := tx.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	return stmt.GetContext(ctx, dest, arg)
+}
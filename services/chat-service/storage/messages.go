@@ -3,15 +3,19 @@ package storage
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"net/http"
 	"time"
 
 	"chat-service/internal/domain"
 
 	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
 )
 
+// maxBatchInsertRows caps the rows per multi-row INSERT, keeping each statement well
+// below PostgreSQL's limit of 65535 bind parameters
+const maxBatchInsertRows = 1000
+
 // Named queries
 const (
 	insertMessageQuery = `
@@ -22,7 +26,8 @@ const (
 			content,
 			role,
 			created_at,
-			updated_at
+			updated_at,
+			metadata
 		) VALUES (
 			:id,
 			:user_id,
@@ -30,11 +35,34 @@ const (
 			:content,
 			:role,
 			:created_at,
-			:updated_at
+			:updated_at,
+			:metadata
 		)
-		RETURNING id, user_id, conversation_id, content, role, created_at, updated_at
+		RETURNING id, user_id, conversation_id, content, role, created_at, updated_at, metadata
 	`
 
+	// Expanded by sqlx into a single multi-row INSERT when executed with a slice
+	insertMessagesBatchQuery = `
+		INSERT INTO messages (
+			id,
+			user_id,
+			conversation_id,
+			content,
+			role,
+			created_at,
+			updated_at,
+			metadata
+		) VALUES (
+			:id,
+			:user_id,
+			:conversation_id,
+			:content,
+			:role,
+			:created_at,
+			:updated_at,
+			:metadata
+		)`
+
 	getMessageByIDQuery = `
 		SELECT 
 			id,
@@ -43,9 +71,10 @@ const (
 			content,
 			role,
 			created_at,
-			updated_at
+			updated_at,
+			metadata
 		FROM messages
-		WHERE id = :id
+		WHERE id = :id AND deleted_at IS NULL
 	`
 
 	getMessagesByConversationIDQuery = `
@@ -56,15 +85,33 @@ const (
 			content,
 			role,
 			created_at,
-			updated_at
+			updated_at,
+			metadata
 		FROM messages
-		WHERE conversation_id = :conversation_id
-		ORDER BY created_at ASC
+		WHERE conversation_id = :conversation_id AND deleted_at IS NULL
+		ORDER BY created_at ASC, id ASC
 		LIMIT :limit OFFSET :offset
 	`
 
+	getMessagesAfterCursorQuery = `
+		SELECT 
+			id,
+			user_id,
+			conversation_id,
+			content,
+			role,
+			created_at,
+			updated_at,
+			metadata
+		FROM messages
+		WHERE conversation_id = :conversation_id AND deleted_at IS NULL
+			AND (created_at, id) > (:cursor_created_at, :cursor_id)
+		ORDER BY created_at ASC, id ASC
+		LIMIT :limit
+	`
+
 	countMessagesByConversationIDQuery = `
-		SELECT COUNT(*) FROM messages WHERE conversation_id = :conversation_id
+		SELECT COUNT(*) FROM messages WHERE conversation_id = :conversation_id AND deleted_at IS NULL
 	`
 
 	getMessagesByUserIDQuery = `
@@ -75,32 +122,66 @@ const (
 			content,
 			role,
 			created_at,
-			updated_at
+			updated_at,
+			metadata
 		FROM messages
-		WHERE user_id = :user_id
+		WHERE user_id = :user_id AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT :limit OFFSET :offset
 	`
 
 	countMessagesByUserIDQuery = `
-		SELECT COUNT(*) FROM messages WHERE user_id = :user_id
+		SELECT COUNT(*) FROM messages WHERE user_id = :user_id AND deleted_at IS NULL
+	`
+
+	searchMessagesQuery = `
+		SELECT 
+			m.id,
+			m.user_id,
+			m.conversation_id,
+			m.content,
+			m.role,
+			m.created_at,
+			m.updated_at,
+			m.metadata,
+			ts_headline('english', m.content, q, 'MaxWords=35, MinWords=15, StartSel=«, StopSel=»') AS snippet
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		CROSS JOIN plainto_tsquery('english', :query) q
+		WHERE c.user_id = :user_id
+			AND m.deleted_at IS NULL AND c.deleted_at IS NULL
+			AND to_tsvector('english', m.content) @@ q
+		ORDER BY ts_rank(to_tsvector('english', m.content), q) DESC, m.created_at DESC, m.id ASC
+		LIMIT :limit OFFSET :offset
 	`
 
 	updateMessageContentQuery = `
 		UPDATE messages 
 		SET content = :content, updated_at = :updated_at
-		WHERE id = :id AND user_id = :user_id
-		RETURNING id, user_id, conversation_id, content, role, created_at, updated_at
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
+		RETURNING id, user_id, conversation_id, content, role, created_at, updated_at, metadata
 	`
 
 	deleteMessageQuery = `
-		DELETE FROM messages 
-		WHERE id = :id AND user_id = :user_id
+		UPDATE messages 
+		SET deleted_at = :deleted_at
+		WHERE id = :id AND user_id = :user_id AND deleted_at IS NULL
 	`
 )
 
-// CreateMessage inserts a new message into the database
+// CreateMessage inserts a new message into the database. If its ID is already taken, the
+// message is given a new one and inserted again, once.
 func (db *DB) CreateMessage(ctx context.Context, message *domain.Message) (*domain.Message, error) {
+	regenerateID := func() { message.ID = uuid.New().String() }
+	return retryOnIDCollision(ctx, db, "create message", regenerateID, func() (*domain.Message, error) {
+		return retryWrite(ctx, db, "create message", func() (*domain.Message, error) {
+			return db.createMessage(ctx, message)
+		})
+	})
+}
+
+// createMessage runs one attempt of CreateMessage
+func (db *DB) createMessage(ctx context.Context, message *domain.Message) (*domain.Message, error) {
 	if message.ID == "" {
 		message.ID = uuid.New().String()
 	}
@@ -113,7 +194,9 @@ func (db *DB) CreateMessage(ctx context.Context, message *domain.Message) (*doma
 	defer stmt.Close()
 
 	var newMessage domain.Message
-	if err := stmt.GetContext(ctx, &newMessage, message); err != nil {
+	if err := db.timeQuery(ctx, "insert message", func() error {
+		return stmt.GetContext(ctx, &newMessage, message)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "insert failed", status)
 		return nil, mappedErr
@@ -130,6 +213,66 @@ func (db *DB) CreateMessage(ctx context.Context, message *domain.Message) (*doma
 	return &newMessage, nil
 }
 
+// CreateMessagesBatch inserts messages with multi-row INSERTs in one transaction. Either
+// every message is stored or, if any row fails, none are.
+func (db *DB) CreateMessagesBatch(ctx context.Context, messages []*domain.Message) error {
+	return db.withRetry(ctx, "create messages batch", func() error {
+		return db.createMessagesBatch(ctx, messages)
+	})
+}
+
+// createMessagesBatch runs one attempt of CreateMessagesBatch
+func (db *DB) createMessagesBatch(ctx context.Context, messages []*domain.Message) error {
+	tx, err := db.BeginTxx(ctx, nil)
+	if err != nil {
+		db.logger.Error(ctx, err, "begin batch insert failed", http.StatusInternalServerError)
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := db.insertMessagesBatch(ctx, tx, messages); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		db.logger.Error(ctx, err, "commit batch insert failed", http.StatusInternalServerError)
+		return err
+	}
+
+	db.logger.Info(ctx, "messages created successfully", map[string]any{
+		"count": len(messages),
+	})
+
+	return nil
+}
+
+// insertMessagesBatch inserts messages within tx, maxBatchInsertRows rows per statement.
+// Messages without an ID are given one.
+func (db *DB) insertMessagesBatch(ctx context.Context, tx *sqlx.Tx, messages []*domain.Message) error {
+	for _, message := range messages {
+		if message.ID == "" {
+			message.ID = uuid.New().String()
+		}
+	}
+
+	for start := 0; start < len(messages); start += maxBatchInsertRows {
+		end := min(start+maxBatchInsertRows, len(messages))
+		if err := db.timeQuery(ctx, "insert messages batch", func() error {
+			_, err := tx.NamedExecContext(ctx, insertMessagesBatchQuery, messages[start:end])
+			return err
+		}); err != nil {
+			status, mappedErr := HandlePgError(err)
+			db.logger.Error(ctx, mappedErr, "batch insert failed", status, map[string]any{
+				"offset": start,
+				"count":  end - start,
+			})
+			return mappedErr
+		}
+	}
+
+	return nil
+}
+
 // GetMessageByID retrieves a message by ID
 func (db *DB) GetMessageByID(ctx context.Context, id string) (*domain.Message, error) {
 	params := map[string]any{
@@ -144,12 +287,14 @@ func (db *DB) GetMessageByID(ctx context.Context, id string) (*domain.Message, e
 	}
 	defer stmt.Close()
 
-	if err := stmt.GetContext(ctx, &message, params); err != nil {
+	if err := db.timeQuery(ctx, "get message by id", func() error {
+		return stmt.GetContext(ctx, &message, params)
+	}); err != nil {
 		if err == sql.ErrNoRows {
 			db.logger.Info(ctx, "message not found", map[string]any{
 				"message_id": id,
 			})
-			return nil, errors.New("message not found")
+			return nil, ErrMessageNotFound
 		}
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
@@ -175,7 +320,9 @@ func (db *DB) GetMessagesByConversationID(ctx context.Context, conversationID st
 	}
 	defer stmt.Close()
 
-	if err := stmt.SelectContext(ctx, &messages, params); err != nil {
+	if err := db.timeQuery(ctx, "get messages by conversation id", func() error {
+		return stmt.SelectContext(ctx, &messages, params)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
 		return nil, mappedErr
@@ -191,6 +338,42 @@ func (db *DB) GetMessagesByConversationID(ctx context.Context, conversationID st
 	return messages, nil
 }
 
+// GetMessagesAfterCursor retrieves the messages of a conversation that follow the cursor,
+// ordered by creation time
+func (db *DB) GetMessagesAfterCursor(ctx context.Context, conversationID string, cursor domain.Cursor, limit int) ([]domain.Message, error) {
+	params := map[string]any{
+		"conversation_id":   conversationID,
+		"cursor_created_at": cursor.CreatedAt,
+		"cursor_id":         cursor.ID,
+		"limit":             limit,
+	}
+
+	var messages []domain.Message
+	stmt, err := db.PrepareNamedContext(ctx, getMessagesAfterCursorQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare select failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := db.timeQuery(ctx, "get messages after cursor", func() error {
+		return stmt.SelectContext(ctx, &messages, params)
+	}); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "select failed", status)
+		return nil, mappedErr
+	}
+
+	db.logger.Info(ctx, "messages retrieved successfully", map[string]any{
+		"conversation_id": conversationID,
+		"count":           len(messages),
+		"limit":           limit,
+		"cursor_id":       cursor.ID,
+	})
+
+	return messages, nil
+}
+
 // CountMessagesByConversationID returns the total number of messages in a conversation
 func (db *DB) CountMessagesByConversationID(ctx context.Context, conversationID string) (int, error) {
 	params := map[string]any{
@@ -205,7 +388,9 @@ func (db *DB) CountMessagesByConversationID(ctx context.Context, conversationID
 	}
 	defer stmt.Close()
 
-	if err := stmt.GetContext(ctx, &count, params); err != nil {
+	if err := db.timeQuery(ctx, "count messages by conversation id", func() error {
+		return stmt.GetContext(ctx, &count, params)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "count failed", status)
 		return 0, mappedErr
@@ -230,7 +415,9 @@ func (db *DB) GetMessagesByUserID(ctx context.Context, userID string, limit, off
 	}
 	defer stmt.Close()
 
-	if err := stmt.SelectContext(ctx, &messages, params); err != nil {
+	if err := db.timeQuery(ctx, "get messages by user id", func() error {
+		return stmt.SelectContext(ctx, &messages, params)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "select failed", status)
 		return nil, mappedErr
@@ -260,7 +447,9 @@ func (db *DB) CountMessagesByUserID(ctx context.Context, userID string) (int, er
 	}
 	defer stmt.Close()
 
-	if err := stmt.GetContext(ctx, &count, params); err != nil {
+	if err := db.timeQuery(ctx, "count messages by user id", func() error {
+		return stmt.GetContext(ctx, &count, params)
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "count failed", status)
 		return 0, mappedErr
@@ -269,15 +458,57 @@ func (db *DB) CountMessagesByUserID(ctx context.Context, userID string) (int, er
 	return count, nil
 }
 
-// UpdateMessageContent updates the content of a message
-func (db *DB) UpdateMessageContent(ctx context.Context, id, userID, content string) (*domain.Message, error) {
+// SearchMessages runs a full-text search over the messages in the user's conversations,
+// best matches first
+func (db *DB) SearchMessages(ctx context.Context, userID, query string, limit, offset int) ([]domain.MessageSearchResult, error) {
 	params := map[string]any{
-		"id":         id,
-		"user_id":    userID,
-		"content":    content,
-		"updated_at": time.Now(),
+		"user_id": userID,
+		"query":   query,
+		"limit":   limit,
+		"offset":  offset,
+	}
+
+	var results []domain.MessageSearchResult
+	stmt, err := db.PrepareNamedContext(ctx, searchMessagesQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare search failed", http.StatusInternalServerError)
+		return nil, err
+	}
+	defer stmt.Close()
+
+	if err := db.timeQuery(ctx, "search messages", func() error {
+		return stmt.SelectContext(ctx, &results, params)
+	}); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "search failed", status)
+		return nil, mappedErr
 	}
 
+	db.logger.Info(ctx, "messages searched successfully", map[string]any{
+		"user_id": userID,
+		"count":   len(results),
+		"limit":   limit,
+		"offset":  offset,
+	})
+
+	return results, nil
+}
+
+// UpdateMessageContent updates the content of a message
+func (db *DB) UpdateMessageContent(ctx context.Context, id, userID, content string) (*domain.Message, error) {
+	return retryWrite(ctx, db, "update message content", func() (*domain.Message, error) {
+		return db.updateMessageContent(ctx, id, userID, content)
+	})
+}
+
+// updateMessageContent runs one attempt of UpdateMessageContent
+func (db *DB) updateMessageContent(ctx context.Context, id, userID, content string) (*domain.Message, error) {
+	params := touchUpdatedAt(map[string]any{
+		"id":      id,
+		"user_id": userID,
+		"content": content,
+	})
+
 	stmt, err := db.PrepareNamedContext(ctx, updateMessageContentQuery)
 	if err != nil {
 		db.logger.Error(ctx, err, "prepare update failed", http.StatusInternalServerError)
@@ -286,13 +517,15 @@ func (db *DB) UpdateMessageContent(ctx context.Context, id, userID, content stri
 	defer stmt.Close()
 
 	var message domain.Message
-	if err := stmt.GetContext(ctx, &message, params); err != nil {
+	if err := db.timeQuery(ctx, "update message content", func() error {
+		return stmt.GetContext(ctx, &message, params)
+	}); err != nil {
 		if err == sql.ErrNoRows {
 			db.logger.Info(ctx, "message not found or user not authorized", map[string]any{
 				"message_id": id,
 				"user_id":    userID,
 			})
-			return nil, errors.New("message not found or user not authorized")
+			return nil, ErrMessageNotFound
 		}
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "update failed", status)
@@ -308,11 +541,19 @@ func (db *DB) UpdateMessageContent(ctx context.Context, id, userID, content stri
 	return &message, nil
 }
 
-// DeleteMessage deletes a message
+// DeleteMessage soft deletes a message
 func (db *DB) DeleteMessage(ctx context.Context, id, userID string) error {
+	return db.withRetry(ctx, "delete message", func() error {
+		return db.deleteMessage(ctx, id, userID)
+	})
+}
+
+// deleteMessage runs one attempt of DeleteMessage
+func (db *DB) deleteMessage(ctx context.Context, id, userID string) error {
 	params := map[string]any{
-		"id":      id,
-		"user_id": userID,
+		"id":         id,
+		"user_id":    userID,
+		"deleted_at": time.Now().UTC(),
 	}
 
 	stmt, err := db.PrepareNamedContext(ctx, deleteMessageQuery)
@@ -322,8 +563,11 @@ func (db *DB) DeleteMessage(ctx context.Context, id, userID string) error {
 	}
 	defer stmt.Close()
 
-	result, err := stmt.ExecContext(ctx, params)
-	if err != nil {
+	var result sql.Result
+	if err := db.timeQuery(ctx, "delete message", func() (err error) {
+		result, err = stmt.ExecContext(ctx, params)
+		return err
+	}); err != nil {
 		status, mappedErr := HandlePgError(err)
 		db.logger.Error(ctx, mappedErr, "delete failed", status)
 		return mappedErr
@@ -340,7 +584,7 @@ func (db *DB) DeleteMessage(ctx context.Context, id, userID string) error {
 			"message_id": id,
 			"user_id":    userID,
 		})
-		return errors.New("message not found or user not authorized")
+		return ErrMessageNotFound
 	}
 
 	db.logger.Info(ctx, "message deleted successfully", map[string]any{
@@ -0,0 +1,10 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Create composite indexes backing the (created_at, id) keyset pagination
+CREATE INDEX IF NOT EXISTS idx_conversations_user_id_created_at_id ON conversations(user_id, created_at, id);
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id_created_at_id ON messages(conversation_id, created_at, id);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_messages_conversation_id_created_at_id;
+DROP INDEX IF EXISTS idx_conversations_user_id_created_at_id;
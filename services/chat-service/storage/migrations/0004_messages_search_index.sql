@@ -0,0 +1,9 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Create a GIN index backing full-text search over message content. The expression
+-- must match the to_tsvector call in searchMessagesQuery for the index to be used.
+CREATE INDEX IF NOT EXISTS idx_messages_content_fts ON messages USING GIN (to_tsvector('english', content));
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_messages_content_fts;
@@ -0,0 +1,16 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Deletes set deleted_at instead of removing rows so conversations can be restored
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+-- Create partial indexes so listings only scan rows that have not been deleted
+CREATE INDEX IF NOT EXISTS idx_conversations_user_id_active ON conversations(user_id) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id_active ON messages(conversation_id) WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_messages_conversation_id_active;
+DROP INDEX IF EXISTS idx_conversations_user_id_active;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE conversations DROP COLUMN IF EXISTS deleted_at;
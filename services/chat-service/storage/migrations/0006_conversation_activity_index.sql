@@ -0,0 +1,8 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Lets conversation listings count live messages and find the latest one from the index alone
+CREATE INDEX IF NOT EXISTS idx_messages_conversation_id_created_at_active ON messages(conversation_id, created_at) WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_messages_conversation_id_created_at_active;
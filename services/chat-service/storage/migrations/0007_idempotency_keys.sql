@@ -0,0 +1,19 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Remembers the message created for a client supplied Idempotency-Key so retried sends
+-- return it instead of inserting a duplicate
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL,
+    key VARCHAR(255) NOT NULL,
+    message_id UUID NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, key)
+);
+
+-- Create index on created_at for purging expired keys
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_created_at ON idempotency_keys(created_at);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_idempotency_keys_created_at;
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,13 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Archived conversations are kept but left out of the default conversation list
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS archived BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create a partial index for the archival job, which only looks at live conversations
+-- that have not been archived yet
+CREATE INDEX IF NOT EXISTS idx_conversations_updated_at_unarchived ON conversations(updated_at) WHERE deleted_at IS NULL AND NOT archived;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_conversations_updated_at_unarchived;
+ALTER TABLE conversations DROP COLUMN IF EXISTS archived;
@@ -0,0 +1,12 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Pinned conversations are listed before the others
+ALTER TABLE conversations ADD COLUMN IF NOT EXISTS pinned BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Create a partial index backing the default conversation list order
+CREATE INDEX IF NOT EXISTS idx_conversations_user_id_pinned_updated_at ON conversations(user_id, pinned DESC, updated_at DESC) WHERE deleted_at IS NULL;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP INDEX IF EXISTS idx_conversations_user_id_pinned_updated_at;
+ALTER TABLE conversations DROP COLUMN IF EXISTS pinned;
@@ -0,0 +1,8 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Optional client-supplied annotations stored with each message
+ALTER TABLE messages ADD COLUMN IF NOT EXISTS metadata JSONB;
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+ALTER TABLE messages DROP COLUMN IF EXISTS metadata;
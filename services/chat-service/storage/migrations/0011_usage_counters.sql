@@ -0,0 +1,15 @@
+-- +goose Up
+-- SQL in this section is executed when the migration is applied.
+-- Tokens each user spent on AI chats per UTC day, checked against DAILY_TOKEN_BUDGET. A new
+-- day starts a new row, so budgets reset at midnight UTC without a cleanup job.
+CREATE TABLE IF NOT EXISTS usage_counters (
+    user_id UUID NOT NULL,
+    day DATE NOT NULL,
+    tokens BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, day)
+);
+
+-- +goose Down
+-- SQL in this section is executed when the migration is rolled back.
+DROP TABLE IF EXISTS usage_counters;
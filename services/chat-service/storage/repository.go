@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"chat-service/internal/domain"
 )
@@ -9,22 +10,37 @@ import (
 // Repository defines the interface for chat storage operations
 type Repository interface {
 	// Conversation operations
-	CreateConversation(ctx context.Context, conversation *domain.Conversation) (*domain.Conversation, error)
-	GetConversationByID(ctx context.Context, id string) (*domain.Conversation, error)
-	GetConversationsByUserID(ctx context.Context, userID string, limit, offset int) ([]domain.Conversation, error)
-	CountConversationsByUserID(ctx context.Context, userID string) (int, error)
+	CreateConversation(ctx context.Context, conversation *domain.Conversation, maxConversations int) (*domain.Conversation, error)
+	CreateConversationWithMessages(ctx context.Context, conversation *domain.Conversation, messages []*domain.Message, maxConversations int) (*domain.Conversation, error)
+	GetConversationByID(ctx context.Context, id, userID string) (*domain.Conversation, error)
+	GetConversationWithActivity(ctx context.Context, id, userID string) (*domain.Conversation, error)
+	GetConversationsByUserID(ctx context.Context, userID string, limit, offset int, includeArchived bool) ([]domain.Conversation, error)
+	GetConversationsAfterCursor(ctx context.Context, userID string, cursor domain.Cursor, limit int, includeArchived bool) ([]domain.Conversation, error)
+	CountConversationsByUserID(ctx context.Context, userID string, includeArchived bool) (int, error)
 	UpdateConversationTitle(ctx context.Context, id, userID, title string) (*domain.Conversation, error)
 	DeleteConversation(ctx context.Context, id, userID string) error
+	ClearConversationMessages(ctx context.Context, id, userID string) (int, error)
+	RestoreConversation(ctx context.Context, id, userID string) (*domain.Conversation, error)
+	SetConversationFlag(ctx context.Context, id, userID string, flag ConversationFlag, value bool) (*domain.Conversation, error)
+	ArchiveIdleConversations(ctx context.Context, cutoff time.Time) (int64, error)
 
 	// Message operations
 	CreateMessage(ctx context.Context, message *domain.Message) (*domain.Message, error)
+	CreateMessagesBatch(ctx context.Context, messages []*domain.Message) error
+	CreateMessageWithIdempotencyKey(ctx context.Context, conversation *domain.Conversation, message *domain.Message, key string, expiresBefore time.Time, maxConversations int) (*domain.Message, bool, error)
 	GetMessageByID(ctx context.Context, id string) (*domain.Message, error)
 	GetMessagesByConversationID(ctx context.Context, conversationID string, limit, offset int) ([]domain.Message, error)
+	GetMessagesAfterCursor(ctx context.Context, conversationID string, cursor domain.Cursor, limit int) ([]domain.Message, error)
 	CountMessagesByConversationID(ctx context.Context, conversationID string) (int, error)
 	GetMessagesByUserID(ctx context.Context, userID string, limit, offset int) ([]domain.Message, error)
 	CountMessagesByUserID(ctx context.Context, userID string) (int, error)
+	SearchMessages(ctx context.Context, userID, query string, limit, offset int) ([]domain.MessageSearchResult, error)
 	UpdateMessageContent(ctx context.Context, id, userID, content string) (*domain.Message, error)
 	DeleteMessage(ctx context.Context, id, userID string) error
+
+	// Usage operations
+	ReserveDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens, budget int) (bool, error)
+	AddDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens int) (int, error)
 }
 
 // Ensure DB implements Repository interface
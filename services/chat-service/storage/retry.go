@@ -0,0 +1,80 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// maxWriteAttempts bounds how many times a write is run when it keeps failing transiently
+	maxWriteAttempts = 3
+	// retryBaseDelay is the wait before the first retry, doubled for each one after it
+	retryBaseDelay = 20 * time.Millisecond
+)
+
+// isTransient reports whether HandlePgError classifies err as a serialization failure or
+// deadlock, which Postgres resolves by aborting one of the transactions involved
+func isTransient(err error) bool {
+	_, mappedErr := HandlePgError(err)
+	return errors.Is(mappedErr, ErrSerializationFailure) || errors.Is(mappedErr, ErrDeadlockDetected)
+}
+
+// isIDCollision reports whether HandlePgError classifies err as a primary key violation
+func isIDCollision(err error) bool {
+	_, mappedErr := HandlePgError(err)
+	return errors.Is(mappedErr, ErrDuplicateID)
+}
+
+// withRetry runs op and, while it fails with a transient error, runs it again with
+// exponential backoff, up to maxWriteAttempts times in all. op must be safe to repeat: a
+// single statement or a whole transaction, never part of one.
+func (db *DB) withRetry(ctx context.Context, operation string, op func() error) error {
+	delay := retryBaseDelay
+	for attempt := 1; ; attempt++ {
+		err := op()
+		if err == nil || attempt == maxWriteAttempts || !isTransient(err) {
+			return err
+		}
+
+		db.logger.Warn(ctx, "transient database error, retrying", map[string]any{
+			"operation": operation,
+			"attempt":   attempt,
+			"error":     err.Error(),
+		})
+
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// retryWrite is withRetry for writes that return the stored row
+func retryWrite[T any](ctx context.Context, db *DB, operation string, op func() (T, error)) (T, error) {
+	var result T
+	err := db.withRetry(ctx, operation, func() error {
+		var err error
+		result, err = op()
+		return err
+	})
+	return result, err
+}
+
+// retryOnIDCollision runs op and, if it fails because the row's ID is already taken, gives
+// the row a new ID with regenerateID and runs op once more. A second collision is returned.
+func retryOnIDCollision[T any](ctx context.Context, db *DB, operation string, regenerateID func(), op func() (T, error)) (T, error) {
+	result, err := op()
+	if err == nil || !isIDCollision(err) {
+		return result, err
+	}
+
+	db.logger.Warn(ctx, "generated ID already exists, retrying with a new one", map[string]any{
+		"operation": operation,
+		"error":     err.Error(),
+	})
+	regenerateID()
+	return op()
+}
@@ -0,0 +1,26 @@
+package storage
+
+import (
+	"context"
+	"time"
+)
+
+// timeQuery runs fn, one execution of the query called name, and logs a warning with its
+// duration when it took longer than the slow query threshold. A zero threshold disables
+// the check.
+func (db *DB) timeQuery(ctx context.Context, name string, fn func() error) error {
+	if db.slowQueryThreshold <= 0 {
+		return fn()
+	}
+
+	start := time.Now()
+	err := fn()
+	if elapsed := time.Since(start); elapsed > db.slowQueryThreshold {
+		db.logger.Warn(ctx, "slow query", map[string]any{
+			"query":        name,
+			"duration_ms":  float64(elapsed.Microseconds()) / 1000,
+			"threshold_ms": db.slowQueryThreshold.Milliseconds(),
+		})
+	}
+	return err
+}
@@ -2,20 +2,22 @@ package storage
 
 import (
 	"context"
+	"database/sql"
 	"errors"
 	"fmt"
 	"net/http"
-	"os"
 	"strings"
 	"time"
 
 	"chat-service/configs"
 
+	"packages/database"
 	zlog "packages/logger"
 
+	"github.com/XSAM/otelsql"
 	"github.com/jmoiron/sqlx"
 	"github.com/lib/pq"
-	"github.com/pressly/goose"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
 )
 
 const (
@@ -31,42 +33,103 @@ var (
 	ErrExclusionViolation  = fmt.Errorf("exclusion constraint violation")
 )
 
+// ErrDuplicateID wraps a unique violation of a table's primary key, which for generated IDs
+// means the ID is already taken
+var ErrDuplicateID = errors.New("id already exists")
+
+// Transient errors abort a transaction that can succeed when it is run again
+var (
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrDeadlockDetected     = errors.New("deadlock detected")
+)
+
+// Errors returned when a row does not exist or belongs to another user
+var (
+	ErrConversationNotFound = errors.New("conversation not found or user not authorized")
+	ErrMessageNotFound      = errors.New("message not found or user not authorized")
+)
+
+// ErrConversationQuotaExceeded is returned when inserting a conversation would take its user
+// over the maximum number of conversations passed to the insert
+var ErrConversationQuotaExceeded = errors.New("conversation quota exceeded")
+
 // DB wraps sqlx.DB with additional functionality
 type DB struct {
 	*sqlx.DB
-	logger *zlog.Logger
+	logger        *zlog.Logger
+	restoreWindow time.Duration // how long soft deleted conversations stay restorable
+	// slowQueryThreshold is how long a query may take before it is logged as slow; zero
+	// disables the log
+	slowQueryThreshold time.Duration
 }
 
 // Config holds database configuration
 type Config struct {
 	ConnStr         string
 	MigrationsDir   string
+	RunMigrations   bool
 	ConnTimeout     time.Duration
 	MaxOpenConns    int
 	MaxIdleConns    int
 	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+	RestoreWindow   time.Duration
+	// SlowQueryThreshold is how long a query may take before it is logged as slow; zero
+	// disables the log
+	SlowQueryThreshold time.Duration
+}
+
+// touchUpdatedAt sets the updated_at parameter of an update query to the current
+// time and returns params
+func touchUpdatedAt(params map[string]any) map[string]any {
+	params["updated_at"] = time.Now().UTC()
+	return params
 }
 
 type NamedPreparer interface {
 	PrepareNamedContext(ctx context.Context, query string) (*sqlx.NamedStmt, error)
 }
 
+// execNamed runs a named statement, timed as the query called name, and returns the number
+// of affected rows
+func (db *DB) execNamed(ctx context.Context, p NamedPreparer, name, query string, params map[string]any) (int64, error) {
+	stmt, err := p.PrepareNamedContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var result sql.Result
+	if err := db.timeQuery(ctx, name, func() (err error) {
+		result, err = stmt.ExecContext(ctx, params)
+		return err
+	}); err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 // NewDB initializes a new database connection with the provided configuration
 func NewDB(ctx context.Context, cfg *Config, logger *zlog.Logger) (*DB, error) {
 	if cfg == nil || logger == nil {
 		return nil, fmt.Errorf("config and logger must not be nil")
 	}
 
-	dbx, err := sqlx.Open("postgres", cfg.ConnStr)
+	// Every query runs in a span under the caller's trace
+	sqlDB, err := otelsql.Open("postgres", cfg.ConnStr, otelsql.WithAttributes(semconv.DBSystemNamePostgreSQL))
 	if err != nil {
 		logger.Error(ctx, err, "Failed to open database connection", http.StatusInternalServerError)
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	dbx := sqlx.NewDb(sqlDB, "postgres")
 
-	// Configure connection pool
-	dbx.SetMaxOpenConns(cfg.MaxOpenConns)
-	dbx.SetMaxIdleConns(cfg.MaxIdleConns)
-	dbx.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	configurePool(dbx, cfg)
+	logger.Info(ctx, "Database connection pool configured", map[string]any{
+		"max_open_conns":     cfg.MaxOpenConns,
+		"max_idle_conns":     cfg.MaxIdleConns,
+		"conn_max_lifetime":  cfg.ConnMaxLifetime.String(),
+		"conn_max_idle_time": cfg.ConnMaxIdleTime.String(),
+	})
 
 	ctxTimeout, cancel := context.WithTimeout(ctx, cfg.ConnTimeout)
 	defer cancel()
@@ -78,19 +141,27 @@ func NewDB(ctx context.Context, cfg *Config, logger *zlog.Logger) (*DB, error) {
 	}
 
 	// Run migrations
-	if err := runMigrations(ctx, dbx, cfg.MigrationsDir, logger); err != nil {
+	if err := database.RunMigrations(ctx, dbx.DB, database.MigrationConfig{Dir: cfg.MigrationsDir, Enabled: cfg.RunMigrations}, logger); err != nil {
 		logger.Error(ctx, err, "Database migrations failed", http.StatusInternalServerError)
 		return nil, fmt.Errorf("failed to run migrations: %w", err)
 	}
 
-	logger.Info(ctx, "Database connection established and migrations applied successfully")
-	return &DB{DB: dbx, logger: func() *zlog.Logger {
+	logger.Info(ctx, "Database connection established")
+	return &DB{DB: dbx, restoreWindow: cfg.RestoreWindow, slowQueryThreshold: cfg.SlowQueryThreshold, logger: func() *zlog.Logger {
 		return logger.WithFields(map[string]any{
 			"layer": APP_LAYER,
 		})
 	}()}, nil
 }
 
+// configurePool applies the connection pool limits from cfg to db
+func configurePool(db *sqlx.DB, cfg *Config) {
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+}
+
 // InitDB initializes the database using the application config
 func InitDB(ctx context.Context, appCfg *configs.Config, logger *zlog.Logger) (*DB, error) {
 	cfg := FromConfig(appCfg)
@@ -118,34 +189,16 @@ func FromConfig(appCfg *configs.Config) *Config {
 			appCfg.DBSSLMode,
 		),
 		MigrationsDir:   appCfg.MigrationsDir,
+		RunMigrations:   appCfg.RunMigrations,
 		ConnTimeout:     time.Duration(appCfg.DBConnectionTimeout) * time.Second,
 		MaxOpenConns:    appCfg.DBMaxConnections,
 		MaxIdleConns:    appCfg.DBMaxIdleConnections,
-		ConnMaxLifetime: DefaultConnMaxLifetime,
-	}
-}
+		ConnMaxLifetime: time.Duration(appCfg.DBConnMaxLifetime) * time.Second,
+		ConnMaxIdleTime: time.Duration(appCfg.DBConnMaxIdleTime) * time.Second,
+		RestoreWindow:   time.Duration(appCfg.RestoreWindowHours) * time.Hour,
 
-// runMigrations applies database migrations from the specified directory
-func runMigrations(ctx context.Context, db *sqlx.DB, migrationsDir string, logger *zlog.Logger) error {
-	if _, err := os.Stat(migrationsDir); os.IsNotExist(err) {
-		logger.Error(ctx, err, "Migrations directory not found", http.StatusInternalServerError, map[string]any{"path": migrationsDir})
-		return fmt.Errorf("migrations directory does not exist: %w", err)
+		SlowQueryThreshold: time.Duration(appCfg.SlowQueryThresholdMS) * time.Millisecond,
 	}
-
-	logger.Info(ctx, "Applying migrations", map[string]any{"migrations_dir": migrationsDir})
-
-	if err := goose.SetDialect("postgres"); err != nil {
-		logger.Error(ctx, err, "Failed to set goose dialect", http.StatusInternalServerError)
-		return fmt.Errorf("failed to set goose dialect: %w", err)
-	}
-
-	if err := goose.Up(db.DB, migrationsDir); err != nil {
-		logger.Error(ctx, err, "Failed to apply migrations", http.StatusInternalServerError)
-		return fmt.Errorf("failed to apply migrations: %w", err)
-	}
-
-	logger.Info(ctx, "Migrations applied successfully")
-	return nil
 }
 
 // defaultUniqueMessage generates a user-friendly message for unique constraint violations
@@ -169,15 +222,20 @@ func HandlePgError(err error) (int, error) {
 		status int
 		err    error
 	}{
-		"unique_violation":     {http.StatusConflict, ErrUniqueViolation},
+		"unique_violation":      {http.StatusConflict, ErrUniqueViolation},
 		"foreign_key_violation": {http.StatusBadRequest, ErrForeignKeyViolation},
-		"not_null_violation":   {http.StatusBadRequest, ErrNotNullViolation},
-		"check_violation":      {http.StatusBadRequest, ErrCheckViolation},
-		"exclusion_violation":  {http.StatusBadRequest, ErrExclusionViolation},
+		"not_null_violation":    {http.StatusBadRequest, ErrNotNullViolation},
+		"check_violation":       {http.StatusBadRequest, ErrCheckViolation},
+		"exclusion_violation":   {http.StatusBadRequest, ErrExclusionViolation},
+		"serialization_failure": {http.StatusServiceUnavailable, ErrSerializationFailure},
+		"deadlock_detected":     {http.StatusServiceUnavailable, ErrDeadlockDetected},
 	}
 
 	if errorInfo, exists := errorCodeMap[pgErr.Code.Name()]; exists {
 		if pgErr.Code.Name() == "unique_violation" {
+			if strings.HasSuffix(pgErr.Constraint, "_pkey") {
+				return errorInfo.status, fmt.Errorf("%w: %w", errorInfo.err, ErrDuplicateID)
+			}
 			return errorInfo.status, fmt.Errorf("%w: %s", errorInfo.err, defaultUniqueMessage(pgErr.Constraint))
 		}
 		return errorInfo.status, errorInfo.err
@@ -1,22 +1,41 @@
 package storage
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"regexp"
+	"strings"
 	"testing"
 	"time"
 
+	"chat-service/configs"
 	"chat-service/internal/domain"
+	"chat-service/internal/domain/domaintest"
+	zlog "packages/logger"
 
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestNewMessage(t *testing.T) {
 	userID := "test-user-123"
 	conversationID := "test-conversation-456"
 	content := "Hello, world!"
-	role := "user"
+	role := domain.RoleUser
 
-	message := domain.NewMessage(userID, conversationID, content, role)
+	message, err := domain.NewMessage(userID, conversationID, content, role)
 
+	require.NoError(t, err)
 	assert.NotEmpty(t, message.ID)
 	assert.Equal(t, userID, message.UserID)
 	assert.Equal(t, conversationID, message.ConversationID)
@@ -214,6 +233,21 @@ func TestConversationValidation(t *testing.T) {
 func TestRepositoryInterface(t *testing.T) {
 	// This test ensures that the DB struct implements the Repository interface
 	var _ Repository = (*DB)(nil)
+
+	// Every storage operation DB adds must be listed in Repository too, or the service and
+	// its test fakes could not use it. Methods promoted from sqlx.DB and Close, which only
+	// the server calls, are not storage operations.
+	repository := reflect.TypeOf((*Repository)(nil)).Elem()
+	sqlxDB := reflect.TypeOf((*sqlx.DB)(nil))
+	dbType := reflect.TypeOf((*DB)(nil))
+	for i := 0; i < dbType.NumMethod(); i++ {
+		name := dbType.Method(i).Name
+		if _, promoted := sqlxDB.MethodByName(name); promoted || name == "Close" {
+			continue
+		}
+		_, listed := repository.MethodByName(name)
+		assert.True(t, listed, "DB.%s is missing from the Repository interface", name)
+	}
 }
 
 func TestConfigDefaults(t *testing.T) {
@@ -232,4 +266,1145 @@ func TestErrorConstants(t *testing.T) {
 	assert.NotNil(t, ErrNotNullViolation)
 	assert.NotNil(t, ErrCheckViolation)
 	assert.NotNil(t, ErrExclusionViolation)
+	assert.NotNil(t, ErrConversationNotFound)
+	assert.NotNil(t, ErrMessageNotFound)
+}
+
+func TestMessageMutationsAreScopedToOwner(t *testing.T) {
+	// Edits and deletes must only ever touch rows owned by the requesting user
+	for name, query := range map[string]string{
+		"update": updateMessageContentQuery,
+		"delete": deleteMessageQuery,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Contains(t, query, "AND user_id = :user_id")
+		})
+	}
+}
+
+func TestCursorQueriesUseKeysetPredicate(t *testing.T) {
+	// Cursor pages must seek past the last row rather than skip rows by offset
+	for name, query := range map[string]string{
+		"messages":      getMessagesAfterCursorQuery,
+		"conversations": getConversationsAfterCursorQuery,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Contains(t, query, "(created_at, id) > (:cursor_created_at, :cursor_id)")
+			assert.Contains(t, query, "ORDER BY created_at ASC, id ASC")
+			assert.NotContains(t, query, "OFFSET")
+		})
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	cursor := domain.NewCursor(time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC), "11111111-1111-1111-1111-111111111111")
+
+	decoded, err := domain.DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+
+	start, err := domain.ParseCursor("")
+	require.NoError(t, err)
+	assert.Equal(t, domain.StartCursor(), start)
+
+	for _, invalid := range []string{"not base64!", "bm8tc2VwYXJhdG9y", domain.NewCursor(time.Now(), "not-a-uuid").Encode()} {
+		_, err := domain.DecodeCursor(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+// timeArg is a sqlmock argument matcher that records the time it is matched against
+type timeArg struct {
+	value *time.Time
+}
+
+func (a timeArg) Match(v driver.Value) bool {
+	t, ok := v.(time.Time)
+	if ok {
+		*a.value = t
+	}
+	return ok
+}
+
+func newMockDB(t *testing.T) (*DB, sqlmock.Sqlmock) {
+	t.Helper()
+	mockDB, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	t.Cleanup(func() { mockDB.Close() })
+
+	logger := zlog.NewLogger(zlog.Config{Level: "debug", Output: io.Discard})
+	return &DB{DB: sqlx.NewDb(mockDB, "postgres"), logger: logger}, mock
+}
+
+func TestUpdateConversationTitle_AdvancesUpdatedAt(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "Old Title")
+	conversation.CreatedAt = time.Now().Add(-time.Hour).UTC()
+	conversation.UpdatedAt = conversation.CreatedAt
+
+	var updatedAt time.Time
+	before := time.Now().UTC()
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectQuery().
+		WithArgs("New Title", timeArg{value: &updatedAt}, conversation.ID, conversation.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, "New Title", conversation.CreatedAt, before))
+
+	updated, err := db.UpdateConversationTitle(context.Background(), conversation.ID, conversation.UserID, "New Title")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, "New Title", updated.Title)
+	assert.Equal(t, time.UTC, updatedAt.Location())
+	assert.False(t, updatedAt.Before(before))
+	assert.True(t, updatedAt.After(conversation.UpdatedAt))
+	assert.True(t, updatedAt.After(conversation.CreatedAt))
+}
+
+func TestUpdateMessageContent_AdvancesUpdatedAt(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	message := domaintest.NewMessage("11111111-1111-1111-1111-111111111111", "33333333-3333-3333-3333-333333333333", "Old", "user")
+	message.CreatedAt = time.Now().Add(-time.Hour).UTC()
+
+	var updatedAt time.Time
+	before := time.Now().UTC()
+	mock.ExpectPrepare("UPDATE messages").
+		ExpectQuery().
+		WithArgs("New", timeArg{value: &updatedAt}, message.ID, message.UserID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, "New", "user", message.CreatedAt, before))
+
+	_, err := db.UpdateMessageContent(context.Background(), message.ID, message.UserID, "New")
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, time.UTC, updatedAt.Location())
+	assert.False(t, updatedAt.Before(before))
+	assert.True(t, updatedAt.After(message.CreatedAt))
+}
+
+func TestSearchMessagesQueryIsScopedToOwner(t *testing.T) {
+	// Search must only ever look at messages in conversations owned by the requesting user
+	assert.Contains(t, searchMessagesQuery, "JOIN conversations c ON c.id = m.conversation_id")
+	assert.Contains(t, searchMessagesQuery, "WHERE c.user_id = :user_id")
+	assert.Contains(t, searchMessagesQuery, "to_tsvector('english', m.content) @@ q")
+}
+
+func TestSearchMessages(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	userID := "11111111-1111-1111-1111-111111111111"
+	message := domaintest.NewMessage(userID, "33333333-3333-3333-3333-333333333333", "Deploying the service to production", "user")
+
+	mock.ExpectPrepare("FROM messages m").
+		ExpectQuery().
+		WithArgs("deploy", userID, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at", "snippet"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt,
+				"«Deploying» the service to production"))
+
+	results, err := db.SearchMessages(context.Background(), userID, "deploy", 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, results, 1)
+	assert.Equal(t, message.ID, results[0].ID)
+	assert.Equal(t, message.ConversationID, results[0].ConversationID)
+	assert.Equal(t, "«Deploying» the service to production", results[0].Snippet)
+}
+
+func TestSearchMessages_SnippetIsPlainText(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	userID := "11111111-1111-1111-1111-111111111111"
+	content := `deploy <script>alert("xss")</script>`
+	message := domaintest.NewMessage(userID, "33333333-3333-3333-3333-333333333333", content, "user")
+
+	// Highlighting with HTML tags would mix markup into raw user content, so the query
+	// must ask ts_headline for plain-text markers
+	mock.ExpectPrepare(regexp.QuoteMeta("StartSel=«, StopSel=»")).
+		ExpectQuery().
+		WithArgs("deploy", userID, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at", "snippet"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt,
+				`«deploy» <script>alert("xss")</script>`))
+
+	results, err := db.SearchMessages(context.Background(), userID, "deploy", 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, results, 1)
+	assert.Equal(t, `«deploy» <script>alert("xss")</script>`, results[0].Snippet)
+	assert.NotContains(t, searchMessagesQuery, "<b>")
+}
+
+func TestConversationListQueriesAggregateActivity(t *testing.T) {
+	// Listings count live messages only and report the newest one
+	for name, query := range map[string]string{
+		"conversation by id":    getConversationWithActivityQuery,
+		"conversations by user": getConversationsByUserIDQuery,
+		"conversations cursor":  getConversationsAfterCursorQuery,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Contains(t, query, "LEFT JOIN LATERAL")
+			assert.Contains(t, query, "WHERE m.conversation_id = conversations.id AND m.deleted_at IS NULL")
+			assert.Contains(t, query, "activity.message_count")
+			assert.Contains(t, query, "activity.last_message_at")
+		})
+	}
+}
+
+func TestGetConversationByID_ScopesLookupToOwner(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	userID := "11111111-1111-1111-1111-111111111111"
+	conversation := domain.NewConversation(userID, "Mine")
+
+	// The ownership check is a plain lookup, without the message activity join
+	assert.NotContains(t, getConversationByIDQuery, "LATERAL")
+	mock.ExpectPrepare(regexp.QuoteMeta("WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL")).
+		ExpectQuery().
+		WithArgs(conversation.ID, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+
+	got, err := db.GetConversationByID(context.Background(), conversation.ID, userID)
+	require.NoError(t, err)
+	assert.Equal(t, conversation.ID, got.ID)
+
+	// Another user's conversation matches no row, just like a missing one
+	otherUserID := "22222222-2222-2222-2222-222222222222"
+	mock.ExpectPrepare(regexp.QuoteMeta("WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL")).
+		ExpectQuery().
+		WithArgs(conversation.ID, otherUserID).
+		WillReturnError(sql.ErrNoRows)
+
+	_, err = db.GetConversationByID(context.Background(), conversation.ID, otherUserID)
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetConversationsByUserID_IncludesMessageActivity(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	userID := "11111111-1111-1111-1111-111111111111"
+	active := domain.NewConversation(userID, "Active")
+	empty := domain.NewConversation(userID, "Empty")
+	lastMessageAt := time.Now().UTC()
+
+	mock.ExpectPrepare("LEFT JOIN LATERAL").
+		ExpectQuery().
+		WithArgs(userID, false, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at", "message_count", "last_message_at"}).
+			AddRow(active.ID, active.UserID, active.Title, active.CreatedAt, active.UpdatedAt, 3, lastMessageAt).
+			AddRow(empty.ID, empty.UserID, empty.Title, empty.CreatedAt, empty.UpdatedAt, 0, nil))
+
+	conversations, err := db.GetConversationsByUserID(context.Background(), userID, 10, 0, false)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, conversations, 2)
+	assert.Equal(t, 3, conversations[0].MessageCount)
+	require.NotNil(t, conversations[0].LastMessageAt)
+	assert.True(t, lastMessageAt.Equal(*conversations[0].LastMessageAt))
+	assert.Equal(t, 0, conversations[1].MessageCount)
+	assert.Nil(t, conversations[1].LastMessageAt)
+}
+
+func TestReadQueriesExcludeSoftDeleted(t *testing.T) {
+	for name, query := range map[string]string{
+		"conversation by id":       getConversationByIDQuery,
+		"conversation activity":    getConversationWithActivityQuery,
+		"conversations by user":    getConversationsByUserIDQuery,
+		"conversations cursor":     getConversationsAfterCursorQuery,
+		"count conversations":      countConversationsByUserIDQuery,
+		"message by id":            getMessageByIDQuery,
+		"messages by conversation": getMessagesByConversationIDQuery,
+		"messages cursor":          getMessagesAfterCursorQuery,
+		"count messages":           countMessagesByConversationIDQuery,
+		"messages by user":         getMessagesByUserIDQuery,
+		"count user messages":      countMessagesByUserIDQuery,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Contains(t, query, "deleted_at IS NULL")
+		})
+	}
+	assert.Contains(t, searchMessagesQuery, "m.deleted_at IS NULL AND c.deleted_at IS NULL")
+}
+
+func TestDeleteConversation_SoftDeletesMessages(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := "11111111-1111-1111-1111-111111111111"
+	conversationID := "33333333-3333-3333-3333-333333333333"
+
+	var deletedAt, messagesDeletedAt time.Time
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectExec().
+		WithArgs(timeArg{value: &deletedAt}, conversationID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE messages").
+		ExpectExec().
+		WithArgs(timeArg{value: &messagesDeletedAt}, conversationID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	require.NoError(t, db.DeleteConversation(context.Background(), conversationID, userID))
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, deletedAt.Equal(messagesDeletedAt), "messages must share the deleted_at of their conversation")
+}
+
+func TestDeleteConversation_NotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := db.DeleteConversation(context.Background(), "33333333-3333-3333-3333-333333333333", "11111111-1111-1111-1111-111111111111")
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestClearConversationMessages_KeepsConversation(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := "11111111-1111-1111-1111-111111111111"
+	conversationID := "33333333-3333-3333-3333-333333333333"
+
+	var updatedAt time.Time
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE conversations SET updated_at").
+		ExpectExec().
+		WithArgs(timeArg{value: &updatedAt}, conversationID, userID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE messages").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), conversationID).
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectCommit()
+
+	begin := time.Now()
+	deleted, err := db.ClearConversationMessages(context.Background(), conversationID, userID)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 4, deleted)
+	assert.False(t, updatedAt.Before(begin.UTC().Truncate(time.Microsecond)), "clearing must bump updated_at")
+	assert.NotContains(t, touchConversationQuery, "deleted_at =", "the conversation itself must not be deleted")
+}
+
+func TestClearConversationMessages_NotOwner(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectExec().
+		WithArgs(sqlmock.AnyArg(), "33333333-3333-3333-3333-333333333333", "22222222-2222-2222-2222-222222222222").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	deleted, err := db.ClearConversationMessages(context.Background(), "33333333-3333-3333-3333-333333333333", "22222222-2222-2222-2222-222222222222")
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	assert.Zero(t, deleted)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRestoreConversation(t *testing.T) {
+	db, mock := newMockDB(t)
+	db.restoreWindow = 24 * time.Hour
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "Restored")
+
+	var restorableAfter time.Time
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE messages m").
+		ExpectExec().
+		WithArgs(conversation.ID, conversation.UserID, timeArg{value: &restorableAfter}).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectQuery().
+		WithArgs(conversation.ID, conversation.UserID, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+	mock.ExpectCommit()
+
+	restored, err := db.RestoreConversation(context.Background(), conversation.ID, conversation.UserID)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, conversation.ID, restored.ID)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), restorableAfter, time.Minute)
+}
+
+func TestRestoreConversation_OutsideWindow(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE messages m").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}))
+	mock.ExpectRollback()
+
+	_, err := db.RestoreConversation(context.Background(), "33333333-3333-3333-3333-333333333333", "11111111-1111-1111-1111-111111111111")
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConversationListQueriesExcludeArchived(t *testing.T) {
+	for name, query := range map[string]string{
+		"conversations by user": getConversationsByUserIDQuery,
+		"conversations cursor":  getConversationsAfterCursorQuery,
+		"count conversations":   countConversationsByUserIDQuery,
+	} {
+		t.Run(name, func(t *testing.T) {
+			assert.Contains(t, query, "AND (:include_archived OR NOT archived)")
+		})
+	}
+}
+
+func TestArchiveIdleConversationsQuery(t *testing.T) {
+	// Only live, unarchived conversations untouched since the cutoff, whose live messages
+	// are all older than it, are selected
+	assert.Contains(t, archiveIdleConversationsQuery, "WHERE deleted_at IS NULL AND NOT archived")
+	assert.Contains(t, archiveIdleConversationsQuery, "AND updated_at < :cutoff")
+	assert.Contains(t, archiveIdleConversationsQuery, "NOT EXISTS")
+	assert.Contains(t, archiveIdleConversationsQuery, "WHERE m.conversation_id = conversations.id AND m.deleted_at IS NULL")
+	assert.Contains(t, archiveIdleConversationsQuery, "AND m.created_at >= :cutoff")
+}
+
+func TestArchiveIdleConversations_PassesCutoff(t *testing.T) {
+	db, mock := newMockDB(t)
+	cutoff := time.Now().UTC().Add(-30 * 24 * time.Hour)
+
+	// One seeded conversation is older than the cutoff on both checks
+	var updatedBefore, messagesBefore time.Time
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectExec().
+		WithArgs(timeArg{value: &updatedBefore}, timeArg{value: &messagesBefore}).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	archived, err := db.ArchiveIdleConversations(context.Background(), cutoff)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, int64(1), archived)
+	assert.True(t, cutoff.Equal(updatedBefore))
+	assert.True(t, cutoff.Equal(messagesBefore))
+}
+
+func TestSetConversationFlag(t *testing.T) {
+	tests := []struct {
+		flag  ConversationFlag
+		check func(*domain.Conversation) bool
+	}{
+		{flag: ConversationArchived, check: func(c *domain.Conversation) bool { return c.Archived }},
+		{flag: ConversationPinned, check: func(c *domain.Conversation) bool { return c.Pinned }},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.flag), func(t *testing.T) {
+			db, mock := newMockDB(t)
+			conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "Flagged")
+
+			mock.ExpectPrepare(regexp.QuoteMeta("SET "+string(tt.flag)+" = $1")).
+				ExpectQuery().
+				WithArgs(true, conversation.ID, conversation.UserID).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at", "archived", "pinned"}).
+					AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt,
+						tt.flag == ConversationArchived, tt.flag == ConversationPinned))
+
+			updated, err := db.SetConversationFlag(context.Background(), conversation.ID, conversation.UserID, tt.flag, true)
+			require.NoError(t, err)
+			require.NoError(t, mock.ExpectationsWereMet())
+			assert.True(t, tt.check(updated))
+		})
+	}
+}
+
+func TestSetConversationFlag_NotFound(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at", "archived", "pinned"}))
+
+	_, err := db.SetConversationFlag(context.Background(), "33333333-3333-3333-3333-333333333333", "11111111-1111-1111-1111-111111111111", ConversationPinned, true)
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSetConversationFlag_UnknownFlag(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	_, err := db.SetConversationFlag(context.Background(), "33333333-3333-3333-3333-333333333333", "11111111-1111-1111-1111-111111111111", ConversationFlag("title = 'x', archived"), true)
+	assert.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestConversationListQueryOrdersPinnedFirst(t *testing.T) {
+	assert.Contains(t, getConversationsByUserIDQuery, "ORDER BY pinned DESC, updated_at DESC")
+}
+
+// newBatchMessages returns n messages in conversationID with increasing timestamps
+func newBatchMessages(conversationID string, n int) []*domain.Message {
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	messages := make([]*domain.Message, n)
+	for i := range messages {
+		messages[i] = &domain.Message{
+			UserID:         "11111111-1111-1111-1111-111111111111",
+			ConversationID: conversationID,
+			Content:        fmt.Sprintf("message %d", i),
+			Role:           "user",
+			CreatedAt:      base.Add(time.Duration(i) * time.Microsecond),
+			UpdatedAt:      base.Add(time.Duration(i) * time.Microsecond),
+		}
+	}
+	return messages
+}
+
+// batchArgs returns the bind arguments of a multi-row insert of messages, in order
+func batchArgs(messages []*domain.Message) []driver.Value {
+	var args []driver.Value
+	for _, m := range messages {
+		args = append(args, m.ID, m.UserID, m.ConversationID, m.Content, m.Role, m.CreatedAt, m.UpdatedAt, nil)
+	}
+	return args
+}
+
+func TestCreateMessagesBatch_UsesOneMultiRowInsert(t *testing.T) {
+	db, mock := newMockDB(t)
+	messages := newBatchMessages("33333333-3333-3333-3333-333333333333", 3)
+	for i, m := range messages {
+		m.ID = fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO messages .* VALUES \(.+\),\s*\(.+\),\s*\(.+\)$`).
+		WithArgs(batchArgs(messages)...).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	require.NoError(t, db.CreateMessagesBatch(context.Background(), messages))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMessagesBatch_AssignsIDs(t *testing.T) {
+	db, mock := newMockDB(t)
+	messages := newBatchMessages("33333333-3333-3333-3333-333333333333", 2)
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO messages").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	require.NoError(t, db.CreateMessagesBatch(context.Background(), messages))
+	require.NoError(t, mock.ExpectationsWereMet())
+	for _, m := range messages {
+		_, err := uuid.Parse(m.ID)
+		assert.NoError(t, err)
+	}
+}
+
+func TestCreateMessagesBatch_SplitsLargeBatches(t *testing.T) {
+	db, mock := newMockDB(t)
+	messages := newBatchMessages("33333333-3333-3333-3333-333333333333", maxBatchInsertRows+1)
+	for _, m := range messages {
+		m.ID = uuid.NewString()
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO messages").WillReturnResult(sqlmock.NewResult(0, maxBatchInsertRows))
+	mock.ExpectExec("INSERT INTO messages").
+		WithArgs(batchArgs(messages[maxBatchInsertRows:])...).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, db.CreateMessagesBatch(context.Background(), messages))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateMessagesBatch_RollsBackOnFailure(t *testing.T) {
+	db, mock := newMockDB(t)
+	messages := newBatchMessages("33333333-3333-3333-3333-333333333333", maxBatchInsertRows+1)
+
+	// The first chunk succeeds, so only the rollback keeps its rows out of the table
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO messages").WillReturnResult(sqlmock.NewResult(0, maxBatchInsertRows))
+	mock.ExpectExec("INSERT INTO messages").WillReturnError(&pq.Error{Code: "23503"})
+	mock.ExpectRollback()
+
+	err := db.CreateMessagesBatch(context.Background(), messages)
+	assert.ErrorIs(t, err, ErrForeignKeyViolation)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateConversationWithMessages(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "Imported")
+	messages := newBatchMessages("", 2)
+	for i, m := range messages {
+		m.ID = fmt.Sprintf("00000000-0000-0000-0000-00000000000%d", i)
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO conversations").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+	mock.ExpectExec("INSERT INTO messages").
+		WithArgs(
+			messages[0].ID, messages[0].UserID, conversation.ID, messages[0].Content, messages[0].Role, messages[0].CreatedAt, messages[0].UpdatedAt, nil,
+			messages[1].ID, messages[1].UserID, conversation.ID, messages[1].Content, messages[1].Role, messages[1].CreatedAt, messages[1].UpdatedAt, nil,
+		).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	imported, err := db.CreateConversationWithMessages(context.Background(), conversation, messages, 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, conversation.ID, imported.ID)
+	for _, m := range messages {
+		assert.Equal(t, conversation.ID, m.ConversationID)
+	}
+}
+
+func TestCreateConversationWithMessages_RollsBackOnMessageFailure(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "Imported")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("INSERT INTO conversations").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+	mock.ExpectExec("INSERT INTO messages").WillReturnError(&pq.Error{Code: "23502"})
+	mock.ExpectRollback()
+
+	_, err := db.CreateConversationWithMessages(context.Background(), conversation, newBatchMessages("", 2), 0)
+	assert.ErrorIs(t, err, ErrNotNullViolation)
+	require.NoError(t, mock.ExpectationsWereMet(), "the conversation insert must be rolled back")
+}
+
+func TestCreateConversation_WithinQuota(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "New Conversation")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("pg_advisory_xact_lock").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(`INSERT INTO conversations .* WHERE \(`).
+		ExpectQuery().
+		WithArgs(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt, conversation.UserID, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+	mock.ExpectCommit()
+
+	created, err := db.CreateConversation(context.Background(), conversation, 2)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, conversation.ID, created.ID)
+}
+
+func TestCreateConversation_QuotaExceeded(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "New Conversation")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("pg_advisory_xact_lock").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(`INSERT INTO conversations .* WHERE \(`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}))
+	mock.ExpectRollback()
+
+	_, err := db.CreateConversation(context.Background(), conversation, 2)
+	assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateConversationWithMessages_QuotaExceededInsertsNoMessages(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "Imported")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("pg_advisory_xact_lock").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare(`INSERT INTO conversations .* WHERE \(`).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}))
+	mock.ExpectRollback()
+
+	_, err := db.CreateConversationWithMessages(context.Background(), conversation, newBatchMessages("", 2), 2)
+	assert.ErrorIs(t, err, ErrConversationQuotaExceeded)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestFromConfig_PoolSettings(t *testing.T) {
+	cfg := FromConfig(&configs.Config{
+		DBMaxConnections:     20,
+		DBMaxIdleConnections: 4,
+		DBConnectionTimeout:  15,
+		DBConnMaxLifetime:    600,
+		DBConnMaxIdleTime:    60,
+	})
+
+	assert.Equal(t, 20, cfg.MaxOpenConns)
+	assert.Equal(t, 4, cfg.MaxIdleConns)
+	assert.Equal(t, 15*time.Second, cfg.ConnTimeout)
+	assert.Equal(t, 10*time.Minute, cfg.ConnMaxLifetime)
+	assert.Equal(t, time.Minute, cfg.ConnMaxIdleTime)
+}
+
+func TestConfigurePool_AppliesSettings(t *testing.T) {
+	mockDB, _, err := sqlmock.New()
+	require.NoError(t, err)
+	db := sqlx.NewDb(mockDB, "postgres")
+	defer db.Close()
+
+	configurePool(db, &Config{MaxOpenConns: 3, MaxIdleConns: 2, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: time.Hour})
+	assert.Equal(t, 3, db.Stats().MaxOpenConnections)
+
+	// Release more connections than may stay idle; the surplus is closed
+	ctx := context.Background()
+	var conns []*sql.Conn
+	for range 3 {
+		conn, err := db.Conn(ctx)
+		require.NoError(t, err)
+		conns = append(conns, conn)
+	}
+	for _, conn := range conns {
+		require.NoError(t, conn.Close())
+	}
+	stats := db.Stats()
+	assert.Equal(t, 2, stats.Idle)
+	assert.Equal(t, int64(1), stats.MaxIdleClosed)
+
+	// Idle connections are closed once they exceed the idle time
+	configurePool(db, &Config{MaxOpenConns: 3, MaxIdleConns: 2, ConnMaxLifetime: time.Hour, ConnMaxIdleTime: time.Millisecond})
+	assert.Eventually(t, func() bool {
+		return db.Stats().Idle == 0
+	}, 3*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(2), db.Stats().MaxIdleTimeClosed)
+}
+
+func TestCreateMessageWithIdempotencyKey_FirstRequestInserts(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	userID := "11111111-1111-1111-1111-111111111111"
+	conversation := domain.NewConversation(userID, "New Conversation")
+	message := domaintest.NewMessage(userID, conversation.ID, "hello", "user")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM idempotency_keys").
+		ExpectExec().
+		WithArgs(userID, "retry-1", sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO conversations").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow(conversation.ID, conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt))
+	mock.ExpectPrepare("INSERT INTO idempotency_keys").
+		ExpectExec().
+		WithArgs(userID, "retry-1", message.ID, sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	stored, created, err := db.CreateMessageWithIdempotencyKey(context.Background(), conversation, message, "retry-1", time.Now().Add(-time.Hour), 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	assert.True(t, created)
+	assert.Equal(t, message.ID, stored.ID)
+}
+
+func TestCreateMessageWithIdempotencyKey_RepeatReturnsOriginal(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	userID := "11111111-1111-1111-1111-111111111111"
+	conversationID := "33333333-3333-3333-3333-333333333333"
+	original := domaintest.NewMessage(userID, conversationID, "hello", "user")
+	retry := domaintest.NewMessage(userID, conversationID, "hello", "user")
+
+	mock.ExpectBegin()
+	mock.ExpectPrepare("DELETE FROM idempotency_keys").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at"}).
+			AddRow(retry.ID, retry.UserID, retry.ConversationID, retry.Content, retry.Role, retry.CreatedAt, retry.UpdatedAt))
+	mock.ExpectPrepare("INSERT INTO idempotency_keys").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+	mock.ExpectPrepare("FROM idempotency_keys k").
+		ExpectQuery().
+		WithArgs(userID, "retry-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at"}).
+			AddRow(original.ID, original.UserID, original.ConversationID, original.Content, original.Role, original.CreatedAt, original.UpdatedAt))
+
+	stored, created, err := db.CreateMessageWithIdempotencyKey(context.Background(), nil, retry, "retry-1", time.Now().Add(-time.Hour), 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet(), "the retried insert must be rolled back")
+
+	assert.False(t, created)
+	assert.Equal(t, original.ID, stored.ID)
+}
+
+func TestIdempotencyKeyQueries(t *testing.T) {
+	assert.Contains(t, insertIdempotencyKeyQuery, "ON CONFLICT (user_id, key) DO NOTHING")
+	assert.Contains(t, deleteExpiredIdempotencyKeyQuery, "WHERE user_id = :user_id AND key = :key")
+	assert.Contains(t, getMessageByIdempotencyKeyQuery, "WHERE k.user_id = :user_id AND k.key = :key")
+}
+
+func TestHandlePgError_ClassifiesTransientFailures(t *testing.T) {
+	status, err := HandlePgError(&pq.Error{Code: "40001"})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.ErrorIs(t, err, ErrSerializationFailure)
+	assert.True(t, isTransient(err))
+
+	status, err = HandlePgError(&pq.Error{Code: "40P01"})
+	assert.Equal(t, http.StatusServiceUnavailable, status)
+	assert.ErrorIs(t, err, ErrDeadlockDetected)
+	assert.True(t, isTransient(&pq.Error{Code: "40P01"}))
+
+	assert.False(t, isTransient(&pq.Error{Code: "23505"}))
+	assert.False(t, isTransient(sql.ErrNoRows))
+}
+
+func TestCreateMessage_RetriesSerializationFailure(t *testing.T) {
+	db, mock := newMockDB(t)
+	message := domaintest.NewMessage("11111111-1111-1111-1111-111111111111", "33333333-3333-3333-3333-333333333333", "Hello", "user")
+
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WillReturnError(&pq.Error{Code: "40001"})
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt))
+
+	created, err := db.CreateMessage(context.Background(), message)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, message.ID, created.ID)
+}
+
+func TestDeleteConversation_RetriesDeadlockInTransaction(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := "11111111-1111-1111-1111-111111111111"
+	conversationID := "33333333-3333-3333-3333-333333333333"
+
+	// The whole transaction is run again, not just the statement that failed
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE messages").
+		ExpectExec().
+		WillReturnError(&pq.Error{Code: "40P01"})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectPrepare("UPDATE conversations").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectPrepare("UPDATE messages").
+		ExpectExec().
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	require.NoError(t, db.DeleteConversation(context.Background(), conversationID, userID))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestWithRetry_GivesUpAfterMaxAttempts(t *testing.T) {
+	db, _ := newMockDB(t)
+
+	attempts := 0
+	err := db.withRetry(context.Background(), "test", func() error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+
+	assert.Equal(t, maxWriteAttempts, attempts)
+	var pgErr *pq.Error
+	assert.ErrorAs(t, err, &pgErr)
+}
+
+func TestWithRetry_DoesNotRetryPermanentErrors(t *testing.T) {
+	db, _ := newMockDB(t)
+
+	attempts := 0
+	err := db.withRetry(context.Background(), "test", func() error {
+		attempts++
+		return ErrConversationNotFound
+	})
+
+	assert.Equal(t, 1, attempts)
+	assert.ErrorIs(t, err, ErrConversationNotFound)
+}
+
+func TestWithRetry_StopsWhenContextIsDone(t *testing.T) {
+	db, _ := newMockDB(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	attempts := 0
+	err := db.withRetry(ctx, "test", func() error {
+		attempts++
+		return &pq.Error{Code: "40001"}
+	})
+
+	assert.Equal(t, 1, attempts)
+	assert.Error(t, err)
+}
+
+// otherIDArg is a sqlmock argument matcher for any ID except a taken one
+type otherIDArg struct {
+	taken string
+}
+
+func (a otherIDArg) Match(v driver.Value) bool {
+	id, ok := v.(string)
+	return ok && id != "" && id != a.taken
+}
+
+func TestHandlePgError_ClassifiesPrimaryKeyViolations(t *testing.T) {
+	status, err := HandlePgError(&pq.Error{Code: "23505", Constraint: "conversations_pkey"})
+	assert.Equal(t, http.StatusConflict, status)
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.ErrorIs(t, err, ErrDuplicateID)
+	assert.True(t, isIDCollision(err))
+
+	_, err = HandlePgError(&pq.Error{Code: "23505", Constraint: "idempotency_keys_user_id_key"})
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.False(t, isIDCollision(err))
+}
+
+func TestCreateConversation_RegeneratesCollidingID(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "New Conversation")
+	takenID := conversation.ID
+
+	mock.ExpectPrepare("INSERT INTO conversations").
+		ExpectQuery().
+		WithArgs(takenID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "conversations_pkey"})
+	mock.ExpectPrepare("INSERT INTO conversations").
+		ExpectQuery().
+		WithArgs(otherIDArg{taken: takenID}, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "title", "created_at", "updated_at"}).
+			AddRow("44444444-4444-4444-4444-444444444444", conversation.UserID, conversation.Title, conversation.CreatedAt, conversation.UpdatedAt))
+
+	created, err := db.CreateConversation(context.Background(), conversation, 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.NotEqual(t, takenID, conversation.ID)
+	assert.Equal(t, "44444444-4444-4444-4444-444444444444", created.ID)
+}
+
+func TestCreateMessage_RegeneratesCollidingID(t *testing.T) {
+	db, mock := newMockDB(t)
+	message := domaintest.NewMessage("11111111-1111-1111-1111-111111111111", "33333333-3333-3333-3333-333333333333", "Hello", "user")
+	takenID := message.ID
+
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WithArgs(takenID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "messages_pkey"})
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WithArgs(otherIDArg{taken: takenID}, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at"}).
+			AddRow("55555555-5555-5555-5555-555555555555", message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt))
+
+	created, err := db.CreateMessage(context.Background(), message)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.NotEqual(t, takenID, message.ID)
+	assert.Equal(t, "55555555-5555-5555-5555-555555555555", created.ID)
+}
+
+func TestCreateMessage_GivesUpAfterSecondCollision(t *testing.T) {
+	db, mock := newMockDB(t)
+	message := domaintest.NewMessage("11111111-1111-1111-1111-111111111111", "33333333-3333-3333-3333-333333333333", "Hello", "user")
+
+	for range 2 {
+		mock.ExpectPrepare("INSERT INTO messages").
+			ExpectQuery().
+			WillReturnError(&pq.Error{Code: "23505", Constraint: "messages_pkey"})
+	}
+
+	_, err := db.CreateMessage(context.Background(), message)
+	assert.ErrorIs(t, err, ErrDuplicateID)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestCreateConversation_DoesNotRetryOtherUniqueViolations(t *testing.T) {
+	db, mock := newMockDB(t)
+	conversation := domain.NewConversation("11111111-1111-1111-1111-111111111111", "New Conversation")
+	originalID := conversation.ID
+
+	mock.ExpectPrepare("INSERT INTO conversations").
+		ExpectQuery().
+		WillReturnError(&pq.Error{Code: "23505", Constraint: "conversations_title_key"})
+
+	_, err := db.CreateConversation(context.Background(), conversation, 0)
+	assert.ErrorIs(t, err, ErrUniqueViolation)
+	assert.NotErrorIs(t, err, ErrDuplicateID)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, originalID, conversation.ID)
+}
+
+func TestMessageMetadata_RoundTrip(t *testing.T) {
+	db, mock := newMockDB(t)
+	message := domaintest.NewMessage("11111111-1111-1111-1111-111111111111", "33333333-3333-3333-3333-333333333333", "Hello", "user")
+	message.Metadata = domain.Metadata{"source": "mobile", "tags": []any{"draft"}}
+	stored := []byte(`{"source":"mobile","tags":["draft"]}`)
+
+	mock.ExpectPrepare("INSERT INTO messages").
+		ExpectQuery().
+		WithArgs(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt, stored).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at", "metadata"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt, stored))
+	mock.ExpectPrepare("FROM messages").
+		ExpectQuery().
+		WithArgs(message.ConversationID, 10, 0).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "conversation_id", "content", "role", "created_at", "updated_at", "metadata"}).
+			AddRow(message.ID, message.UserID, message.ConversationID, message.Content, message.Role, message.CreatedAt, message.UpdatedAt, stored).
+			AddRow("55555555-5555-5555-5555-555555555555", message.UserID, message.ConversationID, "Hi", "assistant", message.CreatedAt, message.UpdatedAt, nil))
+
+	created, err := db.CreateMessage(context.Background(), message)
+	require.NoError(t, err)
+	assert.Equal(t, message.Metadata, created.Metadata)
+
+	history, err := db.GetMessagesByConversationID(context.Background(), message.ConversationID, 10, 0)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+
+	require.Len(t, history, 2)
+	assert.Equal(t, message.Metadata, history[0].Metadata)
+	assert.Nil(t, history[1].Metadata)
+}
+
+func TestReserveDailyTokenUsage_KeysByUTCDate(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := "11111111-1111-1111-1111-111111111111"
+
+	// 23:30 on the 1st at UTC-5 is already the 2nd in UTC
+	day := time.Date(2025, 8, 1, 23, 30, 0, 0, time.FixedZone("EST", -5*60*60))
+	mock.ExpectPrepare("INSERT INTO usage_counters").
+		ExpectQuery().
+		WithArgs(userID, "2025-08-02", 300, sqlmock.AnyArg(), 2000).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens"}).AddRow(1800))
+
+	reserved, err := db.ReserveDailyTokenUsage(context.Background(), userID, day, 300, 2000)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.True(t, reserved)
+}
+
+func TestReserveDailyTokenUsage_RejectedWhenOverBudget(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := "11111111-1111-1111-1111-111111111111"
+
+	// The guarded upsert updates no row once the reservation doesn't fit
+	mock.ExpectPrepare(regexp.QuoteMeta("WHERE usage_counters.tokens + EXCLUDED.tokens <= $5")).
+		ExpectQuery().
+		WillReturnRows(sqlmock.NewRows([]string{"tokens"}))
+
+	reserved, err := db.ReserveDailyTokenUsage(context.Background(), userID, time.Now(), 300, 2000)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.False(t, reserved)
+}
+
+func TestReserveDailyTokenUsage_EstimateOverBudgetSkipsQuery(t *testing.T) {
+	db, mock := newMockDB(t)
+
+	reserved, err := db.ReserveDailyTokenUsage(context.Background(), "11111111-1111-1111-1111-111111111111", time.Now(), 2001, 2000)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.False(t, reserved)
+}
+
+func TestAddDailyTokenUsage_ReturnsDayTotal(t *testing.T) {
+	db, mock := newMockDB(t)
+	userID := "11111111-1111-1111-1111-111111111111"
+
+	mock.ExpectPrepare("INSERT INTO usage_counters").
+		ExpectQuery().
+		WithArgs(userID, "2025-08-02", 250, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"tokens"}).AddRow(1750))
+
+	total, err := db.AddDailyTokenUsage(context.Background(), userID, time.Date(2025, 8, 2, 12, 0, 0, 0, time.UTC), 250)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+	assert.Equal(t, 1750, total)
+}
+
+func TestTimeQuery_WarnsOnlyPastThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold time.Duration
+		duration  time.Duration
+		wantWarn  bool
+	}{
+		{name: "fast query", threshold: 50 * time.Millisecond, duration: 0, wantWarn: false},
+		{name: "slow query", threshold: 10 * time.Millisecond, duration: 30 * time.Millisecond, wantWarn: true},
+		{name: "disabled", threshold: 0, duration: 30 * time.Millisecond, wantWarn: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var logs strings.Builder
+			db := &DB{logger: zlog.New(zlog.Config{Level: "debug", Output: &logs, JSONFormat: true}), slowQueryThreshold: tt.threshold}
+			queryErr := errors.New("query failed")
+
+			err := db.timeQuery(context.Background(), "get message by id", func() error {
+				time.Sleep(tt.duration)
+				return queryErr
+			})
+
+			assert.ErrorIs(t, err, queryErr, "the query's error must be returned unchanged")
+			if tt.wantWarn {
+				assert.Contains(t, logs.String(), `"message":"slow query"`)
+				assert.Contains(t, logs.String(), `"query":"get message by id"`)
+				assert.Contains(t, logs.String(), `"duration_ms"`)
+			} else {
+				assert.Empty(t, logs.String())
+			}
+		})
+	}
+}
+
+func TestGetMessagesByConversationID_LogsSlowQuery(t *testing.T) {
+	db, mock := newMockDB(t)
+	var logs strings.Builder
+	db.logger = zlog.New(zlog.Config{Level: "warn", Output: &logs, JSONFormat: true})
+	db.slowQueryThreshold = 10 * time.Millisecond
+
+	mock.ExpectPrepare("SELECT").
+		ExpectQuery().
+		WillDelayFor(30 * time.Millisecond).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	_, err := db.GetMessagesByConversationID(context.Background(), uuid.New().String(), 10, 0)
+
+	require.NoError(t, err)
+	assert.Contains(t, logs.String(), `"query":"get messages by conversation id"`)
+	assert.NoError(t, mock.ExpectationsWereMet())
 }
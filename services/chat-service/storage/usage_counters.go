@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// Named queries
+const (
+	// The row lock taken by the upsert serializes concurrent reservations of the same user and
+	// day, so the guard sees every reservation committed before it. When the guard fails, no
+	// row is returned.
+	reserveDailyTokenUsageQuery = `
+		INSERT INTO usage_counters (
+			user_id,
+			day,
+			tokens,
+			updated_at
+		) VALUES (
+			:user_id,
+			:day,
+			:tokens,
+			:updated_at
+		)
+		ON CONFLICT (user_id, day) DO UPDATE
+		SET tokens = usage_counters.tokens + EXCLUDED.tokens, updated_at = EXCLUDED.updated_at
+		WHERE usage_counters.tokens + EXCLUDED.tokens <= :budget
+		RETURNING tokens
+	`
+
+	addDailyTokenUsageQuery = `
+		INSERT INTO usage_counters (
+			user_id,
+			day,
+			tokens,
+			updated_at
+		) VALUES (
+			:user_id,
+			:day,
+			:tokens,
+			:updated_at
+		)
+		ON CONFLICT (user_id, day) DO UPDATE
+		SET tokens = usage_counters.tokens + EXCLUDED.tokens, updated_at = EXCLUDED.updated_at
+		RETURNING tokens
+	`
+)
+
+// usageDay returns the usage_counters day key of t, the date of t in UTC
+func usageDay(t time.Time) string {
+	return t.UTC().Format(time.DateOnly)
+}
+
+// ReserveDailyTokenUsage adds tokens to the user's usage for the UTC day of day unless that
+// takes the day's total over budget, in which case nothing is added and reserved is false.
+// Concurrent reservations cannot overshoot the budget together.
+func (db *DB) ReserveDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens, budget int) (reserved bool, err error) {
+	if tokens > budget {
+		return false, nil
+	}
+	return retryWrite(ctx, db, "reserve daily token usage", func() (bool, error) {
+		return db.reserveDailyTokenUsage(ctx, userID, day, tokens, budget)
+	})
+}
+
+// reserveDailyTokenUsage runs one attempt of ReserveDailyTokenUsage
+func (db *DB) reserveDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens, budget int) (bool, error) {
+	params := map[string]any{
+		"user_id":    userID,
+		"day":        usageDay(day),
+		"tokens":     tokens,
+		"updated_at": time.Now().UTC(),
+		"budget":     budget,
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, reserveDailyTokenUsageQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare usage reservation failed", http.StatusInternalServerError)
+		return false, err
+	}
+	defer stmt.Close()
+
+	var total int
+	err = stmt.GetContext(ctx, &total, params)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "usage reservation failed", status)
+		return false, mappedErr
+	}
+
+	return true, nil
+}
+
+// AddDailyTokenUsage adds tokens to the user's usage for the UTC day of day and returns the
+// day's new total. tokens is negative to give back part of a reservation.
+func (db *DB) AddDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens int) (int, error) {
+	return retryWrite(ctx, db, "add daily token usage", func() (int, error) {
+		return db.addDailyTokenUsage(ctx, userID, day, tokens)
+	})
+}
+
+// addDailyTokenUsage runs one attempt of AddDailyTokenUsage
+func (db *DB) addDailyTokenUsage(ctx context.Context, userID string, day time.Time, tokens int) (int, error) {
+	params := map[string]any{
+		"user_id":    userID,
+		"day":        usageDay(day),
+		"tokens":     tokens,
+		"updated_at": time.Now().UTC(),
+	}
+
+	stmt, err := db.PrepareNamedContext(ctx, addDailyTokenUsageQuery)
+	if err != nil {
+		db.logger.Error(ctx, err, "prepare usage upsert failed", http.StatusInternalServerError)
+		return 0, err
+	}
+	defer stmt.Close()
+
+	var total int
+	if err := stmt.GetContext(ctx, &total, params); err != nil {
+		status, mappedErr := HandlePgError(err)
+		db.logger.Error(ctx, mappedErr, "usage upsert failed", status)
+		return 0, mappedErr
+	}
+
+	return total, nil
+}